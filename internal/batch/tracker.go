@@ -8,6 +8,7 @@ import (
 )
 
 type tag struct {
+	index int
 }
 
 type tagType *tag
@@ -61,3 +62,107 @@ func withTag(tag tagType, p types.Part) types.Part {
 
 	return message.WithContext(ctx, p)
 }
+
+//------------------------------------------------------------------------------
+
+// Tracker tags the messages of a batch such that the messages that failed can
+// be identified from a batch error returned by a downstream component, even
+// when the component only received a subset of the batch. This allows
+// components that retry or reroute batches to only do so for the messages that
+// failed.
+type Tracker struct {
+	source  types.Message
+	tags    []tagType
+	indexes []int
+	msg     types.Message
+}
+
+// NewTracker creates a tracker for a message batch.
+func NewTracker(msg types.Message) *Tracker {
+	tags := make([]tagType, msg.Len())
+	indexes := make([]int, msg.Len())
+	taggedParts := make([]types.Part, msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		tags[i] = &tag{index: i}
+		indexes[i] = i
+		taggedParts[i] = withTag(tags[i], p)
+		return nil
+	})
+	source := message.New(nil)
+	source.SetAll(taggedParts)
+	return &Tracker{
+		source:  source,
+		tags:    tags,
+		indexes: indexes,
+		msg:     source,
+	}
+}
+
+// Message returns the tagged messages that are currently being tracked, which
+// is either the whole batch or a subset of it that previously failed.
+func (t *Tracker) Message() types.Message {
+	return t.msg
+}
+
+// Failed returns a tracker of only the messages of the current batch that
+// failed according to an error returned after sending Message. If the error
+// does not identify individual messages, or they cannot be linked back to the
+// batch, then the tracker is returned unchanged.
+func (t *Tracker) Failed(err error) *Tracker {
+	walkable, ok := err.(WalkableError)
+	if !ok || walkable.IndexedErrors() == 0 {
+		return t
+	}
+
+	seen := map[int]bool{}
+	walkable.WalkParts(func(_ int, p types.Part, pErr error) bool {
+		for _, i := range t.indexes {
+			if hasTag(p, t.tags[i]) {
+				if failed, exists := seen[i]; !exists || !failed {
+					seen[i] = pErr != nil
+				}
+			}
+		}
+		return true
+	})
+
+	var failedIndexes []int
+	for _, i := range t.indexes {
+		// Messages that weren't seen are assumed to have failed.
+		if failed, exists := seen[i]; !exists || failed {
+			failedIndexes = append(failedIndexes, i)
+		}
+	}
+	if len(failedIndexes) == 0 || len(failedIndexes) == len(t.indexes) {
+		return t
+	}
+
+	msg := message.New(nil)
+	for _, i := range failedIndexes {
+		msg.Append(t.source.Get(i))
+	}
+	return &Tracker{
+		source:  t.source,
+		tags:    t.tags,
+		indexes: failedIndexes,
+		msg:     msg,
+	}
+}
+
+// Err converts an error returned after sending Message into an error for the
+// original batch. When only a subset of the batch failed the result is a
+// batch error where only the failed messages are marked as such.
+func (t *Tracker) Err(err error) error {
+	if err == nil {
+		return nil
+	}
+	failed := t.Failed(err)
+	if len(failed.indexes) == t.source.Len() {
+		return err
+	}
+	batchErr := NewError(t.source, err)
+	for _, i := range failed.indexes {
+		batchErr.Failed(i, batchErr.Unwrap())
+	}
+	return batchErr
+}
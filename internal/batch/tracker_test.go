@@ -0,0 +1,73 @@
+package batch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trackerContents(msg types.Message) []string {
+	var contents []string
+	msg.Iter(func(i int, p types.Part) error {
+		contents = append(contents, string(p.Get()))
+		return nil
+	})
+	return contents
+}
+
+func TestTrackerFailed(t *testing.T) {
+	tracker := NewTracker(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"), []byte("buz"),
+	}))
+	assert.Equal(t, []string{"foo", "bar", "baz", "buz"}, trackerContents(tracker.Message()))
+
+	// Errors that don't identify messages result in the whole batch.
+	assert.Equal(t, tracker, tracker.Failed(errors.New("nope")))
+	assert.Equal(t, tracker, tracker.Failed(NewError(tracker.Message(), errors.New("nope"))))
+
+	failed := tracker.Failed(
+		NewError(tracker.Message(), errors.New("nope")).
+			Failed(1, errors.New("a")).
+			Failed(3, errors.New("b")),
+	)
+	assert.Equal(t, []string{"bar", "buz"}, trackerContents(failed.Message()))
+
+	// Errors from the subset are linked back to the original messages.
+	failed = failed.Failed(NewError(failed.Message(), errors.New("nope")).Failed(1, errors.New("c")))
+	assert.Equal(t, []string{"buz"}, trackerContents(failed.Message()))
+
+	err := failed.Err(errors.New("nope"))
+	walkable, ok := err.(WalkableError)
+	require.True(t, ok)
+
+	var failedIndexes []int
+	walkable.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failedIndexes = append(failedIndexes, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{3}, failedIndexes)
+	assert.Equal(t, "nope", err.Error())
+}
+
+func TestTrackerUnseenParts(t *testing.T) {
+	tracker := NewTracker(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+
+	// A downstream component that only reports on part of the batch.
+	partial := message.New(nil)
+	partial.Append(tracker.Message().Get(0))
+	partial.Append(tracker.Message().Get(1))
+
+	failed := tracker.Failed(NewError(partial, errors.New("nope")).Failed(0, errors.New("a")))
+	assert.Equal(t, []string{"foo", "baz"}, trackerContents(failed.Message()))
+
+	assert.Equal(t, errors.New("nope"), tracker.Err(errors.New("nope")))
+	assert.Nil(t, tracker.Err(nil))
+}
@@ -3,6 +3,7 @@ package mapping
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
@@ -273,6 +274,119 @@ func (e *Executor) QueryTargets(ctx query.TargetsContext) []query.TargetPath {
 	return paths
 }
 
+// ReturnTypes returns the set of value types that the mapping might result in
+// when executed as a query function, or an empty slice if the types are
+// unknown.
+func (e *Executor) ReturnTypes() []query.ValueType {
+	var rootTypes [][]query.ValueType
+	for _, stmt := range e.statements {
+		assign, isJSON := stmt.assignment.(*JSONAssignment)
+		if !isJSON {
+			continue
+		}
+		if len(assign.path) > 0 {
+			rootTypes = append(rootTypes, []query.ValueType{query.ValueObject})
+		} else {
+			rootTypes = append(rootTypes, query.InferTypes(stmt.query))
+		}
+	}
+	if len(rootTypes) == 0 {
+		return nil
+	}
+	return query.UnionTypes(rootTypes...)
+}
+
+// TypeCheck always returns nil as the type errors of a mapping are those of
+// its statements, which are obtained with CheckTypes.
+func (e *Executor) TypeCheck() error {
+	return nil
+}
+
+// Children returns the query functions of each statement of the mapping.
+func (e *Executor) Children() []query.Function {
+	children := make([]query.Function, 0, len(e.statements))
+	for _, stmt := range e.statements {
+		children = append(children, stmt.query)
+	}
+	return children
+}
+
+// CheckTypes statically analyses the statements and maps of the mapping and
+// returns any type errors that are certain to occur during execution,
+// regardless of the input data.
+func (e *Executor) CheckTypes() []error {
+	var errs []error
+	seen := map[string]struct{}{}
+	addErr := func(err error) {
+		if _, exists := seen[err.Error()]; !exists {
+			seen[err.Error()] = struct{}{}
+			errs = append(errs, err)
+		}
+	}
+
+	e.checkStatementTypes(e.input, addErr)
+
+	mapNames := make([]string, 0, len(e.maps))
+	for k := range e.maps {
+		mapNames = append(mapNames, k)
+	}
+	sort.Strings(mapNames)
+	for _, k := range mapNames {
+		if exec, isExec := e.maps[k].(*Executor); isExec {
+			exec.checkStatementTypes(e.input, addErr)
+		}
+	}
+	return errs
+}
+
+func (e *Executor) checkStatementTypes(root []rune, addErr func(error)) {
+	for _, stmt := range e.statements {
+		line := lineOfClip(root, stmt.input)
+		query.WalkFunctions(stmt.query, func(fn query.Function) bool {
+			if exec, isExec := fn.(*Executor); isExec {
+				exec.checkStatementTypes(root, addErr)
+				return false
+			}
+			if typed, isTyped := fn.(query.TypedFunction); isTyped {
+				if err := typed.TypeCheck(); err != nil {
+					addErr(&TypeCheckError{Line: line, Err: err})
+				}
+			}
+			return true
+		})
+	}
+}
+
+// TypeCheckError describes a type error found during static analysis of a
+// mapping, along with the line of the statement that contains it.
+type TypeCheckError struct {
+	Line int
+	Err  error
+}
+
+// Error returns a human readable description of the error.
+func (t *TypeCheckError) Error() string {
+	return fmt.Sprintf("line %v: %v", t.Line, t.Err)
+}
+
+// Unwrap returns the underlying type error.
+func (t *TypeCheckError) Unwrap() error {
+	return t.Err
+}
+
+// lineOfClip returns the line number of a clip within an input, or zero if the
+// clip is not a tail of the input, which is the case for imported mappings.
+func lineOfClip(input, clip []rune) int {
+	if len(input) == 0 || len(clip) == 0 || len(clip) > len(input) {
+		return 0
+	}
+	if &input[len(input)-1] != &clip[len(clip)-1] {
+		return 0
+	}
+	line, _ := LineAndColOf(input, clip)
+	return line
+}
+
 // AssignmentTargets returns a slice of all targets assigned to by statements
 // within the mapping.
 func (e *Executor) AssignmentTargets() []TargetPath {
@@ -36,3 +36,18 @@ func NewMapping(path, expr string) (*mapping.Executor, error) {
 	}
 	return e, nil
 }
+
+// NewMappingWithCoverage attempts to parse and create a Bloblang mapping from a
+// string, where the executions of the branches of match and if expressions
+// within the mapping are recorded by a coverage recorder.
+func NewMappingWithCoverage(path, expr string, cov *query.BranchCoverage) (*mapping.Executor, error) {
+	e, err := parser.ParseMapping(path, expr, parser.Context{
+		Functions: query.AllFunctions,
+		Methods:   query.AllMethods,
+		Coverage:  cov,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
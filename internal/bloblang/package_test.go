@@ -37,6 +37,7 @@ func TestFunctionExamples(t *testing.T) {
 			for i, e := range spec.Examples {
 				m, err := NewMapping("", e.Mapping)
 				require.NoError(t, err)
+				assert.Empty(t, m.CheckTypes(), i)
 
 				for j, io := range e.Results {
 					msg := message.New([][]byte{[]byte(io[0])})
@@ -85,6 +86,7 @@ func TestMethodExamples(t *testing.T) {
 			for i, e := range spec.Examples {
 				m, err := NewMapping("", e.Mapping)
 				require.NoError(t, err)
+				assert.Empty(t, m.CheckTypes(), i)
 
 				for j, io := range e.Results {
 					msg := message.New([][]byte{[]byte(io[0])})
@@ -106,6 +108,7 @@ func TestMethodExamples(t *testing.T) {
 				for i, e := range target.Examples {
 					m, err := NewMapping("", e.Mapping)
 					require.NoError(t, err)
+					assert.Empty(t, m.CheckTypes(), fmt.Sprintf("%v-%v", target.Category, i))
 
 					for j, io := range e.Results {
 						msg := message.New([][]byte{[]byte(io[0])})
@@ -216,3 +219,110 @@ func TestMappings(t *testing.T) {
 		})
 	}
 }
+
+func TestMappingTypeChecks(t *testing.T) {
+	tests := map[string]struct {
+		mapping string
+		errs    []string
+	}{
+		"no type errors": {
+			mapping: `root.a = this.foo.uppercase()
+root.b = uuid_v4().length()
+root.c = (this.a | "default").trim()
+root.d = [1, 2, 3].sum() + 10`,
+		},
+		"bad method target": {
+			mapping: `root.a = this.foo.uppercase()
+root.b = 10.uppercase()`,
+			errs: []string{
+				"line 2: method uppercase: expected string or bytes value, found number",
+			},
+		},
+		"bad method chain": {
+			mapping: `root = this.foo.keys().abs()`,
+			errs: []string{
+				"line 1: method abs: expected number value, found array",
+			},
+		},
+		"bad arithmetic operand": {
+			mapping: `root = this.foo - uuid_v4()`,
+			errs: []string{
+				"line 1: right operand: expected number value, found string",
+			},
+		},
+		"bytes concatenation": {
+			mapping: `root.a = content() + "-suffix"
+root.b = this.foo.decode("base64") + "bar"
+root.c = (content() + "baz").uppercase()`,
+		},
+		"bad logical operands": {
+			mapping: `root.a = this.foo && true
+root.b = count("foo") || true`,
+			errs: []string{
+				"line 2: left operand: expected bool value, found number",
+			},
+		},
+		"bad conditions": {
+			mapping: `root.a = if uuid_v4() { "foo" }
+root.b = !count("foo")`,
+			errs: []string{
+				"line 1: if condition: expected bool value, found string",
+				"line 2: not operator: expected bool value, found number",
+			},
+		},
+		"if expression types": {
+			mapping: `root = if this.foo { "a" } else { "b" }.abs()`,
+			errs: []string{
+				"line 1: method abs: expected number value, found string",
+			},
+		},
+		"match expression types": {
+			mapping: `root.a = (match this.a { "x" => 5, _ => 6 }).uppercase()
+root.b = match { _ => "x" }.uppercase()
+root.c = match this.c { "x" => "y" }.uppercase()`,
+			errs: []string{
+				"line 1: method uppercase: expected string or bytes value, found number",
+			},
+		},
+		"user function body": {
+			mapping: `func greet(name) {
+  root = "hello " + $name.uppercase()
+}
+
+func broken() {
+  root = uuid_v4().keys()
+}
+
+root.a = greet("foo").abs()
+root.b = broken()
+root.c = broken()`,
+			errs: []string{
+				"line 9: method abs: expected number value, found string",
+				"line 6: method keys: expected object value, found string",
+			},
+		},
+		"maps": {
+			mapping: `map foo {
+  root = 10.lowercase()
+}
+root = this.apply("foo")`,
+			errs: []string{
+				"line 2: method lowercase: expected string or bytes value, found number",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			m, err := NewMapping("", test.mapping)
+			require.NoError(t, err)
+
+			var errStrs []string
+			for _, err := range m.CheckTypes() {
+				errStrs = append(errStrs, err.Error())
+			}
+			assert.Equal(t, test.errs, errStrs)
+		})
+	}
+}
@@ -150,6 +150,19 @@ func (e *Error) Add(from *Error) {
 	}
 }
 
+// ErrorMsg returns a human readable error string without any positional
+// information, which is useful for tooling that reports the position of an
+// error separately.
+func (e *Error) ErrorMsg() string {
+	if importErr, isImport := e.Err.(*ImportError); isImport {
+		return fmt.Sprintf(
+			"failed to parse import '%v': %v", importErr.filepath,
+			importErr.perr.ErrorAtPosition(importErr.content),
+		)
+	}
+	return e.errorMsg(false)
+}
+
 // ErrorAtPosition returns a human readable error string including the line and
 // character position of the error.
 func (e *Error) ErrorAtPosition(input []rune) string {
@@ -19,12 +19,13 @@ import (
 // messages.
 func ParseMapping(filepath string, expr string, pCtx Context) (*mapping.Executor, *Error) {
 	in := []rune(expr)
+	pCtx.source = in
 	dir := ""
 	if len(filepath) > 0 {
 		dir = path.Dir(filepath)
 	}
 	res := BestMatch(
-		parseExecutor(dir, pCtx),
+		parseExecutor(dir, pCtx, newUserFunctionSet(pCtx.Functions)),
 		singleRootMapping(pCtx),
 	)(in)
 	if res.Err != nil {
@@ -35,18 +36,23 @@ func ParseMapping(filepath string, expr string, pCtx Context) (*mapping.Executor
 
 //------------------------------------------------------------------------------'
 
-func parseExecutor(baseDir string, pCtx Context) Func {
+// parseExecutor parses a mapping and any functions it declares, which are
+// added to the provided user function set so that they can be imported.
+func parseExecutor(baseDir string, pCtx Context, funcs *userFunctionSet) Func {
 	newline := NewlineAllowComment()
 	whitespace := SpacesAndTabs()
 	allWhitespace := DiscardAll(OneOf(whitespace, newline))
 
+	pCtx.Functions = funcs
+
 	return func(input []rune) Result {
 		maps := map[string]query.Function{}
 		statements := []mapping.Statement{}
 
 		statement := OneOf(
-			importParser(baseDir, maps, pCtx),
+			importParser(baseDir, maps, funcs, pCtx),
 			mapParser(maps, pCtx),
+			funcParser(funcs, pCtx),
 			letStatementParser(pCtx),
 			metaStatementParser(false, pCtx),
 			plainMappingStatementParser(pCtx),
@@ -128,7 +134,7 @@ func varNameParser() Func {
 	)
 }
 
-func importParser(baseDir string, maps map[string]query.Function, pCtx Context) Func {
+func importParser(baseDir string, maps map[string]query.Function, funcs *userFunctionSet, pCtx Context) Func {
 	p := Sequence(
 		Term("import"),
 		SpacesAndTabs(),
@@ -154,14 +160,17 @@ func importParser(baseDir string, maps map[string]query.Function, pCtx Context)
 		}
 
 		importContent := []rune(string(contents))
-		execRes := parseExecutor(path.Dir(filepath), pCtx)(importContent)
+		importFuncs := newUserFunctionSet(funcs)
+		importCtx := pCtx
+		importCtx.source = importContent
+		execRes := parseExecutor(path.Dir(filepath), importCtx, importFuncs)(importContent)
 		if execRes.Err != nil {
 			return Fail(NewFatalError(input, NewImportError(filepath, importContent, execRes.Err)), input)
 		}
 
 		exec := execRes.Payload.(*mapping.Executor)
-		if len(exec.Maps()) == 0 {
-			err := fmt.Errorf("no maps to import from '%v'", filepath)
+		if len(exec.Maps()) == 0 && len(importFuncs.funcs) == 0 {
+			err := fmt.Errorf("no maps or functions to import from '%v'", filepath)
 			return Fail(NewFatalError(input, err), input)
 		}
 
@@ -178,6 +187,18 @@ func importParser(baseDir string, maps map[string]query.Function, pCtx Context)
 			return Fail(NewFatalError(input, err), input)
 		}
 
+		for _, fn := range importFuncs.funcs {
+			if _, exists := funcs.funcs[fn.name]; exists {
+				collisions = append(collisions, fn.name)
+			} else {
+				funcs.funcs[fn.name] = fn
+			}
+		}
+		if len(collisions) > 0 {
+			err := fmt.Errorf("function name collisions from import '%v': %v", filepath, collisions)
+			return Fail(NewFatalError(input, err), input)
+		}
+
 		return Success(filepath, res.Remaining)
 	}
 }
@@ -249,6 +270,89 @@ func mapParser(maps map[string]query.Function, pCtx Context) Func {
 	}
 }
 
+func funcParser(funcs *userFunctionSet, pCtx Context) Func {
+	newline := NewlineAllowComment()
+	whitespace := SpacesAndTabs()
+	allWhitespace := DiscardAll(OneOf(whitespace, newline))
+
+	header := Sequence(
+		Term("func"),
+		whitespace,
+		Expect(SnakeCase(), "function name"),
+		Discard(SpacesAndTabs()),
+		MustBe(Expect(DelimitedPattern(
+			Sequence(Char('('), Discard(SpacesAndTabs())),
+			Expect(SnakeCase(), "parameter name"),
+			Sequence(Discard(SpacesAndTabs()), Char(','), Discard(SpacesAndTabs())),
+			Sequence(Discard(SpacesAndTabs()), Char(')')),
+			false,
+		), "function parameters")),
+		SpacesAndTabs(),
+	)
+
+	body := MustBe(DelimitedPattern(
+		Sequence(
+			Char('{'),
+			allWhitespace,
+		),
+		OneOf(
+			letStatementParser(pCtx),
+			metaStatementParser(true, pCtx),
+			plainMappingStatementParser(pCtx),
+		),
+		Sequence(
+			Discard(whitespace),
+			newline,
+			allWhitespace,
+		),
+		Sequence(
+			allWhitespace,
+			Char('}'),
+		),
+		true,
+	))
+
+	return func(input []rune) Result {
+		res := header(input)
+		if res.Err != nil {
+			return res
+		}
+
+		seqSlice := res.Payload.([]interface{})
+		name := seqSlice[2].(string)
+
+		var params []string
+		seen := map[string]struct{}{}
+		for _, p := range seqSlice[4].([]interface{}) {
+			param := p.(string)
+			if _, exists := seen[param]; exists {
+				return Fail(NewFatalError(input, fmt.Errorf("duplicate parameter name in function %v: %v", name, param)), input)
+			}
+			seen[param] = struct{}{}
+			params = append(params, param)
+		}
+
+		if res = body(res.Remaining); res.Err != nil {
+			return Fail(res.Err, input)
+		}
+
+		stmtSlice := res.Payload.([]interface{})
+		statements := make([]mapping.Statement, len(stmtSlice))
+		for i, v := range stmtSlice {
+			statements[i] = v.(mapping.Statement)
+		}
+
+		if err := funcs.add(&userFunction{
+			name:   name,
+			params: params,
+			body:   mapping.NewExecutor(input, map[string]query.Function{}, statements...),
+		}); err != nil {
+			return Fail(NewFatalError(input, err), input)
+		}
+		return Success(name, res.Remaining)
+	}
+}
+
 func letStatementParser(pCtx Context) Func {
 	p := Sequence(
 		Expect(Term("let"), "assignment"),
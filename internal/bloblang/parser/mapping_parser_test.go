@@ -34,12 +34,12 @@ func TestMappingErrors(t *testing.T) {
 	}{
 		"no mappings": {
 			mapping: ``,
-			err:     `line 1 char 1: expected import, map, or assignment`,
+			err:     `line 1 char 1: expected import, map, func, or assignment`,
 		},
 		"no mappings 2": {
 			mapping: `
    `,
-			err: `line 2 char 4: expected import, map, or assignment`,
+			err: `line 2 char 4: expected import, map, func, or assignment`,
 		},
 		"double mapping": {
 			mapping: `foo = bar bar = baz`,
@@ -75,13 +75,13 @@ foo = bar bar = baz
 		"bad char 2": {
 			mapping: `let foo = bar
 !foo = bar`,
-			err: `line 2 char 1: expected import, map, or assignment`,
+			err: `line 2 char 1: expected import, map, func, or assignment`,
 		},
 		"bad char 3": {
 			mapping: `let foo = bar
 !foo = bar
 this = that`,
-			err: `line 2 char 1: expected import, map, or assignment`,
+			err: `line 2 char 1: expected import, map, func, or assignment`,
 		},
 		"bad query": {
 			mapping: `foo = blah.`,
@@ -131,7 +131,7 @@ foo = bar.apply("from_import")`, badMapFile),
 			mapping: fmt.Sprintf(`import "%v"
 
 foo = bar.apply("from_import")`, noMapsFile),
-			err: fmt.Sprintf(`line 1 char 1: no maps to import from '%v'`, noMapsFile),
+			err: fmt.Sprintf(`line 1 char 1: no maps or functions to import from '%v'`, noMapsFile),
 		},
 		"colliding maps file import": {
 			mapping: fmt.Sprintf(`map "foo" { this = that }			
@@ -144,7 +144,7 @@ foo = bar.apply("foo")`, goodMapFile),
 		"quotes at root": {
 			mapping: `
 "root.something" = 5 + 2`,
-			err: "line 2 char 1: expected import, map, or assignment",
+			err: "line 2 char 1: expected import, map, func, or assignment",
 		},
 	}
 
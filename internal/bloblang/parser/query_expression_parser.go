@@ -1,12 +1,50 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 )
 
+// branchLabel summarises the source of a branch for coverage reports by
+// collapsing whitespace and truncating long expressions.
+func branchLabel(source []rune) string {
+	label := strings.Join(strings.Fields(string(source)), " ")
+	if runes := []rune(label); len(runes) > 60 {
+		label = string(runes[:57]) + "..."
+	}
+	return label
+}
+
+// captureSource wraps a parser and, when it succeeds, writes the input that was
+// consumed to dst.
+func captureSource(p Func, dst *[]rune) Func {
+	return func(input []rune) Result {
+		res := p(input)
+		if res.Err == nil {
+			*dst = input[:len(input)-len(res.Remaining)]
+		}
+		return res
+	}
+}
+
+// captureInput wraps a parser and, when it succeeds, writes the input at which
+// it began to dst.
+func captureInput(p Func, dst *[]rune) Func {
+	return func(input []rune) Result {
+		res := p(input)
+		if res.Err == nil {
+			*dst = input
+		}
+		return res
+	}
+}
+
 func matchCaseParser(pCtx Context) Func {
 	whitespace := SpacesAndTabs()
 
+	var bodyInput []rune
+
 	p := Sequence(
 		OneOf(
 			Sequence(
@@ -27,7 +65,7 @@ func matchCaseParser(pCtx Context) Func {
 			),
 		),
 		Optional(whitespace),
-		queryParser(pCtx),
+		captureInput(queryParser(pCtx), &bodyInput),
 	)
 
 	return func(input []rune) Result {
@@ -56,8 +94,9 @@ func matchCaseParser(pCtx Context) Func {
 			caseFn = query.NewLiteralFunction(true)
 		}
 
+		label := "match case " + branchLabel(input[:len(input)-len(res.Remaining)])
 		return Success(
-			query.NewMatchCase(caseFn, seqSlice[2].(query.Function)),
+			query.NewMatchCase(caseFn, pCtx.coverBranch(bodyInput, label, seqSlice[2].(query.Function))),
 			res.Remaining,
 		)
 	}
@@ -126,14 +165,15 @@ func ifExpressionParser(pCtx Context) Func {
 	)
 
 	return func(input []rune) Result {
+		var condSource, ifInput, elseInput []rune
 		res := Sequence(
 			Term("if"),
 			SpacesAndTabs(),
-			MustBe(queryParser(pCtx)),
+			MustBe(captureSource(queryParser(pCtx), &condSource)),
 			optionalWhitespace,
 			MustBe(Char('{')),
 			optionalWhitespace,
-			MustBe(queryParser(pCtx)),
+			MustBe(captureInput(queryParser(pCtx), &ifInput)),
 			optionalWhitespace,
 			MustBe(Char('}')),
 			Optional(
@@ -143,7 +183,7 @@ func ifExpressionParser(pCtx Context) Func {
 					optionalWhitespace,
 					MustBe(Char('{')),
 					optionalWhitespace,
-					MustBe(queryParser(pCtx)),
+					MustBe(captureInput(queryParser(pCtx), &elseInput)),
 					optionalWhitespace,
 					MustBe(Char('}')),
 				),
@@ -155,12 +195,15 @@ func ifExpressionParser(pCtx Context) Func {
 
 		seqSlice := res.Payload.([]interface{})
 		queryFn := seqSlice[2].(query.Function)
-		ifFn := seqSlice[6].(query.Function)
+		ifLabel := "if " + branchLabel(condSource)
+		ifFn := pCtx.coverBranch(ifInput, ifLabel, seqSlice[6].(query.Function))
 
 		var elseFn query.Function
 		elseSlice, _ := seqSlice[9].([]interface{})
 		if len(elseSlice) > 0 {
-			elseFn, _ = elseSlice[5].(query.Function)
+			if elseFn, _ = elseSlice[5].(query.Function); elseFn != nil {
+				elseFn = pCtx.coverBranch(elseInput, "else of "+ifLabel, elseFn)
+			}
 		}
 
 		res.Payload = query.NewIfFunction(queryFn, ifFn, elseFn)
@@ -237,3 +237,54 @@ func TestExpressionsParser(t *testing.T) {
 		})
 	}
 }
+
+func TestExpressionsBranchCoverage(t *testing.T) {
+	cov := query.NewBranchCoverage()
+	pCtx := Context{
+		Functions: query.AllFunctions,
+		Methods:   query.AllMethods,
+		Coverage:  cov,
+	}
+
+	mappingStr := `root.a = match {
+  this.type == "a" => if this.id > 5 { "big a" } else { "small a" }
+  this.type == "b" => "b"
+  _ => "other"
+}
+root.b = if (this.id | 0) > 5 { "big" } else { "small" }`
+
+	// Parsing the same mapping twice should result in the same branches.
+	_, err := ParseMapping("", mappingStr, pCtx)
+	require.Nil(t, err)
+	e, err := ParseMapping("", mappingStr, pCtx)
+	require.Nil(t, err)
+
+	for _, v := range []interface{}{
+		map[string]interface{}{"type": "a", "id": int64(10)},
+		map[string]interface{}{"type": "c"},
+		map[string]interface{}{"type": "a", "id": int64(12)},
+	} {
+		v := v
+		_, execErr := e.Exec(query.FunctionContext{
+			MsgBatch: message.New(nil),
+		}.WithValueFunc(func() *interface{} { return &v }))
+		require.NoError(t, execErr)
+	}
+
+	var labels []string
+	var hits []int64
+	for _, b := range cov.Branches() {
+		labels = append(labels, b.Label)
+		hits = append(hits, b.Hits())
+	}
+	assert.Equal(t, []string{
+		`line 2: if this.id > 5`,
+		`line 2: else of if this.id > 5`,
+		`line 2: match case this.type == "a" => if this.id > 5 { "big a" } else { "sm...`,
+		`line 3: match case this.type == "b" => "b"`,
+		`line 4: match case _ => "other"`,
+		`line 6: if (this.id | 0) > 5`,
+		`line 6: else of if (this.id | 0) > 5`,
+	}, labels)
+	assert.Equal(t, []int64{2, 0, 2, 0, 1, 2, 1}, hits)
+}
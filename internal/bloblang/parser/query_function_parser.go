@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
@@ -20,7 +21,7 @@ func functionArgsParser(pCtx Context) Func {
 	return func(input []rune) Result {
 		return DelimitedPattern(
 			Expect(Sequence(open, whitespace), "function arguments"),
-			MustBe(Expect(queryParser(pCtx), "function argument")),
+			MustBe(Expect(OneOf(namedArgParser(pCtx), queryParser(pCtx)), "function argument")),
 			MustBe(Expect(Sequence(Discard(SpacesAndTabs()), comma, whitespace), "comma")),
 			MustBe(Expect(Sequence(whitespace, close), "closing bracket")),
 			true,
@@ -28,6 +29,28 @@ func functionArgsParser(pCtx Context) Func {
 	}
 }
 
+func namedArgParser(pCtx Context) Func {
+	p := Sequence(
+		SnakeCase(),
+		Discard(SpacesAndTabs()),
+		Char(':'),
+		Discard(SpacesAndTabs()),
+		queryParser(pCtx),
+	)
+
+	return func(input []rune) Result {
+		res := p(input)
+		if res.Err != nil {
+			return res
+		}
+		seqSlice := res.Payload.([]interface{})
+		return Success(namedArg{
+			name:  seqSlice[0].(string),
+			value: seqSlice[4],
+		}, res.Remaining)
+	}
+}
+
 func parseFunctionTail(fn query.Function, pCtx Context) Func {
 	openBracket := Char('(')
 	closeBracket := Char(')')
@@ -291,6 +314,12 @@ func methodParser(fn query.Function, pCtx Context) Func {
 
 		targetMethod := seqSlice[0].(string)
 		args := seqSlice[1].([]interface{})
+		if _, named, err := splitArgs(args); err != nil || len(named) > 0 {
+			if err == nil {
+				err = fmt.Errorf("method '%v' does not support named arguments", targetMethod)
+			}
+			return Fail(NewFatalError(input, err), input)
+		}
 
 		method, err := pCtx.InitMethod(targetMethod, fn, args...)
 		if err != nil {
@@ -319,6 +348,14 @@ func functionParser(pCtx Context) Func {
 
 		targetFunc := seqSlice[0].(string)
 		args := seqSlice[1].([]interface{})
+		if _, isUserSet := pCtx.Functions.(*userFunctionSet); !isUserSet {
+			if _, named, err := splitArgs(args); err != nil || len(named) > 0 {
+				if err == nil {
+					err = fmt.Errorf("function '%v' does not support named arguments", targetFunc)
+				}
+				return Fail(NewFatalError(input, err), input)
+			}
+		}
 
 		fn, err := pCtx.InitFunction(targetFunc, args...)
 		if err != nil {
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 )
 
@@ -19,6 +21,14 @@ type MethodSet interface {
 type Context struct {
 	Functions FunctionSet
 	Methods   MethodSet
+
+	// Coverage is an optional recorder of the branches of match and if
+	// expressions, which when set counts the executions of each branch.
+	Coverage *query.BranchCoverage
+
+	// The full input being parsed, used for identifying the position of
+	// branches for coverage.
+	source []rune
 }
 
 // InitFunction attempts to initialise a function from the available
@@ -33,6 +43,18 @@ func (pCtx Context) InitMethod(name string, target query.Function, args ...inter
 	return pCtx.Methods.Init(name, target, args...)
 }
 
+// coverBranch wraps the function of a branch with coverage recording when the
+// parser context has a coverage recorder, where input is the input at which the
+// branch begins.
+func (pCtx Context) coverBranch(input []rune, label string, fn query.Function) query.Function {
+	if pCtx.Coverage == nil || len(input) > len(pCtx.source) {
+		return fn
+	}
+	line, _ := LineAndColOf(pCtx.source, input)
+	label = fmt.Sprintf("line %v: %v", line, label)
+	return pCtx.Coverage.Cover(string(pCtx.source), len(pCtx.source)-len(input), label, fn)
+}
+
 func queryParser(pCtx Context) func(input []rune) Result {
 	rootParser := parseWithTails(Expect(
 		OneOf(
@@ -0,0 +1,199 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+)
+
+// namedArg is the payload of a function argument that was provided in the
+// form `name: value`.
+type namedArg struct {
+	name  string
+	value interface{}
+}
+
+// splitArgs separates positional arguments from named arguments, returning an
+// error if a positional argument follows a named one.
+func splitArgs(args []interface{}) ([]interface{}, []namedArg, error) {
+	var positional []interface{}
+	var named []namedArg
+	for _, arg := range args {
+		if nArg, isNamed := arg.(namedArg); isNamed {
+			named = append(named, nArg)
+			continue
+		}
+		if len(named) > 0 {
+			return nil, nil, errors.New("positional arguments cannot follow named arguments")
+		}
+		positional = append(positional, arg)
+	}
+	return positional, named, nil
+}
+
+//------------------------------------------------------------------------------
+
+// userFunction is a function declared within a mapping with the `func`
+// keyword.
+type userFunction struct {
+	name   string
+	params []string
+	body   *mapping.Executor
+}
+
+// resolveArgs orders positional and named arguments according to the
+// parameters of the function, and returns an error if they do not match the
+// declared arity.
+func (u *userFunction) resolveArgs(positional []interface{}, named []namedArg) ([]query.Function, error) {
+	if len(positional) > len(u.params) {
+		return nil, fmt.Errorf("function '%v' expects %v arguments, received %v", u.name, len(u.params), len(positional)+len(named))
+	}
+
+	args := make([]query.Function, len(u.params))
+	for i, arg := range positional {
+		args[i] = arg.(query.Function)
+	}
+
+	for _, nArg := range named {
+		index := -1
+		for i, p := range u.params {
+			if p == nArg.name {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			return nil, fmt.Errorf("function '%v' has no parameter named '%v'", u.name, nArg.name)
+		}
+		if args[index] != nil {
+			return nil, fmt.Errorf("parameter '%v' of function '%v' was provided more than once", nArg.name, u.name)
+		}
+		args[index] = nArg.value.(query.Function)
+	}
+
+	for i, arg := range args {
+		if arg == nil {
+			return nil, fmt.Errorf("missing argument for parameter '%v' of function '%v'", u.params[i], u.name)
+		}
+	}
+	return args, nil
+}
+
+// call returns a query function that executes the body of the user function
+// with its parameters bound to the results of the provided arguments. The
+// body is executed within the same context as the caller, but with an isolated
+// set of variables.
+func (u *userFunction) call(args []query.Function) query.Function {
+	fn := query.ClosureFunction(func(ctx query.FunctionContext) (interface{}, error) {
+		vars := make(map[string]interface{}, len(args))
+		for i, arg := range args {
+			v, err := arg.Exec(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve argument '%v' of function '%v': %w", u.params[i], u.name, err)
+			}
+			vars[u.params[i]] = v
+		}
+
+		// Functions only see their own parameters as variables, isolating them
+		// from the variables of the calling mapping.
+		ctx.Vars = vars
+		res, err := u.body.Exec(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("function '%v': %w", u.name, err)
+		}
+		return res, nil
+	}, func(ctx query.TargetsContext) []query.TargetPath {
+		var paths []query.TargetPath
+		for _, arg := range args {
+			paths = append(paths, arg.QueryTargets(ctx)...)
+		}
+		for _, p := range u.body.QueryTargets(ctx) {
+			if p.Type != query.TargetVariable {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	})
+	return &userFunctionCall{Function: fn, fn: u, args: args}
+}
+
+// userFunctionCall is an invocation of a user defined function, which exposes
+// the body of the function for static type checking.
+type userFunctionCall struct {
+	query.Function
+	fn   *userFunction
+	args []query.Function
+}
+
+func (c *userFunctionCall) ReturnTypes() []query.ValueType {
+	return c.fn.body.ReturnTypes()
+}
+
+func (c *userFunctionCall) TypeCheck() error {
+	return nil
+}
+
+func (c *userFunctionCall) Children() []query.Function {
+	children := make([]query.Function, 0, len(c.args)+1)
+	children = append(children, c.args...)
+	return append(children, c.fn.body)
+}
+
+//------------------------------------------------------------------------------
+
+// userFunctionSet is a FunctionSet that resolves functions declared within a
+// mapping before falling back to a parent set.
+type userFunctionSet struct {
+	parent FunctionSet
+	funcs  map[string]*userFunction
+}
+
+func newUserFunctionSet(parent FunctionSet) *userFunctionSet {
+	if uSet, ok := parent.(*userFunctionSet); ok {
+		parent = uSet.parent
+	}
+	return &userFunctionSet{
+		parent: parent,
+		funcs:  map[string]*userFunction{},
+	}
+}
+
+// add a user function to the set, returning an error if the name collides with
+// an existing function.
+func (u *userFunctionSet) add(fn *userFunction) error {
+	if _, exists := u.funcs[fn.name]; exists {
+		return fmt.Errorf("function name collision: %v", fn.name)
+	}
+	if lister, ok := u.parent.(interface{ List() []string }); ok {
+		for _, name := range lister.List() {
+			if name == fn.name {
+				return fmt.Errorf("function name collides with a builtin function: %v", fn.name)
+			}
+		}
+	}
+	u.funcs[fn.name] = fn
+	return nil
+}
+
+// Init attempts to initialize a function by name, where user functions are
+// checked first.
+func (u *userFunctionSet) Init(name string, args ...interface{}) (query.Function, error) {
+	positional, named, err := splitArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	fn, exists := u.funcs[name]
+	if !exists {
+		if len(named) > 0 {
+			return nil, fmt.Errorf("function '%v' does not support named arguments", name)
+		}
+		return u.parent.Init(name, args...)
+	}
+	fnArgs, err := fn.resolveArgs(positional, named)
+	if err != nil {
+		return nil, err
+	}
+	return fn.call(fnArgs), nil
+}
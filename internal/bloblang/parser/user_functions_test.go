@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFunctionErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_user_function_errors")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	funcFile := filepath.Join(dir, "funcs.blobl")
+	require.NoError(t, ioutil.WriteFile(funcFile, []byte(`func add(a, b) {
+  root = $a + $b
+}`), 0777))
+
+	tests := map[string]struct {
+		mapping string
+		err     string
+	}{
+		"too many args": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+root = add(1, 2, 3)`,
+			err: `line 4 char 8: function 'add' expects 2 arguments, received 3`,
+		},
+		"too few args": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+root = add(1)`,
+			err: `line 4 char 8: missing argument for parameter 'b' of function 'add'`,
+		},
+		"unknown named arg": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+root = add(a: 1, c: 2)`,
+			err: `line 4 char 8: function 'add' has no parameter named 'c'`,
+		},
+		"duplicate named arg": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+root = add(1, a: 2)`,
+			err: `line 4 char 8: parameter 'a' of function 'add' was provided more than once`,
+		},
+		"positional after named": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+root = add(a: 1, 2)`,
+			err: `line 4 char 8: positional arguments cannot follow named arguments`,
+		},
+		"named args to builtin": {
+			mapping: `root = uuid_v4(foo: "bar")`,
+			err:     `line 1 char 8: function 'uuid_v4' does not support named arguments`,
+		},
+		"named args to method": {
+			mapping: `root = this.uppercase(foo: "bar")`,
+			err:     `line 1 char 13: method 'uppercase' does not support named arguments`,
+		},
+		"called before declared": {
+			mapping: `root = add(1, 2)
+func add(a, b) {
+  root = $a + $b
+}`,
+			err: `line 1 char 8: unrecognised function 'add'`,
+		},
+		"duplicate declaration": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+func add(a, b) {
+  root = $a + $b
+}`,
+			err: `line 4 char 1: function name collision: add`,
+		},
+		"builtin collision": {
+			mapping: `func uuid_v4() {
+  root = "nope"
+}`,
+			err: `line 1 char 1: function name collides with a builtin function: uuid_v4`,
+		},
+		"duplicate param": {
+			mapping: `func add(a, a) {
+  root = $a + $a
+}`,
+			err: `line 1 char 1: duplicate parameter name in function add: a`,
+		},
+		"bad params": {
+			mapping: `func add(a b) {
+  root = $a + $b
+}`,
+			err: `line 1 char 12: required: expected function parameters`,
+		},
+		"meta in body": {
+			mapping: `func add(a, b) {
+  meta foo = $a + $b
+}`,
+			err: `line 2 char 3: setting meta fields from within a map is not allowed`,
+		},
+		"colliding import": {
+			mapping: fmt.Sprintf(`func add(a, b) {
+  root = $a + $b
+}
+import "%v"`, funcFile),
+			err: fmt.Sprintf(`line 4 char 1: function name collisions from import '%v': [add]`, funcFile),
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			exec, err := ParseMapping("", test.mapping, Context{
+				Functions: query.AllFunctions,
+				Methods:   query.AllMethods,
+			})
+			require.NotNil(t, err)
+			assert.Equal(t, test.err, err.ErrorAtPosition([]rune(test.mapping)))
+			assert.Nil(t, exec)
+		})
+	}
+}
+
+func TestUserFunctions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_user_functions")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	funcFile := filepath.Join(dir, "funcs.blobl")
+	require.NoError(t, ioutil.WriteFile(funcFile, []byte(`func greet(greeting, name) {
+  root = "%s %s!".format($greeting, $name)
+}`), 0777))
+
+	tests := map[string]struct {
+		mapping string
+		input   string
+		output  string
+	}{
+		"positional args": {
+			mapping: `func add(a, b) {
+  root = $a + $b
+}
+root.sum = add(this.a, this.b)`,
+			input:  `{"a":3,"b":4}`,
+			output: `{"sum":7}`,
+		},
+		"named args": {
+			mapping: `func sub(a, b) {
+  root = $a - $b
+}
+root.res = sub(b: this.a, a: this.b)`,
+			input:  `{"a":3,"b":4}`,
+			output: `{"res":1}`,
+		},
+		"mixed args": {
+			mapping: `func sub(a, b) {
+  root = $a - $b
+}
+root.res = sub(this.b, b: this.a)`,
+			input:  `{"a":3,"b":4}`,
+			output: `{"res":1}`,
+		},
+		"no args uses context": {
+			mapping: `func names() {
+  root = this.people.map_each(this.name)
+}
+root.names = names()`,
+			input:  `{"people":[{"name":"foo"},{"name":"bar"}]}`,
+			output: `{"names":["foo","bar"]}`,
+		},
+		"structured result": {
+			mapping: `func wrap(v, key) {
+  let tmp = $v.uppercase()
+  root = {}
+  root.key = $key
+  root.value = $tmp
+}
+root = wrap(this.value, "foo")`,
+			input:  `{"value":"hello"}`,
+			output: `{"key":"foo","value":"HELLO"}`,
+		},
+		"isolated variables": {
+			mapping: `let outer = "outer"
+func get_var() {
+  root = $outer | "not set"
+}
+root.res = get_var()`,
+			input:  `{}`,
+			output: `{"res":"not set"}`,
+		},
+		"nested calls": {
+			mapping: `func double(v) {
+  root = $v * 2
+}
+func quad(v) {
+  root = double(double($v))
+}
+root.res = quad(this.v)`,
+			input:  `{"v":3}`,
+			output: `{"res":12}`,
+		},
+		"imported function": {
+			mapping: fmt.Sprintf(`import "%v"
+root.res = greet(name: this.name, greeting: "hello")`, funcFile),
+			input:  `{"name":"world"}`,
+			output: `{"res":"hello world!"}`,
+		},
+		"field named func": {
+			mapping: `func = "still works"`,
+			input:   `{}`,
+			output:  `{"func":"still works"}`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			exec, perr := ParseMapping("", test.mapping, Context{
+				Functions: query.AllFunctions,
+				Methods:   query.AllMethods,
+			})
+			require.Nil(t, perr)
+
+			resPart, err := exec.MapPart(0, message.New([][]byte{[]byte(test.input)}))
+			require.NoError(t, err)
+			assert.Equal(t, test.output, string(resPart.Get()))
+		})
+	}
+}
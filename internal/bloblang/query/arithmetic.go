@@ -435,11 +435,14 @@ func NewArithmeticExpression(fns []Function, ops []ArithmeticOperator) (Function
 	fnsNew, opsNew := []Function{fns[0]}, []ArithmeticOperator{}
 	for i, op := range ops {
 		if opFunc, isProd := prodOp(op); isProd {
-			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(fnsNew[len(fnsNew)-1], fns[i+1], opFunc); err != nil {
+			lhs := fnsNew[len(fnsNew)-1]
+			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(lhs, fns[i+1], opFunc); err != nil {
 				return nil, err
 			}
+			fnsNew[len(fnsNew)-1] = typedArithmetic(fnsNew[len(fnsNew)-1], lhs, fns[i+1], op)
 		} else if op == ArithmeticPipe {
-			fnsNew[len(fnsNew)-1] = coalesce(fnsNew[len(fnsNew)-1], fns[i+1])
+			lhs := fnsNew[len(fnsNew)-1]
+			fnsNew[len(fnsNew)-1] = typedArithmetic(coalesce(lhs, fns[i+1]), lhs, fns[i+1], op)
 		} else {
 			fnsNew = append(fnsNew, fns[i+1])
 			opsNew = append(opsNew, op)
@@ -454,9 +457,11 @@ func NewArithmeticExpression(fns []Function, ops []ArithmeticOperator) (Function
 	fnsNew, opsNew = []Function{fns[0]}, []ArithmeticOperator{}
 	for i, op := range ops {
 		if opFunc, isSum := sumOp(op); isSum {
-			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(fnsNew[len(fnsNew)-1], fns[i+1], opFunc); err != nil {
+			lhs := fnsNew[len(fnsNew)-1]
+			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(lhs, fns[i+1], opFunc); err != nil {
 				return nil, err
 			}
+			fnsNew[len(fnsNew)-1] = typedArithmetic(fnsNew[len(fnsNew)-1], lhs, fns[i+1], op)
 		} else {
 			fnsNew = append(fnsNew, fns[i+1])
 			opsNew = append(opsNew, op)
@@ -471,9 +476,11 @@ func NewArithmeticExpression(fns []Function, ops []ArithmeticOperator) (Function
 	fnsNew, opsNew = []Function{fns[0]}, []ArithmeticOperator{}
 	for i, op := range ops {
 		if opFunc, isCompare := compareOp(op); isCompare {
-			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(fnsNew[len(fnsNew)-1], fns[i+1], opFunc); err != nil {
+			lhs := fnsNew[len(fnsNew)-1]
+			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(lhs, fns[i+1], opFunc); err != nil {
 				return nil, err
 			}
+			fnsNew[len(fnsNew)-1] = typedArithmetic(fnsNew[len(fnsNew)-1], lhs, fns[i+1], op)
 		} else {
 			fnsNew = append(fnsNew, fns[i+1])
 			opsNew = append(opsNew, op)
@@ -489,9 +496,11 @@ func NewArithmeticExpression(fns []Function, ops []ArithmeticOperator) (Function
 	for i, op := range ops {
 		switch op {
 		case ArithmeticAnd:
-			fnsNew[len(fnsNew)-1] = boolAnd(fnsNew[len(fnsNew)-1], fns[i+1])
+			lhs := fnsNew[len(fnsNew)-1]
+			fnsNew[len(fnsNew)-1] = typedArithmetic(boolAnd(lhs, fns[i+1]), lhs, fns[i+1], op)
 		case ArithmeticOr:
-			fnsNew[len(fnsNew)-1] = boolOr(fnsNew[len(fnsNew)-1], fns[i+1])
+			lhs := fnsNew[len(fnsNew)-1]
+			fnsNew[len(fnsNew)-1] = typedArithmetic(boolOr(lhs, fns[i+1]), lhs, fns[i+1], op)
 		default:
 			fnsNew = append(fnsNew, fns[i+1])
 			opsNew = append(opsNew, op)
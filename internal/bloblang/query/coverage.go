@@ -0,0 +1,94 @@
+package query
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+//------------------------------------------------------------------------------
+
+// BranchStat describes a branch of a match or if expression along with the
+// number of times it has been executed.
+type BranchStat struct {
+	Label string
+	hits  int64
+}
+
+// Hits returns the number of times the branch has been executed.
+func (b *BranchStat) Hits() int64 {
+	return atomic.LoadInt64(&b.hits)
+}
+
+// BranchCoverage records the branches of match and if expressions that are
+// parsed with it, and counts the executions of each branch. Branches are
+// identified by the mapping they belong to and their position within it, which
+// means a mapping that is parsed multiple times is counted as one.
+type BranchCoverage struct {
+	mut      sync.Mutex
+	branches []*BranchStat
+	byKey    map[branchKey]*BranchStat
+}
+
+type branchKey struct {
+	mapping string
+	offset  int
+}
+
+// NewBranchCoverage creates an empty branch coverage recorder.
+func NewBranchCoverage() *BranchCoverage {
+	return &BranchCoverage{
+		byKey: map[branchKey]*BranchStat{},
+	}
+}
+
+// Cover returns a function that records each execution of the provided
+// function as a branch of a mapping, where offset is the position of the
+// branch within the mapping.
+func (b *BranchCoverage) Cover(mapping string, offset int, label string, fn Function) Function {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	key := branchKey{mapping: mapping, offset: offset}
+	stat, exists := b.byKey[key]
+	if !exists {
+		stat = &BranchStat{Label: label}
+		b.byKey[key] = stat
+		b.branches = append(b.branches, stat)
+	}
+	return &coveredFunction{fn: fn, stat: stat}
+}
+
+// Branches returns the branches recorded in the order they were first parsed.
+func (b *BranchCoverage) Branches() []*BranchStat {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return append([]*BranchStat{}, b.branches...)
+}
+
+//------------------------------------------------------------------------------
+
+type coveredFunction struct {
+	fn   Function
+	stat *BranchStat
+}
+
+func (c *coveredFunction) Exec(ctx FunctionContext) (interface{}, error) {
+	atomic.AddInt64(&c.stat.hits, 1)
+	return c.fn.Exec(ctx)
+}
+
+func (c *coveredFunction) QueryTargets(ctx TargetsContext) []TargetPath {
+	return c.fn.QueryTargets(ctx)
+}
+
+func (c *coveredFunction) ReturnTypes() []ValueType {
+	return InferTypes(c.fn)
+}
+
+func (c *coveredFunction) TypeCheck() error {
+	return nil
+}
+
+func (c *coveredFunction) Children() []Function {
+	return []Function{c.fn}
+}
@@ -66,6 +66,14 @@ type FunctionSpec struct {
 
 	// Examples shows general usage for the function.
 	Examples []ExampleSpec
+
+	// ParamTypes optionally describes the types accepted by each positional
+	// parameter of the function, used for static type checking.
+	ParamTypes [][]ValueType
+
+	// ReturnTypes optionally describes the types that the function returns,
+	// used for static type inference.
+	ReturnTypes []ValueType
 }
 
 // NewFunctionSpec creates a new function spec.
@@ -85,6 +93,22 @@ func (s FunctionSpec) Beta() FunctionSpec {
 	return s
 }
 
+// Param adds the types accepted by the next positional parameter of the
+// function, which are used for static type checking.
+func (s FunctionSpec) Param(types ...ValueType) FunctionSpec {
+	params := make([][]ValueType, 0, len(s.ParamTypes)+1)
+	params = append(params, s.ParamTypes...)
+	s.ParamTypes = append(params, types)
+	return s
+}
+
+// Returns sets the types that the function might return, which are used for
+// static type inference.
+func (s FunctionSpec) Returns(types ...ValueType) FunctionSpec {
+	s.ReturnTypes = types
+	return s
+}
+
 // NewDeprecatedFunctionSpec creates a new function spec that is deprecated.
 func NewDeprecatedFunctionSpec(name, description string, examples ...ExampleSpec) FunctionSpec {
 	return FunctionSpec{
@@ -147,6 +171,18 @@ type MethodSpec struct {
 
 	// Categories that this method fits within.
 	Categories []MethodCatSpec
+
+	// InputTypes optionally describes the types of values that the method can
+	// be applied to, used for static type checking.
+	InputTypes []ValueType
+
+	// ParamTypes optionally describes the types accepted by each positional
+	// parameter of the method, used for static type checking.
+	ParamTypes [][]ValueType
+
+	// ReturnTypes optionally describes the types that the method returns,
+	// used for static type inference.
+	ReturnTypes []ValueType
 }
 
 // NewMethodSpec creates a new method spec.
@@ -184,6 +220,29 @@ func (m MethodSpec) Beta() MethodSpec {
 	return m
 }
 
+// Accepts sets the types of values that the method can be applied to, which
+// are used for static type checking.
+func (m MethodSpec) Accepts(types ...ValueType) MethodSpec {
+	m.InputTypes = types
+	return m
+}
+
+// Param adds the types accepted by the next positional parameter of the
+// method, which are used for static type checking.
+func (m MethodSpec) Param(types ...ValueType) MethodSpec {
+	params := make([][]ValueType, 0, len(m.ParamTypes)+1)
+	params = append(params, m.ParamTypes...)
+	m.ParamTypes = append(params, types)
+	return m
+}
+
+// Returns sets the types that the method might return, which are used for
+// static type inference.
+func (m MethodSpec) Returns(types ...ValueType) MethodSpec {
+	m.ReturnTypes = types
+	return m
+}
+
 // InCategory describes the methods behaviour in the context of a given
 // category, methods can belong to multiple categories. For example, the
 // `contains` method behaves differently in the object and array category versus
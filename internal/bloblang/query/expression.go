@@ -31,7 +31,7 @@ func NewMatchFunction(contextFn Function, cases ...MatchCase) Function {
 			return value, nil
 		}, nil)
 	}
-	return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
+	fn := ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
 		ctxVal, err := contextFn.Exec(ctx)
 		if err != nil {
 			return nil, err
@@ -61,13 +61,37 @@ func NewMatchFunction(contextFn Function, cases ...MatchCase) Function {
 		targets = append(targets, contextTargets...)
 		return targets
 	})
+
+	children := []Function{contextFn}
+	for _, c := range cases {
+		children = append(children, c.caseFn, c.queryFn)
+	}
+	return &typedFunction{
+		Function: fn,
+		returnTypes: func() []ValueType {
+			var branchTypes [][]ValueType
+			catchAll := false
+			for _, c := range cases {
+				branchTypes = append(branchTypes, InferTypes(c.queryFn))
+				if lit, isLiteral := c.caseFn.(*Literal); isLiteral && lit.Value == true {
+					catchAll = true
+					break
+				}
+			}
+			if !catchAll {
+				branchTypes = append(branchTypes, []ValueType{ValueNothing})
+			}
+			return UnionTypes(branchTypes...)
+		},
+		children: children,
+	}
 }
 
 // NewIfFunction creates a logical if expression from a query which should
 // return a boolean value. If the returned boolean is true then the ifFn is
 // executed and returned, otherwise elseFn is executed and returned.
 func NewIfFunction(queryFn Function, ifFn Function, elseFn Function) Function {
-	return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
+	fn := ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
 		queryVal, err := queryFn.Exec(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check if condition: %w", err)
@@ -80,4 +104,21 @@ func NewIfFunction(queryFn Function, ifFn Function, elseFn Function) Function {
 		}
 		return Nothing(nil), nil
 	}, aggregateTargetPaths(queryFn, ifFn, elseFn))
+
+	return &typedFunction{
+		Function: fn,
+		returnTypes: func() []ValueType {
+			if elseFn == nil {
+				return UnionTypes([]ValueType{ValueNothing}, InferTypes(ifFn))
+			}
+			return UnionTypes(InferTypes(ifFn), InferTypes(elseFn))
+		},
+		check: func() error {
+			if err := checkTypesOf(InferTypes(queryFn), ValueBool); err != nil {
+				return fmt.Errorf("if condition: %w", err)
+			}
+			return nil
+		},
+		children: functionsOf(queryFn, ifFn, elseFn),
+	}
 }
@@ -29,6 +29,7 @@ func (f *FunctionSet) Add(spec FunctionSpec, ctor FunctionCtor, autoResolveFunct
 	if autoResolveFunctionArgs {
 		ctor = functionWithAutoResolvedFunctionArgs(ctor)
 	}
+	ctor = functionWithTypes(spec, ctor)
 	if _, exists := f.constructors[spec.Name]; exists {
 		return fmt.Errorf("conflicting function name: %v", spec.Name)
 	}
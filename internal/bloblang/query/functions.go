@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/gofrs/uuid"
@@ -100,7 +101,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root = if batch_index() > 0 { deleted() }`,
 		),
-	),
+	).Returns(ValueNumber),
 	false, func(...interface{}) (Function, error) {
 		return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
 			return int64(ctx.Index), nil
@@ -117,7 +118,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.foo = batch_size()`,
 		),
-	),
+	).Returns(ValueNumber),
 	false, func(...interface{}) (Function, error) {
 		return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
 			return int64(ctx.MsgBatch.Len()), nil
@@ -136,7 +137,7 @@ var _ = RegisterFunction(
 			`{"foo":"bar"}`,
 			`{"doc":"{\"foo\":\"bar\"}"}`,
 		),
-	),
+	).Returns(ValueBytes),
 	false, contentFunction,
 )
 
@@ -160,7 +161,7 @@ root.id = count("bloblang_function_example")`,
 			`{"message":"bar"}`,
 			`{"id":2,"message":"bar"}`,
 		),
-	),
+	).Returns(ValueNumber),
 	true, countFunction,
 	ExpectNArgs(1),
 	ExpectStringArg(0),
@@ -210,7 +211,7 @@ root.bar = deleted()`,
 			`{"nums":[3,11,4,17]}`,
 			`{"new_nums":[1,7]}`,
 		),
-	),
+	).Returns(ValueDelete),
 	false, func(...interface{}) (Function, error) {
 		return NewLiteralFunction(Delete(nil)), nil
 	},
@@ -225,7 +226,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.thing.key = env("key")`,
 		),
-	),
+	).Returns(ValueString),
 	true, envFunction,
 	ExpectNArgs(1),
 	ExpectStringArg(0),
@@ -245,7 +246,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.doc.error = error()`,
 		),
-	),
+	).Returns(ValueString),
 	false, errorFunction,
 )
 
@@ -262,7 +263,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.doc.status = if errored() { 400 } else { 200 }`,
 		),
-	),
+	).Returns(ValueBool),
 	false, erroredFunction,
 )
 
@@ -283,7 +284,7 @@ var _ = RegisterFunction(
 			`{}`,
 			`{"doc":{"foo":"bar"}}`,
 		),
-	).Beta(),
+	).Beta().Returns(ValueBytes),
 	true, fileFunction,
 	ExpectNArgs(1),
 	ExpectStringArg(0),
@@ -310,7 +311,7 @@ root.c = range(0, -this.max, -2)`,
 			`{"max":10}`,
 			`{"a":[0,1,2,3,4,5,6,7,8,9],"b":[0,2,4,6,8],"c":[0,-2,-4,-6,-8]}`,
 		),
-	),
+	).Returns(ValueArray),
 	true, rangeFunction,
 	ExpectBetweenNAndMArgs(2, 3),
 	ExpectIntArg(0),
@@ -344,7 +345,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.thing.host = hostname()`,
 		),
-	),
+	).Returns(ValueString),
 	false, hostnameFunction,
 )
 
@@ -363,6 +364,30 @@ func hostnameFunction(...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "ingestion_timestamp",
+		"Returns the time at which a message was consumed by an input as a string in ISO 8601 format with the local timezone, or `null` if the message has no ingestion time. This is useful for measuring how long a message has spent within the pipeline.",
+		NewExampleSpec("",
+			`root.ingested_at = ingestion_timestamp()`,
+		),
+		NewExampleSpec("",
+			`root.seconds_in_pipeline = timestamp_unix() - ingestion_timestamp().parse_timestamp_unix()`,
+		),
+	).Returns(ValueString, ValueNull),
+	false, func(...interface{}) (Function, error) {
+		return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
+			t, exists := message.GetIngestionTime(ctx.MsgBatch.Get(ctx.Index))
+			if !exists {
+				return nil, nil
+			}
+			return t.Format(time.RFC3339Nano), nil
+		}, nil), nil
+	},
+)
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterFunction(
 	NewFunctionSpec(
 		FunctionCategoryMessage, "json",
@@ -481,7 +506,7 @@ var _ = RegisterFunction(
 			`root.first = random_int()
 root.second = random_int(1)`,
 		),
-	),
+	).Returns(ValueNumber),
 	true, randomIntFunction,
 	ExpectOneOrZeroArgs(),
 	ExpectIntArg(1),
@@ -513,7 +538,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.received_at = now().format_timestamp("Mon Jan 2 15:04:05 -0700 MST 2006", "UTC")`,
 		),
-	),
+	).Returns(ValueString),
 	true, func(args ...interface{}) (Function, error) {
 		return ClosureFunction(func(_ FunctionContext) (interface{}, error) {
 			return time.Now().Format(time.RFC3339Nano), nil
@@ -571,7 +596,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.received_at = timestamp_unix()`,
 		),
-	),
+	).Returns(ValueNumber),
 	false, func(...interface{}) (Function, error) {
 		return ClosureFunction(func(_ FunctionContext) (interface{}, error) {
 			return time.Now().Unix(), nil
@@ -586,7 +611,7 @@ var _ = RegisterFunction(
 		NewExampleSpec("",
 			`root.received_at = timestamp_unix_nano()`,
 		),
-	),
+	).Returns(ValueNumber),
 	false, func(...interface{}) (Function, error) {
 		return ClosureFunction(func(_ FunctionContext) (interface{}, error) {
 			return time.Now().UnixNano(), nil
@@ -630,7 +655,7 @@ var _ = RegisterFunction(
 		FunctionCategoryGeneral, "uuid_v4",
 		"Generates a new RFC-4122 UUID each time it is invoked and prints a string representation.",
 		NewExampleSpec("", `root.id = uuid_v4()`),
-	),
+	).Returns(ValueString),
 	false, uuidFunction,
 )
 
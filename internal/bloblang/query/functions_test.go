@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/stretchr/testify/assert"
@@ -227,3 +228,22 @@ func TestRandomInt(t *testing.T) {
 		assert.LessOrEqual(t, v, int64(10))
 	}
 }
+
+func TestIngestionTimestampFunction(t *testing.T) {
+	e, err := InitFunction("ingestion_timestamp")
+	require.NoError(t, err)
+
+	ts := time.Date(2021, 2, 3, 4, 5, 6, 7, time.UTC)
+
+	msg := message.New(nil)
+	msg.Append(message.WithIngestionTime(ts, message.NewPart([]byte("foo"))))
+	msg.Append(message.NewPart([]byte("bar")))
+
+	res, err := e.Exec(FunctionContext{MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, "2021-02-03T04:05:06.000000007Z", res)
+
+	res, err = e.Exec(FunctionContext{MsgBatch: msg, Index: 1})
+	require.NoError(t, err)
+	assert.Nil(t, res)
+}
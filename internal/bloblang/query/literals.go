@@ -117,6 +117,22 @@ func (m *mapLiteral) QueryTargets(ctx TargetsContext) []TargetPath {
 	return targetPaths
 }
 
+func (m *mapLiteral) ReturnTypes() []ValueType {
+	return []ValueType{ValueObject}
+}
+
+func (m *mapLiteral) TypeCheck() error {
+	return nil
+}
+
+func (m *mapLiteral) Children() []Function {
+	var children []Function
+	for _, kv := range m.keyValues {
+		children = append(children, functionsOf(kv[0], kv[1])...)
+	}
+	return children
+}
+
 //------------------------------------------------------------------------------
 
 var _ Function = &arrayLiteral{}
@@ -180,3 +196,15 @@ func (a *arrayLiteral) QueryTargets(ctx TargetsContext) []TargetPath {
 	}
 	return targetPaths
 }
+
+func (a *arrayLiteral) ReturnTypes() []ValueType {
+	return []ValueType{ValueArray}
+}
+
+func (a *arrayLiteral) TypeCheck() error {
+	return nil
+}
+
+func (a *arrayLiteral) Children() []Function {
+	return functionsOf(a.values...)
+}
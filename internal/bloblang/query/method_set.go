@@ -25,6 +25,7 @@ func (m *MethodSet) Add(spec MethodSpec, ctor MethodCtor, autoResolveFunctionArg
 	if autoResolveFunctionArgs {
 		ctor = methodWithAutoResolvedFunctionArgs(ctor)
 	}
+	ctor = methodWithTypes(spec, ctor)
 	if _, exists := m.constructors[spec.Name]; exists {
 		return fmt.Errorf("conflicting method name: %v", spec.Name)
 	}
@@ -76,7 +76,7 @@ func applyMethod(target Function, args ...interface{}) (Function, error) {
 //------------------------------------------------------------------------------
 
 var _ = RegisterMethod(
-	NewMethodSpec("bool", "").InCategory(
+	NewMethodSpec("bool", "").Returns(ValueBool).InCategory(
 		MethodCategoryCoercion,
 		"Attempt to parse a value into a boolean. An optional argument can be provided, in which case if the value cannot be parsed the argument will be returned instead. If the value is a number then any non-zero value will resolve to `true`, if the value is a string then any of the following values are considered valid: `1, t, T, TRUE, true, True, 0, f, F, FALSE`.",
 		NewExampleSpec("",
@@ -277,6 +277,18 @@ func (g *getMethod) QueryTargets(ctx TargetsContext) []TargetPath {
 	return targets
 }
 
+func (g *getMethod) ReturnTypes() []ValueType {
+	return nil
+}
+
+func (g *getMethod) TypeCheck() error {
+	return nil
+}
+
+func (g *getMethod) Children() []Function {
+	return []Function{g.fn}
+}
+
 // NewGetMethod creates a new get method.
 func NewGetMethod(target Function, path string) (Function, error) {
 	return getMethodCtor(target, path)
@@ -323,7 +335,7 @@ func mapMethod(target Function, args ...interface{}) (Function, error) {
 	if !ok {
 		return nil, fmt.Errorf("expected query argument, received %T", args[0])
 	}
-	return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
+	fn := ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
 		res, err := target.Exec(ctx)
 		if err != nil {
 			return nil, err
@@ -331,7 +343,14 @@ func mapMethod(target Function, args ...interface{}) (Function, error) {
 		return mapFn.Exec(ctx.WithValue(res))
 	}, func(ctx TargetsContext) []TargetPath {
 		return expandTargetPaths(target.QueryTargets(ctx), mapFn.QueryTargets(ctx))
-	}), nil
+	})
+	return &typedFunction{
+		Function: fn,
+		returnTypes: func() []ValueType {
+			return InferTypes(mapFn)
+		},
+		children: []Function{target, mapFn},
+	}, nil
 }
 
 //------------------------------------------------------------------------------
@@ -368,6 +387,21 @@ func (n *notMethod) QueryTargets(ctx TargetsContext) []TargetPath {
 	return n.fn.QueryTargets(ctx)
 }
 
+func (n *notMethod) ReturnTypes() []ValueType {
+	return []ValueType{ValueBool}
+}
+
+func (n *notMethod) TypeCheck() error {
+	if err := checkTypesOf(InferTypes(n.fn), ValueBool); err != nil {
+		return fmt.Errorf("not operator: %w", err)
+	}
+	return nil
+}
+
+func (n *notMethod) Children() []Function {
+	return []Function{n.fn}
+}
+
 func notMethodCtor(target Function, _ ...interface{}) (Function, error) {
 	return &notMethod{fn: target}, nil
 }
@@ -406,7 +440,7 @@ func notNullMethod(target Function, _ ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"number", "",
-	).InCategory(
+	).Returns(ValueNumber).InCategory(
 		MethodCategoryCoercion,
 		"Attempt to parse a value into a number. An optional argument can be provided, in which case if the value cannot be parsed into a number the argument will be returned instead.",
 		NewExampleSpec("",
@@ -479,7 +513,7 @@ func orMethod(fn Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"type", "",
-	).InCategory(
+	).Returns(ValueString).InCategory(
 		MethodCategoryCoercion,
 		"Returns the type of a value as a string, providing one of the following values: `string`, `bytes`, `number`, `bool`, `array`, `object` or `null`.",
 		NewExampleSpec("",
@@ -9,7 +9,7 @@ import (
 //------------------------------------------------------------------------------
 
 var _ = RegisterMethod(
-	NewMethodSpec("abs", "Returns the absolute value of a number.").InCategory(
+	NewMethodSpec("abs", "Returns the absolute value of a number.").Accepts(ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers, "",
 		NewExampleSpec("",
 			`root.new_value = this.value.abs()`,
@@ -36,7 +36,7 @@ var _ = RegisterMethod(
 )
 
 var _ = RegisterMethod(
-	NewMethodSpec("ceil", "Returns the least integer value greater than or equal to a number.").InCategory(
+	NewMethodSpec("ceil", "Returns the least integer value greater than or equal to a number.").Accepts(ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers, "",
 		NewExampleSpec("",
 			`root.new_value = this.value.ceil()`,
@@ -63,7 +63,7 @@ var _ = RegisterMethod(
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"floor", "Returns the greatest integer value less than or equal to the target number.",
-	).InCategory(
+	).Accepts(ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers,
 		"",
 		NewExampleSpec("",
@@ -88,7 +88,7 @@ var _ = RegisterMethod(
 )
 
 var _ = RegisterMethod(
-	NewMethodSpec("log", "Returns the natural logarithm of a number.").InCategory(
+	NewMethodSpec("log", "Returns the natural logarithm of a number.").Accepts(ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers, "",
 		NewExampleSpec("",
 			`root.new_value = this.value.log().round()`,
@@ -115,7 +115,7 @@ var _ = RegisterMethod(
 )
 
 var _ = RegisterMethod(
-	NewMethodSpec("log10", "Returns the decimal logarithm of a number.").InCategory(
+	NewMethodSpec("log10", "Returns the decimal logarithm of a number.").Accepts(ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers, "",
 		NewExampleSpec("",
 			`root.new_value = this.value.log10()`,
@@ -145,7 +145,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"max",
 		"Returns the largest numerical value found within an array. All values must be numerical and the array must not be empty, otherwise an error is returned.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers, "",
 		NewExampleSpec("",
 			`root.biggest = this.values.max()`,
@@ -189,7 +189,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"min",
 		"Returns the smallest numerical value found within an array. All values must be numerical and the array must not be empty, otherwise an error is returned.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers, "",
 		NewExampleSpec("",
 			`root.smallest = this.values.min()`,
@@ -232,7 +232,7 @@ var _ = RegisterMethod(
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"round", "Rounds numbers to the nearest integer, rounding half away from zero.",
-	).InCategory(
+	).Accepts(ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryNumbers,
 		"",
 		NewExampleSpec("",
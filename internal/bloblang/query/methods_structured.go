@@ -18,7 +18,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"all",
 		"Checks each element of an array against a query and returns true if all elements passed. An error occurs if the target is not an array, or if any element results in the provided query returning a non-boolean result. Returns false if the target array is empty.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueBool).InCategory(
 		MethodCategoryObjectAndArray,
 		"",
 		NewExampleSpec("",
@@ -73,7 +73,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"any",
 		"Checks the elements of an array against a query and returns true if any element passes. An error occurs if the target is not an array, or if an element results in the provided query returning a non-boolean result. Returns false if the target array is empty.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueBool).InCategory(
 		MethodCategoryObjectAndArray,
 		"",
 		NewExampleSpec("",
@@ -130,7 +130,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"append",
 		"Returns an array with new elements appended to the end.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray,
 		"",
 		NewExampleSpec("",
@@ -199,7 +199,7 @@ func collapseMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"contains", "",
-	).InCategory(
+	).Accepts(ValueString, ValueBytes, ValueArray, ValueObject).Returns(ValueBool).InCategory(
 		MethodCategoryObjectAndArray,
 		"Checks whether an array contains an element matching the argument, or an object contains a value matching the argument, and returns a boolean result.",
 		NewExampleSpec("",
@@ -262,7 +262,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"enumerated",
 		"Converts an array into a new array of objects, where each object has a field index containing the `index` of the element and a field `value` containing the original value of the element.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray, "",
 		NewExampleSpec("",
 			`root.foo = this.foo.enumerated()`,
@@ -306,7 +306,7 @@ var _ = RegisterMethod(
 			`{"foo":{}}`,
 			`{"result":false}`,
 		),
-	),
+	).Returns(ValueBool),
 	true, existsMethod,
 	ExpectNArgs(1),
 	ExpectStringArg(0),
@@ -383,7 +383,7 @@ func explodeMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"filter", "",
-	).InCategory(
+	).Accepts(ValueArray, ValueObject).Returns(ValueArray, ValueObject).InCategory(
 		MethodCategoryObjectAndArray,
 		"Executes a mapping query argument for each element of an array or key/value pair of an object. If the query returns `false` the item is removed from the resulting array or object. The item will also be removed if the query returns any non-boolean value.",
 		NewExampleSpec(``,
@@ -456,7 +456,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"flatten",
 		"Iterates an array and any element that is itself an array is removed and has its elements inserted directly in the resulting array.",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray, "",
 		NewExampleSpec(``,
 			`root.result = this.flatten()`,
@@ -493,7 +493,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"fold",
 		"Takes two arguments: an initial value, and a mapping query. For each element of an array the mapping context is an object with two fields `tally` and `value`, where `tally` contains the current accumulated value and `value` is the value of the current element. The mapping must return the result of adding the value to the tally.\n\nThe first argument is the value that `tally` will have on the first call.",
-	).InCategory(
+	).Accepts(ValueArray).InCategory(
 		MethodCategoryObjectAndArray, "",
 		NewExampleSpec(``,
 			`root.sum = this.foo.fold(0, this.tally + this.value)`,
@@ -683,7 +683,7 @@ var _ = RegisterMethod(
 	NewMethodSpec(
 		"keys",
 		"Returns the keys of an object as an array. The order of the resulting array will be random.",
-	).InCategory(
+	).Accepts(ValueObject).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray, "",
 		NewExampleSpec("",
 			`root.foo_keys = this.foo.keys()`,
@@ -716,7 +716,7 @@ func keysMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"length", "",
-	).InCategory(
+	).Accepts(ValueString, ValueBytes, ValueArray, ValueObject).Returns(ValueNumber).InCategory(
 		MethodCategoryStrings, "Returns the length of a string.",
 		NewExampleSpec("",
 			`root.foo_len = this.foo.length()`,
@@ -989,7 +989,7 @@ func notEmptyMethod(target Function, _ ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"sort", "",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray,
 		"Attempts to sort the values of an array in increasing order. The type of all values must match in order for the ordering to be accurate. Supports string and number values.",
 		NewExampleSpec("",
@@ -1090,7 +1090,7 @@ func sortMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"slice", "",
-	).InCategory(
+	).Accepts(ValueString, ValueBytes, ValueArray).Returns(ValueString, ValueBytes, ValueArray).InCategory(
 		MethodCategoryStrings,
 		"Extract a slice from a string by specifying two indices, a low and high bound, which selects a half-open range that includes the first character, but excludes the last one. If the second index is omitted then it defaults to the length of the input sequence.",
 		NewExampleSpec("",
@@ -1195,7 +1195,7 @@ func sliceMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"sum", "",
-	).InCategory(
+	).Accepts(ValueArray, ValueNumber).Returns(ValueNumber).InCategory(
 		MethodCategoryObjectAndArray,
 		"Sum the numerical values of an array.",
 		NewExampleSpec("",
@@ -1250,7 +1250,7 @@ func sumMethod(target Function, _ ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"unique", "",
-	).InCategory(
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray,
 		"Attempts to remove duplicate values from an array. The array may contain a combination of different value types, but numbers and strings are checked separately (`\"5\"` is a different element to `5`).",
 		NewExampleSpec("",
@@ -1354,7 +1354,7 @@ func uniqueMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"values", "",
-	).InCategory(
+	).Accepts(ValueObject).Returns(ValueArray).InCategory(
 		MethodCategoryObjectAndArray,
 		"Returns the values of an object as an array. The order of the resulting array will be random.",
 		NewExampleSpec("",
@@ -1385,7 +1385,7 @@ func valuesMethod(target Function, args ...interface{}) (Function, error) {
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"without", "",
-	).InCategory(
+	).Accepts(ValueObject).Returns(ValueObject).InCategory(
 		MethodCategoryObjectAndArray,
 		`Returns an object where one or more [field path][field_paths] arguments are removed. Each path specifies a specific field to be deleted from the input object, allowing for nested fields.
 
@@ -1447,3 +1447,283 @@ func withoutMethod(target Function, args ...interface{}) (Function, error) {
 }
 
 //------------------------------------------------------------------------------
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"zip", "",
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
+		MethodCategoryObjectAndArray,
+		"Zips an array value with one or more argument arrays, returning an array where each element is an array of the elements at the same index of each input. All arrays must be of the same length.",
+		NewExampleSpec("",
+			`root.pairs = this.names.zip(this.ages)`,
+			`{"names":["foo","bar"],"ages":[21,43]}`,
+			`{"pairs":[["foo",21],["bar",43]]}`,
+		),
+	),
+	true, zipMethod,
+	ExpectAtLeastOneArg(),
+)
+
+func zipMethod(target Function, args ...interface{}) (Function, error) {
+	argArrays := make([][]interface{}, 0, len(args))
+	for i, arg := range args {
+		a, ok := arg.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("argument %v: %w", i, NewTypeError(arg, ValueArray))
+		}
+		argArrays = append(argArrays, a)
+	}
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		array, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+		for i, a := range argArrays {
+			if len(a) != len(array) {
+				return nil, fmt.Errorf("argument %v: array length %v does not match target array length %v", i, len(a), len(array))
+			}
+		}
+		zipped := make([]interface{}, len(array))
+		for i, ele := range array {
+			row := make([]interface{}, 0, len(argArrays)+1)
+			row = append(row, ele)
+			for _, a := range argArrays {
+				row = append(row, a[i])
+			}
+			zipped[i] = row
+		}
+		return zipped, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"sort_by", "",
+	).Accepts(ValueArray).Returns(ValueArray).InCategory(
+		MethodCategoryObjectAndArray,
+		"Attempts to sort the elements of an array in increasing order by a value extracted from each element with a query argument. The extracted values must all be either numbers or strings. The sort is stable, and therefore elements with equal values retain their original order.",
+		NewExampleSpec("",
+			`root.sorted = this.foo.sort_by(this.v)`,
+			`{"foo":[{"id":"foo","v":"bbb"},{"id":"bar","v":"ccc"},{"id":"baz","v":"aaa"}]}`,
+			`{"sorted":[{"id":"baz","v":"aaa"},{"id":"foo","v":"bbb"},{"id":"bar","v":"ccc"}]}`,
+		),
+	),
+	false, sortByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func sortByMethod(target Function, args ...interface{}) (Function, error) {
+	mapFn, ok := args[0].(Function)
+	if !ok {
+		return nil, fmt.Errorf("expected query argument, received %T", args[0])
+	}
+
+	return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueArray)
+		}
+
+		type keyedValue struct {
+			key   interface{}
+			value interface{}
+		}
+		values := make([]keyedValue, 0, len(m))
+		for i, ele := range m {
+			key, err := mapFn.Exec(ctx.WithValue(ele))
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			switch t := ISanitize(key).(type) {
+			case float64, int64, uint64:
+				key = t
+			case string:
+				key = t
+			case []byte:
+				key = string(t)
+			default:
+				return nil, fmt.Errorf("element %v: %w", i, NewTypeError(key, ValueNumber, ValueString))
+			}
+			values = append(values, keyedValue{key: key, value: ele})
+		}
+
+		sort.SliceStable(values, func(i, j int) bool {
+			if err != nil {
+				return false
+			}
+			switch lhs := values[i].key.(type) {
+			case string:
+				rhs, ok := values[j].key.(string)
+				if !ok {
+					err = fmt.Errorf("element %v: %w", j, NewTypeError(values[j].key, ValueString))
+				}
+				return lhs < rhs
+			default:
+				if _, ok := values[j].key.(string); ok {
+					err = fmt.Errorf("element %v: %w", j, NewTypeError(values[j].key, ValueNumber))
+					return false
+				}
+				return numberLess(lhs, values[j].key)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sorted := make([]interface{}, len(values))
+		for i, kv := range values {
+			sorted[i] = kv.value
+		}
+		return sorted, nil
+	}, aggregateTargetPaths(target, mapFn)), nil
+}
+
+// numberLess returns whether a number is less than another, where integers of
+// the same type are compared directly in order to retain their precision.
+func numberLess(lhs, rhs interface{}) bool {
+	switch l := lhs.(type) {
+	case int64:
+		if r, ok := rhs.(int64); ok {
+			return l < r
+		}
+	case uint64:
+		if r, ok := rhs.(uint64); ok {
+			return l < r
+		}
+	}
+	lf, _ := IGetNumber(lhs)
+	rf, _ := IGetNumber(rhs)
+	return lf < rf
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"key_values", "",
+	).Accepts(ValueObject).Returns(ValueArray).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the key/value pairs of an object as an array of objects, each containing the fields `key` and `value`, sorted by their keys.",
+		NewExampleSpec("",
+			`root.foo_key_values = this.foo.key_values()`,
+			`{"foo":{"bar":1,"baz":2}}`,
+			`{"foo_key_values":[{"key":"bar","value":1},{"key":"baz","value":2}]}`,
+		),
+	),
+	false, keyValuesMethod,
+	ExpectNArgs(0),
+)
+
+func keyValuesMethod(target Function, _ ...interface{}) (Function, error) {
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, NewTypeError(v, ValueObject)
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		kvs := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			kvs = append(kvs, map[string]interface{}{
+				"key":   k,
+				"value": obj[k],
+			})
+		}
+		return kvs, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"group_by", "",
+	).Accepts(ValueArray, ValueObject).Returns(ValueArray).InCategory(
+		MethodCategoryObjectAndArray,
+		"Groups the elements of an array, or the values of an object, by the result of a query argument applied to each element. Returns an array of objects, one for each unique group, containing the fields `group` and `elements`. Groups are ordered by their first appearance, and when grouping the values of an object the values are visited in key order.",
+		NewExampleSpec("",
+			`root.by_type = this.things.group_by(this.type)`,
+			`{"things":[{"type":"fruit","name":"apple"},{"type":"vegetable","name":"leek"},{"type":"fruit","name":"pear"}]}`,
+			`{"by_type":[{"elements":[{"name":"apple","type":"fruit"},{"name":"pear","type":"fruit"}],"group":"fruit"},{"elements":[{"name":"leek","type":"vegetable"}],"group":"vegetable"}]}`,
+		),
+		NewExampleSpec("",
+			`root.by_parity = this.scores.group_by(if this % 2 == 0 { "even" } else { "odd" })`,
+			`{"scores":{"a":1,"b":2,"c":3}}`,
+			`{"by_parity":[{"elements":[1,3],"group":"odd"},{"elements":[2],"group":"even"}]}`,
+		),
+	),
+	false, groupByMethod,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func groupByMethod(target Function, args ...interface{}) (Function, error) {
+	groupFn, ok := args[0].(Function)
+	if !ok {
+		return nil, fmt.Errorf("expected query argument, received %T", args[0])
+	}
+
+	return ClosureFunction(func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var elements []interface{}
+		switch t := v.(type) {
+		case []interface{}:
+			elements = t
+		case map[string]interface{}:
+			keys := make([]string, 0, len(t))
+			for k := range t {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				elements = append(elements, t[k])
+			}
+		default:
+			return nil, NewTypeError(v, ValueArray, ValueObject)
+		}
+
+		var groupKeys []string
+		groups := map[string]map[string]interface{}{}
+		for i, ele := range elements {
+			groupV, err := groupFn.Exec(ctx.WithValue(ele))
+			if err != nil {
+				return nil, fmt.Errorf("element %v: %w", i, err)
+			}
+			// Include the type of the group within the key so that values of
+			// different types, such as 1 and "1", are grouped separately.
+			groupKey := string(ITypeOf(groupV)) + ":" + IToString(groupV)
+			group, exists := groups[groupKey]
+			if !exists {
+				group = map[string]interface{}{
+					"group":    groupV,
+					"elements": []interface{}{},
+				}
+				groups[groupKey] = group
+				groupKeys = append(groupKeys, groupKey)
+			}
+			group["elements"] = append(group["elements"].([]interface{}), ele)
+		}
+
+		result := make([]interface{}, 0, len(groupKeys))
+		for _, k := range groupKeys {
+			result = append(result, groups[k])
+		}
+		return result, nil
+	}, aggregateTargetPaths(target, groupFn)), nil
+}
@@ -1,7 +1,11 @@
 package query
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"strconv"
 	"testing"
 
@@ -1906,6 +1910,136 @@ func TestMethods(t *testing.T) {
 			})),
 			output: []byte("ITAhello!ITA BOLDworld!BOLD"),
 		},
+		"check parse_yaml": {
+			input: methods(
+				literalFn("foo: 10\nbar:\n  1: [a, 2.5]\n"),
+				method("parse_yaml"),
+			),
+			output: map[string]interface{}{
+				"foo": int64(10),
+				"bar": map[string]interface{}{
+					"1": []interface{}{"a", 2.5},
+				},
+			},
+		},
+		"check parse_yaml error": {
+			input: methods(
+				literalFn("foo: [bar"),
+				method("parse_yaml"),
+			),
+			err: "failed to parse value as YAML: yaml: line 1: did not find expected ',' or ']'",
+		},
+		"check msgpack round trip": {
+			input: methods(
+				jsonFn(`{"a":[1,-200,3.5,70000],"b":{"c":true,"d":null},"e":"foo"}`),
+				method("format_msgpack"),
+				method("parse_msgpack"),
+			),
+			output: map[string]interface{}{
+				"a": []interface{}{int64(1), int64(-200), 3.5, uint64(70000)},
+				"b": map[string]interface{}{"c": true, "d": nil},
+				"e": "foo",
+			},
+		},
+		"check format_json compact": {
+			input: methods(
+				jsonFn(`{"b":"<tag>","a":[1,2]}`),
+				method("format_json", ""),
+			),
+			output: []byte(`{"a":[1,2],"b":"<tag>"}`),
+		},
+		"check parse_url no user": {
+			input: methods(
+				literalFn("http://localhost/foo?bar=baz"),
+				method("parse_url"),
+			),
+			output: map[string]interface{}{
+				"scheme":    "http",
+				"opaque":    "",
+				"host":      "localhost",
+				"hostname":  "localhost",
+				"port":      "",
+				"path":      "/foo",
+				"raw_path":  "",
+				"raw_query": "bar=baz",
+				"query":     map[string]interface{}{"bar": "baz"},
+				"fragment":  "",
+			},
+		},
+		"check parse_duration error": {
+			input: methods(
+				literalFn("nope"),
+				method("parse_duration"),
+			),
+			err: `time: invalid duration "nope"`,
+		},
+		"check add_duration nanoseconds": {
+			input: methods(
+				literalFn(int64(1597405526)),
+				method("add_duration", int64(-1000000000)),
+				method("format_timestamp", "2006-01-02T15:04:05", "UTC"),
+			),
+			output: "2020-08-14T11:45:25",
+		},
+		"check zip multiple": {
+			input: methods(
+				jsonFn(`["a","b"]`),
+				method("zip", []interface{}{int64(1), int64(2)}, []interface{}{true, false}),
+			),
+			output: []interface{}{
+				[]interface{}{"a", int64(1), true},
+				[]interface{}{"b", int64(2), false},
+			},
+		},
+		"check zip length mismatch": {
+			input: methods(
+				jsonFn(`["a","b"]`),
+				method("zip", []interface{}{int64(1)}),
+			),
+			err: "argument 0: array length 1 does not match target array length 2",
+		},
+		"check sort_by numbers stable": {
+			input: methods(
+				jsonFn(`[{"n":2,"id":"a"},{"n":1,"id":"b"},{"n":2,"id":"c"}]`),
+				method("sort_by", NewFieldFunction("n")),
+				method("map_each", NewFieldFunction("id")),
+			),
+			output: []interface{}{"b", "a", "c"},
+		},
+		"check sort_by mixed types": {
+			input: methods(
+				jsonFn(`[{"n":2},{"n":"b"}]`),
+				method("sort_by", NewFieldFunction("n")),
+			),
+			err: "element 0: expected string value, found number: 2",
+		},
+		"check sort_by large integers": {
+			input: methods(
+				literalFn([]interface{}{int64(9007199254740993), int64(9007199254740992)}),
+				method("sort_by", NewFieldFunction("")),
+			),
+			output: []interface{}{int64(9007199254740992), int64(9007199254740993)},
+		},
+		"check group_by distinguishes types": {
+			input: methods(
+				literalFn([]interface{}{"1", int64(1), "1"}),
+				method("group_by", NewFieldFunction("")),
+			),
+			output: []interface{}{
+				map[string]interface{}{"group": "1", "elements": []interface{}{"1", "1"}},
+				map[string]interface{}{"group": int64(1), "elements": []interface{}{int64(1)}},
+			},
+		},
+		"check group_by non string groups": {
+			input: methods(
+				jsonFn(`[1,2,3,4]`),
+				method("group_by", arithmetic(NewFieldFunction(""), literalFn(int64(2)), ArithmeticMod)),
+			),
+			output: []interface{}{
+				map[string]interface{}{"group": int64(1), "elements": []interface{}{float64(1), float64(3)}},
+				map[string]interface{}{"group": int64(0), "elements": []interface{}{float64(2), float64(4)}},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -2027,3 +2161,67 @@ func TestMethodNoArgsTargets(t *testing.T) {
 		assert.Contains(t, targets, exp)
 	}
 }
+
+func TestMethodCompressionRoundTrip(t *testing.T) {
+	input := []byte("hello world, hello world, hello world, hello world")
+
+	for _, algo := range []string{"gzip", "zlib", "flate", "snappy"} {
+		algo := algo
+		t.Run(algo, func(t *testing.T) {
+			compressed, err := InitMethod("compress", NewLiteralFunction(input), algo)
+			require.NoError(t, err)
+
+			decompressed, err := InitMethod("decompress", compressed, algo)
+			require.NoError(t, err)
+
+			res, err := decompressed.Exec(FunctionContext{})
+			require.NoError(t, err)
+			assert.Equal(t, input, res)
+		})
+	}
+
+	_, err := InitMethod("compress", NewLiteralFunction(input), "bzip2")
+	require.EqualError(t, err, "unrecognized compression algorithm: bzip2")
+}
+
+func TestMethodRSASignatures(t *testing.T) {
+	privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privKey),
+	})
+	pubDER, err := x509.MarshalPKIXPublicKey(&privKey.PublicKey)
+	require.NoError(t, err)
+	pubPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pubDER,
+	})
+
+	for _, algo := range []string{"rsa_sha256", "rsa_sha512"} {
+		algo := algo
+		t.Run(algo, func(t *testing.T) {
+			signFn, err := InitMethod("sign", NewLiteralFunction("hello world"), algo, string(privPEM))
+			require.NoError(t, err)
+
+			sig, err := signFn.Exec(FunctionContext{})
+			require.NoError(t, err)
+
+			for input, valid := range map[string]bool{
+				"hello world": true,
+				"hello earth": false,
+			} {
+				verifyFn, err := InitMethod("verify_signature", NewLiteralFunction(input), algo, string(pubPEM), sig)
+				require.NoError(t, err)
+
+				res, err := verifyFn.Exec(FunctionContext{})
+				require.NoError(t, err)
+				assert.Equal(t, valid, res, input)
+			}
+		})
+	}
+
+	_, err = InitMethod("sign", NewLiteralFunction("hello world"), "rsa_sha256", "not a key")
+	require.EqualError(t, err, "failed to decode PEM private key")
+}
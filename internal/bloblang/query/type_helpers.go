@@ -230,6 +230,43 @@ func ISanitize(i interface{}) interface{} {
 	return nil
 }
 
+// iSanitizeStructured recursively sanitizes a structured value, such as one
+// obtained from a third party decoder, converting object keys to strings and
+// numerical values to the types supported by Bloblang.
+func iSanitizeStructured(i interface{}) interface{} {
+	switch t := i.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = iSanitizeStructured(v)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprintf("%v", k)] = iSanitizeStructured(v)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, v := range t {
+			a[i] = iSanitizeStructured(v)
+		}
+		return a
+	case int8:
+		return int64(t)
+	case int16:
+		return int64(t)
+	case uint8:
+		return uint64(t)
+	case uint16:
+		return uint64(t)
+	case nil:
+		return nil
+	}
+	return ISanitize(i)
+}
+
 // IToBytes takes a boxed value of any type and attempts to convert it into a
 // byte slice.
 func IToBytes(i interface{}) []byte {
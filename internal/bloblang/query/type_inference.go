@@ -0,0 +1,314 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypedFunction is an optional interface implemented by query functions that
+// are able to describe, without being executed, the types of values that they
+// might return and the functions that they are composed of.
+type TypedFunction interface {
+	Function
+
+	// ReturnTypes returns the set of value types that the function might
+	// return when executed. An empty slice indicates that the types are
+	// unknown.
+	ReturnTypes() []ValueType
+
+	// TypeCheck returns an error if the function is certain to fail due to the
+	// types of its inputs, regardless of the input data. Errors from children
+	// of the function are not included.
+	TypeCheck() error
+
+	// Children returns the functions that this function is composed of.
+	Children() []Function
+}
+
+// InferTypes attempts to determine the set of value types that a function
+// might return when executed, without executing it. An empty slice is returned
+// when the types cannot be determined.
+func InferTypes(fn Function) []ValueType {
+	switch t := fn.(type) {
+	case TypedFunction:
+		return t.ReturnTypes()
+	case *Literal:
+		return inferValueTypes(t.Value)
+	}
+	return nil
+}
+
+// inferValueTypes returns the types of an argument value, which is either a
+// static value or a function.
+func inferValueTypes(v interface{}) []ValueType {
+	if fn, isFn := v.(Function); isFn {
+		return InferTypes(fn)
+	}
+	t := ITypeOf(v)
+	if t == ValueUnknown {
+		return nil
+	}
+	return []ValueType{t}
+}
+
+// WalkFunctions calls a closure for a function and each of its descendants
+// that can be determined statically, in depth-first order. If the closure
+// returns false then the descendants of that function are not visited.
+func WalkFunctions(fn Function, visit func(fn Function) bool) {
+	if fn == nil || !visit(fn) {
+		return
+	}
+	if typed, isTyped := fn.(TypedFunction); isTyped {
+		for _, child := range typed.Children() {
+			WalkFunctions(child, visit)
+		}
+	}
+}
+
+// CheckTypes walks a function and its descendants and returns any definite
+// type errors that were found.
+func CheckTypes(fn Function) []error {
+	var errs []error
+	WalkFunctions(fn, func(f Function) bool {
+		if typed, isTyped := f.(TypedFunction); isTyped {
+			if err := typed.TypeCheck(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return true
+	})
+	return errs
+}
+
+//------------------------------------------------------------------------------
+
+// UnionTypes returns the union of sets of types, where if any set is unknown
+// (empty) then the result is also unknown.
+func UnionTypes(sets ...[]ValueType) []ValueType {
+	var res []ValueType
+	seen := map[ValueType]struct{}{}
+	for _, set := range sets {
+		if len(set) == 0 {
+			return nil
+		}
+		for _, t := range set {
+			if _, exists := seen[t]; !exists {
+				seen[t] = struct{}{}
+				res = append(res, t)
+			}
+		}
+	}
+	return res
+}
+
+// checkTypesOf returns a TypeError if the set of actual types is known and
+// none of them are within the set of expected types.
+func checkTypesOf(actual []ValueType, expected ...ValueType) error {
+	if len(actual) == 0 || len(expected) == 0 {
+		return nil
+	}
+	for _, a := range actual {
+		if a == ValueUnknown {
+			return nil
+		}
+		for _, e := range expected {
+			if a == e {
+				return nil
+			}
+		}
+	}
+	actualStrs := make([]string, len(actual))
+	for i, a := range actual {
+		actualStrs[i] = string(a)
+	}
+	return &TypeError{
+		Expected: expected,
+		Actual:   ValueType(strings.Join(actualStrs, " or ")),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// typedFunction decorates a function with type information.
+type typedFunction struct {
+	Function
+	returnTypes func() []ValueType
+	check       func() error
+	children    []Function
+}
+
+func (t *typedFunction) ReturnTypes() []ValueType {
+	if t.returnTypes == nil {
+		return nil
+	}
+	return t.returnTypes()
+}
+
+func (t *typedFunction) TypeCheck() error {
+	if t.check == nil {
+		return nil
+	}
+	return t.check()
+}
+
+func (t *typedFunction) Children() []Function {
+	return t.children
+}
+
+func functionsOf(values ...interface{}) []Function {
+	var fns []Function
+	for _, v := range values {
+		if fn, isFn := v.(Function); isFn && fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	return fns
+}
+
+// checkParamTypes returns an error if any argument is of a definite type that
+// does not match the declared types of its parameter.
+func checkParamTypes(params [][]ValueType, args []interface{}) error {
+	for i, arg := range args {
+		if i >= len(params) {
+			break
+		}
+		if err := checkTypesOf(inferValueTypes(arg), params[i]...); err != nil {
+			return fmt.Errorf("argument %v: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// functionWithTypes wraps a function constructor so that the functions it
+// creates expose the type information of their spec.
+func functionWithTypes(spec FunctionSpec, ctor FunctionCtor) FunctionCtor {
+	return func(args ...interface{}) (Function, error) {
+		fn, err := ctor(args...)
+		if err != nil {
+			return nil, err
+		}
+		if _, isLit := fn.(*Literal); isLit {
+			return fn, nil
+		}
+		return typedFunctionFromSpec(fn, spec, args), nil
+	}
+}
+
+// methodWithTypes wraps a method constructor so that the methods it creates
+// expose the type information of their spec.
+func methodWithTypes(spec MethodSpec, ctor MethodCtor) MethodCtor {
+	return func(target Function, args ...interface{}) (Function, error) {
+		fn, err := ctor(target, args...)
+		if err != nil {
+			return nil, err
+		}
+		if _, isLit := fn.(*Literal); isLit {
+			return fn, nil
+		}
+		return typedMethodFromSpec(fn, spec, target, args), nil
+	}
+}
+
+func typedFunctionFromSpec(fn Function, spec FunctionSpec, args []interface{}) Function {
+	return &typedFunction{
+		Function: fn,
+		returnTypes: func() []ValueType {
+			return spec.ReturnTypes
+		},
+		check: func() error {
+			if err := checkParamTypes(spec.ParamTypes, args); err != nil {
+				return fmt.Errorf("function %v: %w", spec.Name, err)
+			}
+			return nil
+		},
+		children: functionsOf(args...),
+	}
+}
+
+func typedMethodFromSpec(fn Function, spec MethodSpec, target Function, args []interface{}) Function {
+	return &typedFunction{
+		Function: fn,
+		returnTypes: func() []ValueType {
+			return spec.ReturnTypes
+		},
+		check: func() error {
+			if err := checkTypesOf(InferTypes(target), spec.InputTypes...); err != nil {
+				return fmt.Errorf("method %v: %w", spec.Name, err)
+			}
+			if err := checkParamTypes(spec.ParamTypes, args); err != nil {
+				return fmt.Errorf("method %v: %w", spec.Name, err)
+			}
+			return nil
+		},
+		children: functionsOf(append([]interface{}{target}, args...)...),
+	}
+}
+
+// typedArithmetic decorates the result of an arithmetic operation with type
+// information. Literal results, where the operation was resolved during
+// parsing, are returned as they are.
+func typedArithmetic(fn Function, lhs, rhs Function, op ArithmeticOperator) Function {
+	if _, isLit := fn.(*Literal); isLit {
+		return fn
+	}
+	var returnTypes func() []ValueType
+	var check func() error
+
+	checkBoth := func(expected ...ValueType) error {
+		if err := checkTypesOf(InferTypes(lhs), expected...); err != nil {
+			return fmt.Errorf("left operand: %w", err)
+		}
+		if err := checkTypesOf(InferTypes(rhs), expected...); err != nil {
+			return fmt.Errorf("right operand: %w", err)
+		}
+		return nil
+	}
+
+	switch op {
+	case ArithmeticAdd:
+		returnTypes = func() []ValueType {
+			lTypes := InferTypes(lhs)
+			if len(lTypes) == 1 {
+				switch lTypes[0] {
+				case ValueNumber, ValueString:
+					return lTypes
+				case ValueBytes:
+					return []ValueType{ValueString}
+				}
+			}
+			return []ValueType{ValueNumber, ValueString}
+		}
+		check = func() error {
+			return checkBoth(ValueNumber, ValueString, ValueBytes)
+		}
+	case ArithmeticSub, ArithmeticMul, ArithmeticDiv, ArithmeticMod:
+		returnTypes = func() []ValueType {
+			return []ValueType{ValueNumber}
+		}
+		check = func() error {
+			return checkBoth(ValueNumber)
+		}
+	case ArithmeticAnd, ArithmeticOr:
+		returnTypes = func() []ValueType {
+			return []ValueType{ValueBool}
+		}
+		check = func() error {
+			return checkBoth(ValueBool)
+		}
+	case ArithmeticEq, ArithmeticNeq, ArithmeticGt, ArithmeticLt, ArithmeticGte, ArithmeticLte:
+		returnTypes = func() []ValueType {
+			return []ValueType{ValueBool}
+		}
+	case ArithmeticPipe:
+		returnTypes = func() []ValueType {
+			return UnionTypes(InferTypes(lhs), InferTypes(rhs))
+		}
+	}
+
+	return &typedFunction{
+		Function:    fn,
+		returnTypes: returnTypes,
+		check:       check,
+		children:    functionsOf(lhs, rhs),
+	}
+}
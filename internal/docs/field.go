@@ -48,6 +48,10 @@ type FieldSpec struct {
 	// supports.
 	Interpolation FieldInterpolation
 
+	// Bloblang is true for fields that contain a Bloblang mapping or query,
+	// which allows tooling such as the config linter to check them.
+	Bloblang bool
+
 	// Examples is a slice of optional example values for a field.
 	Examples []interface{}
 
@@ -75,6 +79,13 @@ func (f FieldSpec) SupportsInterpolation(batchWide bool) FieldSpec {
 	return f
 }
 
+// IsBloblang returns a new FieldSpec that specifies the field contains a
+// Bloblang mapping.
+func (f FieldSpec) IsBloblang() FieldSpec {
+	f.Bloblang = true
+	return f
+}
+
 // HasType returns a new FieldSpec that specifies a specific type.
 func (f FieldSpec) HasType(t FieldType) FieldSpec {
 	f.Type = t
@@ -0,0 +1,27 @@
+// Package interop provides helpers for components to access features that are
+// optionally provided by the manager they are constructed with.
+package interop
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+type branchCoverageProvider interface {
+	GetBloblangBranchCoverage() *query.BranchCoverage
+}
+
+// NewBloblangMapping attempts to parse a Bloblang mapping for a component
+// constructed with a manager. When the manager provides a branch coverage
+// recorder, which is the case during config tests, the executions of the
+// branches of the mapping are recorded with it.
+func NewBloblangMapping(mgr types.Manager, expr string) (*mapping.Executor, error) {
+	if cp, ok := mgr.(branchCoverageProvider); ok {
+		if cov := cp.GetBloblangBranchCoverage(); cov != nil {
+			return bloblang.NewMappingWithCoverage("", expr, cov)
+		}
+	}
+	return bloblang.NewMapping("", expr)
+}
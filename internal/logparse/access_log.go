@@ -0,0 +1,64 @@
+package logparse
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var accessLogRegexp = regexp.MustCompile(
+	`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}|-) (\d+|-)` +
+		`(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`,
+)
+
+var accessLogUnescaper = strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+
+// AccessLog parses a line of an Apache or Nginx access log in either the
+// common or combined log format. Fields that are not present, which these
+// formats represent with a hyphen, are omitted. Any fields following the
+// combined format are ignored. Timestamps of access logs always contain an
+// offset and therefore loc is ignored.
+func AccessLog(b []byte, loc *time.Location) (map[string]interface{}, error) {
+	m := accessLogRegexp.FindStringSubmatch(strings.TrimSpace(string(b)))
+	if m == nil {
+		return nil, errors.New("line does not match the common or combined log format")
+	}
+
+	doc := map[string]interface{}{
+		"remote_host": m[1],
+	}
+	setIfPresent := func(key, value string) {
+		if value != "-" && value != "" {
+			doc[key] = value
+		}
+	}
+
+	setIfPresent("ident", m[2])
+	setIfPresent("user", m[3])
+	if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[4]); err == nil {
+		doc["timestamp"] = t.UTC().Format(time.RFC3339Nano)
+	} else {
+		doc["time_local"] = m[4]
+	}
+
+	request := accessLogUnescaper.Replace(m[5])
+	doc["request"] = request
+	if parts := strings.Split(request, " "); len(parts) == 3 {
+		doc["method"] = parts[0]
+		doc["path"] = parts[1]
+		doc["protocol"] = parts[2]
+	}
+
+	if status, err := strconv.ParseInt(m[6], 10, 64); err == nil {
+		doc["status"] = status
+	}
+	if bytes, err := strconv.ParseInt(m[7], 10, 64); err == nil {
+		doc["bytes"] = bytes
+	}
+
+	setIfPresent("referer", accessLogUnescaper.Replace(m[8]))
+	setIfPresent("user_agent", accessLogUnescaper.Replace(m[9]))
+	return doc, nil
+}
@@ -0,0 +1,255 @@
+package logparse
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// splitHeader splits the pipe delimited header of a CEF or LEEF event into n
+// fields followed by the remainder of the event. Pipes and backslashes within
+// header fields may be escaped with a backslash.
+func splitHeader(s string, n int) ([]string, string, bool) {
+	fields := make([]string, 0, n)
+	var field strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\') {
+				i++
+				field.WriteByte(s[i])
+			} else {
+				field.WriteByte(c)
+			}
+		case '|':
+			fields = append(fields, field.String())
+			field.Reset()
+			if len(fields) == n {
+				return fields, s[i+1:], true
+			}
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return fields, field.String(), false
+}
+
+// syslogPrefix returns the prefix of an event before the given marker, which
+// is usually a syslog header, and the event starting at the marker.
+func syslogPrefix(b []byte, marker string) (string, string, bool) {
+	i := bytes.Index(b, []byte(marker))
+	if i < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(string(b[:i])), strings.TrimRight(string(b[i:]), "\r\n"), true
+}
+
+//------------------------------------------------------------------------------
+
+// CEF parses an ArcSight Common Event Format event, which may be prefixed with
+// a syslog header. The resulting document contains the header fields and an
+// object of extensions, and the field timestamp is set when either the rt, end
+// or start extension or the syslog header contains a recognisable timestamp.
+func CEF(b []byte, loc *time.Location) (map[string]interface{}, error) {
+	prefix, event, ok := syslogPrefix(b, "CEF:")
+	if !ok {
+		return nil, errors.New("missing CEF header")
+	}
+
+	header, extStr, ok := splitHeader(event[len("CEF:"):], 7)
+	if !ok {
+		return nil, errors.New("expected seven pipe delimited header fields")
+	}
+
+	version, err := strconv.ParseInt(strings.TrimSpace(header[0]), 10, 64)
+	if err != nil {
+		return nil, errors.New("failed to parse CEF version")
+	}
+
+	ext := parseCEFExtensions(extStr)
+	doc := map[string]interface{}{
+		"version":        version,
+		"device_vendor":  header[1],
+		"device_product": header[2],
+		"device_version": header[3],
+		"signature_id":   header[4],
+		"name":           header[5],
+		"severity":       header[6],
+		"extensions":     ext,
+	}
+	if sev, err := strconv.ParseInt(header[6], 10, 64); err == nil {
+		doc["severity"] = sev
+	}
+
+	var candidates []string
+	for _, k := range []string{"rt", "end", "start"} {
+		if v, exists := ext[k]; exists {
+			candidates = append(candidates, v.(string))
+		}
+	}
+	setTimestamp(doc, loc, candidates...)
+	if _, exists := doc["timestamp"]; !exists && prefix != "" {
+		if t, ok := FindTimestamp(prefix, loc); ok {
+			doc["timestamp"] = t.Format(time.RFC3339Nano)
+		}
+	}
+	return doc, nil
+}
+
+func isCEFKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '[' || c == ']' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// parseCEFExtensions parses space delimited key=value pairs, where values may
+// contain spaces and extend until the next key. Equals signs, backslashes and
+// line breaks within values are escaped with a backslash.
+func parseCEFExtensions(s string) map[string]interface{} {
+	ext := map[string]interface{}{}
+
+	// Find the start and end of each key, which is a sequence of key
+	// characters preceded by whitespace and followed by an unescaped equals.
+	type keyPos struct {
+		start, eq int
+	}
+	var keys []keyPos
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] != '=' {
+			continue
+		}
+		start := i
+		for start > 0 && isCEFKeyChar(s[start-1]) {
+			start--
+		}
+		if start == i || (start > 0 && s[start-1] != ' ') {
+			continue
+		}
+		keys = append(keys, keyPos{start: start, eq: i})
+	}
+
+	for i, k := range keys {
+		end := len(s)
+		if i+1 < len(keys) {
+			end = keys[i+1].start
+		}
+		ext[s[k.start:k.eq]] = unescapeCEFValue(strings.TrimRight(s[k.eq+1:end], " "))
+	}
+	return ext
+}
+
+func unescapeCEFValue(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+//------------------------------------------------------------------------------
+
+// LEEF parses an IBM QRadar Log Event Extended Format event of version 1.0 or
+// 2.0, which may be prefixed with a syslog header. The resulting document
+// contains the header fields and an object of attributes, and the field
+// timestamp is set when either the devTime attribute or the syslog header
+// contains a recognisable timestamp.
+func LEEF(b []byte, loc *time.Location) (map[string]interface{}, error) {
+	prefix, event, ok := syslogPrefix(b, "LEEF:")
+	if !ok {
+		return nil, errors.New("missing LEEF header")
+	}
+	event = event[len("LEEF:"):]
+
+	header, attrStr, ok := splitHeader(event, 5)
+	if !ok {
+		return nil, errors.New("expected five pipe delimited header fields")
+	}
+
+	version := strings.TrimSpace(header[0])
+	delim := "\t"
+	switch version {
+	case "1.0":
+	case "2.0":
+		// The delimiter field is optional, in which case the remainder of the
+		// event are the attributes.
+		if i := strings.IndexByte(attrStr, '|'); i >= 0 && !strings.Contains(attrStr[:i], "=") {
+			var err error
+			if delim, err = parseLEEFDelimiter(attrStr[:i]); err != nil {
+				return nil, err
+			}
+			attrStr = attrStr[i+1:]
+		}
+	default:
+		return nil, errors.New("unsupported LEEF version: " + version)
+	}
+
+	attrs := map[string]interface{}{}
+	for _, pair := range strings.Split(attrStr, delim) {
+		if pair == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			continue
+		}
+		attrs[pair[:i]] = pair[i+1:]
+	}
+
+	doc := map[string]interface{}{
+		"version":         version,
+		"vendor":          header[1],
+		"product":         header[2],
+		"product_version": header[3],
+		"event_id":        header[4],
+		"attributes":      attrs,
+	}
+
+	if devTime, exists := attrs["devTime"]; exists {
+		setTimestamp(doc, loc, devTime.(string))
+	}
+	if _, exists := doc["timestamp"]; !exists && prefix != "" {
+		if t, ok := FindTimestamp(prefix, loc); ok {
+			doc["timestamp"] = t.Format(time.RFC3339Nano)
+		}
+	}
+	return doc, nil
+}
+
+// parseLEEFDelimiter parses the attribute delimiter of a LEEF 2.0 header, which
+// is either a single character or a hex code point such as x09 or 0x09.
+func parseLEEFDelimiter(s string) (string, error) {
+	switch {
+	case s == "":
+		return "\t", nil
+	case len(s) == 1:
+		return s, nil
+	case s == "\\t":
+		return "\t", nil
+	}
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "0"), "x")
+	c, err := strconv.ParseUint(hex, 16, 8)
+	if err != nil || c == 0 {
+		return "", errors.New("failed to parse LEEF delimiter: " + s)
+	}
+	return string(rune(c)), nil
+}
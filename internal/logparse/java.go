@@ -0,0 +1,159 @@
+package logparse
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	javaExceptionRegexp  = regexp.MustCompile(`^(?:Exception in thread "([^"]*)" )?((?:[A-Za-z_$][\w$]*\.)+[A-Za-z_$][\w$]*)(?:: ?(.*))?$`)
+	javaCausedByRegexp   = regexp.MustCompile(`^\s*Caused by: (.*)$`)
+	javaFrameRegexp      = regexp.MustCompile(`^\s+at (?:[^\s/]+/)?([\w$.<>]+)\.([\w$<>\-]+)\((.*)\)$`)
+	javaOmittedRegexp    = regexp.MustCompile(`^\s+\.\.\. (\d+) more$`)
+	javaSuppressedRegexp = regexp.MustCompile(`^\s+Suppressed: `)
+)
+
+func isJavaExceptionClass(class string) bool {
+	for _, suffix := range []string{"Exception", "Error", "Throwable"} {
+		if strings.HasSuffix(class, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// JavaStackTrace parses a multiline Java stack trace, optionally preceded by
+// log lines, into a document containing the exception class, message, stack
+// frames and chain of causes. Lines preceding the exception are set as the
+// field message, and the field timestamp is set when they contain a
+// recognisable timestamp.
+func JavaStackTrace(b []byte, loc *time.Location) (map[string]interface{}, error) {
+	lines := strings.Split(strings.TrimRight(strings.Replace(string(b), "\r\n", "\n", -1), "\n"), "\n")
+
+	// The exception header is the first line that looks like a class name
+	// and is either named like an exception or followed by a stack frame.
+	headerIndex := -1
+	for i := 0; i < len(lines) && headerIndex < 0; i++ {
+		m := javaExceptionRegexp.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		if m[1] != "" || isJavaExceptionClass(m[2]) {
+			headerIndex = i
+			break
+		}
+		for _, next := range lines[i+1:] {
+			if javaFrameRegexp.MatchString(next) {
+				headerIndex = i
+				break
+			}
+			if javaExceptionRegexp.MatchString(next) {
+				break
+			}
+		}
+	}
+	if headerIndex < 0 {
+		return nil, errors.New("no exception found")
+	}
+
+	doc := map[string]interface{}{}
+	if headerIndex > 0 {
+		message := strings.TrimSpace(strings.Join(lines[:headerIndex], "\n"))
+		doc["message"] = message
+		if t, ok := FindTimestamp(message, loc); ok {
+			doc["timestamp"] = t.Format(time.RFC3339Nano)
+		}
+	}
+
+	m := javaExceptionRegexp.FindStringSubmatch(lines[headerIndex])
+	if m[1] != "" {
+		doc["thread"] = m[1]
+	}
+
+	root := newJavaException(m[2], m[3])
+	current := root
+	inSuppressed := false
+
+	for _, line := range lines[headerIndex+1:] {
+		if fm := javaFrameRegexp.FindStringSubmatch(line); fm != nil {
+			if !inSuppressed {
+				current.addFrame(fm[1], fm[2], fm[3])
+			}
+			continue
+		}
+		if om := javaOmittedRegexp.FindStringSubmatch(line); om != nil {
+			if !inSuppressed {
+				current.doc["omitted_frames"], _ = strconv.ParseInt(om[1], 10, 64)
+			}
+			continue
+		}
+		if cm := javaCausedByRegexp.FindStringSubmatch(line); cm != nil && !strings.HasPrefix(line, "\t\t") {
+			if em := javaExceptionRegexp.FindStringSubmatch(cm[1]); em != nil {
+				cause := newJavaException(em[2], em[3])
+				current.doc["caused_by"] = cause.doc
+				current = cause
+				inSuppressed = false
+				continue
+			}
+		}
+		if javaSuppressedRegexp.MatchString(line) {
+			// Suppressed exceptions are nested within the trace and are
+			// skipped along with their frames.
+			inSuppressed = true
+			continue
+		}
+		if current.frames == 0 && !inSuppressed {
+			// Lines between the exception header and the first frame continue
+			// a multiline exception message.
+			msg, _ := current.doc["message"].(string)
+			current.doc["message"] = strings.TrimLeft(msg+"\n"+line, "\n")
+		}
+	}
+
+	doc["exception"] = root.doc
+	return doc, nil
+}
+
+type javaException struct {
+	doc    map[string]interface{}
+	frames int
+}
+
+func newJavaException(class, message string) *javaException {
+	e := &javaException{
+		doc: map[string]interface{}{
+			"class":  class,
+			"frames": []interface{}{},
+		},
+	}
+	if message != "" {
+		e.doc["message"] = message
+	}
+	return e
+}
+
+func (e *javaException) addFrame(class, method, location string) {
+	frame := map[string]interface{}{
+		"class":  class,
+		"method": method,
+	}
+	switch {
+	case location == "Native Method":
+		frame["native"] = true
+	case location == "Unknown Source":
+	default:
+		file := location
+		if i := strings.LastIndexByte(location, ':'); i >= 0 {
+			if line, err := strconv.ParseInt(location[i+1:], 10, 64); err == nil {
+				file = location[:i]
+				frame["line"] = line
+			}
+		}
+		frame["file"] = file
+	}
+	e.doc["frames"] = append(e.doc["frames"].([]interface{}), frame)
+	e.frames++
+}
@@ -0,0 +1,182 @@
+package logparse
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var logfmtTimestampKeys = []string{"ts", "time", "timestamp", "@timestamp"}
+
+// Logfmt parses a line of space delimited key=value pairs, where values
+// containing spaces are quoted with double quotes. Keys without a value are
+// set to true. The resulting document contains an object of the pairs as the
+// field fields, and the field timestamp is set when either of the keys ts,
+// time, timestamp or @timestamp contains a recognisable timestamp.
+func Logfmt(b []byte, loc *time.Location) (map[string]interface{}, error) {
+	s := strings.TrimSpace(string(b))
+	fields := map[string]interface{}{}
+
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] != '=' && s[i] != ' ' && s[i] != '\t' {
+			if s[i] == '"' {
+				return nil, errors.New("unexpected quote within key")
+			}
+			i++
+		}
+		key := s[start:i]
+		if i >= len(s) || s[i] != '=' {
+			fields[key] = true
+			continue
+		}
+		if key == "" {
+			return nil, errors.New("unexpected equals sign without a key")
+		}
+		i++
+
+		if i < len(s) && s[i] == '"' {
+			var value strings.Builder
+			closed := false
+			for i++; i < len(s); i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					case 'r':
+						value.WriteByte('\r')
+					default:
+						value.WriteByte(s[i])
+					}
+					continue
+				}
+				if s[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				value.WriteByte(s[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated quoted value for key: " + key)
+			}
+			fields[key] = value.String()
+			continue
+		}
+
+		start = i
+		for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+			i++
+		}
+		fields[key] = s[start:i]
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New("no key value pairs found")
+	}
+
+	var candidates []string
+	for _, k := range logfmtTimestampKeys {
+		if v, ok := fields[k].(string); ok {
+			candidates = append(candidates, v)
+		}
+	}
+	doc := map[string]interface{}{
+		"fields": fields,
+	}
+	setTimestamp(doc, loc, candidates...)
+	return doc, nil
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	windowsKVLineRegexp      = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.\-]*)=(.*)$`)
+	windowsKVInlineKeyRegexp = regexp.MustCompile(`(?:^|\s)([A-Za-z_][A-Za-z0-9_.\-]*)=`)
+	windowsKVTimestampKeys   = []string{"TimeCreated", "TimeGenerated", "EventTime", "Time"}
+)
+
+// WindowsKV parses key=value pairs in the style of exported Windows event
+// logs, where each pair is on its own line and lines that do not begin with a
+// key continue the value of the previous pair, which allows values such as
+// Message to span multiple lines. Lines preceding the first pair are treated
+// as a header. When the input is a single line the pairs are delimited by
+// whitespace and values extend until the next key.
+//
+// The resulting document contains an object of the pairs as the field fields,
+// and the field timestamp is set when either the header or one of the keys
+// TimeCreated, TimeGenerated, EventTime or Time contains a recognisable
+// timestamp.
+func WindowsKV(b []byte, loc *time.Location) (map[string]interface{}, error) {
+	s := strings.TrimSpace(strings.Replace(string(b), "\r\n", "\n", -1))
+
+	fields := map[string]interface{}{}
+	var header []string
+
+	if !strings.Contains(s, "\n") {
+		matches := windowsKVInlineKeyRegexp.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) > 0 {
+			header = append(header, s[:matches[0][0]])
+		}
+		for i, m := range matches {
+			end := len(s)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			fields[s[m[2]:m[3]]] = strings.TrimSpace(s[m[1]:end])
+		}
+	} else {
+		var key string
+		var value []string
+		flush := func() {
+			if key != "" {
+				fields[key] = strings.TrimRight(strings.Join(value, "\n"), "\n")
+			}
+		}
+		for _, line := range strings.Split(s, "\n") {
+			if m := windowsKVLineRegexp.FindStringSubmatch(line); m != nil {
+				flush()
+				key, value = m[1], []string{strings.TrimRight(m[2], " \t")}
+				continue
+			}
+			if key == "" {
+				header = append(header, line)
+				continue
+			}
+			value = append(value, strings.TrimRight(line, " \t"))
+		}
+		flush()
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New("no key value pairs found")
+	}
+
+	doc := map[string]interface{}{
+		"fields": fields,
+	}
+	if h := strings.TrimSpace(strings.Join(header, " ")); h != "" {
+		if t, ok := FindTimestamp(h, loc); ok {
+			doc["timestamp"] = t.Format(time.RFC3339Nano)
+			return doc, nil
+		}
+	}
+
+	var candidates []string
+	for _, k := range windowsKVTimestampKeys {
+		if v, ok := fields[k].(string); ok {
+			candidates = append(candidates, v)
+		}
+	}
+	setTimestamp(doc, loc, candidates...)
+	return doc, nil
+}
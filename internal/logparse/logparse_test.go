@@ -0,0 +1,259 @@
+package logparse
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := map[string]string{
+		"2021-04-01T12:30:45Z":            "2021-04-01T12:30:45Z",
+		"2021-04-01T12:30:45.123+02:00":   "2021-04-01T10:30:45.123Z",
+		"2021-04-01 12:30:45,123":         "2021-04-01T12:30:45.123Z",
+		"2021-04-01 12:30:45 +0100":       "2021-04-01T11:30:45Z",
+		"01/Apr/2021:12:30:45 -0700":      "2021-04-01T19:30:45Z",
+		"Apr 1 2021 12:30:45":             "2021-04-01T12:30:45Z",
+		"Apr 01 2021 12:30:45 UTC":        "2021-04-01T12:30:45Z",
+		"04/01/2021 1:30:45 PM":           "2021-04-01T13:30:45Z",
+		"2021/04/01 12:30:45":             "2021-04-01T12:30:45Z",
+		"1617280245":                      "2021-04-01T12:30:45Z",
+		"1617280245.5":                    "2021-04-01T12:30:45.5Z",
+		"1617280245123":                   "2021-04-01T12:30:45.123Z",
+		"1617280245123456":                "2021-04-01T12:30:45.123456Z",
+		"1617280245123456789":             "2021-04-01T12:30:45.123456789Z",
+		"Thu, 01 Apr 2021 12:30:45 +0000": "2021-04-01T12:30:45Z",
+	}
+	for input, exp := range tests {
+		ts, ok := ParseTimestamp(input, nil)
+		require.True(t, ok, input)
+		assert.Equal(t, exp, ts.Format(time.RFC3339Nano), input)
+	}
+
+	for _, input := range []string{"", "nope", "12", "2021-13-45"} {
+		_, ok := ParseTimestamp(input, nil)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestFindTimestamp(t *testing.T) {
+	ts, ok := FindTimestamp("<134>host 2021-04-01 12:30:45.5 ERROR foo", nil)
+	require.True(t, ok)
+	assert.Equal(t, "2021-04-01T12:30:45.5Z", ts.Format(time.RFC3339Nano))
+
+	_, ok = FindTimestamp("nothing to see here", nil)
+	assert.False(t, ok)
+}
+
+func TestParseTimestampLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	ts, ok := ParseTimestamp("2021-04-01 12:30:45", loc)
+	require.True(t, ok)
+	assert.Equal(t, "2021-04-01T16:30:45Z", ts.Format(time.RFC3339Nano))
+
+	ts, ok = ParseTimestamp("2021-04-01T12:30:45Z", loc)
+	require.True(t, ok)
+	assert.Equal(t, "2021-04-01T12:30:45Z", ts.Format(time.RFC3339Nano))
+
+	doc, err := Logfmt([]byte(`ts="2021-04-01 12:30:45"`), loc)
+	require.NoError(t, err)
+	assert.Equal(t, "2021-04-01T16:30:45Z", doc["timestamp"])
+}
+
+func testParser(t *testing.T, parser Parser, tests map[string]string) {
+	t.Helper()
+	for input, exp := range tests {
+		doc, err := parser([]byte(input), nil)
+		require.NoError(t, err, input)
+		act, err := json.Marshal(doc)
+		require.NoError(t, err)
+		assert.JSONEq(t, exp, string(act), input)
+	}
+}
+
+func TestCEF(t *testing.T) {
+	testParser(t, CEF, map[string]string{
+		`CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232`: `{
+			"version":0,"device_vendor":"Security","device_product":"threatmanager","device_version":"1.0",
+			"signature_id":"100","name":"worm successfully stopped","severity":10,
+			"extensions":{"src":"10.0.0.1","dst":"2.1.2.2","spt":"1232"}
+		}`,
+		`Sep 19 08:26:10 host CEF:0|Foo \| Bar|prod|2|sig|detected a \\ in message|Very-High|msg=spaces in value and \= sign rt=Sep 19 2021 08:26:10 cs1Label=url cs1=http://x?a\=b`: `{
+			"version":0,"device_vendor":"Foo | Bar","device_product":"prod","device_version":"2",
+			"signature_id":"sig","name":"detected a \\ in message","severity":"Very-High",
+			"extensions":{"msg":"spaces in value and = sign","rt":"Sep 19 2021 08:26:10","cs1Label":"url","cs1":"http://x?a=b"},
+			"timestamp":"2021-09-19T08:26:10Z"
+		}`,
+		`<134>2021-04-01T12:30:45Z host CEF:1|a|b|c|d|e|3|`: `{
+			"version":1,"device_vendor":"a","device_product":"b","device_version":"c",
+			"signature_id":"d","name":"e","severity":3,"extensions":{},
+			"timestamp":"2021-04-01T12:30:45Z"
+		}`,
+		`CEF:0|a|b|c|d|e|3|rt=1617280245123 msg=line one\nline two`: `{
+			"version":0,"device_vendor":"a","device_product":"b","device_version":"c",
+			"signature_id":"d","name":"e","severity":3,
+			"extensions":{"rt":"1617280245123","msg":"line one\nline two"},
+			"timestamp":"2021-04-01T12:30:45.123Z"
+		}`,
+	})
+
+	for _, input := range []string{
+		`not cef`,
+		`CEF:0|a|b|c`,
+		`CEF:x|a|b|c|d|e|3|`,
+	} {
+		_, err := CEF([]byte(input), nil)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestLEEF(t *testing.T) {
+	testParser(t, LEEF, map[string]string{
+		"LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tdevTime=Apr 01 2021 12:30:45": `{
+			"version":"1.0","vendor":"Microsoft","product":"MSExchange","product_version":"4.0 SP1","event_id":"15345",
+			"attributes":{"src":"192.0.2.0","dst":"172.50.123.1","devTime":"Apr 01 2021 12:30:45"},
+			"timestamp":"2021-04-01T12:30:45Z"
+		}`,
+		"LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5": `{
+			"version":"2.0","vendor":"Lancope","product":"StealthWatch","product_version":"1.0","event_id":"41",
+			"attributes":{"src":"10.0.1.8","dst":"10.0.0.5","sev":"5"}
+		}`,
+		"LEEF:2.0|Lancope|StealthWatch|1.0|41|0x7c|src=10.0.1.8|dst=10.0.0.5": `{
+			"version":"2.0","vendor":"Lancope","product":"StealthWatch","product_version":"1.0","event_id":"41",
+			"attributes":{"src":"10.0.1.8","dst":"10.0.0.5"}
+		}`,
+		"<13>2021-04-01T12:30:45Z host LEEF:2.0|a|b|c|d|src=1.2.3.4\tdevTime=nope": `{
+			"version":"2.0","vendor":"a","product":"b","product_version":"c","event_id":"d",
+			"attributes":{"src":"1.2.3.4","devTime":"nope"},
+			"timestamp":"2021-04-01T12:30:45Z"
+		}`,
+	})
+
+	for _, input := range []string{
+		`not leef`,
+		`LEEF:1.0|a|b`,
+		`LEEF:3.0|a|b|c|d|foo=bar`,
+		`LEEF:2.0|a|b|c|d|xzz|foo=bar`,
+	} {
+		_, err := LEEF([]byte(input), nil)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestLogfmt(t *testing.T) {
+	testParser(t, Logfmt, map[string]string{
+		`level=info msg="hello \"world\"" ts=2021-04-01T12:30:45.123+01:00 count=10 debug`: `{
+			"fields":{"level":"info","msg":"hello \"world\"","ts":"2021-04-01T12:30:45.123+01:00","count":"10","debug":true},
+			"timestamp":"2021-04-01T11:30:45.123Z"
+		}`,
+		`timestamp=1617280245 service=api`: `{
+			"fields":{"timestamp":"1617280245","service":"api"},
+			"timestamp":"2021-04-01T12:30:45Z"
+		}`,
+		`a= b=""  c=foo=bar`: `{"fields":{"a":"","b":"","c":"foo=bar"}}`,
+		`time=nope`:          `{"fields":{"time":"nope"}}`,
+	})
+
+	for _, input := range []string{
+		``,
+		`a="unterminated`,
+		`=foo`,
+		`a"b=c`,
+	} {
+		_, err := Logfmt([]byte(input), nil)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestWindowsKV(t *testing.T) {
+	testParser(t, WindowsKV, map[string]string{
+		"04/01/2021 12:30:45 PM\r\nLogName=Security\r\nSourceName=Microsoft Windows security auditing.\r\nEventCode=4624\r\nMessage=An account was successfully logged on.\r\n\r\nSubject:\r\n\tAccount Name:\t\t-\r\n": `{
+			"fields":{
+				"LogName":"Security","SourceName":"Microsoft Windows security auditing.","EventCode":"4624",
+				"Message":"An account was successfully logged on.\n\nSubject:\n\tAccount Name:\t\t-"
+			},
+			"timestamp":"2021-04-01T12:30:45Z"
+		}`,
+		"EventCode=4625 TimeGenerated=2021-04-01 12:30:45 Message=An account failed to log on. Account=bob": `{
+			"fields":{"EventCode":"4625","TimeGenerated":"2021-04-01 12:30:45","Message":"An account failed to log on.","Account":"bob"},
+			"timestamp":"2021-04-01T12:30:45Z"
+		}`,
+	})
+
+	_, err := WindowsKV([]byte("no pairs here"), nil)
+	assert.Error(t, err)
+}
+
+func TestAccessLog(t *testing.T) {
+	testParser(t, AccessLog, map[string]string{
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`: `{
+			"remote_host":"127.0.0.1","user":"frank","timestamp":"2000-10-10T20:55:36Z",
+			"request":"GET /apache_pb.gif HTTP/1.0","method":"GET","path":"/apache_pb.gif","protocol":"HTTP/1.0",
+			"status":200,"bytes":2326
+		}`,
+		`10.0.0.2 - - [01/Apr/2021:12:30:45 +0000] "POST /api?q=\"x\" HTTP/1.1" 404 - "https://example.com/" "Mozilla/5.0 (X11)" "1.2.3.4"`: `{
+			"remote_host":"10.0.0.2","timestamp":"2021-04-01T12:30:45Z",
+			"request":"POST /api?q=\"x\" HTTP/1.1","method":"POST","path":"/api?q=\"x\"","protocol":"HTTP/1.1",
+			"status":404,"referer":"https://example.com/","user_agent":"Mozilla/5.0 (X11)"
+		}`,
+		`::1 - - [bad time] "-" 400 0 "-" "-"`: `{
+			"remote_host":"::1","time_local":"bad time","request":"-","status":400,"bytes":0
+		}`,
+	})
+
+	_, err := AccessLog([]byte("not an access log"), nil)
+	assert.Error(t, err)
+}
+
+func TestJavaStackTrace(t *testing.T) {
+	testParser(t, JavaStackTrace, map[string]string{
+		`2021-04-01 12:30:45,123 ERROR [main] com.example.App - Request failed
+java.lang.IllegalStateException: Failed to process
+	at com.example.App.process(App.java:42)
+	at java.base/java.lang.Thread.run(Thread.java:829)
+	at sun.reflect.NativeMethodAccessorImpl.invoke0(Native Method)
+Caused by: java.io.IOException: Disk full
+	at com.example.Store.write(Store.java:10)
+	... 3 more
+	Suppressed: java.lang.RuntimeException: ignored
+		at com.example.Store.close(Store.java:20)
+Caused by: com.example.StoreError
+	at com.example.Store.<init>(Unknown Source)
+`: `{
+			"message":"2021-04-01 12:30:45,123 ERROR [main] com.example.App - Request failed",
+			"timestamp":"2021-04-01T12:30:45.123Z",
+			"exception":{
+				"class":"java.lang.IllegalStateException","message":"Failed to process",
+				"frames":[
+					{"class":"com.example.App","method":"process","file":"App.java","line":42},
+					{"class":"java.lang.Thread","method":"run","file":"Thread.java","line":829},
+					{"class":"sun.reflect.NativeMethodAccessorImpl","method":"invoke0","native":true}
+				],
+				"caused_by":{
+					"class":"java.io.IOException","message":"Disk full",
+					"frames":[{"class":"com.example.Store","method":"write","file":"Store.java","line":10}],
+					"omitted_frames":3,
+					"caused_by":{
+						"class":"com.example.StoreError",
+						"frames":[{"class":"com.example.Store","method":"<init>"}]
+					}
+				}
+			}
+		}`,
+		"Exception in thread \"worker-1\" com.example.Oops: first line\nsecond line\n\tat com.example.Worker.run(Worker.kt:5)": `{
+			"thread":"worker-1",
+			"exception":{
+				"class":"com.example.Oops","message":"first line\nsecond line",
+				"frames":[{"class":"com.example.Worker","method":"run","file":"Worker.kt","line":5}]
+			}
+		}`,
+	})
+
+	_, err := JavaStackTrace([]byte("just a regular log line"), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+// Package logparse contains parsers for common log formats that produce
+// generic structures that can be serialized to JSON. The parsers are shared by
+// the parse_log processor and the matching Bloblang methods.
+package logparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser parses a log line (or lines) into a structured document. Timestamps
+// without a timezone are parsed in the location loc, or UTC when loc is nil.
+type Parser func(b []byte, loc *time.Location) (map[string]interface{}, error)
+
+//------------------------------------------------------------------------------
+
+var timestampLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"02/Jan/2006:15:04:05 -0700",
+	"Jan 2 2006 15:04:05 MST",
+	"Jan 2 2006 15:04:05",
+	"01/02/2006 3:04:05 PM",
+	"01/02/2006 15:04:05",
+	"2006/01/02 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+}
+
+var (
+	isoDateTimeRegexp = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[T ](\d{2}:\d{2}:\d{2})(?:[.,](\d+))?\s?(Z|[+-]\d{2}:?\d{2})?$`)
+	epochRegexp       = regexp.MustCompile(`^\d{9,19}(?:\.\d+)?$`)
+	spacesRegexp      = regexp.MustCompile(`\s+`)
+)
+
+// ParseTimestamp attempts to parse a string as a timestamp by trying a range of
+// common formats, including unix epochs in seconds, milliseconds, microseconds
+// or nanoseconds. Timestamps without a timezone are parsed in the location
+// loc, or UTC when loc is nil. The result is always in UTC.
+func ParseTimestamp(s string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	if epochRegexp.MatchString(s) {
+		return parseEpoch(s)
+	}
+
+	// Normalise variants of ISO 8601 such as "2006-01-02 15:04:05,000".
+	if m := isoDateTimeRegexp.FindStringSubmatch(s); m != nil {
+		s = m[1] + "T" + m[2]
+		if m[3] != "" {
+			s += "." + m[3]
+		}
+		s += m[4]
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	s = spacesRegexp.ReplaceAllString(s, " ")
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseEpoch(s string) (time.Time, bool) {
+	intStr, fracStr := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intStr, fracStr = s[:i], s[i+1:]
+	}
+	i, err := strconv.ParseInt(intStr, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var t time.Time
+	switch digits := len(intStr); {
+	case digits <= 10:
+		t = time.Unix(i, 0)
+		if fracStr != "" {
+			if len(fracStr) > 9 {
+				fracStr = fracStr[:9]
+			}
+			nanos, _ := strconv.ParseInt(fracStr+strings.Repeat("0", 9-len(fracStr)), 10, 64)
+			t = t.Add(time.Duration(nanos))
+		}
+	case digits <= 13:
+		t = time.Unix(0, i*int64(time.Millisecond))
+	case digits <= 16:
+		t = time.Unix(0, i*int64(time.Microsecond))
+	default:
+		t = time.Unix(0, i)
+	}
+	return t.UTC(), true
+}
+
+var timestampSearchRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|\s?[+-]\d{2}:?\d{2}\b)?`),
+	regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`),
+	regexp.MustCompile(`[A-Z][a-z]{2} +\d{1,2} \d{4} \d{2}:\d{2}:\d{2}(?:\.\d+)?`),
+	regexp.MustCompile(`\d{2}/\d{2}/\d{4} \d{1,2}:\d{2}:\d{2}(?: [AP]M)?`),
+	regexp.MustCompile(`\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`),
+}
+
+// FindTimestamp attempts to find and parse a timestamp within a larger string,
+// such as the header of a log line.
+func FindTimestamp(s string, loc *time.Location) (time.Time, bool) {
+	for _, re := range timestampSearchRegexps {
+		if m := re.FindString(s); m != "" {
+			if t, ok := ParseTimestamp(m, loc); ok {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// setTimestamp sets the field timestamp of a document to the first of a list
+// of candidate values that can be parsed as a timestamp.
+func setTimestamp(doc map[string]interface{}, loc *time.Location, candidates ...string) {
+	for _, c := range candidates {
+		if t, ok := ParseTimestamp(c, loc); ok {
+			doc["timestamp"] = t.Format(time.RFC3339Nano)
+			return
+		}
+	}
+}
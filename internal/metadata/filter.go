@@ -0,0 +1,116 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// FilterConfig describes which metadata keys of a message should be included
+// when metadata is written to an output.
+type FilterConfig struct {
+	IncludePrefixes []string `json:"include_prefixes" yaml:"include_prefixes"`
+	IncludePatterns []string `json:"include_patterns" yaml:"include_patterns"`
+	ExcludePrefixes []string `json:"exclude_prefixes" yaml:"exclude_prefixes"`
+	ExcludePatterns []string `json:"exclude_patterns" yaml:"exclude_patterns"`
+}
+
+// NewFilterConfig returns a FilterConfig with default values, which includes
+// all metadata keys.
+func NewFilterConfig() FilterConfig {
+	return FilterConfig{
+		IncludePrefixes: []string{},
+		IncludePatterns: []string{},
+		ExcludePrefixes: []string{},
+		ExcludePatterns: []string{},
+	}
+}
+
+// FilterFieldSpec returns a spec for a metadata filter field.
+func FilterFieldSpec(name, description string) docs.FieldSpec {
+	return docs.FieldAdvanced(name, description).WithChildren(
+		docs.FieldCommon("include_prefixes", "Provide a list of explicit metadata key prefixes to be included. When neither this field nor `include_patterns` are set all metadata keys are included.", []string{"kafka_", "app_"}).HasType(docs.FieldArray),
+		docs.FieldCommon("include_patterns", "Provide a list of regular expressions, metadata keys that match any of the expressions are included. When neither this field nor `include_prefixes` are set all metadata keys are included.", []string{".*"}, []string{"_timestamp_unix$"}).HasType(docs.FieldArray),
+		docs.FieldCommon("exclude_prefixes", "Provide a list of explicit metadata key prefixes to be excluded, this takes precedence over the include fields.", []string{"kafka_"}).HasType(docs.FieldArray),
+		docs.FieldCommon("exclude_patterns", "Provide a list of regular expressions, metadata keys that match any of the expressions are excluded, this takes precedence over the include fields.", []string{"^_"}).HasType(docs.FieldArray),
+	)
+}
+
+//------------------------------------------------------------------------------
+
+// Filter determines whether metadata keys should be included.
+type Filter struct {
+	includePrefixes []string
+	includePatterns []*regexp.Regexp
+	excludePrefixes []string
+	excludePatterns []*regexp.Regexp
+}
+
+// Filter attempts to construct a metadata filter from the config.
+func (c FilterConfig) Filter() (*Filter, error) {
+	f := &Filter{
+		includePrefixes: c.IncludePrefixes,
+		excludePrefixes: c.ExcludePrefixes,
+	}
+	for _, p := range c.IncludePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile include pattern '%v': %v", p, err)
+		}
+		f.includePatterns = append(f.includePatterns, re)
+	}
+	for _, p := range c.ExcludePatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile exclude pattern '%v': %v", p, err)
+		}
+		f.excludePatterns = append(f.excludePatterns, re)
+	}
+	return f, nil
+}
+
+// Match returns true if a metadata key should be included.
+func (f *Filter) Match(key string) bool {
+	for _, p := range f.excludePrefixes {
+		if strings.HasPrefix(key, p) {
+			return false
+		}
+	}
+	for _, re := range f.excludePatterns {
+		if re.MatchString(key) {
+			return false
+		}
+	}
+	if len(f.includePrefixes) == 0 && len(f.includePatterns) == 0 {
+		return true
+	}
+	for _, p := range f.includePrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	for _, re := range f.includePatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Iter calls a closure for each metadata key value pair of a message part
+// that matches the filter.
+func (f *Filter) Iter(p types.Part, fn func(k, v string) error) error {
+	return p.Metadata().Iter(func(k, v string) error {
+		if !f.Match(k) {
+			return nil
+		}
+		return fn(k, v)
+	})
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := map[string]struct {
+		conf     func(c *FilterConfig)
+		included []string
+		excluded []string
+	}{
+		"default includes all": {
+			conf:     func(c *FilterConfig) {},
+			included: []string{"foo", "kafka_key", ""},
+		},
+		"include prefixes": {
+			conf: func(c *FilterConfig) {
+				c.IncludePrefixes = []string{"foo_", "bar_"}
+			},
+			included: []string{"foo_a", "bar_b"},
+			excluded: []string{"baz_c", "foo"},
+		},
+		"include patterns": {
+			conf: func(c *FilterConfig) {
+				c.IncludePatterns = []string{"_id$"}
+			},
+			included: []string{"user_id", "_id"},
+			excluded: []string{"user_name"},
+		},
+		"exclude prefixes": {
+			conf: func(c *FilterConfig) {
+				c.ExcludePrefixes = []string{"kafka_"}
+			},
+			included: []string{"foo", "kafk"},
+			excluded: []string{"kafka_key", "kafka_lag"},
+		},
+		"exclude takes precedence": {
+			conf: func(c *FilterConfig) {
+				c.IncludePrefixes = []string{"kafka_"}
+				c.ExcludePatterns = []string{"^kafka_timestamp"}
+			},
+			included: []string{"kafka_key"},
+			excluded: []string{"kafka_timestamp_unix", "foo"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewFilterConfig()
+			test.conf(&conf)
+
+			f, err := conf.Filter()
+			require.NoError(t, err)
+
+			for _, k := range test.included {
+				assert.True(t, f.Match(k), k)
+			}
+			for _, k := range test.excluded {
+				assert.False(t, f.Match(k), k)
+			}
+		})
+	}
+}
+
+func TestFilterBadPattern(t *testing.T) {
+	conf := NewFilterConfig()
+	conf.ExcludePatterns = []string{"("}
+	_, err := conf.Filter()
+	require.Error(t, err)
+}
+
+func TestFilterIter(t *testing.T) {
+	conf := NewFilterConfig()
+	conf.ExcludePrefixes = []string{"b"}
+
+	f, err := conf.Filter()
+	require.NoError(t, err)
+
+	var p types.Part = message.NewPart(nil)
+	p.Metadata().Set("a", "1").Set("b", "2").Set("c", "3")
+
+	act := map[string]string{}
+	require.NoError(t, f.Iter(p, func(k, v string) error {
+		act[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"a": "1", "c": "3"}, act)
+}
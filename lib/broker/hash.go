@@ -0,0 +1,73 @@
+package broker
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+// hashRingReplicas is the number of points each output occupies on a hash
+// ring, which evens out the distribution of keys across outputs.
+const hashRingReplicas = 128
+
+// hashRing maps keys onto outputs by consistent hashing, such that adding or
+// removing an output only moves the keys of a proportional share of the ring.
+type hashRing struct {
+	points  []uint64
+	outputs map[uint64]int
+}
+
+func newHashRing(nOutputs int) *hashRing {
+	r := &hashRing{
+		outputs: map[uint64]int{},
+	}
+	for i := 0; i < nOutputs; i++ {
+		for j := 0; j < hashRingReplicas; j++ {
+			point := xxhash.ChecksumString64(strconv.Itoa(i) + "-" + strconv.Itoa(j))
+			if _, exists := r.outputs[point]; exists {
+				continue
+			}
+			r.outputs[point] = i
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i] < r.points[j]
+	})
+	return r
+}
+
+// Get returns the output index that a key belongs to.
+func (r *hashRing) Get(key string) int {
+	h := xxhash.ChecksumString64(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.outputs[r.points[i]]
+}
+
+//------------------------------------------------------------------------------
+
+// NewHash creates a new Routed broker that sends each message to an output
+// chosen by consistent hashing of a key, which results in messages of a key
+// always being sent to the same output.
+func NewHash(
+	outputs []types.Output, key field.Expression, logger log.Modular, stats metrics.Type,
+) (*Routed, error) {
+	ring := newHashRing(len(outputs))
+	return NewRouted(outputs, func(index int, msg types.Message) int {
+		return ring.Get(key.String(index, msg))
+	}, logger, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,235 @@
+package broker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/throttle"
+	"golang.org/x/sync/errgroup"
+)
+
+//------------------------------------------------------------------------------
+
+// RouteFunc returns the index of the output that a message of a batch should be
+// routed to.
+type RouteFunc func(index int, msg types.Message) int
+
+// Routed is a broker that implements types.Consumer and sends each message of a
+// batch to a single output chosen by a routing function. Batches are split
+// into a batch per output, and the original batch is only acknowledged once
+// each output has acknowledged its part.
+type Routed struct {
+	logger log.Modular
+	stats  metrics.Type
+
+	route        RouteFunc
+	maxInFlight  int
+	transactions <-chan types.Transaction
+
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+// NewRouted creates a new Routed type by providing outputs and a function that
+// chooses an output for each message.
+func NewRouted(
+	outputs []types.Output, route RouteFunc, logger log.Modular, stats metrics.Type,
+) (*Routed, error) {
+	ctx, done := context.WithCancel(context.Background())
+	o := &Routed{
+		maxInFlight:  1,
+		stats:        stats,
+		logger:       logger,
+		route:        route,
+		transactions: nil,
+		outputs:      outputs,
+		closedChan:   make(chan struct{}),
+		ctx:          ctx,
+		close:        done,
+	}
+
+	o.outputTsChans = make([]chan types.Transaction, len(o.outputs))
+	for i := range o.outputTsChans {
+		o.outputTsChans[i] = make(chan types.Transaction)
+		if err := o.outputs[i].Consume(o.outputTsChans[i]); err != nil {
+			// Shut down the outputs that are already consuming, as nothing
+			// else holds a reference to their transaction channels.
+			for j := 0; j < i; j++ {
+				close(o.outputTsChans[j])
+			}
+			for _, out := range o.outputs {
+				out.CloseAsync()
+			}
+			done()
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// WithMaxInFlight sets the maximum number of in-flight messages this broker
+// supports. This must be set before calling Consume.
+func (o *Routed) WithMaxInFlight(i int) *Routed {
+	if i < 1 {
+		i = 1
+	}
+	o.maxInFlight = i
+	return o
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the broker to read.
+func (o *Routed) Consume(transactions <-chan types.Transaction) error {
+	if o.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	o.transactions = transactions
+
+	go o.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (o *Routed) Connected() bool {
+	for _, out := range o.outputs {
+		if !out.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// split divides a batch into a batch for each output that it is routed to.
+func (o *Routed) split(msg types.Message) map[int]types.Message {
+	batches := map[int]types.Message{}
+	msg.Iter(func(i int, p types.Part) error {
+		target := o.route(i, msg)
+		if target < 0 || target >= len(o.outputs) {
+			target = 0
+		}
+		b, exists := batches[target]
+		if !exists {
+			b = message.New(nil)
+			batches[target] = b
+		}
+		b.Append(p.Copy())
+		return nil
+	})
+	return batches
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (o *Routed) loop() {
+	var (
+		wg         = sync.WaitGroup{}
+		mMsgsRcvd  = o.stats.GetCounter("messages.received")
+		mOutputErr = o.stats.GetCounter("error")
+		mMsgsSnt   = o.stats.GetCounter("messages.sent")
+	)
+
+	defer func() {
+		wg.Wait()
+		for _, c := range o.outputTsChans {
+			close(c)
+		}
+		close(o.closedChan)
+	}()
+
+	sendLoop := func() {
+		defer wg.Done()
+
+		for {
+			var ts types.Transaction
+			var open bool
+			select {
+			case ts, open = <-o.transactions:
+				if !open {
+					return
+				}
+			case <-o.ctx.Done():
+				return
+			}
+			mMsgsRcvd.Incr(1)
+
+			var owg errgroup.Group
+			for target, msg := range o.split(ts.Payload) {
+				tracker, i := batch.NewTracker(msg), target
+				owg.Go(func() error {
+					throt := throttle.New(throttle.OptCloseChan(o.ctx.Done()))
+					resChan := make(chan types.Response)
+
+					// Try until success or shutdown.
+					for {
+						select {
+						case o.outputTsChans[i] <- types.NewTransaction(tracker.Message(), resChan):
+						case <-o.ctx.Done():
+							return types.ErrTypeClosed
+						}
+						select {
+						case res := <-resChan:
+							if res.Error() != nil {
+								o.logger.Errorf("Failed to dispatch routed message to output '%v': %v\n", i, res.Error())
+								mOutputErr.Incr(1)
+								if !throt.Retry() {
+									return types.ErrTypeClosed
+								}
+								tracker = tracker.Failed(res.Error())
+							} else {
+								mMsgsSnt.Incr(1)
+								return nil
+							}
+						case <-o.ctx.Done():
+							return types.ErrTypeClosed
+						}
+					}
+				})
+			}
+
+			if owg.Wait() == nil {
+				select {
+				case ts.ResponseChan <- response.NewAck():
+				case <-o.ctx.Done():
+					return
+				}
+			}
+		}
+	}
+
+	// Max in flight
+	for i := 0; i < o.maxInFlight; i++ {
+		wg.Add(1)
+		go sendLoop()
+	}
+}
+
+// CloseAsync shuts down the Routed broker and stops processing requests.
+func (o *Routed) CloseAsync() {
+	o.close()
+}
+
+// WaitForClose blocks until the Routed broker has closed down.
+func (o *Routed) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-o.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,201 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutedInterfaces(t *testing.T) {
+	f := &Routed{}
+	if types.Consumer(f) == nil {
+		t.Errorf("Routed: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("Routed: nil types.Closable")
+	}
+}
+
+func TestRoutedSplitsBatches(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	key, err := bloblang.NewField(`${! content() }`)
+	require.NoError(t, err)
+
+	oTM, err := NewRouted(outputs, func(index int, msg types.Message) int {
+		var i int
+		fmt.Sscanf(key.String(index, msg), "%d", &i)
+		return i
+	}, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	require.NoError(t, oTM.Consume(readChan))
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{
+		[]byte("0"), []byte("2"), []byte("0"), []byte("2"),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	var firstAttempt chan<- types.Response
+	for _, i := range []int{0, 2} {
+		select {
+		case ts := <-mockOutputs[i].TChan:
+			require.Equal(t, 2, ts.Payload.Len())
+			assert.Equal(t, fmt.Sprintf("%v", i), string(ts.Payload.Get(0).Get()))
+			assert.Equal(t, fmt.Sprintf("%v", i), string(ts.Payload.Get(1).Get()))
+			if i == 0 {
+				firstAttempt = ts.ResponseChan
+				continue
+			}
+			select {
+			case ts.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	// Failing the first output results in a retry of only its batch.
+	select {
+	case firstAttempt <- response.NewError(errors.New("nope")):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case ts := <-mockOutputs[0].TChan:
+		require.Equal(t, 2, ts.Payload.Len())
+		select {
+		case ts.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	case <-mockOutputs[2].TChan:
+		t.Fatal("unexpected retry")
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	oTM.CloseAsync()
+	require.NoError(t, oTM.WaitForClose(time.Second))
+}
+
+func TestHashRing(t *testing.T) {
+	ring := newHashRing(4)
+
+	counts := map[int]int{}
+	assignments := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key-%v", i)
+		target := ring.Get(key)
+		assert.Equal(t, target, ring.Get(key))
+		assignments[key] = target
+		counts[target]++
+	}
+	require.Len(t, counts, 4)
+	for i, c := range counts {
+		assert.Greater(t, c, 1500, i)
+	}
+
+	// Adding an output only moves keys to the new output.
+	biggerRing := newHashRing(5)
+	moved := 0
+	for key, target := range assignments {
+		if newTarget := biggerRing.Get(key); newTarget != target {
+			assert.Equal(t, 4, newTarget)
+			moved++
+		}
+	}
+	assert.Greater(t, moved, 1000)
+	assert.Less(t, moved, 3000)
+}
+
+func TestWeightedRoute(t *testing.T) {
+	route, err := weightedRoute([]float64{90, 10, 0}, nil)
+	require.NoError(t, err)
+
+	counts := map[int]int{}
+	msg := message.New([][]byte{[]byte("foo")})
+	for i := 0; i < 10000; i++ {
+		counts[route(0, msg)]++
+	}
+	assert.InDelta(t, 9000, counts[0], 500)
+	assert.InDelta(t, 1000, counts[1], 500)
+	assert.Equal(t, 0, counts[2])
+
+	key, err := bloblang.NewField(`${! content() }`)
+	require.NoError(t, err)
+
+	route, err = weightedRoute([]float64{50, 50}, key)
+	require.NoError(t, err)
+
+	counts = map[int]int{}
+	for i := 0; i < 1000; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("key-%v", i))})
+		target := route(0, msg)
+		assert.Equal(t, target, route(0, msg))
+		counts[target]++
+	}
+	assert.InDelta(t, 500, counts[0], 100)
+
+	_, err = weightedRoute([]float64{}, nil)
+	assert.Error(t, err)
+
+	_, err = weightedRoute([]float64{0, 0}, nil)
+	assert.Error(t, err)
+
+	_, err = weightedRoute([]float64{-1, 2}, nil)
+	assert.Error(t, err)
+}
+
+type mockConsumeErrOutput struct {
+	MockOutputType
+}
+
+func (m *mockConsumeErrOutput) Consume(msgs <-chan types.Transaction) error {
+	return errors.New("nope")
+}
+
+func TestRoutedConsumeError(t *testing.T) {
+	first := &MockOutputType{}
+	outputs := []types.Output{first, &mockConsumeErrOutput{}}
+
+	_, err := NewRouted(outputs, func(index int, msg types.Message) int {
+		return 0
+	}, log.Noop(), metrics.Noop())
+	require.EqualError(t, err, "nope")
+
+	select {
+	case _, open := <-first.TChan:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -136,9 +138,13 @@ func (t *Try) loop() {
 			}
 			mMsgsRcvd.Incr(1)
 
+			// Only the messages of a batch that failed are sent to the next
+			// output when an output returns a batch error.
+			tracker := batch.NewTracker(tran.Payload)
+
 			rChan := make(chan types.Response)
 			select {
-			case t.outputTsChans[0] <- types.NewTransaction(tran.Payload, rChan):
+			case t.outputTsChans[0] <- types.NewTransaction(tracker.Message(), rChan):
 			case <-t.ctx.Done():
 				return
 			}
@@ -155,6 +161,11 @@ func (t *Try) loop() {
 					}
 					if res.Error() != nil {
 						mErrs[i-1].Incr(1)
+						if i < len(t.outputTsChans) {
+							tracker = tracker.Failed(res.Error())
+						} else {
+							res = response.NewError(tracker.Err(res.Error()))
+						}
 					} else {
 						break triesLoop
 					}
@@ -164,7 +175,7 @@ func (t *Try) loop() {
 
 				if i < len(t.outputTsChans) {
 					select {
-					case t.outputTsChans[i] <- types.NewTransaction(tran.Payload, rChan):
+					case t.outputTsChans[i] <- types.NewTransaction(tracker.Message(), rChan):
 					case <-t.ctx.Done():
 						return
 					}
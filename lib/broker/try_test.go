@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
@@ -291,6 +292,96 @@ func TestTryAllFail(t *testing.T) {
 	}
 }
 
+func TestTryPartialBatch(t *testing.T) {
+	outputs := []types.Output{}
+	mockOutputs := []*MockOutputType{
+		{},
+		{},
+		{},
+	}
+
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewTry(outputs, metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readChan <- types.NewTransaction(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"), []byte("buz"),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for broker send")
+	}
+
+	expContents := [][]string{
+		{"foo", "bar", "baz", "buz"},
+		{"bar", "buz"},
+		{"buz"},
+	}
+	for j, exp := range expContents {
+		var ts types.Transaction
+		select {
+		case ts = <-mockOutputs[j].TChan:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker propagate")
+		}
+
+		var act []string
+		for _, b := range message.GetAllBytes(ts.Payload) {
+			act = append(act, string(b))
+		}
+		if fmt.Sprintf("%v", exp) != fmt.Sprintf("%v", act) {
+			t.Errorf("Wrong contents for output %v: %v != %v", j, act, exp)
+		}
+
+		batchErr := batch.NewError(ts.Payload, errors.New("test error")).Failed(ts.Payload.Len()-1, errors.New("test error"))
+		if j == 0 {
+			batchErr.Failed(1, errors.New("test error"))
+		}
+
+		select {
+		case ts.ResponseChan <- response.NewError(batchErr):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to broker")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		walkable, ok := res.Error().(batch.WalkableError)
+		if !ok {
+			t.Fatalf("Expected batch error, got: %v", res.Error())
+		}
+		var failed []int
+		walkable.WalkParts(func(i int, _ types.Part, err error) bool {
+			if err != nil {
+				failed = append(failed, i)
+			}
+			return true
+		})
+		if exp, act := []int{3}, failed; fmt.Sprintf("%v", exp) != fmt.Sprintf("%v", act) {
+			t.Errorf("Wrong failed indexes: %v != %v", act, exp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out responding to broker")
+	}
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestTryAllFailParallel(t *testing.T) {
 	outputs := []types.Output{}
 	mockOutputs := []*MockOutputType{
@@ -0,0 +1,88 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+// weightedRoute returns a RouteFunc that chooses outputs at random in
+// proportion to their weights. When a key is provided the choice is derived
+// from a hash of the key instead, which results in messages of a key always
+// being sent to the same output.
+func weightedRoute(weights []float64, key field.Expression) (RouteFunc, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("at least one weight must be provided")
+	}
+
+	var total float64
+	cumulative := make([]float64, len(weights))
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, fmt.Errorf("weight %v of output %v is invalid", w, i)
+		}
+		total += w
+		cumulative[i] = total
+	}
+	if total <= 0 {
+		return nil, errors.New("the sum of weights must be greater than zero")
+	}
+
+	pick := func(v float64) int {
+		point := v * total
+		i := sort.Search(len(cumulative), func(i int) bool {
+			return cumulative[i] > point
+		})
+		if i == len(cumulative) {
+			i = len(cumulative) - 1
+		}
+		return i
+	}
+
+	if key != nil {
+		return func(index int, msg types.Message) int {
+			h := xxhash.ChecksumString64(key.String(index, msg))
+			return pick(float64(h>>11) / float64(1<<53))
+		}, nil
+	}
+
+	var rMut sync.Mutex
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return func(index int, msg types.Message) int {
+		rMut.Lock()
+		v := rnd.Float64()
+		rMut.Unlock()
+		return pick(v)
+	}, nil
+}
+
+// NewWeighted creates a new Routed broker that sends each message to an output
+// chosen in proportion to the weight of each output. An optional key can be
+// provided in order to choose outputs by a hash of the key rather than at
+// random.
+func NewWeighted(
+	outputs []types.Output, weights []float64, key field.Expression, logger log.Modular, stats metrics.Type,
+) (*Routed, error) {
+	if len(weights) != len(outputs) {
+		return nil, fmt.Errorf("expected %v weights to match the number of outputs, got %v", len(outputs), len(weights))
+	}
+	route, err := weightedRoute(weights, key)
+	if err != nil {
+		return nil, err
+	}
+	return NewRouted(outputs, route, logger, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -216,9 +216,10 @@ func Descriptions() string {
 }
 
 // New creates a buffer type based on a buffer configuration.
-func New(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+func New(conf Config, mgr types.Manager, logger log.Modular, stats metrics.Type) (Type, error) {
+	logger = log.ForType(logger, conf.Type)
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(conf, mgr, log, stats)
+		return c.constructor(conf, mgr, logger, stats)
 	}
 	return nil, types.ErrInvalidBufferType
 }
@@ -3,10 +3,10 @@ package condition
 import (
 	"fmt"
 
-	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -17,6 +17,7 @@ import (
 func init() {
 	Constructors[TypeBloblang] = TypeSpec{
 		constructor: NewBloblang,
+		Bloblang:    true,
 		Summary: `
 Executes a [Bloblang](/docs/guides/bloblang/about) query on messages, expecting
 a boolean result. If the result of the query is true then the condition passes,
@@ -64,7 +65,7 @@ type Bloblang struct {
 func NewBloblang(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	fn, err := bloblang.NewMapping("", string(conf.Bloblang))
+	fn, err := interop.NewBloblangMapping(mgr, string(conf.Bloblang))
 	if err != nil {
 		if perr, ok := err.(*parser.Error); ok {
 			return nil, fmt.Errorf("%v", perr.ErrorAtPosition([]rune(conf.Bloblang)))
@@ -26,6 +26,10 @@ type TypeSpec struct {
 	) (Type, error)
 	sanitiseConfigFunc func(conf Config) (interface{}, error)
 
+	// Bloblang indicates whether this condition is configured with a single
+	// Bloblang query rather than an object of fields.
+	Bloblang bool
+
 	Status      docs.Status
 	Summary     string
 	Description string
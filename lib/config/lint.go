@@ -6,6 +6,15 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/condition"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/tracer"
 	"gopkg.in/yaml.v3"
 )
 
@@ -28,9 +37,72 @@ var keyValueRules = []func(line int, path, key string, value interface{}) []stri
 	},
 }
 
-func lintWalkObj(path string, rawNode *yaml.Node, raw, processed map[interface{}]interface{}) []string {
+// lintBloblang checks a Bloblang mapping for type errors.
+func lintBloblang(line int, path string, value interface{}) []string {
+	valueStr, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	// Parse errors are reported when the config is loaded, therefore we only
+	// check for type errors here.
+	exec, err := bloblang.NewMapping("", valueStr)
+	if err != nil {
+		return nil
+	}
+	var lints []string
+	for _, err := range exec.CheckTypes() {
+		lints = append(lints, fmt.Sprintf("line %v: path '%v': mapping type error at %v", line, path, err))
+	}
+	return lints
+}
+
+// componentSpec returns a field spec describing the config of components of a
+// given type, which is a merge of all component kinds that share the name.
+func componentSpec(typeStr string) docs.FieldSpec {
+	var spec docs.FieldSpec
+	if s, exists := input.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+	}
+	if s, exists := buffer.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+	}
+	if s, exists := processor.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+		spec.Bloblang = spec.Bloblang || s.Bloblang
+	}
+	if s, exists := condition.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+		spec.Bloblang = spec.Bloblang || s.Bloblang
+	}
+	if s, exists := output.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+	}
+	if s, exists := metrics.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+	}
+	if s, exists := tracer.Constructors[typeStr]; exists {
+		spec.Children = append(spec.Children, s.FieldSpecs...)
+	}
+	return spec
+}
+
+// childSpec returns the spec of a named field from a list of specs.
+func childSpec(specs docs.FieldSpecs, name string) docs.FieldSpec {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return spec
+		}
+	}
+	return docs.FieldSpec{}
+}
+
+func lintWalkObj(path string, rawNode *yaml.Node, raw, processed map[interface{}]interface{}, specs docs.FieldSpecs) []string {
 	lints := []string{}
 
+	// Objects with a type are components, where the field of the same name
+	// contains the config of the component.
+	typeStr, _ := processed["type"].(string)
+
 	keys := []string{}
 	for k := range raw {
 		keys = append(keys, fmt.Sprintf("%v", k))
@@ -57,7 +129,16 @@ func lintWalkObj(path string, rawNode *yaml.Node, raw, processed map[interface{}
 		for _, rule := range keyValueRules {
 			lints = append(lints, rule(line, newPath, k, y)...)
 		}
-		if l := lintWalk(newPath, keyNode, y, x); len(l) > 0 {
+		var spec docs.FieldSpec
+		if len(typeStr) > 0 && k == typeStr {
+			spec = componentSpec(k)
+		} else {
+			spec = childSpec(specs, k)
+		}
+		if spec.Bloblang {
+			lints = append(lints, lintBloblang(line, newPath, y)...)
+		}
+		if l := lintWalk(newPath, keyNode, y, x, spec.Children); len(l) > 0 {
 			lints = append(lints, l...)
 		}
 	}
@@ -115,7 +196,7 @@ func getNodeChildOfIndex(node *yaml.Node, index int) *yaml.Node {
 	return node.Content[index]
 }
 
-func lintWalk(path string, rawNode *yaml.Node, raw, processed interface{}) []string {
+func lintWalk(path string, rawNode *yaml.Node, raw, processed interface{}, specs docs.FieldSpecs) []string {
 	line := 0
 	if rawNode != nil {
 		line = rawNode.Line
@@ -126,13 +207,13 @@ func lintWalk(path string, rawNode *yaml.Node, raw, processed interface{}) []str
 		if !ok {
 			return []string{fmt.Sprintf("line %v: path '%v': wrong type detected. Expected object but found %T", line, path, raw)}
 		}
-		return lintWalkObj(path, rawNode, y, x)
+		return lintWalkObj(path, rawNode, y, x, specs)
 	case map[string]interface{}:
 		y, ok := getObjMap(raw)
 		if !ok {
 			return []string{fmt.Sprintf("line %v: path '%v': wrong type detected. Expected object but found %T", line, path, raw)}
 		}
-		return lintWalkObj(path, rawNode, y, mapToObjMap(x))
+		return lintWalkObj(path, rawNode, y, mapToObjMap(x), specs)
 	case []interface{}:
 		y, ok := raw.([]interface{})
 		if !ok {
@@ -144,7 +225,7 @@ func lintWalk(path string, rawNode *yaml.Node, raw, processed interface{}) []str
 				break
 			}
 			indexNode := getNodeChildOfIndex(rawNode, i)
-			if l := lintWalk(fmt.Sprintf("%v[%v]", path, i), indexNode, v, x[i]); len(l) > 0 {
+			if l := lintWalk(fmt.Sprintf("%v[%v]", path, i), indexNode, v, x[i], specs); len(l) > 0 {
 				lints = append(lints, l...)
 			}
 		}
@@ -187,7 +268,7 @@ func Lint(rawBytes []byte, config Type) ([]string, error) {
 	} else if err = yaml.Unmarshal(processedBytes, &processed); err != nil {
 		return nil, err
 	}
-	return lintWalk("", &rawNode, raw, processed), nil
+	return lintWalk("", &rawNode, raw, processed, nil), nil
 }
 
 //------------------------------------------------------------------------------
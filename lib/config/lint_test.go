@@ -93,6 +93,46 @@ pipeline:
 				"line 6: path 'pipeline.processors[0].type': Type 'batch' is unsafe outside of the 'input' section, for more information read https://benthos.dev/docs/configuration/batching",
 			},
 		},
+		{
+			name: "bloblang mapping type errors",
+			conf: `pipeline:
+  processors:
+  - bloblang: |
+      root.foo = this.foo.uppercase()
+      root.bar = this.bar.keys().abs()
+  - switch:
+    - check: this.foo.length() > 5
+      processors:
+      - bloblang: 'root = uuid_v4().sum()'`,
+			lints: []string{
+				"line 3: path 'pipeline.processors[0].bloblang': mapping type error at line 2: method abs: expected number value, found array",
+				"line 9: path 'pipeline.processors[1].switch[0].processors[0].bloblang': mapping type error at line 1: method sum: expected array or number value, found string",
+			},
+		},
+		{
+			name: "bloblang type errors only in bloblang fields",
+			conf: `input:
+  generate:
+    mapping: 'root = content() + "-suffix"'
+    interval: 1s
+pipeline:
+  processors:
+  - log:
+      fields:
+        mapping: 'root = uuid_v4().sum()'
+  - branch:
+      request_map: 'root = this.foo.keys().abs()'
+output:
+  switch:
+    cases:
+    - check: count("foo").uppercase() == "A"
+      output:
+        drop: {}`,
+			lints: []string{
+				"line 15: path 'output.switch.cases[0].check': mapping type error at line 1: method uppercase: expected string or bytes value, found number",
+				"line 11: path 'pipeline.processors[1].branch.request_map': mapping type error at line 1: method abs: expected number value, found array",
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
@@ -178,6 +179,7 @@ func (r *AsyncReader) loop() {
 		}
 
 		resChan := make(chan types.Response)
+		message.InitIngestionTimes(time.Now(), msg)
 		tracing.InitSpans("input_"+r.typeStr, msg)
 		select {
 		case r.transactions <- types.NewTransaction(msg, resChan):
@@ -134,7 +134,8 @@ type kinesisReader struct {
 	leasePeriod     time.Duration
 	rebalancePeriod time.Duration
 
-	mRebalanced metrics.StatCounter
+	mRebalanced         metrics.StatCounter
+	mMillisBehindLatest metrics.StatGaugeVec
 
 	cMut    sync.Mutex
 	msgChan chan asyncMessage
@@ -156,13 +157,14 @@ func newKinesisReader(
 	}
 
 	k := kinesisReader{
-		conf:         conf,
-		stats:        stats,
-		log:          log,
-		mgr:          mgr,
-		mRebalanced:  stats.GetCounter("rebalanced"),
-		closedChan:   make(chan struct{}),
-		streamShards: map[string][]string{},
+		conf:                conf,
+		stats:               stats,
+		log:                 log,
+		mgr:                 mgr,
+		mRebalanced:         stats.GetCounter("rebalanced"),
+		mMillisBehindLatest: stats.GetGaugeVec("shard.millis_behind_latest", []string{"stream", "shard"}),
+		closedChan:          make(chan struct{}),
+		streamShards:        map[string][]string{},
 	}
 	k.ctx, k.done = context.WithCancel(context.Background())
 
@@ -287,6 +289,9 @@ func (k *kinesisReader) getRecords(streamID, shardID, shardIter string) ([]*kine
 	if err != nil {
 		return nil, shardIter, err
 	}
+	if res.MillisBehindLatest != nil {
+		k.mMillisBehindLatest.With(streamID, shardID).Set(*res.MillisBehindLatest)
+	}
 
 	nextIter := ""
 	if res.NextShardIterator != nil {
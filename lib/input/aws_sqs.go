@@ -97,15 +97,18 @@ type awsSQS struct {
 	pendingMut  sync.Mutex
 	nextRequest time.Time
 
+	backlog *reader.SQSBacklog
+
 	log   log.Modular
 	stats metrics.Type
 }
 
 func newAWSSQS(conf AWSSQSConfig, log log.Modular, stats metrics.Type) (*awsSQS, error) {
 	return &awsSQS{
-		conf:  conf,
-		log:   log,
-		stats: stats,
+		conf:    conf,
+		log:     log,
+		stats:   stats,
+		backlog: reader.NewSQSBacklog(conf.URL, log, stats),
 	}, nil
 }
 
@@ -124,6 +127,8 @@ func (a *awsSQS) ConnectWithContext(ctx context.Context) error {
 	a.sqs = sqs.New(sess)
 	a.session = sess
 
+	a.backlog.Start(a.sqs)
+
 	a.log.Infof("Receiving Amazon SQS messages from URL: %v\n", a.conf.URL)
 	return nil
 }
@@ -237,6 +242,7 @@ func (a *awsSQS) ReadWithContext(ctx context.Context) (types.Message, reader.Asy
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *awsSQS) CloseAsync() {
+	a.backlog.Close()
 	go func() {
 		a.pendingMut.Lock()
 		defer a.pendingMut.Unlock()
@@ -158,6 +158,7 @@ const (
 	TypeHTTPClient        = "http_client"
 	TypeHTTPServer        = "http_server"
 	TypeInproc            = "inproc"
+	TypeJoin              = "join"
 	TypeKafka             = "kafka"
 	TypeKafkaBalanced     = "kafka_balanced"
 	TypeKinesis           = "kinesis"
@@ -212,6 +213,7 @@ type Config struct {
 	HTTPClient        HTTPClientConfig             `json:"http_client" yaml:"http_client"`
 	HTTPServer        HTTPServerConfig             `json:"http_server" yaml:"http_server"`
 	Inproc            InprocConfig                 `json:"inproc" yaml:"inproc"`
+	Join              JoinConfig                   `json:"join" yaml:"join"`
 	Kafka             reader.KafkaConfig           `json:"kafka" yaml:"kafka"`
 	KafkaBalanced     reader.KafkaBalancedConfig   `json:"kafka_balanced" yaml:"kafka_balanced"`
 	Kinesis           reader.KinesisConfig         `json:"kinesis" yaml:"kinesis"`
@@ -267,6 +269,7 @@ func NewConfig() Config {
 		HTTPClient:        NewHTTPClientConfig(),
 		HTTPServer:        NewHTTPServerConfig(),
 		Inproc:            NewInprocConfig(),
+		Join:              NewJoinConfig(),
 		Kafka:             reader.NewKafkaConfig(),
 		KafkaBalanced:     reader.NewKafkaBalancedConfig(),
 		Kinesis:           reader.NewKinesisConfig(),
@@ -419,15 +422,16 @@ func newHasBatchProcessor(
 	hasBatchProc bool,
 	conf Config,
 	mgr types.Manager,
-	log log.Modular,
+	logger log.Modular,
 	stats metrics.Type,
 	pipelines ...types.PipelineConstructorFunc,
 ) (Type, error) {
+	logger = log.ForType(logger, conf.Type)
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(hasBatchProc, conf, mgr, log, stats, pipelines...)
+		return c.constructor(hasBatchProc, conf, mgr, logger, stats, pipelines...)
 	}
 	if c, ok := pluginSpecs[conf.Type]; ok {
-		return c.constructor(hasBatchProc, conf, mgr, log, stats, pipelines...)
+		return c.constructor(hasBatchProc, conf, mgr, logger, stats, pipelines...)
 	}
 	return nil, types.ErrInvalidInputType
 }
@@ -385,6 +385,8 @@ func extractMessageFromRequest(r *http.Request) (types.Message, error) {
 	}
 	message.SetAllMetadata(msg, meta)
 
+	message.InitIngestionTimes(time.Now(), msg)
+
 	// Try to either extract parent span from headers, or create a new one.
 	carrier := opentracing.HTTPHeadersCarrier(r.Header)
 	if clientSpanContext, serr := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, carrier); serr == nil {
@@ -615,6 +617,7 @@ func (h *HTTPServer) wsHandler(w http.ResponseWriter, r *http.Request) {
 		for _, c := range r.Cookies() {
 			meta.Set(c.Name, c.Value)
 		}
+		message.InitIngestionTimes(time.Now(), msg)
 		tracing.InitSpans("input_http_server_websocket", msg)
 
 		store := roundtrip.NewResultStore()
@@ -0,0 +1,1002 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/gabs/v2"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJoin] = TypeSpec{
+		constructor: fromSimpleConstructor(NewJoin),
+		Status:      docs.StatusExperimental,
+		Summary: `
+Consumes from two or more unbounded inputs in parallel and joins their messages
+by a key when they arrive within a window of each other.`,
+		Description: `
+The key of each message is obtained by executing the ` + "`key_mapping`" + `,
+which must result in a string. Messages must be structured objects, those that
+are not, or that do not result in a key, are dropped.
+
+For each key the latest message received from each input is stored, and
+messages from the same input that share a key before the join completes are
+merged together. Once a message of a key has been received from every input the
+messages are merged in the order of the inputs into a single document, which is
+then emitted, and the state of the key is discarded. Further messages with the
+same key begin a new join.
+
+### Join Types
+
+The ` + "`type`" + ` determines what happens when the ` + "`window`" + ` of a
+key expires before a message has been received from every input, where the
+window of a key begins when the first message of that key arrives:
+
+- An ` + "`inner`" + ` join discards the messages of the key.
+- A ` + "`left`" + ` join emits the messages of the key merged together only if
+  a message was received from the first input.
+- An ` + "`outer`" + ` join always emits the messages of the key merged together.
+
+### State
+
+Messages waiting to be joined are stored within a
+` + "[`cache` resource](/docs/components/caches/about)" + `, and a message is
+only acknowledged once it has been written to the cache, or when it completes a
+join once the joined message has been delivered. Entries are written with a TTL
+of twice the window in order to ensure that abandoned state is eventually
+removed, and therefore the cache must support per-key TTLs.
+
+The deadlines of keys waiting to be joined are also recorded within the cache,
+which allows the expiry of windows to resume after a restart, where windows that
+expired while the service was down are expired immediately. The state of a
+joined or expired message is kept within the cache until it has been delivered,
+and messages that were not delivered before a restart are sent again.
+
+All keys are written to the cache with the prefix ` + "`cache_key_prefix`" + `,
+which must be unique for each join input that shares a cache. This input should
+not be run with multiple instances sharing a prefix, as the read and update of a
+key is not atomic across instances.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Orders and Payments",
+				Summary: `
+In this example we join a stream of orders with a stream of payments, each
+consumed from a Kafka topic, by their order ID. Orders that do not receive a
+payment within an hour are still emitted without payment details:`,
+				Config: `
+input:
+  join:
+    type: left
+    key_mapping: root = this.order_id
+    window: 1h
+    cache: pending_orders
+    inputs:
+      - kafka_balanced:
+          addresses: [ localhost:9092 ]
+          topics: [ orders ]
+          consumer_group: benthos_join
+      - kafka_balanced:
+          addresses: [ localhost:9092 ]
+          topics: [ payments ]
+          consumer_group: benthos_join
+        processors:
+          - bloblang: |
+              root.order_id = this.order_id
+              root.payment = this
+
+resources:
+  caches:
+    pending_orders:
+      redis:
+        url: tcp://localhost:6379
+`,
+			},
+		},
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			inputsSanit := make([]interface{}, 0, len(conf.Join.Inputs))
+			for _, in := range conf.Join.Inputs {
+				sanit, err := SanitiseConfig(in)
+				if err != nil {
+					return nil, err
+				}
+				inputsSanit = append(inputsSanit, sanit)
+			}
+			return map[string]interface{}{
+				"type":             conf.Join.Type,
+				"key_mapping":      conf.Join.KeyMapping,
+				"window":           conf.Join.Window,
+				"cache":            conf.Join.Cache,
+				"cache_key_prefix": conf.Join.CacheKeyPrefix,
+				"merge_strategy":   conf.Join.MergeStrategy,
+				"inputs":           inputsSanit,
+			}, nil
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("type", "The type of join to perform.").HasOptions("inner", "left", "outer"),
+			docs.FieldCommon(
+				"key_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that results in the key to join messages by.",
+				`root = this.id`, `root = meta("kafka_key")`,
+			).IsBloblang(),
+			docs.FieldCommon("window", "The period after the first message of a key arrives during which messages from the other inputs can be joined to it."),
+			docs.FieldCommon("cache", "A [`cache` resource](/docs/components/caches/about) to store messages waiting to be joined, which must support per-key TTLs."),
+			docs.FieldAdvanced("cache_key_prefix", "A prefix for the keys under which join state is stored within the `cache`, which must be unique for each join input that shares a cache."),
+			docs.FieldAdvanced(
+				"merge_strategy",
+				"The strategy to use when merging messages results in a collision of field values with different values. The strategy `array` means non-array colliding values are placed into an array and colliding arrays are merged. The strategy `replace` replaces old values with new values. The strategy `keep` keeps the old value.",
+			).HasOptions("array", "replace", "keep"),
+			docs.FieldCommon("inputs", "An array of two or more inputs to consume from and join."),
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JoinConfig contains configuration values for the Join input type.
+type JoinConfig struct {
+	Type           string   `json:"type" yaml:"type"`
+	KeyMapping     string   `json:"key_mapping" yaml:"key_mapping"`
+	Window         string   `json:"window" yaml:"window"`
+	Cache          string   `json:"cache" yaml:"cache"`
+	CacheKeyPrefix string   `json:"cache_key_prefix" yaml:"cache_key_prefix"`
+	MergeStrategy  string   `json:"merge_strategy" yaml:"merge_strategy"`
+	Inputs         []Config `json:"inputs" yaml:"inputs"`
+}
+
+// NewJoinConfig creates a new JoinConfig with default values.
+func NewJoinConfig() JoinConfig {
+	return JoinConfig{
+		Type:           "inner",
+		KeyMapping:     "",
+		Window:         "1m",
+		Cache:          "",
+		CacheKeyPrefix: "benthos_join",
+		MergeStrategy:  "array",
+		Inputs:         []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// joinStatePart is a message from one input waiting to be joined.
+type joinStatePart struct {
+	Doc  map[string]interface{} `json:"doc"`
+	Meta map[string]string      `json:"meta"`
+}
+
+// joinState is the state of a key waiting to be joined, which is stored within
+// the cache.
+type joinState struct {
+	Created time.Time        `json:"created"`
+	Parts   []*joinStatePart `json:"parts"`
+}
+
+func (s *joinState) complete() bool {
+	for _, p := range s.Parts {
+		if p == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// joinResult is a set of parts emitted by a join, along with the keys of the
+// cache entries that hold their state until they are delivered.
+type joinResult struct {
+	parts []types.Part
+	keys  []string
+}
+
+func (r *joinResult) add(part types.Part, key string) {
+	r.parts = append(r.parts, part)
+	r.keys = append(r.keys, key)
+}
+
+type joinPending struct {
+	key      string
+	deadline time.Time
+}
+
+// joinDeadlineBuckets is the number of buckets per window that the deadlines
+// of keys are grouped into within the cache.
+const joinDeadlineBuckets = 10
+
+// streamJoiner holds the logic for joining messages by key, with state stored
+// in a cache and the expiry of keys tracked in memory.
+type streamJoiner struct {
+	joinType    string
+	window      time.Duration
+	inputs      int
+	cacheName   string
+	prefix      string
+	mgr         types.Manager
+	keyMapping  *mapping.Executor
+	collisionFn messageJoinerCollisionFn
+
+	// Keys waiting to be joined ordered by their deadline, entries are stale
+	// when their deadline no longer matches the pending map.
+	pending      map[string]time.Time
+	pendingQueue []joinPending
+
+	// Distinguishes the delivery entries created at the same time.
+	deliverySeq uint64
+
+	nowFn func() time.Time
+}
+
+func (s *streamJoiner) cache() (types.CacheWithTTL, error) {
+	c, err := s.mgr.GetCache(s.cacheName)
+	if err != nil {
+		return nil, err
+	}
+	cttl, ok := c.(types.CacheWithTTL)
+	if !ok {
+		return nil, errors.New("cache does not support per-key TTLs")
+	}
+	return cttl, nil
+}
+
+func (s *streamJoiner) stateKey(key string) string {
+	return s.prefix + "_key_" + key
+}
+
+func (s *streamJoiner) deadlineBucket(t time.Time) int64 {
+	width := int64(s.window) / joinDeadlineBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return t.UnixNano() / width
+}
+
+func (s *streamJoiner) deadlinesKey(bucket int64) string {
+	return fmt.Sprintf("%v_deadlines_%v", s.prefix, bucket)
+}
+
+func (s *streamJoiner) deliveriesKey(bucket int64) string {
+	return fmt.Sprintf("%v_deliveries_%v", s.prefix, bucket)
+}
+
+func (s *streamJoiner) readState(c types.Cache, key string) (*joinState, []byte, error) {
+	data, err := c.Get(s.stateKey(key))
+	if err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	var state joinState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse join state: %w", err)
+	}
+	if len(state.Parts) != s.inputs {
+		return nil, nil, fmt.Errorf("join state has %v inputs, expected %v", len(state.Parts), s.inputs)
+	}
+	return &state, data, nil
+}
+
+func (s *streamJoiner) writeState(c types.CacheWithTTL, key string, data []byte) error {
+	ttl := s.window * 2
+	return c.SetWithTTL(s.stateKey(key), data, &ttl)
+}
+
+// trackDeadline records a key within the cache entry of the bucket of its
+// deadline, which allows pending keys to be found again after a restart.
+func (s *streamJoiner) trackDeadline(c types.CacheWithTTL, key string, deadline time.Time) error {
+	return s.trackKey(c, s.deadlinesKey(s.deadlineBucket(deadline)), key)
+}
+
+// readKeys reads a list of keys recorded within a cache entry.
+func (s *streamJoiner) readKeys(c types.Cache, listKey string) ([]string, error) {
+	data, err := c.Get(listKey)
+	if err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var keys []string
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse join keys: %w", err)
+	}
+	return keys, nil
+}
+
+// trackKey adds a key to the list of keys recorded within a cache entry.
+func (s *streamJoiner) trackKey(c types.CacheWithTTL, listKey, key string) error {
+	keys, err := s.readKeys(c, listKey)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k == key {
+			return nil
+		}
+	}
+	data, err := json.Marshal(append(keys, key))
+	if err != nil {
+		return err
+	}
+	ttl := s.window * 2
+	return c.SetWithTTL(listKey, data, &ttl)
+}
+
+// deliver writes the state of a key that is about to be emitted to a new cache
+// entry, which is recorded within the bucket of the current time so that it
+// can be found again after a restart. The key of the entry is returned and it
+// should be deleted with Delivered once the emitted message is acknowledged.
+func (s *streamJoiner) deliver(c types.CacheWithTTL, key string, state *joinState, now time.Time) (string, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	s.deliverySeq++
+	deliveryKey := fmt.Sprintf("%v_delivery_%v_%v_%v", s.prefix, now.UnixNano(), s.deliverySeq, key)
+
+	ttl := s.window * 2
+	if err = c.SetWithTTL(deliveryKey, data, &ttl); err != nil {
+		return "", err
+	}
+	if err = s.trackKey(c, s.deliveriesKey(s.deadlineBucket(now)), deliveryKey); err != nil {
+		_ = c.Delete(deliveryKey)
+		return "", err
+	}
+	return deliveryKey, nil
+}
+
+// Delivered removes the cache entries of emitted parts once they have been
+// delivered. This only accesses the cache and is therefore safe to call
+// concurrently with other methods.
+func (s *streamJoiner) Delivered(keys []string) error {
+	c, err := s.cache()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err = c.Delete(k); err != nil && err != types.ErrKeyNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// schedule sets the deadline of a key, keeping the queue ordered by deadline.
+func (s *streamJoiner) schedule(key string, deadline time.Time) {
+	if d, exists := s.pending[key]; exists && d.Equal(deadline) {
+		return
+	}
+	s.pending[key] = deadline
+
+	i := sort.Search(len(s.pendingQueue), func(i int) bool {
+		return s.pendingQueue[i].deadline.After(deadline)
+	})
+	s.pendingQueue = append(s.pendingQueue, joinPending{})
+	copy(s.pendingQueue[i+1:], s.pendingQueue[i:])
+	s.pendingQueue[i] = joinPending{key: key, deadline: deadline}
+}
+
+// Restore schedules the expiry of keys waiting to be joined from the deadlines
+// recorded in the cache, covering all deadlines of keys that could still be
+// present within the cache. Parts that were emitted but not delivered before
+// a restart are returned so that they can be sent again.
+func (s *streamJoiner) Restore() (joinResult, error) {
+	var undelivered joinResult
+
+	c, err := s.cache()
+	if err != nil {
+		return undelivered, err
+	}
+
+	now := s.nowFn()
+	for b := s.deadlineBucket(now.Add(-s.window * 2)); b <= s.deadlineBucket(now.Add(s.window)); b++ {
+		keys, err := s.readKeys(c, s.deadlinesKey(b))
+		if err != nil {
+			return undelivered, err
+		}
+		for _, key := range keys {
+			if _, exists := s.pending[key]; exists {
+				continue
+			}
+			state, _, err := s.readState(c, key)
+			if err != nil {
+				return undelivered, err
+			}
+			if state != nil {
+				s.schedule(key, state.Created.Add(s.window))
+			}
+		}
+
+		if keys, err = s.readKeys(c, s.deliveriesKey(b)); err != nil {
+			return undelivered, err
+		}
+		for _, deliveryKey := range keys {
+			data, err := c.Get(deliveryKey)
+			if err != nil {
+				if err == types.ErrKeyNotFound {
+					continue
+				}
+				return undelivered, err
+			}
+			var state joinState
+			if err = json.Unmarshal(data, &state); err != nil {
+				return undelivered, fmt.Errorf("failed to parse join state: %w", err)
+			}
+			undelivered.add(s.merge(&state), deliveryKey)
+		}
+	}
+	return undelivered, nil
+}
+
+func (s *streamJoiner) key(index int, msg types.Message) (string, error) {
+	res, err := s.keyMapping.Exec(query.FunctionContext{
+		Maps:     s.keyMapping.Maps(),
+		Vars:     map[string]interface{}{},
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	}))
+	if err != nil {
+		return "", err
+	}
+	key, ok := res.(string)
+	if !ok {
+		return "", fmt.Errorf("expected key mapping to result in a string, got %T", res)
+	}
+	if key == "" {
+		return "", errors.New("key mapping resulted in an empty string")
+	}
+	return key, nil
+}
+
+func (s *streamJoiner) merge(state *joinState) types.Part {
+	merged := gabs.New()
+	meta := message.NewPart(nil).Metadata()
+	for _, p := range state.Parts {
+		if p == nil {
+			continue
+		}
+		_ = merged.MergeFn(gabs.Wrap(p.Doc), s.collisionFn)
+		for k, v := range p.Meta {
+			meta.Set(k, v)
+		}
+	}
+	part := message.NewPart(nil)
+	part.SetJSON(merged.Data())
+	part.SetMetadata(meta)
+	return part
+}
+
+// joinCompleted is the state of a key prior to a batch completing its join.
+type joinCompleted struct {
+	data     []byte
+	deadline time.Time
+}
+
+// Add the messages of a batch received from an input to the join state,
+// returning any parts that complete a join. An error is returned if the state
+// could not be read from or written to the cache, in which case the state of
+// keys that were joined by the batch is restored and the batch should be
+// reattempted.
+func (s *streamJoiner) Add(inputIndex int, msg types.Message, onDrop func(error)) (joinResult, error) {
+	var joined joinResult
+
+	c, err := s.cache()
+	if err != nil {
+		return joined, err
+	}
+
+	completed := map[string]joinCompleted{}
+	restore := func(err error) error {
+		if rerr := s.Delivered(joined.keys); rerr != nil {
+			return fmt.Errorf("%w, and failed to restore join state: %v", err, rerr)
+		}
+		for key, prior := range completed {
+			if rerr := s.writeState(c, key, prior.data); rerr != nil {
+				return fmt.Errorf("%w, and failed to restore join state: %v", err, rerr)
+			}
+			s.schedule(key, prior.deadline)
+		}
+		return err
+	}
+
+	now := s.nowFn()
+	for i := 0; i < msg.Len(); i++ {
+		p := msg.Get(i)
+
+		jData, err := p.JSON()
+		if err != nil {
+			onDrop(fmt.Errorf("failed to parse message as structured: %w", err))
+			continue
+		}
+		doc, ok := jData.(map[string]interface{})
+		if !ok {
+			onDrop(fmt.Errorf("expected message to be an object, got %T", jData))
+			continue
+		}
+
+		key, err := s.key(i, msg)
+		if err != nil {
+			onDrop(fmt.Errorf("failed to resolve key: %w", err))
+			continue
+		}
+
+		state, priorData, err := s.readState(c, key)
+		if err != nil {
+			return joinResult{}, restore(err)
+		}
+		if state == nil {
+			state = &joinState{
+				Created: now,
+				Parts:   make([]*joinStatePart, s.inputs),
+			}
+		}
+		deadline := state.Created.Add(s.window)
+
+		meta := map[string]string{}
+		p.Metadata().Iter(func(k, v string) error {
+			meta[k] = v
+			return nil
+		})
+		if existing := state.Parts[inputIndex]; existing != nil {
+			gExisting := gabs.Wrap(existing.Doc)
+			_ = gExisting.MergeFn(gabs.Wrap(doc), s.collisionFn)
+			for k, v := range meta {
+				existing.Meta[k] = v
+			}
+		} else {
+			state.Parts[inputIndex] = &joinStatePart{Doc: doc, Meta: meta}
+		}
+
+		if state.complete() {
+			deliveryKey, err := s.deliver(c, key, state, now)
+			if err != nil {
+				return joinResult{}, restore(err)
+			}
+			joined.add(s.merge(state), deliveryKey)
+			if err = c.Delete(s.stateKey(key)); err != nil && err != types.ErrKeyNotFound {
+				return joinResult{}, restore(err)
+			}
+			if _, exists := completed[key]; !exists && priorData != nil {
+				completed[key] = joinCompleted{data: priorData, deadline: deadline}
+			}
+			continue
+		}
+
+		if priorData == nil {
+			if err = s.trackDeadline(c, key, deadline); err != nil {
+				return joinResult{}, restore(err)
+			}
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return joinResult{}, restore(err)
+		}
+		if err = s.writeState(c, key, data); err != nil {
+			return joinResult{}, restore(err)
+		}
+		s.schedule(key, deadline)
+	}
+
+	for key, prior := range completed {
+		if d, exists := s.pending[key]; exists && d.Equal(prior.deadline) {
+			delete(s.pending, key)
+		}
+	}
+	return joined, nil
+}
+
+// NextDeadline returns the time at which the next key expires, if any.
+func (s *streamJoiner) NextDeadline() (time.Time, bool) {
+	for len(s.pendingQueue) > 0 {
+		head := s.pendingQueue[0]
+		if d, exists := s.pending[head.key]; exists && d.Equal(head.deadline) {
+			return head.deadline, true
+		}
+		s.pendingQueue = s.pendingQueue[1:]
+	}
+	return time.Time{}, false
+}
+
+// Expire removes the state of all keys whose window has passed, returning the
+// parts that should be emitted according to the join type. When all is true
+// all keys are expired regardless of their deadline.
+func (s *streamJoiner) Expire(all bool) (joinResult, error) {
+	var expired joinResult
+
+	c, err := s.cache()
+	if err != nil {
+		return expired, err
+	}
+
+	now := s.nowFn()
+	for {
+		deadline, exists := s.NextDeadline()
+		if !exists || (!all && now.Before(deadline)) {
+			break
+		}
+		key := s.pendingQueue[0].key
+
+		state, _, err := s.readState(c, key)
+		if err != nil {
+			return expired, err
+		}
+		if state != nil {
+			if s.joinType == "outer" || (s.joinType == "left" && state.Parts[0] != nil) {
+				deliveryKey, err := s.deliver(c, key, state, now)
+				if err != nil {
+					return expired, err
+				}
+				expired.add(s.merge(state), deliveryKey)
+			}
+			if err = c.Delete(s.stateKey(key)); err != nil && err != types.ErrKeyNotFound {
+				return expired, err
+			}
+		}
+		delete(s.pending, key)
+		s.pendingQueue = s.pendingQueue[1:]
+	}
+	return expired, nil
+}
+
+//------------------------------------------------------------------------------
+
+type joinTransaction struct {
+	index int
+	tran  types.Transaction
+}
+
+// Join is an input type that consumes from multiple inputs in parallel and
+// joins their messages by a key.
+type Join struct {
+	joiner      *streamJoiner
+	undelivered joinResult
+	inputs      []Type
+
+	stats metrics.Type
+	log   log.Modular
+
+	mJoined  metrics.StatCounter
+	mExpired metrics.StatCounter
+	mDropped metrics.StatCounter
+	mErr     metrics.StatCounter
+
+	transactions chan types.Transaction
+
+	ctx        context.Context
+	closeFn    func()
+	closedChan chan struct{}
+}
+
+// NewJoin creates a new Join input type.
+func NewJoin(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if len(conf.Join.Inputs) < 2 {
+		return nil, errors.New("requires at least two child inputs")
+	}
+
+	switch conf.Join.Type {
+	case "inner", "left", "outer":
+	default:
+		return nil, fmt.Errorf("join type '%v' was not recognised", conf.Join.Type)
+	}
+
+	window, err := time.ParseDuration(conf.Join.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse window: %v", err)
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+
+	if conf.Join.KeyMapping == "" {
+		return nil, errors.New("a key mapping must be provided")
+	}
+	keyMapping, err := interop.NewBloblangMapping(mgr, conf.Join.KeyMapping)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			return nil, fmt.Errorf("failed to parse key mapping: %v", perr.ErrorAtPosition([]rune(conf.Join.KeyMapping)))
+		}
+		return nil, fmt.Errorf("failed to parse key mapping: %v", err)
+	}
+
+	collisionFn, err := getMessageJoinerCollisionFn(conf.Join.MergeStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := mgr.GetCache(conf.Join.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Join.Cache, err)
+	}
+	if _, ok := c.(types.CacheWithTTL); !ok {
+		return nil, fmt.Errorf("cache '%v' does not support per-key TTLs", conf.Join.Cache)
+	}
+
+	j := &Join{
+		joiner: &streamJoiner{
+			joinType:   conf.Join.Type,
+			window:     window,
+			inputs:     len(conf.Join.Inputs),
+			cacheName:  conf.Join.Cache,
+			prefix:     conf.Join.CacheKeyPrefix,
+			mgr:        mgr,
+			keyMapping: keyMapping,
+			collisionFn: func(dest, source interface{}) interface{} {
+				if reflect.DeepEqual(dest, source) {
+					return dest
+				}
+				return collisionFn(dest, source)
+			},
+			pending: map[string]time.Time{},
+			nowFn:   time.Now,
+		},
+
+		log:   log.NewModule(".join"),
+		stats: metrics.Namespaced(stats, "join"),
+
+		transactions: make(chan types.Transaction),
+		closedChan:   make(chan struct{}),
+	}
+	j.mJoined = j.stats.GetCounter("joined")
+	j.mExpired = j.stats.GetCounter("expired")
+	j.mDropped = j.stats.GetCounter("dropped")
+	j.mErr = j.stats.GetCounter("error")
+	j.ctx, j.closeFn = context.WithCancel(context.Background())
+
+	if j.undelivered, err = j.joiner.Restore(); err != nil {
+		return nil, fmt.Errorf("failed to restore join state: %v", err)
+	}
+
+	for i, c := range conf.Join.Inputs {
+		ns := fmt.Sprintf("join.inputs.%v", i)
+		in, err := New(c, mgr, log.NewModule("."+ns), metrics.Namespaced(stats, ns))
+		if err != nil {
+			for _, prev := range j.inputs {
+				prev.CloseAsync()
+			}
+			return nil, fmt.Errorf("failed to initialize input index %v: %w", i, err)
+		}
+		j.inputs = append(j.inputs, in)
+	}
+
+	go j.loop()
+	return j, nil
+}
+
+//------------------------------------------------------------------------------
+
+// dispatch sends a message of joined parts downstream and reattempts it until
+// it is acknowledged, at which point their state is removed from the cache and
+// onDelivered is called.
+func (j *Join) dispatch(wg *sync.WaitGroup, result joinResult, onDelivered func()) bool {
+	msg := message.New(nil)
+	msg.SetAll(result.parts)
+
+	resChan := make(chan types.Response)
+	tran := types.NewTransaction(msg, resChan)
+	select {
+	case j.transactions <- tran:
+	case <-j.ctx.Done():
+		return false
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case res := <-resChan:
+				if res.Error() == nil {
+					if err := j.joiner.Delivered(result.keys); err != nil {
+						j.mErr.Incr(1)
+						j.log.Errorf("Failed to remove delivered join state: %v\n", err)
+					}
+					if onDelivered != nil {
+						onDelivered()
+					}
+					return
+				}
+				j.log.Errorf("Failed to send joined message: %v\n", res.Error())
+			case <-j.ctx.Done():
+				return
+			}
+			select {
+			case <-time.After(time.Second):
+			case <-j.ctx.Done():
+				return
+			}
+			select {
+			case j.transactions <- tran:
+			case <-j.ctx.Done():
+				return
+			}
+		}
+	}()
+	return true
+}
+
+func (j *Join) respond(tran types.Transaction, res types.Response) bool {
+	select {
+	case tran.ResponseChan <- res:
+	case <-j.ctx.Done():
+		return false
+	}
+	return true
+}
+
+func (j *Join) expire(wg *sync.WaitGroup, all bool) bool {
+	expired, err := j.joiner.Expire(all)
+	if err != nil {
+		j.mErr.Incr(1)
+		j.log.Errorf("Failed to expire join state: %v\n", err)
+	}
+	if len(expired.parts) == 0 {
+		return true
+	}
+	j.mExpired.Incr(int64(len(expired.parts)))
+	return j.dispatch(wg, expired, nil)
+}
+
+func (j *Join) loop() {
+	var dispatchWG, inputsWG sync.WaitGroup
+	defer func() {
+		for _, in := range j.inputs {
+			in.CloseAsync()
+		}
+		inputsWG.Wait()
+		dispatchWG.Wait()
+		close(j.transactions)
+		close(j.closedChan)
+	}()
+
+	incoming := make(chan joinTransaction)
+	inputsDone := make(chan struct{})
+	for i, in := range j.inputs {
+		inputsWG.Add(1)
+		go func(index int, in Type) {
+			defer inputsWG.Done()
+			for {
+				select {
+				case tran, open := <-in.TransactionChan():
+					if !open {
+						return
+					}
+					select {
+					case incoming <- joinTransaction{index: index, tran: tran}:
+					case <-j.ctx.Done():
+						return
+					}
+				case <-j.ctx.Done():
+					return
+				}
+			}
+		}(i, in)
+	}
+	go func() {
+		inputsWG.Wait()
+		close(inputsDone)
+	}()
+
+	if len(j.undelivered.parts) > 0 {
+		j.log.Infof("Sending %v joined messages that were not delivered before a restart.\n", len(j.undelivered.parts))
+		if !j.dispatch(&dispatchWG, j.undelivered, nil) {
+			return
+		}
+		j.undelivered = joinResult{}
+	}
+
+	for {
+		var expireChan <-chan time.Time
+		if deadline, exists := j.joiner.NextDeadline(); exists {
+			expireChan = time.After(time.Until(deadline))
+		}
+
+		select {
+		case jt := <-incoming:
+			joined, err := j.joiner.Add(jt.index, jt.tran.Payload, func(err error) {
+				j.mDropped.Incr(1)
+				j.log.Debugf("Dropping message from input %v: %v\n", jt.index, err)
+			})
+			if err != nil {
+				j.mErr.Incr(1)
+				j.log.Errorf("Failed to update join state: %v\n", err)
+				if !j.respond(jt.tran, response.NewError(err)) {
+					return
+				}
+				continue
+			}
+			if len(joined.parts) == 0 {
+				if !j.respond(jt.tran, response.NewAck()) {
+					return
+				}
+				continue
+			}
+			j.mJoined.Incr(int64(len(joined.parts)))
+			tran := jt.tran
+			if !j.dispatch(&dispatchWG, joined, func() {
+				j.respond(tran, response.NewAck())
+			}) {
+				return
+			}
+		case <-expireChan:
+			if !j.expire(&dispatchWG, false) {
+				return
+			}
+		case <-inputsDone:
+			j.log.Infoln("All join inputs have terminated, flushing remaining state and shutting down.")
+			j.expire(&dispatchWG, true)
+			dispatchWG.Wait()
+			return
+		case <-j.ctx.Done():
+			return
+		}
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (j *Join) TransactionChan() <-chan types.Transaction {
+	return j.transactions
+}
+
+// Connected returns a boolean indicating whether all child inputs are
+// currently connected to their targets.
+func (j *Join) Connected() bool {
+	for _, in := range j.inputs {
+		if !in.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+// CloseAsync shuts down the Join input and stops processing requests.
+func (j *Join) CloseAsync() {
+	j.closeFn()
+}
+
+// WaitForClose blocks until the Join input and its child inputs have closed
+// down.
+func (j *Join) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	select {
+	case <-j.closedChan:
+	case <-time.After(time.Until(stopBy)):
+		return types.ErrTimeout
+	}
+	for _, in := range j.inputs {
+		if err := in.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
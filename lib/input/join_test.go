@@ -0,0 +1,452 @@
+package input
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type joinTTLCache struct {
+	types.Cache
+	failKey string
+}
+
+func newJoinTTLCache(t *testing.T) *joinTTLCache {
+	t.Helper()
+
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return &joinTTLCache{Cache: memCache}
+}
+
+func (c *joinTTLCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	if key == c.failKey {
+		return errors.New("nope")
+	}
+	return c.Set(key, value)
+}
+
+func (c *joinTTLCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := c.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *joinTTLCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return c.Add(key, value)
+}
+
+func newTestStreamJoiner(t *testing.T, joinType string, now *time.Time) (*streamJoiner, *joinTTLCache) {
+	t.Helper()
+
+	memCache := newJoinTTLCache(t)
+
+	keyMapping, err := bloblang.NewMapping("", "root = this.id")
+	require.NoError(t, err)
+
+	collisionFn, err := getMessageJoinerCollisionFn("replace")
+	require.NoError(t, err)
+
+	return &streamJoiner{
+		joinType:  joinType,
+		window:    time.Minute,
+		inputs:    2,
+		cacheName: "foocache",
+		prefix:    "join",
+		mgr: &fakeCacheMgr{
+			caches: map[string]types.Cache{
+				"foocache": memCache,
+			},
+		},
+		keyMapping:  keyMapping,
+		collisionFn: collisionFn,
+		pending:     map[string]time.Time{},
+		nowFn: func() time.Time {
+			return *now
+		},
+	}, memCache
+}
+
+func joinParts(parts []types.Part) []string {
+	var strs []string
+	for _, p := range parts {
+		strs = append(strs, string(p.Get()))
+	}
+	return strs
+}
+
+func TestStreamJoinerInner(t *testing.T) {
+	now := time.Unix(100, 0)
+	joiner, memCache := newTestStreamJoiner(t, "inner", &now)
+
+	var dropped []error
+	onDrop := func(err error) {
+		dropped = append(dropped, err)
+	}
+
+	joined, err := joiner.Add(0, message.New([][]byte{
+		[]byte(`{"id":"foo","order":1}`),
+		[]byte(`{"id":"bar","order":2}`),
+		[]byte(`not structured`),
+	}), onDrop)
+	require.NoError(t, err)
+	assert.Empty(t, joined.parts)
+	assert.Len(t, dropped, 1)
+
+	_, err = memCache.Get("join_key_foo")
+	require.NoError(t, err)
+
+	joined, err = joiner.Add(1, message.New([][]byte{
+		[]byte(`{"id":"foo","payment":"a"}`),
+	}), onDrop)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"foo","order":1,"payment":"a"}`}, joinParts(joined.parts))
+
+	_, err = memCache.Get("join_key_foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.Len(t, joined.keys, 1)
+	_, err = memCache.Get(joined.keys[0])
+	require.NoError(t, err, "joined state is kept until delivered")
+	require.NoError(t, joiner.Delivered(joined.keys))
+	_, err = memCache.Get(joined.keys[0])
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	expired, err := joiner.Expire(false)
+	require.NoError(t, err)
+	assert.Empty(t, expired.parts)
+
+	now = now.Add(time.Minute)
+	expired, err = joiner.Expire(false)
+	require.NoError(t, err)
+	assert.Empty(t, expired.parts)
+
+	_, err = memCache.Get("join_key_bar")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	_, exists := joiner.NextDeadline()
+	assert.False(t, exists)
+}
+
+func TestStreamJoinerLeftAndOuter(t *testing.T) {
+	for _, test := range []struct {
+		joinType string
+		expired  []string
+	}{
+		{joinType: "left", expired: []string{`{"id":"foo","order":1}`}},
+		{joinType: "outer", expired: []string{`{"id":"foo","order":1}`, `{"id":"bar","payment":"b"}`}},
+	} {
+		test := test
+		t.Run(test.joinType, func(t *testing.T) {
+			now := time.Unix(100, 0)
+			joiner, _ := newTestStreamJoiner(t, test.joinType, &now)
+
+			_, err := joiner.Add(0, message.New([][]byte{
+				[]byte(`{"id":"foo","order":1}`),
+			}), nil)
+			require.NoError(t, err)
+
+			now = now.Add(time.Second)
+			_, err = joiner.Add(1, message.New([][]byte{
+				[]byte(`{"id":"bar","payment":"b"}`),
+			}), nil)
+			require.NoError(t, err)
+
+			deadline, exists := joiner.NextDeadline()
+			require.True(t, exists)
+			assert.Equal(t, time.Unix(160, 0), deadline)
+
+			now = time.Unix(161, 0)
+			expired, err := joiner.Expire(false)
+			require.NoError(t, err)
+			assert.Equal(t, test.expired, joinParts(expired.parts))
+		})
+	}
+}
+
+func TestStreamJoinerSameInputMerge(t *testing.T) {
+	now := time.Unix(100, 0)
+	joiner, _ := newTestStreamJoiner(t, "outer", &now)
+
+	_, err := joiner.Add(0, message.New([][]byte{
+		[]byte(`{"id":"foo","a":1}`),
+	}), nil)
+	require.NoError(t, err)
+
+	_, err = joiner.Add(0, message.New([][]byte{
+		[]byte(`{"id":"foo","b":2}`),
+	}), nil)
+	require.NoError(t, err)
+
+	expired, err := joiner.Expire(true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"a":1,"b":2,"id":"foo"}`}, joinParts(expired.parts))
+}
+
+func TestStreamJoinerRestore(t *testing.T) {
+	now := time.Unix(100, 0)
+	joiner, memCache := newTestStreamJoiner(t, "outer", &now)
+
+	_, err := joiner.Add(0, message.New([][]byte{
+		[]byte(`{"id":"foo","order":1}`),
+	}), nil)
+	require.NoError(t, err)
+
+	now = now.Add(10 * time.Second)
+	_, err = joiner.Add(1, message.New([][]byte{
+		[]byte(`{"id":"bar","payment":"b"}`),
+	}), nil)
+	require.NoError(t, err)
+
+	restarted, _ := newTestStreamJoiner(t, "outer", &now)
+	restarted.mgr = joiner.mgr
+	undelivered, err := restarted.Restore()
+	require.NoError(t, err)
+	assert.Empty(t, undelivered.parts)
+
+	deadline, exists := restarted.NextDeadline()
+	require.True(t, exists)
+	assert.Equal(t, int64(160), deadline.Unix())
+
+	now = time.Unix(165, 0)
+	expired, err := restarted.Expire(false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"foo","order":1}`}, joinParts(expired.parts))
+
+	deadline, exists = restarted.NextDeadline()
+	require.True(t, exists)
+	assert.Equal(t, int64(170), deadline.Unix())
+
+	_, err = memCache.Get("join_key_foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestStreamJoinerRestoreUndelivered(t *testing.T) {
+	now := time.Unix(100, 0)
+	joiner, memCache := newTestStreamJoiner(t, "left", &now)
+
+	_, err := joiner.Add(0, message.New([][]byte{
+		[]byte(`{"id":"foo","order":1}`),
+		[]byte(`{"id":"bar","order":2}`),
+	}), nil)
+	require.NoError(t, err)
+
+	joined, err := joiner.Add(1, message.New([][]byte{
+		[]byte(`{"id":"foo","payment":"a"}`),
+	}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"foo","order":1,"payment":"a"}`}, joinParts(joined.parts))
+
+	now = time.Unix(165, 0)
+	expired, err := joiner.Expire(false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"bar","order":2}`}, joinParts(expired.parts))
+
+	_, err = memCache.Get("join_key_bar")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	restarted, _ := newTestStreamJoiner(t, "left", &now)
+	restarted.mgr = joiner.mgr
+	undelivered, err := restarted.Restore()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"id":"foo","order":1,"payment":"a"}`,
+		`{"id":"bar","order":2}`,
+	}, joinParts(undelivered.parts))
+
+	require.NoError(t, restarted.Delivered(undelivered.keys))
+	undelivered, err = restarted.Restore()
+	require.NoError(t, err)
+	assert.Empty(t, undelivered.parts)
+}
+
+func TestStreamJoinerPartialFailure(t *testing.T) {
+	now := time.Unix(100, 0)
+	joiner, memCache := newTestStreamJoiner(t, "inner", &now)
+
+	_, err := joiner.Add(0, message.New([][]byte{
+		[]byte(`{"id":"foo","order":1}`),
+	}), nil)
+	require.NoError(t, err)
+
+	batch := message.New([][]byte{
+		[]byte(`{"id":"foo","payment":"a"}`),
+		[]byte(`{"id":"bar","payment":"b"}`),
+	})
+
+	memCache.failKey = "join_key_bar"
+	_, err = joiner.Add(1, batch, nil)
+	require.Error(t, err)
+
+	_, err = memCache.Get("join_key_foo")
+	require.NoError(t, err, "state of the joined key is restored")
+	deadline, exists := joiner.NextDeadline()
+	require.True(t, exists)
+	assert.Equal(t, int64(160), deadline.Unix())
+
+	undelivered, err := joiner.Restore()
+	require.NoError(t, err)
+	assert.Empty(t, undelivered.parts, "delivery state of the failed batch is removed")
+
+	memCache.failKey = ""
+	joined, err := joiner.Add(1, batch, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"id":"foo","order":1,"payment":"a"}`}, joinParts(joined.parts))
+}
+
+func TestJoinInput(t *testing.T) {
+	memCache := newJoinTTLCache(t)
+
+	mgr := &fakeCacheMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	// Counters are global, so we use unique names for repeated runs.
+	counterSuffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	ordersConf := NewConfig()
+	ordersConf.Type = TypeGenerate
+	ordersConf.Generate.Count = 3
+	ordersConf.Generate.Interval = "1ms"
+	ordersConf.Generate.Mapping = `root = {"id":count("orders` + counterSuffix + `").string(),"order":true}`
+
+	paymentsConf := NewConfig()
+	paymentsConf.Type = TypeGenerate
+	paymentsConf.Generate.Count = 2
+	paymentsConf.Generate.Interval = "1ms"
+	paymentsConf.Generate.Mapping = `root = {"id":count("payments` + counterSuffix + `").string(),"payment":true}`
+
+	conf := NewConfig()
+	conf.Type = TypeJoin
+	conf.Join.Type = "left"
+	conf.Join.KeyMapping = "root = this.id"
+	conf.Join.Window = "1h"
+	conf.Join.Cache = "foocache"
+	conf.Join.Inputs = append(conf.Join.Inputs, ordersConf, paymentsConf)
+
+	rdr, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	var results []string
+consumeLoop:
+	for {
+		select {
+		case tran, open := <-rdr.TransactionChan():
+			if !open {
+				break consumeLoop
+			}
+			tran.Payload.Iter(func(i int, p types.Part) error {
+				results = append(results, string(p.Get()))
+				return nil
+			})
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("Failed to consume message after: %v", results)
+		}
+	}
+
+	sort.Strings(results)
+	assert.Equal(t, []string{
+		`{"id":"1","order":true,"payment":true}`,
+		`{"id":"2","order":true,"payment":true}`,
+		`{"id":"3","order":true}`,
+	}, results)
+
+	rdr.CloseAsync()
+	require.NoError(t, rdr.WaitForClose(time.Second))
+}
+
+func TestJoinConfigErrors(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mgr := &fakeCacheMgr{
+		caches: map[string]types.Cache{
+			"foocache": &joinTTLCache{Cache: memCache},
+			"barcache": memCache,
+		},
+	}
+
+	tests := map[string]struct {
+		conf        func(c *JoinConfig)
+		errContains string
+	}{
+		"too few inputs": {
+			conf: func(c *JoinConfig) {
+				c.Inputs = c.Inputs[:1]
+			},
+			errContains: "at least two child inputs",
+		},
+		"bad type": {
+			conf: func(c *JoinConfig) {
+				c.Type = "nope"
+			},
+			errContains: "join type 'nope' was not recognised",
+		},
+		"bad window": {
+			conf: func(c *JoinConfig) {
+				c.Window = "nope"
+			},
+			errContains: "failed to parse window",
+		},
+		"missing key mapping": {
+			conf: func(c *JoinConfig) {
+				c.KeyMapping = ""
+			},
+			errContains: "key mapping must be provided",
+		},
+		"missing cache": {
+			conf: func(c *JoinConfig) {
+				c.Cache = "bazcache"
+			},
+			errContains: "failed to obtain cache",
+		},
+		"cache without ttl": {
+			conf: func(c *JoinConfig) {
+				c.Cache = "barcache"
+			},
+			errContains: "does not support per-key TTLs",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			inConf := NewConfig()
+			inConf.Type = TypeGenerate
+			inConf.Generate.Mapping = `root = {"id":"foo"}`
+
+			conf := NewConfig()
+			conf.Type = TypeJoin
+			conf.Join.KeyMapping = "root = this.id"
+			conf.Join.Cache = "foocache"
+			conf.Join.Inputs = append(conf.Join.Inputs, inConf, inConf)
+			test.conf(&conf.Join)
+
+			_, err := New(conf, mgr, log.Noop(), metrics.Noop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -14,10 +15,10 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
-	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Jeffail/benthos/v3/lib/util/kafka/sasl"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 	"github.com/Shopify/sarama"
@@ -37,23 +38,19 @@ The Kafka input allows parallel processing of messages from different topic part
 
 Alternatively, if you perform batching at the input level using the ` + "[`batching`](#batching)" + ` field it is done per-partition and therefore avoids stalling.
 
-### Metadata
+### Topic Patterns
 
-This input adds the following metadata fields to each message:
+When the field ` + "`regexp_topics`" + ` is set to ` + "`true`" + ` each topic is instead treated as a regular expression, and the consumer group subscribes to all topics within the cluster that match any of the expressions. The list of matching topics is refreshed periodically according to ` + "`topic_refresh_period`" + `, and when it changes the consumer group is rejoined with the new topics.
 
-` + "``` text" + `
-- kafka_key
-- kafka_topic
-- kafka_partition
-- kafka_offset
-- kafka_lag
-- kafka_timestamp_unix
-- All existing message headers (version 0.11+)
-` + "```" + `
+### Explicit Partitions
 
-The field ` + "`kafka_lag`" + ` is the calculated difference between the high water mark offset of the partition at the time of ingestion and the current message offset.
+When explicit partitions are listed the consumer group is not used to balance partitions, but offsets are still committed under the consumer group by default. For replay jobs it's possible to avoid a consumer group altogether by setting the field ` + "`checkpoint_cache`" + ` to the name of a [cache resource](/docs/components/caches/about), in which case offsets are stored within the cache under keys of the form ` + "`<client_id>:<topic>:<partition>`" + `.
 
-You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
+### Starting From a Timestamp
+
+The field ` + "`start_from_timestamp`" + ` can be used in order to begin consuming each partition that does not yet have a committed offset from the earliest message with a timestamp at or after the one given. When consuming as a consumer group the offset of each such partition is resolved when it is first claimed, and is committed under the consumer group.
+
+` + kafka.MetadataDescription,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return sanitiseWithBatch(conf.Kafka, conf.Kafka.Batching)
 		},
@@ -64,14 +61,18 @@ You can access these metadata fields using [function interpolation](/docs/config
 			),
 			docs.FieldCommon(
 				"topics",
-				"A list of topics to consume from. Multiple comma separated topics can be listed in a single element. Partitions are automatically distributed across consumers of a topic. Alternatively, it's possible to specify an explicit partition to consume from with a colon after the topic name, e.g. `foo:0` would consume the partition 0 of the topic foo.",
-				[]string{"foo", "bar"}, []string{"foo,bar"}, []string{"foo:0", "bar:1", "bar:3"}, []string{"foo:0,bar:1,bar:3"},
+				"A list of topics to consume from. Multiple comma separated topics can be listed in a single element. Partitions are automatically distributed across consumers of a topic. Alternatively, it's possible to specify an explicit partition to consume from with a colon after the topic name, e.g. `foo:0` would consume the partition 0 of the topic foo, or an inclusive range of partitions, e.g. `foo:0-3` would consume partitions 0 to 3 of the topic foo.",
+				[]string{"foo", "bar"}, []string{"foo,bar"}, []string{"foo:0", "bar:1", "bar:3"}, []string{"foo:0,bar:1,bar:3"}, []string{"foo:0-3"},
 			).AtVersion("3.33.0"),
+			docs.FieldAdvanced("regexp_topics", "Whether listed topics should be interpreted as regular expression patterns for matching multiple topics. Explicit partitions cannot be specified when this is enabled.").AtVersion("3.42.0"),
+			docs.FieldAdvanced("topic_refresh_period", "The period of time between each refresh of the topics that match the patterns specified when `regexp_topics` is enabled.").AtVersion("3.42.0"),
 			btls.FieldSpec(),
 			sasl.FieldSpec(),
 			docs.FieldCommon("consumer_group", "An identifier for the consumer group of the connection."),
 			docs.FieldCommon("client_id", "An identifier for the client connection."),
 			docs.FieldAdvanced("start_from_oldest", "If an offset is not found for a topic parition, determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset."),
+			docs.FieldAdvanced("start_from_timestamp", "An optional timestamp, either in RFC 3339 format or as a unix timestamp in seconds, from which to begin consuming partitions that do not have a committed offset. This takes precedence over `start_from_oldest`.", "2021-06-01T00:00:00Z", "1622505600").AtVersion("3.42.0"),
+			docs.FieldAdvanced("checkpoint_cache", "An optional [cache resource](/docs/components/caches/about) to store the offsets of explicit partitions within, instead of committing them under a consumer group.").AtVersion("3.42.0"),
 			docs.FieldCommon(
 				"checkpoint_limit", "EXPERIMENTAL: The maximum number of messages of the same topic and partition that can be processed at a given time. Increasing this limit enables parallel processing and batching at the output level to work on individual partitions. Any given offset will not be committed unless all messages under that offset are delivered in order to preserve at least once delivery guarantees.",
 			).AtVersion("3.33.0"),
@@ -150,12 +151,16 @@ type kafkaReader struct {
 
 	topicPartitions map[string][]int32
 	balancedTopics  []string
+	topicPatterns   []*regexp.Regexp
+
+	startFromTimestamp *time.Time
 
-	commitPeriod      time.Duration
-	sessionTimeout    time.Duration
-	heartbeatInterval time.Duration
-	rebalanceTimeout  time.Duration
-	maxProcPeriod     time.Duration
+	topicRefreshPeriod time.Duration
+	commitPeriod       time.Duration
+	sessionTimeout     time.Duration
+	heartbeatInterval  time.Duration
+	rebalanceTimeout   time.Duration
+	maxProcPeriod      time.Duration
 
 	// Connection resources
 	cMut            sync.Mutex
@@ -164,7 +169,9 @@ type kafkaReader struct {
 	msgChan         chan asyncMessage
 	session         offsetMarker
 
-	mRebalanced metrics.StatCounter
+	mRebalanced    metrics.StatCounter
+	mPartitionRcvd metrics.StatCounterVec
+	mPartitionLag  metrics.StatGaugeVec
 
 	conf  reader.KafkaConfig
 	stats metrics.Type
@@ -190,6 +197,8 @@ func newKafkaReader(
 		log:             log,
 		mgr:             mgr,
 		mRebalanced:     stats.GetCounter("rebalanced"),
+		mPartitionRcvd:  stats.GetCounterVec("partition.received", []string{"topic", "partition"}),
+		mPartitionLag:   stats.GetGaugeVec("partition.lag", []string{"topic", "partition"}),
 		closedChan:      make(chan struct{}),
 		topicPartitions: map[string][]int32{},
 	}
@@ -212,7 +221,13 @@ func newKafkaReader(
 	for _, t := range conf.Topics {
 		for _, splitTopics := range strings.Split(t, ",") {
 			if trimmed := strings.TrimSpace(splitTopics); len(trimmed) > 0 {
-				if withParts := strings.Split(trimmed, ":"); len(withParts) > 1 {
+				if conf.RegexpTopics {
+					re, err := regexp.Compile(trimmed)
+					if err != nil {
+						return nil, fmt.Errorf("failed to compile topic pattern '%v': %w", trimmed, err)
+					}
+					k.topicPatterns = append(k.topicPatterns, re)
+				} else if withParts := strings.Split(trimmed, ":"); len(withParts) > 1 {
 					if len(k.balancedTopics) > 0 {
 						return nil, errCannotMixBalanced
 					}
@@ -220,11 +235,11 @@ func newKafkaReader(
 						return nil, fmt.Errorf("topic '%v' is invalid, only one partition should be specified and the same topic can be listed multiple times, e.g. use `foo:0,foo:1` not `foo:0:1`", trimmed)
 					}
 					topic := strings.TrimSpace(withParts[0])
-					partition, err := strconv.ParseInt(withParts[1], 10, 32)
+					partitions, err := parsePartitionRange(withParts[1])
 					if err != nil {
-						return nil, fmt.Errorf("failed to parse partition number: %w", err)
+						return nil, err
 					}
-					k.topicPartitions[topic] = append(k.topicPartitions[topic], int32(partition))
+					k.topicPartitions[topic] = append(k.topicPartitions[topic], partitions...)
 				} else {
 					if len(k.topicPartitions) > 0 {
 						return nil, errCannotMixBalanced
@@ -234,6 +249,30 @@ func newKafkaReader(
 			}
 		}
 	}
+	if conf.StartFromTimestamp != "" {
+		ts, err := kafka.ParseTimestamp(conf.StartFromTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start_from_timestamp: %w", err)
+		}
+		k.startFromTimestamp = &ts
+	}
+	if conf.CheckpointCache != "" {
+		if len(k.topicPartitions) == 0 {
+			return nil, errors.New("the field checkpoint_cache can only be used with explicit topic partitions")
+		}
+		if _, err := mgr.GetCache(conf.CheckpointCache); err != nil {
+			return nil, fmt.Errorf("failed to obtain checkpoint cache: %w", err)
+		}
+	}
+	if tout := conf.TopicRefreshPeriod; len(tout) > 0 {
+		var err error
+		if k.topicRefreshPeriod, err = time.ParseDuration(tout); err != nil {
+			return nil, fmt.Errorf("failed to parse topic refresh period string: %v", err)
+		}
+	}
+	if len(k.topicPatterns) > 0 && k.topicRefreshPeriod <= 0 {
+		return nil, errors.New("topic_refresh_period must be greater than zero when regexp_topics is enabled")
+	}
 	if tout := conf.CommitPeriod; len(tout) > 0 {
 		var err error
 		if k.commitPeriod, err = time.ParseDuration(tout); err != nil {
@@ -274,7 +313,38 @@ func newKafkaReader(
 
 //------------------------------------------------------------------------------
 
-func (k *kafkaReader) asyncCheckpointer(topic string, partition int32) func(context.Context, chan<- asyncMessage, types.Message, int64) bool {
+// parsePartitionRange parses either a single partition number or an inclusive
+// range of partitions in the form `N-M`.
+func parsePartitionRange(str string) ([]int32, error) {
+	rangeParts := strings.Split(str, "-")
+	if len(rangeParts) > 2 {
+		return nil, fmt.Errorf("partition '%v' is invalid, only one range can be specified", str)
+	}
+
+	lower, err := strconv.ParseInt(strings.TrimSpace(rangeParts[0]), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse partition number: %w", err)
+	}
+	upper := lower
+	if len(rangeParts) == 2 {
+		if upper, err = strconv.ParseInt(strings.TrimSpace(rangeParts[1]), 10, 32); err != nil {
+			return nil, fmt.Errorf("failed to parse partition number: %w", err)
+		}
+		if upper < lower {
+			return nil, fmt.Errorf("partition range '%v' is invalid, lower bound is greater than upper bound", str)
+		}
+	}
+
+	partitions := make([]int32, 0, upper-lower+1)
+	for i := lower; i <= upper; i++ {
+		partitions = append(partitions, int32(i))
+	}
+	return partitions, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (k *kafkaReader) asyncCheckpointer(topic string, partition int32, lag *kafka.PartitionLag) func(context.Context, chan<- asyncMessage, types.Message, int64) bool {
 	cp := checkpoint.NewCapped(k.conf.CheckpointLimit)
 	return func(ctx context.Context, c chan<- asyncMessage, msg types.Message, offset int64) bool {
 		if msg == nil {
@@ -298,6 +368,7 @@ func (k *kafkaReader) asyncCheckpointer(topic string, partition int32) func(cont
 				if k.session != nil {
 					k.log.Debugf("Marking offset for topic '%v' partition '%v'.\n", topic, partition)
 					k.session.MarkOffset(topic, partition, int64(maxOffset), "")
+					lag.Committed(int64(maxOffset))
 				} else {
 					k.log.Debugf("Unable to mark offset for topic '%v' partition '%v'.\n", topic, partition)
 				}
@@ -312,7 +383,7 @@ func (k *kafkaReader) asyncCheckpointer(topic string, partition int32) func(cont
 	}
 }
 
-func (k *kafkaReader) syncCheckpointer(topic string, partition int32) func(context.Context, chan<- asyncMessage, types.Message, int64) bool {
+func (k *kafkaReader) syncCheckpointer(topic string, partition int32, lag *kafka.PartitionLag) func(context.Context, chan<- asyncMessage, types.Message, int64) bool {
 	ackedChan := make(chan error)
 	return func(ctx context.Context, c chan<- asyncMessage, msg types.Message, offset int64) bool {
 		if msg == nil {
@@ -328,6 +399,7 @@ func (k *kafkaReader) syncCheckpointer(topic string, partition int32) func(conte
 					if k.session != nil {
 						k.log.Debugf("Marking offset for topic '%v' partition '%v'.\n", topic, partition)
 						k.session.MarkOffset(topic, partition, offset, "")
+						lag.Committed(offset)
 					} else {
 						k.log.Debugf("Unable to mark offset for topic '%v' partition '%v'.\n", topic, partition)
 					}
@@ -356,29 +428,6 @@ func (k *kafkaReader) syncCheckpointer(topic string, partition int32) func(conte
 	}
 }
 
-func dataToPart(highestOffset int64, data *sarama.ConsumerMessage) types.Part {
-	part := message.NewPart(data.Value)
-
-	meta := part.Metadata()
-	for _, hdr := range data.Headers {
-		meta.Set(string(hdr.Key), string(hdr.Value))
-	}
-
-	lag := highestOffset - data.Offset - 1
-	if lag < 0 {
-		lag = 0
-	}
-
-	meta.Set("kafka_key", string(data.Key))
-	meta.Set("kafka_partition", strconv.Itoa(int(data.Partition)))
-	meta.Set("kafka_topic", data.Topic)
-	meta.Set("kafka_offset", strconv.Itoa(int(data.Offset)))
-	meta.Set("kafka_lag", strconv.FormatInt(lag, 10))
-	meta.Set("kafka_timestamp_unix", strconv.FormatInt(data.Timestamp.Unix(), 10))
-
-	return part
-}
-
 //------------------------------------------------------------------------------
 
 func (k *kafkaReader) closeGroupAndConsumers() {
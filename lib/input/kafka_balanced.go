@@ -7,6 +7,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Jeffail/benthos/v3/lib/util/kafka/sasl"
 	"github.com/Jeffail/benthos/v3/lib/util/tls"
 )
@@ -37,26 +38,7 @@ due its sequential transaction model.
 
 The functionality of this input is now covered by the general ` + "[`kafka` input](/docs/components/inputs/kafka)" + `.
 
-### Metadata
-
-This input adds the following metadata fields to each message:
-
-` + "``` text" + `
-- kafka_key
-- kafka_topic
-- kafka_partition
-- kafka_offset
-- kafka_lag
-- kafka_timestamp_unix
-- All existing message headers (version 0.11+)
-` + "```" + `
-
-The field ` + "`kafka_lag`" + ` is the calculated difference between the high
-water mark offset of the partition at the time of ingestion and the current
-message offset.
-
-You can access these metadata fields using
-[function interpolation](/docs/configuration/interpolation#metadata).`,
+` + kafka.MetadataDescription,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return sanitiseWithBatch(conf.KafkaBalanced, conf.KafkaBalanced.Batching)
 		},
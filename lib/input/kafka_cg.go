@@ -2,11 +2,16 @@ package input
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Shopify/sarama"
 )
 
@@ -37,6 +42,14 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 	defer k.log.Debugf("Stopped consuming messages from topic '%v' partition '%v'\n", topic, partition)
 
 	latestOffset := claim.InitialOffset()
+	partStr := strconv.Itoa(int(partition))
+	mRcvd := k.mPartitionRcvd.With(topic, partStr)
+
+	lag := kafka.NewPartitionLag(claim.InitialOffset(), claim.HighWaterMarkOffset, k.mPartitionLag.With(topic, partStr))
+	lagCtx, lagDone := context.WithCancel(sess.Context())
+	defer lagDone()
+	go lag.Run(lagCtx)
+
 	batchPolicy, err := batch.NewPolicy(k.conf.Batching, k.mgr, k.log, k.stats)
 	if err != nil {
 		k.log.Errorf("Failed to initialise batch policy: %v.\n", err)
@@ -50,9 +63,9 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 	var nextTimedBatchChan <-chan time.Time
 	var flushBatch func(context.Context, chan<- asyncMessage, types.Message, int64) bool
 	if k.conf.CheckpointLimit > 1 {
-		flushBatch = k.asyncCheckpointer(claim.Topic(), claim.Partition())
+		flushBatch = k.asyncCheckpointer(claim.Topic(), claim.Partition(), lag)
 	} else {
-		flushBatch = k.syncCheckpointer(claim.Topic(), claim.Partition())
+		flushBatch = k.syncCheckpointer(claim.Topic(), claim.Partition(), lag)
 	}
 
 	for {
@@ -73,7 +86,9 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 			}
 
 			latestOffset = data.Offset
-			part := dataToPart(claim.HighWaterMarkOffset(), data)
+			part := kafka.MessageToPart(claim.HighWaterMarkOffset(), data)
+			mRcvd.Incr(1)
+			lag.Consumed(data.Offset)
 
 			if batchPolicy.Add(part) {
 				nextTimedBatchChan = nil
@@ -89,10 +104,145 @@ func (k *kafkaReader) ConsumeClaim(sess sarama.ConsumerGroupSession, claim saram
 
 //------------------------------------------------------------------------------
 
+// timestampGroupHandler is a consumer group handler that, before consuming the
+// partitions claimed by a session, sets the offsets of those without a
+// committed offset according to start_from_timestamp.
+type timestampGroupHandler struct {
+	*kafkaReader
+	client sarama.Client
+}
+
+func (t timestampGroupHandler) Setup(sesh sarama.ConsumerGroupSession) error {
+	coordinator, err := t.client.Coordinator(t.conf.ConsumerGroup)
+	if err != nil {
+		return err
+	}
+	store := &groupOffsetStore{
+		coordinator: coordinator,
+		group:       t.conf.ConsumerGroup,
+		clientID:    t.conf.ClientID,
+		log:         t.log,
+	}
+	if err = t.markClaimsFromTimestamp(sesh, store, t.client); err != nil {
+		return err
+	}
+	return t.kafkaReader.Setup(sesh)
+}
+
+// markClaimsFromTimestamp marks the offset of each claimed partition that
+// doesn't have a committed offset as the earliest offset with a timestamp at
+// or after start_from_timestamp, which is where the session then begins
+// consuming the partition from.
+func (k *kafkaReader) markClaimsFromTimestamp(sesh sarama.ConsumerGroupSession, store explicitOffsetStore, client sarama.Client) error {
+	committed, err := store.Fetch(sesh.Claims())
+	if err != nil {
+		return err
+	}
+	for topic, partitions := range sesh.Claims() {
+		for _, partition := range partitions {
+			if _, exists := committed.get(topic, partition); exists {
+				continue
+			}
+			offset, err := offsetFromTimestamp(client, topic, partition, *k.startFromTimestamp)
+			if err != nil {
+				return fmt.Errorf("failed to resolve offset from timestamp for topic %v partition %v: %v", topic, partition, err)
+			}
+			k.log.Debugf("Starting topic '%v' partition '%v' from offset '%v'.\n", topic, partition, offset)
+			sesh.MarkOffset(topic, partition, offset, "")
+		}
+	}
+	return nil
+}
+
+// offsetFromTimestamp resolves the earliest offset of a partition with a
+// timestamp equal to or greater than ts, or the newest offset if there are
+// none.
+func offsetFromTimestamp(client sarama.Client, topic string, partition int32, ts time.Time) (int64, error) {
+	offset, err := client.GetOffset(topic, partition, ts.UnixNano()/int64(time.Millisecond))
+	if err != nil {
+		return 0, err
+	}
+	if offset < 0 {
+		offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+	}
+	return offset, err
+}
+
+//------------------------------------------------------------------------------
+
+// resolveTopics returns the list of topics to consume from the consumer group,
+// which when topic patterns are configured is the sorted list of topics within
+// the cluster that match any pattern.
+func (k *kafkaReader) resolveTopics(client sarama.Client) ([]string, error) {
+	if len(k.topicPatterns) == 0 {
+		return k.balancedTopics, nil
+	}
+	if err := client.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+	allTopics, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+	var topics []string
+	for _, topic := range allTopics {
+		for _, re := range k.topicPatterns {
+			if re.MatchString(topic) {
+				topics = append(topics, topic)
+				break
+			}
+		}
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// watchTopics periodically resolves the topics matching our patterns and
+// calls changedFn when they differ from the topics being consumed.
+func (k *kafkaReader) watchTopics(ctx context.Context, client sarama.Client, topics []string, changedFn func()) {
+	ticker := time.NewTicker(k.topicRefreshPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		newTopics, err := k.resolveTopics(client)
+		if err != nil {
+			k.log.Errorf("Failed to refresh matching topics: %v\n", err)
+			continue
+		}
+		if !stringSlicesEqual(topics, newTopics) {
+			k.log.Infof("Matching topics have changed from %v to %v, rejoining consumer group\n", topics, newTopics)
+			changedFn()
+			return
+		}
+	}
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, v := range a {
+		if b[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
 func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.Config) error {
+	client, err := sarama.NewClient(k.addresses, config)
+	if err != nil {
+		return err
+	}
+
 	// Start a new consumer group
-	group, err := sarama.NewConsumerGroup(k.addresses, k.conf.ConsumerGroup, config)
+	group, err := sarama.NewConsumerGroupFromClient(k.conf.ConsumerGroup, client)
 	if err != nil {
+		client.Close()
 		return err
 	}
 
@@ -115,6 +265,11 @@ func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.
 		}
 	}()
 
+	var handler sarama.ConsumerGroupHandler = k
+	if k.startFromTimestamp != nil {
+		handler = timestampGroupHandler{kafkaReader: k, client: client}
+	}
+
 	consumerDoneCtx, finishedFn := context.WithCancel(context.Background())
 	go func() {
 		defer finishedFn()
@@ -126,8 +281,29 @@ func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.
 			k.consumerCloseFn = doneFn
 			k.cMut.Unlock()
 
+			topics, terr := k.resolveTopics(client)
+			if terr == nil && len(topics) == 0 {
+				terr = errors.New("no topics match the configured patterns")
+			}
+			if terr != nil {
+				k.log.Errorf("Failed to resolve topics: %v\n", terr)
+				select {
+				case <-time.After(k.topicRefreshPeriod):
+				case <-ctx.Done():
+					break groupLoop
+				}
+				doneFn()
+				continue
+			}
+
+			sessCtx, sessDoneFn := context.WithCancel(ctx)
+			if len(k.topicPatterns) > 0 {
+				go k.watchTopics(sessCtx, client, topics, sessDoneFn)
+			}
+
 			k.log.Debugln("Starting consumer group")
-			gerr := group.Consume(ctx, k.balancedTopics, k)
+			gerr := group.Consume(sessCtx, topics, handler)
+			sessDoneFn()
 			select {
 			case <-ctx.Done():
 				break groupLoop
@@ -144,6 +320,7 @@ func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.
 		k.log.Debugln("Closing consumer group")
 
 		group.Close()
+		client.Close()
 
 		k.cMut.Lock()
 		if k.msgChan != nil {
@@ -155,6 +332,10 @@ func (k *kafkaReader) connectBalancedTopics(ctx context.Context, config *sarama.
 
 	k.msgChan = make(chan asyncMessage)
 	k.consumerDoneCtx = consumerDoneCtx
-	k.log.Infof("Consuming kafka topics %v from brokers %s as group '%v'\n", k.balancedTopics, k.addresses, k.conf.ConsumerGroup)
+	if len(k.topicPatterns) > 0 {
+		k.log.Infof("Consuming kafka topics matching %v from brokers %s as group '%v'\n", k.conf.Topics, k.addresses, k.conf.ConsumerGroup)
+	} else {
+		k.log.Infof("Consuming kafka topics %v from brokers %s as group '%v'\n", k.balancedTopics, k.addresses, k.conf.ConsumerGroup)
+	}
 	return nil
 }
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Shopify/sarama"
 )
 
@@ -44,12 +47,20 @@ func (k *kafkaReader) runPartitionConsumer(
 	}
 	defer batchPolicy.CloseAsync()
 
+	partStr := strconv.Itoa(int(partition))
+	mRcvd := k.mPartitionRcvd.With(topic, partStr)
+
+	lag := kafka.NewPartitionLag(-1, consumer.HighWaterMarkOffset, k.mPartitionLag.With(topic, partStr))
+	lagCtx, lagDone := context.WithCancel(ctx)
+	defer lagDone()
+	go lag.Run(lagCtx)
+
 	var nextTimedBatchChan <-chan time.Time
 	var flushBatch func(context.Context, chan<- asyncMessage, types.Message, int64) bool
 	if k.conf.CheckpointLimit > 1 {
-		flushBatch = k.asyncCheckpointer(topic, partition)
+		flushBatch = k.asyncCheckpointer(topic, partition, lag)
 	} else {
-		flushBatch = k.syncCheckpointer(topic, partition)
+		flushBatch = k.syncCheckpointer(topic, partition, lag)
 	}
 
 	var latestOffset int64
@@ -74,7 +85,9 @@ partMsgLoop:
 			k.log.Tracef("Received message from topic %v partition %v\n", topic, partition)
 
 			latestOffset = data.Offset
-			part := dataToPart(consumer.HighWaterMarkOffset(), data)
+			part := kafka.MessageToPart(consumer.HighWaterMarkOffset(), data)
+			mRcvd.Incr(1)
+			lag.Consumed(data.Offset)
 
 			if batchPolicy.Add(part) {
 				nextTimedBatchChan = nil
@@ -100,8 +113,162 @@ partMsgLoop:
 	}
 }
 
+//------------------------------------------------------------------------------
+
+// partitionOffsets maps topics and their partitions to offsets.
+type partitionOffsets map[string]map[int32]int64
+
+func (p partitionOffsets) set(topic string, partition int32, offset int64) {
+	parts, exists := p[topic]
+	if !exists {
+		parts = map[int32]int64{}
+		p[topic] = parts
+	}
+	parts[partition] = offset
+}
+
+func (p partitionOffsets) get(topic string, partition int32) (int64, bool) {
+	offset, exists := p[topic][partition]
+	return offset, exists
+}
+
+// explicitOffsetStore fetches and commits the offsets of explicitly consumed
+// topic partitions.
+type explicitOffsetStore interface {
+	Fetch(topicPartitions map[string][]int32) (partitionOffsets, error)
+	Commit(offsets partitionOffsets) error
+	Close()
+}
+
+// groupOffsetStore stores offsets within Kafka under a consumer group.
+type groupOffsetStore struct {
+	coordinator *sarama.Broker
+	group       string
+	clientID    string
+	log         log.Modular
+}
+
+func (g *groupOffsetStore) Fetch(topicPartitions map[string][]int32) (partitionOffsets, error) {
+	offsetGetReq := sarama.OffsetFetchRequest{
+		ConsumerGroup: g.group,
+	}
+	for topic, parts := range topicPartitions {
+		for _, part := range parts {
+			offsetGetReq.AddPartition(topic, part)
+		}
+	}
+
+	offsets := partitionOffsets{}
+	offsetRes, err := g.coordinator.FetchOffset(&offsetGetReq)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return offsets, nil
+		}
+		return nil, fmt.Errorf("failed to acquire offsets from broker: %v", err)
+	}
+
+	for topic, parts := range topicPartitions {
+		for _, part := range parts {
+			block := offsetRes.GetBlock(topic, part)
+			if block == nil {
+				g.log.Debugf("Failed to acquire offset for topic %v partition %v\n", topic, part)
+				continue
+			}
+			if block.Err != sarama.ErrNoError {
+				g.log.Debugf("Failed to acquire offset for topic %v partition %v: %v\n", topic, part, block.Err)
+				continue
+			}
+			if block.Offset >= 0 {
+				offsets.set(topic, part, block.Offset)
+			}
+		}
+	}
+	return offsets, nil
+}
+
+func (g *groupOffsetStore) Commit(offsets partitionOffsets) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	offsetPutReq := &sarama.OffsetCommitRequest{
+		ConsumerGroup: g.group,
+		ConsumerID:    g.clientID,
+	}
+	for topic, parts := range offsets {
+		for part, offset := range parts {
+			offsetPutReq.AddBlock(topic, part, offset, time.Now().Unix(), "")
+		}
+	}
+	_, err := g.coordinator.CommitOffset(offsetPutReq)
+	return err
+}
+
+func (g *groupOffsetStore) Close() {
+	g.coordinator.Close()
+}
+
+// cacheOffsetStore stores offsets within a cache resource, keyed by the client
+// ID, topic and partition.
+type cacheOffsetStore struct {
+	mgr      types.Manager
+	cache    string
+	clientID string
+}
+
+func (c *cacheOffsetStore) key(topic string, partition int32) string {
+	return fmt.Sprintf("%v:%v:%v", c.clientID, topic, partition)
+}
+
+func (c *cacheOffsetStore) Fetch(topicPartitions map[string][]int32) (partitionOffsets, error) {
+	cache, err := c.mgr.GetCache(c.cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain checkpoint cache: %w", err)
+	}
+
+	offsets := partitionOffsets{}
+	for topic, parts := range topicPartitions {
+		for _, part := range parts {
+			key := c.key(topic, part)
+			offsetBytes, err := cache.Get(key)
+			if err != nil {
+				if errors.Is(err, types.ErrKeyNotFound) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to acquire offset '%v' from cache: %w", key, err)
+			}
+			offset, err := strconv.ParseInt(string(offsetBytes), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse offset '%v' from cache: %w", key, err)
+			}
+			offsets.set(topic, part, offset)
+		}
+	}
+	return offsets, nil
+}
+
+func (c *cacheOffsetStore) Commit(offsets partitionOffsets) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	cache, err := c.mgr.GetCache(c.cache)
+	if err != nil {
+		return fmt.Errorf("failed to obtain checkpoint cache: %w", err)
+	}
+	items := map[string][]byte{}
+	for topic, parts := range offsets {
+		for part, offset := range parts {
+			items[c.key(topic, part)] = []byte(strconv.FormatInt(offset, 10))
+		}
+	}
+	return cache.SetMulti(items)
+}
+
+func (c *cacheOffsetStore) Close() {}
+
+//------------------------------------------------------------------------------
+
 func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.Config) error {
-	var coordinator *sarama.Broker
+	var store explicitOffsetStore
 	var consumer sarama.Consumer
 	var client sarama.Client
 	var err error
@@ -111,8 +278,8 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 			if consumer != nil {
 				consumer.Close()
 			}
-			if coordinator != nil {
-				coordinator.Close()
+			if store != nil {
+				store.Close()
 			}
 			if client != nil {
 				client.Close()
@@ -123,41 +290,40 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 	if client, err = sarama.NewClient(k.addresses, config); err != nil {
 		return err
 	}
-	if coordinator, err = client.Coordinator(k.conf.ConsumerGroup); err != nil {
-		return err
+	if k.conf.CheckpointCache != "" {
+		store = &cacheOffsetStore{
+			mgr:      k.mgr,
+			cache:    k.conf.CheckpointCache,
+			clientID: k.conf.ClientID,
+		}
+	} else {
+		var coordinator *sarama.Broker
+		if coordinator, err = client.Coordinator(k.conf.ConsumerGroup); err != nil {
+			return err
+		}
+		store = &groupOffsetStore{
+			coordinator: coordinator,
+			group:       k.conf.ConsumerGroup,
+			clientID:    k.conf.ClientID,
+			log:         k.log,
+		}
 	}
 	if consumer, err = sarama.NewConsumerFromClient(client); err != nil {
 		return err
 	}
 
-	offsetGetReq := sarama.OffsetFetchRequest{
-		ConsumerGroup: k.conf.ConsumerGroup,
-	}
-	for topic, parts := range k.topicPartitions {
-		for _, part := range parts {
-			offsetGetReq.AddPartition(topic, part)
-		}
-	}
-
-	var offsetRes *sarama.OffsetFetchResponse
-	if offsetRes, err = coordinator.FetchOffset(&offsetGetReq); err != nil {
-		if errors.Is(err, io.EOF) {
-			offsetRes = &sarama.OffsetFetchResponse{}
-		} else {
-			return fmt.Errorf("failed to acquire offsets from broker: %v", err)
-		}
+	var committed partitionOffsets
+	if committed, err = store.Fetch(k.topicPartitions); err != nil {
+		return err
 	}
 
-	offsetPutReq := &sarama.OffsetCommitRequest{
-		ConsumerGroup: k.conf.ConsumerGroup,
-		ConsumerID:    k.conf.ClientID,
-	}
+	pendingOffsets := partitionOffsets{}
 	offsetTracker := &closureOffsetTracker{
 		// Note: We don't need to wrap this call in a mutex lock because the
 		// checkpointer that uses it already does this, but it's not
 		// particularly clear, hence this comment.
 		fn: func(topic string, partition int32, offset int64, metadata string) {
-			offsetPutReq.AddBlock(topic, partition, offset, time.Now().Unix(), metadata)
+			pendingOffsets.set(topic, partition, offset)
 		},
 	}
 
@@ -172,14 +338,13 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 			if k.conf.StartFromOldest {
 				offset = sarama.OffsetOldest
 			}
-			if block := offsetRes.GetBlock(topic, partition); block != nil {
-				if block.Err == sarama.ErrNoError {
-					offset = block.Offset
-				} else {
-					k.log.Debugf("Failed to acquire offset for topic %v partition %v: %v\n", topic, partition, block.Err)
+			if committedOffset, exists := committed.get(topic, partition); exists {
+				offset = committedOffset
+			} else if k.startFromTimestamp != nil {
+				if offset, err = offsetFromTimestamp(client, topic, partition, *k.startFromTimestamp); err != nil {
+					doneFn()
+					return fmt.Errorf("failed to resolve offset from timestamp for topic %v partition %v: %v", topic, partition, err)
 				}
-			} else {
-				k.log.Debugf("Failed to acquire offset for topic %v partition %v\n", topic, partition)
 			}
 
 			var partConsumer sarama.PartitionConsumer
@@ -202,7 +367,11 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 			go k.runPartitionConsumer(ctx, &consumerWG, topic, partition, partConsumer)
 		}
 
-		k.log.Infof("Consuming kafka topic %v, partitions %v from brokers %s as group '%v'\n", topic, partitions, k.addresses, k.conf.ConsumerGroup)
+		if k.conf.CheckpointCache != "" {
+			k.log.Infof("Consuming kafka topic %v, partitions %v from brokers %s with offsets stored in cache '%v'\n", topic, partitions, k.addresses, k.conf.CheckpointCache)
+		} else {
+			k.log.Infof("Consuming kafka topic %v, partitions %v from brokers %s as group '%v'\n", topic, partitions, k.addresses, k.conf.ConsumerGroup)
+		}
 	}
 
 	doneCtx, doneFn := context.WithCancel(context.Background())
@@ -216,20 +385,17 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 			case <-time.After(k.commitPeriod):
 			}
 			k.cMut.Lock()
-			putReq := offsetPutReq
-			offsetPutReq = &sarama.OffsetCommitRequest{
-				ConsumerGroup: k.conf.ConsumerGroup,
-				ConsumerID:    k.conf.ClientID,
-			}
+			putOffsets := pendingOffsets
+			pendingOffsets = partitionOffsets{}
 			k.cMut.Unlock()
-			if _, err := coordinator.CommitOffset(putReq); err != nil {
+			if err := store.Commit(putOffsets); err != nil {
 				k.log.Errorf("Failed to commit offsets: %v\n", err)
 			}
 		}
 		for _, consumer := range partConsumers {
 			consumer.AsyncClose()
 		}
-		consumerWG.Done()
+		consumerWG.Wait()
 
 		k.cMut.Lock()
 		if k.msgChan != nil {
@@ -238,7 +404,7 @@ func (k *kafkaReader) connectExplicitTopics(ctx context.Context, config *sarama.
 		}
 		k.cMut.Unlock()
 
-		coordinator.Close()
+		store.Close()
 		client.Close()
 	}()
 
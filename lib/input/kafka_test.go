@@ -0,0 +1,281 @@
+package input
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCacheMgr struct {
+	types.DudMgr
+	caches map[string]types.Cache
+}
+
+func (f *fakeCacheMgr) GetCache(name string) (types.Cache, error) {
+	if c, exists := f.caches[name]; exists {
+		return c, nil
+	}
+	return nil, types.ErrCacheNotFound
+}
+
+func TestKafkaPartitionRange(t *testing.T) {
+	parts, err := parsePartitionRange("3")
+	require.NoError(t, err)
+	assert.Equal(t, []int32{3}, parts)
+
+	parts, err = parsePartitionRange("1-4")
+	require.NoError(t, err)
+	assert.Equal(t, []int32{1, 2, 3, 4}, parts)
+
+	_, err = parsePartitionRange("4-1")
+	require.Error(t, err)
+
+	_, err = parsePartitionRange("1-2-3")
+	require.Error(t, err)
+
+	_, err = parsePartitionRange("nope")
+	require.Error(t, err)
+}
+
+func TestKafkaReaderConfig(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mgr := &fakeCacheMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	tests := map[string]struct {
+		conf        func(c *reader.KafkaConfig)
+		errContains string
+		check       func(t *testing.T, k *kafkaReader)
+	}{
+		"partition ranges": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo:0-2,bar:5"}
+			},
+			check: func(t *testing.T, k *kafkaReader) {
+				assert.Equal(t, map[string][]int32{
+					"foo": {0, 1, 2},
+					"bar": {5},
+				}, k.topicPartitions)
+			},
+		},
+		"regexp topics": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo-.*", "bar:baz"}
+				c.RegexpTopics = true
+			},
+			check: func(t *testing.T, k *kafkaReader) {
+				require.Len(t, k.topicPatterns, 2)
+				assert.True(t, k.topicPatterns[0].MatchString("foo-1"))
+				assert.Empty(t, k.topicPartitions)
+			},
+		},
+		"bad regexp topic": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo-("}
+				c.RegexpTopics = true
+			},
+			errContains: "failed to compile topic pattern",
+		},
+		"start from timestamp": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo:0"}
+				c.StartFromTimestamp = "1600000000"
+			},
+			check: func(t *testing.T, k *kafkaReader) {
+				require.NotNil(t, k.startFromTimestamp)
+				assert.Equal(t, time.Unix(1600000000, 0), *k.startFromTimestamp)
+			},
+		},
+		"start from timestamp balanced": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo"}
+				c.StartFromTimestamp = "1600000000"
+			},
+			check: func(t *testing.T, k *kafkaReader) {
+				require.NotNil(t, k.startFromTimestamp)
+				assert.Equal(t, []string{"foo"}, k.balancedTopics)
+			},
+		},
+		"bad start from timestamp": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo"}
+				c.StartFromTimestamp = "nope"
+			},
+			errContains: "failed to parse start_from_timestamp",
+		},
+		"checkpoint cache": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo:0"}
+				c.CheckpointCache = "foocache"
+			},
+		},
+		"checkpoint cache missing": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo:0"}
+				c.CheckpointCache = "barcache"
+			},
+			errContains: "failed to obtain checkpoint cache",
+		},
+		"checkpoint cache balanced": {
+			conf: func(c *reader.KafkaConfig) {
+				c.Topics = []string{"foo"}
+				c.CheckpointCache = "foocache"
+			},
+			errContains: "explicit topic partitions",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := reader.NewKafkaConfig()
+			test.conf(&conf)
+
+			k, err := newKafkaReader(conf, mgr, log.Noop(), metrics.Noop())
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			if test.check != nil {
+				test.check(t, k)
+			}
+		})
+	}
+}
+
+func TestKafkaCacheOffsetStore(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	store := &cacheOffsetStore{
+		mgr: &fakeCacheMgr{
+			caches: map[string]types.Cache{
+				"foocache": memCache,
+			},
+		},
+		cache:    "foocache",
+		clientID: "fooclient",
+	}
+
+	topicPartitions := map[string][]int32{
+		"foo": {0, 1},
+		"bar": {2},
+	}
+
+	offsets, err := store.Fetch(topicPartitions)
+	require.NoError(t, err)
+	assert.Empty(t, offsets)
+
+	toCommit := partitionOffsets{}
+	toCommit.set("foo", 1, 10)
+	toCommit.set("bar", 2, 20)
+	require.NoError(t, store.Commit(toCommit))
+
+	v, err := memCache.Get("fooclient:foo:1")
+	require.NoError(t, err)
+	assert.Equal(t, "10", string(v))
+
+	offsets, err = store.Fetch(topicPartitions)
+	require.NoError(t, err)
+
+	_, exists := offsets.get("foo", 0)
+	assert.False(t, exists)
+
+	offset, exists := offsets.get("foo", 1)
+	assert.True(t, exists)
+	assert.Equal(t, int64(10), offset)
+
+	offset, exists = offsets.get("bar", 2)
+	assert.True(t, exists)
+	assert.Equal(t, int64(20), offset)
+}
+
+type fakeGroupSession struct {
+	sarama.ConsumerGroupSession
+	claims map[string][]int32
+	marked partitionOffsets
+}
+
+func (f *fakeGroupSession) Claims() map[string][]int32 {
+	return f.claims
+}
+
+func (f *fakeGroupSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	f.marked.set(topic, partition, offset)
+}
+
+type fakeTimestampClient struct {
+	sarama.Client
+	timeOffsets partitionOffsets
+	newest      int64
+}
+
+func (f *fakeTimestampClient) GetOffset(topic string, partition int32, time int64) (int64, error) {
+	if time == sarama.OffsetNewest {
+		return f.newest, nil
+	}
+	if offset, exists := f.timeOffsets.get(topic, partition); exists {
+		return offset, nil
+	}
+	return -1, nil
+}
+
+func TestKafkaGroupStartFromTimestamp(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	store := &cacheOffsetStore{
+		mgr: &fakeCacheMgr{
+			caches: map[string]types.Cache{
+				"foocache": memCache,
+			},
+		},
+		cache:    "foocache",
+		clientID: "fooclient",
+	}
+
+	committed := partitionOffsets{}
+	committed.set("foo", 0, 100)
+	require.NoError(t, store.Commit(committed))
+
+	conf := reader.NewKafkaConfig()
+	conf.Topics = []string{"foo"}
+	conf.StartFromTimestamp = "1600000000"
+
+	k, err := newKafkaReader(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	client := &fakeTimestampClient{
+		timeOffsets: partitionOffsets{},
+		newest:      500,
+	}
+	client.timeOffsets.set("foo", 1, 42)
+
+	sesh := &fakeGroupSession{
+		claims: map[string][]int32{
+			"foo": {0, 1, 2},
+		},
+		marked: partitionOffsets{},
+	}
+	require.NoError(t, k.markClaimsFromTimestamp(sesh, store, client))
+
+	expected := partitionOffsets{}
+	expected.set("foo", 1, 42)
+	expected.set("foo", 2, 500)
+	assert.Equal(t, expected, sesh.marked)
+}
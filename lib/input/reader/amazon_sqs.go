@@ -3,6 +3,8 @@ package reader
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -15,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 )
 
 //------------------------------------------------------------------------------
@@ -41,6 +44,89 @@ func NewAmazonSQSConfig() AmazonSQSConfig {
 
 //------------------------------------------------------------------------------
 
+// SQSBacklog periodically updates gauges with the approximate number of
+// messages in an SQS queue that are visible and in flight.
+type SQSBacklog struct {
+	url string
+	log log.Modular
+
+	mVisible  metrics.StatGauge
+	mInFlight metrics.StatGauge
+
+	startOnce sync.Once
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewSQSBacklog creates gauges for the backlog of an SQS queue, which are not
+// updated until Start is called.
+func NewSQSBacklog(url string, log log.Modular, stats metrics.Type) *SQSBacklog {
+	return &SQSBacklog{
+		url:       url,
+		log:       log,
+		mVisible:  stats.GetGauge("queue.approximate_visible"),
+		mInFlight: stats.GetGauge("queue.approximate_in_flight"),
+		closeChan: make(chan struct{}),
+	}
+}
+
+// Start updating the gauges periodically with a client until Close is called.
+// Only the first call has an effect, and therefore readers can call it each
+// time they connect.
+func (b *SQSBacklog) Start(svc sqsiface.SQSAPI) {
+	b.startOnce.Do(func() {
+		go b.loop(svc)
+	})
+}
+
+// Close stops updating the gauges.
+func (b *SQSBacklog) Close() {
+	b.closeOnce.Do(func() {
+		close(b.closeChan)
+	})
+}
+
+func (b *SQSBacklog) loop(svc sqsiface.SQSAPI) {
+	ticker := time.NewTicker(backlogMetricsPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.closeChan:
+			return
+		}
+
+		ctx, done := context.WithTimeout(context.Background(), backlogMetricsPeriod)
+		res, err := svc.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl: aws.String(b.url),
+			AttributeNames: []*string{
+				aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages),
+				aws.String(sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+			},
+		})
+		done()
+		if err != nil {
+			b.log.Debugf("Failed to obtain queue attributes: %v\n", err)
+			continue
+		}
+		setAttributeGauge(b.mVisible, res.Attributes, sqs.QueueAttributeNameApproximateNumberOfMessages)
+		setAttributeGauge(b.mInFlight, res.Attributes, sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible)
+	}
+}
+
+func setAttributeGauge(gauge metrics.StatGauge, attrs map[string]*string, name string) {
+	v, exists := attrs[name]
+	if !exists || v == nil {
+		return
+	}
+	if n, err := strconv.ParseInt(*v, 10, 64); err == nil {
+		gauge.Set(n)
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // AmazonSQS is a benthos reader.Type implementation that reads messages from an
 // Amazon SQS queue.
 type AmazonSQS struct {
@@ -52,6 +138,8 @@ type AmazonSQS struct {
 	sqs     *sqs.SQS
 	timeout time.Duration
 
+	backlog *SQSBacklog
+
 	log   log.Modular
 	stats metrics.Type
 }
@@ -75,6 +163,7 @@ func NewAmazonSQS(
 		stats:          stats,
 		timeout:        timeout,
 		pendingHandles: map[string]string{},
+		backlog:        NewSQSBacklog(conf.URL, log, stats),
 	}, nil
 }
 
@@ -98,6 +187,8 @@ func (a *AmazonSQS) ConnectWithContext(ctx context.Context) error {
 	a.sqs = sqs.New(sess)
 	a.session = sess
 
+	a.backlog.Start(a.sqs)
+
 	a.log.Infof("Receiving Amazon SQS messages from URL: %v\n", a.conf.URL)
 	return nil
 }
@@ -332,6 +423,7 @@ func (a *AmazonSQS) Acknowledge(err error) error {
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
 func (a *AmazonSQS) CloseAsync() {
+	a.backlog.Close()
 }
 
 // WaitForClose will block until either the reader is closed or a specified
@@ -0,0 +1,71 @@
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSQSAttributes struct {
+	sqsiface.SQSAPI
+	calls chan string
+}
+
+func (m *mockSQSAttributes) GetQueueAttributesWithContext(ctx aws.Context, in *sqs.GetQueueAttributesInput, _ ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	m.calls <- *in.QueueUrl
+	return &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]*string{
+			sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String("12"),
+			sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String("3"),
+		},
+	}, nil
+}
+
+func TestSQSBacklogGauges(t *testing.T) {
+	defer func(p time.Duration) {
+		backlogMetricsPeriod = p
+	}(backlogMetricsPeriod)
+	backlogMetricsPeriod = time.Millisecond
+
+	stats := metrics.NewLocal()
+	backlog := NewSQSBacklog("http://foo/queue", log.Noop(), stats)
+
+	svc := &mockSQSAttributes{calls: make(chan string)}
+	backlog.Start(svc)
+	backlog.Start(svc)
+
+	select {
+	case url := <-svc.calls:
+		assert.Equal(t, "http://foo/queue", url)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	assert.Eventually(t, func() bool {
+		counters := stats.GetCounters()
+		return counters["queue.approximate_visible"] == 12 &&
+			counters["queue.approximate_in_flight"] == 3
+	}, time.Second, time.Millisecond)
+
+	backlog.Close()
+	backlog.Close()
+
+	// Serve a request that may have been in flight during the close, after
+	// which no further requests should be made.
+	select {
+	case <-svc.calls:
+	case <-time.After(time.Millisecond * 50):
+	}
+	select {
+	case <-svc.calls:
+		t.Error("backlog gauges updated after close")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +13,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Jeffail/benthos/v3/lib/util/kafka/sasl"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 	"github.com/Shopify/sarama"
@@ -26,6 +26,8 @@ import (
 type KafkaConfig struct {
 	Addresses           []string                 `json:"addresses" yaml:"addresses"`
 	Topics              []string                 `json:"topics" yaml:"topics"`
+	RegexpTopics        bool                     `json:"regexp_topics" yaml:"regexp_topics"`
+	TopicRefreshPeriod  string                   `json:"topic_refresh_period" yaml:"topic_refresh_period"`
 	ClientID            string                   `json:"client_id" yaml:"client_id"`
 	ConsumerGroup       string                   `json:"consumer_group" yaml:"consumer_group"`
 	Group               KafkaBalancedGroupConfig `json:"group" yaml:"group"`
@@ -36,6 +38,8 @@ type KafkaConfig struct {
 	Topic               string                   `json:"topic" yaml:"topic"`
 	Partition           int32                    `json:"partition" yaml:"partition"`
 	StartFromOldest     bool                     `json:"start_from_oldest" yaml:"start_from_oldest"`
+	StartFromTimestamp  string                   `json:"start_from_timestamp" yaml:"start_from_timestamp"`
+	CheckpointCache     string                   `json:"checkpoint_cache" yaml:"checkpoint_cache"`
 	TargetVersion       string                   `json:"target_version" yaml:"target_version"`
 	// TODO: V4 Remove this.
 	MaxBatchCount int                `json:"max_batch_count" yaml:"max_batch_count"`
@@ -57,6 +61,8 @@ func NewKafkaConfig() KafkaConfig {
 	return KafkaConfig{
 		Addresses:           []string{"localhost:9092"},
 		Topics:              []string{},
+		RegexpTopics:        false,
+		TopicRefreshPeriod:  "1m",
 		ClientID:            "benthos_kafka_input",
 		ConsumerGroup:       "benthos_consumer_group",
 		Group:               NewKafkaBalancedGroupConfig(),
@@ -67,6 +73,8 @@ func NewKafkaConfig() KafkaConfig {
 		Topic:               "benthos_stream",
 		Partition:           0,
 		StartFromOldest:     true,
+		StartFromTimestamp:  "",
+		CheckpointCache:     "",
 		TargetVersion:       sarama.V1_0_0_0.String(),
 		MaxBatchCount:       1,
 		TLS:                 btls.NewConfig(),
@@ -362,25 +370,7 @@ func (k *Kafka) ReadNextWithContext(ctx context.Context) (types.Message, error)
 
 	addPart := func(data *sarama.ConsumerMessage) {
 		k.offset = data.Offset + 1
-		part := message.NewPart(data.Value)
-
-		meta := part.Metadata()
-		for _, hdr := range data.Headers {
-			meta.Set(string(hdr.Key), string(hdr.Value))
-		}
-
-		lag := hwm - data.Offset
-		if lag < 0 {
-			lag = 0
-		}
-
-		meta.Set("kafka_key", string(data.Key))
-		meta.Set("kafka_partition", strconv.Itoa(int(data.Partition)))
-		meta.Set("kafka_topic", data.Topic)
-		meta.Set("kafka_offset", strconv.FormatInt(data.Offset, 10))
-		meta.Set("kafka_lag", strconv.FormatInt(lag, 10))
-		meta.Set("kafka_timestamp_unix", strconv.FormatInt(data.Timestamp.Unix(), 10))
-
+		part := kafka.MessageToPart(hwm, data)
 		msg.Append(part)
 	}
 
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +15,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Jeffail/benthos/v3/lib/util/kafka/sasl"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 	"github.com/Shopify/sarama"
@@ -385,25 +385,7 @@ func (k *KafkaBalanced) Read() (types.Message, error) {
 
 	msg := message.New(nil)
 	addPart := func(data consumerMessage) {
-		part := message.NewPart(data.Value)
-
-		meta := part.Metadata()
-		for _, hdr := range data.Headers {
-			meta.Set(string(hdr.Key), string(hdr.Value))
-		}
-
-		lag := data.highWaterMark - data.Offset - 1
-		if lag < 0 {
-			lag = 0
-		}
-
-		meta.Set("kafka_key", string(data.Key))
-		meta.Set("kafka_partition", strconv.Itoa(int(data.Partition)))
-		meta.Set("kafka_topic", data.Topic)
-		meta.Set("kafka_offset", strconv.Itoa(int(data.Offset)))
-		meta.Set("kafka_lag", strconv.FormatInt(lag, 10))
-		meta.Set("kafka_timestamp_unix", strconv.FormatInt(data.Timestamp.Unix(), 10))
-
+		part := kafka.MessageToPart(data.highWaterMark, data.ConsumerMessage)
 		msg.Append(part)
 
 		k.setOffset(data.Topic, data.Partition, data.Offset)
@@ -11,10 +11,10 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
-	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Shopify/sarama"
 )
 
@@ -44,7 +44,8 @@ type KafkaCG struct {
 	session       sarama.ConsumerGroupSession
 	msgChan       chan asyncMessage
 
-	mRebalanced metrics.StatCounter
+	mRebalanced   metrics.StatCounter
+	mPartitionLag metrics.StatGaugeVec
 
 	conf  KafkaBalancedConfig
 	stats metrics.Type
@@ -69,6 +70,7 @@ func NewKafkaCG(
 		log:           log,
 		mgr:           mgr,
 		mRebalanced:   stats.GetCounter("rebalanced"),
+		mPartitionLag: stats.GetGaugeVec("partition.lag", []string{"topic", "partition"}),
 		closedChan:    make(chan struct{}),
 	}
 	if conf.TLS.Enabled {
@@ -159,6 +161,14 @@ func (k *KafkaCG) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.Co
 
 	ackedChan := make(chan error)
 
+	lag := kafka.NewPartitionLag(
+		claim.InitialOffset(), claim.HighWaterMarkOffset,
+		k.mPartitionLag.With(topic, strconv.Itoa(int(partition))),
+	)
+	lagCtx, lagDone := context.WithCancel(sess.Context())
+	defer lagDone()
+	go lag.Run(lagCtx)
+
 	latestOffset := claim.InitialOffset()
 	batchPolicy, err := batch.NewPolicy(k.conf.Batching, k.mgr, k.log, k.stats)
 	if err != nil {
@@ -192,6 +202,7 @@ func (k *KafkaCG) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.Co
 					if k.session != nil {
 						k.log.Debugf("Marking offset for topic '%v' partition '%v'.\n", topic, partition)
 						k.session.MarkOffset(topic, partition, offset, "")
+						lag.Committed(offset)
 					} else {
 						k.log.Debugf("Unable to mark offset for topic '%v' partition '%v'.\n", topic, partition)
 					}
@@ -235,24 +246,8 @@ func (k *KafkaCG) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.Co
 				return nil
 			}
 			latestOffset = data.Offset
-			part := message.NewPart(data.Value)
-
-			meta := part.Metadata()
-			for _, hdr := range data.Headers {
-				meta.Set(string(hdr.Key), string(hdr.Value))
-			}
-
-			lag := claim.HighWaterMarkOffset() - data.Offset - 1
-			if lag < 0 {
-				lag = 0
-			}
-
-			meta.Set("kafka_key", string(data.Key))
-			meta.Set("kafka_partition", strconv.Itoa(int(data.Partition)))
-			meta.Set("kafka_topic", data.Topic)
-			meta.Set("kafka_offset", strconv.Itoa(int(data.Offset)))
-			meta.Set("kafka_lag", strconv.FormatInt(lag, 10))
-			meta.Set("kafka_timestamp_unix", strconv.FormatInt(data.Timestamp.Unix(), 10))
+			part := kafka.MessageToPart(claim.HighWaterMarkOffset(), data)
+			lag.Consumed(data.Offset)
 
 			if batchPolicy.Add(part) {
 				if !flushBatch(claim.Topic(), claim.Partition(), latestOffset+1) {
@@ -4,6 +4,7 @@ package reader
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -12,6 +13,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/patrobinson/gokini"
 )
 
@@ -80,6 +83,9 @@ func NewKinesisBalanced(
 	if err != nil {
 		return nil, err
 	}
+	lag := newKinesisShardLag(conf.Stream, stats)
+	sess.Handlers.Complete.PushBack(lag.onComplete)
+
 	kc := &gokini.KinesisConsumer{
 		StreamName:                  conf.Stream,
 		ShardIteratorType:           "TRIM_HORIZON",
@@ -230,3 +236,58 @@ func (k *KinesisBalanced) Shutdown() {
 }
 
 //------------------------------------------------------------------------------
+
+// kinesisShardLag sets a gauge to the MillisBehindLatest of GetRecords
+// responses. The consumer library doesn't expose it, and the requests only
+// identify shards by their iterator, so the shard of each iterator is tracked
+// from GetShardIterator responses onwards.
+type kinesisShardLag struct {
+	stream string
+	gauge  metrics.StatGaugeVec
+
+	mut        sync.Mutex
+	iterShards map[string]string
+}
+
+func newKinesisShardLag(stream string, stats metrics.Type) *kinesisShardLag {
+	return &kinesisShardLag{
+		stream:     stream,
+		gauge:      stats.GetGaugeVec("shard.millis_behind_latest", []string{"stream", "shard"}),
+		iterShards: map[string]string{},
+	}
+}
+
+// onComplete is an AWS request handler that is called after every request made
+// with the session of the consumer.
+func (l *kinesisShardLag) onComplete(r *request.Request) {
+	if r.Error != nil {
+		return
+	}
+	switch out := r.Data.(type) {
+	case *kinesis.GetShardIteratorOutput:
+		in, ok := r.Params.(*kinesis.GetShardIteratorInput)
+		if !ok || in.ShardId == nil || out.ShardIterator == nil {
+			return
+		}
+		l.mut.Lock()
+		l.iterShards[*out.ShardIterator] = *in.ShardId
+		l.mut.Unlock()
+	case *kinesis.GetRecordsOutput:
+		in, ok := r.Params.(*kinesis.GetRecordsInput)
+		if !ok || in.ShardIterator == nil {
+			return
+		}
+		l.mut.Lock()
+		shardID, exists := l.iterShards[*in.ShardIterator]
+		delete(l.iterShards, *in.ShardIterator)
+		if exists && out.NextShardIterator != nil {
+			l.iterShards[*out.NextShardIterator] = shardID
+		}
+		l.mut.Unlock()
+		if exists && out.MillisBehindLatest != nil {
+			l.gauge.With(l.stream, shardID).Set(*out.MillisBehindLatest)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
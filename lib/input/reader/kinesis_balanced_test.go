@@ -0,0 +1,58 @@
+// +build !wasm
+
+package reader
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKinesisShardLagGauge(t *testing.T) {
+	stats := metrics.NewLocal()
+	lag := newKinesisShardLag("foo", stats)
+
+	lag.onComplete(&request.Request{
+		Params: &kinesis.GetShardIteratorInput{ShardId: aws.String("shard-1")},
+		Data:   &kinesis.GetShardIteratorOutput{ShardIterator: aws.String("iter-a")},
+	})
+	lag.onComplete(&request.Request{
+		Params: &kinesis.GetRecordsInput{ShardIterator: aws.String("iter-a")},
+		Data: &kinesis.GetRecordsOutput{
+			MillisBehindLatest: aws.Int64(500),
+			NextShardIterator:  aws.String("iter-b"),
+		},
+	})
+	assert.Equal(t, int64(500), stats.GetCounters()["shard.millis_behind_latest"])
+
+	// Following the next iterator of the shard.
+	lag.onComplete(&request.Request{
+		Params: &kinesis.GetRecordsInput{ShardIterator: aws.String("iter-b")},
+		Data: &kinesis.GetRecordsOutput{
+			MillisBehindLatest: aws.Int64(20),
+			NextShardIterator:  aws.String("iter-c"),
+		},
+	})
+	assert.Equal(t, int64(20), stats.GetCounters()["shard.millis_behind_latest"])
+
+	// Unknown iterators are ignored.
+	lag.onComplete(&request.Request{
+		Params: &kinesis.GetRecordsInput{ShardIterator: aws.String("iter-nope")},
+		Data: &kinesis.GetRecordsOutput{
+			MillisBehindLatest: aws.Int64(1000),
+		},
+	})
+	assert.Equal(t, int64(20), stats.GetCounters()["shard.millis_behind_latest"])
+
+	labelled := stats.GetCountersWithLabels()["shard.millis_behind_latest"]
+	assert.True(t, labelled.HasLabelWithValue("stream", "foo"))
+	assert.True(t, labelled.HasLabelWithValue("shard", "shard-1"))
+
+	lag.mut.Lock()
+	assert.Equal(t, map[string]string{"iter-c": "shard-1"}, lag.iterShards)
+	lag.mut.Unlock()
+}
@@ -1,3 +1,9 @@
 // Package reader defines implementations of an interface for generic message
 // reading from various third party sources.
 package reader
+
+import "time"
+
+// backlogMetricsPeriod is the period at which readers that report the backlog
+// of their source update the respective gauges.
+var backlogMetricsPeriod = time.Second * 5
@@ -79,6 +79,8 @@ type RedisStreams struct {
 
 	deprecatedAckFns []AsyncAckFn
 
+	mPending metrics.StatGaugeVec
+
 	stats metrics.Type
 	log   log.Modular
 
@@ -95,6 +97,7 @@ func NewRedisStreams(
 		conf:       conf,
 		stats:      stats,
 		log:        log,
+		mPending:   stats.GetGaugeVec("stream.pending", []string{"stream"}),
 		backlogs:   make(map[string]string, len(conf.Streams)),
 		ackSend:    make(map[string][]string, len(conf.Streams)),
 		closeChan:  make(chan struct{}),
@@ -142,11 +145,18 @@ func (r *RedisStreams) loop() {
 		close(r.closedChan)
 	}()
 	commitTimer := time.NewTicker(r.commitPeriod)
+	defer commitTimer.Stop()
+
+	backlogTimer := time.NewTicker(backlogMetricsPeriod)
+	defer backlogTimer.Stop()
 
 	closed := false
 	for !closed {
 		select {
 		case <-commitTimer.C:
+		case <-backlogTimer.C:
+			r.updatePending()
+			continue
 		case <-r.closeChan:
 			closed = true
 		}
@@ -154,6 +164,27 @@ func (r *RedisStreams) loop() {
 	}
 }
 
+// updatePending sets a gauge for each stream to the number of messages that
+// have been delivered to the consumer group but not yet acknowledged.
+func (r *RedisStreams) updatePending() {
+	r.cMut.Lock()
+	client := r.client
+	r.cMut.Unlock()
+
+	if client == nil {
+		return
+	}
+
+	for _, str := range r.conf.Streams {
+		res, err := client.XPending(str, r.conf.ConsumerGroup).Result()
+		if err != nil {
+			r.log.Debugf("Failed to obtain pending entries of stream '%v': %v\n", str, err)
+			continue
+		}
+		r.mPending.With(str).Set(res.Count)
+	}
+}
+
 func (r *RedisStreams) addAsyncAcks(stream string, ids ...string) {
 	r.aMut.Lock()
 	if acks, exists := r.ackSend[stream]; exists {
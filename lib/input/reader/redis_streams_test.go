@@ -0,0 +1,110 @@
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/go-redis/redis/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveRedisPending runs a minimal Redis server that replies to XPENDING
+// commands with a fixed count of pending messages, and to anything else with
+// OK.
+func serveRedisPending(t *testing.T, count int64) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rdr := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(rdr)
+					if err != nil {
+						return
+					}
+					reply := "+OK\r\n"
+					if strings.EqualFold(args[0], "xpending") {
+						reply = fmt.Sprintf("*4\r\n:%v\r\n$3\r\n1-0\r\n$3\r\n9-0\r\n*0\r\n", count)
+					}
+					if _, err = conn.Write([]byte(reply)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func readRESPCommand(rdr *bufio.Reader) ([]string, error) {
+	line, err := rdr.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err = rdr.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := rdr.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSpace(arg))
+	}
+	return args, nil
+}
+
+func TestRedisStreamsPendingGauge(t *testing.T) {
+	defer func(p time.Duration) {
+		backlogMetricsPeriod = p
+	}(backlogMetricsPeriod)
+	backlogMetricsPeriod = time.Millisecond
+
+	addr := serveRedisPending(t, 7)
+
+	conf := NewRedisStreamsConfig()
+	conf.Streams = []string{"foo"}
+
+	stats := metrics.NewLocal()
+	r, err := NewRedisStreams(conf, log.Noop(), stats)
+	require.NoError(t, err)
+	defer func() {
+		r.CloseAsync()
+		assert.NoError(t, r.WaitForClose(time.Second))
+	}()
+
+	r.cMut.Lock()
+	r.client = redis.NewClient(&redis.Options{Addr: addr})
+	r.cMut.Unlock()
+
+	assert.Eventually(t, func() bool {
+		return stats.GetCounters()["stream.pending"] == 7
+	}, time.Second, time.Millisecond)
+	pending := stats.GetCountersWithLabels()["stream.pending"]
+	assert.True(t, pending.HasLabelWithValue("stream", "foo"))
+}
@@ -28,7 +28,8 @@ Reads messages from a sequence of child inputs, starting with the first and once
 that input gracefully terminates starts consuming from the next, and so on.`,
 		Description: `
 This input is useful for consuming from inputs that have an explicit end but
-must not be consumed in parallel.`,
+must not be consumed in parallel. In order to join messages from inputs that do
+not have an explicit end use the ` + "[`join`](/docs/components/inputs/join)" + ` input instead.`,
 		Examples: []docs.AnnotatedExample{
 			{
 				Title:   "End of Stream Message",
@@ -168,6 +168,7 @@ func (t *SocketServer) Addr() net.Addr {
 
 func (t *SocketServer) sendMsg(msg types.Message) bool {
 	tStarted := time.Now()
+	message.InitIngestionTimes(tStarted, msg)
 
 	// Block whilst retries are happening
 	t.retriesMut.Lock()
@@ -165,6 +165,7 @@ func (t *TCPServer) loop() {
 
 	sendMsg := func(msg types.Message) error {
 		tStarted := time.Now()
+		message.InitIngestionTimes(tStarted, msg)
 		mPartsRcvd.Incr(int64(msg.Len()))
 		mRcvd.Incr(1)
 
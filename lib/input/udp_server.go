@@ -156,6 +156,7 @@ func (t *UDPServer) loop() {
 
 	sendMsg := func(msg types.Message) error {
 		tStarted := time.Now()
+		message.InitIngestionTimes(tStarted, msg)
 		mPartsRcvd.Incr(int64(msg.Len()))
 		mRcvd.Incr(1)
 
@@ -0,0 +1,123 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+//------------------------------------------------------------------------------
+
+// FileConfig contains configuration fields for writing logs to a file.
+type FileConfig struct {
+	Path             string `json:"path" yaml:"path"`
+	Rotate           bool   `json:"rotate" yaml:"rotate"`
+	RotateMaxSizeMB  int    `json:"rotate_max_size_mb" yaml:"rotate_max_size_mb"`
+	RotateInterval   string `json:"rotate_interval" yaml:"rotate_interval"`
+	RotateMaxAgeDays int    `json:"rotate_max_age_days" yaml:"rotate_max_age_days"`
+	RotateMaxBackups int    `json:"rotate_max_backups" yaml:"rotate_max_backups"`
+	RotateCompress   bool   `json:"rotate_compress" yaml:"rotate_compress"`
+}
+
+// NewFileConfig returns a FileConfig with default values.
+func NewFileConfig() FileConfig {
+	return FileConfig{
+		Path:             "",
+		Rotate:           false,
+		RotateMaxSizeMB:  100,
+		RotateInterval:   "",
+		RotateMaxAgeDays: 0,
+		RotateMaxBackups: 0,
+		RotateCompress:   false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// rotatingFile is a log file that is rotated by size, and optionally on an
+// interval until it is closed.
+type rotatingFile struct {
+	*lumberjack.Logger
+
+	ticker    *time.Ticker
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func (r *rotatingFile) loop() {
+	for {
+		select {
+		case <-r.ticker.C:
+			_ = r.Rotate()
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// Close stops any interval based rotation and closes the current file.
+func (r *rotatingFile) Close() error {
+	r.closeOnce.Do(func() {
+		if r.ticker != nil {
+			r.ticker.Stop()
+		}
+		close(r.closeChan)
+	})
+	return r.Logger.Close()
+}
+
+// newFileWriter creates a writer that appends logs to a file. When rotation is
+// enabled the file is rotated once it reaches a maximum size, and optionally
+// on an interval, with old files being compressed and removed according to the
+// config.
+func newFileWriter(conf FileConfig) (io.WriteCloser, error) {
+	var interval time.Duration
+	if conf.Rotate && conf.RotateInterval != "" {
+		var err error
+		if interval, err = time.ParseDuration(conf.RotateInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse rotate interval: %v", err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("rotate interval must be greater than zero, got %v", conf.RotateInterval)
+		}
+	}
+
+	if !conf.Rotate {
+		f, err := os.OpenFile(conf.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+		return f, nil
+	}
+
+	// Open the file immediately in order to surface any errors, the rotating
+	// writer otherwise only opens it on the first write.
+	f, err := os.OpenFile(conf.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+	f.Close()
+
+	w := &rotatingFile{
+		Logger: &lumberjack.Logger{
+			Filename:   conf.Path,
+			MaxSize:    conf.RotateMaxSizeMB,
+			MaxAge:     conf.RotateMaxAgeDays,
+			MaxBackups: conf.RotateMaxBackups,
+			Compress:   conf.RotateCompress,
+		},
+		closeChan: make(chan struct{}),
+	}
+
+	if interval > 0 {
+		w.ticker = time.NewTicker(interval)
+		go w.loop()
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
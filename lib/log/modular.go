@@ -80,12 +80,15 @@ func logLevelToInt(level string) int {
 
 // Config holds configuration options for a logger object.
 type Config struct {
-	Prefix       string            `json:"prefix" yaml:"prefix"`
-	LogLevel     string            `json:"level" yaml:"level"`
-	Format       string            `json:"format" yaml:"format"`
-	AddTimeStamp bool              `json:"add_timestamp" yaml:"add_timestamp"`
-	JSONFormat   bool              `json:"json_format" yaml:"json_format"`
-	StaticFields map[string]string `json:"static_fields" yaml:"static_fields"`
+	Prefix         string            `json:"prefix" yaml:"prefix"`
+	LogLevel       string            `json:"level" yaml:"level"`
+	Format         string            `json:"format" yaml:"format"`
+	AddTimeStamp   bool              `json:"add_timestamp" yaml:"add_timestamp"`
+	JSONFormat     bool              `json:"json_format" yaml:"json_format"`
+	StaticFields   map[string]string `json:"static_fields" yaml:"static_fields"`
+	File           FileConfig        `json:"file" yaml:"file"`
+	LevelOverrides map[string]string `json:"level_overrides" yaml:"level_overrides"`
+	Sampling       SamplingConfig    `json:"sampling" yaml:"sampling"`
 }
 
 // NewConfig returns a config struct with the default values for each field.
@@ -99,6 +102,9 @@ func NewConfig() Config {
 		StaticFields: map[string]string{
 			"@service": "benthos",
 		},
+		File:           NewFileConfig(),
+		LevelOverrides: map[string]string{},
+		Sampling:       NewSamplingConfig(),
 	}
 }
 
@@ -179,6 +185,12 @@ type Logger struct {
 	addTimestamp bool
 	level        int
 	formatter    logFormatter
+
+	path           string
+	rootLevel      int
+	levelOverrides map[string]int
+	sampler        *logSampler
+	closer         io.Closer
 }
 
 // New creates and returns a new logger object.
@@ -204,11 +216,17 @@ func New(stream io.Writer, config Config) Modular {
 		addTimestamp: config.AddTimeStamp,
 		level:        logLevelToInt(config.LogLevel),
 	}
+	extrasErr := logger.initExtras(config)
 
 	logger.formatter, _ = getFormatter(config.Format, config.Prefix, config.AddTimeStamp, fields)
 	if logger.formatter == nil {
 		logger.formatter = deprecatedFormatter(config.Prefix, config.AddTimeStamp)
 	}
+	if extrasErr != nil {
+		// This constructor has no way of returning the error, so we write it
+		// regardless of the log level in order to avoid it going unnoticed.
+		logger.formatter(logger.stream, "Failed to apply logger config, the fields file, level_overrides and sampling may be ignored: %v", "ERROR", extrasErr)
+	}
 	return &logger
 }
 
@@ -236,6 +254,10 @@ func NewV2(stream io.Writer, config Config) (Modular, error) {
 		level:        logLevelToInt(config.LogLevel),
 	}
 
+	if err := logger.initExtras(config); err != nil {
+		return nil, err
+	}
+
 	var err error
 	if logger.formatter, err = getFormatter(config.Format, config.Prefix, config.AddTimeStamp, fields); err != nil {
 		return nil, err
@@ -243,6 +265,59 @@ func NewV2(stream io.Writer, config Config) (Modular, error) {
 	return &logger, nil
 }
 
+// initExtras sets up the optional file output, per-component levels and
+// sampling of a logger from a config.
+func (l *Logger) initExtras(config Config) error {
+	l.rootLevel = l.level
+
+	if len(config.LevelOverrides) > 0 {
+		l.levelOverrides = make(map[string]int, len(config.LevelOverrides))
+		for k, v := range config.LevelOverrides {
+			level := logLevelToInt(v)
+			if level < 0 {
+				return fmt.Errorf("log level '%v' of component '%v' not recognized", v, k)
+			}
+			l.levelOverrides[strings.Trim(k, ".")] = level
+		}
+		l.level = l.levelFor(l.path)
+	}
+
+	var err error
+	if l.sampler, err = newLogSampler(config.Sampling); err != nil {
+		return err
+	}
+
+	if config.File.Path != "" {
+		w, err := newFileWriter(config.File)
+		if err != nil {
+			return err
+		}
+		l.stream, l.closer = w, w
+	}
+	return nil
+}
+
+// levelFor returns the log level of a component, which is the level of the
+// longest matching override of its path, or the root level if there are none.
+// The path of a component is its prefix without the root prefix, followed by
+// its type when known, e.g. a kafka output with the component `benthos.output`
+// has the path `output.kafka`, and is matched by overrides for both
+// `output.kafka` and `output`.
+func (l *Logger) levelFor(path string) int {
+	path = strings.Trim(path, ".")
+
+	level, matchLen := l.rootLevel, -1
+	for k, v := range l.levelOverrides {
+		if path != k && !strings.HasPrefix(path, k+".") {
+			continue
+		}
+		if len(k) > matchLen {
+			level, matchLen = v, len(k)
+		}
+	}
+	return level
+}
+
 //------------------------------------------------------------------------------
 
 // Noop creates and returns a new logger object that writes nothing.
@@ -284,11 +359,53 @@ func (l *Logger) NewModule(name string) Modular {
 		stream:       l.stream,
 		prefix:       newPrefix,
 		fields:       newFields,
-		level:        l.level,
+		level:        l.levelFor(l.path + name),
 		format:       l.format,
 		addTimestamp: l.addTimestamp,
 		formatter:    formatter,
+
+		path:           l.path + name,
+		rootLevel:      l.rootLevel,
+		levelOverrides: l.levelOverrides,
+		sampler:        l.sampler,
+		closer:         l.closer,
+	}
+}
+
+// ForType returns a logger for a component of a given type. The component
+// field of its logs is unchanged, but the type is added to the path that
+// level overrides are matched against, which allows overrides such as
+// `output.kafka`.
+func ForType(l Modular, typeStr string) Modular {
+	logger, ok := l.(*Logger)
+	if !ok || typeStr == "" {
+		return l
+	}
+	newLogger := *logger
+	newLogger.path = logger.path + "." + typeStr
+	if logger.levelOverrides != nil {
+		newLogger.level = logger.levelFor(newLogger.path)
+	}
+	return &newLogger
+}
+
+// Close releases resources held by the logger, such as an open log file. It
+// should only be called once the logger and all loggers derived from it are
+// no longer in use.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Close attempts to cast the Modular implementation into an io.Closer, and if
+// successful closes it.
+func Close(l Modular) error {
+	if c, ok := l.(io.Closer); ok {
+		return c.Close()
 	}
+	return nil
 }
 
 // WithFields returns a logger with new fields added to the JSON formatted
@@ -315,6 +432,12 @@ func (l *Logger) WithFields(inboundFields map[string]string) Modular {
 		format:       l.format,
 		addTimestamp: l.addTimestamp,
 		formatter:    formatter,
+
+		path:           l.path,
+		rootLevel:      l.rootLevel,
+		levelOverrides: l.levelOverrides,
+		sampler:        l.sampler,
+		closer:         l.closer,
 	}
 }
 
@@ -351,6 +474,12 @@ func (l *Logger) With(args ...interface{}) Modular {
 		format:       l.format,
 		addTimestamp: l.addTimestamp,
 		formatter:    formatter,
+
+		path:           l.path,
+		rootLevel:      l.rootLevel,
+		levelOverrides: l.levelOverrides,
+		sampler:        l.sampler,
+		closer:         l.closer,
 	}
 }
 
@@ -462,9 +591,22 @@ func getFormatter(format, component string, addTimestamp bool, fields map[string
 
 // write prints a log message with any configured extras prepended.
 func (l *Logger) write(message string, level string, other ...interface{}) {
+	if !l.sampler.allow(l.prefix, level, message) {
+		return
+	}
 	l.formatter(l.stream, message, level, other...)
 }
 
+// writeln prints a log message without a format string. Since the message is
+// likely to contain values it is sampled along with all other such messages
+// of the component at the same level.
+func (l *Logger) writeln(message string, level string) {
+	if !l.sampler.allow(l.prefix, level, "") {
+		return
+	}
+	l.formatter(l.stream, message, level)
+}
+
 //------------------------------------------------------------------------------
 
 // Fatalf prints a fatal message to the console. Does NOT cause panic.
@@ -514,42 +656,42 @@ func (l *Logger) Tracef(format string, v ...interface{}) {
 // Fatalln prints a fatal message to the console. Does NOT cause panic.
 func (l *Logger) Fatalln(message string) {
 	if LogFatal <= l.level {
-		l.write(message, "FATAL")
+		l.writeln(message, "FATAL")
 	}
 }
 
 // Errorln prints an error message to the console.
 func (l *Logger) Errorln(message string) {
 	if LogError <= l.level {
-		l.write(message, "ERROR")
+		l.writeln(message, "ERROR")
 	}
 }
 
 // Warnln prints a warning message to the console.
 func (l *Logger) Warnln(message string) {
 	if LogWarn <= l.level {
-		l.write(message, "WARN")
+		l.writeln(message, "WARN")
 	}
 }
 
 // Infoln prints an information message to the console.
 func (l *Logger) Infoln(message string) {
 	if LogInfo <= l.level {
-		l.write(message, "INFO")
+		l.writeln(message, "INFO")
 	}
 }
 
 // Debugln prints a debug message to the console.
 func (l *Logger) Debugln(message string) {
 	if LogDebug <= l.level {
-		l.write(message, "DEBUG")
+		l.writeln(message, "DEBUG")
 	}
 }
 
 // Traceln prints a trace message to the console.
 func (l *Logger) Traceln(message string) {
 	if LogTrace <= l.level {
-		l.write(message, "TRACE")
+		l.writeln(message, "TRACE")
 	}
 }
 
@@ -2,7 +2,11 @@ package log
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -276,3 +280,249 @@ func TestLogLevels(t *testing.T) {
 		}
 	}
 }
+
+func TestLevelOverrides(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.LogLevel = "WARN"
+	loggerConfig.LevelOverrides = map[string]string{
+		"output":         "DEBUG",
+		"output.broker":  "ERROR",
+		"pipeline.proc":  "OFF",
+		".input.broker.": "INFO",
+	}
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	logger.Infoln("root info")
+	logger.NewModule(".output").Debugln("output debug")
+	logger.NewModule(".output").NewModule(".foo").Debugln("output.foo debug")
+	logger.NewModule(".output").NewModule(".broker").Warnln("output.broker warn")
+	logger.NewModule(".output").NewModule(".broker").Errorln("output.broker error")
+	logger.NewModule(".outputs").Debugln("outputs debug")
+	logger.NewModule(".pipeline.processor").Warnln("pipeline.processor warn")
+	logger.NewModule(".input.broker").WithFields(map[string]string{"foo": "bar"}).Infoln("input.broker info")
+
+	expected := "DEBUG | root.output | output debug\n" +
+		"DEBUG | root.output.foo | output.foo debug\n" +
+		"ERROR | root.output.broker | output.broker error\n" +
+		"WARN | root.pipeline.processor | pipeline.processor warn\n" +
+		"INFO | root.input.broker | input.broker info\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestLevelOverridesComponentType(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.LogLevel = "WARN"
+	loggerConfig.LevelOverrides = map[string]string{
+		"output.kafka": "DEBUG",
+	}
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	outLogger := logger.NewModule(".output")
+	outLogger.Debugln("output debug")
+	ForType(outLogger, "kafka").Debugln("kafka debug")
+	ForType(outLogger, "kafka").NewModule(".batching").Debugln("kafka batching debug")
+	ForType(outLogger, "stdout").Debugln("stdout debug")
+
+	expected := "DEBUG | root.output | kafka debug\n" +
+		"DEBUG | root.output.batching | kafka batching debug\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestNewBadExtras(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.LogLevel = "OFF"
+	loggerConfig.LevelOverrides = map[string]string{
+		"output": "NOPE",
+	}
+
+	var buf bytes.Buffer
+	_ = New(&buf, loggerConfig)
+
+	assert.Contains(t, buf.String(), "ERROR | root | Failed to apply logger config")
+	assert.Contains(t, buf.String(), "log level 'NOPE' of component 'output' not recognized")
+}
+
+func TestLevelOverridesBadLevel(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.LevelOverrides = map[string]string{
+		"output": "NOPE",
+	}
+
+	_, err := NewV2(&bytes.Buffer{}, loggerConfig)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "log level 'NOPE' of component 'output' not recognized")
+}
+
+func TestSampling(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.Sampling.Enabled = true
+	loggerConfig.Sampling.Interval = "1h"
+	loggerConfig.Sampling.First = 2
+	loggerConfig.Sampling.Thereafter = 3
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	procLogger := logger.NewModule(".processor")
+	for i := 0; i < 10; i++ {
+		procLogger.Errorf("failed to process: %v\n", i)
+		if i < 2 {
+			logger.Infoln("different message")
+		}
+	}
+
+	expected := "ERROR | root.processor | failed to process: 0\n" +
+		"INFO | root | different message\n" +
+		"ERROR | root.processor | failed to process: 1\n" +
+		"INFO | root | different message\n" +
+		"ERROR | root.processor | failed to process: 4\n" +
+		"ERROR | root.processor | failed to process: 7\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestSamplingLineMessages(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.Sampling.Enabled = true
+	loggerConfig.Sampling.Interval = "1h"
+	loggerConfig.Sampling.First = 2
+	loggerConfig.Sampling.Thereafter = 0
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		logger.Errorln(fmt.Sprintf("failed to process: %v", i))
+	}
+
+	expected := "ERROR | root | failed to process: 0\n" +
+		"ERROR | root | failed to process: 1\n"
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestSamplingEviction(t *testing.T) {
+	s, err := newLogSampler(SamplingConfig{
+		Enabled:  true,
+		Interval: "10ms",
+		First:    1,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, s.allow("root", "INFO", fmt.Sprintf("message %v", i)))
+	}
+	assert.Len(t, s.counts, 10)
+
+	<-time.After(20 * time.Millisecond)
+	assert.True(t, s.allow("root", "INFO", "another message"))
+	assert.Len(t, s.counts, 1)
+}
+
+func TestFileOutput(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "benthos.log")
+
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.File.Path = logPath
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	logger.Infoln("foo")
+	logger.NewModule(".bar").Infoln("bar")
+
+	assert.Empty(t, buf.String())
+
+	require.NoError(t, Close(logger))
+
+	logBytes, err := ioutil.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO | root | foo\nINFO | root.bar | bar\n", string(logBytes))
+}
+
+func TestFileOutputRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "benthos.log")
+
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "classic"
+	loggerConfig.Prefix = "root"
+	loggerConfig.File.Path = logPath
+	loggerConfig.File.Rotate = true
+	loggerConfig.File.RotateInterval = "50ms"
+
+	logger, err := NewV2(&bytes.Buffer{}, loggerConfig)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, Close(logger))
+	}()
+
+	logger.Infoln("before rotation")
+
+	assert.Eventually(t, func() bool {
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		return len(files) > 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+
+	var rotated []string
+	for _, f := range files {
+		if f.Name() == "benthos.log" {
+			continue
+		}
+		logBytes, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		require.NoError(t, err)
+		rotated = append(rotated, string(logBytes))
+	}
+	assert.Contains(t, rotated, "INFO | root | before rotation\n")
+}
+
+func TestFileOutputBadInterval(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.File.Path = filepath.Join(t.TempDir(), "benthos.log")
+	loggerConfig.File.Rotate = true
+	loggerConfig.File.RotateInterval = "nope"
+
+	_, err := NewV2(&bytes.Buffer{}, loggerConfig)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse rotate interval")
+}
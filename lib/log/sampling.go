@@ -0,0 +1,104 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// SamplingConfig contains configuration fields for limiting the number of
+// repeated log messages that are written.
+type SamplingConfig struct {
+	Enabled    bool   `json:"enabled" yaml:"enabled"`
+	Interval   string `json:"interval" yaml:"interval"`
+	First      int64  `json:"first" yaml:"first"`
+	Thereafter int64  `json:"thereafter" yaml:"thereafter"`
+}
+
+// NewSamplingConfig returns a SamplingConfig with default values.
+func NewSamplingConfig() SamplingConfig {
+	return SamplingConfig{
+		Enabled:    false,
+		Interval:   "1s",
+		First:      10,
+		Thereafter: 100,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sampleCount struct {
+	windowStart time.Time
+	n           int64
+}
+
+// logSampler limits repeated log messages, which are identified by their
+// component, level and format string. Within each interval the first N of a
+// message are written, and thereafter only every Mth. Counts of messages that
+// haven't been seen for an interval are evicted.
+type logSampler struct {
+	interval   time.Duration
+	first      int64
+	thereafter int64
+
+	mut       sync.Mutex
+	lastEvict time.Time
+	counts    map[string]*sampleCount
+}
+
+func newLogSampler(conf SamplingConfig) (*logSampler, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(conf.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sampling interval: %v", err)
+	}
+	return &logSampler{
+		interval:   interval,
+		first:      conf.First,
+		thereafter: conf.Thereafter,
+		lastEvict:  time.Now(),
+		counts:     map[string]*sampleCount{},
+	}, nil
+}
+
+// allow returns true if a log message should be written.
+func (s *logSampler) allow(component, level, format string) bool {
+	if s == nil || level == "FATAL" {
+		return true
+	}
+	key := component + "\x00" + level + "\x00" + format
+	now := time.Now()
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if now.Sub(s.lastEvict) >= s.interval {
+		for k, c := range s.counts {
+			if now.Sub(c.windowStart) >= s.interval {
+				delete(s.counts, k)
+			}
+		}
+		s.lastEvict = now
+	}
+
+	c, exists := s.counts[key]
+	if !exists {
+		c = &sampleCount{windowStart: now}
+		s.counts[key] = c
+	} else if now.Sub(c.windowStart) >= s.interval {
+		c.windowStart = now
+		c.n = 0
+	}
+	c.n++
+
+	if c.n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (c.n-s.first)%s.thereafter == 0
+}
+
+//------------------------------------------------------------------------------
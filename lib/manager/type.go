@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/condition"
 	"github.com/Jeffail/benthos/v3/lib/input"
@@ -223,6 +224,9 @@ type Type struct {
 
 	pipes    map[string]<-chan types.Transaction
 	pipeLock sync.RWMutex
+
+	httpRoundTripper http.RoundTripper
+	branchCoverage   *query.BranchCoverage
 }
 
 // New returns an instance of manager.Type, which can be shared amongst
@@ -232,6 +236,7 @@ func New(
 	apiReg APIReg,
 	log log.Modular,
 	stats metrics.Type,
+	opts ...func(*Type),
 ) (*Type, error) {
 	t := &Type{
 		apiReg:     apiReg,
@@ -244,6 +249,9 @@ func New(
 		plugins:    map[string]interface{}{},
 		pipes:      map[string]<-chan types.Transaction{},
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
 
 	// Sometimes resources of a type might refer to other resources of the same
 	// type. When they are constructed they will check with the manager to
@@ -370,6 +378,36 @@ func New(
 
 //------------------------------------------------------------------------------
 
+// OptSetHTTPRoundTripper sets an HTTP round tripper to be used by all HTTP
+// clients created by components of the manager, overriding their configured
+// transports. This is used for mocking HTTP endpoints during config tests.
+func OptSetHTTPRoundTripper(rt http.RoundTripper) func(*Type) {
+	return func(t *Type) {
+		t.httpRoundTripper = rt
+	}
+}
+
+// GetHTTPRoundTripper returns an HTTP round tripper that overrides the
+// configured transport of HTTP clients, or nil if none has been set.
+func (t *Type) GetHTTPRoundTripper() http.RoundTripper {
+	return t.httpRoundTripper
+}
+
+// OptSetBloblangBranchCoverage sets a recorder for the branches of Bloblang
+// mappings parsed by components of the manager. This is used for reporting the
+// coverage of config tests.
+func OptSetBloblangBranchCoverage(c *query.BranchCoverage) func(*Type) {
+	return func(t *Type) {
+		t.branchCoverage = c
+	}
+}
+
+// GetBloblangBranchCoverage returns a recorder for the branches of Bloblang
+// mappings, or nil if none has been set.
+func (t *Type) GetBloblangBranchCoverage() *query.BranchCoverage {
+	return t.branchCoverage
+}
+
 // RegisterEndpoint registers a server wide HTTP endpoint.
 func (t *Type) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
 	t.apiReg.RegisterEndpoint(path, desc, h)
@@ -42,7 +42,7 @@ Allows you to configure a [batching policy](/docs/configuration/batching).`,
 				"check",
 				"A [Bloblang query](/docs/guides/bloblang/about/) that should return a boolean value indicating whether a message should end a batch.",
 				`this.type == "end_of_transaction"`,
-			).HasDefault(""),
+			).IsBloblang().HasDefault(""),
 			docs.FieldDeprecated("condition"),
 			docs.FieldAdvanced(
 				"processors",
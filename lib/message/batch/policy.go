@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/condition"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -178,7 +178,7 @@ func NewPolicy(
 	}
 	var check *mapping.Executor
 	if len(conf.Check) > 0 {
-		if check, err = bloblang.NewMapping("", conf.Check); err != nil {
+		if check, err = interop.NewBloblangMapping(mgr, conf.Check); err != nil {
 			return nil, fmt.Errorf("failed to parse check: %v", err)
 		}
 	}
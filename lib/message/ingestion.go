@@ -0,0 +1,44 @@
+package message
+
+import (
+	"context"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+type ingestionTimeKey struct{}
+
+// GetIngestionTime returns the time at which a message part was first consumed
+// by an input, and a boolean indicating whether an ingestion time is attached
+// to the part.
+func GetIngestionTime(p types.Part) (time.Time, bool) {
+	t, ok := GetContext(p).Value(ingestionTimeKey{}).(time.Time)
+	return t, ok
+}
+
+// WithIngestionTime returns the same message part with an ingestion time
+// attached to its context, which can subsequently be received with
+// GetIngestionTime.
+func WithIngestionTime(t time.Time, p types.Part) types.Part {
+	return WithContext(context.WithValue(GetContext(p), ingestionTimeKey{}, t), p)
+}
+
+// InitIngestionTimes attaches an ingestion time to each part of a message that
+// does not already have one.
+func InitIngestionTimes(t time.Time, msg types.Message) {
+	stampedParts := make([]types.Part, msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		if _, exists := GetIngestionTime(p); exists {
+			stampedParts[i] = p
+			return nil
+		}
+		stampedParts[i] = WithIngestionTime(t, p)
+		return nil
+	})
+	msg.SetAll(stampedParts)
+}
+
+//------------------------------------------------------------------------------
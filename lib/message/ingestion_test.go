@@ -0,0 +1,46 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestionTime(t *testing.T) {
+	var p types.Part = NewPart([]byte(`foo`))
+	_, exists := GetIngestionTime(p)
+	assert.False(t, exists)
+
+	tOne := time.Unix(100, 0)
+	p = WithIngestionTime(tOne, p)
+
+	act, exists := GetIngestionTime(p)
+	assert.True(t, exists)
+	assert.Equal(t, tOne, act)
+
+	act, exists = GetIngestionTime(p.Copy())
+	assert.True(t, exists)
+	assert.Equal(t, tOne, act)
+}
+
+func TestInitIngestionTimes(t *testing.T) {
+	tOne, tTwo := time.Unix(100, 0), time.Unix(200, 0)
+
+	msg := New(nil)
+	msg.Append(WithIngestionTime(tOne, NewPart([]byte(`foo`))))
+	msg.Append(NewPart([]byte(`bar`)))
+
+	InitIngestionTimes(tTwo, msg)
+
+	var times []time.Time
+	msg.Iter(func(i int, p types.Part) error {
+		ts, exists := GetIngestionTime(p)
+		assert.True(t, exists)
+		times = append(times, ts)
+		return nil
+	})
+	assert.Equal(t, []time.Time{tOne, tTwo}, times)
+	assert.Equal(t, "bar", string(msg.Get(1).Get()))
+}
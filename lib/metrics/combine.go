@@ -20,6 +20,20 @@ func Combine(t1, t2 Type) Type {
 	}
 }
 
+// namespaced only takes over a namespace when at least one of the underlying
+// types handles namespaces itself.
+func (c *combinedWrapper) namespaced(ns string) (Type, bool) {
+	_, ok1 := c.t1.(namespacer)
+	_, ok2 := c.t2.(namespacer)
+	if !ok1 && !ok2 {
+		return nil, false
+	}
+	return &combinedWrapper{
+		t1: Namespaced(c.t1, ns),
+		t2: Namespaced(c.t2, ns),
+	}, true
+}
+
 func unwrapMetric(t Type) Type {
 	u, ok := t.(interface {
 		Unwrap() Type
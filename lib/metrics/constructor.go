@@ -49,6 +49,7 @@ const (
 	TypeCloudWatch    = "cloudwatch"
 	TypeHTTPServer    = "http_server"
 	TypeInfluxDB      = "influxdb"
+	TypeOpenTelemetry = "open_telemetry"
 	TypePrometheus    = "prometheus"
 	TypeRename        = "rename"
 	TypeStatsd        = "statsd"
@@ -61,17 +62,18 @@ const (
 // Config is the all encompassing configuration struct for all metric output
 // types.
 type Config struct {
-	Type          string           `json:"type" yaml:"type"`
-	AWSCloudWatch CloudWatchConfig `json:"aws_cloudwatch" yaml:"aws_cloudwatch"`
-	Blacklist     BlacklistConfig  `json:"blacklist" yaml:"blacklist"`
-	CloudWatch    CloudWatchConfig `json:"cloudwatch" yaml:"cloudwatch"`
-	HTTP          HTTPConfig       `json:"http_server" yaml:"http_server"`
-	InfluxDB      InfluxDBConfig   `json:"influxdb" yaml:"influxdb"`
-	Prometheus    PrometheusConfig `json:"prometheus" yaml:"prometheus"`
-	Rename        RenameConfig     `json:"rename" yaml:"rename"`
-	Statsd        StatsdConfig     `json:"statsd" yaml:"statsd"`
-	Stdout        StdoutConfig     `json:"stdout" yaml:"stdout"`
-	Whitelist     WhitelistConfig  `json:"whitelist" yaml:"whitelist"`
+	Type          string              `json:"type" yaml:"type"`
+	AWSCloudWatch CloudWatchConfig    `json:"aws_cloudwatch" yaml:"aws_cloudwatch"`
+	Blacklist     BlacklistConfig     `json:"blacklist" yaml:"blacklist"`
+	CloudWatch    CloudWatchConfig    `json:"cloudwatch" yaml:"cloudwatch"`
+	HTTP          HTTPConfig          `json:"http_server" yaml:"http_server"`
+	InfluxDB      InfluxDBConfig      `json:"influxdb" yaml:"influxdb"`
+	OpenTelemetry OpenTelemetryConfig `json:"open_telemetry" yaml:"open_telemetry"`
+	Prometheus    PrometheusConfig    `json:"prometheus" yaml:"prometheus"`
+	Rename        RenameConfig        `json:"rename" yaml:"rename"`
+	Statsd        StatsdConfig        `json:"statsd" yaml:"statsd"`
+	Stdout        StdoutConfig        `json:"stdout" yaml:"stdout"`
+	Whitelist     WhitelistConfig     `json:"whitelist" yaml:"whitelist"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -83,6 +85,7 @@ func NewConfig() Config {
 		CloudWatch:    NewCloudWatchConfig(),
 		HTTP:          NewHTTPConfig(),
 		InfluxDB:      NewInfluxDBConfig(),
+		OpenTelemetry: NewOpenTelemetryConfig(),
 		Prometheus:    NewPrometheusConfig(),
 		Rename:        NewRenameConfig(),
 		Statsd:        NewStatsdConfig(),
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+// componentKinds maps namespace segments to the type of component that the
+// metrics beneath them belong to.
+var componentKinds = map[string]string{
+	"buffer":     "buffer",
+	"cache":      "cache",
+	"condition":  "condition",
+	"conditions": "condition",
+	"input":      "input",
+	"inputs":     "input",
+	"output":     "output",
+	"outputs":    "output",
+	"pipeline":   "pipeline",
+	"plugin":     "plugin",
+	"processor":  "processor",
+	"processors": "processor",
+	"rate_limit": "rate_limit",
+}
+
+func splitNamespace(ns string) []string {
+	return strings.Split(ns, ".")
+}
+
+func isIndexSegment(seg string) bool {
+	if len(seg) == 0 {
+		return false
+	}
+	for _, c := range seg {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func sanitiseMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// labelledMetric converts the namespace of a metric, which identifies the
+// component that emits it, and the path of the metric into a stable metric name
+// along with values for the labels `path` and `label`.
+//
+// The name is the type of the innermost component within the namespace
+// followed by the path of the metric, e.g. a namespace `pipeline.processor.0`
+// and path `batch.sent` results in the name `processor_batch_sent`. Indexes
+// are removed from the name and the names of resources become the `label`
+// label, whereas the `path` label is the full namespace.
+func labelledMetric(ns []string, path string) (name, componentPath, label string) {
+	kindIndex := -1
+	for i, seg := range ns {
+		if _, exists := componentKinds[seg]; exists {
+			kindIndex = i
+		}
+	}
+
+	var nameSegs []string
+	start := 0
+	if kindIndex >= 0 {
+		nameSegs = append(nameSegs, componentKinds[ns[kindIndex]])
+		start = kindIndex + 1
+		if kindIndex > 0 && ns[kindIndex-1] == "resource" && start < len(ns) {
+			label = ns[start]
+			start++
+		}
+	}
+	for _, seg := range ns[start:] {
+		if !isIndexSegment(seg) {
+			nameSegs = append(nameSegs, seg)
+		}
+	}
+	nameSegs = append(nameSegs, path)
+
+	name = sanitiseMetricName(strings.Join(nameSegs, "_"))
+	componentPath = strings.Join(ns, ".")
+	return
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelledMetric(t *testing.T) {
+	tests := []struct {
+		ns    string
+		path  string
+		name  string
+		label string
+	}{
+		{ns: "", path: "uptime", name: "uptime"},
+		{ns: "input", path: "received", name: "input_received"},
+		{ns: "pipeline.processor.0", path: "batch.sent", name: "processor_batch_sent"},
+		{ns: "pipeline.processor.1.0.2", path: "count", name: "processor_count"},
+		{ns: "output.broker.outputs.1", path: "send.success", name: "output_send_success"},
+		{ns: "output.switch.0.condition", path: "true", name: "condition_true"},
+		{ns: "output.batching", path: "on_count", name: "output_batching_on_count"},
+		{ns: "resource.cache.foo", path: "get.success", name: "cache_get_success", label: "foo"},
+		{ns: "resource.processor.bar-baz", path: "count", name: "processor_count", label: "bar-baz"},
+		{ns: "processor.0.client", path: "send.success", name: "processor_client_send_success"},
+		{ns: "foo.input", path: "received", name: "input_received"},
+	}
+
+	for _, test := range tests {
+		var ns []string
+		if test.ns != "" {
+			ns = splitNamespace(test.ns)
+		}
+		name, path, label := labelledMetric(ns, test.path)
+		assert.Equal(t, test.name, name, test.ns)
+		assert.Equal(t, test.ns, path, test.ns)
+		assert.Equal(t, test.label, label, test.ns)
+	}
+}
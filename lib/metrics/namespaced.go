@@ -13,9 +13,22 @@ type namespacedWrapper struct {
 	t  Type
 }
 
+// namespacer is implemented by metrics types that are able to handle namespaces
+// themselves rather than having them prefixed to the paths of metrics, which
+// allows them to identify the component that emits a metric. The returned bool
+// is false when the type does not wish to handle a namespace.
+type namespacer interface {
+	namespaced(ns string) (Type, bool)
+}
+
 // Namespaced embeds an existing metrics aggregator under a new namespace. The
 // prefix of the embedded aggregator is still the ultimate prefix of metrics.
 func Namespaced(t Type, ns string) Type {
+	if n, ok := t.(namespacer); ok {
+		if nt, ok := n.namespaced(ns); ok {
+			return nt
+		}
+	}
 	return namespacedWrapper{
 		ns: ns,
 		t:  t,
@@ -0,0 +1,519 @@
+// +build !wasm
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+//------------------------------------------------------------------------------
+
+type otelKind int
+
+const (
+	otelKindCounter otelKind = iota
+	otelKindGauge
+	otelKindTimer
+)
+
+func (k otelKind) String() string {
+	switch k {
+	case otelKindCounter:
+		return "counter"
+	case otelKindGauge:
+		return "gauge"
+	}
+	return "timer"
+}
+
+// otelSeries is the state of a metric for a single set of attributes.
+type otelSeries struct {
+	attrs []*commonpb.KeyValue
+
+	// Used by counters and gauges.
+	value int64
+
+	// Used by timers.
+	mut     sync.Mutex
+	bounds  []float64
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+func (s *otelSeries) Incr(count int64) error {
+	atomic.AddInt64(&s.value, count)
+	return nil
+}
+
+func (s *otelSeries) Decr(count int64) error {
+	atomic.AddInt64(&s.value, -count)
+	return nil
+}
+
+func (s *otelSeries) Set(value int64) error {
+	atomic.StoreInt64(&s.value, value)
+	return nil
+}
+
+func (s *otelSeries) Timing(delta int64) error {
+	secs := float64(delta) / float64(time.Second)
+	s.mut.Lock()
+	s.count++
+	s.sum += secs
+	s.buckets[sort.SearchFloat64s(s.bounds, secs)]++
+	s.mut.Unlock()
+	return nil
+}
+
+type otelInstrument struct {
+	name   string
+	kind   otelKind
+	series map[string]*otelSeries
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetry is a metrics type that aggregates metrics in memory and pushes
+// them to an OpenTelemetry collector on an interval.
+type OpenTelemetry struct {
+	config      OpenTelemetryConfig
+	pathMapping *pathMapping
+	log         log.Modular
+
+	resource     *resourcepb.Resource
+	bounds       []float64
+	startTime    time.Time
+	pushInterval time.Duration
+	timeout      time.Duration
+
+	mut         sync.Mutex
+	instruments map[string]*otelInstrument
+
+	grpcConn   *grpc.ClientConn
+	grpcClient colmetricspb.MetricsServiceClient
+	httpClient *http.Client
+	httpURL    string
+
+	closeOnce  sync.Once
+	shutSig    chan struct{}
+	loopClosed chan struct{}
+}
+
+// NewOpenTelemetry creates and returns a new OpenTelemetry object.
+func NewOpenTelemetry(config Config, opts ...func(Type)) (Type, error) {
+	conf := config.OpenTelemetry
+	o := &OpenTelemetry{
+		config:      conf,
+		log:         log.Noop(),
+		startTime:   time.Now(),
+		instruments: map[string]*otelInstrument{},
+		shutSig:     make(chan struct{}),
+		loopClosed:  make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var err error
+	if o.pathMapping, err = newPathMapping(conf.PathMapping, o.log); err != nil {
+		return nil, fmt.Errorf("failed to init path mapping: %v", err)
+	}
+	if o.pushInterval, err = time.ParseDuration(conf.PushInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse push interval: %v", err)
+	}
+	if o.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+
+	o.bounds = prometheus.DefBuckets
+	if len(conf.HistogramBuckets) > 0 {
+		o.bounds = append([]float64{}, conf.HistogramBuckets...)
+		sort.Float64s(o.bounds)
+	}
+
+	resAttrs := map[string]string{}
+	for k, v := range conf.ResourceAttributes {
+		resAttrs[k] = v
+	}
+	if conf.ServiceName != "" {
+		resAttrs["service.name"] = conf.ServiceName
+	}
+	resKeys := make([]string, 0, len(resAttrs))
+	for k := range resAttrs {
+		resKeys = append(resKeys, k)
+	}
+	sort.Strings(resKeys)
+	resValues := make([]string, len(resKeys))
+	for i, k := range resKeys {
+		resValues[i] = resAttrs[k]
+	}
+	o.resource = &resourcepb.Resource{
+		Attributes: otelAttributes(resKeys, resValues),
+	}
+
+	switch conf.Protocol {
+	case "grpc":
+		dialOpts := []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}
+		if conf.TLS.Enabled {
+			tlsConf, err := conf.TLS.Get()
+			if err != nil {
+				return nil, err
+			}
+			dialOpts[0] = grpc.WithTransportCredentials(credentials.NewTLS(tlsConf))
+		}
+		if o.grpcConn, err = grpc.Dial(conf.Endpoint, dialOpts...); err != nil {
+			return nil, fmt.Errorf("failed to create gRPC client: %v", err)
+		}
+		o.grpcClient = colmetricspb.NewMetricsServiceClient(o.grpcConn)
+	case "http":
+		scheme := "http"
+		o.httpClient = &http.Client{Timeout: o.timeout}
+		if conf.TLS.Enabled {
+			tlsConf, err := conf.TLS.Get()
+			if err != nil {
+				return nil, err
+			}
+			o.httpClient.Transport = &http.Transport{TLSClientConfig: tlsConf}
+			scheme = "https"
+		}
+		o.httpURL = scheme + "://" + conf.Endpoint + "/v1/metrics"
+	default:
+		return nil, fmt.Errorf("protocol '%v' was not recognised, expected grpc or http", conf.Protocol)
+	}
+
+	go o.loop()
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+func otelAttributes(keys, values []string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(keys))
+	for i, k := range keys {
+		var v string
+		if i < len(values) {
+			v = values[i]
+		}
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key: k,
+			Value: &commonpb.AnyValue{
+				Value: &commonpb.AnyValue_StringValue{StringValue: v},
+			},
+		})
+	}
+	return attrs
+}
+
+// getSeries returns the series of a metric for a set of attributes, creating
+// it if it does not yet exist. Returns nil if the metric has already been
+// registered as a different kind.
+func (o *OpenTelemetry) getSeries(kind otelKind, name string, keys, values []string) *otelSeries {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	inst, exists := o.instruments[name]
+	if !exists {
+		inst = &otelInstrument{
+			name:   name,
+			kind:   kind,
+			series: map[string]*otelSeries{},
+		}
+		o.instruments[name] = inst
+	} else if inst.kind != kind {
+		o.log.Errorf("Metric '%v' has already been registered as a %v and cannot be used as a %v\n", name, inst.kind, kind)
+		return nil
+	}
+
+	seriesKey := strings.Join(values, "\x00")
+	s, exists := inst.series[seriesKey]
+	if !exists {
+		s = &otelSeries{
+			attrs: otelAttributes(keys, values),
+		}
+		if kind == otelKindTimer {
+			s.bounds = o.bounds
+			s.buckets = make([]uint64, len(o.bounds)+1)
+		}
+		inst.series[seriesKey] = s
+	}
+	return s
+}
+
+func (o *OpenTelemetry) get(kind otelKind, path string) (*otelSeries, bool) {
+	name, keys, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return nil, false
+	}
+	s := o.getSeries(kind, name, keys, values)
+	return s, s != nil
+}
+
+func (o *OpenTelemetry) getVec(kind otelKind, path string, labelNames []string) func(labelValues []string) (*otelSeries, bool) {
+	name, keys, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return func([]string) (*otelSeries, bool) {
+			return nil, false
+		}
+	}
+	keys = append(append([]string{}, keys...), labelNames...)
+	return func(labelValues []string) (*otelSeries, bool) {
+		s := o.getSeries(kind, name, keys, append(append([]string{}, values...), labelValues...))
+		return s, s != nil
+	}
+}
+
+// GetCounter returns a stat counter object for a path.
+func (o *OpenTelemetry) GetCounter(path string) StatCounter {
+	if s, ok := o.get(otelKindCounter, path); ok {
+		return s
+	}
+	return DudStat{}
+}
+
+// GetCounterVec returns a stat counter object for a path with the labels
+// added as attributes.
+func (o *OpenTelemetry) GetCounterVec(path string, n []string) StatCounterVec {
+	f := o.getVec(otelKindCounter, path, n)
+	return fakeCounterVec(func(l []string) StatCounter {
+		if s, ok := f(l); ok {
+			return s
+		}
+		return DudStat{}
+	})
+}
+
+// GetTimer returns a stat timer object for a path.
+func (o *OpenTelemetry) GetTimer(path string) StatTimer {
+	if s, ok := o.get(otelKindTimer, path); ok {
+		return s
+	}
+	return DudStat{}
+}
+
+// GetTimerVec returns a stat timer object for a path with the labels added as
+// attributes.
+func (o *OpenTelemetry) GetTimerVec(path string, n []string) StatTimerVec {
+	f := o.getVec(otelKindTimer, path, n)
+	return fakeTimerVec(func(l []string) StatTimer {
+		if s, ok := f(l); ok {
+			return s
+		}
+		return DudStat{}
+	})
+}
+
+// GetGauge returns a stat gauge object for a path.
+func (o *OpenTelemetry) GetGauge(path string) StatGauge {
+	if s, ok := o.get(otelKindGauge, path); ok {
+		return s
+	}
+	return DudStat{}
+}
+
+// GetGaugeVec returns a stat gauge object for a path with the labels added as
+// attributes.
+func (o *OpenTelemetry) GetGaugeVec(path string, n []string) StatGaugeVec {
+	f := o.getVec(otelKindGauge, path, n)
+	return fakeGaugeVec(func(l []string) StatGauge {
+		if s, ok := f(l); ok {
+			return s
+		}
+		return DudStat{}
+	})
+}
+
+// SetLogger sets the logger used to print connection errors.
+func (o *OpenTelemetry) SetLogger(log log.Modular) {
+	o.log = log
+}
+
+//------------------------------------------------------------------------------
+
+// buildRequest creates a request containing the current state of all metrics.
+func (o *OpenTelemetry) buildRequest(now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	startNanos, nowNanos := uint64(o.startTime.UnixNano()), uint64(now.UnixNano())
+
+	o.mut.Lock()
+	names := make([]string, 0, len(o.instruments))
+	for k := range o.instruments {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	metrics := make([]*metricspb.Metric, 0, len(names))
+	for _, name := range names {
+		inst := o.instruments[name]
+
+		seriesKeys := make([]string, 0, len(inst.series))
+		for k := range inst.series {
+			seriesKeys = append(seriesKeys, k)
+		}
+		sort.Strings(seriesKeys)
+
+		m := &metricspb.Metric{Name: name}
+		switch inst.kind {
+		case otelKindCounter, otelKindGauge:
+			points := make([]*metricspb.NumberDataPoint, 0, len(seriesKeys))
+			for _, k := range seriesKeys {
+				s := inst.series[k]
+				points = append(points, &metricspb.NumberDataPoint{
+					Attributes:        s.attrs,
+					StartTimeUnixNano: startNanos,
+					TimeUnixNano:      nowNanos,
+					Value:             &metricspb.NumberDataPoint_AsInt{AsInt: atomic.LoadInt64(&s.value)},
+				})
+			}
+			if inst.kind == otelKindCounter {
+				m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+					DataPoints:             points,
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					IsMonotonic:            true,
+				}}
+			} else {
+				m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+					DataPoints: points,
+				}}
+			}
+		case otelKindTimer:
+			m.Unit = "s"
+			points := make([]*metricspb.HistogramDataPoint, 0, len(seriesKeys))
+			for _, k := range seriesKeys {
+				s := inst.series[k]
+				s.mut.Lock()
+				sum := s.sum
+				points = append(points, &metricspb.HistogramDataPoint{
+					Attributes:        s.attrs,
+					StartTimeUnixNano: startNanos,
+					TimeUnixNano:      nowNanos,
+					Count:             s.count,
+					Sum:               &sum,
+					BucketCounts:      append([]uint64{}, s.buckets...),
+					ExplicitBounds:    s.bounds,
+				})
+				s.mut.Unlock()
+			}
+			m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				DataPoints:             points,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			}}
+		}
+		metrics = append(metrics, m)
+	}
+	o.mut.Unlock()
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: o.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope: &commonpb.InstrumentationScope{
+					Name: "benthos",
+				},
+				Metrics: metrics,
+			}},
+		}},
+	}
+}
+
+func (o *OpenTelemetry) push() error {
+	req := o.buildRequest(time.Now())
+
+	ctx, done := context.WithTimeout(context.Background(), o.timeout)
+	defer done()
+
+	if o.grpcClient != nil {
+		if len(o.config.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.config.Headers))
+		}
+		_, err := o.grpcClient.Export(ctx, req)
+		return err
+	}
+
+	reqBytes, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.httpURL, bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range o.config.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	res, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("collector returned status %v: %s", res.StatusCode, body)
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+func (o *OpenTelemetry) loop() {
+	defer close(o.loopClosed)
+
+	ticker := time.NewTicker(o.pushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.push(); err != nil {
+				o.log.Errorf("Failed to push metrics: %v\n", err)
+			}
+		case <-o.shutSig:
+			return
+		}
+	}
+}
+
+// Close stops the OpenTelemetry object from aggregating metrics and pushes
+// the final state of metrics to the collector.
+func (o *OpenTelemetry) Close() error {
+	var err error
+	o.closeOnce.Do(func() {
+		close(o.shutSig)
+		<-o.loopClosed
+
+		if err = o.push(); err != nil {
+			err = fmt.Errorf("failed to push metrics: %w", err)
+		}
+		if o.grpcConn != nil {
+			if cerr := o.grpcConn.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	})
+	return err
+}
+
+//------------------------------------------------------------------------------
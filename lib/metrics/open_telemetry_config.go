@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOpenTelemetry] = TypeSpec{
+		constructor: NewOpenTelemetry,
+		Status:      docs.StatusExperimental,
+		Version:     "3.42.0",
+		Summary: `
+Push metrics to an [OpenTelemetry collector](https://opentelemetry.io/docs/collector/) using the OTLP protocol over gRPC or HTTP.`,
+		Description: `
+Counters are exported as cumulative monotonic sums, gauges as gauges and timing
+metrics as cumulative histograms with values in seconds. Labels of metrics, as
+well as those created with a ` + "`path_mapping`" + `, are added as attributes
+of each data point.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("endpoint", "The host and port of the collector to push metrics to. Collectors typically listen on port `4317` for gRPC and `4318` for HTTP.", "localhost:4317", "otel-collector:4318"),
+			docs.FieldCommon("protocol", "The protocol used to push metrics.").HasOptions("grpc", "http"),
+			docs.FieldCommon("service_name", "The name of the service, which is set as the `service.name` resource attribute."),
+			docs.FieldCommon("resource_attributes", "A map of attributes added to the resource that metrics are attributed to.", map[string]string{
+				"deployment.environment": "production",
+				"service.namespace":      "ingest",
+			}),
+			btls.FieldSpec(),
+			docs.FieldAdvanced("headers", "A map of headers added to each push request.", map[string]string{
+				"Authorization": "Bearer ${OTEL_TOKEN}",
+			}),
+			docs.FieldAdvanced("push_interval", "The period of time between each push to the collector."),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a push to complete."),
+			docs.FieldAdvanced("histogram_buckets", "The explicit bounds of timing histograms in seconds. When empty the default Prometheus buckets are used."),
+			pathMappingDocs(true),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetryConfig contains config fields for the OpenTelemetry metrics
+// type.
+type OpenTelemetryConfig struct {
+	Endpoint           string            `json:"endpoint" yaml:"endpoint"`
+	Protocol           string            `json:"protocol" yaml:"protocol"`
+	ServiceName        string            `json:"service_name" yaml:"service_name"`
+	ResourceAttributes map[string]string `json:"resource_attributes" yaml:"resource_attributes"`
+	TLS                btls.Config       `json:"tls" yaml:"tls"`
+	Headers            map[string]string `json:"headers" yaml:"headers"`
+	PushInterval       string            `json:"push_interval" yaml:"push_interval"`
+	Timeout            string            `json:"timeout" yaml:"timeout"`
+	HistogramBuckets   []float64         `json:"histogram_buckets" yaml:"histogram_buckets"`
+	PathMapping        string            `json:"path_mapping" yaml:"path_mapping"`
+}
+
+// NewOpenTelemetryConfig creates an OpenTelemetryConfig struct with default
+// values.
+func NewOpenTelemetryConfig() OpenTelemetryConfig {
+	return OpenTelemetryConfig{
+		Endpoint:           "localhost:4317",
+		Protocol:           "grpc",
+		ServiceName:        "benthos",
+		ResourceAttributes: map[string]string{},
+		TLS:                btls.NewConfig(),
+		Headers:            map[string]string{},
+		PushInterval:       "10s",
+		Timeout:            "5s",
+		HistogramBuckets:   []float64{},
+		PathMapping:        "",
+	}
+}
+
+//------------------------------------------------------------------------------
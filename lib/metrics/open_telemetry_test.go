@@ -0,0 +1,261 @@
+// +build !wasm
+
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+type otelCollectorStub struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mut      sync.Mutex
+	requests []*colmetricspb.ExportMetricsServiceRequest
+	headers  []string
+}
+
+func (c *otelCollectorStub) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.requests = append(c.requests, req)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		c.headers = append(c.headers, md.Get("x-token")...)
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func (c *otelCollectorStub) last(t *testing.T) *colmetricspb.ExportMetricsServiceRequest {
+	t.Helper()
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	require.NotEmpty(t, c.requests)
+	return c.requests[len(c.requests)-1]
+}
+
+func otelAttrMap(attrs []*commonpb.KeyValue) map[string]string {
+	m := map[string]string{}
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value.GetStringValue()
+	}
+	return m
+}
+
+func otelMetricsByName(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest) map[string]*metricspb.Metric {
+	t.Helper()
+	require.Len(t, req.ResourceMetrics, 1)
+	require.Len(t, req.ResourceMetrics[0].ScopeMetrics, 1)
+	m := map[string]*metricspb.Metric{}
+	for _, metric := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		m[metric.Name] = metric
+	}
+	return m
+}
+
+func writeOTelTestMetrics(o Type) {
+	o.GetCounter("foo.count").Incr(3)
+	o.GetCounterVec("foo.vec", []string{"topic"}).With("a").Incr(1)
+	o.GetCounterVec("foo.vec", []string{"topic"}).With("b").Incr(2)
+	o.GetGauge("foo.gauge").Set(10)
+	o.GetGaugeVec("foo.gauge_vec", []string{"partition"}).With("0").Set(7)
+	o.GetTimer("foo.latency").Timing(int64(20 * time.Millisecond))
+	o.GetTimer("foo.latency").Timing(int64(2 * time.Second))
+	o.GetTimerVec("foo.latency_vec", []string{"topic"}).With("a").Timing(int64(time.Millisecond))
+}
+
+func checkOTelTestMetrics(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	assert.Equal(t, map[string]string{
+		"service.name": "benthos",
+		"env":          "test",
+	}, otelAttrMap(req.ResourceMetrics[0].Resource.Attributes))
+
+	metrics := otelMetricsByName(t, req)
+
+	count := metrics["foo.count"].GetSum()
+	require.NotNil(t, count)
+	assert.True(t, count.IsMonotonic)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, count.AggregationTemporality)
+	require.Len(t, count.DataPoints, 1)
+	assert.Equal(t, int64(3), count.DataPoints[0].GetAsInt())
+
+	vec := metrics["foo.vec"].GetSum()
+	require.NotNil(t, vec)
+	require.Len(t, vec.DataPoints, 2)
+	assert.Equal(t, map[string]string{"topic": "a"}, otelAttrMap(vec.DataPoints[0].Attributes))
+	assert.Equal(t, int64(1), vec.DataPoints[0].GetAsInt())
+	assert.Equal(t, map[string]string{"topic": "b"}, otelAttrMap(vec.DataPoints[1].Attributes))
+	assert.Equal(t, int64(2), vec.DataPoints[1].GetAsInt())
+
+	gauge := metrics["foo.gauge"].GetGauge()
+	require.NotNil(t, gauge)
+	require.Len(t, gauge.DataPoints, 1)
+	assert.Equal(t, int64(10), gauge.DataPoints[0].GetAsInt())
+
+	gaugeVec := metrics["foo.gauge_vec"].GetGauge()
+	require.NotNil(t, gaugeVec)
+	require.Len(t, gaugeVec.DataPoints, 1)
+	assert.Equal(t, map[string]string{"partition": "0"}, otelAttrMap(gaugeVec.DataPoints[0].Attributes))
+	assert.Equal(t, int64(7), gaugeVec.DataPoints[0].GetAsInt())
+
+	assert.Equal(t, "s", metrics["foo.latency"].Unit)
+	latency := metrics["foo.latency"].GetHistogram()
+	require.NotNil(t, latency)
+	require.Len(t, latency.DataPoints, 1)
+	point := latency.DataPoints[0]
+	assert.Equal(t, uint64(2), point.Count)
+	assert.InDelta(t, 2.02, point.GetSum(), 0.0001)
+	assert.Equal(t, []float64{0.01, 0.1, 1}, point.ExplicitBounds)
+	assert.Equal(t, []uint64{0, 1, 0, 1}, point.BucketCounts)
+
+	latencyVec := metrics["foo.latency_vec"].GetHistogram()
+	require.NotNil(t, latencyVec)
+	require.Len(t, latencyVec.DataPoints, 1)
+	assert.Equal(t, map[string]string{"topic": "a"}, otelAttrMap(latencyVec.DataPoints[0].Attributes))
+	assert.Equal(t, []uint64{1, 0, 0, 0}, latencyVec.DataPoints[0].BucketCounts)
+}
+
+func newOTelTestConfig(protocol, endpoint string) Config {
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = protocol
+	conf.OpenTelemetry.Endpoint = endpoint
+	conf.OpenTelemetry.ResourceAttributes = map[string]string{"env": "test"}
+	conf.OpenTelemetry.Headers = map[string]string{"x-token": "foo"}
+	conf.OpenTelemetry.HistogramBuckets = []float64{1, 0.01, 0.1}
+	conf.OpenTelemetry.PushInterval = "1h"
+	return conf
+}
+
+func TestOpenTelemetryGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &otelCollectorStub{}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, collector)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	o, err := NewOpenTelemetry(newOTelTestConfig("grpc", lis.Addr().String()))
+	require.NoError(t, err)
+
+	writeOTelTestMetrics(o)
+	require.NoError(t, o.Close())
+
+	checkOTelTestMetrics(t, collector.last(t))
+	assert.Equal(t, []string{"foo"}, collector.headers)
+}
+
+func TestOpenTelemetryHTTP(t *testing.T) {
+	collector := &otelCollectorStub{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		collector.mut.Lock()
+		collector.headers = append(collector.headers, r.Header.Get("x-token"))
+		collector.mut.Unlock()
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := &colmetricspb.ExportMetricsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+		_, _ = collector.Export(context.Background(), req)
+
+		resBytes, err := proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(resBytes)
+	}))
+	defer server.Close()
+
+	o, err := NewOpenTelemetry(newOTelTestConfig("http", strings.TrimPrefix(server.URL, "http://")))
+	require.NoError(t, err)
+
+	writeOTelTestMetrics(o)
+	require.NoError(t, o.Close())
+
+	checkOTelTestMetrics(t, collector.last(t))
+	assert.Equal(t, []string{"foo"}, collector.headers)
+}
+
+func TestOpenTelemetryPushInterval(t *testing.T) {
+	collector := &otelCollectorStub{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := &colmetricspb.ExportMetricsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+		_, _ = collector.Export(context.Background(), req)
+	}))
+	defer server.Close()
+
+	conf := newOTelTestConfig("http", strings.TrimPrefix(server.URL, "http://"))
+	conf.OpenTelemetry.PushInterval = "10ms"
+
+	o, err := NewOpenTelemetry(conf)
+	require.NoError(t, err)
+	defer o.Close()
+
+	o.GetCounter("foo.count").Incr(5)
+
+	assert.Eventually(t, func() bool {
+		collector.mut.Lock()
+		defer collector.mut.Unlock()
+		return len(collector.requests) > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestOpenTelemetryPathMapping(t *testing.T) {
+	conf := newOTelTestConfig("http", "localhost:4318")
+	conf.OpenTelemetry.PathMapping = `meta component = this.split(".").index(0)
+root = if this.has_prefix("drop") { deleted() } else { this.split(".").slice(1).join(".") }`
+
+	o, err := NewOpenTelemetry(conf)
+	require.NoError(t, err)
+	ot := o.(*OpenTelemetry)
+
+	o.GetCounter("input.received").Incr(1)
+	o.GetCounter("output.received").Incr(2)
+	o.GetCounter("drop.me").Incr(3)
+	o.GetGauge("input.received").Set(10)
+
+	metrics := otelMetricsByName(t, ot.buildRequest(time.Now()))
+	require.Len(t, metrics, 1)
+
+	received := metrics["received"].GetSum()
+	require.NotNil(t, received)
+	require.Len(t, received.DataPoints, 2)
+	assert.Equal(t, map[string]string{"component": "input"}, otelAttrMap(received.DataPoints[0].Attributes))
+	assert.Equal(t, int64(1), received.DataPoints[0].GetAsInt())
+	assert.Equal(t, map[string]string{"component": "output"}, otelAttrMap(received.DataPoints[1].Attributes))
+	assert.Equal(t, int64(2), received.DataPoints[1].GetAsInt())
+}
+
+func TestOpenTelemetryBadProtocol(t *testing.T) {
+	_, err := NewOpenTelemetry(newOTelTestConfig("nope", "localhost:4317"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "protocol 'nope' was not recognised")
+}
@@ -57,12 +57,17 @@ func (p *PromCounter) Incr(count int64) error {
 // PromTiming is a representation of a single metric stat. Interactions with
 // this stat are thread safe.
 type PromTiming struct {
-	sum prometheus.Observer
+	sum       prometheus.Observer
+	asSeconds bool
 }
 
 // Timing sets a timing metric.
 func (p *PromTiming) Timing(val int64) error {
-	p.sum.Observe(float64(val))
+	if p.asSeconds {
+		p.sum.Observe(float64(val) / float64(time.Second))
+	} else {
+		p.sum.Observe(float64(val))
+	}
 	return nil
 }
 
@@ -82,13 +87,15 @@ func (p *PromCounterVec) With(labelValues ...string) StatCounter {
 
 // PromTimingVec creates StatTimers with dynamic labels.
 type PromTimingVec struct {
-	sum *prometheus.SummaryVec
+	sum       prometheus.ObserverVec
+	asSeconds bool
 }
 
 // With returns a StatTimer with a set of label values.
 func (p *PromTimingVec) With(labelValues ...string) StatTimer {
 	return &PromTiming{
-		sum: p.sum.WithLabelValues(labelValues...),
+		sum:       p.sum.WithLabelValues(labelValues...),
+		asSeconds: p.asSeconds,
 	}
 }
 
@@ -116,12 +123,17 @@ type Prometheus struct {
 	config      PrometheusConfig
 	pathMapping *pathMapping
 	prefix      string
+	buckets     []float64
 
-	pusher *push.Pusher
+	pusher  *push.Pusher
+	handler http.Handler
 
 	counters map[string]*prometheus.CounterVec
 	gauges   map[string]*prometheus.GaugeVec
-	timers   map[string]*prometheus.SummaryVec
+	timers   map[string]prometheus.ObserverVec
+
+	// The label names that each metric was registered with.
+	labelNames map[string][]string
 
 	sync.Mutex
 }
@@ -136,7 +148,14 @@ func NewPrometheus(config Config, opts ...func(Type)) (Type, error) {
 		prefix:     config.Prometheus.Prefix,
 		counters:   map[string]*prometheus.CounterVec{},
 		gauges:     map[string]*prometheus.GaugeVec{},
-		timers:     map[string]*prometheus.SummaryVec{},
+		timers:     map[string]prometheus.ObserverVec{},
+		labelNames: map[string][]string{},
+		handler: promhttp.InstrumentMetricHandler(
+			prometheus.DefaultRegisterer,
+			promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+				EnableOpenMetrics: true,
+			}),
+		),
 	}
 
 	for _, opt := range opts {
@@ -148,6 +167,12 @@ func NewPrometheus(config Config, opts ...func(Type)) (Type, error) {
 		return nil, fmt.Errorf("failed to init path mapping: %v", err)
 	}
 
+	if p.config.UseHistogramTiming {
+		if p.buckets = p.config.HistogramBuckets; len(p.buckets) == 0 {
+			p.buckets = prometheus.DefBuckets
+		}
+	}
+
 	if len(p.config.PushURL) > 0 {
 		p.pusher = push.New(p.config.PushURL, p.config.PushJobName).Gatherer(prometheus.DefaultGatherer)
 
@@ -180,43 +205,156 @@ func NewPrometheus(config Config, opts ...func(Type)) (Type, error) {
 
 //------------------------------------------------------------------------------
 
-// HandlerFunc returns an http.HandlerFunc for scraping metrics.
+// HandlerFunc returns an http.HandlerFunc for scraping metrics. The OpenMetrics
+// exposition format is served to scrapers that request it.
 func (p *Prometheus) HandlerFunc() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		promhttp.Handler().ServeHTTP(w, r)
-	}
+	return p.handler.ServeHTTP
 }
 
 //------------------------------------------------------------------------------
 
 func (p *Prometheus) toPromName(dotSepName string) (string, []string, []string) {
+	if p.config.UseLabels {
+		return p.toLabelledPromName(nil, dotSepName)
+	}
 	dotSepName = strings.Replace(dotSepName, "_", "__", -1)
 	dotSepName = strings.Replace(dotSepName, "-", "__", -1)
 	return p.pathMapping.mapPathWithTags(strings.Replace(dotSepName, ".", "_", -1))
 }
 
-// GetCounter returns a stat counter object for a path.
-func (p *Prometheus) GetCounter(path string) StatCounter {
-	stat, labels, values := p.toPromName(path)
+// toLabelledPromName converts a namespace and path into a stable metric name
+// where the component path and label are added as labels.
+func (p *Prometheus) toLabelledPromName(ns []string, path string) (string, []string, []string) {
+	name, componentPath, label := labelledMetric(ns, path)
+	stat, labels, values := p.pathMapping.mapPathWithTags(name)
 	if len(stat) == 0 {
-		return DudStat{}
+		return "", nil, nil
 	}
+	labels = append([]string{"path", "label"}, labels...)
+	values = append([]string{componentPath, label}, values...)
+	return stat, labels, values
+}
+
+// checkLabels returns false, and logs an error, when a metric has previously
+// been registered with different label names. Must be called whilst holding
+// the lock.
+func (p *Prometheus) checkLabels(stat string, labelNames []string) bool {
+	existing, exists := p.labelNames[stat]
+	if !exists {
+		p.labelNames[stat] = labelNames
+		return true
+	}
+	if len(existing) == len(labelNames) {
+		match := true
+		for i, l := range existing {
+			if labelNames[i] != l {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	p.log.Errorf("Metric '%v' was registered with labels %v and cannot also be registered with labels %v\n", stat, existing, labelNames)
+	return false
+}
 
-	var ctr *prometheus.CounterVec
+func (p *Prometheus) counterVec(stat string, labelNames []string) *prometheus.CounterVec {
+	if p.config.UseLabels && !strings.HasSuffix(stat, "_total") {
+		// Counters follow the Prometheus naming conventions when labels are
+		// enabled.
+		stat += "_total"
+	}
 
 	p.Lock()
-	var exists bool
-	if ctr, exists = p.counters[stat]; !exists {
+	defer p.Unlock()
+
+	if !p.checkLabels(stat, labelNames) {
+		return nil
+	}
+	ctr, exists := p.counters[stat]
+	if !exists {
 		ctr = prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: p.prefix,
 			Name:      stat,
 			Help:      "Benthos Counter metric",
-		}, labels)
+		}, labelNames)
 		prometheus.MustRegister(ctr)
 		p.counters[stat] = ctr
 	}
-	p.Unlock()
+	return ctr
+}
+
+func (p *Prometheus) timerVec(stat string, labelNames []string) prometheus.ObserverVec {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.checkLabels(stat, labelNames) {
+		return nil
+	}
+	tmr, exists := p.timers[stat]
+	if !exists {
+		if len(p.buckets) > 0 {
+			hist := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: p.prefix,
+				Name:      stat,
+				Help:      "Benthos Timing metric in seconds",
+				Buckets:   p.buckets,
+			}, labelNames)
+			prometheus.MustRegister(hist)
+			tmr = hist
+		} else {
+			sum := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+				Namespace:  p.prefix,
+				Name:       stat,
+				Help:       "Benthos Timing metric",
+				Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			}, labelNames)
+			prometheus.MustRegister(sum)
+			tmr = sum
+		}
+		p.timers[stat] = tmr
+	}
+	return tmr
+}
+
+func (p *Prometheus) gaugeVec(stat string, labelNames []string) *prometheus.GaugeVec {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.checkLabels(stat, labelNames) {
+		return nil
+	}
+	ctr, exists := p.gauges[stat]
+	if !exists {
+		ctr = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: p.prefix,
+			Name:      stat,
+			Help:      "Benthos Gauge metric",
+		}, labelNames)
+		prometheus.MustRegister(ctr)
+		p.gauges[stat] = ctr
+	}
+	return ctr
+}
+
+//------------------------------------------------------------------------------
+
+// GetCounter returns a stat counter object for a path.
+func (p *Prometheus) GetCounter(path string) StatCounter {
+	stat, labels, values := p.toPromName(path)
+	if len(stat) == 0 {
+		return DudStat{}
+	}
+	return p.getCounter(stat, labels, values)
+}
 
+func (p *Prometheus) getCounter(stat string, labels, values []string) StatCounter {
+	ctr := p.counterVec(stat, labels)
+	if ctr == nil {
+		return DudStat{}
+	}
 	return &PromCounter{
 		ctr: ctr.WithLabelValues(values...),
 	}
@@ -228,25 +366,17 @@ func (p *Prometheus) GetTimer(path string) StatTimer {
 	if len(stat) == 0 {
 		return DudStat{}
 	}
+	return p.getTimer(stat, labels, values)
+}
 
-	var tmr *prometheus.SummaryVec
-
-	p.Lock()
-	var exists bool
-	if tmr, exists = p.timers[stat]; !exists {
-		tmr = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-			Namespace:  p.prefix,
-			Name:       stat,
-			Help:       "Benthos Timing metric",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
-		}, labels)
-		prometheus.MustRegister(tmr)
-		p.timers[stat] = tmr
+func (p *Prometheus) getTimer(stat string, labels, values []string) StatTimer {
+	tmr := p.timerVec(stat, labels)
+	if tmr == nil {
+		return DudStat{}
 	}
-	p.Unlock()
-
 	return &PromTiming{
-		sum: tmr.WithLabelValues(values...),
+		sum:       tmr.WithLabelValues(values...),
+		asSeconds: len(p.buckets) > 0,
 	}
 }
 
@@ -256,22 +386,14 @@ func (p *Prometheus) GetGauge(path string) StatGauge {
 	if len(stat) == 0 {
 		return DudStat{}
 	}
+	return p.getGauge(stat, labels, values)
+}
 
-	var ctr *prometheus.GaugeVec
-
-	p.Lock()
-	var exists bool
-	if ctr, exists = p.gauges[stat]; !exists {
-		ctr = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Namespace: p.prefix,
-			Name:      stat,
-			Help:      "Benthos Gauge metric",
-		}, labels)
-		prometheus.MustRegister(ctr)
-		p.gauges[stat] = ctr
+func (p *Prometheus) getGauge(stat string, labels, values []string) StatGauge {
+	ctr := p.gaugeVec(stat, labels)
+	if ctr == nil {
+		return DudStat{}
 	}
-	p.Unlock()
-
 	return &PromGauge{
 		ctr: ctr.WithLabelValues(values...),
 	}
@@ -287,25 +409,19 @@ func (p *Prometheus) GetCounterVec(path string, labelNames []string) StatCounter
 			return DudStat{}
 		})
 	}
+	return p.getCounterVec(stat, labels, values, labelNames)
+}
+
+func (p *Prometheus) getCounterVec(stat string, labels, values, labelNames []string) StatCounterVec {
 	if len(labels) > 0 {
-		labelNames = append(labels, labelNames...)
+		labelNames = append(append([]string{}, labels...), labelNames...)
 	}
-
-	var ctr *prometheus.CounterVec
-
-	p.Lock()
-	var exists bool
-	if ctr, exists = p.counters[stat]; !exists {
-		ctr = prometheus.NewCounterVec(prometheus.CounterOpts{
-			Namespace: p.prefix,
-			Name:      stat,
-			Help:      "Benthos Counter metric",
-		}, labelNames)
-		prometheus.MustRegister(ctr)
-		p.counters[stat] = ctr
+	ctr := p.counterVec(stat, labelNames)
+	if ctr == nil {
+		return fakeCounterVec(func([]string) StatCounter {
+			return DudStat{}
+		})
 	}
-	p.Unlock()
-
 	if len(labels) > 0 {
 		return fakeCounterVec(func(vs []string) StatCounter {
 			fvs := append([]string{}, values...)
@@ -330,38 +446,31 @@ func (p *Prometheus) GetTimerVec(path string, labelNames []string) StatTimerVec
 			return DudStat{}
 		})
 	}
+	return p.getTimerVec(stat, labels, values, labelNames)
+}
+
+func (p *Prometheus) getTimerVec(stat string, labels, values, labelNames []string) StatTimerVec {
 	if len(labels) > 0 {
-		labelNames = append(labels, labelNames...)
+		labelNames = append(append([]string{}, labels...), labelNames...)
 	}
-
-	var tmr *prometheus.SummaryVec
-
-	p.Lock()
-	var exists bool
-	if tmr, exists = p.timers[stat]; !exists {
-		tmr = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-			Namespace:  p.prefix,
-			Name:       stat,
-			Help:       "Benthos Timing metric",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
-		}, labelNames)
-		prometheus.MustRegister(tmr)
-		p.timers[stat] = tmr
+	tmr := p.timerVec(stat, labelNames)
+	if tmr == nil {
+		return fakeTimerVec(func([]string) StatTimer {
+			return DudStat{}
+		})
+	}
+	tVec := &PromTimingVec{
+		sum:       tmr,
+		asSeconds: len(p.buckets) > 0,
 	}
-	p.Unlock()
-
 	if len(labels) > 0 {
 		return fakeTimerVec(func(vs []string) StatTimer {
 			fvs := append([]string{}, values...)
 			fvs = append(fvs, vs...)
-			return (&PromTimingVec{
-				sum: tmr,
-			}).With(fvs...)
+			return tVec.With(fvs...)
 		})
 	}
-	return &PromTimingVec{
-		sum: tmr,
-	}
+	return tVec
 }
 
 // GetGaugeVec returns an editable gauge stat for a given path with labels,
@@ -374,25 +483,19 @@ func (p *Prometheus) GetGaugeVec(path string, labelNames []string) StatGaugeVec
 			return DudStat{}
 		})
 	}
+	return p.getGaugeVec(stat, labels, values, labelNames)
+}
+
+func (p *Prometheus) getGaugeVec(stat string, labels, values, labelNames []string) StatGaugeVec {
 	if len(labels) > 0 {
-		labelNames = append(labels, labelNames...)
+		labelNames = append(append([]string{}, labels...), labelNames...)
 	}
-
-	var ctr *prometheus.GaugeVec
-
-	p.Lock()
-	var exists bool
-	if ctr, exists = p.gauges[stat]; !exists {
-		ctr = prometheus.NewGaugeVec(prometheus.GaugeOpts{
-			Namespace: p.prefix,
-			Name:      stat,
-			Help:      "Benthos Gauge metric",
-		}, labelNames)
-		prometheus.MustRegister(ctr)
-		p.gauges[stat] = ctr
+	ctr := p.gaugeVec(stat, labelNames)
+	if ctr == nil {
+		return fakeGaugeVec(func([]string) StatGauge {
+			return DudStat{}
+		})
 	}
-	p.Unlock()
-
 	if len(labels) > 0 {
 		return fakeGaugeVec(func(vs []string) StatGauge {
 			fvs := append([]string{}, values...)
@@ -407,6 +510,17 @@ func (p *Prometheus) GetGaugeVec(path string, labelNames []string) StatGaugeVec
 	}
 }
 
+// namespaced exposes namespaces as labels when labels are enabled.
+func (p *Prometheus) namespaced(ns string) (Type, bool) {
+	if !p.config.UseLabels {
+		return nil, false
+	}
+	return &promLabelled{
+		p:  p,
+		ns: splitNamespace(ns),
+	}, true
+}
+
 // SetLogger does nothing.
 func (p *Prometheus) SetLogger(log log.Modular) {
 	p.log = log
@@ -425,3 +539,88 @@ func (p *Prometheus) Close() error {
 }
 
 //------------------------------------------------------------------------------
+
+// promLabelled is a view of a Prometheus type where metrics are namespaced by
+// the path of the component that emits them, which is exposed as labels.
+type promLabelled struct {
+	p  *Prometheus
+	ns []string
+}
+
+func (l *promLabelled) namespaced(ns string) (Type, bool) {
+	fullNS := append([]string{}, l.ns...)
+	fullNS = append(fullNS, splitNamespace(ns)...)
+	return &promLabelled{
+		p:  l.p,
+		ns: fullNS,
+	}, true
+}
+
+// Unwrap to the underlying metrics type.
+func (l *promLabelled) Unwrap() Type {
+	return l.p
+}
+
+func (l *promLabelled) GetCounter(path string) StatCounter {
+	stat, labels, values := l.p.toLabelledPromName(l.ns, path)
+	if len(stat) == 0 {
+		return DudStat{}
+	}
+	return l.p.getCounter(stat, labels, values)
+}
+
+func (l *promLabelled) GetCounterVec(path string, labelNames []string) StatCounterVec {
+	stat, labels, values := l.p.toLabelledPromName(l.ns, path)
+	if len(stat) == 0 {
+		return fakeCounterVec(func([]string) StatCounter {
+			return DudStat{}
+		})
+	}
+	return l.p.getCounterVec(stat, labels, values, labelNames)
+}
+
+func (l *promLabelled) GetTimer(path string) StatTimer {
+	stat, labels, values := l.p.toLabelledPromName(l.ns, path)
+	if len(stat) == 0 {
+		return DudStat{}
+	}
+	return l.p.getTimer(stat, labels, values)
+}
+
+func (l *promLabelled) GetTimerVec(path string, labelNames []string) StatTimerVec {
+	stat, labels, values := l.p.toLabelledPromName(l.ns, path)
+	if len(stat) == 0 {
+		return fakeTimerVec(func([]string) StatTimer {
+			return DudStat{}
+		})
+	}
+	return l.p.getTimerVec(stat, labels, values, labelNames)
+}
+
+func (l *promLabelled) GetGauge(path string) StatGauge {
+	stat, labels, values := l.p.toLabelledPromName(l.ns, path)
+	if len(stat) == 0 {
+		return DudStat{}
+	}
+	return l.p.getGauge(stat, labels, values)
+}
+
+func (l *promLabelled) GetGaugeVec(path string, labelNames []string) StatGaugeVec {
+	stat, labels, values := l.p.toLabelledPromName(l.ns, path)
+	if len(stat) == 0 {
+		return fakeGaugeVec(func([]string) StatGauge {
+			return DudStat{}
+		})
+	}
+	return l.p.getGaugeVec(stat, labels, values, labelNames)
+}
+
+func (l *promLabelled) SetLogger(log log.Modular) {
+	l.p.SetLogger(log)
+}
+
+func (l *promLabelled) Close() error {
+	return l.p.Close()
+}
+
+//------------------------------------------------------------------------------
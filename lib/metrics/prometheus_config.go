@@ -15,6 +15,9 @@ instead be underscores.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("prefix", "A string prefix to add to all metrics."),
 			pathMappingDocs(true),
+			docs.FieldAdvanced("use_labels", "Whether to name metrics after the type of component that emits them, with the path of the component within the config and the name of resources added as the labels `path` and `label`. See [labelled metrics](#labelled-metrics)."),
+			docs.FieldAdvanced("use_histogram_timing", "Whether to export timing metrics as histograms in seconds rather than summaries in nanoseconds."),
+			docs.FieldAdvanced("histogram_buckets", "The buckets of timing histograms in seconds. When empty the default Prometheus buckets are used."),
 			docs.FieldAdvanced("push_url", "An optional [Push Gateway URL](#push-gateway) to push metrics to."),
 			docs.FieldAdvanced("push_interval", "The period of time between each push when sending metrics to a Push Gateway."),
 			docs.FieldAdvanced("push_job_name", "An identifier for push jobs."),
@@ -24,6 +27,35 @@ instead be underscores.`,
 			),
 		},
 		Footnotes: `
+## Labelled Metrics
+
+By default the full path of each metric is converted into a metric name, e.g.
+` + "`pipeline.processor.0.count`" + ` becomes ` + "`benthos_pipeline_processor_0_count`" + `,
+which results in a distinct metric for each component.
+
+When ` + "`use_labels`" + ` is enabled metric names are instead stable, consisting
+of the type of the component that emits a metric followed by the name of the
+metric, and the component is identified with labels. For example, the metric
+above becomes ` + "`benthos_processor_count{path=\"pipeline.processor.0\",label=\"\"}`" + `,
+and a metric ` + "`resource.cache.foo.get.success`" + ` becomes
+` + "`benthos_cache_get_success{path=\"resource.cache.foo\",label=\"foo\"}`" + `.
+Components may also add labels of their own, such as ` + "`topic`" + ` and
+` + "`partition`" + `. When labels are enabled the ` + "`path_mapping`" + `
+is applied to the stable metric name.
+
+Labels are only applied when the ` + "`prometheus`" + ` type is used directly,
+and not as the child of another metrics type such as ` + "`rename`" + `.
+
+## Histograms
+
+When ` + "`use_histogram_timing`" + ` is enabled timing metrics are exported as
+histograms, with values in seconds, which can be aggregated across instances.
+
+## OpenMetrics
+
+The ` + "`/metrics`" + ` endpoint serves the OpenMetrics exposition format to
+scrapers that request it, and the classic Prometheus text format otherwise.
+
 ## Push Gateway
 
 The field ` + "`push_url`" + ` is optional and when set will trigger a push of
@@ -43,12 +75,15 @@ If the Push Gateway requires HTTP Basic Authentication it can be configured with
 
 // PrometheusConfig is config for the Prometheus metrics type.
 type PrometheusConfig struct {
-	Prefix        string                        `json:"prefix" yaml:"prefix"`
-	PathMapping   string                        `json:"path_mapping" yaml:"path_mapping"`
-	PushURL       string                        `json:"push_url" yaml:"push_url"`
-	PushBasicAuth PrometheusPushBasicAuthConfig `json:"push_basic_auth" yaml:"push_basic_auth"`
-	PushInterval  string                        `json:"push_interval" yaml:"push_interval"`
-	PushJobName   string                        `json:"push_job_name" yaml:"push_job_name"`
+	Prefix             string                        `json:"prefix" yaml:"prefix"`
+	PathMapping        string                        `json:"path_mapping" yaml:"path_mapping"`
+	UseLabels          bool                          `json:"use_labels" yaml:"use_labels"`
+	UseHistogramTiming bool                          `json:"use_histogram_timing" yaml:"use_histogram_timing"`
+	HistogramBuckets   []float64                     `json:"histogram_buckets" yaml:"histogram_buckets"`
+	PushURL            string                        `json:"push_url" yaml:"push_url"`
+	PushBasicAuth      PrometheusPushBasicAuthConfig `json:"push_basic_auth" yaml:"push_basic_auth"`
+	PushInterval       string                        `json:"push_interval" yaml:"push_interval"`
+	PushJobName        string                        `json:"push_job_name" yaml:"push_job_name"`
 }
 
 // PrometheusPushBasicAuthConfig contains parameters for establishing basic
@@ -69,12 +104,15 @@ func NewPrometheusPushBasicAuthConfig() PrometheusPushBasicAuthConfig {
 // NewPrometheusConfig creates an PrometheusConfig struct with default values.
 func NewPrometheusConfig() PrometheusConfig {
 	return PrometheusConfig{
-		Prefix:        "benthos",
-		PathMapping:   "",
-		PushURL:       "",
-		PushBasicAuth: NewPrometheusPushBasicAuthConfig(),
-		PushInterval:  "",
-		PushJobName:   "benthos_push",
+		Prefix:             "benthos",
+		PathMapping:        "",
+		UseLabels:          false,
+		UseHistogramTiming: false,
+		HistogramBuckets:   []float64{},
+		PushURL:            "",
+		PushBasicAuth:      NewPrometheusPushBasicAuthConfig(),
+		PushInterval:       "",
+		PushJobName:        "benthos_push",
 	}
 }
 
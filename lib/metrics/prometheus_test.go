@@ -3,10 +3,12 @@ package metrics
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPrometheusNoPushGateway(t *testing.T) {
@@ -82,3 +84,37 @@ func TestPrometheusWithPushGatewayAndPushInterval(t *testing.T) {
 		assert.Fail(t, "PushGateway did not receive expected messages after close")
 	}
 }
+
+func TestPrometheusLabelled(t *testing.T) {
+	config := NewConfig()
+	config.Prometheus.Prefix = "labelled_test"
+	config.Prometheus.UseLabels = true
+	config.Prometheus.UseHistogramTiming = true
+	config.Prometheus.HistogramBuckets = []float64{0.5, 1}
+
+	p, err := NewPrometheus(config)
+	require.NoError(t, err)
+
+	Namespaced(Namespaced(p, "pipeline"), "processor.0").GetCounter("count").Incr(2)
+	Namespaced(Namespaced(p, "pipeline"), "processor.1").GetCounter("count").Incr(3)
+	Namespaced(p, "resource.cache.foo").GetTimer("get.latency").Timing(int64(time.Millisecond * 700))
+	Namespaced(p, "input").GetCounterVec("partition.received", []string{"topic", "partition"}).With("foo", "3").Incr(1)
+
+	// Registering a metric again with different labels is ignored.
+	Namespaced(p, "input").GetCounterVec("partition.received", []string{"topic"}).With("foo").Incr(1)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	rec := httptest.NewRecorder()
+	p.(WithHandlerFunc).HandlerFunc()(rec, req)
+
+	assert.Contains(t, rec.Header().Get("Content-Type"), "application/openmetrics-text")
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `labelled_test_processor_count_total{label="",path="pipeline.processor.0"} 2`)
+	assert.Contains(t, body, `labelled_test_processor_count_total{label="",path="pipeline.processor.1"} 3`)
+	assert.Contains(t, body, `labelled_test_cache_get_latency_bucket{label="foo",path="resource.cache.foo",le="0.5"} 0`)
+	assert.Contains(t, body, `labelled_test_cache_get_latency_bucket{label="foo",path="resource.cache.foo",le="1.0"} 1`)
+	assert.Contains(t, body, `labelled_test_input_partition_received_total{label="",partition="3",path="input",topic="foo"} 1`)
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
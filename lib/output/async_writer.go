@@ -2,6 +2,7 @@ package output
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
 	"github.com/Jeffail/benthos/v3/lib/util/throttle"
 )
 
@@ -42,6 +44,7 @@ type AsyncWriter struct {
 
 	typeStr     string
 	maxInflight int
+	limiter     *concurrency.Limiter
 	writer      AsyncSink
 
 	log   log.Modular
@@ -82,6 +85,35 @@ func NewAsyncWriter(
 	return aWriter, nil
 }
 
+// NewAdaptiveAsyncWriter creates a new AsyncWriter output type where, when
+// enabled, the number of messages in flight is adjusted between the minimum and
+// maximum of the adaptive concurrency config according to the latency and
+// errors of writes, and maxInflight is ignored. When adaptive concurrency is
+// disabled this is equivalent to NewAsyncWriter.
+func NewAdaptiveAsyncWriter(
+	typeStr string,
+	maxInflight int,
+	conf concurrency.Config,
+	w AsyncSink,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	var limiter *concurrency.Limiter
+	if conf.Enabled {
+		var err error
+		if limiter, err = conf.NewLimiter(); err != nil {
+			return nil, fmt.Errorf("failed to create adaptive concurrency limiter: %v", err)
+		}
+		maxInflight = conf.MaxInFlight
+	}
+	t, err := NewAsyncWriter(typeStr, maxInflight, w, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	t.(*AsyncWriter).limiter = limiter
+	return t, nil
+}
+
 //------------------------------------------------------------------------------
 
 func (w *AsyncWriter) latencyMeasuringWrite(msg types.Message) (latencyNs int64, err error) {
@@ -100,9 +132,11 @@ func (w *AsyncWriter) loop() {
 		mSent       = w.stats.GetCounter("batch.sent")
 		mBytesSent  = w.stats.GetCounter("batch.bytes")
 		mLatency    = w.stats.GetTimer("batch.latency")
+		mE2ELatency = w.stats.GetTimer("end_to_end.latency")
 		mConn       = w.stats.GetCounter("connection.up")
 		mFailedConn = w.stats.GetCounter("connection.failed")
 		mLostConn   = w.stats.GetCounter("connection.lost")
+		mLimit      = w.stats.GetGauge("in_flight.limit")
 	)
 
 	defer func() {
@@ -179,6 +213,18 @@ func (w *AsyncWriter) loop() {
 		return
 	}
 
+	acquire, release := func() bool { return true }, func(int64, error) {}
+	if w.limiter != nil {
+		mLimit.Set(int64(w.limiter.Limit()))
+		acquire = func() bool {
+			return w.limiter.Acquire(w.ctx)
+		}
+		release = func(latency int64, err error) {
+			w.limiter.Release(time.Duration(latency), err != nil)
+			mLimit.Set(int64(w.limiter.Limit()))
+		}
+	}
+
 	writerLoop := func() {
 		defer wg.Done()
 
@@ -195,6 +241,16 @@ func (w *AsyncWriter) loop() {
 				return
 			}
 
+			// The slot is acquired once a transaction is received so that it
+			// is always released.
+			if !acquire() {
+				select {
+				case ts.ResponseChan <- response.NewError(types.ErrTypeClosed):
+				case <-w.ctx.Done():
+				}
+				return
+			}
+
 			w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 			spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
 			latency, err := w.latencyMeasuringWrite(ts.Payload)
@@ -203,6 +259,7 @@ func (w *AsyncWriter) loop() {
 			if err == types.ErrNotConnected {
 				latency, err = connectLoop(ts.Payload)
 			}
+			release(latency, err)
 
 			// Close immediately if our writer is closed.
 			if err == types.ErrTypeClosed {
@@ -223,6 +280,7 @@ func (w *AsyncWriter) loop() {
 				mPartsSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
 				mBytesSent.Incr(int64(message.GetAllBytesLen(ts.Payload)))
 				mLatency.Timing(latency)
+				recordEndToEndLatency(mE2ELatency, ts.Payload)
 				w.log.Tracef("Successfully wrote %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 				throt.Reset() // TODO BAD PAYLOAD NAUGHTY RESETS
 			}
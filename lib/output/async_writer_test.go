@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //------------------------------------------------------------------------------
@@ -566,6 +570,68 @@ func TestAsyncWriterHappyPath(t *testing.T) {
 	}
 }
 
+func TestAsyncWriterEndToEndLatency(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newMockWriter()
+	stats := metrics.NewLocal()
+
+	w, err := NewAsyncWriter(
+		"foo", 1, writerImpl,
+		log.Noop(), stats,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = w.Consume(msgChan); err != nil {
+		t.Error(err)
+	}
+
+	msg := message.New([][]byte{[]byte("foo")})
+	message.InitIngestionTimes(time.Now().Add(-time.Second), msg)
+
+	go func() {
+		select {
+		case msgChan <- types.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case writerImpl.writeChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err := res.Error(); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	if act := stats.GetTimings()["end_to_end.latency"]; act < int64(time.Second) {
+		t.Errorf("Wrong end to end latency: %v", time.Duration(act))
+	}
+}
+
 func TestAsyncWriterSadPath(t *testing.T) {
 	t.Parallel()
 
@@ -635,3 +701,82 @@ func TestAsyncWriterSadPath(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+//------------------------------------------------------------------------------
+
+type concurrencyCountingWriter struct {
+	inFlight    int32
+	maxInFlight int32
+	release     chan struct{}
+}
+
+func (w *concurrencyCountingWriter) ConnectWithContext(ctx context.Context) error {
+	return nil
+}
+func (w *concurrencyCountingWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	n := atomic.AddInt32(&w.inFlight, 1)
+	for {
+		m := atomic.LoadInt32(&w.maxInFlight)
+		if n <= m || atomic.CompareAndSwapInt32(&w.maxInFlight, m, n) {
+			break
+		}
+	}
+	<-w.release
+	atomic.AddInt32(&w.inFlight, -1)
+	return nil
+}
+func (w *concurrencyCountingWriter) CloseAsync() {}
+func (w *concurrencyCountingWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestAsyncWriterAdaptiveConcurrency(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := &concurrencyCountingWriter{
+		release: make(chan struct{}),
+	}
+
+	conf := concurrency.NewConfig()
+	conf.Enabled = true
+	conf.MinInFlight = 2
+	conf.MaxInFlight = 10
+
+	stats := metrics.NewLocal()
+	w, err := NewAdaptiveAsyncWriter("foo", 1, conf, writerImpl, log.Noop(), stats)
+	require.NoError(t, err)
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response, 100)
+	require.NoError(t, w.Consume(msgChan))
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			msgChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan)
+		}
+	}()
+
+	<-time.After(time.Millisecond * 100)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&writerImpl.inFlight))
+
+	for i := 0; i < 100; i++ {
+		select {
+		case writerImpl.release <- struct{}{}:
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	assert.Greater(t, atomic.LoadInt32(&writerImpl.maxInFlight), int32(2))
+	assert.LessOrEqual(t, atomic.LoadInt32(&writerImpl.maxInFlight), int32(10))
+	assert.Greater(t, stats.GetCounters()["in_flight.limit"], int64(2))
+
+	w.CloseAsync()
+	require.NoError(t, w.WaitForClose(time.Second))
+}
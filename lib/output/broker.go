@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/broker"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -86,16 +88,57 @@ potentially disproportionate message allocations to those outputs. Each message
 is sent to a single output, which is determined by allowing outputs to claim
 messages as soon as they are able to process them. This results in certain
 faster outputs potentially processing more messages at the cost of slower
-outputs.`,
+outputs.
+
+### ` + "`hash`" + `
+
+With the hash pattern each message is sent to a single output chosen by
+consistent hashing of the interpolated string ` + "`key`" + `, and therefore
+messages that share a key are always sent to the same output. When outputs are
+added or removed from the end of the list only a proportional share of keys are
+moved to a different output.
+
+Batches are split into a batch for each output, and a batch is only acknowledged
+once all outputs have acknowledged their part. If an output fails to send its
+part it will be retried continuously until completion or service shut down.
+
+### ` + "`weighted`" + `
+
+With the weighted pattern each message is sent to a single output chosen at
+random in proportion to the ` + "`weights`" + ` of each output, which is useful
+for directing a percentage of traffic to a canary output:
+
+` + "```yaml" + `
+output:
+  broker:
+    pattern: weighted
+    weights: [ 95, 5 ]
+    outputs:
+      - resource: stable
+      - resource: canary
+` + "```" + `
+
+When ` + "`key`" + ` is set outputs are chosen by a hash of the key rather than
+at random, which results in messages that share a key always being sent to the
+same output. Batches are split and retried in the same way as the ` + "`hash`" + `
+pattern.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldAdvanced("copies", "The number of copies of each configured output to spawn."),
 			docs.FieldCommon("pattern", "The brokering pattern to use.").HasOptions(
-				"fan_out", "fan_out_sequential", "round_robin", "greedy",
+				"fan_out", "fan_out_sequential", "round_robin", "greedy", "hash", "weighted",
 			),
 			docs.FieldCommon(
 				"max_in_flight",
-				"The maximum number of messages to dispatch at any given time. Only relevant for `fan_out`, `fan_out_sequential` brokers.",
+				"The maximum number of messages to dispatch at any given time. Only relevant for `fan_out`, `fan_out_sequential`, `hash` and `weighted` brokers.",
 			),
+			docs.FieldCommon(
+				"key", "An interpolated string that determines the output of each message. Required for the `hash` pattern and optional for the `weighted` pattern.",
+				`${! meta("kafka_key") }`, `${! json("user_id") }`,
+			).SupportsInterpolation(false).AtVersion("3.42.0"),
+			docs.FieldCommon(
+				"weights", "A list of weights for the `weighted` pattern, one for each output, that determine the proportion of messages sent to each output.",
+				[]float64{95, 5},
+			).AtVersion("3.42.0"),
 			docs.FieldCommon("outputs", "A list of child outputs to broker."),
 			batch.FieldSpec(),
 		},
@@ -117,6 +160,8 @@ outputs.`,
 				"copies":        conf.Broker.Copies,
 				"pattern":       conf.Broker.Pattern,
 				"max_in_flight": conf.Broker.MaxInFlight,
+				"key":           conf.Broker.Key,
+				"weights":       conf.Broker.Weights,
 				"outputs":       outSlice,
 				"batching":      batchSanit,
 			}, nil
@@ -134,6 +179,8 @@ type BrokerConfig struct {
 	Copies      int                `json:"copies" yaml:"copies"`
 	Pattern     string             `json:"pattern" yaml:"pattern"`
 	MaxInFlight int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Key         string             `json:"key" yaml:"key"`
+	Weights     []float64          `json:"weights" yaml:"weights"`
 	Outputs     brokerOutputList   `json:"outputs" yaml:"outputs"`
 	Batching    batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
@@ -144,6 +191,8 @@ func NewBrokerConfig() BrokerConfig {
 		Copies:      1,
 		Pattern:     "fan_out",
 		MaxInFlight: 1,
+		Key:         "",
+		Weights:     []float64{},
 		Outputs:     brokerOutputList{},
 		Batching:    batch.NewPolicyConfig(),
 	}
@@ -224,6 +273,36 @@ func NewBroker(
 		b, err = broker.NewGreedy(outputs)
 	case "try":
 		b, err = broker.NewTry(outputs, stats)
+	case "hash":
+		if conf.Broker.Key == "" {
+			return nil, errors.New("a key must be specified for the hash pattern")
+		}
+		var key field.Expression
+		if key, err = bloblang.NewField(conf.Broker.Key); err != nil {
+			return nil, fmt.Errorf("failed to parse key expression: %v", err)
+		}
+		var bTmp *broker.Routed
+		if bTmp, err = broker.NewHash(outputs, key, log, stats); err == nil {
+			b = bTmp.WithMaxInFlight(conf.Broker.MaxInFlight)
+		}
+	case "weighted":
+		if len(conf.Broker.Weights) != len(outputConfs) {
+			return nil, fmt.Errorf("expected %v weights to match the number of outputs, got %v", len(outputConfs), len(conf.Broker.Weights))
+		}
+		weights := make([]float64, 0, lOutputs)
+		for j := 0; j < conf.Broker.Copies; j++ {
+			weights = append(weights, conf.Broker.Weights...)
+		}
+		var key field.Expression
+		if conf.Broker.Key != "" {
+			if key, err = bloblang.NewField(conf.Broker.Key); err != nil {
+				return nil, fmt.Errorf("failed to parse key expression: %v", err)
+			}
+		}
+		var bTmp *broker.Routed
+		if bTmp, err = broker.NewWeighted(outputs, weights, key, log, stats); err == nil {
+			b = bTmp.WithMaxInFlight(conf.Broker.MaxInFlight)
+		}
 	default:
 		return nil, fmt.Errorf("broker pattern was not recognised: %v", conf.Broker.Pattern)
 	}
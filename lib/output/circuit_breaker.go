@@ -0,0 +1,396 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/circuit"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCircuitBreaker] = TypeSpec{
+		constructor: fromSimpleConstructor(NewCircuitBreaker),
+		Status:      docs.StatusBeta,
+		Version:     "3.42.0",
+		Summary: `
+Writes messages to a child output and stops attempting to do so when the rate of
+failed writes exceeds a threshold, either failing messages immediately, routing
+them to a fallback output, or pausing until the child output is attempted
+again.`,
+		Description: `
+The circuit breaker begins in a closed state where all messages are written to
+the child output. When the ratio of failed writes within ` + "`window`" + ` reaches
+` + "`error_threshold`" + ` the circuit opens and, for the duration of
+` + "`open_period`" + `, messages are handled according to ` + "`on_open`" + `.
+
+Once ` + "`open_period`" + ` has passed the circuit becomes half-open, where a
+limited number of messages are written to the child output. If they succeed the
+circuit closes again, otherwise it reopens.
+
+The state of the circuit breaker is exposed as the gauge
+` + "`circuit_breaker.state`" + `, where ` + "`0`" + ` is closed, ` + "`1`" + ` is
+half-open and ` + "`2`" + ` is open. When a ` + "`name`" + ` is specified the
+state can also be queried from the HTTP endpoint
+` + "`/circuit_breakers/{name}`" + `.`,
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.CircuitBreaker)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.CircuitBreaker.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.CircuitBreaker.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+
+			var fallbackSanit interface{} = struct{}{}
+			if conf.CircuitBreaker.Fallback != nil {
+				if fallbackSanit, err = SanitiseConfig(*conf.CircuitBreaker.Fallback); err != nil {
+					return nil, err
+				}
+			}
+			confMap["fallback"] = fallbackSanit
+			return confMap, nil
+		},
+		FieldSpecs: circuit.FieldSpecs().Add(
+			docs.FieldCommon("on_open", "How messages are handled while the circuit is open.").HasAnnotatedOptions(
+				"fail_fast", "Messages are rejected immediately with an error without being written to the child output.",
+				"fallback", "Messages are written to the `fallback` output instead.",
+				"pause", "Messages are held until the circuit becomes half-open, which applies back pressure to the input.",
+			),
+			docs.FieldCommon("output", "A child output."),
+			docs.FieldCommon("fallback", "An output to write messages to while the circuit is open, required when `on_open` is `fallback`."),
+		),
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Dead letter queue while open",
+				Summary: "In this example messages are sent to an HTTP endpoint, and when more than half of the requests within ten seconds fail the messages are written to a Kafka topic instead for one minute before the endpoint is attempted again.",
+				Config: `
+output:
+  circuit_breaker:
+    name: foo_api
+    error_threshold: 0.5
+    window: 10s
+    open_period: 1m
+    on_open: fallback
+    output:
+      http_client:
+        url: http://example.com/foo/messages
+        verb: POST
+    fallback:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: foo_dead_letter
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CircuitBreakerConfig contains configuration values for the CircuitBreaker
+// output type.
+type CircuitBreakerConfig struct {
+	circuit.Config `json:",inline" yaml:",inline"`
+	OnOpen         string  `json:"on_open" yaml:"on_open"`
+	Output         *Config `json:"output" yaml:"output"`
+	Fallback       *Config `json:"fallback" yaml:"fallback"`
+}
+
+// NewCircuitBreakerConfig creates a new CircuitBreakerConfig with default
+// values.
+func NewCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Config:   circuit.NewConfig(),
+		OnOpen:   "fail_fast",
+		Output:   nil,
+		Fallback: nil,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyCircuitBreakerConfig struct {
+	circuit.Config `json:",inline" yaml:",inline"`
+	OnOpen         string      `json:"on_open" yaml:"on_open"`
+	Output         interface{} `json:"output" yaml:"output"`
+	Fallback       interface{} `json:"fallback" yaml:"fallback"`
+}
+
+func (c CircuitBreakerConfig) dummy() dummyCircuitBreakerConfig {
+	dummy := dummyCircuitBreakerConfig{
+		Config:   c.Config,
+		OnOpen:   c.OnOpen,
+		Output:   c.Output,
+		Fallback: c.Fallback,
+	}
+	if c.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if c.Fallback == nil {
+		dummy.Fallback = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (c CircuitBreakerConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (c CircuitBreakerConfig) MarshalYAML() (interface{}, error) {
+	return c.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// CircuitBreaker is an output type that writes messages to a child output
+// until the rate of errors exceeds a threshold, at which point messages are
+// handled according to the configured behaviour while the circuit is open.
+type CircuitBreaker struct {
+	onOpen   string
+	breaker  *circuit.Breaker
+	wrapped  Type
+	fallback Type
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+	fallbackOut     chan types.Transaction
+
+	ctx        context.Context
+	done       func()
+	closedChan chan struct{}
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker output type.
+func NewCircuitBreaker(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	if conf.CircuitBreaker.Output == nil {
+		return nil, errors.New("cannot create circuit_breaker output without a child")
+	}
+
+	switch conf.CircuitBreaker.OnOpen {
+	case "fail_fast", "pause":
+	case "fallback":
+		if conf.CircuitBreaker.Fallback == nil {
+			return nil, errors.New("a fallback output is required when on_open is fallback")
+		}
+	default:
+		return nil, fmt.Errorf("on_open behaviour not recognised: %v", conf.CircuitBreaker.OnOpen)
+	}
+
+	breaker, err := conf.CircuitBreaker.NewBreaker(stats)
+	if err != nil {
+		return nil, err
+	}
+
+	if mgr != nil {
+		if err = breaker.RegisterEndpoint(mgr); err != nil {
+			return nil, err
+		}
+	}
+
+	wrapped, err := New(*conf.CircuitBreaker.Output, mgr, log, stats)
+	if err != nil {
+		breaker.Close()
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.CircuitBreaker.Output.Type, err)
+	}
+
+	var fallback Type
+	if conf.CircuitBreaker.OnOpen == "fallback" {
+		if fallback, err = New(
+			*conf.CircuitBreaker.Fallback, mgr,
+			log.NewModule(".fallback"),
+			metrics.Namespaced(stats, "fallback"),
+		); err != nil {
+			wrapped.CloseAsync()
+			breaker.Close()
+			return nil, fmt.Errorf("failed to create fallback output '%v': %v", conf.CircuitBreaker.Fallback.Type, err)
+		}
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	return &CircuitBreaker{
+		onOpen:   conf.CircuitBreaker.OnOpen,
+		breaker:  breaker,
+		wrapped:  wrapped,
+		fallback: fallback,
+
+		log:   log,
+		stats: stats,
+
+		transactionsOut: make(chan types.Transaction),
+		fallbackOut:     make(chan types.Transaction),
+
+		ctx:        ctx,
+		done:       done,
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *CircuitBreaker) loop() {
+	mFallback := c.stats.GetCounter("circuit_breaker.batch.fallback")
+
+	wg := sync.WaitGroup{}
+
+	defer func() {
+		wg.Wait()
+		close(c.transactionsOut)
+		close(c.fallbackOut)
+		c.wrapped.CloseAsync()
+		if c.fallback != nil {
+			c.fallback.CloseAsync()
+		}
+		for c.wrapped.WaitForClose(time.Second) != nil {
+		}
+		if c.fallback != nil {
+			for c.fallback.WaitForClose(time.Second) != nil {
+			}
+		}
+		c.breaker.Close()
+		close(c.closedChan)
+	}()
+
+	forward := func(ts types.Transaction, out chan<- types.Transaction, permit *circuit.Permit) bool {
+		resChan := make(chan types.Response)
+		select {
+		case out <- types.NewTransaction(ts.Payload, resChan):
+		case <-c.ctx.Done():
+			return false
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var res types.Response
+			select {
+			case res = <-resChan:
+			case <-c.ctx.Done():
+				return
+			}
+			if permit != nil {
+				c.breaker.Record(*permit, res.Error() != nil)
+			}
+			select {
+			case ts.ResponseChan <- res:
+			case <-c.ctx.Done():
+			}
+		}()
+		return true
+	}
+
+	for {
+		var ts types.Transaction
+		var open bool
+		select {
+		case ts, open = <-c.transactionsIn:
+			if !open {
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+
+		permit, allowed := c.breaker.Allow()
+		if !allowed && c.onOpen == "pause" {
+			if permit, allowed = c.breaker.Wait(c.ctx); !allowed {
+				return
+			}
+		}
+
+		if allowed {
+			if !forward(ts, c.transactionsOut, &permit) {
+				return
+			}
+			continue
+		}
+
+		if c.onOpen == "fallback" {
+			mFallback.Incr(1)
+			if !forward(ts, c.fallbackOut, nil) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case ts.ResponseChan <- response.NewError(circuit.ErrOpen):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (c *CircuitBreaker) Consume(ts <-chan types.Transaction) error {
+	if c.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := c.wrapped.Consume(c.transactionsOut); err != nil {
+		return err
+	}
+	if c.fallback != nil {
+		if err := c.fallback.Consume(c.fallbackOut); err != nil {
+			close(c.transactionsOut)
+			c.wrapped.CloseAsync()
+			c.breaker.Close()
+			return err
+		}
+	}
+	c.transactionsIn = ts
+	go c.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (c *CircuitBreaker) Connected() bool {
+	return c.wrapped.Connected()
+}
+
+// CloseAsync shuts down the CircuitBreaker output and stops processing
+// requests.
+func (c *CircuitBreaker) CloseAsync() {
+	c.done()
+}
+
+// WaitForClose blocks until the CircuitBreaker output has closed down.
+func (c *CircuitBreaker) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-c.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
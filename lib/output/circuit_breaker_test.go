@@ -0,0 +1,170 @@
+package output
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/circuit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func circuitBreakerTestServer(t *testing.T, status int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &count
+}
+
+func circuitBreakerSend(t *testing.T, tChan chan types.Transaction, rChan chan types.Response) types.Response {
+	t.Helper()
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("foobar")}), rChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-rChan:
+		return res
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func httpClientConf(url string) *Config {
+	conf := NewConfig()
+	conf.Type = TypeHTTPClient
+	conf.HTTPClient.URL = url
+	conf.HTTPClient.NumRetries = 0
+	return &conf
+}
+
+func TestCircuitBreakerFailFast(t *testing.T) {
+	ts, count := circuitBreakerTestServer(t, http.StatusInternalServerError)
+
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.MinRequests = 2
+	conf.CircuitBreaker.OpenPeriod = "1h"
+	conf.CircuitBreaker.Output = httpClientConf(ts.URL)
+
+	o, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		o.CloseAsync()
+		assert.NoError(t, o.WaitForClose(time.Second*5))
+	})
+
+	tChan, rChan := make(chan types.Transaction), make(chan types.Response)
+	require.NoError(t, o.Consume(tChan))
+
+	for i := 0; i < 2; i++ {
+		res := circuitBreakerSend(t, tChan, rChan)
+		require.Error(t, res.Error())
+		assert.NotEqual(t, circuit.ErrOpen, res.Error())
+	}
+
+	res := circuitBreakerSend(t, tChan, rChan)
+	assert.Equal(t, circuit.ErrOpen, res.Error())
+	assert.Equal(t, int32(2), atomic.LoadInt32(count))
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	ts, count := circuitBreakerTestServer(t, http.StatusInternalServerError)
+	fallbackTS, fallbackCount := circuitBreakerTestServer(t, http.StatusOK)
+
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.MinRequests = 1
+	conf.CircuitBreaker.OpenPeriod = "1h"
+	conf.CircuitBreaker.OnOpen = "fallback"
+	conf.CircuitBreaker.Output = httpClientConf(ts.URL)
+	conf.CircuitBreaker.Fallback = httpClientConf(fallbackTS.URL)
+
+	o, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		o.CloseAsync()
+		assert.NoError(t, o.WaitForClose(time.Second*5))
+	})
+
+	tChan, rChan := make(chan types.Transaction), make(chan types.Response)
+	require.NoError(t, o.Consume(tChan))
+
+	require.Error(t, circuitBreakerSend(t, tChan, rChan).Error())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, circuitBreakerSend(t, tChan, rChan).Error())
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(count))
+	assert.Equal(t, int32(3), atomic.LoadInt32(fallbackCount))
+}
+
+func TestCircuitBreakerPause(t *testing.T) {
+	var fail int32 = 1
+	var count int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.MinRequests = 1
+	conf.CircuitBreaker.OpenPeriod = "100ms"
+	conf.CircuitBreaker.OnOpen = "pause"
+	conf.CircuitBreaker.Output = httpClientConf(ts.URL)
+
+	o, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		o.CloseAsync()
+		assert.NoError(t, o.WaitForClose(time.Second*5))
+	})
+
+	tChan, rChan := make(chan types.Transaction), make(chan types.Response)
+	require.NoError(t, o.Consume(tChan))
+
+	require.Error(t, circuitBreakerSend(t, tChan, rChan).Error())
+	atomic.StoreInt32(&fail, 0)
+
+	startedAt := time.Now()
+	assert.NoError(t, circuitBreakerSend(t, tChan, rChan).Error())
+	assert.True(t, time.Since(startedAt) >= time.Millisecond*50)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestCircuitBreakerConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.CircuitBreaker.Output = httpClientConf("http://localhost:1")
+	conf.CircuitBreaker.OnOpen = "fallback"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.CircuitBreaker.OnOpen = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
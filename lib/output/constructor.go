@@ -131,6 +131,7 @@ const (
 	TypeBroker             = "broker"
 	TypeCache              = "cache"
 	TypeCassandra          = "cassandra"
+	TypeCircuitBreaker     = "circuit_breaker"
 	TypeDrop               = "drop"
 	TypeDropOn             = "drop_on"
 	TypeDropOnError        = "drop_on_error"
@@ -198,6 +199,7 @@ type Config struct {
 	Broker             BrokerConfig                   `json:"broker" yaml:"broker"`
 	Cache              writer.CacheConfig             `json:"cache" yaml:"cache"`
 	Cassandra          CassandraConfig                `json:"cassandra" yaml:"cassandra"`
+	CircuitBreaker     CircuitBreakerConfig           `json:"circuit_breaker" yaml:"circuit_breaker"`
 	Drop               writer.DropConfig              `json:"drop" yaml:"drop"`
 	DropOn             DropOnConfig                   `json:"drop_on" yaml:"drop_on"`
 	DropOnError        DropOnErrorConfig              `json:"drop_on_error" yaml:"drop_on_error"`
@@ -266,6 +268,7 @@ func NewConfig() Config {
 		Broker:             NewBrokerConfig(),
 		Cache:              writer.NewCacheConfig(),
 		Cassandra:          NewCassandraConfig(),
+		CircuitBreaker:     NewCircuitBreakerConfig(),
 		Drop:               writer.NewDropConfig(),
 		DropOn:             NewDropOnConfig(),
 		DropOnError:        NewDropOnErrorConfig(),
@@ -425,15 +428,16 @@ func (conf *Config) UnmarshalYAML(value *yaml.Node) error {
 func New(
 	conf Config,
 	mgr types.Manager,
-	log log.Modular,
+	logger log.Modular,
 	stats metrics.Type,
 	pipelines ...types.PipelineConstructorFunc,
 ) (Type, error) {
+	logger = log.ForType(logger, conf.Type)
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(conf, mgr, log, stats, pipelines...)
+		return c.constructor(conf, mgr, logger, stats, pipelines...)
 	}
 	if c, ok := pluginSpecs[conf.Type]; ok {
-		return c.constructor(conf, mgr, log, stats, pipelines...)
+		return c.constructor(conf, mgr, logger, stats, pipelines...)
 	}
 	return nil, types.ErrInvalidOutputType
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
 	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
 	"github.com/Jeffail/benthos/v3/lib/util/retries"
 	"github.com/Jeffail/benthos/v3/lib/util/tls"
@@ -47,6 +48,7 @@ false for connections to succeed.`,
 			docs.FieldAdvanced("timeout", "The maximum time to wait before abandoning a request (and trying again)."),
 			tls.FieldSpec(),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			concurrency.FieldSpec(),
 		}.Merge(retries.FieldSpecs()).Add(
 			auth.BasicAuthFieldSpec(),
 			batch.FieldSpec(),
@@ -71,13 +73,13 @@ func NewElasticsearch(conf Config, mgr types.Manager, log log.Modular, stats met
 		return nil, err
 	}
 	var w Type
-	if conf.Elasticsearch.MaxInFlight == 1 {
+	if conf.Elasticsearch.MaxInFlight == 1 && !conf.Elasticsearch.AdaptiveConcurrency.Enabled {
 		w, err = NewWriter(
 			TypeElasticsearch, elasticWriter, log, stats,
 		)
 	} else {
-		w, err = NewAsyncWriter(
-			TypeElasticsearch, conf.Elasticsearch.MaxInFlight, elasticWriter, log, stats,
+		w, err = NewAdaptiveAsyncWriter(
+			TypeElasticsearch, conf.Elasticsearch.MaxInFlight, conf.Elasticsearch.AdaptiveConcurrency, elasticWriter, log, stats,
 		)
 	}
 	if err != nil {
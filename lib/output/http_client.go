@@ -7,6 +7,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
 	"github.com/Jeffail/benthos/v3/lib/util/http/client"
 )
 
@@ -44,6 +45,7 @@ these propagated responses.`,
 		FieldSpecs: client.FieldSpecs().Add(
 			docs.FieldAdvanced("propagate_response", "Whether responses from the server should be [propagated back](/docs/guides/sync_responses) to the input."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			concurrency.FieldSpec(),
 		).Add(batch.FieldSpec()),
 		Categories: []Category{
 			CategoryNetwork,
@@ -58,10 +60,10 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 		return nil, err
 	}
 	var w Type
-	if conf.HTTPClient.MaxInFlight == 1 {
+	if conf.HTTPClient.MaxInFlight == 1 && !conf.HTTPClient.AdaptiveConcurrency.Enabled {
 		w, err = NewWriter(TypeHTTPClient, h, log, stats)
 	} else {
-		w, err = NewAsyncWriter(TypeHTTPClient, conf.HTTPClient.MaxInFlight, h, log, stats)
+		w, err = NewAdaptiveAsyncWriter(TypeHTTPClient, conf.HTTPClient.MaxInFlight, conf.HTTPClient.AdaptiveConcurrency, h, log, stats)
 	}
 	if err != nil {
 		return w, err
@@ -2,6 +2,7 @@ package output
 
 import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/metadata"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -22,7 +23,15 @@ The kafka output type writes a batch of messages to Kafka brokers and waits for
 		Description: `
 The config field ` + "`ack_replicas`" + ` determines whether we wait for acknowledgement from all replicas or just a single broker.
 
-Both the ` + "`key` and `topic`" + ` fields can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).
+The fields ` + "`key`, `topic`, `partition` and `timestamp`" + ` can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).
+
+### Headers
+
+For target versions of 0.11 and above the metadata of each message is written as Kafka record headers, along with any ` + "`static_headers`" + `. The metadata keys that are sent can be restricted with the ` + "[`metadata`](#metadata)" + ` fields, which is useful when messages consumed from a Kafka input carry metadata such as ` + "`kafka_key`" + ` that should not be forwarded.
+
+### Idempotent Writes
+
+When ` + "`idempotent_write`" + ` is enabled the producer is configured so that retried sends do not result in duplicate messages within a partition. This requires a ` + "`target_version`" + ` of at least 0.11, waits for acknowledgement from all replicas, and limits the number of open requests per broker connection to one.
 
 ### Strict Ordering and Retries
 
@@ -44,11 +53,15 @@ However, this also means that manual intervention will eventually be required in
 			docs.FieldCommon("topic", "The topic to publish messages to.").SupportsInterpolation(false),
 			docs.FieldCommon("client_id", "An identifier for the client connection."),
 			docs.FieldCommon("key", "The key to publish messages with.").SupportsInterpolation(false),
-			docs.FieldCommon("partitioner", "The partitioning algorithm to use.").HasOptions("fnv1a_hash", "murmur2_hash", "random", "round_robin"),
+			docs.FieldCommon("partitioner", "The partitioning algorithm to use.").HasOptions("fnv1a_hash", "murmur2_hash", "random", "round_robin", "manual"),
+			docs.FieldAdvanced("partition", "The manually-specified partition to publish messages to, relevant only when the field `partitioner` is set to `manual`. Must be able to parse as a 32-bit integer.", `${! meta("partition") }`).SupportsInterpolation(false).AtVersion("3.42.0"),
 			docs.FieldCommon("compression", "The compression algorithm to use.").HasOptions("none", "snappy", "lz4", "gzip"),
 			docs.FieldCommon("static_headers", "An optional map of static headers that should be added to messages in addition to metadata.", map[string]string{"first-static-header": "value-1", "second-static-header": "value-2"}),
+			metadata.FilterFieldSpec("metadata", "Determine which (if any) metadata values should be added to messages as headers.").AtVersion("3.42.0"),
 			docs.FieldCommon("max_in_flight", "The maximum number of parallel message batches to have in flight at any given time."),
 			docs.FieldAdvanced("ack_replicas", "Ensure that messages have been copied across all replicas before acknowledging receipt."),
+			docs.FieldAdvanced("idempotent_write", "Enable the idempotent write producer option, which prevents retried sends from producing duplicate messages. Requires a `target_version` of at least 0.11.").AtVersion("3.42.0"),
+			docs.FieldAdvanced("timestamp", "An optional timestamp to set for each message, either as an RFC 3339 string or a unix timestamp in seconds. When left empty the current time is used.", `${! meta("kafka_timestamp_unix") }`, `${! ingestion_timestamp() }`).SupportsInterpolation(false).AtVersion("3.42.0"),
 			docs.FieldAdvanced("max_msg_bytes", "The maximum size in bytes of messages sent to the target topic."),
 			docs.FieldAdvanced("timeout", "The maximum period of time to wait for message sends before abandoning the request and retrying."),
 			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use."),
@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -37,7 +38,13 @@ we want to avoid reapplying to the same message more than once in the pipeline.
 
 Rather than retrying the same output you may wish to retry the send using a
 different output target (a dead letter queue). In which case you should instead
-use the ` + "[`try`](/docs/components/outputs/try)" + ` output type.`,
+use the ` + "[`try`](/docs/components/outputs/try)" + ` output type.
+
+### Batching
+
+When the child output reports which specific messages of a batch failed only
+those messages are retried, otherwise the whole batch is retried in order to
+preserve at-least-once guarantees.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			confBytes, err := json.Marshal(conf.Retry)
 			if err != nil {
@@ -231,15 +238,19 @@ func (r *Retry) loop() {
 			return
 		}
 
+		// Tracking the messages of the batch allows us to only reattempt the
+		// messages that failed when the output returns a batch error.
+		tracker := batch.NewTracker(tran.Payload)
+
 		rChan := make(chan types.Response)
 		select {
-		case r.transactionsOut <- types.NewTransaction(tran.Payload, rChan):
+		case r.transactionsOut <- types.NewTransaction(tracker.Message(), rChan):
 		case <-r.closeChan:
 			return
 		}
 
 		wg.Add(1)
-		go func(ts types.Transaction, resChan chan types.Response) {
+		go func(ts types.Transaction, tracker *batch.Tracker, resChan chan types.Response) {
 			var backOff backoff.BackOff
 			var resOut types.Response
 			var inErrLoop bool
@@ -291,8 +302,9 @@ func (r *Retry) loop() {
 						return
 					}
 
+					tracker = tracker.Failed(res.Error())
 					select {
-					case r.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+					case r.transactionsOut <- types.NewTransaction(tracker.Message(), resChan):
 					case <-r.closeChan:
 						return
 					}
@@ -309,7 +321,7 @@ func (r *Retry) loop() {
 			case <-r.closeChan:
 				return
 			}
-		}(tran, rChan)
+		}(tran, tracker, rChan)
 	}
 }
 
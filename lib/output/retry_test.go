@@ -1,9 +1,12 @@
 package output
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -72,7 +75,7 @@ func TestRetryBasic(t *testing.T) {
 		t.Fatal("timed out")
 	}
 
-	if tran.Payload != testMsg {
+	if !reflect.DeepEqual(message.GetAllBytes(tran.Payload), message.GetAllBytes(testMsg)) {
 		t.Error("Wrong payload returned")
 	}
 
@@ -147,7 +150,7 @@ func TestRetrySadPath(t *testing.T) {
 			t.Fatal("timed out")
 		}
 
-		if tran.Payload != testMsg {
+		if !reflect.DeepEqual(message.GetAllBytes(tran.Payload), message.GetAllBytes(testMsg)) {
 			t.Error("Wrong payload returned")
 		}
 
@@ -166,7 +169,7 @@ func TestRetrySadPath(t *testing.T) {
 		t.Fatal("timed out")
 	}
 
-	if tran.Payload != testMsg {
+	if !reflect.DeepEqual(message.GetAllBytes(tran.Payload), message.GetAllBytes(testMsg)) {
 		t.Error("Wrong payload returned")
 	}
 
@@ -322,3 +325,95 @@ func TestRetryParallel(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRetryPartialBatch(t *testing.T) {
+	conf := NewConfig()
+
+	childConf := NewConfig()
+	conf.Retry.Output = &childConf
+	conf.Retry.Backoff.InitialInterval = "10us"
+	conf.Retry.Backoff.MaxInterval = "10us"
+
+	output, err := NewRetry(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, ok := output.(*Retry)
+	if !ok {
+		t.Fatal("Failed to cast")
+	}
+
+	mOut := &mockOutput{
+		ts: make(chan types.Transaction),
+	}
+	ret.wrapped = mOut
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	if err = ret.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	testMsg := message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	})
+	go func() {
+		select {
+		case tChan <- types.NewTransaction(testMsg, resChan):
+		case <-time.After(time.Second):
+			t.Error("timed out")
+		}
+	}()
+
+	var tran types.Transaction
+	select {
+	case tran = <-mOut.ts:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if exp, act := [][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}, message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong payload returned: %s != %s", act, exp)
+	}
+
+	batchErr := batch.NewError(tran.Payload, errors.New("nope")).Failed(1, errors.New("nope"))
+	select {
+	case tran.ResponseChan <- response.NewError(batchErr):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case tran = <-mOut.ts:
+	case <-resChan:
+		t.Fatal("Received response not retry")
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	if exp, act := [][]byte{[]byte("bar")}, message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong payload returned: %s != %s", act, exp)
+	}
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		if err = res.Error(); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	output.CloseAsync()
+	if err = output.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
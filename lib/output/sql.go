@@ -15,6 +15,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
 
 	// SQL Drivers
 	_ "github.com/ClickHouse/clickhouse-go"
@@ -30,7 +31,7 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			w, err := NewAsyncWriter(TypeSQL, conf.SQL.MaxInFlight, s, log, stats)
+			w, err := NewAdaptiveAsyncWriter(TypeSQL, conf.SQL.MaxInFlight, conf.SQL.AdaptiveConcurrency, s, log, stats)
 			if err != nil {
 				return nil, err
 			}
@@ -120,6 +121,7 @@ output:
 				"A list of arguments for the query to be resolved for each message.",
 			).SupportsInterpolation(true),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			concurrency.FieldSpec(),
 			batch.FieldSpec(),
 		},
 	}
@@ -129,23 +131,25 @@ output:
 
 // SQLConfig contains configuration fields for the SQL processor.
 type SQLConfig struct {
-	Driver         string             `json:"driver" yaml:"driver"`
-	DataSourceName string             `json:"data_source_name" yaml:"data_source_name"`
-	Query          string             `json:"query" yaml:"query"`
-	Args           []string           `json:"args" yaml:"args"`
-	MaxInFlight    int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Driver              string             `json:"driver" yaml:"driver"`
+	DataSourceName      string             `json:"data_source_name" yaml:"data_source_name"`
+	Query               string             `json:"query" yaml:"query"`
+	Args                []string           `json:"args" yaml:"args"`
+	MaxInFlight         int                `json:"max_in_flight" yaml:"max_in_flight"`
+	AdaptiveConcurrency concurrency.Config `json:"adaptive_concurrency" yaml:"adaptive_concurrency"`
+	Batching            batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
 
 // NewSQLConfig returns a SQLConfig with default values.
 func NewSQLConfig() SQLConfig {
 	return SQLConfig{
-		Driver:         "mysql",
-		DataSourceName: "",
-		Query:          "",
-		Args:           []string{},
-		MaxInFlight:    1,
-		Batching:       batch.NewPolicyConfig(),
+		Driver:              "mysql",
+		DataSourceName:      "",
+		Query:               "",
+		Args:                []string{},
+		MaxInFlight:         1,
+		AdaptiveConcurrency: concurrency.NewConfig(),
+		Batching:            batch.NewPolicyConfig(),
 	}
 }
 
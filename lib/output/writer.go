@@ -67,6 +67,18 @@ func (w *Writer) latencyMeasuringWrite(msg types.Message) (latencyNs int64, err
 	return latencyNs, err
 }
 
+// recordEndToEndLatency records the time elapsed since each part of a message
+// was consumed by an input, skipping parts without an ingestion time.
+func recordEndToEndLatency(timer metrics.StatTimer, msg types.Message) {
+	now := time.Now()
+	msg.Iter(func(i int, p types.Part) error {
+		if t, exists := message.GetIngestionTime(p); exists {
+			timer.Timing(now.Sub(t).Nanoseconds())
+		}
+		return nil
+	})
+}
+
 // loop is an internal loop that brokers incoming messages to output pipe.
 func (w *Writer) loop() {
 	// Metrics paths
@@ -76,6 +88,7 @@ func (w *Writer) loop() {
 		mSent       = w.stats.GetCounter("batch.sent")
 		mBytesSent  = w.stats.GetCounter("batch.bytes")
 		mLatency    = w.stats.GetTimer("batch.latency")
+		mE2ELatency = w.stats.GetTimer("end_to_end.latency")
 		mConn       = w.stats.GetCounter("connection.up")
 		mFailedConn = w.stats.GetCounter("connection.failed")
 		mLostConn   = w.stats.GetCounter("connection.lost")
@@ -171,6 +184,7 @@ func (w *Writer) loop() {
 			mPartsSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
 			mBytesSent.Incr(int64(message.GetAllBytesLen(ts.Payload)))
 			mLatency.Timing(latency)
+			recordEndToEndLatency(mE2ELatency, ts.Payload)
 			w.log.Tracef("Successfully wrote %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 			throt.Reset()
 		}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -15,6 +16,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	sess "github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
 	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
 	"github.com/Jeffail/benthos/v3/lib/util/retries"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
@@ -37,20 +39,21 @@ type OptionalAWSConfig struct {
 // ElasticsearchConfig contains configuration fields for the Elasticsearch
 // output type.
 type ElasticsearchConfig struct {
-	URLs           []string             `json:"urls" yaml:"urls"`
-	Sniff          bool                 `json:"sniff" yaml:"sniff"`
-	Healthcheck    bool                 `json:"healthcheck" yaml:"healthcheck"`
-	ID             string               `json:"id" yaml:"id"`
-	Index          string               `json:"index" yaml:"index"`
-	Pipeline       string               `json:"pipeline" yaml:"pipeline"`
-	Type           string               `json:"type" yaml:"type"`
-	Timeout        string               `json:"timeout" yaml:"timeout"`
-	TLS            btls.Config          `json:"tls" yaml:"tls"`
-	Auth           auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
-	AWS            OptionalAWSConfig    `json:"aws" yaml:"aws"`
-	MaxInFlight    int                  `json:"max_in_flight" yaml:"max_in_flight"`
-	retries.Config `json:",inline" yaml:",inline"`
-	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
+	URLs                []string             `json:"urls" yaml:"urls"`
+	Sniff               bool                 `json:"sniff" yaml:"sniff"`
+	Healthcheck         bool                 `json:"healthcheck" yaml:"healthcheck"`
+	ID                  string               `json:"id" yaml:"id"`
+	Index               string               `json:"index" yaml:"index"`
+	Pipeline            string               `json:"pipeline" yaml:"pipeline"`
+	Type                string               `json:"type" yaml:"type"`
+	Timeout             string               `json:"timeout" yaml:"timeout"`
+	TLS                 btls.Config          `json:"tls" yaml:"tls"`
+	Auth                auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	AWS                 OptionalAWSConfig    `json:"aws" yaml:"aws"`
+	MaxInFlight         int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	AdaptiveConcurrency concurrency.Config   `json:"adaptive_concurrency" yaml:"adaptive_concurrency"`
+	retries.Config      `json:",inline" yaml:",inline"`
+	Batching            batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
 
 // NewElasticsearchConfig creates a new ElasticsearchConfig with default values.
@@ -75,9 +78,10 @@ func NewElasticsearchConfig() ElasticsearchConfig {
 			Enabled: false,
 			Config:  sess.NewConfig(),
 		},
-		MaxInFlight: 1,
-		Config:      rConf,
-		Batching:    batch.NewPolicyConfig(),
+		MaxInFlight:         1,
+		AdaptiveConcurrency: concurrency.NewConfig(),
+		Config:              rConf,
+		Batching:            batch.NewPolicyConfig(),
 	}
 }
 
@@ -261,6 +265,7 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 	}
 
 	requests := map[string]*pendingBulkIndex{}
+	requestIndexes := map[string][]int{}
 	msg.Iter(func(i int, part types.Part) error {
 		jObj, ierr := part.JSON()
 		if ierr != nil {
@@ -268,7 +273,9 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 			e.log.Errorf("Failed to marshal message into JSON document: %v\n", ierr)
 			return nil
 		}
-		requests[e.idStr.String(i, msg)] = &pendingBulkIndex{
+		id := e.idStr.String(i, msg)
+		requestIndexes[id] = append(requestIndexes[id], i)
+		requests[id] = &pendingBulkIndex{
 			Index:    e.indexStr.String(i, msg),
 			Pipeline: e.pipelineStr.String(i, msg),
 			Type:     e.conf.Type,
@@ -289,6 +296,16 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 		)
 	}
 
+	var batchErr *batchInternal.Error
+	failRequest := func(id string, err error) {
+		if batchErr == nil {
+			batchErr = batchInternal.NewError(msg, err)
+		}
+		for _, i := range requestIndexes[id] {
+			batchErr.Failed(i, err)
+		}
+	}
+
 	for b.NumberOfActions() != 0 {
 		result, err := b.Do(context.Background())
 		if err != nil {
@@ -302,12 +319,21 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 
 		wait := boff.NextBackOff()
 		for i := 0; i < len(failed); i++ {
+			id := failed[i].Id
+			var reason string
+			if failed[i].Error != nil {
+				reason = failed[i].Error.Reason
+			}
 			if !shouldRetry(failed[i].Status) {
-				e.log.Errorf("Elasticsearch message '%v' rejected with code [%s]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
-				return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
+				e.log.Errorf("Elasticsearch message '%v' rejected with code [%v]: %v\n", id, failed[i].Status, reason)
+				failRequest(id, fmt.Errorf("document rejected with code [%v]: %v", failed[i].Status, reason))
+				continue
+			}
+			e.log.Errorf("Elasticsearch message '%v' failed with code [%v]: %v\n", id, failed[i].Status, reason)
+			if wait == backoff.Stop {
+				failRequest(id, fmt.Errorf("document failed with code [%v]: %v", failed[i].Status, reason))
+				continue
 			}
-			e.log.Errorf("Elasticsearch message '%v' failed with code [%s]: %v\n", failed[i].Id, failed[i].Status, failed[i].Error.Reason)
-			id := failed[i].Id
 			req := requests[id]
 			b.Add(
 				elastic.NewBulkIndexRequest().
@@ -318,12 +344,14 @@ func (e *Elasticsearch) Write(msg types.Message) error {
 					Doc(req.Doc),
 			)
 		}
-		if wait == backoff.Stop {
-			return fmt.Errorf("failed to send %v parts from message: %v", len(failed), failed[0].Error.Reason)
+		if b.NumberOfActions() > 0 {
+			time.Sleep(wait)
 		}
-		time.Sleep(wait)
 	}
 
+	if batchErr != nil {
+		return batchErr
+	}
 	return nil
 }
 
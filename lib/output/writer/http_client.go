@@ -9,6 +9,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/concurrency"
 	"github.com/Jeffail/benthos/v3/lib/util/http/client"
 )
 
@@ -17,19 +18,21 @@ import (
 // HTTPClientConfig contains configuration fields for the HTTPClient output
 // type.
 type HTTPClientConfig struct {
-	client.Config     `json:",inline" yaml:",inline"`
-	MaxInFlight       int                `json:"max_in_flight" yaml:"max_in_flight"`
-	PropagateResponse bool               `json:"propagate_response" yaml:"propagate_response"`
-	Batching          batch.PolicyConfig `json:"batching" yaml:"batching"`
+	client.Config       `json:",inline" yaml:",inline"`
+	MaxInFlight         int                `json:"max_in_flight" yaml:"max_in_flight"`
+	AdaptiveConcurrency concurrency.Config `json:"adaptive_concurrency" yaml:"adaptive_concurrency"`
+	PropagateResponse   bool               `json:"propagate_response" yaml:"propagate_response"`
+	Batching            batch.PolicyConfig `json:"batching" yaml:"batching"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
 func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
-		Config:            client.NewConfig(),
-		MaxInFlight:       1, // TODO: Increase this default?
-		PropagateResponse: false,
-		Batching:          batch.NewPolicyConfig(),
+		Config:              client.NewConfig(),
+		MaxInFlight:         1, // TODO: Increase this default?
+		AdaptiveConcurrency: concurrency.NewConfig(),
+		PropagateResponse:   false,
+		Batching:            batch.NewPolicyConfig(),
 	}
 }
 
@@ -3,7 +3,9 @@ package writer
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -11,11 +13,13 @@ import (
 	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/metadata"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/hash/murmur2"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka"
 	"github.com/Jeffail/benthos/v3/lib/util/kafka/sasl"
 	"github.com/Jeffail/benthos/v3/lib/util/retries"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
@@ -27,23 +31,27 @@ import (
 
 // KafkaConfig contains configuration fields for the Kafka output type.
 type KafkaConfig struct {
-	Addresses      []string    `json:"addresses" yaml:"addresses"`
-	ClientID       string      `json:"client_id" yaml:"client_id"`
-	Key            string      `json:"key" yaml:"key"`
-	Partitioner    string      `json:"partitioner" yaml:"partitioner"`
-	Topic          string      `json:"topic" yaml:"topic"`
-	Compression    string      `json:"compression" yaml:"compression"`
-	MaxMsgBytes    int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
-	Timeout        string      `json:"timeout" yaml:"timeout"`
-	AckReplicas    bool        `json:"ack_replicas" yaml:"ack_replicas"`
-	TargetVersion  string      `json:"target_version" yaml:"target_version"`
-	TLS            btls.Config `json:"tls" yaml:"tls"`
-	SASL           sasl.Config `json:"sasl" yaml:"sasl"`
-	MaxInFlight    int         `json:"max_in_flight" yaml:"max_in_flight"`
-	retries.Config `json:",inline" yaml:",inline"`
-	RetryAsBatch   bool               `json:"retry_as_batch" yaml:"retry_as_batch"`
-	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
-	StaticHeaders  map[string]string  `json:"static_headers" yaml:"static_headers"`
+	Addresses       []string    `json:"addresses" yaml:"addresses"`
+	ClientID        string      `json:"client_id" yaml:"client_id"`
+	Key             string      `json:"key" yaml:"key"`
+	Partitioner     string      `json:"partitioner" yaml:"partitioner"`
+	Partition       string      `json:"partition" yaml:"partition"`
+	Topic           string      `json:"topic" yaml:"topic"`
+	Timestamp       string      `json:"timestamp" yaml:"timestamp"`
+	Compression     string      `json:"compression" yaml:"compression"`
+	MaxMsgBytes     int         `json:"max_msg_bytes" yaml:"max_msg_bytes"`
+	Timeout         string      `json:"timeout" yaml:"timeout"`
+	AckReplicas     bool        `json:"ack_replicas" yaml:"ack_replicas"`
+	IdempotentWrite bool        `json:"idempotent_write" yaml:"idempotent_write"`
+	TargetVersion   string      `json:"target_version" yaml:"target_version"`
+	TLS             btls.Config `json:"tls" yaml:"tls"`
+	SASL            sasl.Config `json:"sasl" yaml:"sasl"`
+	MaxInFlight     int         `json:"max_in_flight" yaml:"max_in_flight"`
+	retries.Config  `json:",inline" yaml:",inline"`
+	RetryAsBatch    bool                  `json:"retry_as_batch" yaml:"retry_as_batch"`
+	Batching        batch.PolicyConfig    `json:"batching" yaml:"batching"`
+	StaticHeaders   map[string]string     `json:"static_headers" yaml:"static_headers"`
+	Metadata        metadata.FilterConfig `json:"metadata" yaml:"metadata"`
 
 	// TODO: V4 remove this.
 	RoundRobinPartitions bool `json:"round_robin_partitions" yaml:"round_robin_partitions"`
@@ -62,13 +70,17 @@ func NewKafkaConfig() KafkaConfig {
 		Key:                  "",
 		RoundRobinPartitions: false,
 		Partitioner:          "fnv1a_hash",
+		Partition:            "",
 		Topic:                "benthos_stream",
+		Timestamp:            "",
 		Compression:          "none",
 		MaxMsgBytes:          1000000,
 		Timeout:              "5s",
 		AckReplicas:          false,
+		IdempotentWrite:      false,
 		TargetVersion:        sarama.V1_0_0_0.String(),
 		StaticHeaders:        map[string]string{},
+		Metadata:             metadata.NewFilterConfig(),
 		TLS:                  btls.NewConfig(),
 		SASL:                 sasl.NewConfig(),
 		MaxInFlight:          1,
@@ -95,14 +107,17 @@ type Kafka struct {
 	version   sarama.KafkaVersion
 	conf      KafkaConfig
 
-	key   field.Expression
-	topic field.Expression
+	key       field.Expression
+	topic     field.Expression
+	partition field.Expression
+	timestamp field.Expression
 
 	producer    sarama.SyncProducer
 	compression sarama.CompressionCodec
 	partitioner sarama.PartitionerConstructor
 
 	staticHeaders map[string]string
+	metaFilter    *metadata.Filter
 
 	connMut sync.RWMutex
 }
@@ -141,6 +156,24 @@ func NewKafka(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metric
 	if k.topic, err = bloblang.NewField(conf.Topic); err != nil {
 		return nil, fmt.Errorf("failed to parse topic expression: %v", err)
 	}
+	if conf.Partitioner == "manual" {
+		if conf.Partition == "" {
+			return nil, errors.New("partition field required for 'manual' partitioner")
+		}
+		if k.partition, err = bloblang.NewField(conf.Partition); err != nil {
+			return nil, fmt.Errorf("failed to parse partition expression: %v", err)
+		}
+	} else if conf.Partition != "" {
+		return nil, errors.New("explicit partition is only allowed with the 'manual' partitioner")
+	}
+	if conf.Timestamp != "" {
+		if k.timestamp, err = bloblang.NewField(conf.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp expression: %v", err)
+		}
+	}
+	if k.metaFilter, err = conf.Metadata.Filter(); err != nil {
+		return nil, fmt.Errorf("failed to construct metadata filter: %v", err)
+	}
 	if k.backoffCtor, err = conf.Config.GetCtor(); err != nil {
 		return nil, err
 	}
@@ -162,6 +195,9 @@ func NewKafka(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metric
 	if k.version, err = sarama.ParseKafkaVersion(conf.TargetVersion); err != nil {
 		return nil, err
 	}
+	if conf.IdempotentWrite && !k.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, errors.New("idempotent writes require a target_version of at least 0.11.0.0")
+	}
 
 	for _, addr := range conf.Addresses {
 		for _, splitAddr := range strings.Split(addr, ",") {
@@ -205,6 +241,8 @@ func strToPartitioner(str string) (sarama.PartitionerConstructor, error) {
 		return sarama.NewRandomPartitioner, nil
 	case "round_robin":
 		return sarama.NewRoundRobinPartitioner, nil
+	case "manual":
+		return sarama.NewManualPartitioner, nil
 	default:
 	}
 	return nil, fmt.Errorf("partitioner not recognised: %v", str)
@@ -212,11 +250,10 @@ func strToPartitioner(str string) (sarama.PartitionerConstructor, error) {
 
 //------------------------------------------------------------------------------
 
-func buildSystemHeaders(version sarama.KafkaVersion, part types.Part) []sarama.RecordHeader {
+func buildSystemHeaders(version sarama.KafkaVersion, filter *metadata.Filter, part types.Part) []sarama.RecordHeader {
 	if version.IsAtLeast(sarama.V0_11_0_0) {
 		out := []sarama.RecordHeader{}
-		meta := part.Metadata()
-		meta.Iter(func(k, v string) error {
+		_ = filter.Iter(part, func(k, v string) error {
 			out = append(out, sarama.RecordHeader{
 				Key:   []byte(k),
 				Value: []byte(v),
@@ -252,6 +289,19 @@ func buildUserDefinedHeaders(version sarama.KafkaVersion, staticHeaders map[stri
 
 //------------------------------------------------------------------------------
 
+func parsePartition(str string) (int32, error) {
+	partition, err := strconv.ParseInt(str, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse partition '%v': %v", str, err)
+	}
+	if partition < 0 {
+		return 0, fmt.Errorf("partition must not be negative, got %v", partition)
+	}
+	return int32(partition), nil
+}
+
+//------------------------------------------------------------------------------
+
 // ConnectWithContext attempts to establish a connection to a Kafka broker.
 func (k *Kafka) ConnectWithContext(ctx context.Context) error {
 	return k.Connect()
@@ -291,6 +341,15 @@ func (k *Kafka) Connect() error {
 		config.Producer.RequiredAcks = sarama.WaitForLocal
 	}
 
+	if k.conf.IdempotentWrite {
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Net.MaxOpenRequests = 1
+		if config.Producer.Retry.Max < 1 {
+			config.Producer.Retry.Max = 1
+		}
+	}
+
 	var err error
 	k.producer, err = sarama.NewSyncProducer(k.addresses, config)
 
@@ -322,20 +381,53 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 
 	userDefinedHeaders := buildUserDefinedHeaders(version, k.staticHeaders)
 	msgs := []*sarama.ProducerMessage{}
-	msg.Iter(func(i int, p types.Part) error {
+
+	// Messages with a partition or timestamp that fails to resolve are
+	// excluded from the send and nacked individually.
+	var resolveErr *batchInternal.Error
+	failResolve := func(i int, err error) {
+		if resolveErr == nil {
+			resolveErr = batchInternal.NewError(msg, err)
+		}
+		resolveErr.Failed(i, err)
+	}
+
+	_ = msg.Iter(func(i int, p types.Part) error {
 		key := k.key.Bytes(i, msg)
 		nextMsg := &sarama.ProducerMessage{
 			Topic:    k.topic.String(i, msg),
 			Value:    sarama.ByteEncoder(p.Get()),
-			Headers:  append(buildSystemHeaders(version, p), userDefinedHeaders...),
+			Headers:  append(buildSystemHeaders(version, k.metaFilter, p), userDefinedHeaders...),
 			Metadata: i, // Store the original index for later reference.
 		}
 		if len(key) > 0 {
 			nextMsg.Key = sarama.ByteEncoder(key)
 		}
+		if k.partition != nil {
+			partition, err := parsePartition(k.partition.String(i, msg))
+			if err != nil {
+				failResolve(i, err)
+				return nil
+			}
+			nextMsg.Partition = partition
+		}
+		if k.timestamp != nil {
+			ts, err := kafka.ParseTimestamp(k.timestamp.String(i, msg))
+			if err != nil {
+				failResolve(i, err)
+				return nil
+			}
+			nextMsg.Timestamp = ts
+		}
 		msgs = append(msgs, nextMsg)
 		return nil
 	})
+	if resolveErr != nil {
+		k.log.Errorf("Failed to resolve '%v' messages: %v\n", resolveErr.IndexedErrors(), resolveErr)
+		if len(msgs) == 0 {
+			return resolveErr
+		}
+	}
 
 	err := producer.SendMessages(msgs)
 	for err != nil {
@@ -344,6 +436,16 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 				break
 			}
 			batchErr := batchInternal.NewError(msg, pErrs[0].Err)
+			expectedErrs := len(pErrs)
+			if resolveErr != nil {
+				resolveErr.WalkParts(func(i int, _ types.Part, rErr error) bool {
+					if rErr != nil {
+						batchErr.Failed(i, rErr)
+					}
+					return true
+				})
+				expectedErrs += resolveErr.IndexedErrors()
+			}
 			msgs = nil
 			for _, pErr := range pErrs {
 				if mIndex, ok := pErr.Msg.Metadata.(int); ok {
@@ -351,7 +453,7 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 				}
 				msgs = append(msgs, pErr.Msg)
 			}
-			if len(pErrs) == batchErr.IndexedErrors() {
+			if expectedErrs == batchErr.IndexedErrors() {
 				err = batchErr
 			} else {
 				// If these lengths don't match then somehow we failed to obtain
@@ -385,6 +487,9 @@ func (k *Kafka) WriteWithContext(ctx context.Context, msg types.Message) error {
 		err = producer.SendMessages(msgs)
 	}
 
+	if resolveErr != nil {
+		return resolveErr
+	}
 	return nil
 }
 
@@ -0,0 +1,159 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/metadata"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaConfigValidation(t *testing.T) {
+	tests := map[string]struct {
+		conf        func(c *KafkaConfig)
+		errContains string
+	}{
+		"manual without partition": {
+			conf: func(c *KafkaConfig) {
+				c.Partitioner = "manual"
+			},
+			errContains: "partition field required",
+		},
+		"partition without manual": {
+			conf: func(c *KafkaConfig) {
+				c.Partition = "1"
+			},
+			errContains: "only allowed with the 'manual' partitioner",
+		},
+		"manual with partition": {
+			conf: func(c *KafkaConfig) {
+				c.Partitioner = "manual"
+				c.Partition = `${! meta("partition") }`
+			},
+		},
+		"idempotent old version": {
+			conf: func(c *KafkaConfig) {
+				c.IdempotentWrite = true
+				c.TargetVersion = "0.10.2.0"
+			},
+			errContains: "idempotent writes require",
+		},
+		"idempotent": {
+			conf: func(c *KafkaConfig) {
+				c.IdempotentWrite = true
+			},
+		},
+		"bad metadata pattern": {
+			conf: func(c *KafkaConfig) {
+				c.Metadata.IncludePatterns = []string{"("}
+			},
+			errContains: "failed to construct metadata filter",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewKafkaConfig()
+			test.conf(&conf)
+
+			_, err := NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+			if test.errContains == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			}
+		})
+	}
+}
+
+func TestKafkaParsePartition(t *testing.T) {
+	p, err := parsePartition("3")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), p)
+
+	_, err = parsePartition("-1")
+	require.Error(t, err)
+
+	_, err = parsePartition("nope")
+	require.Error(t, err)
+}
+
+type fakeSyncProducer struct {
+	sent []*sarama.ProducerMessage
+}
+
+func (f *fakeSyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	f.sent = append(f.sent, msg)
+	return msg.Partition, 0, nil
+}
+
+func (f *fakeSyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	f.sent = append(f.sent, msgs...)
+	return nil
+}
+
+func (f *fakeSyncProducer) Close() error {
+	return nil
+}
+
+func TestKafkaBadPartitionIndexes(t *testing.T) {
+	conf := NewKafkaConfig()
+	conf.Partitioner = "manual"
+	conf.Partition = `${! meta("partition") }`
+
+	k, err := NewKafka(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	producer := &fakeSyncProducer{}
+	k.producer = producer
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	msg.Get(0).Metadata().Set("partition", "1")
+	msg.Get(1).Metadata().Set("partition", "nope")
+	msg.Get(2).Metadata().Set("partition", "2")
+
+	err = k.WriteWithContext(context.Background(), msg)
+	require.Error(t, err)
+
+	var bErr *batchInternal.Error
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkParts(func(i int, _ types.Part, pErr error) bool {
+		if pErr != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+
+	require.Len(t, producer.sent, 2)
+	assert.Equal(t, int32(1), producer.sent[0].Partition)
+	assert.Equal(t, int32(2), producer.sent[1].Partition)
+}
+
+func TestKafkaSystemHeaders(t *testing.T) {
+	fConf := metadata.NewFilterConfig()
+	fConf.ExcludePrefixes = []string{"kafka_"}
+	filter, err := fConf.Filter()
+	require.NoError(t, err)
+
+	part := message.NewPart(nil)
+	part.Metadata().Set("foo", "bar").Set("kafka_key", "baz")
+
+	assert.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("foo"), Value: []byte("bar")},
+	}, buildSystemHeaders(sarama.V1_0_0_0, filter, part))
+
+	assert.Nil(t, buildSystemHeaders(sarama.V0_10_0_0, filter, part))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -204,6 +205,21 @@ func (a *Kinesis) WriteWithContext(ctx context.Context, msg types.Message) error
 		return err
 	}
 
+	recordIndexes := make(map[*kinesis.PutRecordsRequestEntry]int, len(records))
+	for i, r := range records {
+		recordIndexes[r] = i
+	}
+
+	var batchErr *batchInternal.Error
+	failRecords := func(err error, failed ...*kinesis.PutRecordsRequestEntry) {
+		if batchErr == nil {
+			batchErr = batchInternal.NewError(msg, err)
+		}
+		for _, r := range failed {
+			batchErr.Failed(recordIndexes[r], err)
+		}
+	}
+
 	input := &kinesis.PutRecordsInput{
 		Records:    records,
 		StreamName: a.streamName,
@@ -216,7 +232,6 @@ func (a *Kinesis) WriteWithContext(ctx context.Context, msg types.Message) error
 		records = nil
 	}
 
-	var failed []*kinesis.PutRecordsRequestEntry
 	backOff.Reset()
 	for len(input.Records) > 0 {
 		wait := backOff.NextBackOff()
@@ -227,35 +242,42 @@ func (a *Kinesis) WriteWithContext(ctx context.Context, msg types.Message) error
 			a.log.Warnf("kinesis error: %v\n", err)
 			// bail if a message is too large or all retry attempts expired
 			if wait == backoff.Stop {
-				return err
+				failRecords(err, input.Records...)
+				failRecords(err, records...)
+				return batchErr
 			}
 			continue
 		}
 
-		// requeue any individual records that failed due to throttling
-		failed = nil
+		// requeue any individual records that failed due to throttling, and
+		// register the remaining failures against their message index
+		var throttled []*kinesis.PutRecordsRequestEntry
 		if output.FailedRecordCount != nil {
 			for i, entry := range output.Records {
-				if entry.ErrorCode != nil {
-					failed = append(failed, input.Records[i])
-					if *entry.ErrorCode != kinesis.ErrCodeProvisionedThroughputExceededException && *entry.ErrorCode != kinesis.ErrCodeKMSThrottlingException {
-						err = fmt.Errorf("record failed with code [%s] %s: %+v", *entry.ErrorCode, *entry.ErrorMessage, input.Records[i])
-						a.log.Errorf("kinesis record error: %v\n", err)
-						return err
-					}
+				if entry.ErrorCode == nil {
+					continue
+				}
+				if *entry.ErrorCode == kinesis.ErrCodeProvisionedThroughputExceededException || *entry.ErrorCode == kinesis.ErrCodeKMSThrottlingException {
+					throttled = append(throttled, input.Records[i])
+					continue
 				}
+				rErr := fmt.Errorf("record failed with code [%s] %s", *entry.ErrorCode, aws.StringValue(entry.ErrorMessage))
+				a.log.Errorf("kinesis record error: %v\n", rErr)
+				failRecords(rErr, input.Records[i])
 			}
 		}
-		input.Records = failed
+		input.Records = throttled
 
 		// if throttling errors detected, pause briefly
-		l := len(failed)
+		l := len(throttled)
 		if l > 0 {
 			a.mThrottled.Incr(1)
 			a.mPartsThrottled.Incr(int64(l))
 			a.log.Warnf("scheduling retry of throttled records (%d)\n", l)
 			if wait == backoff.Stop {
-				return types.ErrTimeout
+				failRecords(types.ErrTimeout, input.Records...)
+				failRecords(types.ErrTimeout, records...)
+				return batchErr
 			}
 			time.Sleep(wait)
 		}
@@ -269,7 +291,10 @@ func (a *Kinesis) WriteWithContext(ctx context.Context, msg types.Message) error
 			}
 		}
 	}
-	return err
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
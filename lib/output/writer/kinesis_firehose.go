@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -173,6 +174,21 @@ func (a *KinesisFirehose) WriteWithContext(ctx context.Context, msg types.Messag
 		return err
 	}
 
+	recordIndexes := make(map[*firehose.Record]int, len(records))
+	for i, r := range records {
+		recordIndexes[r] = i
+	}
+
+	var batchErr *batchInternal.Error
+	failRecords := func(err error, failed ...*firehose.Record) {
+		if batchErr == nil {
+			batchErr = batchInternal.NewError(msg, err)
+		}
+		for _, r := range failed {
+			batchErr.Failed(recordIndexes[r], err)
+		}
+	}
+
 	input := &firehose.PutRecordBatchInput{
 		Records:            records,
 		DeliveryStreamName: a.streamName,
@@ -185,7 +201,6 @@ func (a *KinesisFirehose) WriteWithContext(ctx context.Context, msg types.Messag
 		records = nil
 	}
 
-	var failed []*firehose.Record
 	for len(input.Records) > 0 {
 		wait := backOff.NextBackOff()
 
@@ -195,35 +210,42 @@ func (a *KinesisFirehose) WriteWithContext(ctx context.Context, msg types.Messag
 			a.log.Warnf("kinesis firehose error: %v\n", err)
 			// bail if a message is too large or all retry attempts expired
 			if wait == backoff.Stop {
-				return err
+				failRecords(err, input.Records...)
+				failRecords(err, records...)
+				return batchErr
 			}
 			continue
 		}
 
-		// requeue any individual records that failed due to throttling
-		failed = nil
+		// requeue any individual records that failed due to throttling, and
+		// register the remaining failures against their message index
+		var throttled []*firehose.Record
 		if output.FailedPutCount != nil {
 			for i, entry := range output.RequestResponses {
-				if entry.ErrorCode != nil {
-					failed = append(failed, input.Records[i])
-					if *entry.ErrorCode != firehose.ErrCodeServiceUnavailableException {
-						err = fmt.Errorf("record failed with code [%s] %s: %+v", *entry.ErrorCode, *entry.ErrorMessage, input.Records[i])
-						a.log.Errorf("kinesis firehose record error: %v\n", err)
-						return err
-					}
+				if entry.ErrorCode == nil {
+					continue
+				}
+				if *entry.ErrorCode == firehose.ErrCodeServiceUnavailableException {
+					throttled = append(throttled, input.Records[i])
+					continue
 				}
+				rErr := fmt.Errorf("record failed with code [%s] %s", *entry.ErrorCode, aws.StringValue(entry.ErrorMessage))
+				a.log.Errorf("kinesis firehose record error: %v\n", rErr)
+				failRecords(rErr, input.Records[i])
 			}
 		}
-		input.Records = failed
+		input.Records = throttled
 
 		// if throttling errors detected, pause briefly
-		l := len(failed)
+		l := len(throttled)
 		if l > 0 {
 			a.mThrottled.Incr(1)
 			a.mPartsThrottled.Incr(int64(l))
 			a.log.Warnf("scheduling retry of throttled records (%d)\n", l)
 			if wait == backoff.Stop {
-				return types.ErrTimeout
+				failRecords(types.ErrTimeout, input.Records...)
+				failRecords(types.ErrTimeout, records...)
+				return batchErr
 			}
 			time.Sleep(wait)
 		}
@@ -237,7 +259,10 @@ func (a *KinesisFirehose) WriteWithContext(ctx context.Context, msg types.Messag
 			}
 		}
 	}
-	return err
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
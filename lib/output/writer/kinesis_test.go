@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"testing"
 
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -357,3 +359,78 @@ func TestKinesisWriteBackoffMaxRetriesExceeded(t *testing.T) {
 		t.Errorf("Expected kinesis.PutRecords to have call count %d, got %d", exp, calls)
 	}
 }
+
+func TestKinesisWriteRecordFailures(t *testing.T) {
+	t.Parallel()
+	var calls [][]*kinesis.PutRecordsRequestEntry
+	k := Kinesis{
+		backoffCtor: func() backoff.BackOff {
+			return backoff.NewExponentialBackOff()
+		},
+		session: session.Must(session.NewSession(&aws.Config{
+			Credentials: credentials.NewStaticCredentials("xxxxx", "xxxxx", "xxxxx"),
+		})),
+		kinesis: &mockKinesis{
+			fn: func(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+				records := make([]*kinesis.PutRecordsRequestEntry, len(input.Records))
+				copy(records, input.Records)
+				calls = append(calls, records)
+				var failed int64
+				var output kinesis.PutRecordsOutput
+				for _, r := range input.Records {
+					entry := kinesis.PutRecordsResultEntry{}
+					switch *r.PartitionKey {
+					case "456":
+						failed++
+						entry.SetErrorCode("InternalFailure")
+						entry.SetErrorMessage("nope")
+					case "789":
+						if len(calls) == 1 {
+							failed++
+							entry.SetErrorCode(kinesis.ErrCodeProvisionedThroughputExceededException)
+						}
+					}
+					output.Records = append(output.Records, &entry)
+				}
+				output.SetFailedRecordCount(failed)
+				return &output, nil
+			},
+		},
+		mThrottled:       mThrottled,
+		mThrottledF:      mThrottledF,
+		mPartsThrottled:  mPartsThrottled,
+		mPartsThrottledF: mPartsThrottledF,
+		log:              log.Noop(),
+	}
+
+	k.partitionKey, _ = bloblang.NewField("${!json(\"id\")}")
+	k.hashKey, _ = bloblang.NewField("")
+
+	msg := message.New(nil)
+	msg.Append(message.NewPart([]byte(`{"foo":"bar","id":123}`)))
+	msg.Append(message.NewPart([]byte(`{"foo":"baz","id":456}`)))
+	msg.Append(message.NewPart([]byte(`{"foo":"qux","id":789}`)))
+
+	err := k.Write(msg)
+	walkable, ok := err.(batchInternal.WalkableError)
+	if !ok {
+		t.Fatalf("Expected batch error, got: %v", err)
+	}
+
+	var failed []int
+	walkable.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	if exp, act := []int{1}, failed; fmt.Sprintf("%v", exp) != fmt.Sprintf("%v", act) {
+		t.Errorf("Expected failed indexes %v, got %v", exp, act)
+	}
+	if exp, act := 2, len(calls); act != exp {
+		t.Errorf("Expected kinesis.PutRecords to have call count %d, got %d", exp, act)
+	}
+	if exp, act := 1, len(calls[1]); act != exp {
+		t.Errorf("Expected throttled records to be retried with length %d, got %d", exp, act)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -21,6 +22,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/cenkalti/backoff/v4"
 )
 
@@ -71,7 +73,7 @@ type AmazonSQS struct {
 	conf AmazonSQSConfig
 
 	session *session.Session
-	sqs     *sqs.SQS
+	sqs     sqsiface.SQSAPI
 
 	backoffCtor func() backoff.BackOff
 
@@ -208,19 +210,20 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 	backOff := a.backoffCtor()
 
 	entries := []*sqs.SendMessageBatchRequestEntry{}
-	attrMap := map[string]sqsAttributes{}
+	entryMap := map[string]*sqs.SendMessageBatchRequestEntry{}
 	msg.Iter(func(i int, p types.Part) error {
 		id := strconv.FormatInt(int64(i), 10)
 		attrs := a.getSQSAttributes(msg, i)
-		attrMap[id] = attrs
 
-		entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+		entry := &sqs.SendMessageBatchRequestEntry{
 			Id:                     aws.String(id),
 			MessageBody:            aws.String(string(p.Get())),
 			MessageAttributes:      attrs.attrMap,
 			MessageGroupId:         attrs.groupID,
 			MessageDeduplicationId: attrs.dedupeID,
-		})
+		}
+		entryMap[id] = entry
+		entries = append(entries, entry)
 		return nil
 	})
 
@@ -236,16 +239,28 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 		entries = nil
 	}
 
-	var err error
+	var batchErr *batchInternal.Error
+	failEntries := func(err error, failed ...*sqs.SendMessageBatchRequestEntry) {
+		if batchErr == nil {
+			batchErr = batchInternal.NewError(msg, err)
+		}
+		for _, e := range failed {
+			i, _ := strconv.Atoi(*e.Id)
+			batchErr.Failed(i, err)
+		}
+	}
+
 	for len(input.Entries) > 0 {
 		wait := backOff.NextBackOff()
 
-		var batchResult *sqs.SendMessageBatchOutput
-		if batchResult, err = a.sqs.SendMessageBatch(input); err != nil {
+		batchResult, err := a.sqs.SendMessageBatch(input)
+		if err != nil {
 			a.log.Warnf("SQS error: %v\n", err)
 			// bail if a message is too large or all retry attempts expired
 			if wait == backoff.Stop {
-				return err
+				failEntries(err, input.Entries...)
+				failEntries(err, entries...)
+				return batchErr
 			}
 			select {
 			case <-time.After(wait):
@@ -257,31 +272,28 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 			continue
 		}
 
-		if unproc := batchResult.Failed; len(unproc) > 0 {
-			input.Entries = []*sqs.SendMessageBatchRequestEntry{}
-			for _, v := range unproc {
-				if *v.SenderFault {
-					err = fmt.Errorf("record failed with code: %v, message: %v", *v.Code, *v.Message)
-					a.log.Errorf("SQS record error: %v\n", err)
-					return err
-				}
-				aMap := attrMap[*v.Id]
-				input.Entries = append(input.Entries, &sqs.SendMessageBatchRequestEntry{
-					Id:                     v.Id,
-					MessageBody:            v.Message,
-					MessageAttributes:      aMap.attrMap,
-					MessageGroupId:         aMap.groupID,
-					MessageDeduplicationId: aMap.dedupeID,
-				})
+		var retryEntries []*sqs.SendMessageBatchRequestEntry
+		for _, v := range batchResult.Failed {
+			entry, exists := entryMap[aws.StringValue(v.Id)]
+			if !exists {
+				continue
 			}
-			err = fmt.Errorf("failed to send %v messages", len(unproc))
-		} else {
-			input.Entries = nil
+			rErr := fmt.Errorf("record failed with code: %v, message: %v", aws.StringValue(v.Code), aws.StringValue(v.Message))
+			if aws.BoolValue(v.SenderFault) {
+				a.log.Errorf("SQS record error: %v\n", rErr)
+				failEntries(rErr, entry)
+				continue
+			}
+			retryEntries = append(retryEntries, entry)
 		}
+		input.Entries = retryEntries
 
-		if err != nil {
+		if l := len(retryEntries); l > 0 {
+			err = fmt.Errorf("failed to send %v messages", l)
 			if wait == backoff.Stop {
-				break
+				failEntries(err, input.Entries...)
+				failEntries(err, entries...)
+				return batchErr
 			}
 			select {
 			case <-time.After(wait):
@@ -303,7 +315,10 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 		}
 	}
 
-	return err
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
@@ -1,6 +1,20 @@
 package writer
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
 
 func TestSQSHeaderCheck(t *testing.T) {
 	type testCase struct {
@@ -93,3 +107,79 @@ func TestSQSHeaderCheck(t *testing.T) {
 		}
 	}
 }
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	fn func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error)
+}
+
+func (m *mockSQS) SendMessageBatch(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+	return m.fn(input)
+}
+
+func TestSQSWriteRecordFailures(t *testing.T) {
+	var calls [][]string
+
+	conf := NewAmazonSQSConfig()
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	w, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.session = session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("xxxxx", "xxxxx", "xxxxx"),
+	}))
+	w.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			var bodies []string
+			output := &sqs.SendMessageBatchOutput{}
+			for _, e := range input.Entries {
+				bodies = append(bodies, *e.MessageBody)
+				switch *e.MessageBody {
+				case "bar":
+					output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+						Code:        aws.String("InvalidMessageContents"),
+						Id:          e.Id,
+						Message:     aws.String("nope"),
+						SenderFault: aws.Bool(true),
+					})
+				case "baz":
+					if len(calls) == 0 {
+						output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+							Code:        aws.String("InternalError"),
+							Id:          e.Id,
+							Message:     aws.String("try again"),
+							SenderFault: aws.Bool(false),
+						})
+					}
+				}
+			}
+			calls = append(calls, bodies)
+			return output, nil
+		},
+	}
+
+	err = w.Write(message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+	walkable, ok := err.(batchInternal.WalkableError)
+	if !ok {
+		t.Fatalf("Expected batch error, got: %v", err)
+	}
+
+	var failed []int
+	walkable.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	if exp, act := []int{1}, failed; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Expected failed indexes %v, got %v", exp, act)
+	}
+	if exp, act := [][]string{{"foo", "bar", "baz"}, {"baz"}}, calls; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Expected calls %v, got %v", exp, act)
+	}
+}
@@ -3,6 +3,8 @@ package pipeline
 import (
 	"fmt"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/processor"
@@ -19,16 +21,21 @@ import (
 // In order to fully utilise each processing thread you must either have a
 // number of parallel inputs that matches or surpasses the number of pipeline
 // threads, or use a memory buffer.
+//
+// When an ordering key is set messages that share a key are always processed by
+// the same thread, which preserves their order.
 type Config struct {
-	Threads    int                `json:"threads" yaml:"threads"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	Threads     int                `json:"threads" yaml:"threads"`
+	OrderingKey string             `json:"ordering_key" yaml:"ordering_key"`
+	Processors  []processor.Config `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Threads:    1,
-		Processors: []processor.Config{},
+		Threads:     1,
+		OrderingKey: "",
+		Processors:  []processor.Config{},
 	}
 }
 
@@ -50,8 +57,9 @@ func (conf Config) Sanitised(removeDeprecated bool) (interface{}, error) {
 		}
 	}
 	return map[string]interface{}{
-		"threads":    conf.Threads,
-		"processors": procConfs,
+		"threads":      conf.Threads,
+		"ordering_key": conf.OrderingKey,
+		"processors":   procConfs,
 	}, nil
 }
 
@@ -86,9 +94,19 @@ func New(
 		}
 		return NewProcessor(log, stats, processors...), nil
 	}
+	var key field.Expression
+	if conf.OrderingKey != "" {
+		var err error
+		if key, err = bloblang.NewField(conf.OrderingKey); err != nil {
+			return nil, fmt.Errorf("failed to parse ordering key: %v", err)
+		}
+	}
 	if conf.Threads == 1 {
 		return procCtor(&procs)
 	}
+	if key != nil {
+		return NewKeyedPool(procCtor, conf.Threads, key, log, stats)
+	}
 	return NewPool(procCtor, conf.Threads, log, stats)
 }
 
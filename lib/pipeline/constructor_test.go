@@ -20,6 +20,7 @@ func TestSanitise(t *testing.T) {
 	var err error
 
 	exp := `{` +
+		`"ordering_key":"",` +
 		`"processors":[],` +
 		`"threads":10` +
 		`}`
@@ -39,6 +40,7 @@ func TestSanitise(t *testing.T) {
 	}
 
 	exp = `{` +
+		`"ordering_key":"",` +
 		`"processors":[` +
 		`{` +
 		`"type":"log",` +
@@ -161,3 +163,15 @@ func TestProcCtor(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestConstructorBadOrderingKey(t *testing.T) {
+	for _, threads := range []int{1, 2} {
+		conf := NewConfig()
+		conf.Threads = threads
+		conf.OrderingKey = "${! not_a_function() }"
+
+		if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error from bad ordering key with %v threads", threads)
+		}
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
 )
 
 //------------------------------------------------------------------------------
@@ -15,10 +19,15 @@ import (
 // Pool is a pool of pipelines. Each pipeline reads from a shared transaction
 // channel. Inputs remain coupled to their outputs as they propagate the
 // response channel in the transaction.
+//
+// When an ordering key is set each message is instead routed to a worker
+// chosen by hashing its key, which preserves the order of messages that share
+// a key.
 type Pool struct {
 	running uint32
 
 	workers []types.Pipeline
+	key     field.Expression
 
 	log   log.Modular
 	stats metrics.Type
@@ -62,8 +71,133 @@ func NewPool(
 	return p, nil
 }
 
+// NewKeyedPool returns a new pipeline pool that utilises multiple processor
+// threads, where each message is pinned to a thread by hashing an ordering key
+// resolved from it. Batches that contain messages pinned to different threads
+// are split, and messages that share a key are therefore processed in the order
+// that they were received.
+func NewKeyedPool(
+	constructor types.PipelineConstructorFunc,
+	threads int,
+	key field.Expression,
+	log log.Modular,
+	stats metrics.Type,
+) (*Pool, error) {
+	p, err := NewPool(constructor, threads, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	p.key = key
+	return p, nil
+}
+
 //------------------------------------------------------------------------------
 
+// workerIndex returns the index of the worker that a message of a batch is
+// pinned to.
+func (p *Pool) workerIndex(index int, msg types.Message) int {
+	return int(xxhash.ChecksumString64(p.key.String(index, msg)) % uint64(len(p.workers)))
+}
+
+// split divides a transaction into a transaction for each worker that its
+// messages are pinned to, ordered by the first message pinned to each worker.
+// When the batch is split a goroutine responds to the original transaction
+// once all of the split transactions have received a response.
+func (p *Pool) split(t types.Transaction) ([]int, []types.Transaction) {
+	var indexes []int
+	batches := map[int]types.Message{}
+	t.Payload.Iter(func(i int, part types.Part) error {
+		index := p.workerIndex(i, t.Payload)
+		b, exists := batches[index]
+		if !exists {
+			b = message.New(nil)
+			batches[index] = b
+			indexes = append(indexes, index)
+		}
+		b.Append(part.Copy())
+		return nil
+	})
+	if len(indexes) <= 1 {
+		if len(indexes) == 0 {
+			indexes = append(indexes, 0)
+		}
+		return indexes, []types.Transaction{t}
+	}
+
+	// Response channels are buffered as split transactions can be responded to
+	// in any order.
+	resChans := make([]chan types.Response, len(indexes))
+	trans := make([]types.Transaction, len(indexes))
+	for i, index := range indexes {
+		resChans[i] = make(chan types.Response, 1)
+		trans[i] = types.NewTransaction(batches[index], resChans[i])
+	}
+
+	go func() {
+		var resErr error
+		skipAcks := 0
+		for _, c := range resChans {
+			select {
+			case res := <-c:
+				if err := res.Error(); err != nil {
+					if resErr == nil {
+						resErr = err
+					}
+				} else if res.SkipAck() {
+					skipAcks++
+				}
+			case <-p.closeChan:
+				return
+			}
+		}
+
+		var res types.Response
+		switch {
+		case resErr != nil:
+			res = response.NewError(resErr)
+		case skipAcks == len(resChans):
+			res = response.NewUnack()
+		default:
+			res = response.NewAck()
+		}
+		select {
+		case t.ResponseChan <- res:
+		case <-p.closeChan:
+		}
+	}()
+	return indexes, trans
+}
+
+// dispatchKeyed routes transactions from the shared transaction channel to the
+// channel of the worker they are pinned to.
+func (p *Pool) dispatchKeyed(workerChans []chan types.Transaction) {
+	defer func() {
+		for _, c := range workerChans {
+			close(c)
+		}
+	}()
+	for {
+		var t types.Transaction
+		var open bool
+		select {
+		case t, open = <-p.messagesIn:
+			if !open {
+				return
+			}
+		case <-p.closeChan:
+			return
+		}
+		indexes, trans := p.split(t)
+		for i, index := range indexes {
+			select {
+			case workerChans[index] <- trans[i]:
+			case <-p.closeChan:
+				return
+			}
+		}
+	}
+}
+
 // loop is the processing loop of this pipeline.
 func (p *Pool) loop() {
 	defer func() {
@@ -90,8 +224,22 @@ func (p *Pool) loop() {
 	internalMessages := make(chan types.Transaction)
 	remainingWorkers := int64(len(p.workers))
 
-	for _, worker := range p.workers {
-		if err := worker.Consume(p.messagesIn); err != nil {
+	workerInputs := make([]<-chan types.Transaction, len(p.workers))
+	if p.key != nil {
+		workerChans := make([]chan types.Transaction, len(p.workers))
+		for i := range workerChans {
+			workerChans[i] = make(chan types.Transaction)
+			workerInputs[i] = workerChans[i]
+		}
+		go p.dispatchKeyed(workerChans)
+	} else {
+		for i := range workerInputs {
+			workerInputs[i] = p.messagesIn
+		}
+	}
+
+	for i, worker := range p.workers {
+		if err := worker.Consume(workerInputs[i]); err != nil {
 			p.log.Errorf("Failed to start pipeline worker: %v\n", err)
 			atomic.AddInt64(&remainingWorkers, -1)
 			continue
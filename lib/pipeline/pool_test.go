@@ -1,17 +1,23 @@
 package pipeline
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPoolBasic(t *testing.T) {
@@ -323,3 +329,162 @@ func TestPoolMultiNaturalClose(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+type mockSleepProcessor struct{}
+
+func (m mockSleepProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	time.Sleep(time.Duration(rand.Intn(2000)) * time.Microsecond)
+	return []types.Message{msg}, nil
+}
+
+func (m mockSleepProcessor) CloseAsync() {}
+
+func (m mockSleepProcessor) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestPoolKeyedOrdering(t *testing.T) {
+	constr := func(i *int) (types.Pipeline, error) {
+		return NewProcessor(
+			log.Noop(),
+			metrics.Noop(),
+			mockSleepProcessor{},
+		), nil
+	}
+
+	key, err := bloblang.NewField(`${! json("key") }`)
+	require.NoError(t, err)
+
+	proc, err := NewKeyedPool(constr, 4, key, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, proc.Consume(tChan))
+
+	keys, perKey := 6, 20
+	go func() {
+		for i := 0; i < perKey; i++ {
+			for k := 0; k < keys; k++ {
+				resChan := make(chan types.Response, 1)
+				msg := message.New([][]byte{
+					[]byte(fmt.Sprintf(`{"key":"k%v","seq":%v}`, k, i)),
+				})
+				select {
+				case tChan <- types.NewTransaction(msg, resChan):
+				case <-time.After(time.Second * 5):
+					t.Error("Timed out")
+					return
+				}
+			}
+		}
+	}()
+
+	seqs := map[string][]int{}
+	for i := 0; i < keys*perKey; i++ {
+		select {
+		case tran, open := <-proc.TransactionChan():
+			require.True(t, open)
+			var doc struct {
+				Key string `json:"key"`
+				Seq int    `json:"seq"`
+			}
+			require.NoError(t, json.Unmarshal(tran.Payload.Get(0).Get(), &doc))
+			seqs[doc.Key] = append(seqs[doc.Key], doc.Seq)
+			tran.ResponseChan <- response.NewAck()
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+	}
+
+	require.Len(t, seqs, keys)
+	for k, s := range seqs {
+		require.Len(t, s, perKey, k)
+		for i, seq := range s {
+			assert.Equal(t, i, seq, k)
+		}
+	}
+
+	proc.CloseAsync()
+	require.NoError(t, proc.WaitForClose(time.Second*5))
+}
+
+func TestPoolKeyedSplitBatches(t *testing.T) {
+	constr := func(i *int) (types.Pipeline, error) {
+		return NewProcessor(
+			log.Noop(),
+			metrics.Noop(),
+			mockSleepProcessor{},
+		), nil
+	}
+
+	key, err := bloblang.NewField(`${! json("key") }`)
+	require.NoError(t, err)
+
+	proc, err := NewKeyedPool(constr, 4, key, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, proc.Consume(tChan))
+
+	var parts [][]byte
+	for i := 0; i < 20; i++ {
+		parts = append(parts, []byte(fmt.Sprintf(`{"key":"k%v","seq":%v}`, i%5, i)))
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New(parts), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	seqs := map[string][]int{}
+	var batches []types.Transaction
+	for received := 0; received < len(parts); {
+		select {
+		case tran := <-proc.TransactionChan():
+			tran.Payload.Iter(func(i int, p types.Part) error {
+				var doc struct {
+					Key string `json:"key"`
+					Seq int    `json:"seq"`
+				}
+				require.NoError(t, json.Unmarshal(p.Get(), &doc))
+				seqs[doc.Key] = append(seqs[doc.Key], doc.Seq)
+				return nil
+			})
+			received += tran.Payload.Len()
+			batches = append(batches, tran)
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out")
+		}
+	}
+	assert.Greater(t, len(batches), 1)
+	for k, s := range seqs {
+		for i := 1; i < len(s); i++ {
+			assert.Less(t, s[i-1], s[i], k)
+		}
+	}
+
+	for i, tran := range batches {
+		select {
+		case res := <-resChan:
+			t.Fatalf("Received response before all batches were acknowledged: %v", res)
+		default:
+		}
+		var res types.Response = response.NewAck()
+		if i == 0 {
+			res = response.NewError(errors.New("nope"))
+		}
+		tran.ResponseChan <- res
+	}
+
+	select {
+	case res := <-resChan:
+		assert.EqualError(t, res.Error(), "nope")
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out")
+	}
+
+	proc.CloseAsync()
+	require.NoError(t, proc.WaitForClose(time.Second*5))
+}
@@ -44,6 +44,7 @@ func NewProcessor(
 	return &Processor{
 		running:       1,
 		msgProcessors: msgProcessors,
+		log:           log,
 		stats:         stats,
 		messagesOut:   make(chan types.Transaction),
 		responsesIn:   make(chan types.Response),
@@ -66,6 +67,33 @@ func (p *Processor) loop() {
 		close(p.closed)
 	}()
 
+	flushChan := make(chan int)
+	flushDone := make(chan struct{})
+	defer close(flushDone)
+
+	for i, proc := range p.msgProcessors {
+		f, ok := proc.(processor.Flusher)
+		if !ok || f.FlushPeriod() <= 0 {
+			continue
+		}
+		go func(index int, period time.Duration) {
+			ticker := time.NewTicker(period)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+				case <-flushDone:
+					return
+				}
+				select {
+				case flushChan <- index:
+				case <-flushDone:
+					return
+				}
+			}
+		}(i, f.FlushPeriod())
+	}
+
 	var open bool
 	for atomic.LoadInt32(&p.running) == 1 {
 		var tran types.Transaction
@@ -74,6 +102,9 @@ func (p *Processor) loop() {
 			if !open {
 				return
 			}
+		case index := <-flushChan:
+			p.flush(index)
+			continue
 		case <-p.closeChan:
 			return
 		}
@@ -104,6 +135,23 @@ func (p *Processor) loop() {
 	}
 }
 
+// flush sends the messages held back by a processor through the processors
+// that follow it. Since these messages have no origin transaction their
+// delivery is retried until success and the final response is discarded.
+func (p *Processor) flush(index int) {
+	var resultMsgs []types.Message
+	for _, msg := range p.msgProcessors[index].(processor.Flusher).Flush() {
+		msgs, res := processor.ExecuteAll(p.msgProcessors[index+1:], msg)
+		if res != nil && res.Error() != nil {
+			p.log.Errorf("Failed to process flushed message: %v\n", res.Error())
+		}
+		resultMsgs = append(resultMsgs, msgs...)
+	}
+	if len(resultMsgs) > 0 {
+		p.dispatchMessages(resultMsgs, make(chan types.Response, 1))
+	}
+}
+
 // dispatchMessages attempts to send a multiple messages results of processors
 // over the shared messages channel. This send is retried until success.
 func (p *Processor) dispatchMessages(msgs []types.Message, ogResChan chan<- types.Response) {
@@ -403,3 +403,55 @@ func TestProcessorMultiMsgsOddSync(t *testing.T) {
 		t.Error("Expected mockproc to have waited for close")
 	}
 }
+
+type mockFlushProcessor struct {
+	mockMsgProcessor
+	flushes chan types.Message
+}
+
+func (m *mockFlushProcessor) FlushPeriod() time.Duration {
+	return time.Millisecond
+}
+
+func (m *mockFlushProcessor) Flush() []types.Message {
+	select {
+	case msg := <-m.flushes:
+		return []types.Message{msg}
+	default:
+	}
+	return nil
+}
+
+func TestProcessorPipelineFlush(t *testing.T) {
+	flushProc := &mockFlushProcessor{flushes: make(chan types.Message, 1)}
+	mockProc := &mockMsgProcessor{dropChan: make(chan bool)}
+
+	proc := NewProcessor(log.Noop(), metrics.Noop(), flushProc, mockProc)
+	if err := proc.Consume(make(chan types.Transaction)); err != nil {
+		t.Fatal(err)
+	}
+
+	flushProc.flushes <- message.New([][]byte{[]byte("flushed")})
+	go func() {
+		mockProc.dropChan <- false
+	}()
+
+	select {
+	case tran := <-proc.TransactionChan():
+		if exp, act := [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(tran.Payload); !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong flushed message: %s != %s", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"sort"
 	"time"
 
-	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
@@ -147,7 +147,7 @@ pipeline:
 } else {
 	deleted()
 }`,
-			),
+			).IsBloblang(),
 			docs.FieldCommon(
 				"processors",
 				"A list of processors to apply to mapped requests. When processing message batches the resulting batch must match the size and ordering of the input batch, therefore filtering, grouping should not be performed within these processors.",
@@ -166,7 +166,7 @@ root.bar.id = this.user.id`,
 } else {
 	this
 }`,
-			),
+			).IsBloblang(),
 		},
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return conf.Branch.Sanitise()
@@ -275,12 +275,12 @@ func newBranch(
 
 	var err error
 	if len(conf.RequestMap) > 0 {
-		if b.requestMap, err = bloblang.NewMapping("", conf.RequestMap); err != nil {
+		if b.requestMap, err = interop.NewBloblangMapping(mgr, conf.RequestMap); err != nil {
 			return nil, fmt.Errorf("failed to parse request mapping: %w", err)
 		}
 	}
 	if len(conf.ResultMap) > 0 {
-		if b.resultMap, err = bloblang.NewMapping("", conf.ResultMap); err != nil {
+		if b.resultMap, err = interop.NewBloblangMapping(mgr, conf.ResultMap); err != nil {
 			return nil, fmt.Errorf("failed to parse result mapping: %w", err)
 		}
 	}
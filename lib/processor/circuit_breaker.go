@@ -0,0 +1,319 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/circuit"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeCircuitBreaker] = TypeSpec{
+		constructor: NewCircuitBreaker,
+		Categories: []Category{
+			CategoryComposition,
+		},
+		Status:  docs.StatusBeta,
+		Version: "3.42.0",
+		Summary: `
+Executes a list of child processors and stops doing so when the rate of batches
+that fail them exceeds a threshold, either failing messages immediately, passing
+them through a list of fallback processors, or pausing until the child
+processors are attempted again.`,
+		Description: `
+This processor is most useful for wrapping processors that call out to other
+services, such as ` + "[`http`](/docs/components/processors/http)" + `, in
+order to prevent a failing service from being flooded with requests that are
+unlikely to succeed.
+
+The circuit breaker begins in a closed state where all batches are processed by
+the child processors. A batch is considered failed when the child processors
+flag any message of it as failed. When the ratio of failed batches within
+` + "`window`" + ` reaches ` + "`error_threshold`" + ` the circuit opens and,
+for the duration of ` + "`open_period`" + `, batches are handled according to
+` + "`on_open`" + `. Messages rejected with ` + "`fail_fast`" + ` are flagged as
+failed and can be handled with [error handling patterns](/docs/configuration/error_handling).
+
+Once ` + "`open_period`" + ` has passed the circuit becomes half-open, where a
+limited number of batches are processed by the child processors. If they succeed
+the circuit closes again, otherwise it reopens.
+
+The state of the circuit breaker is exposed as the gauge
+` + "`circuit_breaker.state`" + `, where ` + "`0`" + ` is closed, ` + "`1`" + ` is
+half-open and ` + "`2`" + ` is open. When a ` + "`name`" + ` is specified the
+state can also be queried from the HTTP endpoint
+` + "`/circuit_breakers/{name}`" + `.`,
+		FieldSpecs: circuit.FieldSpecs().Add(
+			docs.FieldCommon("on_open", "How batches are handled while the circuit is open.").HasAnnotatedOptions(
+				"fail_fast", "Messages are flagged as failed without being processed by the child processors.",
+				"fallback", "Messages are processed by the `fallback` processors instead.",
+				"pause", "Processing is blocked until the circuit becomes half-open, which applies back pressure to the input.",
+			),
+			docs.FieldCommon("processors", "A list of child processors to execute while the circuit is closed."),
+			docs.FieldCommon("fallback", "A list of processors to execute while the circuit is open, required when `on_open` is `fallback`."),
+		),
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			sanitChildren := func(confs []Config) ([]interface{}, error) {
+				procConfs := make([]interface{}, len(confs))
+				for i, pConf := range confs {
+					var err error
+					if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+						return nil, err
+					}
+				}
+				return procConfs, nil
+			}
+			procConfs, err := sanitChildren(conf.CircuitBreaker.Processors)
+			if err != nil {
+				return nil, err
+			}
+			fallbackConfs, err := sanitChildren(conf.CircuitBreaker.Fallback)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"name":               conf.CircuitBreaker.Name,
+				"error_threshold":    conf.CircuitBreaker.ErrorThreshold,
+				"min_requests":       conf.CircuitBreaker.MinRequests,
+				"window":             conf.CircuitBreaker.Window,
+				"open_period":        conf.CircuitBreaker.OpenPeriod,
+				"half_open_requests": conf.CircuitBreaker.HalfOpenRequests,
+				"on_open":            conf.CircuitBreaker.OnOpen,
+				"processors":         procConfs,
+				"fallback":           fallbackConfs,
+			}, nil
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title:   "Enrichment with a fallback",
+				Summary: "In this example documents are enriched with the response of an HTTP service, and while the service is failing the documents are instead marked as not enriched for five minutes before the service is attempted again.",
+				Config: `
+pipeline:
+  processors:
+    - circuit_breaker:
+        name: enrichment
+        open_period: 5m
+        on_open: fallback
+        processors:
+          - branch:
+              request_map: 'root = this.id'
+              processors:
+                - http:
+                    url: http://example.com/enrich
+                    verb: POST
+              result_map: 'root.enrichment = this'
+        fallback:
+          - bloblang: 'root.enrichment = null'
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CircuitBreakerConfig is a config struct containing fields for the
+// CircuitBreaker processor.
+type CircuitBreakerConfig struct {
+	circuit.Config `json:",inline" yaml:",inline"`
+	OnOpen         string   `json:"on_open" yaml:"on_open"`
+	Processors     []Config `json:"processors" yaml:"processors"`
+	Fallback       []Config `json:"fallback" yaml:"fallback"`
+}
+
+// NewCircuitBreakerConfig returns a default CircuitBreakerConfig.
+func NewCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Config:     circuit.NewConfig(),
+		OnOpen:     "fail_fast",
+		Processors: []Config{},
+		Fallback:   []Config{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CircuitBreaker is a processor that applies child processors to batches until
+// the rate of failed batches exceeds a threshold, at which point batches are
+// handled according to the configured behaviour while the circuit is open.
+type CircuitBreaker struct {
+	onOpen   string
+	breaker  *circuit.Breaker
+	children []types.Processor
+	fallback []types.Processor
+
+	ctx  context.Context
+	done func()
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mFailFast  metrics.StatCounter
+	mFallback  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewCircuitBreaker returns a CircuitBreaker processor.
+func NewCircuitBreaker(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	switch conf.CircuitBreaker.OnOpen {
+	case "fail_fast", "pause":
+	case "fallback":
+		if len(conf.CircuitBreaker.Fallback) == 0 {
+			return nil, fmt.Errorf("fallback processors are required when on_open is fallback")
+		}
+	default:
+		return nil, fmt.Errorf("on_open behaviour not recognised: %v", conf.CircuitBreaker.OnOpen)
+	}
+
+	breaker, err := conf.CircuitBreaker.NewBreaker(stats)
+	if err != nil {
+		return nil, err
+	}
+
+	if mgr != nil {
+		if err = breaker.RegisterEndpoint(mgr); err != nil {
+			return nil, err
+		}
+	}
+
+	var children []types.Processor
+	for i, pconf := range conf.CircuitBreaker.Processors {
+		ns := fmt.Sprintf("circuit_breaker.%v", i)
+		proc, err := New(pconf, mgr, log.NewModule("."+ns), metrics.Namespaced(stats, ns))
+		if err != nil {
+			breaker.Close()
+			return nil, err
+		}
+		children = append(children, proc)
+	}
+
+	var fallback []types.Processor
+	for i, pconf := range conf.CircuitBreaker.Fallback {
+		ns := fmt.Sprintf("circuit_breaker.fallback.%v", i)
+		proc, err := New(pconf, mgr, log.NewModule("."+ns), metrics.Namespaced(stats, ns))
+		if err != nil {
+			breaker.Close()
+			return nil, err
+		}
+		fallback = append(fallback, proc)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	return &CircuitBreaker{
+		onOpen:   conf.CircuitBreaker.OnOpen,
+		breaker:  breaker,
+		children: children,
+		fallback: fallback,
+
+		ctx:  ctx,
+		done: done,
+
+		log: log,
+
+		mCount:     stats.GetCounter("count"),
+		mFailFast:  stats.GetCounter("circuit_breaker.fail_fast"),
+		mFallback:  stats.GetCounter("circuit_breaker.fallback"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func countFailed(msgs ...types.Message) (failed int) {
+	for _, m := range msgs {
+		m.Iter(func(i int, p types.Part) error {
+			if HasFailed(p) {
+				failed++
+			}
+			return nil
+		})
+	}
+	return
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (c *CircuitBreaker) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	c.mCount.Incr(1)
+
+	permit, allowed := c.breaker.Allow()
+	if !allowed && c.onOpen == "pause" {
+		permit, allowed = c.breaker.Wait(c.ctx)
+	}
+
+	var msgs []types.Message
+	var res types.Response
+
+	switch {
+	case allowed:
+		failedBefore := countFailed(msg)
+		msgs, res = ExecuteAll(c.children, msg)
+		failed := res != nil && res.Error() != nil
+		if !failed {
+			failed = countFailed(msgs...) > failedBefore
+		}
+		c.breaker.Record(permit, failed)
+	case c.onOpen == "fallback":
+		c.mFallback.Incr(1)
+		msgs, res = ExecuteAll(c.fallback, msg)
+	default:
+		c.mFailFast.Incr(1)
+		newMsg := msg.Copy()
+		newMsg.Iter(func(i int, p types.Part) error {
+			FlagErr(p, circuit.ErrOpen)
+			return nil
+		})
+		msgs = []types.Message{newMsg}
+	}
+
+	if len(msgs) == 0 {
+		return nil, res
+	}
+
+	c.mBatchSent.Incr(int64(len(msgs)))
+	for _, m := range msgs {
+		c.mSent.Incr(int64(m.Len()))
+	}
+	return msgs, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (c *CircuitBreaker) CloseAsync() {
+	c.done()
+	c.breaker.Close()
+	for _, p := range c.children {
+		p.CloseAsync()
+	}
+	for _, p := range c.fallback {
+		p.CloseAsync()
+	}
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (c *CircuitBreaker) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+	for _, p := range c.children {
+		if err := p.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	for _, p := range c.fallback {
+		if err := p.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/util/circuit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreakerProcFailFast(t *testing.T) {
+	failConf := NewConfig()
+	failConf.Type = TypeBloblang
+	failConf.Bloblang = `root = if this.fail { throw("nope") } else { this }`
+
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.MinRequests = 2
+	conf.CircuitBreaker.ErrorThreshold = 0.6
+	conf.CircuitBreaker.OpenPeriod = "1h"
+	conf.CircuitBreaker.Processors = append(conf.CircuitBreaker.Processors, failConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"fail":false}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+
+	for i := 0; i < 2; i++ {
+		msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`{"fail":true}`)}))
+		require.Nil(t, res)
+		require.Len(t, msgs, 1)
+		assert.True(t, HasFailed(msgs[0].Get(0)))
+		assert.NotEqual(t, circuit.ErrOpen.Error(), GetFail(msgs[0].Get(0)))
+	}
+
+	input := message.New([][]byte{[]byte(`{"fail":false}`)})
+	msgs, res = proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, circuit.ErrOpen.Error(), GetFail(msgs[0].Get(0)))
+	assert.False(t, HasFailed(input.Get(0)))
+}
+
+func TestCircuitBreakerProcFallback(t *testing.T) {
+	failConf := NewConfig()
+	failConf.Type = TypeBloblang
+	failConf.Bloblang = `root = throw("nope")`
+
+	fallbackConf := NewConfig()
+	fallbackConf.Type = TypeBloblang
+	fallbackConf.Bloblang = `root = "fallback"`
+
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.MinRequests = 1
+	conf.CircuitBreaker.OpenPeriod = "1h"
+	conf.CircuitBreaker.OnOpen = "fallback"
+	conf.CircuitBreaker.Processors = append(conf.CircuitBreaker.Processors, failConf)
+	conf.CircuitBreaker.Fallback = append(conf.CircuitBreaker.Fallback, fallbackConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`foo`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`bar`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, "fallback", string(msgs[0].Get(0).Get()))
+}
+
+func TestCircuitBreakerProcPause(t *testing.T) {
+	failConf := NewConfig()
+	failConf.Type = TypeBloblang
+	failConf.Bloblang = `root = if this.fail { throw("nope") } else { this }`
+
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.MinRequests = 1
+	conf.CircuitBreaker.OpenPeriod = "100ms"
+	conf.CircuitBreaker.OnOpen = "pause"
+	conf.CircuitBreaker.Processors = append(conf.CircuitBreaker.Processors, failConf)
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"fail":true}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+
+	start := time.Now()
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`{"fail":false}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+	assert.True(t, time.Since(start) >= time.Millisecond*50)
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`{"fail":true}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		proc.CloseAsync()
+	}()
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`{"fail":false}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, circuit.ErrOpen.Error(), GetFail(msgs[0].Get(0)))
+
+	assert.NoError(t, proc.WaitForClose(time.Second))
+}
+
+func TestCircuitBreakerProcConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeCircuitBreaker
+	conf.CircuitBreaker.OnOpen = "fallback"
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.CircuitBreaker.OnOpen = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
@@ -0,0 +1,807 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/opentracing/opentracing-go"
+	olog "github.com/opentracing/opentracing-go/log"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJavaScript] = TypeSpec{
+		constructor: NewJavaScript,
+		Categories: []Category{
+			CategoryMapping,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.42.0",
+		Summary: `
+Executes a JavaScript program on each message of a batch with an embedded
+interpreter, allowing imperative transformations that are awkward to express
+with [Bloblang](/docs/guides/bloblang/about).`,
+		Description: `
+Programs are interpreted with [goja](https://github.com/dop251/goja), which
+supports ECMAScript 5.1 and a subset of later features.
+
+The program is executed once when the processor is created and must declare a
+global function ` + "`process`" + `, which is then called for each message with
+a message object as its only argument. The return value of ` + "`process`" + `
+determines the result:
+
+- ` + "`undefined`" + ` (nothing is returned) keeps the message, including any
+  changes made to it.
+- ` + "`null`" + ` drops the message.
+- A message object replaces the message.
+- A string replaces the contents of the message.
+- An array results in a message for each element, where elements can be any of
+  the above types other than arrays. An empty array drops the message.
+- Any other value replaces the contents of the message with the value
+  serialised as JSON.
+
+Messages created from strings or other values keep the metadata of the
+original message. If the program throws an exception the message is kept
+unchanged and flagged as failed, and can be handled with
+[error handling patterns](/docs/configuration/error_handling).
+
+Global variables of the program persist between calls to ` + "`process`" + `,
+and therefore can be used to hold state.
+
+### Modules
+
+Modules can be loaded with ` + "`require`" + `, which follows the resolution
+rules of Node.js. Modules are only loaded from the directories listed in
+` + "`module_paths`" + ` and the directory of ` + "`file`" + ` when it is set,
+any other module is treated as not found.
+
+### Sandboxing
+
+Programs have no access to the file system, network or environment other than
+loading modules as described above. The execution time of each call to
+` + "`process`" + ` is limited by ` + "`timeout`" + `, and when
+` + "`max_memory`" + ` is set the call is also interrupted once the strings,
+arrays and objects it creates exceed that number of bytes. The memory used is
+an estimate based on the assignments made by the program and the results of
+built in string and array methods, and therefore programs should still be
+trusted not to exhaust the memory of the process by other means. Enabling the
+limit adds a small cost to each assignment.`,
+		Footnotes: `
+## Message Object
+
+The message object passed to ` + "`process`" + ` has the following methods:
+
+` + "### `content()`" + `
+
+Returns the contents of the message as a string.
+
+` + "### `setContent(value)`" + `
+
+Sets the contents of the message to a string.
+
+` + "### `structured()`" + `
+
+Returns the contents of the message parsed as JSON. Changes to the returned
+value do not modify the message until it is passed to ` + "`setStructured`" + `.
+
+` + "### `setStructured(value)`" + `
+
+Sets the contents of the message to a value serialised as JSON.
+
+` + "### `meta(key)`" + `
+
+Returns the value of a metadata key, or an empty string if it does not exist.
+
+` + "### `setMeta(key, value)`" + `
+
+Sets a metadata key to a string value.
+
+` + "### `deleteMeta(key)`" + `
+
+Removes a metadata key.
+
+` + "### `metadata()`" + `
+
+Returns an object of all metadata keys and values.
+
+` + "### `clone()`" + `
+
+Returns a deep copy of the message, which is useful for returning multiple
+messages.
+
+## Console
+
+The global ` + "`console`" + ` object supports the methods ` + "`debug`" + `,
+` + "`log`" + `, ` + "`info`" + `, ` + "`warn`" + ` and ` + "`error`" + `,
+which write to the Benthos logger at the corresponding level.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("code", "An inline JavaScript program to execute. Either this field or `file` must be set.").HasDefault(""),
+			docs.FieldCommon("file", "A path to a file containing a JavaScript program to execute. Either this field or `code` must be set.").HasDefault(""),
+			docs.FieldCommon("module_paths", "A list of directories from which modules can be loaded with `require`.", []string{"./js_modules"}),
+			docs.FieldCommon("timeout", "The maximum period of time that a single call to `process` may execute for before it is interrupted. Set to an empty string in order to disable the limit."),
+			docs.FieldAdvanced("max_memory", "An optional estimate of the maximum number of bytes that a single call to `process` may allocate for strings, arrays and objects before it is interrupted. Set to zero in order to disable the limit."),
+		},
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Splitting and Filtering",
+				Summary: `
+In this example documents containing an array of ` + "`items`" + ` are split into
+a message per item, with items that are out of stock removed and the count of
+remaining items written to each message as metadata.`,
+				Config: `
+pipeline:
+  processors:
+    - javascript:
+        code: |
+          function process(msg) {
+            var doc = msg.structured();
+            var items = doc.items.filter(function(item) {
+              return item.stock > 0;
+            });
+            return items.map(function(item) {
+              var out = msg.clone();
+              out.setStructured({ order: doc.id, item: item });
+              out.setMeta("item_count", String(items.length));
+              return out;
+            });
+          }
+`,
+			},
+			{
+				Title: "Shared Modules",
+				Summary: `
+Programs can import functions shared across configs from a directory of modules.`,
+				Config: `
+pipeline:
+  processors:
+    - javascript:
+        module_paths: [ ./js_modules ]
+        code: |
+          var addresses = require("addresses");
+          function process(msg) {
+            var doc = msg.structured();
+            doc.address = addresses.normalise(doc.address);
+            msg.setStructured(doc);
+          }
+`,
+			},
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JavaScriptConfig contains configuration fields for the JavaScript processor.
+type JavaScriptConfig struct {
+	Code        string   `json:"code" yaml:"code"`
+	File        string   `json:"file" yaml:"file"`
+	ModulePaths []string `json:"module_paths" yaml:"module_paths"`
+	Timeout     string   `json:"timeout" yaml:"timeout"`
+	MaxMemory   int      `json:"max_memory" yaml:"max_memory"`
+}
+
+// NewJavaScriptConfig returns a JavaScriptConfig with default values.
+func NewJavaScriptConfig() JavaScriptConfig {
+	return JavaScriptConfig{
+		Code:        "",
+		File:        "",
+		ModulePaths: []string{},
+		Timeout:     "1s",
+		MaxMemory:   0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	errJavaScriptTimeout = errors.New("execution timed out")
+	errJavaScriptMemory  = errors.New("execution exceeded memory limit")
+)
+
+// JavaScript is a processor that executes a JavaScript program on each message
+// of a batch.
+type JavaScript struct {
+	timeout time.Duration
+
+	mut         sync.Mutex
+	vm          *goja.Runtime
+	process     goja.Callable
+	jsonParse   goja.Callable
+	resetAllocs goja.Callable
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewJavaScript returns a JavaScript processor.
+func NewJavaScript(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	jConf := conf.JavaScript
+
+	if (jConf.Code == "") == (jConf.File == "") {
+		return nil, errors.New("exactly one of code or file must be set")
+	}
+
+	j := &JavaScript{
+		log: log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mDropped:   stats.GetCounter("dropped"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	if jConf.Timeout != "" {
+		var err error
+		if j.timeout, err = time.ParseDuration(jConf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	if jConf.MaxMemory < 0 {
+		return nil, errors.New("max_memory must not be negative")
+	}
+
+	code, name := jConf.Code, "code"
+	allowedDirs := make([]string, 0, len(jConf.ModulePaths)+1)
+	if jConf.File != "" {
+		codeBytes, err := ioutil.ReadFile(jConf.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+		code, name = string(codeBytes), filepath.ToSlash(jConf.File)
+		allowedDirs = append(allowedDirs, filepath.Dir(jConf.File))
+	}
+	allowedDirs = append(allowedDirs, jConf.ModulePaths...)
+
+	if jConf.MaxMemory > 0 {
+		var err error
+		if code, err = jsInstrumentAllocs(name, code, false); err != nil {
+			return nil, fmt.Errorf("failed to compile program: %v", err)
+		}
+	}
+	program, err := goja.Compile(name, code, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile program: %v", err)
+	}
+
+	j.vm = goja.New()
+	j.vm.SetFieldNameMapper(goja.UncapFieldNameMapper())
+	registry := require.NewRegistry(
+		require.WithLoader(jsSandboxedLoader(allowedDirs, jConf.MaxMemory > 0)),
+		require.WithGlobalFolders(jConf.ModulePaths...),
+	)
+	registry.Enable(j.vm)
+	if err = j.registerConsole(); err != nil {
+		return nil, err
+	}
+	if jConf.MaxMemory > 0 {
+		if err = j.registerAllocHook(jConf.MaxMemory); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = j.exec(func() (err error) {
+		_, err = j.vm.RunProgram(program)
+		return
+	}); err != nil {
+		return nil, fmt.Errorf("failed to run program: %v", err)
+	}
+
+	var ok bool
+	if j.process, ok = goja.AssertFunction(j.vm.Get("process")); !ok {
+		return nil, errors.New("program must declare a function process")
+	}
+	if j.jsonParse, ok = goja.AssertFunction(j.vm.Get("JSON").ToObject(j.vm).Get("parse")); !ok {
+		return nil, errors.New("failed to obtain JSON.parse function")
+	}
+	return j, nil
+}
+
+//------------------------------------------------------------------------------
+
+// jsSandboxedLoader returns a module loader that only reads files within a
+// list of directories, all other files are treated as though they do not
+// exist. When instrument is true the allocations of loaded modules are
+// accounted for in the same way as the program.
+func jsSandboxedLoader(dirs []string, instrument bool) require.SourceLoader {
+	absDirs := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		if abs, err := filepath.Abs(d); err == nil {
+			absDirs = append(absDirs, abs)
+		}
+	}
+	return func(path string) ([]byte, error) {
+		abs, err := filepath.Abs(filepath.FromSlash(path))
+		if err != nil {
+			return nil, require.ModuleFileDoesNotExistError
+		}
+		for _, d := range absDirs {
+			if abs == d || strings.HasPrefix(abs, d+string(filepath.Separator)) {
+				src, err := require.DefaultSourceLoader(path)
+				if err != nil || !instrument {
+					return src, err
+				}
+				instrumented, err := jsInstrumentAllocs(path, string(src), true)
+				if err != nil {
+					return nil, err
+				}
+				return []byte(instrumented), nil
+			}
+		}
+		return nil, require.ModuleFileDoesNotExistError
+	}
+}
+
+func (j *JavaScript) registerConsole() error {
+	console := j.vm.NewObject()
+	for name, logFn := range map[string]func(string){
+		"debug": j.log.Debugln,
+		"log":   j.log.Infoln,
+		"info":  j.log.Infoln,
+		"warn":  j.log.Warnln,
+		"error": j.log.Errorln,
+	} {
+		fn := logFn
+		if err := console.Set(name, func(call goja.FunctionCall) goja.Value {
+			args := make([]string, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				args[i] = arg.String()
+			}
+			fn(strings.Join(args, " "))
+			return goja.Undefined()
+		}); err != nil {
+			return err
+		}
+	}
+	return j.vm.Set("console", console)
+}
+
+// jsAllocHook is the name of a global object that the values of assignments
+// are also assigned to when max_memory is set, which accounts for their size.
+const jsAllocHook = "__benthos_alloc"
+
+// jsAllocPrelude installs the allocation hook and wraps built in methods that
+// create strings and arrays so that their results are accounted for. It
+// evaluates to a function that resets the count of allocated bytes.
+const jsAllocPrelude = `(function(global, limit, exceeded) {
+  "use strict";
+  var used = 0;
+  var apply = Reflect.apply;
+  var isArray = Array.isArray;
+  var defineProperty = Object.defineProperty;
+
+  function size(v) {
+    if (typeof v === "string") {
+      return v.length;
+    }
+    if (typeof v === "object" && v !== null) {
+      return isArray(v) ? v.length * 16 : 64;
+    }
+    return 0;
+  }
+
+  function account(n) {
+    used += n;
+    if (used > limit) {
+      exceeded();
+    }
+  }
+
+  function wrap(target, names, countArgs) {
+    names.forEach(function(name) {
+      var fn = target[name];
+      defineProperty(target, name, {
+        configurable: true,
+        writable: true,
+        value: function() {
+          if (countArgs) {
+            var n = 0;
+            for (var i = 0; i < arguments.length; i++) {
+              n += 16 + size(arguments[i]);
+            }
+            account(n);
+            return apply(fn, this, arguments);
+          }
+          var result = apply(fn, this, arguments);
+          account(size(result));
+          return result;
+        }
+      });
+    });
+  }
+
+  wrap(String.prototype, ["concat", "padEnd", "padStart", "repeat", "replace"], false);
+  wrap(Array.prototype, ["concat", "filter", "join", "map", "slice"], false);
+  wrap(Array.prototype, ["push", "splice", "unshift"], true);
+  wrap(JSON, ["stringify"], false);
+
+  defineProperty(global, "` + jsAllocHook + `", {
+    value: Object.freeze({
+      set value(v) {
+        account(size(v));
+      }
+    })
+  });
+
+  return function() {
+    used = 0;
+  };
+})`
+
+// registerAllocHook installs the allocation hook into the runtime, which
+// interrupts it once the bytes allocated since the last reset exceed a limit.
+func (j *JavaScript) registerAllocHook(limit int) error {
+	install, err := j.vm.RunString(jsAllocPrelude)
+	if err != nil {
+		return fmt.Errorf("failed to install memory limit: %v", err)
+	}
+	installFn, ok := goja.AssertFunction(install)
+	if !ok {
+		return errors.New("failed to install memory limit")
+	}
+	reset, err := installFn(
+		goja.Undefined(),
+		j.vm.GlobalObject(),
+		j.vm.ToValue(limit),
+		j.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			j.vm.Interrupt(errJavaScriptMemory)
+			return goja.Undefined()
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to install memory limit: %v", err)
+	}
+	if j.resetAllocs, ok = goja.AssertFunction(reset); !ok {
+		return errors.New("failed to install memory limit")
+	}
+	return nil
+}
+
+// jsInstrumentAllocs rewrites a program so that the value of each assignment
+// and variable initialiser is also assigned to the allocation hook. Modules are
+// parsed within a function body as they are allowed to return early.
+func jsInstrumentAllocs(name, src string, module bool) (string, error) {
+	prefix, parseSrc := "", src
+	if module {
+		prefix = "(function(){"
+		parseSrc = prefix + src + "\n})"
+	}
+	prog, err := parser.ParseFile(nil, name, parseSrc, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var offsets []int
+	addOffset := func(e ast.Expression) {
+		if e == nil {
+			return
+		}
+		// Keep function literals as direct initialisers so that they are
+		// still named after their variable.
+		if _, isFn := e.(*ast.FunctionLiteral); isFn {
+			return
+		}
+		if offset := int(e.Idx0()) - 1 - len(prefix); offset >= 0 && offset <= len(src) {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	type seenKey struct {
+		t reflect.Type
+		p uintptr
+	}
+	seen := map[seenKey]struct{}{}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			key := seenKey{t: v.Type(), p: v.Pointer()}
+			if _, exists := seen[key]; exists {
+				return
+			}
+			seen[key] = struct{}{}
+			switch n := v.Interface().(type) {
+			case *ast.AssignExpression:
+				addOffset(n.Right)
+			case *ast.VariableExpression:
+				addOffset(n.Initializer)
+			}
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).PkgPath == "" {
+					walk(v.Field(i))
+				}
+			}
+		}
+	}
+	walk(reflect.ValueOf(prog))
+
+	sort.Ints(offsets)
+	var b strings.Builder
+	last := 0
+	for i, offset := range offsets {
+		if i > 0 && offset == offsets[i-1] {
+			continue
+		}
+		b.WriteString(src[last:offset])
+		b.WriteString(jsAllocHook + ".value = ")
+		last = offset
+	}
+	b.WriteString(src[last:])
+	return b.String(), nil
+}
+
+// exec runs a function against the runtime, interrupting it when the execution
+// time or memory limits are exceeded.
+func (j *JavaScript) exec(fn func() error) error {
+	if j.resetAllocs != nil {
+		if _, err := j.resetAllocs(goja.Undefined()); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if j.timeout > 0 {
+		timer := time.NewTimer(j.timeout)
+		defer timer.Stop()
+
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-timer.C:
+				j.vm.Interrupt(errJavaScriptTimeout)
+			case <-done:
+			}
+		}()
+
+		err = fn()
+
+		// Wait for the watchdog to exit so that an interrupt cannot be raised
+		// after it is cleared.
+		close(done)
+		wg.Wait()
+	} else {
+		err = fn()
+	}
+	j.vm.ClearInterrupt()
+
+	return checkInterrupted(err)
+}
+
+func checkInterrupted(err error) error {
+	var iErr *goja.InterruptedError
+	if errors.As(err, &iErr) {
+		if vErr, ok := iErr.Value().(error); ok {
+			return vErr
+		}
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// jsMessage is the message object exposed to JavaScript programs, methods are
+// exposed with the first letter in lower case.
+type jsMessage struct {
+	j    *JavaScript
+	part types.Part
+}
+
+func (m *jsMessage) Content() string {
+	return string(m.part.Get())
+}
+
+func (m *jsMessage) SetContent(value string) {
+	m.part.Set([]byte(value))
+}
+
+func (m *jsMessage) Structured() (goja.Value, error) {
+	return m.j.jsonParse(goja.Undefined(), m.j.vm.ToValue(string(m.part.Get())))
+}
+
+func (m *jsMessage) SetStructured(value goja.Value) error {
+	return m.part.SetJSON(value.Export())
+}
+
+func (m *jsMessage) Meta(key string) string {
+	return m.part.Metadata().Get(key)
+}
+
+func (m *jsMessage) SetMeta(key, value string) {
+	m.part.Metadata().Set(key, value)
+}
+
+func (m *jsMessage) DeleteMeta(key string) {
+	m.part.Metadata().Delete(key)
+}
+
+func (m *jsMessage) Metadata() map[string]interface{} {
+	meta := map[string]interface{}{}
+	m.part.Metadata().Iter(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+	return meta
+}
+
+func (m *jsMessage) Clone() *jsMessage {
+	return &jsMessage{j: m.j, part: m.part.Copy()}
+}
+
+//------------------------------------------------------------------------------
+
+// resultParts converts the value returned by the process function into message
+// parts, where original is used as the basis for values that are not messages.
+func (j *JavaScript) resultParts(original *jsMessage, v goja.Value) ([]types.Part, error) {
+	if v == nil || goja.IsUndefined(v) {
+		return []types.Part{original.part}, nil
+	}
+	if goja.IsNull(v) {
+		return nil, nil
+	}
+
+	if obj, ok := v.(*goja.Object); ok && obj.ClassName() == "Array" {
+		var parts []types.Part
+		for _, k := range obj.Keys() {
+			ele := obj.Get(k)
+			if ele == nil || goja.IsUndefined(ele) || goja.IsNull(ele) {
+				continue
+			}
+			if eleObj, ok := ele.(*goja.Object); ok && eleObj.ClassName() == "Array" {
+				return nil, errors.New("nested arrays are not supported")
+			}
+			p, err := j.resultPart(original, ele)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, p)
+		}
+		return parts, nil
+	}
+
+	p, err := j.resultPart(original, v)
+	if err != nil {
+		return nil, err
+	}
+	return []types.Part{p}, nil
+}
+
+func (j *JavaScript) resultPart(original *jsMessage, v goja.Value) (types.Part, error) {
+	switch t := v.Export().(type) {
+	case *jsMessage:
+		return t.part, nil
+	case string:
+		p := original.part.Copy()
+		p.Set([]byte(t))
+		return p, nil
+	default:
+		p := original.part.Copy()
+		jBytes, err := json.Marshal(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialise result: %v", err)
+		}
+		p.Set(jBytes)
+		return p, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (j *JavaScript) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	j.mCount.Incr(1)
+
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	newParts := make([]types.Part, 0, msg.Len())
+
+	msg.Iter(func(i int, part types.Part) error {
+		span := tracing.GetSpan(part)
+		if span == nil {
+			span = opentracing.StartSpan(TypeJavaScript)
+		} else {
+			span = opentracing.StartSpan(
+				TypeJavaScript,
+				opentracing.ChildOf(span.Context()),
+			)
+		}
+		defer span.Finish()
+
+		jMsg := &jsMessage{j: j, part: part.Copy()}
+
+		var parts []types.Part
+		err := j.exec(func() error {
+			res, err := j.process(goja.Undefined(), j.vm.ToValue(jMsg))
+			if err != nil {
+				return err
+			}
+			parts, err = j.resultParts(jMsg, res)
+			return err
+		})
+		if err != nil {
+			p := part.Copy()
+			j.mErr.Incr(1)
+			j.log.Errorf("Failed to execute program: %v\n", err)
+			FlagErr(p, err)
+			span.SetTag("error", true)
+			span.LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+			newParts = append(newParts, p)
+			return nil
+		}
+
+		if len(parts) == 0 {
+			j.mDropped.Incr(1)
+		}
+		newParts = append(newParts, parts...)
+		return nil
+	})
+
+	if len(newParts) == 0 {
+		return nil, response.NewAck()
+	}
+
+	newMsg := message.New(nil)
+	newMsg.SetAll(newParts)
+
+	j.mBatchSent.Incr(1)
+	j.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (j *JavaScript) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (j *JavaScript) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
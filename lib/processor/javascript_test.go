@@ -0,0 +1,369 @@
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJavaScriptTestProc(t *testing.T, conf Config) Type {
+	t.Helper()
+
+	conf.Type = TypeJavaScript
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	})
+	return proc
+}
+
+func TestJavaScriptMutate(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.Code = `
+var count = 0;
+function process(msg) {
+  count++;
+  const doc = msg.structured();
+  doc.count = count;
+  doc.source = msg.meta("source");
+  msg.setStructured(doc);
+  msg.setMeta("processed", "true");
+  msg.deleteMeta("source");
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	input := message.New([][]byte{
+		[]byte(`{"id":"a"}`),
+		[]byte(`{"id":"b"}`),
+	})
+	input.Get(0).Metadata().Set("source", "foo")
+	input.Get(1).Metadata().Set("source", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, `{"count":1,"id":"a","source":"foo"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, `{"count":2,"id":"b","source":"bar"}`, string(msgs[0].Get(1).Get()))
+	assert.Equal(t, "true", msgs[0].Get(0).Metadata().Get("processed"))
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get("source"))
+
+	assert.Equal(t, `{"id":"a"}`, string(input.Get(0).Get()))
+	assert.Equal(t, "foo", input.Get(0).Metadata().Get("source"))
+}
+
+func TestJavaScriptResults(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.Code = `
+function process(msg) {
+  switch (msg.content()) {
+  case "drop":
+    return null;
+  case "empty":
+    return [];
+  case "string":
+    return "from string";
+  case "object":
+    return { from: "object" };
+  case "multiple":
+    const other = msg.clone();
+    other.setContent("second");
+    msg.setContent("first");
+    return [msg, other, "third"];
+  }
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	tests := map[string][]string{
+		"drop":     nil,
+		"empty":    nil,
+		"string":   {"from string"},
+		"object":   {`{"from":"object"}`},
+		"multiple": {"first", "second", "third"},
+		"keep":     {"keep"},
+	}
+
+	for input, exp := range tests {
+		inMsg := message.New([][]byte{[]byte(input)})
+		inMsg.Get(0).Metadata().Set("foo", "bar")
+
+		msgs, res := proc.ProcessMessage(inMsg)
+		if exp == nil {
+			assert.Empty(t, msgs, input)
+			assert.Equal(t, response.NewAck(), res, input)
+			continue
+		}
+
+		require.Nil(t, res, input)
+		require.Len(t, msgs, 1, input)
+		var act []string
+		msgs[0].Iter(func(i int, p types.Part) error {
+			act = append(act, string(p.Get()))
+			assert.Equal(t, "bar", p.Metadata().Get("foo"), input)
+			return nil
+		})
+		assert.Equal(t, exp, act, input)
+	}
+}
+
+func TestJavaScriptErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.Code = `
+function process(msg) {
+  if (msg.content() === "throw") {
+    throw new Error("nope");
+  }
+  msg.structured();
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`throw`),
+		[]byte(`not json`),
+		[]byte(`{}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 3, msgs[0].Len())
+
+	assert.Contains(t, GetFail(msgs[0].Get(0)), "nope")
+	assert.Equal(t, "throw", string(msgs[0].Get(0).Get()))
+	assert.True(t, HasFailed(msgs[0].Get(1)))
+	assert.False(t, HasFailed(msgs[0].Get(2)))
+}
+
+func TestJavaScriptTimeout(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.Timeout = "50ms"
+	conf.JavaScript.Code = `
+function process(msg) {
+  if (msg.content() === "loop") {
+    while (true) {}
+  }
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`loop`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, errJavaScriptTimeout.Error(), GetFail(msgs[0].Get(0)))
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`fine`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+}
+
+func TestJavaScriptMemoryLimit(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.Timeout = "10s"
+	conf.JavaScript.MaxMemory = 10 * 1024 * 1024
+	conf.JavaScript.Code = `
+var hoard = [];
+function process(msg) {
+  while (true) {
+    hoard.push("x".repeat(1024) + hoard.length);
+  }
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`foo`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, errJavaScriptMemory.Error(), GetFail(msgs[0].Get(0)))
+}
+
+func TestJavaScriptMemoryLimitPerCall(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.MaxMemory = 1024 * 1024
+	conf.JavaScript.Code = `
+var double = function(s, n) {
+  for (var i = 0; i < n; i++) {
+    s += s;
+  }
+  return s;
+};
+function process(msg) {
+  var n = parseInt(msg.content(), 10), out = double("x", n);
+  msg.setContent(double.name + " " + out.length);
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	for i := 0; i < 10; i++ {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`16`)}))
+		require.Nil(t, res)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "double 65536", string(msgs[0].Get(0).Get()))
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`30`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, errJavaScriptMemory.Error(), GetFail(msgs[0].Get(0)))
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte(`4`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "double 16", string(msgs[0].Get(0).Get()))
+}
+
+func TestJavaScriptModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_javascript_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	modDir := filepath.Join(dir, "modules")
+	require.NoError(t, os.MkdirAll(modDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(modDir, "shout.js"), []byte(`
+module.exports.shout = function(s) { return s.toUpperCase() + "!"; };
+`), 0644))
+
+	outsideDir := filepath.Join(dir, "outside")
+	require.NoError(t, os.MkdirAll(outsideDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outsideDir, "secret.js"), []byte(`
+module.exports.secret = "hidden";
+`), 0644))
+
+	conf := NewConfig()
+	conf.JavaScript.ModulePaths = []string{modDir}
+	conf.JavaScript.Code = `
+var shout = require("shout").shout;
+function process(msg) {
+  msg.setContent(shout(msg.content()));
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`hello`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "HELLO!", string(msgs[0].Get(0).Get()))
+
+	conf.JavaScript.Code = `
+var secret = require("` + filepath.ToSlash(filepath.Join(outsideDir, "secret.js")) + `").secret;
+function process(msg) {}
+`
+	conf.Type = TypeJavaScript
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestJavaScriptFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_javascript_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "helpers.js"), []byte(`
+module.exports.prefix = "foo: ";
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.js"), []byte(`
+const helpers = require("./helpers.js");
+function process(msg) {
+  return helpers.prefix + msg.content();
+}
+`), 0644))
+
+	conf := NewConfig()
+	conf.JavaScript.File = filepath.Join(dir, "main.js")
+	proc := newJavaScriptTestProc(t, conf)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`bar`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "foo: bar", string(msgs[0].Get(0).Get()))
+}
+
+func TestJavaScriptConfigErrors(t *testing.T) {
+	tests := map[string]JavaScriptConfig{
+		"no code": NewJavaScriptConfig(),
+		"both code and file": func() JavaScriptConfig {
+			c := NewJavaScriptConfig()
+			c.Code = "function process(msg) {}"
+			c.File = "./foo.js"
+			return c
+		}(),
+		"no process function": func() JavaScriptConfig {
+			c := NewJavaScriptConfig()
+			c.Code = "var foo = 10;"
+			return c
+		}(),
+		"bad syntax": func() JavaScriptConfig {
+			c := NewJavaScriptConfig()
+			c.Code = "function process(msg) {"
+			return c
+		}(),
+		"bad timeout": func() JavaScriptConfig {
+			c := NewJavaScriptConfig()
+			c.Code = "function process(msg) {}"
+			c.Timeout = "nope"
+			return c
+		}(),
+		"negative max memory": func() JavaScriptConfig {
+			c := NewJavaScriptConfig()
+			c.Code = "function process(msg) {}"
+			c.MaxMemory = -1
+			return c
+		}(),
+	}
+
+	for name, jConf := range tests {
+		conf := NewConfig()
+		conf.Type = TypeJavaScript
+		conf.JavaScript = jConf
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestJavaScriptSplitExample(t *testing.T) {
+	conf := NewConfig()
+	conf.JavaScript.Code = `
+function process(msg) {
+  var doc = msg.structured();
+  var items = doc.items.filter(function(item) {
+    return item.stock > 0;
+  });
+  return items.map(function(item) {
+    var out = msg.clone();
+    out.setStructured({ order: doc.id, item: item });
+    out.setMeta("item_count", String(items.length));
+    return out;
+  });
+}
+`
+	proc := newJavaScriptTestProc(t, conf)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"foo","items":[{"name":"a","stock":1},{"name":"b","stock":0},{"name":"c","stock":5}]}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, `{"item":{"name":"a","stock":1},"order":"foo"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, `{"item":{"name":"c","stock":5},"order":"foo"}`, string(msgs[0].Get(1).Get()))
+	assert.Equal(t, "2", msgs[0].Get(1).Metadata().Get("item_count"))
+}
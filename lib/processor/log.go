@@ -11,6 +11,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -91,7 +92,7 @@ pipeline:
 root.id = this.id
 root.age = this.user.age.number()
 root.kafka_topic = meta("kafka_topic")`,
-			).AtVersion("3.40.0"),
+			).IsBloblang().AtVersion("3.40.0"),
 			docs.FieldCommon("message", "The message to print.").SupportsInterpolation(true),
 		},
 	}
@@ -162,7 +163,7 @@ func NewLog(
 		if l.loggerWith, ok = logger.(logWith); !ok {
 			return nil, errors.New("the provided logger does not support structured fields required for `fields_mapping`")
 		}
-		if l.fieldsMapping, err = bloblang.NewMapping("", conf.Log.FieldsMapping); err != nil {
+		if l.fieldsMapping, err = interop.NewBloblangMapping(mgr, conf.Log.FieldsMapping); err != nil {
 			return nil, fmt.Errorf("failed to parse fields mapping: %w", err)
 		}
 	}
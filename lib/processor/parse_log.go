@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/logparse"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -27,7 +28,7 @@ Parses common log [formats](#formats) into [structured data](#codecs). This is
 easier and often much faster than ` + "[`grok`](/docs/components/processors/grok)" + `.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("format", "A common log [format](#formats) to parse.").HasOptions(
-				"syslog_rfc5424", "syslog_rfc3164",
+				"syslog_rfc5424", "syslog_rfc3164", "cef", "leef", "logfmt", "access_log", "windows_kv", "java_stack_trace",
 			),
 			docs.FieldCommon("codec", "Specifies the structured format to parse a log into.").HasOptions(
 				"json",
@@ -38,8 +39,9 @@ easier and often much faster than ` + "[`grok`](/docs/components/processors/grok
 			docs.FieldAdvanced("default_year", "Sets the strategy used to set the year for rfc3164 timestamps."+
 				" Applicable to format `syslog_rfc3164`. When set to `current` the current year will be set, when"+
 				" set to an integer that value will be used. Leave this field empty to not set a default year at all."),
-			docs.FieldAdvanced("default_timezone", "Sets the strategy to decide the timezone for rfc3164 timestamps."+
-				" Applicable to format `syslog_rfc3164`. This value should follow the [time.LoadLocation](https://golang.org/pkg/time/#LoadLocation) format."),
+			docs.FieldAdvanced("default_timezone", "Sets the strategy to decide the timezone for rfc3164 timestamps, and the timezone of"+
+				" [extracted timestamps](#timestamps) that do not specify one."+
+				" Applicable to all formats other than `syslog_rfc5424`. This value should follow the [time.LoadLocation](https://golang.org/pkg/time/#LoadLocation) format."),
 
 			partsFieldSpec,
 		},
@@ -81,6 +83,118 @@ spec. The resulting structured document may contain any of the following fields:
 - ` + "`procid`" + ` (string)
 - ` + "`appname`" + ` (string)
 - ` + "`msgid`" + ` (string)
+
+### ` + "`cef`" + `
+
+Attempts to parse an event following the ArcSight Common Event Format, which
+may be prefixed with a syslog header. The resulting structured document may
+contain any of the following fields:
+
+- ` + "`version`" + ` (int)
+- ` + "`device_vendor`" + ` (string)
+- ` + "`device_product`" + ` (string)
+- ` + "`device_version`" + ` (string)
+- ` + "`signature_id`" + ` (string)
+- ` + "`name`" + ` (string)
+- ` + "`severity`" + ` (int, or string for severities such as ` + "`High`" + `)
+- ` + "`extensions`" + ` (object)
+- ` + "`timestamp`" + ` (string, RFC3339)
+
+The ` + "`timestamp`" + ` is extracted from the extension ` + "`rt`" + `,
+` + "`end`" + ` or ` + "`start`" + `, or from the syslog header.
+
+### ` + "`leef`" + `
+
+Attempts to parse an event following the IBM QRadar Log Event Extended Format
+of version 1.0 or 2.0, which may be prefixed with a syslog header. The resulting
+structured document may contain any of the following fields:
+
+- ` + "`version`" + ` (string)
+- ` + "`vendor`" + ` (string)
+- ` + "`product`" + ` (string)
+- ` + "`product_version`" + ` (string)
+- ` + "`event_id`" + ` (string)
+- ` + "`attributes`" + ` (object)
+- ` + "`timestamp`" + ` (string, RFC3339)
+
+The ` + "`timestamp`" + ` is extracted from the attribute ` + "`devTime`" + ` or
+from the syslog header.
+
+### ` + "`logfmt`" + `
+
+Attempts to parse a line of [logfmt](https://brandur.org/logfmt) key/value
+pairs. The resulting structured document may contain the following fields:
+
+- ` + "`fields`" + ` (object of string values, where keys without a value are set to ` + "`true`" + `)
+- ` + "`timestamp`" + ` (string, RFC3339)
+
+The ` + "`timestamp`" + ` is extracted from the first of the keys ` + "`ts`" + `,
+` + "`time`" + `, ` + "`timestamp`" + ` or ` + "`@timestamp`" + ` that contains
+a recognisable timestamp.
+
+### ` + "`access_log`" + `
+
+Attempts to parse a line of an Apache or Nginx access log in either the common
+or combined log format. The resulting structured document may contain any of
+the following fields, where fields logged as a hyphen are omitted:
+
+- ` + "`remote_host`" + ` (string)
+- ` + "`ident`" + ` (string)
+- ` + "`user`" + ` (string)
+- ` + "`timestamp`" + ` (string, RFC3339)
+- ` + "`time_local`" + ` (string, only when the timestamp could not be parsed)
+- ` + "`request`" + ` (string)
+- ` + "`method`" + ` (string)
+- ` + "`path`" + ` (string)
+- ` + "`protocol`" + ` (string)
+- ` + "`status`" + ` (int)
+- ` + "`bytes`" + ` (int)
+- ` + "`referer`" + ` (string)
+- ` + "`user_agent`" + ` (string)
+
+### ` + "`windows_kv`" + `
+
+Attempts to parse key/value pairs in the style of exported Windows event logs.
+Each pair is expected on its own line, where lines that do not begin with a key
+continue the value of the previous pair, allowing values such as
+` + "`Message`" + ` to span multiple lines. When a message is a single line the
+pairs are delimited by whitespace and values extend until the next key. The
+resulting structured document may contain the following fields:
+
+- ` + "`fields`" + ` (object of string values)
+- ` + "`timestamp`" + ` (string, RFC3339)
+
+The ` + "`timestamp`" + ` is extracted from lines preceding the first pair or
+from the first of the keys ` + "`TimeCreated`" + `, ` + "`TimeGenerated`" + `,
+` + "`EventTime`" + ` or ` + "`Time`" + ` that contains a recognisable
+timestamp.
+
+### ` + "`java_stack_trace`" + `
+
+Attempts to parse a multiline Java stack trace, which must be contained within
+a single message and may be preceded by log lines. The resulting structured
+document may contain any of the following fields:
+
+- ` + "`message`" + ` (string, the lines preceding the exception)
+- ` + "`timestamp`" + ` (string, RFC3339)
+- ` + "`thread`" + ` (string)
+- ` + "`exception`" + ` (object)
+
+Where ` + "`exception`" + ` contains the fields ` + "`class`" + `,
+` + "`message`" + `, ` + "`frames`" + `, ` + "`omitted_frames`" + ` and
+` + "`caused_by`" + `, which is an exception of the same structure. Each frame
+contains the fields ` + "`class`" + ` and ` + "`method`" + `, and when known
+` + "`file`" + `, ` + "`line`" + ` and ` + "`native`" + `.
+
+## Timestamps
+
+Timestamps of the formats ` + "`cef`" + `, ` + "`leef`" + `, ` + "`logfmt`" + `,
+` + "`access_log`" + `, ` + "`windows_kv`" + ` and ` + "`java_stack_trace`" + `
+are extracted on a best effort basis by trying a range of common formats,
+including ISO 8601, unix epochs in seconds or milliseconds and the formats of
+the respective log types. Timestamps without a timezone are assumed to be in
+the timezone ` + "`default_timezone`" + `, and when no timestamp is recognised
+the field is omitted.
 `,
 	}
 }
@@ -241,12 +355,37 @@ func parserRFC3164(bestEffort, wrfc3339 bool, year, tz string) (parserFormat, er
 	}, nil
 }
 
+func logparseFormat(parser logparse.Parser, tz string) (parserFormat, error) {
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			return nil, fmt.Errorf("failed to lookup timezone %s - %v", tz, err)
+		}
+	}
+	return func(body []byte) (map[string]interface{}, error) {
+		return parser(body, loc)
+	}, nil
+}
+
 func getParseFormat(parser string, bestEffort, rfc3339 bool, defYear, defTZ string) (parserFormat, error) {
 	switch parser {
 	case "syslog_rfc5424":
 		return parserRFC5424(bestEffort), nil
 	case "syslog_rfc3164":
 		return parserRFC3164(bestEffort, rfc3339, defYear, defTZ)
+	case "cef":
+		return logparseFormat(logparse.CEF, defTZ)
+	case "leef":
+		return logparseFormat(logparse.LEEF, defTZ)
+	case "logfmt":
+		return logparseFormat(logparse.Logfmt, defTZ)
+	case "access_log":
+		return logparseFormat(logparse.AccessLog, defTZ)
+	case "windows_kv":
+		return logparseFormat(logparse.WindowsKV, defTZ)
+	case "java_stack_trace":
+		return logparseFormat(logparse.JavaStackTrace, defTZ)
 	}
 	return nil, fmt.Errorf("format not recognised: %s", parser)
 }
@@ -17,6 +17,7 @@ func TestParseLogCases(t *testing.T) {
 		output  string
 		format  string
 		codec   string
+		tz      string
 		bestEff bool
 	}
 	tests := []testCase{
@@ -44,6 +45,63 @@ func TestParseLogCases(t *testing.T) {
 			input:   `<28>Dec  2 16:49:23 host app[23410]: Test`,
 			output:  fmt.Sprintf(`{"appname":"app","facility":3,"hostname":"host","message":"Test","priority":28,"procid":"23410","severity":4,"timestamp":"%v-12-02T16:49:23Z"}`, time.Now().Year()),
 		},
+		{
+			name:   "valid cef input, valid json output",
+			format: "cef",
+			codec:  "json",
+			input:  `CEF:0|Security|threatmanager|1.0|100|worm stopped|10|src=10.0.0.1 msg=hello world rt=1617280245000`,
+			output: `{"device_product":"threatmanager","device_vendor":"Security","device_version":"1.0","extensions":{"msg":"hello world","rt":"1617280245000","src":"10.0.0.1"},"name":"worm stopped","severity":10,"signature_id":"100","timestamp":"2021-04-01T12:30:45Z","version":0}`,
+		},
+		{
+			name:   "invalid cef input, invalid json output",
+			format: "cef",
+			codec:  "json",
+			input:  `not a cef event`,
+			output: `not a cef event`,
+		},
+		{
+			name:   "valid leef input, valid json output",
+			format: "leef",
+			codec:  "json",
+			input:  "LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdevTime=1617280245",
+			output: `{"attributes":{"devTime":"1617280245","src":"192.0.2.0"},"event_id":"15345","product":"MSExchange","product_version":"4.0 SP1","timestamp":"2021-04-01T12:30:45Z","vendor":"Microsoft","version":"1.0"}`,
+		},
+		{
+			name:   "valid logfmt input, valid json output",
+			format: "logfmt",
+			codec:  "json",
+			input:  `level=warn msg="disk almost full" ts=2021-04-01T12:30:45Z`,
+			output: `{"fields":{"level":"warn","msg":"disk almost full","ts":"2021-04-01T12:30:45Z"},"timestamp":"2021-04-01T12:30:45Z"}`,
+		},
+		{
+			name:   "valid logfmt input with timezone, valid json output",
+			format: "logfmt",
+			codec:  "json",
+			tz:     "America/New_York",
+			input:  `level=warn timestamp="2021-04-01 12:30:45"`,
+			output: `{"fields":{"level":"warn","timestamp":"2021-04-01 12:30:45"},"timestamp":"2021-04-01T16:30:45Z"}`,
+		},
+		{
+			name:   "valid access_log input, valid json output",
+			format: "access_log",
+			codec:  "json",
+			input:  `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+			output: `{"bytes":2326,"method":"GET","path":"/apache_pb.gif","protocol":"HTTP/1.0","referer":"http://www.example.com/start.html","remote_host":"127.0.0.1","request":"GET /apache_pb.gif HTTP/1.0","status":200,"timestamp":"2000-10-10T20:55:36Z","user":"frank","user_agent":"Mozilla/4.08"}`,
+		},
+		{
+			name:   "valid windows_kv input, valid json output",
+			format: "windows_kv",
+			codec:  "json",
+			input:  "04/01/2021 12:30:45 PM\nLogName=Security\nEventCode=4624\nMessage=An account was successfully logged on.\n\tAccount Name:\tbob",
+			output: `{"fields":{"EventCode":"4624","LogName":"Security","Message":"An account was successfully logged on.\n\tAccount Name:\tbob"},"timestamp":"2021-04-01T12:30:45Z"}`,
+		},
+		{
+			name:   "valid java_stack_trace input, valid json output",
+			format: "java_stack_trace",
+			codec:  "json",
+			input:  "2021-04-01 12:30:45,123 ERROR request failed\njava.lang.IllegalStateException: nope\n\tat com.example.App.run(App.java:42)",
+			output: `{"exception":{"class":"java.lang.IllegalStateException","frames":[{"class":"com.example.App","file":"App.java","line":42,"method":"run"}],"message":"nope"},"message":"2021-04-01 12:30:45,123 ERROR request failed","timestamp":"2021-04-01T12:30:45.123Z"}`,
+		},
 	}
 
 	for _, test := range tests {
@@ -51,6 +109,9 @@ func TestParseLogCases(t *testing.T) {
 		conf.ParseLog.Format = test.format
 		conf.ParseLog.Codec = test.codec
 		conf.ParseLog.BestEffort = test.bestEff
+		if test.tz != "" {
+			conf.ParseLog.WithTimezone = test.tz
+		}
 		proc, err := NewParseLog(conf, nil, log.Noop(), metrics.Noop())
 		if err != nil {
 			t.Fatal(err)
@@ -1,6 +1,8 @@
 package processor
 
 import (
+	"time"
+
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -19,4 +21,17 @@ type Type interface {
 	types.Closable
 }
 
+// Flusher is an optional interface implemented by processors that hold
+// messages back, and need to emit them periodically even when no new messages
+// arrive.
+type Flusher interface {
+	// FlushPeriod returns the interval at which Flush should be called, where
+	// a period of zero disables periodic flushes.
+	FlushPeriod() time.Duration
+
+	// Flush returns any messages held by the processor that are ready to be
+	// sent.
+	Flush() []types.Message
+}
+
 //------------------------------------------------------------------------------
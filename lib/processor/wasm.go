@@ -0,0 +1,592 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+	olog "github.com/opentracing/opentracing-go/log"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWASM] = TypeSpec{
+		constructor: NewWASM,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Status:  docs.StatusExperimental,
+		Version: "3.42.0",
+		Summary: `
+Executes a function of a [WebAssembly](https://webassembly.org/) module on each
+message of a batch, allowing custom transformations to be written in any
+language that compiles to WebAssembly and distributed without building Benthos.`,
+		Description: `
+Modules are executed with a pure Go interpreter in a sandbox, where the only
+interaction with the host is through the functions described in
+[the host ABI](#host-abi). Modules must target the WebAssembly MVP feature set.
+
+The exported function named by ` + "`function`" + ` is called once for each
+message, and must take no arguments and return an ` + "`i32`" + ` status, where
+` + "`0`" + ` keeps the message, including any changes made to it, and
+` + "`1`" + ` drops it. Any other status is treated as an error.
+
+Instances of a module are pooled and shared by all processors of the same
+module across pipeline threads, where each instance only processes one message
+at a time. Linear memory and globals of an instance persist between calls.
+
+If the module traps, for example by executing ` + "`unreachable`" + `, accessing
+memory out of bounds or passing invalid pointers to a host function, or a call
+runs for longer than ` + "`timeout`" + `, the message is flagged as failed with
+an error describing the trap and the instance is discarded. Failed messages can be handled with
+[error handling patterns](/docs/configuration/error_handling).`,
+		Footnotes: `
+## Host ABI
+
+Host functions are imported from the module ` + "`benthos`" + `. All parameters
+and results are of type ` + "`i32`" + `, where pointers are offsets into the
+linear memory of the module, and the module is responsible for allocating
+buffers of the correct size before calling functions that write to them.
+
+| Function | Signature | Description |
+|---|---|---|
+| ` + "`content_len`" + ` | ` + "`() -> len`" + ` | Returns the size of the message contents in bytes. |
+| ` + "`content_read`" + ` | ` + "`(ptr)`" + ` | Writes the message contents to memory at ` + "`ptr`" + `. |
+| ` + "`content_set`" + ` | ` + "`(ptr, len)`" + ` | Sets the message contents to ` + "`len`" + ` bytes of memory at ` + "`ptr`" + `. |
+| ` + "`meta_len`" + ` | ` + "`(key_ptr, key_len) -> len`" + ` | Returns the size of a metadata value in bytes, or zero if it does not exist. |
+| ` + "`meta_read`" + ` | ` + "`(key_ptr, key_len, ptr)`" + ` | Writes a metadata value to memory at ` + "`ptr`" + `. |
+| ` + "`meta_set`" + ` | ` + "`(key_ptr, key_len, value_ptr, value_len)`" + ` | Sets a metadata value. |
+| ` + "`meta_delete`" + ` | ` + "`(key_ptr, key_len)`" + ` | Removes a metadata value. |
+| ` + "`set_error`" + ` | ` + "`(ptr, len)`" + ` | Flags the message as failed with an error message. |
+
+Modules do not need to import host functions that they do not use.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The path of a WebAssembly module file to load.", "./plugins/transform.wasm"),
+			docs.FieldAdvanced("function", "The name of the exported function to call for each message."),
+			docs.FieldAdvanced("timeout", "The maximum period of time that a call to `function` may execute for before it is interrupted. Set to an empty string in order to disable the limit."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WASMConfig contains configuration fields for the WASM processor.
+type WASMConfig struct {
+	Path     string `json:"path" yaml:"path"`
+	Function string `json:"function" yaml:"function"`
+	Timeout  string `json:"timeout" yaml:"timeout"`
+}
+
+// NewWASMConfig returns a WASMConfig with default values.
+func NewWASMConfig() WASMConfig {
+	return WASMConfig{
+		Path:     "",
+		Function: "process",
+		Timeout:  "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WASM is a processor that executes a function of a WebAssembly module on each
+// message of a batch.
+type WASM struct {
+	module    *wasmModule
+	timeout   time.Duration
+	closeOnce sync.Once
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mTrap      metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewWASM returns a WASM processor.
+func NewWASM(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.WASM.Path == "" {
+		return nil, errors.New("a module path must be specified")
+	}
+	if conf.WASM.Function == "" {
+		return nil, errors.New("a function name must be specified")
+	}
+
+	var timeout time.Duration
+	if conf.WASM.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(conf.WASM.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+
+	code, err := ioutil.ReadFile(conf.WASM.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %v", err)
+	}
+
+	module, err := acquireWASMModule(conf.WASM.Path, conf.WASM.Function, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WASM{
+		module:  module,
+		timeout: timeout,
+		log:     log,
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mTrap:      stats.GetCounter("trap"),
+		mDropped:   stats.GetCounter("dropped"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// WASMTrapError is flagged on messages when a module traps during execution.
+type WASMTrapError struct {
+	Function string
+	Err      error
+}
+
+// Error returns a human readable description of the trap.
+func (e *WASMTrapError) Error() string {
+	// Errors from the runtime are followed by a stack trace, which is too noisy
+	// to flag on messages.
+	errStr := e.Err.Error()
+	if i := strings.IndexByte(errStr, '\n'); i >= 0 {
+		errStr = errStr[:i]
+	}
+	return fmt.Sprintf("wasm module trapped while executing '%v': %v", e.Function, errStr)
+}
+
+// Unwrap returns the underlying cause of the trap.
+func (e *WASMTrapError) Unwrap() error {
+	return e.Err
+}
+
+var wasmModules = struct {
+	sync.Mutex
+	m map[string]*wasmModule
+}{m: map[string]*wasmModule{}}
+
+// wasmModule is a pool of instances of a module that is shared by all
+// processors executing the same function of the same module contents.
+type wasmModule struct {
+	key      string
+	function string
+	refs     int
+
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+
+	mut    sync.Mutex
+	idle   []*wasmInstance
+	closed bool
+}
+
+func acquireWASMModule(path, function string, code []byte) (*wasmModule, error) {
+	sum := sha256.Sum256(code)
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+	key := absPath + ":" + function + ":" + hex.EncodeToString(sum[:])
+
+	wasmModules.Lock()
+	defer wasmModules.Unlock()
+
+	if m, exists := wasmModules.m[key]; exists {
+		m.refs++
+		return m, nil
+	}
+
+	m, err := newWASMModule(function, code)
+	if err != nil {
+		return nil, err
+	}
+	m.key = key
+	m.refs = 1
+
+	// Create an instance up front in order to surface module errors during
+	// construction.
+	inst, err := m.newInstance()
+	if err != nil {
+		m.runtime.Close(context.Background())
+		return nil, err
+	}
+	m.idle = append(m.idle, inst)
+
+	wasmModules.m[key] = m
+	return m, nil
+}
+
+func newWASMModule(function string, code []byte) (*wasmModule, error) {
+	ctx := context.Background()
+
+	// Calls are interrupted by cancelling their context, which closes the
+	// instance being executed.
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter().
+		WithCoreFeatures(api.CoreFeaturesV1).
+		WithCloseOnContextDone(true))
+
+	m := &wasmModule{
+		function: function,
+		runtime:  runtime,
+	}
+
+	err := m.validate(ctx, code)
+	if err == nil {
+		err = m.registerHostModule(ctx)
+	}
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *wasmModule) validate(ctx context.Context, code []byte) error {
+	compiled, err := m.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to read module: %v", err)
+	}
+	m.compiled = compiled
+
+	for _, imp := range compiled.ImportedFunctions() {
+		modName, name, _ := imp.Import()
+		if modName != "benthos" {
+			return fmt.Errorf("module imports unknown module '%v'", modName)
+		}
+		if _, exists := wasmHostFunctions[name]; !exists {
+			return fmt.Errorf("failed to read module: unknown host function '%v'", name)
+		}
+	}
+
+	fn, exists := compiled.ExportedFunctions()[m.function]
+	if !exists {
+		return fmt.Errorf("module does not export function '%v'", m.function)
+	}
+	if len(fn.ParamTypes()) != 0 ||
+		len(fn.ResultTypes()) != 1 || fn.ResultTypes()[0] != api.ValueTypeI32 {
+		return fmt.Errorf("function '%v' must take no parameters and return an i32", m.function)
+	}
+	return nil
+}
+
+func (m *wasmModule) registerHostModule(ctx context.Context) error {
+	builder := m.runtime.NewHostModuleBuilder("benthos")
+	for name, fn := range wasmHostFunctions {
+		builder = builder.NewFunctionBuilder().WithFunc(fn).Export(name)
+	}
+	if _, err := builder.Instantiate(ctx); err != nil {
+		return fmt.Errorf("failed to instantiate host module: %v", err)
+	}
+	return nil
+}
+
+func (m *wasmModule) release() {
+	wasmModules.Lock()
+	defer wasmModules.Unlock()
+
+	if m.refs--; m.refs > 0 {
+		return
+	}
+	delete(wasmModules.m, m.key)
+
+	m.mut.Lock()
+	m.idle = nil
+	m.closed = true
+	m.mut.Unlock()
+
+	// Closing the runtime also closes all instances, including those still in
+	// use.
+	m.runtime.Close(context.Background())
+}
+
+func (m *wasmModule) get() (*wasmInstance, error) {
+	m.mut.Lock()
+	if l := len(m.idle); l > 0 {
+		inst := m.idle[l-1]
+		m.idle = m.idle[:l-1]
+		m.mut.Unlock()
+		return inst, nil
+	}
+	m.mut.Unlock()
+	return m.newInstance()
+}
+
+func (m *wasmModule) put(inst *wasmInstance) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.closed {
+		inst.close()
+		return
+	}
+	m.idle = append(m.idle, inst)
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	errWASMOutOfBounds = errors.New("host function argument out of memory bounds")
+	errWASMNoMessage   = errors.New("host function called outside of a message")
+	errWASMTimeout     = errors.New("execution timed out")
+)
+
+// wasmInstance is an instantiated module along with the state of the message
+// currently being processed, which is accessed by host functions.
+type wasmInstance struct {
+	mod api.Module
+	fn  api.Function
+
+	part    types.Part
+	errStr  string
+	hostErr error
+}
+
+type wasmInstanceKey struct{}
+
+func (m *wasmModule) newInstance() (*wasmInstance, error) {
+	inst := &wasmInstance{}
+
+	// Start functions are able to call host functions, and therefore the
+	// instance is made available to them.
+	ctx := context.WithValue(context.Background(), wasmInstanceKey{}, inst)
+
+	var err error
+	if inst.mod, err = m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithName("")); err != nil {
+		return nil, fmt.Errorf("failed to instantiate module: %v", err)
+	}
+	inst.fn = inst.mod.ExportedFunction(m.function)
+	return inst, nil
+}
+
+func (w *wasmInstance) close() {
+	w.mod.Close(context.Background())
+}
+
+// wasmHostFunctions are the functions of the host ABI, which operate on the
+// instance stored within the context of a call.
+var wasmHostFunctions = map[string]interface{}{
+	"content_len": func(ctx context.Context, mod api.Module) uint32 {
+		w := wasmState(ctx)
+		return uint32(len(w.part.Get()))
+	},
+	"content_read": func(ctx context.Context, mod api.Module, ptr uint32) {
+		w := wasmState(ctx)
+		w.write(mod, ptr, w.part.Get())
+	},
+	"content_set": func(ctx context.Context, mod api.Module, ptr, length uint32) {
+		w := wasmState(ctx)
+		w.part.Set(w.read(mod, ptr, length))
+	},
+	"meta_len": func(ctx context.Context, mod api.Module, keyPtr, keyLen uint32) uint32 {
+		w := wasmState(ctx)
+		key := w.read(mod, keyPtr, keyLen)
+		return uint32(len(w.part.Metadata().Get(string(key))))
+	},
+	"meta_read": func(ctx context.Context, mod api.Module, keyPtr, keyLen, ptr uint32) {
+		w := wasmState(ctx)
+		key := w.read(mod, keyPtr, keyLen)
+		w.write(mod, ptr, []byte(w.part.Metadata().Get(string(key))))
+	},
+	"meta_set": func(ctx context.Context, mod api.Module, keyPtr, keyLen, valuePtr, valueLen uint32) {
+		w := wasmState(ctx)
+		key := w.read(mod, keyPtr, keyLen)
+		value := w.read(mod, valuePtr, valueLen)
+		w.part.Metadata().Set(string(key), string(value))
+	},
+	"meta_delete": func(ctx context.Context, mod api.Module, keyPtr, keyLen uint32) {
+		w := wasmState(ctx)
+		key := w.read(mod, keyPtr, keyLen)
+		w.part.Metadata().Delete(string(key))
+	},
+	"set_error": func(ctx context.Context, mod api.Module, ptr, length uint32) {
+		w := wasmState(ctx)
+		w.errStr = string(w.read(mod, ptr, length))
+	},
+}
+
+// wasmState returns the instance of a call. Host functions panic in order to
+// trap the module, which is recovered by the runtime.
+func wasmState(ctx context.Context) *wasmInstance {
+	w := ctx.Value(wasmInstanceKey{}).(*wasmInstance)
+	if w.part == nil {
+		w.hostErr = errWASMNoMessage
+		panic(errWASMNoMessage)
+	}
+	return w
+}
+
+func (w *wasmInstance) read(mod api.Module, ptr, length uint32) []byte {
+	b, ok := mod.Memory().Read(ptr, length)
+	if !ok {
+		w.hostErr = errWASMOutOfBounds
+		panic(errWASMOutOfBounds)
+	}
+	// The slice is a view of linear memory, which is modified by subsequent
+	// execution.
+	return append([]byte(nil), b...)
+}
+
+func (w *wasmInstance) write(mod api.Module, ptr uint32, b []byte) {
+	if !mod.Memory().Write(ptr, b) {
+		w.hostErr = errWASMOutOfBounds
+		panic(errWASMOutOfBounds)
+	}
+}
+
+// call executes the function of the module on a part, returning the resulting
+// part or nil if it should be dropped.
+func (w *wasmInstance) call(function string, timeout time.Duration, part types.Part) (types.Part, error) {
+	w.part, w.errStr, w.hostErr = part, "", nil
+	defer func() {
+		w.part = nil
+	}()
+
+	ctx := context.WithValue(context.Background(), wasmInstanceKey{}, w)
+	if timeout > 0 {
+		var done func()
+		ctx, done = context.WithTimeout(ctx, timeout)
+		defer done()
+	}
+
+	res, err := w.fn.Call(ctx)
+	if err != nil {
+		if w.hostErr != nil {
+			err = w.hostErr
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			err = errWASMTimeout
+		}
+		return nil, &WASMTrapError{Function: function, Err: err}
+	}
+
+	status := api.DecodeU32(res[0])
+	switch status {
+	case 0:
+		if w.errStr != "" {
+			FlagErr(part, errors.New(w.errStr))
+		}
+		return part, nil
+	case 1:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("function '%v' returned unrecognised status: %v", function, status)
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *WASM) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	var inst *wasmInstance
+	newParts := make([]types.Part, 0, msg.Len())
+
+	msg.Iter(func(i int, part types.Part) error {
+		span := tracing.GetSpan(part)
+		if span == nil {
+			span = opentracing.StartSpan(TypeWASM)
+		} else {
+			span = opentracing.StartSpan(
+				TypeWASM,
+				opentracing.ChildOf(span.Context()),
+			)
+		}
+		defer span.Finish()
+
+		if inst == nil {
+			var err error
+			if inst, err = w.module.get(); err != nil {
+				p := part.Copy()
+				w.mErr.Incr(1)
+				w.log.Errorf("Failed to instantiate module: %v\n", err)
+				FlagErr(p, err)
+				newParts = append(newParts, p)
+				return nil
+			}
+		}
+
+		p, err := inst.call(w.module.function, w.timeout, part.Copy())
+		if err != nil {
+			var trapErr *WASMTrapError
+			if errors.As(err, &trapErr) {
+				// The state of the instance can no longer be trusted.
+				w.mTrap.Incr(1)
+				inst.close()
+				inst = nil
+			}
+			p = part.Copy()
+			w.mErr.Incr(1)
+			w.log.Errorf("Failed to execute module: %v\n", err)
+			FlagErr(p, err)
+			span.SetTag("error", true)
+			span.LogFields(
+				olog.String("event", "error"),
+				olog.String("type", err.Error()),
+			)
+		}
+
+		if p == nil {
+			w.mDropped.Incr(1)
+			return nil
+		}
+		newParts = append(newParts, p)
+		return nil
+	})
+
+	if inst != nil {
+		w.module.put(inst)
+	}
+
+	if len(newParts) == 0 {
+		return nil, response.NewAck()
+	}
+
+	newMsg := message.New(nil)
+	newMsg.SetAll(newParts)
+
+	w.mBatchSent.Incr(1)
+	w.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (w *WASM) CloseAsync() {
+	w.closeOnce.Do(w.module.release)
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (w *WASM) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
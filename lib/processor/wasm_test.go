@@ -0,0 +1,618 @@
+package processor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//------------------------------------------------------------------------------
+
+type wasmTestImport struct {
+	module  string
+	name    string
+	params  int
+	results int
+}
+
+type wasmTestModule struct {
+	imports []wasmTestImport
+	export  string
+	params  int
+	results int
+	locals  int
+	body    []byte
+	data    map[int32]string
+}
+
+func wasmULEB(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		if v >>= 7; v != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+func wasmSLEB(v int32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func wasmVec(items ...[]byte) []byte {
+	b := wasmULEB(uint32(len(items)))
+	for _, i := range items {
+		b = append(b, i...)
+	}
+	return b
+}
+
+func wasmName(s string) []byte {
+	return append(wasmULEB(uint32(len(s))), s...)
+}
+
+func wasmSection(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, wasmULEB(uint32(len(payload)))...), payload...)
+}
+
+func wasmFuncType(params, results int) []byte {
+	b := []byte{0x60}
+	b = append(b, wasmULEB(uint32(params))...)
+	for i := 0; i < params; i++ {
+		b = append(b, 0x7f)
+	}
+	b = append(b, wasmULEB(uint32(results))...)
+	for i := 0; i < results; i++ {
+		b = append(b, 0x7f)
+	}
+	return b
+}
+
+// encode assembles a module with a single exported function and one page of
+// memory.
+func (m wasmTestModule) encode() []byte {
+	var types, imports [][]byte
+	for i, imp := range m.imports {
+		types = append(types, wasmFuncType(imp.params, imp.results))
+		modName := imp.module
+		if modName == "" {
+			modName = "benthos"
+		}
+		entry := append(wasmName(modName), wasmName(imp.name)...)
+		entry = append(entry, 0x00)
+		entry = append(entry, wasmULEB(uint32(i))...)
+		imports = append(imports, entry)
+	}
+	types = append(types, wasmFuncType(m.params, m.results))
+
+	export := m.export
+	if export == "" {
+		export = "process"
+	}
+
+	var locals []byte
+	if m.locals > 0 {
+		locals = wasmVec(append(wasmULEB(uint32(m.locals)), 0x7f))
+	} else {
+		locals = wasmVec()
+	}
+	code := append(locals, m.body...)
+	code = append(code, 0x0b)
+
+	var data [][]byte
+	for offset, str := range m.data {
+		seg := []byte{0x00, 0x41}
+		seg = append(seg, wasmSLEB(offset)...)
+		seg = append(seg, 0x0b)
+		seg = append(seg, wasmName(str)...)
+		data = append(data, seg)
+	}
+
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	b = append(b, wasmSection(1, wasmVec(types...))...)
+	if len(imports) > 0 {
+		b = append(b, wasmSection(2, wasmVec(imports...))...)
+	}
+	b = append(b, wasmSection(3, wasmVec(wasmULEB(uint32(len(m.imports)))))...)
+	b = append(b, wasmSection(5, wasmVec([]byte{0x00, 0x01}))...)
+	b = append(b, wasmSection(7, wasmVec(
+		append(append(wasmName(export), 0x00), wasmULEB(uint32(len(m.imports)))...),
+		append(wasmName("memory"), 0x02, 0x00),
+	))...)
+	b = append(b, wasmSection(10, wasmVec(append(wasmULEB(uint32(len(code))), code...)))...)
+	if len(data) > 0 {
+		b = append(b, wasmSection(11, wasmVec(data...))...)
+	}
+	return b
+}
+
+func (m wasmTestModule) write(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "benthos_wasm_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "module.wasm")
+	require.NoError(t, ioutil.WriteFile(path, m.encode(), 0644))
+	return path
+}
+
+// Instructions used to assemble test modules.
+func wasmI32(v int32) []byte {
+	return append([]byte{0x41}, wasmSLEB(v)...)
+}
+
+func wasmCall(index int) []byte {
+	return append([]byte{0x10}, wasmULEB(uint32(index))...)
+}
+
+func wasmCode(instrs ...[]byte) []byte {
+	var b []byte
+	for _, i := range instrs {
+		b = append(b, i...)
+	}
+	return b
+}
+
+var (
+	wasmLocalGet0 = []byte{0x20, 0x00}
+	wasmLocalSet0 = []byte{0x21, 0x00}
+)
+
+//------------------------------------------------------------------------------
+
+func TestWASMSetContentAndMetadata(t *testing.T) {
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "meta_set", params: 4},
+			{name: "content_set", params: 2},
+			{name: "meta_delete", params: 2},
+		},
+		results: 1,
+		body: wasmCode(
+			wasmI32(16), wasmI32(3), wasmI32(32), wasmI32(3), wasmCall(0),
+			wasmI32(0), wasmI32(11), wasmCall(1),
+			wasmI32(48), wasmI32(6), wasmCall(2),
+			wasmI32(0),
+		),
+		data: map[int32]string{
+			0:  "hello world",
+			16: "foo",
+			32: "bar",
+			48: "remove",
+		},
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	input := message.New([][]byte{[]byte("first"), []byte("second")})
+	input.Get(0).Metadata().Set("remove", "me")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "hello world", string(msgs[0].Get(i).Get()))
+		assert.Equal(t, "bar", msgs[0].Get(i).Metadata().Get("foo"))
+		assert.Equal(t, "", msgs[0].Get(i).Metadata().Get("remove"))
+		assert.False(t, HasFailed(msgs[0].Get(i)))
+	}
+
+	assert.Equal(t, "first", string(input.Get(0).Get()))
+	assert.Equal(t, "me", input.Get(0).Metadata().Get("remove"))
+}
+
+func TestWASMReadContentAndMetadata(t *testing.T) {
+	// Copies the metadata value "in" to the contents, and the original
+	// contents to the metadata value "raw".
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "content_len", results: 1},
+			{name: "content_read", params: 1},
+			{name: "meta_set", params: 4},
+			{name: "meta_len", params: 2, results: 1},
+			{name: "meta_read", params: 3},
+			{name: "content_set", params: 2},
+		},
+		results: 1,
+		locals:  1,
+		body: wasmCode(
+			wasmCall(0), wasmLocalSet0,
+			wasmI32(128), wasmCall(1),
+			wasmI32(0), wasmI32(3), wasmI32(128), wasmLocalGet0, wasmCall(2),
+
+			wasmI32(16), wasmI32(2), wasmCall(3), wasmLocalSet0,
+			wasmI32(16), wasmI32(2), wasmI32(1024), wasmCall(4),
+			wasmI32(1024), wasmLocalGet0, wasmCall(5),
+			wasmI32(0),
+		),
+		data: map[int32]string{
+			0:  "raw",
+			16: "in",
+		},
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	input := message.New([][]byte{[]byte("original contents")})
+	input.Get(0).Metadata().Set("in", "from metadata")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "from metadata", string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "original contents", msgs[0].Get(0).Metadata().Get("raw"))
+}
+
+func TestWASMStatus(t *testing.T) {
+	// Drops messages that have the metadata key "drop", returns the status
+	// from the metadata key "status" otherwise.
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "meta_len", params: 2, results: 1},
+		},
+		results: 1,
+		body: wasmCode(
+			wasmI32(0), wasmI32(6), wasmCall(0),
+		),
+		data: map[int32]string{
+			0: "status",
+		},
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	input := message.New([][]byte{[]byte("keep"), []byte("drop"), []byte("bad")})
+	input.Get(1).Metadata().Set("status", "x")
+	input.Get(2).Metadata().Set("status", "xxxxx")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, "keep", string(msgs[0].Get(0).Get()))
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, "bad", string(msgs[0].Get(1).Get()))
+	assert.Equal(t, "function 'process' returned unrecognised status: 5", GetFail(msgs[0].Get(1)))
+
+	input = message.New([][]byte{[]byte("drop")})
+	input.Get(0).Metadata().Set("status", "x")
+
+	msgs, res = proc.ProcessMessage(input)
+	assert.Empty(t, msgs)
+	assert.Equal(t, response.NewAck(), res)
+}
+
+func TestWASMSetError(t *testing.T) {
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "set_error", params: 2},
+		},
+		results: 1,
+		body: wasmCode(
+			wasmI32(0), wasmI32(4), wasmCall(0),
+			wasmI32(0),
+		),
+		data: map[int32]string{
+			0: "nope",
+		},
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "nope", GetFail(msgs[0].Get(0)))
+}
+
+func TestWASMTraps(t *testing.T) {
+	// Traps when the contents are "trap", and passes an out of bounds pointer
+	// to content_set when the contents are "oob".
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "content_len", results: 1},
+			{name: "content_set", params: 2},
+		},
+		results: 1,
+		body: wasmCode(
+			wasmCall(0), wasmI32(4), []byte{0x46}, // i32.eq
+			[]byte{0x04, 0x40}, []byte{0x00}, []byte{0x0b}, // if unreachable end
+			wasmCall(0), wasmI32(3), []byte{0x46},
+			[]byte{0x04, 0x40}, wasmI32(70000), wasmI32(10), wasmCall(1), []byte{0x0b},
+			wasmI32(0),
+		),
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("trap"),
+		[]byte("fine!"),
+		[]byte("oob"),
+		[]byte("ok"),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 4, msgs[0].Len())
+
+	assert.Equal(t, "wasm module trapped while executing 'process': wasm error: unreachable", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, "trap", string(msgs[0].Get(0).Get()))
+	assert.False(t, HasFailed(msgs[0].Get(1)))
+	assert.Equal(t, "wasm module trapped while executing 'process': "+errWASMOutOfBounds.Error(), GetFail(msgs[0].Get(2)))
+	assert.False(t, HasFailed(msgs[0].Get(3)))
+}
+
+func TestWASMTrapError(t *testing.T) {
+	module, err := acquireWASMModule("./trap.wasm", "process", wasmTestModule{
+		results: 1,
+		body:    []byte{0x00},
+	}.encode())
+	require.NoError(t, err)
+	defer module.release()
+
+	inst, err := module.get()
+	require.NoError(t, err)
+
+	_, err = inst.call("process", 0, message.NewPart([]byte("foo")))
+	var trapErr *WASMTrapError
+	require.True(t, errors.As(err, &trapErr))
+	assert.Equal(t, "process", trapErr.Function)
+}
+
+func TestWASMTimeout(t *testing.T) {
+	// Loops forever when the contents are "loop".
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "content_len", results: 1},
+		},
+		results: 1,
+		body: wasmCode(
+			wasmCall(0), wasmI32(4), []byte{0x46}, // i32.eq
+			[]byte{0x04, 0x40},                   // if
+			[]byte{0x03, 0x40, 0x0c, 0x00, 0x0b}, // loop br 0 end
+			[]byte{0x0b},                         // end
+			wasmI32(0),
+		),
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+	conf.WASM.Timeout = "50ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("loop"),
+		[]byte("ok"),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+
+	assert.Equal(t, "wasm module trapped while executing 'process': "+errWASMTimeout.Error(), GetFail(msgs[0].Get(0)))
+	assert.False(t, HasFailed(msgs[0].Get(1)))
+}
+
+func TestWASMPutAfterRelease(t *testing.T) {
+	module, err := acquireWASMModule("./release.wasm", "process", wasmTestModule{
+		results: 1,
+		body:    wasmI32(0),
+	}.encode())
+	require.NoError(t, err)
+
+	inst, err := module.get()
+	require.NoError(t, err)
+
+	module.release()
+	module.put(inst)
+
+	assert.Empty(t, module.idle)
+	assert.True(t, inst.mod.IsClosed())
+}
+
+func TestWASMPooling(t *testing.T) {
+	path := wasmTestModule{
+		imports: []wasmTestImport{
+			{name: "content_len", results: 1},
+			{name: "content_read", params: 1},
+			{name: "meta_set", params: 4},
+		},
+		results: 1,
+		locals:  1,
+		body: wasmCode(
+			wasmCall(0), wasmLocalSet0,
+			wasmI32(128), wasmCall(1),
+			wasmI32(0), wasmI32(4), wasmI32(128), wasmLocalGet0, wasmCall(2),
+			wasmI32(0),
+		),
+		data: map[int32]string{
+			0: "copy",
+		},
+	}.write(t)
+
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.Path = path
+
+	var procs []Type
+	for i := 0; i < 3; i++ {
+		proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		defer func() {
+			proc.CloseAsync()
+			assert.NoError(t, proc.WaitForClose(time.Second))
+		}()
+		procs = append(procs, proc)
+	}
+	assert.Equal(t, procs[0].(*WASM).module, procs[1].(*WASM).module)
+	assert.Equal(t, procs[0].(*WASM).module, procs[2].(*WASM).module)
+
+	var wg sync.WaitGroup
+	for i, p := range procs {
+		wg.Add(1)
+		go func(i int, p Type) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				content := []byte(time.Duration(i*1000 + j).String())
+				msgs, res := p.ProcessMessage(message.New([][]byte{content}))
+				if !assert.Nil(t, res) || !assert.Len(t, msgs, 1) {
+					return
+				}
+				assert.Equal(t, string(content), msgs[0].Get(0).Metadata().Get("copy"))
+			}
+		}(i, p)
+	}
+	wg.Wait()
+}
+
+func TestWASMConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		path     string
+		function string
+		timeout  string
+		errStr   string
+	}{
+		"no path": {
+			errStr: "a module path must be specified",
+		},
+		"missing file": {
+			path:   "/does/not/exist.wasm",
+			errStr: "failed to read module",
+		},
+		"missing export": {
+			path:     wasmTestModule{results: 1, body: wasmI32(0)}.write(t),
+			function: "nope",
+			errStr:   "module does not export function 'nope'",
+		},
+		"bad signature": {
+			path:   wasmTestModule{params: 1, body: []byte{}}.write(t),
+			errStr: "function 'process' must take no parameters and return an i32",
+		},
+		"unknown import module": {
+			path: wasmTestModule{
+				imports: []wasmTestImport{{module: "env", name: "foo"}},
+				results: 1,
+				body:    wasmI32(0),
+			}.write(t),
+			errStr: "module imports unknown module 'env'",
+		},
+		"unknown import": {
+			path: wasmTestModule{
+				imports: []wasmTestImport{{name: "nope"}},
+				results: 1,
+				body:    wasmI32(0),
+			}.write(t),
+			errStr: "failed to read module",
+		},
+		"not a module": {
+			path: func() string {
+				p := wasmTestModule{results: 1, body: wasmI32(0)}.write(t)
+				require.NoError(t, ioutil.WriteFile(p, []byte("not wasm"), 0644))
+				return p
+			}(),
+			errStr: "failed to read module",
+		},
+		"bad timeout": {
+			path:    wasmTestModule{results: 1, body: wasmI32(0)}.write(t),
+			timeout: "nope",
+			errStr:  "failed to parse timeout",
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeWASM
+		conf.WASM.Path = test.path
+		if test.function != "" {
+			conf.WASM.Function = test.function
+		}
+		if test.timeout != "" {
+			conf.WASM.Timeout = test.timeout
+		}
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), test.errStr, name)
+	}
+}
@@ -0,0 +1,917 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/interop"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWindow] = TypeSpec{
+		constructor: NewWindow,
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Status:  docs.StatusBeta,
+		Version: "3.42.0",
+		Summary: `
+Aggregates messages into tumbling, sliding or session windows based on event
+time, emitting a message for each window once it closes.`,
+		Description: `
+Each message is assigned a timestamp by executing the
+` + "`timestamp_mapping`" + `, and a key by executing the mapping ` + "`key`" + `.
+The message is then added to each window of that key which
+covers its timestamp by executing the ` + "`reducer_mapping`" + `, where the
+message is the context of the mapping and the current aggregate of the window is
+available as the variable ` + "`$state`" + `. The result of the mapping becomes
+the new aggregate of the window. The variable ` + "`$state`" + ` is undefined
+for the first message of a window, and therefore mappings should provide a
+fallback for it.
+
+Messages added to windows are consumed by this processor, and a window is
+emitted as a new message once it closes, where the contents of the message are
+the final aggregate of the window and the following metadata fields are set:
+
+` + "``` text" + `
+- window_key
+- window_start
+- window_end
+- window_count
+` + "```" + `
+
+The fields ` + "`window_start`" + ` and ` + "`window_end`" + ` are formatted as RFC
+3339 timestamps. When multiple windows close at the same time they are emitted
+as a batch, ordered by their end time.
+
+### Window Types
+
+A ` + "`tumbling`" + ` window has a fixed ` + "`size`" + ` and windows do not
+overlap, and therefore each message is added to exactly one window.
+
+A ` + "`sliding`" + ` window also has a fixed ` + "`size`" + `, but a new window
+starts at each interval of ` + "`slide`" + `, and therefore a message can be
+added to multiple overlapping windows.
+
+A ` + "`session`" + ` window groups messages of a key that arrive with less
+than ` + "`gap`" + ` between them, a session window closes once a period of
+` + "`gap`" + ` has passed since the latest message of the window. When a message
+bridges the gap between two or more sessions of the same key they are merged
+into one by executing the ` + "`merge_mapping`" + `, where the aggregate of the
+later session is the context of the mapping and the aggregate of the earlier
+session is available as the variable ` + "`$state`" + `. A ` + "`merge_mapping`" + `
+is therefore required for session windows.
+
+### Event Time and Lateness
+
+Windows are closed according to a watermark, which is the latest timestamp seen
+by the processor. A window closes once the watermark passes the end of the
+window plus the ` + "`allowed_lateness`" + `, which gives messages that arrive
+out of order a chance to be added to windows that would otherwise have closed.
+Messages that only belong to windows that have already closed are dropped.
+
+When no messages have been processed for a period of ` + "`flush_period`" + ` the
+watermark is advanced by the time that has passed since the latest message was
+processed, which closes the windows of a stream that stops receiving messages.
+Periodic flushes are only performed when this processor is listed directly
+within the processors of an input, pipeline or output, and not when it is
+nested within another processor such as ` + "`branch`" + `.
+
+### State
+
+The state of all open windows can be persisted to a
+` + "[`cache` resource](/docs/components/caches/about)" + ` by setting the field
+` + "`cache`" + `. Each open window is stored under its own key, prefixed with
+` + "`cache_key`" + `, and only windows that have changed are written to the cache
+after each batch is processed. Window state is read back when the processor is
+created, which allows partial aggregates to survive a restart.
+
+Window state is not shared between processor instances, and therefore this
+processor should be placed where it is executed by a single thread, such as
+within the processors of an input or in a pipeline with ` + "`threads`" + ` set
+to ` + "`1`" + `.
+
+## Delivery Guarantees
+
+Messages are acknowledged as soon as they are added to a window, and therefore
+aggregating messages with this processor voids any at-least-once guarantees
+that the pipeline previously had. Persisting window state to a cache reduces
+the data lost during a restart but does not eliminate it.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Tumbling Counts",
+				Summary: `
+Given a stream of page views we can count the number of views per page in each
+minute, using the timestamp of each view as the event time, and allowing views
+to arrive up to ten seconds late:`,
+				Config: `
+pipeline:
+  threads: 1
+  processors:
+    - window:
+        type: tumbling
+        size: 1m
+        allowed_lateness: 10s
+        key: root = this.page
+        timestamp_mapping: root = this.viewed_at
+        reducer_mapping: |
+          root.page = this.page
+          root.views = ($state.views | 0) + 1
+`,
+			},
+			{
+				Title: "User Sessions",
+				Summary: `
+Session windows can be used in order to collect the actions of a user until
+they have been inactive for thirty minutes, persisting the sessions in progress
+to a Redis cache:`,
+				Config: `
+pipeline:
+  threads: 1
+  processors:
+    - window:
+        type: session
+        gap: 30m
+        key: root = this.user_id
+        timestamp_mapping: root = this.timestamp
+        reducer_mapping: |
+          root.user_id = this.user_id
+          root.actions = ($state.actions | []).append(this.action)
+        merge_mapping: |
+          root.user_id = this.user_id
+          root.actions = $state.actions.merge(this.actions)
+        cache: sessions
+
+resources:
+  caches:
+    sessions:
+      redis:
+        url: tcp://localhost:6379
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("type", "The type of window to aggregate messages into.").HasAnnotatedOptions(
+				"tumbling", "Fixed size windows that do not overlap.",
+				"sliding", "Fixed size windows that start at each interval of `slide`.",
+				"session", "Windows that close after a period of inactivity of `gap`.",
+			),
+			docs.FieldCommon("size", "The size of `tumbling` and `sliding` windows."),
+			docs.FieldCommon("slide", "The interval at which new `sliding` windows start, must be less than or equal to `size`."),
+			docs.FieldCommon("gap", "The period of inactivity after which a `session` window closes."),
+			docs.FieldCommon(
+				"key", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in a key to group windows by, allowing you to aggregate independent windows for each distinct value.",
+				`root = meta("kafka_key")`, `root = this.user_id`,
+			).IsBloblang(),
+			docs.FieldCommon(
+				"timestamp_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that extracts the event time of a message, which must result in either a unix timestamp or an RFC 3339 formatted string. Defaults to the time at which the message is processed.",
+				`root = this.timestamp`, `root = meta("kafka_timestamp_unix").number()`,
+			).IsBloblang(),
+			docs.FieldCommon("reducer_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that adds a message to the aggregate of a window, which is available within the mapping as the variable `$state`.").IsBloblang(),
+			docs.FieldCommon(
+				"merge_mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that combines the aggregates of two `session` windows, where the aggregate of the later window is the context of the mapping and the earlier is available as the variable `$state`. Required for `session` windows.",
+				`root.count = $state.count + this.count`,
+			).IsBloblang(),
+			docs.FieldCommon("allowed_lateness", "A period of time after the end of a window during which messages that arrive out of order are still added to it."),
+			docs.FieldAdvanced("flush_period", "The period of inactivity after which the watermark is advanced by processing time in order to close idle windows. Set to an empty string in order to only advance the watermark as messages arrive."),
+			docs.FieldCommon("cache", "An optional [`cache` resource](/docs/components/caches/about) to persist the state of open windows to."),
+			docs.FieldAdvanced("cache_key", "A prefix for the keys under which window state is stored within the `cache`, which must be unique for each window processor that shares a cache."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WindowConfig contains configuration fields for the Window processor.
+type WindowConfig struct {
+	Type             string `json:"type" yaml:"type"`
+	Size             string `json:"size" yaml:"size"`
+	Slide            string `json:"slide" yaml:"slide"`
+	Gap              string `json:"gap" yaml:"gap"`
+	Key              string `json:"key" yaml:"key"`
+	TimestampMapping string `json:"timestamp_mapping" yaml:"timestamp_mapping"`
+	ReducerMapping   string `json:"reducer_mapping" yaml:"reducer_mapping"`
+	MergeMapping     string `json:"merge_mapping" yaml:"merge_mapping"`
+	AllowedLateness  string `json:"allowed_lateness" yaml:"allowed_lateness"`
+	FlushPeriod      string `json:"flush_period" yaml:"flush_period"`
+	Cache            string `json:"cache" yaml:"cache"`
+	CacheKey         string `json:"cache_key" yaml:"cache_key"`
+}
+
+// NewWindowConfig returns a WindowConfig with default values.
+func NewWindowConfig() WindowConfig {
+	return WindowConfig{
+		Type:             "tumbling",
+		Size:             "1m",
+		Slide:            "",
+		Gap:              "",
+		Key:              "",
+		TimestampMapping: "root = now()",
+		ReducerMapping:   "root.count = ($state.count | 0) + 1",
+		MergeMapping:     "",
+		AllowedLateness:  "0s",
+		FlushPeriod:      "1s",
+		Cache:            "",
+		CacheKey:         "benthos_window_state",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// windowState is the aggregate of a single window.
+type windowState struct {
+	ID    uint64      `json:"id"`
+	Key   string      `json:"key"`
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+	Count int64       `json:"count"`
+	Value interface{} `json:"value"`
+
+	dirty bool
+}
+
+// windowIndex is persisted to a cache in order to track the identifiers of
+// open windows, each of which is stored under its own key.
+type windowIndex struct {
+	NextID  uint64   `json:"next_id"`
+	Windows []uint64 `json:"windows"`
+}
+
+// Window is a processor that aggregates messages into windows based on event
+// time.
+type Window struct {
+	conf  WindowConfig
+	mgr   types.Manager
+	log   log.Modular
+	stats metrics.Type
+
+	windowType  string
+	size        time.Duration
+	slide       time.Duration
+	gap         time.Duration
+	lateness    time.Duration
+	flushPeriod time.Duration
+
+	key       *mapping.Executor
+	timestamp *mapping.Executor
+	reducer   *mapping.Executor
+	merger    *mapping.Executor
+
+	mut           sync.Mutex
+	watermark     time.Time
+	lastWatermark time.Time
+	lastProcessed time.Time
+	windows       map[string][]*windowState
+
+	nextID         uint64
+	indexDirty     bool
+	removed        []uint64
+	savedWatermark time.Time
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mErrCache  metrics.StatCounter
+	mLate      metrics.StatCounter
+	mMerged    metrics.StatCounter
+	mOpen      metrics.StatGauge
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewWindow returns a Window processor.
+func NewWindow(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	w := &Window{
+		conf:  conf.Window,
+		mgr:   mgr,
+		log:   log,
+		stats: stats,
+
+		windowType:    conf.Window.Type,
+		windows:       map[string][]*windowState{},
+		lastProcessed: time.Now(),
+
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mErrCache:  stats.GetCounter("error.cache"),
+		mLate:      stats.GetCounter("dropped.late"),
+		mMerged:    stats.GetCounter("windows.merged"),
+		mOpen:      stats.GetGauge("windows.open"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	parseDuration := func(name, str string) (time.Duration, error) {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %v: %v", name, err)
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("%v must be greater than zero", name)
+		}
+		return d, nil
+	}
+
+	switch w.windowType {
+	case "tumbling":
+		if w.size, err = parseDuration("size", conf.Window.Size); err != nil {
+			return nil, err
+		}
+		w.slide = w.size
+	case "sliding":
+		if w.size, err = parseDuration("size", conf.Window.Size); err != nil {
+			return nil, err
+		}
+		if w.slide, err = parseDuration("slide", conf.Window.Slide); err != nil {
+			return nil, err
+		}
+		if w.slide > w.size {
+			return nil, errors.New("slide must be less than or equal to size")
+		}
+	case "session":
+		if w.gap, err = parseDuration("gap", conf.Window.Gap); err != nil {
+			return nil, err
+		}
+		if conf.Window.MergeMapping == "" {
+			return nil, errors.New("a merge_mapping must be specified for session windows")
+		}
+	default:
+		return nil, fmt.Errorf("window type not recognised: %v", w.windowType)
+	}
+
+	if conf.Window.AllowedLateness != "" {
+		if w.lateness, err = time.ParseDuration(conf.Window.AllowedLateness); err != nil {
+			return nil, fmt.Errorf("failed to parse allowed_lateness: %v", err)
+		}
+		if w.lateness < 0 {
+			return nil, errors.New("allowed_lateness must not be negative")
+		}
+	}
+
+	if conf.Window.FlushPeriod != "" {
+		if w.flushPeriod, err = parseDuration("flush_period", conf.Window.FlushPeriod); err != nil {
+			return nil, err
+		}
+	}
+
+	parseMapping := func(name, str string) (*mapping.Executor, error) {
+		exec, err := interop.NewBloblangMapping(mgr, str)
+		if err != nil {
+			if perr, ok := err.(*parser.Error); ok {
+				return nil, fmt.Errorf("failed to parse %v: %v", name, perr.ErrorAtPosition([]rune(str)))
+			}
+			return nil, fmt.Errorf("failed to parse %v: %v", name, err)
+		}
+		return exec, nil
+	}
+	if conf.Window.Key != "" {
+		if w.key, err = parseMapping("key", conf.Window.Key); err != nil {
+			return nil, err
+		}
+	}
+	if w.timestamp, err = parseMapping("timestamp_mapping", conf.Window.TimestampMapping); err != nil {
+		return nil, err
+	}
+	if w.reducer, err = parseMapping("reducer_mapping", conf.Window.ReducerMapping); err != nil {
+		return nil, err
+	}
+	if conf.Window.MergeMapping != "" {
+		if w.merger, err = parseMapping("merge_mapping", conf.Window.MergeMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Window.Cache != "" {
+		c, err := mgr.GetCache(conf.Window.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain cache '%v': %v", conf.Window.Cache, err)
+		}
+		if err = w.restore(c); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) watermarkCacheKey() string {
+	return w.conf.CacheKey + "_watermark"
+}
+
+func (w *Window) windowCacheKey(id uint64) string {
+	return fmt.Sprintf("%v_%v", w.conf.CacheKey, id)
+}
+
+// restoreNumbers replaces json.Number values of a restored aggregate with the
+// int64 or float64 values that the aggregate was originally mapped with.
+func restoreNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = restoreNumbers(v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			t[i] = restoreNumbers(v)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// restore loads the state of windows from a cache.
+func (w *Window) restore(c types.Cache) error {
+	data, err := c.Get(w.conf.CacheKey)
+	if err != nil {
+		if err == types.ErrKeyNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to read window index from cache: %v", err)
+	}
+
+	var index windowIndex
+	if err = json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("failed to parse window index from cache: %v", err)
+	}
+	w.nextID = index.NextID
+
+	if data, err = c.Get(w.watermarkCacheKey()); err == nil {
+		if err = w.watermark.UnmarshalJSON(data); err != nil {
+			return fmt.Errorf("failed to parse window watermark from cache: %v", err)
+		}
+		w.lastWatermark = w.watermark
+		w.savedWatermark = w.watermark
+	} else if err != types.ErrKeyNotFound {
+		return fmt.Errorf("failed to read window watermark from cache: %v", err)
+	}
+
+	restored := 0
+	for _, id := range index.Windows {
+		if data, err = c.Get(w.windowCacheKey(id)); err != nil {
+			if err == types.ErrKeyNotFound {
+				w.log.Warnf("Window %v listed in the cache index was not found\n", id)
+				continue
+			}
+			return fmt.Errorf("failed to read window from cache: %v", err)
+		}
+
+		var ws windowState
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err = dec.Decode(&ws); err != nil {
+			return fmt.Errorf("failed to parse window from cache: %v", err)
+		}
+		ws.Value = restoreNumbers(ws.Value)
+		w.windows[ws.Key] = append(w.windows[ws.Key], &ws)
+		restored++
+	}
+	w.mOpen.Set(int64(restored))
+	w.log.Infof("Restored %v open windows from cache\n", restored)
+	return nil
+}
+
+// persist writes the windows that have changed since the last call to the
+// cache, along with the index of open windows when it has changed, and
+// removes windows that have closed or merged.
+func (w *Window) persist() {
+	if w.conf.Cache == "" {
+		return
+	}
+
+	logErr := func(err error) {
+		w.mErrCache.Incr(1)
+		w.mErr.Incr(1)
+		w.log.Errorf("Failed to write window state to cache: %v\n", err)
+	}
+
+	c, err := w.mgr.GetCache(w.conf.Cache)
+	if err != nil {
+		logErr(err)
+		return
+	}
+
+	index := windowIndex{
+		NextID:  w.nextID,
+		Windows: []uint64{},
+	}
+	for _, windows := range w.windows {
+		for _, ws := range windows {
+			index.Windows = append(index.Windows, ws.ID)
+			if !ws.dirty {
+				continue
+			}
+			data, err := json.Marshal(ws)
+			if err == nil {
+				err = c.Set(w.windowCacheKey(ws.ID), data)
+			}
+			if err != nil {
+				logErr(err)
+				continue
+			}
+			ws.dirty = false
+		}
+	}
+
+	if w.indexDirty {
+		sort.Slice(index.Windows, func(i, j int) bool {
+			return index.Windows[i] < index.Windows[j]
+		})
+		data, err := json.Marshal(index)
+		if err == nil {
+			err = c.Set(w.conf.CacheKey, data)
+		}
+		if err != nil {
+			logErr(err)
+		} else {
+			w.indexDirty = false
+		}
+	}
+
+	if !w.indexDirty {
+		for _, id := range w.removed {
+			if err := c.Delete(w.windowCacheKey(id)); err != nil && err != types.ErrKeyNotFound {
+				logErr(err)
+			}
+		}
+		w.removed = nil
+	}
+
+	if !w.watermark.Equal(w.savedWatermark) {
+		data, err := w.watermark.MarshalJSON()
+		if err == nil {
+			err = c.Set(w.watermarkCacheKey(), data)
+		}
+		if err != nil {
+			logErr(err)
+		} else {
+			w.savedWatermark = w.watermark
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) contextFor(index int, msg types.Message, vars map[string]interface{}) query.FunctionContext {
+	return query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     vars,
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(func() *interface{} {
+		jObj, err := msg.Get(index).JSON()
+		if err != nil {
+			return nil
+		}
+		return &jObj
+	})
+}
+
+func (w *Window) eventTime(index int, msg types.Message) (time.Time, error) {
+	res, err := w.timestamp.Exec(w.contextFor(index, msg, map[string]interface{}{}))
+	if err != nil {
+		return time.Time{}, err
+	}
+	ts, err := query.IGetTimestamp(res)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse event time: %v", err)
+	}
+	return ts, nil
+}
+
+func (w *Window) keyOf(index int, msg types.Message) (string, error) {
+	if w.key == nil {
+		return "", nil
+	}
+	res, err := w.key.Exec(w.contextFor(index, msg, map[string]interface{}{}))
+	if err != nil {
+		return "", err
+	}
+	if _, isNothing := res.(query.Nothing); isNothing {
+		return "", nil
+	}
+	return query.IToString(res), nil
+}
+
+func (w *Window) reduce(ws *windowState, index int, msg types.Message) error {
+	vars := map[string]interface{}{}
+	if ws.Value != nil {
+		vars["state"] = ws.Value
+	}
+	res, err := w.reducer.Exec(w.contextFor(index, msg, vars))
+	if err != nil {
+		return err
+	}
+	if _, isNothing := res.(query.Nothing); !isNothing {
+		ws.Value = res
+	}
+	ws.Count++
+	ws.dirty = true
+	return nil
+}
+
+// merge combines a later session window into an earlier one by executing the
+// merge mapping with the aggregate of the later window as its context.
+func (w *Window) merge(into, from *windowState) {
+	if from.Start.Before(into.Start) {
+		into.Start = from.Start
+	}
+	if from.End.After(into.End) {
+		into.End = from.End
+	}
+	into.Count += from.Count
+	into.dirty = true
+
+	w.removed = append(w.removed, from.ID)
+	w.indexDirty = true
+	w.mMerged.Incr(1)
+
+	switch {
+	case from.Value == nil:
+		return
+	case into.Value == nil:
+		into.Value = from.Value
+		return
+	}
+
+	res, err := w.merger.Exec(query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{"state": into.Value},
+		MsgBatch: message.New(nil),
+	}.WithValueFunc(func() *interface{} {
+		return &from.Value
+	}))
+	if err != nil {
+		w.mErr.Incr(1)
+		w.log.Errorf("Failed to execute merge mapping, the aggregate of the later window is lost: %v\n", err)
+		return
+	}
+	if _, isNothing := res.(query.Nothing); !isNothing {
+		into.Value = res
+	}
+}
+
+// closed returns whether a window ending at a given time has closed.
+func (w *Window) closed(end time.Time) bool {
+	return !w.watermark.Before(end.Add(w.lateness))
+}
+
+func (w *Window) newWindow(key string, start, end time.Time) *windowState {
+	ws := &windowState{ID: w.nextID, Key: key, Start: start, End: end, dirty: true}
+	w.nextID++
+	w.indexDirty = true
+	return ws
+}
+
+// assign returns the windows of a key that a message with a given timestamp
+// should be added to, creating them when they do not already exist.
+func (w *Window) assign(key string, ts time.Time) []*windowState {
+	windows := w.windows[key]
+
+	if w.windowType == "session" {
+		end := ts.Add(w.gap)
+
+		// Collect all sessions that overlap the session of this message, which
+		// are merged into the earliest of them.
+		var target *windowState
+		remaining := windows[:0]
+		var overlapping []*windowState
+		for _, ws := range windows {
+			if ws.Start.Before(end) && ts.Before(ws.End) {
+				overlapping = append(overlapping, ws)
+			} else {
+				remaining = append(remaining, ws)
+			}
+		}
+		if len(overlapping) == 0 {
+			if w.closed(end) {
+				return nil
+			}
+			target = w.newWindow(key, ts, end)
+			w.windows[key] = append(windows, target)
+			return []*windowState{target}
+		}
+
+		sort.Slice(overlapping, func(i, j int) bool {
+			return overlapping[i].Start.Before(overlapping[j].Start)
+		})
+		target = overlapping[0]
+		for _, ws := range overlapping[1:] {
+			w.merge(target, ws)
+		}
+		if ts.Before(target.Start) {
+			target.Start = ts
+		}
+		if end.After(target.End) {
+			target.End = end
+		}
+		w.windows[key] = append(remaining, target)
+		return []*windowState{target}
+	}
+
+	tsNano := ts.UnixNano()
+	firstStart := tsNano - (tsNano % int64(w.slide))
+	if tsNano < 0 && tsNano%int64(w.slide) != 0 {
+		firstStart -= int64(w.slide)
+	}
+
+	var assigned []*windowState
+	for start := time.Unix(0, firstStart); start.After(ts.Add(-w.size)); start = start.Add(-w.slide) {
+		end := start.Add(w.size)
+		if w.closed(end) {
+			break
+		}
+		var target *windowState
+		for _, ws := range windows {
+			if ws.Start.Equal(start) {
+				target = ws
+				break
+			}
+		}
+		if target == nil {
+			target = w.newWindow(key, start, end)
+			windows = append(windows, target)
+		}
+		assigned = append(assigned, target)
+	}
+	w.windows[key] = windows
+	return assigned
+}
+
+// flushClosed removes all windows that have closed and returns them as a
+// message, ordered by their end time.
+func (w *Window) flushClosed() []types.Message {
+	var flushed []*windowState
+	open := 0
+	for key, windows := range w.windows {
+		remaining := windows[:0]
+		for _, ws := range windows {
+			if w.closed(ws.End) {
+				flushed = append(flushed, ws)
+				w.removed = append(w.removed, ws.ID)
+				w.indexDirty = true
+			} else {
+				remaining = append(remaining, ws)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(w.windows, key)
+		} else {
+			w.windows[key] = remaining
+		}
+		open += len(remaining)
+	}
+	w.mOpen.Set(int64(open))
+	w.persist()
+
+	if len(flushed) == 0 {
+		return nil
+	}
+
+	sort.Slice(flushed, func(i, j int) bool {
+		if flushed[i].End.Equal(flushed[j].End) {
+			if flushed[i].Key == flushed[j].Key {
+				return flushed[i].Start.Before(flushed[j].Start)
+			}
+			return flushed[i].Key < flushed[j].Key
+		}
+		return flushed[i].End.Before(flushed[j].End)
+	})
+
+	newMsg := message.New(nil)
+	for _, ws := range flushed {
+		data, err := json.Marshal(ws.Value)
+		if err != nil {
+			w.mErr.Incr(1)
+			w.log.Errorf("Failed to serialise window aggregate: %v\n", err)
+			continue
+		}
+		part := message.NewPart(data)
+		part.Metadata().
+			Set("window_key", ws.Key).
+			Set("window_start", ws.Start.Format(time.RFC3339Nano)).
+			Set("window_end", ws.End.Format(time.RFC3339Nano)).
+			Set("window_count", fmt.Sprintf("%v", ws.Count))
+		newMsg.Append(part)
+	}
+	if newMsg.Len() == 0 {
+		return nil
+	}
+
+	w.mBatchSent.Incr(1)
+	w.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage adds each message of a batch to its windows and returns the
+// aggregates of any windows that have closed as a result.
+func (w *Window) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	msg.Iter(func(i int, p types.Part) error {
+		ts, err := w.eventTime(i, msg)
+		if err != nil {
+			w.mErr.Incr(1)
+			w.log.Errorf("Failed to extract event time: %v\n", err)
+			return nil
+		}
+
+		key, err := w.keyOf(i, msg)
+		if err != nil {
+			w.mErr.Incr(1)
+			w.log.Errorf("Failed to execute key mapping: %v\n", err)
+			return nil
+		}
+
+		windows := w.assign(key, ts)
+		if len(windows) == 0 {
+			w.mLate.Incr(1)
+			w.log.Debugf("Dropping message with event time %v as its windows have closed\n", ts)
+			return nil
+		}
+		for _, ws := range windows {
+			if err = w.reduce(ws, i, msg); err != nil {
+				w.mErr.Incr(1)
+				w.log.Errorf("Failed to execute reducer mapping: %v\n", err)
+			}
+		}
+
+		if ts.After(w.watermark) {
+			w.watermark = ts
+		}
+		return nil
+	})
+	w.lastWatermark = w.watermark
+	w.lastProcessed = time.Now()
+
+	if msgs := w.flushClosed(); len(msgs) > 0 {
+		return msgs, nil
+	}
+	return nil, response.NewAck()
+}
+
+// FlushPeriod returns the period at which idle windows should be flushed.
+func (w *Window) FlushPeriod() time.Duration {
+	return w.flushPeriod
+}
+
+// Flush advances the watermark by the time elapsed since the last message was
+// processed, when no messages have been processed for at least a flush period,
+// and returns the aggregates of any windows that have closed as a result.
+func (w *Window) Flush() []types.Message {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if len(w.windows) == 0 {
+		return nil
+	}
+
+	idle := time.Since(w.lastProcessed)
+	if idle < w.flushPeriod {
+		return nil
+	}
+	if wm := w.lastWatermark.Add(idle); wm.After(w.watermark) {
+		w.watermark = wm
+	}
+	return w.flushClosed()
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (w *Window) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (w *Window) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
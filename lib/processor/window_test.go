@@ -0,0 +1,346 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type windowResult struct {
+	Key     string
+	Start   int64
+	End     int64
+	Count   string
+	Content string
+}
+
+func windowResults(t *testing.T, msgs []types.Message) []windowResult {
+	t.Helper()
+
+	var results []windowResult
+	for _, m := range msgs {
+		m.Iter(func(i int, p types.Part) error {
+			start, err := time.Parse(time.RFC3339Nano, p.Metadata().Get("window_start"))
+			require.NoError(t, err)
+			end, err := time.Parse(time.RFC3339Nano, p.Metadata().Get("window_end"))
+			require.NoError(t, err)
+			results = append(results, windowResult{
+				Key:     p.Metadata().Get("window_key"),
+				Start:   start.Unix(),
+				End:     end.Unix(),
+				Count:   p.Metadata().Get("window_count"),
+				Content: string(p.Get()),
+			})
+			return nil
+		})
+	}
+	return results
+}
+
+func windowProcess(t *testing.T, proc Type, docs ...string) []windowResult {
+	t.Helper()
+
+	var raw [][]byte
+	for _, d := range docs {
+		raw = append(raw, []byte(d))
+	}
+	msgs, res := proc.ProcessMessage(message.New(raw))
+	if len(msgs) == 0 {
+		require.NotNil(t, res)
+		require.NoError(t, res.Error())
+		return nil
+	}
+	return windowResults(t, msgs)
+}
+
+func windowDoc(key string, ts int64, value int) string {
+	return fmt.Sprintf(`{"key":"%v","ts":%v,"value":%v}`, key, ts, value)
+}
+
+func newWindowTestConf() Config {
+	conf := NewConfig()
+	conf.Type = TypeWindow
+	conf.Window.Key = `root = this.key`
+	conf.Window.TimestampMapping = `root = this.ts`
+	conf.Window.ReducerMapping = `root.sum = ($state.sum | 0) + this.value`
+	conf.Window.MergeMapping = `root.sum = $state.sum + this.sum`
+	return conf
+}
+
+func TestWindowTumbling(t *testing.T) {
+	conf := newWindowTestConf()
+	conf.Window.Size = "10s"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Empty(t, windowProcess(t, proc,
+		windowDoc("foo", 100, 1),
+		windowDoc("bar", 101, 2),
+		windowDoc("foo", 105, 3),
+	))
+
+	assert.Equal(t, []windowResult{
+		{Key: "bar", Start: 100, End: 110, Count: "1", Content: `{"sum":2}`},
+		{Key: "foo", Start: 100, End: 110, Count: "2", Content: `{"sum":4}`},
+	}, windowProcess(t, proc, windowDoc("foo", 112, 4)))
+
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 110, End: 120, Count: "1", Content: `{"sum":4}`},
+	}, windowProcess(t, proc, windowDoc("baz", 125, 5)))
+}
+
+func TestWindowSliding(t *testing.T) {
+	conf := newWindowTestConf()
+	conf.Window.Type = "sliding"
+	conf.Window.Size = "10s"
+	conf.Window.Slide = "5s"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Empty(t, windowProcess(t, proc,
+		windowDoc("foo", 101, 1),
+		windowDoc("foo", 104, 2),
+	))
+
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 95, End: 105, Count: "2", Content: `{"sum":3}`},
+	}, windowProcess(t, proc, windowDoc("foo", 108, 3)))
+
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 100, End: 110, Count: "3", Content: `{"sum":6}`},
+		{Key: "foo", Start: 105, End: 115, Count: "1", Content: `{"sum":3}`},
+	}, windowProcess(t, proc, windowDoc("bar", 120, 4)))
+}
+
+func TestWindowSession(t *testing.T) {
+	conf := newWindowTestConf()
+	conf.Window.Type = "session"
+	conf.Window.Gap = "10s"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Empty(t, windowProcess(t, proc,
+		windowDoc("foo", 100, 1),
+		windowDoc("foo", 108, 2),
+		windowDoc("bar", 110, 3),
+		windowDoc("foo", 115, 4),
+	))
+
+	assert.Equal(t, []windowResult{
+		{Key: "bar", Start: 110, End: 120, Count: "1", Content: `{"sum":3}`},
+	}, windowProcess(t, proc, windowDoc("foo", 120, 5)))
+
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 100, End: 130, Count: "4", Content: `{"sum":12}`},
+		{Key: "foo", Start: 131, End: 141, Count: "1", Content: `{"sum":6}`},
+	}, windowProcess(t, proc, windowDoc("foo", 131, 6), windowDoc("baz", 150, 7)))
+}
+
+func TestWindowSessionMerge(t *testing.T) {
+	conf := newWindowTestConf()
+	conf.Window.Type = "session"
+	conf.Window.Gap = "10s"
+	conf.Window.AllowedLateness = "20s"
+
+	stats := metrics.NewLocal()
+	proc, err := New(conf, nil, log.Noop(), stats)
+	require.NoError(t, err)
+
+	assert.Empty(t, windowProcess(t, proc,
+		windowDoc("foo", 100, 1),
+		windowDoc("foo", 125, 2),
+		windowDoc("foo", 108, 3),
+		windowDoc("foo", 116, 4),
+	))
+	assert.Equal(t, int64(1), stats.GetCounters()["windows.merged"])
+
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 100, End: 135, Count: "4", Content: `{"sum":10}`},
+	}, windowProcess(t, proc, windowDoc("bar", 160, 5)))
+}
+
+func TestWindowFlush(t *testing.T) {
+	conf := newWindowTestConf()
+	conf.Window.Size = "10s"
+	conf.Window.FlushPeriod = "1ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	flusher, ok := proc.(Flusher)
+	require.True(t, ok)
+	assert.Equal(t, time.Millisecond, flusher.FlushPeriod())
+
+	assert.Empty(t, windowProcess(t, proc, windowDoc("foo", 100, 1)))
+	proc.(*Window).lastProcessed = time.Now().Add(-5 * time.Second)
+	assert.Empty(t, flusher.Flush())
+
+	proc.(*Window).lastProcessed = time.Now().Add(-10 * time.Second)
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 100, End: 110, Count: "1", Content: `{"sum":1}`},
+	}, windowResults(t, flusher.Flush()))
+	assert.Empty(t, flusher.Flush())
+}
+
+func TestWindowLateness(t *testing.T) {
+	conf := newWindowTestConf()
+	conf.Window.Size = "10s"
+	conf.Window.AllowedLateness = "5s"
+
+	stats := metrics.NewLocal()
+	proc, err := New(conf, nil, log.Noop(), stats)
+	require.NoError(t, err)
+
+	assert.Empty(t, windowProcess(t, proc,
+		windowDoc("foo", 100, 1),
+		windowDoc("foo", 112, 2),
+		windowDoc("foo", 105, 3),
+	))
+
+	assert.Equal(t, []windowResult{
+		{Key: "foo", Start: 100, End: 110, Count: "2", Content: `{"sum":4}`},
+	}, windowProcess(t, proc, windowDoc("foo", 116, 4)))
+
+	assert.Empty(t, windowProcess(t, proc, windowDoc("foo", 108, 5)))
+	assert.Equal(t, int64(1), stats.GetCounters()["dropped.late"])
+}
+
+func TestWindowCacheState(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := newWindowTestConf()
+	conf.Window.Size = "10s"
+	conf.Window.Cache = "foocache"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Empty(t, windowProcess(t, proc,
+		windowDoc("foo", 100, 1),
+		windowDoc("foo", 105, 2),
+		windowDoc("bar", 106, 3),
+	))
+	proc.CloseAsync()
+	require.NoError(t, proc.WaitForClose(time.Second))
+
+	data, err := memCache.Get("benthos_window_state")
+	require.NoError(t, err)
+	assert.Equal(t, `{"next_id":2,"windows":[0,1]}`, string(data))
+
+	data, err = memCache.Get("benthos_window_state_1")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"key":"bar"`)
+
+	proc, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sum": int64(3)}, proc.(*Window).windows["foo"][0].Value)
+
+	assert.Empty(t, windowProcess(t, proc, windowDoc("foo", 95, 3)), "message before watermark was dropped")
+	assert.Equal(t, []windowResult{
+		{Key: "bar", Start: 100, End: 110, Count: "1", Content: `{"sum":3}`},
+		{Key: "foo", Start: 100, End: 110, Count: "3", Content: `{"sum":7}`},
+	}, windowProcess(t, proc, windowDoc("foo", 108, 4), windowDoc("foo", 111, 5)))
+
+	_, err = memCache.Get("benthos_window_state_0")
+	assert.Equal(t, types.ErrKeyNotFound, err, "closed windows are removed from the cache")
+
+	conf.Window.Cache = "barcache"
+	_, err = New(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestWindowConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        func(c *WindowConfig)
+		errContains string
+	}{
+		"bad type": {
+			conf: func(c *WindowConfig) {
+				c.Type = "nope"
+			},
+			errContains: "window type not recognised",
+		},
+		"bad size": {
+			conf: func(c *WindowConfig) {
+				c.Size = "nope"
+			},
+			errContains: "failed to parse size",
+		},
+		"sliding without slide": {
+			conf: func(c *WindowConfig) {
+				c.Type = "sliding"
+			},
+			errContains: "failed to parse slide",
+		},
+		"slide larger than size": {
+			conf: func(c *WindowConfig) {
+				c.Type = "sliding"
+				c.Size = "1s"
+				c.Slide = "2s"
+			},
+			errContains: "slide must be less than or equal to size",
+		},
+		"session without gap": {
+			conf: func(c *WindowConfig) {
+				c.Type = "session"
+			},
+			errContains: "failed to parse gap",
+		},
+		"session without merge mapping": {
+			conf: func(c *WindowConfig) {
+				c.Type = "session"
+				c.Gap = "1s"
+			},
+			errContains: "merge_mapping must be specified",
+		},
+		"bad key": {
+			conf: func(c *WindowConfig) {
+				c.Key = `${! json("key") }`
+			},
+			errContains: "failed to parse key",
+		},
+		"negative lateness": {
+			conf: func(c *WindowConfig) {
+				c.AllowedLateness = "-1s"
+			},
+			errContains: "allowed_lateness must not be negative",
+		},
+		"bad reducer": {
+			conf: func(c *WindowConfig) {
+				c.ReducerMapping = "root = ("
+			},
+			errContains: "failed to parse reducer_mapping",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeWindow
+			test.conf(&conf.Window)
+
+			_, err := New(conf, nil, log.Noop(), metrics.Noop())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
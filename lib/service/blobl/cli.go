@@ -56,6 +56,10 @@ func CliCommand() *cli.Command {
 			},
 		},
 		Action: run,
+		Subcommands: []*cli.Command{
+			serverCliCommand(),
+			lspCliCommand(),
+		},
 	}
 }
 
@@ -0,0 +1,573 @@
+package blobl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/urfave/cli/v2"
+)
+
+func lspCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "lsp",
+		Usage: "EXPERIMENTAL: Run a Bloblang language server over stdio",
+		Description: `
+   Runs a language server implementing the Language Server Protocol over stdin
+   and stdout, providing diagnostics, completion and hover documentation for
+   Bloblang mapping files to editors that support it.`[4:],
+		Action: func(c *cli.Context) error {
+			if err := newLanguageServer(os.Stdout).serve(os.Stdin); err != nil {
+				fmt.Fprintln(os.Stderr, red(err.Error()))
+				os.Exit(1)
+			}
+			os.Exit(0)
+			return nil
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Subset of the LSP types used by the language server.
+
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspMarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspCompletionItem struct {
+	Label         string            `json:"label"`
+	Kind          int               `json:"kind"`
+	Detail        string            `json:"detail,omitempty"`
+	Documentation *lspMarkupContent `json:"documentation,omitempty"`
+}
+
+type lspHover struct {
+	Contents lspMarkupContent `json:"contents"`
+	Range    *lspRange        `json:"range,omitempty"`
+}
+
+type lspTextDocumentPositionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+
+	lspCompletionKindMethod   = 2
+	lspCompletionKindFunction = 3
+
+	lspErrMethodNotFound = -32601
+	lspErrInvalidParams  = -32602
+)
+
+//------------------------------------------------------------------------------
+
+// languageServer is a minimal implementation of the Language Server Protocol
+// for Bloblang documents. Documents are synchronised in full on each change.
+type languageServer struct {
+	outMut sync.Mutex
+	out    io.Writer
+
+	docs map[string]string
+}
+
+func newLanguageServer(out io.Writer) *languageServer {
+	return &languageServer{
+		out:  out,
+		docs: map[string]string{},
+	}
+}
+
+// serve reads and handles messages from an input stream until either the
+// stream ends or an exit notification is received.
+func (l *languageServer) serve(in io.Reader) error {
+	r := bufio.NewReader(in)
+	for {
+		body, err := readRPCMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var msg rpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return fmt.Errorf("failed to decode message: %w", err)
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := l.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func readRPCMessage(r *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		if i := strings.Index(line, ":"); i > 0 && strings.EqualFold(line[:i], "Content-Length") {
+			if contentLength, err = strconv.Atoi(strings.TrimSpace(line[i+1:])); err != nil {
+				return nil, fmt.Errorf("invalid content length header: %w", err)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return nil, errors.New("message is missing a content length header")
+	}
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func (l *languageServer) write(v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	l.outMut.Lock()
+	defer l.outMut.Unlock()
+
+	if _, err = fmt.Fprintf(l.out, "Content-Length: %v\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = l.out.Write(body)
+	return err
+}
+
+func (l *languageServer) respond(id *json.RawMessage, result interface{}, rErr *rpcError) error {
+	return l.write(rpcResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Result:  result,
+		Error:   rErr,
+	})
+}
+
+func (l *languageServer) notify(method string, params interface{}) error {
+	return l.write(rpcNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func (l *languageServer) handle(msg rpcMessage) error {
+	switch msg.Method {
+	case "initialize":
+		return l.respond(msg.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": 1,
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"."},
+				},
+				"hoverProvider": true,
+			},
+			"serverInfo": map[string]interface{}{
+				"name": "blobl",
+			},
+		}, nil)
+	case "shutdown":
+		return l.respond(msg.ID, nil, nil)
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		l.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return l.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		l.docs[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		return l.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		delete(l.docs, params.TextDocument.URI)
+		return l.notify("textDocument/publishDiagnostics", map[string]interface{}{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+	case "textDocument/completion":
+		var params lspTextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return l.respond(msg.ID, nil, &rpcError{Code: lspErrInvalidParams, Message: err.Error()})
+		}
+		return l.respond(msg.ID, l.completion(params.TextDocument.URI, params.Position), nil)
+	case "textDocument/hover":
+		var params lspTextDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return l.respond(msg.ID, nil, &rpcError{Code: lspErrInvalidParams, Message: err.Error()})
+		}
+		return l.respond(msg.ID, l.hover(params.TextDocument.URI, params.Position), nil)
+	}
+
+	// Notifications that we do not support are ignored, requests must be
+	// responded to.
+	if msg.ID != nil {
+		return l.respond(msg.ID, nil, &rpcError{
+			Code:    lspErrMethodNotFound,
+			Message: fmt.Sprintf("method not supported: %v", msg.Method),
+		})
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func filePathFromURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
+// positionOf returns the zero indexed line and character of a rune offset
+// within a document, where characters are counted in UTF-16 code units as
+// required by the protocol.
+func positionOf(doc []rune, offset int) lspPosition {
+	var pos lspPosition
+	for i := 0; i < offset && i < len(doc); i++ {
+		if doc[i] == '\n' {
+			pos.Line++
+			pos.Character = 0
+		} else {
+			pos.Character += utf16.RuneLen(doc[i])
+		}
+	}
+	return pos
+}
+
+// runeIndexOf converts a character of a position, counted in UTF-16 code
+// units, into an index of the runes of a line, clamped to the bounds of the
+// line.
+func runeIndexOf(line []rune, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// characterOf converts an index of the runes of a line into a character
+// counted in UTF-16 code units.
+func characterOf(line []rune, index int) int {
+	units := 0
+	for i := 0; i < index && i < len(line); i++ {
+		units += utf16.RuneLen(line[i])
+	}
+	return units
+}
+
+func diagnosticsFor(path, doc string) []lspDiagnostic {
+	diags := []lspDiagnostic{}
+
+	exec, err := bloblang.NewMapping(path, doc)
+	if err == nil {
+		for _, tErr := range exec.CheckTypes() {
+			var start lspPosition
+			msg := tErr.Error()
+			if tcErr, ok := tErr.(*mapping.TypeCheckError); ok {
+				if tcErr.Line > 0 {
+					start.Line = tcErr.Line - 1
+				}
+				msg = tcErr.Err.Error()
+			}
+			end := start
+			line := lineAt(doc, start.Line)
+			end.Character = characterOf(line, len(line))
+			diags = append(diags, lspDiagnostic{
+				Range:    lspRange{Start: start, End: end},
+				Severity: lspSeverityWarning,
+				Source:   "blobl",
+				Message:  msg,
+			})
+		}
+		return diags
+	}
+
+	docRunes := []rune(doc)
+	var start lspPosition
+	msg := err.Error()
+	if perr, ok := err.(*parser.Error); ok {
+		start = positionOf(docRunes, len(docRunes)-len(perr.Input))
+		msg = perr.ErrorMsg()
+	}
+	end := start
+	end.Character++
+
+	return append(diags, lspDiagnostic{
+		Range:    lspRange{Start: start, End: end},
+		Severity: lspSeverityError,
+		Source:   "blobl",
+		Message:  msg,
+	})
+}
+
+func (l *languageServer) publishDiagnostics(uri string) error {
+	return l.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnosticsFor(filePathFromURI(uri), l.docs[uri]),
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func isIdentRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
+
+// lineAt returns the runes of a given zero indexed line of a document.
+func lineAt(doc string, line int) []rune {
+	lines := strings.Split(doc, "\n")
+	if line < 0 || line >= len(lines) {
+		return nil
+	}
+	return []rune(lines[line])
+}
+
+func functionDocMarkdown(spec query.FunctionSpec) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "```coffee\n%v()\n```\n\n", spec.Name)
+	buf.WriteString(spec.Description)
+	if len(spec.Examples) > 0 {
+		fmt.Fprintf(&buf, "\n\n```coffee\n%v\n```", strings.TrimSpace(spec.Examples[0].Mapping))
+	}
+	return buf.String()
+}
+
+func methodDocMarkdown(spec query.MethodSpec) string {
+	paragraphs := []string{fmt.Sprintf("```coffee\n.%v()\n```", spec.Name)}
+	if len(spec.Description) > 0 {
+		paragraphs = append(paragraphs, spec.Description)
+	}
+	examples := spec.Examples
+	for _, cat := range spec.Categories {
+		if len(cat.Description) > 0 {
+			paragraphs = append(paragraphs, cat.Description)
+		}
+		examples = append(examples, cat.Examples...)
+	}
+	if len(examples) > 0 {
+		paragraphs = append(paragraphs, fmt.Sprintf("```coffee\n%v\n```", strings.TrimSpace(examples[0].Mapping)))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func functionCompletions() []lspCompletionItem {
+	var items []lspCompletionItem
+	for _, spec := range query.FunctionDocs() {
+		if spec.Status == query.StatusHidden || spec.Status == query.StatusDeprecated {
+			continue
+		}
+		items = append(items, lspCompletionItem{
+			Label:  spec.Name,
+			Kind:   lspCompletionKindFunction,
+			Detail: string(spec.Category),
+			Documentation: &lspMarkupContent{
+				Kind:  "markdown",
+				Value: functionDocMarkdown(spec),
+			},
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+func methodCompletions() []lspCompletionItem {
+	var items []lspCompletionItem
+	for _, spec := range query.MethodDocs() {
+		if spec.Status == query.StatusHidden || spec.Status == query.StatusDeprecated {
+			continue
+		}
+		var detail string
+		if len(spec.Categories) > 0 {
+			detail = string(spec.Categories[0].Category)
+		}
+		items = append(items, lspCompletionItem{
+			Label:  spec.Name,
+			Kind:   lspCompletionKindMethod,
+			Detail: detail,
+			Documentation: &lspMarkupContent{
+				Kind:  "markdown",
+				Value: methodDocMarkdown(spec),
+			},
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+func (l *languageServer) completion(uri string, pos lspPosition) []lspCompletionItem {
+	line := lineAt(l.docs[uri], pos.Line)
+	end := runeIndexOf(line, pos.Character)
+	start := end
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:end])
+
+	candidates := functionCompletions()
+	if start > 0 && line[start-1] == '.' {
+		candidates = methodCompletions()
+	}
+
+	items := []lspCompletionItem{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c.Label, prefix) {
+			items = append(items, c)
+		}
+	}
+	return items
+}
+
+func (l *languageServer) hover(uri string, pos lspPosition) *lspHover {
+	line := lineAt(l.docs[uri], pos.Line)
+	if pos.Character < 0 || pos.Character > characterOf(line, len(line)) {
+		return nil
+	}
+	start := runeIndexOf(line, pos.Character)
+	end := start
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isIdentRune(line[end]) {
+		end++
+	}
+	if start == end {
+		return nil
+	}
+
+	name := string(line[start:end])
+	hRange := &lspRange{
+		Start: lspPosition{Line: pos.Line, Character: characterOf(line, start)},
+		End:   lspPosition{Line: pos.Line, Character: characterOf(line, end)},
+	}
+
+	if start > 0 && line[start-1] == '.' {
+		for _, spec := range query.MethodDocs() {
+			if spec.Name == name && spec.Status != query.StatusHidden {
+				return &lspHover{
+					Contents: lspMarkupContent{Kind: "markdown", Value: methodDocMarkdown(spec)},
+					Range:    hRange,
+				}
+			}
+		}
+		return nil
+	}
+
+	if end >= len(line) || line[end] != '(' {
+		return nil
+	}
+	for _, spec := range query.FunctionDocs() {
+		if spec.Name == name && spec.Status != query.StatusHidden {
+			return &lspHover{
+				Contents: lspMarkupContent{Kind: "markdown", Value: functionDocMarkdown(spec)},
+				Range:    hRange,
+			}
+		}
+	}
+	return nil
+}
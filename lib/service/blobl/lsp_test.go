@@ -0,0 +1,176 @@
+package blobl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lspFrame(t *testing.T, v interface{}) string {
+	t.Helper()
+	body, err := json.Marshal(v)
+	require.NoError(t, err)
+	return fmt.Sprintf("Content-Length: %v\r\n\r\n%s", len(body), body)
+}
+
+func readLSPMessages(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var msgs []map[string]interface{}
+	r := bufio.NewReader(out)
+	for {
+		body, err := readRPCMessage(r)
+		if err != nil {
+			break
+		}
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &msg))
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestLanguageServerDiagnostics(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString(lspFrame(t, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "initialize",
+		"params":  map[string]interface{}{},
+	}))
+	in.WriteString(lspFrame(t, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/didOpen",
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"uri":  "untitled:foo.blobl",
+				"text": "root.foo = this.foo\nroot.bar = this.bar.",
+			},
+		},
+	}))
+	in.WriteString(lspFrame(t, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/didChange",
+		"params": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"uri": "untitled:foo.blobl",
+			},
+			"contentChanges": []interface{}{
+				map[string]interface{}{"text": "root.foo = this.foo"},
+			},
+		},
+	}))
+	in.WriteString(lspFrame(t, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      2,
+		"method":  "shutdown",
+	}))
+	in.WriteString(lspFrame(t, map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "exit",
+	}))
+
+	var out bytes.Buffer
+	require.NoError(t, newLanguageServer(&out).serve(&in))
+
+	msgs := readLSPMessages(t, &out)
+	require.Len(t, msgs, 4)
+
+	assert.Equal(t, float64(1), msgs[0]["id"])
+	assert.Contains(t, msgs[0]["result"], "capabilities")
+
+	assert.Equal(t, "textDocument/publishDiagnostics", msgs[1]["method"])
+	diags := msgs[1]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	require.Len(t, diags, 1)
+	diag := diags[0].(map[string]interface{})
+	assert.Equal(t, "required: expected method or field path", diag["message"])
+	assert.Equal(t, map[string]interface{}{
+		"line": float64(1), "character": float64(20),
+	}, diag["range"].(map[string]interface{})["start"])
+
+	assert.Equal(t, "textDocument/publishDiagnostics", msgs[2]["method"])
+	diags = msgs[2]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	assert.Len(t, diags, 0)
+
+	assert.Equal(t, float64(2), msgs[3]["id"])
+}
+
+func TestLanguageServerTypeDiagnostics(t *testing.T) {
+	diags := diagnosticsFor("", "root.foo = this.foo\nroot.bar = 10.uppercase()")
+	require.Len(t, diags, 1)
+	assert.Equal(t, lspSeverityWarning, diags[0].Severity)
+	assert.Equal(t, "method uppercase: expected string or bytes value, found number", diags[0].Message)
+	assert.Equal(t, lspRange{
+		Start: lspPosition{Line: 1},
+		End:   lspPosition{Line: 1, Character: 25},
+	}, diags[0].Range)
+}
+
+func TestLanguageServerCompletion(t *testing.T) {
+	l := newLanguageServer(&bytes.Buffer{})
+	l.docs["foo"] = "root.foo = this.foo.upp\nroot.bar = uuid_"
+
+	labels := func(items []lspCompletionItem) []string {
+		var l []string
+		for _, item := range items {
+			l = append(l, item.Label)
+		}
+		return l
+	}
+
+	methods := l.completion("foo", lspPosition{Line: 0, Character: 23})
+	assert.Equal(t, []string{"uppercase"}, labels(methods))
+	assert.Equal(t, lspCompletionKindMethod, methods[0].Kind)
+
+	functions := l.completion("foo", lspPosition{Line: 1, Character: 16})
+	assert.Equal(t, []string{"uuid_v4"}, labels(functions))
+	assert.Equal(t, lspCompletionKindFunction, functions[0].Kind)
+
+	allMethods := l.completion("foo", lspPosition{Line: 0, Character: 20})
+	assert.Greater(t, len(allMethods), 10)
+
+	assert.NotPanics(t, func() {
+		l.completion("foo", lspPosition{Line: 0, Character: -5})
+		l.completion("foo", lspPosition{Line: -1, Character: -5})
+	})
+}
+
+func TestLanguageServerUTF16Positions(t *testing.T) {
+	l := newLanguageServer(&bytes.Buffer{})
+	// The emoji is two UTF-16 code units and a single rune.
+	l.docs["foo"] = `root.foo = "😀é" + this.foo.upp`
+
+	methods := l.completion("foo", lspPosition{Line: 0, Character: 31})
+	require.Len(t, methods, 1)
+	assert.Equal(t, "uppercase", methods[0].Label)
+
+	l.docs["foo"] = `root.foo = "😀é" + this.foo.uppercase()`
+	hover := l.hover("foo", lspPosition{Line: 0, Character: 31})
+	require.NotNil(t, hover)
+	assert.Equal(t, lspPosition{Line: 0, Character: 28}, hover.Range.Start)
+	assert.Equal(t, lspPosition{Line: 0, Character: 37}, hover.Range.End)
+
+	assert.Equal(t, lspPosition{Line: 1, Character: 3}, positionOf([]rune("a\n😀b"), 4))
+}
+
+func TestLanguageServerHover(t *testing.T) {
+	l := newLanguageServer(&bytes.Buffer{})
+	l.docs["foo"] = "root.foo = this.foo.uppercase()\nroot.bar = uuid_v4()\nroot.baz = this.nope"
+
+	hover := l.hover("foo", lspPosition{Line: 0, Character: 22})
+	require.NotNil(t, hover)
+	assert.Contains(t, hover.Contents.Value, ".uppercase()")
+	assert.Equal(t, lspPosition{Line: 0, Character: 20}, hover.Range.Start)
+	assert.Equal(t, lspPosition{Line: 0, Character: 29}, hover.Range.End)
+
+	hover = l.hover("foo", lspPosition{Line: 1, Character: 12})
+	require.NotNil(t, hover)
+	assert.Contains(t, hover.Contents.Value, "uuid_v4()")
+
+	assert.Nil(t, l.hover("foo", lspPosition{Line: 2, Character: 18}))
+	assert.Nil(t, l.hover("foo", lspPosition{Line: 0, Character: -1}))
+}
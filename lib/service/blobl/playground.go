@@ -0,0 +1,158 @@
+package blobl
+
+// playgroundPage is the HTML template served by the blobl server at its root
+// path. It is a self contained page that calls the /execute endpoint whenever
+// the input, metadata or mapping are modified.
+const playgroundPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Bloblang Playground</title>
+<style>
+	html, body {
+		margin: 0;
+		height: 100%;
+		font-family: sans-serif;
+		background-color: #33353b;
+		color: #ddd;
+	}
+	.grid {
+		display: grid;
+		grid-template-columns: 1fr 1fr;
+		grid-template-rows: 1fr 10em 1fr;
+		grid-template-areas:
+			"input output"
+			"inmeta outmeta"
+			"mapping mapping";
+		gap: 0.5em;
+		height: calc(100% - 1em);
+		padding: 0.5em;
+		box-sizing: border-box;
+	}
+	.panel {
+		display: flex;
+		flex-direction: column;
+		min-height: 0;
+	}
+	.panel h2 {
+		font-size: 0.9em;
+		margin: 0 0 0.3em 0;
+		text-transform: uppercase;
+		color: #aaa;
+	}
+	textarea, pre {
+		flex: 1;
+		margin: 0;
+		padding: 0.5em;
+		resize: none;
+		overflow: auto;
+		font-family: monospace;
+		font-size: 1em;
+		background-color: #282a2e;
+		color: #ddd;
+		border: 1px solid #555;
+	}
+	pre.error {
+		color: #f57;
+	}
+	pre.deleted {
+		color: #999;
+		font-style: italic;
+	}
+</style>
+</head>
+<body>
+<div class="grid">
+	<div class="panel" style="grid-area: input">
+		<h2>Input</h2>
+		<textarea id="input" spellcheck="false">{{.Input}}</textarea>
+	</div>
+	<div class="panel" style="grid-area: inmeta">
+		<h2>Input Metadata (JSON object)</h2>
+		<textarea id="inmeta" spellcheck="false">{}</textarea>
+	</div>
+	<div class="panel" style="grid-area: output">
+		<h2>Output</h2>
+		<pre id="output"></pre>
+	</div>
+	<div class="panel" style="grid-area: outmeta">
+		<h2>Output Metadata</h2>
+		<pre id="outmeta"></pre>
+	</div>
+	<div class="panel" style="grid-area: mapping">
+		<h2>Mapping</h2>
+		<textarea id="mapping" spellcheck="false">{{.Mapping}}</textarea>
+	</div>
+</div>
+<script>
+	const inputArea = document.getElementById("input");
+	const inMetaArea = document.getElementById("inmeta");
+	const mappingArea = document.getElementById("mapping");
+	const outputArea = document.getElementById("output");
+	const outMetaArea = document.getElementById("outmeta");
+
+	function setOutput(text, className) {
+		outputArea.textContent = text;
+		outputArea.className = className;
+	}
+
+	function execute() {
+		let metadata = {};
+		try {
+			metadata = JSON.parse(inMetaArea.value || "{}");
+		} catch (e) {
+			setOutput("Failed to parse input metadata: " + e, "error");
+			return;
+		}
+		for (const k in metadata) {
+			metadata[k] = String(metadata[k]);
+		}
+
+		fetch("/execute", {
+			method: "POST",
+			body: JSON.stringify({
+				mapping: mappingArea.value,
+				input: inputArea.value,
+				metadata: metadata,
+			}),
+		}).then(res => res.json()).then(res => {
+			outMetaArea.textContent = "";
+			if (res.parse_error) {
+				setOutput("Failed to parse mapping: " + res.parse_error, "error");
+			} else if (res.mapping_error) {
+				setOutput("Failed to execute mapping: " + res.mapping_error, "error");
+			} else if (res.deleted) {
+				setOutput("Message deleted", "deleted");
+			} else {
+				setOutput(res.result || "", "");
+				outMetaArea.textContent = JSON.stringify(res.metadata || {}, null, 2);
+			}
+		}).catch(err => {
+			setOutput("Failed to reach server: " + err, "error");
+		});
+	}
+
+	let timer = null;
+	function scheduleExecute() {
+		clearTimeout(timer);
+		timer = setTimeout(execute, 200);
+	}
+
+	[inputArea, inMetaArea, mappingArea].forEach(area => {
+		area.addEventListener("input", scheduleExecute);
+		area.addEventListener("keydown", e => {
+			if (e.key === "Tab") {
+				e.preventDefault();
+				const start = area.selectionStart;
+				area.value = area.value.substring(0, start) + "  " + area.value.substring(area.selectionEnd);
+				area.selectionStart = area.selectionEnd = start + 2;
+				scheduleExecute();
+			}
+		});
+	});
+
+	execute();
+</script>
+</body>
+</html>
+`
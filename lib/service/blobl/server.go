@@ -0,0 +1,292 @@
+package blobl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/urfave/cli/v2"
+)
+
+func serverCliCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "server",
+		Usage: "EXPERIMENTAL: Run a web server that hosts a Bloblang playground",
+		Description: `
+   Hosts a web page that allows you to interactively edit and execute Bloblang
+   mappings against an input document and metadata.
+
+   benthos blobl server --input-file ./input.json -m ./mapping.blobl`[4:],
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Value: "localhost",
+				Usage: "the host to bind to.",
+			},
+			&cli.StringFlag{
+				Name:    "port",
+				Value:   "4195",
+				Aliases: []string{"p"},
+				Usage:   "the port to bind to.",
+			},
+			&cli.BoolFlag{
+				Name:    "no-open",
+				Value:   false,
+				Aliases: []string{"n"},
+				Usage:   "do not open the app in the browser automatically.",
+			},
+			&cli.StringFlag{
+				Name:    "mapping-file",
+				Value:   "",
+				Aliases: []string{"m"},
+				Usage:   "an optional path to a mapping file to load as the initial mapping within the app.",
+			},
+			&cli.StringFlag{
+				Name:    "input-file",
+				Value:   "",
+				Aliases: []string{"i"},
+				Usage:   "an optional path to an input file to load as the initial input to the mapping within the app.",
+			},
+		},
+		Action: runServer,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type executeRequest struct {
+	Mapping  string            `json:"mapping"`
+	Input    string            `json:"input"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+type executeResponse struct {
+	ParseError   string            `json:"parse_error,omitempty"`
+	MappingError string            `json:"mapping_error,omitempty"`
+	Result       string            `json:"result,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Deleted      bool              `json:"deleted,omitempty"`
+}
+
+// executeForPlayground parses and executes a mapping against an input
+// document and metadata, and returns a response describing either the result
+// or the error encountered.
+func executeForPlayground(req executeRequest) executeResponse {
+	var res executeResponse
+
+	executor, err := bloblang.NewMapping("", req.Mapping)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			res.ParseError = perr.ErrorAtPosition([]rune(req.Mapping))
+		} else {
+			res.ParseError = err.Error()
+		}
+		return res
+	}
+
+	msg := message.New([][]byte{[]byte(req.Input)})
+	for k, v := range req.Metadata {
+		msg.Get(0).Metadata().Set(k, v)
+	}
+
+	part, err := executor.MapPart(0, msg)
+	if err != nil {
+		res.MappingError = err.Error()
+		return res
+	}
+	if part == nil {
+		res.Deleted = true
+		return res
+	}
+
+	if jObj, err := part.JSON(); err == nil {
+		res.Result = gabs.Wrap(jObj).StringIndent("", "  ")
+	} else {
+		res.Result = string(part.Get())
+	}
+
+	res.Metadata = map[string]string{}
+	part.Metadata().Iter(func(k, v string) error {
+		res.Metadata[k] = v
+		return nil
+	})
+	return res
+}
+
+// sameOrigin returns true if a request either has no Origin header, which is
+// the case for requests that do not come from a browser, or an origin that
+// matches the host being served, which prevents other websites from executing
+// mappings through the browser of a user running the playground.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+// allowedHost returns true if the Host header of a request names either the
+// host the playground is bound to or a loopback address, which prevents other
+// websites from reaching the playground by rebinding their domain to it. When
+// bound to all interfaces any IP address is allowed, as only domain names can
+// be rebound.
+func allowedHost(r *http.Request, bindHost string) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" || host == bindHost {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	bindIP := net.ParseIP(bindHost)
+	return bindHost == "" || (bindIP != nil && bindIP.IsUnspecified())
+}
+
+func executeHandler(bindHost string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !allowedHost(r, bindHost) {
+			http.Error(w, "requests to this host are not allowed", http.StatusForbidden)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "cross origin requests are not allowed", http.StatusForbidden)
+			return
+		}
+
+		var req executeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		resBytes, err := json.Marshal(executeForPlayground(req))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+}
+
+// newPlaygroundMux creates an HTTP mux that serves the playground page and the
+// endpoints it depends on, where mappings are only executed for requests made
+// to the host the playground is bound to.
+func newPlaygroundMux(bindHost, initialMapping, initialInput string) (*http.ServeMux, error) {
+	tmpl, err := template.New("playground").Parse(playgroundPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse playground template: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/execute", executeHandler(bindHost))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmpl.Execute(w, struct {
+			Mapping string
+			Input   string
+		}{
+			Mapping: initialMapping,
+			Input:   initialInput,
+		}); err != nil {
+			http.Error(w, fmt.Sprintf("failed to render page: %v", err), http.StatusInternalServerError)
+		}
+	})
+	return mux, nil
+}
+
+func openBrowserAt(url string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("xdg-open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open browser: %v\n", err)
+	}
+}
+
+func runServer(c *cli.Context) error {
+	initialMapping := `root = this`
+	initialInput := `{"message":"hello world"}`
+
+	if mappingFile := c.String("mapping-file"); len(mappingFile) > 0 {
+		mappingBytes, err := ioutil.ReadFile(mappingFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, red("failed to read mapping file: %v\n"), err)
+			os.Exit(1)
+		}
+		initialMapping = string(mappingBytes)
+	}
+
+	if inputFile := c.String("input-file"); len(inputFile) > 0 {
+		inputBytes, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, red("failed to read input file: %v\n"), err)
+			os.Exit(1)
+		}
+		initialInput = string(inputBytes)
+	}
+
+	host, port := c.String("host"), c.String("port")
+
+	mux, err := newPlaygroundMux(host, initialMapping, initialInput)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, red(err.Error()))
+		os.Exit(1)
+	}
+	bindAddress := host + ":" + port
+
+	if !c.Bool("no-open") {
+		u := "http://localhost:" + port
+		go func() {
+			<-time.After(time.Millisecond * 500)
+			openBrowserAt(u)
+		}()
+	}
+
+	fmt.Printf("Serving Bloblang playground at: http://%v\n", bindAddress)
+	if err := http.ListenAndServe(bindAddress, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, red("failed to serve playground: %v\n"), err)
+		os.Exit(1)
+	}
+	return nil
+}
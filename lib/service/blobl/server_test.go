@@ -0,0 +1,168 @@
+package blobl
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteForPlayground(t *testing.T) {
+	tests := map[string]struct {
+		req executeRequest
+		exp executeResponse
+	}{
+		"structured result": {
+			req: executeRequest{
+				Mapping: `root.foo = this.foo.uppercase()`,
+				Input:   `{"foo":"bar"}`,
+			},
+			exp: executeResponse{
+				Result:   "{\n  \"foo\": \"BAR\"\n}",
+				Metadata: map[string]string{},
+			},
+		},
+		"raw result with metadata": {
+			req: executeRequest{
+				Mapping: "root = meta(\"foo\") + content()\nmeta bar = \"baz\"",
+				Input:   `hello`,
+				Metadata: map[string]string{
+					"foo": "say ",
+				},
+			},
+			exp: executeResponse{
+				Result: "say hello",
+				Metadata: map[string]string{
+					"foo": "say ",
+					"bar": "baz",
+				},
+			},
+		},
+		"deleted": {
+			req: executeRequest{
+				Mapping: `root = deleted()`,
+				Input:   `{}`,
+			},
+			exp: executeResponse{
+				Deleted: true,
+			},
+		},
+		"parse error": {
+			req: executeRequest{
+				Mapping: `root = this.foo.`,
+				Input:   `{}`,
+			},
+			exp: executeResponse{
+				ParseError: "line 1 char 17: required: expected method or field path",
+			},
+		},
+		"mapping error": {
+			req: executeRequest{
+				Mapping: `root = this.foo.uppercase()`,
+				Input:   `{"foo":10}`,
+			},
+			exp: executeResponse{
+				MappingError: "failed to execute mapping query at line 1: expected string value, found number: 10",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.exp, executeForPlayground(test.req))
+		})
+	}
+}
+
+func TestPlaygroundServer(t *testing.T) {
+	mux, err := newPlaygroundMux("localhost", `root = this.foo`, `{"foo":"bar"}`)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), `root = this.foo`)
+	assert.Contains(t, string(body), `{&#34;foo&#34;:&#34;bar&#34;}`)
+
+	reqBytes, err := json.Marshal(executeRequest{
+		Mapping: `root = this.foo`,
+		Input:   `{"foo":"bar"}`,
+	})
+	require.NoError(t, err)
+
+	res, err = http.Post(server.URL+"/execute", "application/json", bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var execRes executeResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&execRes))
+	assert.Equal(t, "bar", execRes.Result)
+
+	res, err = http.Get(server.URL + "/execute")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/execute", bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	req.Header.Set("Origin", "http://evil.example.com")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/execute", bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	req.Header.Set("Origin", server.URL)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// A rebound domain has a matching origin but an unknown host.
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/execute", bytes.NewReader(reqBytes))
+	require.NoError(t, err)
+	req.Host = "evil.example.com:4195"
+	req.Header.Set("Origin", "http://evil.example.com:4195")
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestPlaygroundAllowedHost(t *testing.T) {
+	tests := []struct {
+		bindHost string
+		host     string
+		allowed  bool
+	}{
+		{bindHost: "localhost", host: "localhost:4195", allowed: true},
+		{bindHost: "localhost", host: "127.0.0.1:4195", allowed: true},
+		{bindHost: "localhost", host: "[::1]:4195", allowed: true},
+		{bindHost: "localhost", host: "evil.example.com:4195", allowed: false},
+		{bindHost: "localhost", host: "192.168.0.10:4195", allowed: false},
+		{bindHost: "blobl.internal", host: "blobl.internal:4195", allowed: true},
+		{bindHost: "0.0.0.0", host: "192.168.0.10:4195", allowed: true},
+		{bindHost: "0.0.0.0", host: "evil.example.com:4195", allowed: false},
+		{bindHost: "", host: "192.168.0.10", allowed: true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/execute", nil)
+		req.Host = test.host
+		assert.Equal(t, test.allowed, allowedHost(req, test.bindHost), "%v bound to %v", test.host, test.bindHost)
+	}
+}
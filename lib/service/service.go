@@ -187,6 +187,7 @@ func cmdService(
 		fmt.Printf("Failed to create logger: %v\n", err)
 		return 1
 	}
+	defer log.Close(logger)
 
 	if len(lints) > 0 {
 		lintlog := logger.NewModule(".linter")
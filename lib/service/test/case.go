@@ -49,11 +49,15 @@ func (i *InputPart) UnmarshalYAML(value *yaml.Node) error {
 
 // Case contains a definition of a single Benthos config test case.
 type Case struct {
-	Name             string            `yaml:"name"`
-	Environment      map[string]string `yaml:"environment"`
-	TargetProcessors string            `yaml:"target_processors"`
-	InputBatch       []InputPart       `yaml:"input_batch"`
-	OutputBatches    [][]ConditionsMap `yaml:"output_batches"`
+	Name             string                              `yaml:"name"`
+	Environment      map[string]string                   `yaml:"environment"`
+	TargetProcessors string                              `yaml:"target_processors"`
+	Stream           *StreamCase                         `yaml:"stream"`
+	Mocks            CaseMocks                           `yaml:"mocks"`
+	InputBatch       []InputPart                         `yaml:"input_batch"`
+	OutputBatches    [][]ConditionsMap                   `yaml:"output_batches"`
+	CacheAssertions  map[string]map[string]ConditionsMap `yaml:"cache_assertions"`
+	HTTPAssertions   []HTTPAssertion                     `yaml:"http_assertions"`
 
 	line int
 }
@@ -119,13 +123,16 @@ type ProcProvider interface {
 	Provide(jsonPtr string, environment map[string]string) ([]types.Processor, error)
 }
 
+func (c *Case) hasMocks() bool {
+	return len(c.Mocks.Caches) > 0 ||
+		len(c.Mocks.Processors) > 0 ||
+		len(c.Mocks.HTTP) > 0 ||
+		len(c.CacheAssertions) > 0 ||
+		len(c.HTTPAssertions) > 0
+}
+
 // Execute attempts to execute a test case against a Benthos configuration.
 func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error) {
-	var procSet []types.Processor
-	if procSet, err = provider.Provide(c.TargetProcessors, c.Environment); err != nil {
-		return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
-	}
-
 	reportFailure := func(reason string) {
 		failures = append(failures, CaseFailure{
 			Name:     c.Name,
@@ -134,6 +141,58 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 		})
 	}
 
+	var transport *mockTransport
+	resMocks := ResourceMocks{
+		Caches:     c.Mocks.Caches,
+		Processors: c.Mocks.Processors,
+	}
+	if len(c.Mocks.HTTP) > 0 || len(c.HTTPAssertions) > 0 {
+		transport = newMockTransport(c.Mocks.HTTP)
+		resMocks.HTTPRoundTripper = transport
+	}
+
+	// Assertions on mocked resources are checked once all output batch
+	// conditions have been checked.
+	checkMocks := func(mgr types.Manager) {
+		for _, f := range checkCacheAssertions(mgr, c.CacheAssertions) {
+			reportFailure(f)
+		}
+		if transport != nil {
+			for _, f := range checkHTTPAssertions(transport, c.HTTPAssertions) {
+				reportFailure(f)
+			}
+		}
+	}
+
+	if c.Stream != nil {
+		if err = c.executeStream(provider, resMocks, reportFailure, checkMocks); err != nil {
+			return nil, err
+		}
+		return
+	}
+
+	var procSet []types.Processor
+	if !c.hasMocks() {
+		if procSet, err = provider.Provide(c.TargetProcessors, c.Environment); err != nil {
+			return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
+		}
+	} else {
+		mockedProvider, ok := provider.(MockedProcProvider)
+		if !ok {
+			return nil, fmt.Errorf("processors provider %T does not support resource mocks", provider)
+		}
+
+		var mgr types.Manager
+		if procSet, mgr, err = mockedProvider.ProvideMocked(c.TargetProcessors, c.Environment, resMocks); err != nil {
+			return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
+		}
+		defer func() {
+			if err == nil {
+				checkMocks(mgr)
+			}
+		}()
+	}
+
 	parts := make([]types.Part, len(c.InputBatch))
 	for i, v := range c.InputBatch {
 		part := message.NewPart([]byte(v.Content))
@@ -156,16 +215,23 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 		return
 	}
 
-	if lExp, lAct := len(c.OutputBatches), len(outputBatches); lAct < lExp {
+	checkOutputBatches(c.OutputBatches, outputBatches, reportFailure)
+	return
+}
+
+// checkOutputBatches compares resulting batches of messages against the
+// expected conditions of each batch and reports any failures.
+func checkOutputBatches(expected [][]ConditionsMap, actual []types.Message, reportFailure func(string)) {
+	if lExp, lAct := len(expected), len(actual); lAct < lExp {
 		reportFailure(fmt.Sprintf("wrong batch count, expected %v, got %v", lExp, lAct))
 	}
 
-	for i, v := range outputBatches {
-		if len(c.OutputBatches) <= i {
+	for i, v := range actual {
+		if len(expected) <= i {
 			reportFailure(fmt.Sprintf("unexpected batch: %s", message.GetAllBytes(v)))
 			continue
 		}
-		expectedBatch := c.OutputBatches[i]
+		expectedBatch := expected[i]
 		if lExp, lAct := len(expectedBatch), v.Len(); lExp != lAct {
 			reportFailure(fmt.Sprintf("mismatch of output batch %v message counts, expected %v, got %v", i, lExp, lAct))
 		}
@@ -184,7 +250,6 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 			return nil
 		})
 	}
-	return
 }
 
 //------------------------------------------------------------------------------
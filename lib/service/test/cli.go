@@ -3,8 +3,10 @@ package test
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/fatih/color"
 	"github.com/urfave/cli/v2"
 )
 
@@ -34,6 +36,16 @@ func CliCommand(testSuffix string) *cli.Command {
 				Value: "",
 				Usage: "allow components to write logs at a provided level to stdout.",
 			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "default",
+				Usage: "the format to write test results in, options are: " + strings.Join(ReportFormats, ", ") + ".",
+			},
+			&cli.BoolFlag{
+				Name:  "coverage",
+				Value: false,
+				Usage: "report which processors and Bloblang mapping branches were executed by the tests of each config.",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if c.Bool("generate") {
@@ -45,18 +57,29 @@ func CliCommand(testSuffix string) *cli.Command {
 				}
 				os.Exit(0)
 			}
+			rep, err := newReporter(c.String("format"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to run tests: %v\n", err)
+				os.Exit(1)
+			}
+			logOut := os.Stdout
+			if _, isDefault := rep.(defaultReporter); !isDefault {
+				color.NoColor = true
+				// Keep machine readable results on stdout free of log lines.
+				logOut = os.Stderr
+			}
+			logger := log.Noop()
 			if logLevel := c.String("log"); len(logLevel) > 0 {
 				logConf := log.NewConfig()
 				logConf.LogLevel = logLevel
-				logger := log.New(os.Stdout, logConf)
-				if runAll(c.Args().Slice(), testSuffix, true, logger, c.StringSlice("resources")) {
-					os.Exit(0)
-				}
-			} else {
-				if runAll(c.Args().Slice(), testSuffix, true, log.Noop(), c.StringSlice("resources")) {
-					os.Exit(0)
+				if logger, err = log.NewV2(logOut, logConf); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+					os.Exit(1)
 				}
 			}
+			if runAll(c.Args().Slice(), testSuffix, true, logger, c.StringSlice("resources"), rep, c.Bool("coverage")) {
+				os.Exit(0)
+			}
 			os.Exit(1)
 			return nil
 		},
@@ -2,6 +2,7 @@ package test
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -155,17 +156,38 @@ func Run(path, testSuffix string, lint bool) bool {
 // a config file, a config files test definition file, a directory, or the
 // wildcard pattern './...'.
 func RunAll(paths []string, testSuffix string, lint bool) bool {
-	return runAll(paths, testSuffix, lint, log.Noop(), nil)
+	return runAll(paths, testSuffix, lint, log.Noop(), nil, defaultReporter{}, false)
 }
 
 // RunAllWithLogger executes the test command for a slice of paths. The path can
 // either be a config file, a config files test definition file, a directory, or
 // the wildcard pattern './...'.
 func RunAllWithLogger(paths []string, testSuffix string, lint bool, logger log.Modular) bool {
-	return runAll(paths, testSuffix, lint, logger, nil)
+	return runAll(paths, testSuffix, lint, logger, nil, defaultReporter{}, false)
 }
 
-func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, resourcesPaths []string) bool {
+func runAll(
+	paths []string,
+	testSuffix string,
+	lint bool,
+	logger log.Modular,
+	resourcesPaths []string,
+	rep reporter,
+	coverage bool,
+) bool {
+	return runAllTo(os.Stdout, paths, testSuffix, lint, logger, resourcesPaths, rep, coverage)
+}
+
+func runAllTo(
+	w io.Writer,
+	paths []string,
+	testSuffix string,
+	lint bool,
+	logger log.Modular,
+	resourcesPaths []string,
+	rep reporter,
+	coverage bool,
+) bool {
 	targets := map[string]Definition{}
 
 	for _, path := range paths {
@@ -182,78 +204,50 @@ func runAll(paths []string, testSuffix string, lint bool, logger log.Modular, re
 	}
 
 	if len(targets) == 0 {
-		fmt.Printf("%v\n", yellow("No tests were found"))
+		noteW := w
+		if _, isDefault := rep.(defaultReporter); !isDefault {
+			noteW = os.Stderr
+		}
+		fmt.Fprintf(noteW, "%v\n", yellow("No tests were found"))
 		return false
 	}
 
-	type failedTarget struct {
-		target string
-		lints  []string
-		cases  []CaseFailure
-	}
-	fails := []failedTarget{}
-
 	targetPaths := make([]string, 0, len(targets))
 	for k := range targets {
 		targetPaths = append(targetPaths, k)
 	}
 	sort.Strings(targetPaths)
 
-	var err error
+	passed := true
+	results := make([]targetResult, 0, len(targetPaths))
 	for _, target := range targetPaths {
-		var lints []string
-		var failCases []CaseFailure
+		result := targetResult{path: target}
+		if coverage {
+			result.coverage = NewCoverage()
+		}
+
+		var err error
 		if lint {
-			if lints, err = lintTarget(target, testSuffix); err != nil {
+			if result.lints, err = lintTarget(target, testSuffix); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 				return false
 			}
 		}
-		if failCases, err = targets[target].execute(target, resourcesPaths, logger); err != nil {
+		if result.cases, err = targets[target].executeCases(target, resourcesPaths, logger, result.coverage); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to execute test target '%v': %v\n", target, err)
 			return false
 		}
-		if len(lints) > 0 || len(failCases) > 0 {
-			fails = append(fails, failedTarget{
-				target: target,
-				lints:  lints,
-				cases:  failCases,
-			})
-			fmt.Printf("Test '%v' %v\n", target, red("failed"))
-		} else {
-			fmt.Printf("Test '%v' %v\n", target, green("succeeded"))
+		if result.failed() {
+			passed = false
 		}
+		rep.target(w, result)
+		results = append(results, result)
 	}
-	if len(fails) > 0 {
-		fmt.Printf("\nFailures:\n\n")
-		for i, fail := range fails {
-			if i > 0 {
-				fmt.Println("")
-			}
-			fmt.Printf("--- %v ---\n\n", fail.target)
-			for _, lint := range fail.lints {
-				fmt.Printf("Lint: %v\n", lint)
-			}
-			if len(fail.cases) > 0 {
-				if len(fail.lints) > 0 {
-					fmt.Println("")
-				}
-				var namePrev string
-				for i, fail := range fail.cases {
-					if namePrev != fail.Name {
-						if i > 0 {
-							fmt.Println("")
-						}
-						fmt.Printf("%v [line %v]:\n", fail.Name, fail.TestLine)
-						namePrev = fail.Name
-					}
-					fmt.Println(fail.Reason)
-				}
-			}
-		}
+	if err := rep.finish(w, results); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write test results: %v\n", err)
 		return false
 	}
-	return true
+	return passed
 }
 
 //------------------------------------------------------------------------------
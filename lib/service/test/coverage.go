@@ -0,0 +1,115 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// CoverageStat describes a component or branch of a config and the number of
+// times it was executed by test cases.
+type CoverageStat struct {
+	Label string `json:"label"`
+	Hits  int64  `json:"hits"`
+}
+
+// Coverage records which processors and Bloblang mapping branches (match cases
+// and if arms) of a config are executed by its test cases.
+type Coverage struct {
+	stats      *metrics.Local
+	branches   *query.BranchCoverage
+	processors []string
+}
+
+// NewCoverage creates an empty coverage recorder.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		stats:    metrics.NewLocal(),
+		branches: query.NewBranchCoverage(),
+	}
+}
+
+// declareProcessors adds the paths of processors within a config to the
+// coverage, which ensures that processors are reported even when no test case
+// was able to construct them.
+func (c *Coverage) declareProcessors(paths []string) {
+	c.processors = append(c.processors, paths...)
+}
+
+// Processors returns the processors of a config, identified by their metrics
+// path, and the number of times each was executed.
+func (c *Coverage) Processors() []CoverageStat {
+	stats := []CoverageStat{}
+	seen := map[string]struct{}{}
+	for k, v := range c.stats.GetCounters() {
+		if !strings.HasSuffix(k, ".count") || !strings.Contains(k, "processor") {
+			continue
+		}
+		label := strings.TrimSuffix(k, ".count")
+		seen[label] = struct{}{}
+		stats = append(stats, CoverageStat{
+			Label: label,
+			Hits:  v,
+		})
+	}
+	for _, path := range c.processors {
+		if _, exists := seen[path]; !exists {
+			seen[path] = struct{}{}
+			stats = append(stats, CoverageStat{Label: path})
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Label < stats[j].Label
+	})
+	return stats
+}
+
+// Branches returns the branches of Bloblang match and if expressions parsed by
+// test cases, in the order they were parsed, and the number of times each was
+// executed.
+func (c *Coverage) Branches() []CoverageStat {
+	stats := []CoverageStat{}
+	for _, b := range c.branches.Branches() {
+		stats = append(stats, CoverageStat{
+			Label: b.Label,
+			Hits:  b.Hits(),
+		})
+	}
+	return stats
+}
+
+//------------------------------------------------------------------------------
+
+func countCovered(stats []CoverageStat) (covered int) {
+	for _, s := range stats {
+		if s.Hits > 0 {
+			covered++
+		}
+	}
+	return
+}
+
+// writeCoverage writes a human readable coverage report, where each line is
+// prefixed with a string.
+func writeCoverage(w io.Writer, prefix string, c *Coverage) {
+	section := func(name string, stats []CoverageStat) {
+		fmt.Fprintf(w, "%v%v: %v/%v executed\n", prefix, name, countCovered(stats), len(stats))
+		for _, s := range stats {
+			if s.Hits > 0 {
+				fmt.Fprintf(w, "%v  %v: %v\n", prefix, s.Label, s.Hits)
+			} else {
+				fmt.Fprintf(w, "%v  %v: %v\n", prefix, s.Label, red("not executed"))
+			}
+		}
+	}
+	section("Processors", c.Processors())
+	section("Bloblang branches", c.Branches())
+}
+
+//------------------------------------------------------------------------------
@@ -2,6 +2,7 @@ package test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"golang.org/x/sync/errgroup"
@@ -37,46 +38,73 @@ func (d Definition) Execute(filepath string) ([]CaseFailure, error) {
 	return d.execute(filepath, nil, log.Noop())
 }
 
+// ExecuteWithCoverage attempts to run a test definition on a target config
+// file, with a logger, recording the processors and Bloblang branches executed
+// by the test cases in a coverage recorder. Returns the result of each test
+// case or an error.
+func (d Definition) ExecuteWithCoverage(filepath string, logger log.Modular, cov *Coverage) ([]CaseResult, error) {
+	return d.executeCases(filepath, nil, logger, cov)
+}
+
 func (d Definition) execute(filepath string, resourcesPaths []string, logger log.Modular) ([]CaseFailure, error) {
-	procsProvider := NewProcessorsProvider(
-		filepath,
+	results, err := d.executeCases(filepath, resourcesPaths, logger, nil)
+	if err != nil {
+		return nil, err
+	}
+	var totalFailures []CaseFailure
+	for _, r := range results {
+		totalFailures = append(totalFailures, r.Failures...)
+	}
+	return totalFailures, nil
+}
+
+func (d Definition) executeCases(filepath string, resourcesPaths []string, logger log.Modular, cov *Coverage) ([]CaseResult, error) {
+	opts := []func(*ProcessorsProvider){
 		OptAddResourcesPaths(resourcesPaths),
 		OptProcessorsProviderSetLogger(logger),
-	)
+	}
+	if cov != nil {
+		opts = append(opts,
+			OptProcessorsProviderSetStats(cov.stats),
+			OptProcessorsProviderSetBranchCoverage(cov.branches),
+		)
+	}
+	procsProvider := NewProcessorsProvider(filepath, opts...)
 	if d.Parallel {
 		// Warm the cache of processor configs.
 		for _, c := range d.Cases {
+			if c.Stream != nil {
+				continue
+			}
 			if _, err := procsProvider.getConfs(c.TargetProcessors, c.Environment); err != nil {
 				return nil, err
 			}
 		}
 	}
 
-	var totalFailures []CaseFailure
+	results := make([]CaseResult, len(d.Cases))
 	if !d.Parallel {
 		for i, c := range d.Cases {
 			cleanupEnv := setEnvironment(c.Environment)
-			failures, err := c.Execute(procsProvider)
+			result, err := c.executeTimed(procsProvider)
+			cleanupEnv()
 			if err != nil {
-				cleanupEnv()
 				return nil, fmt.Errorf("test case %v failed: %v", i, err)
 			}
-			totalFailures = append(totalFailures, failures...)
-			cleanupEnv()
+			results[i] = result
 		}
 	} else {
 		var g errgroup.Group
 
-		failureSlices := make([][]CaseFailure, len(d.Cases))
 		for i, c := range d.Cases {
 			i := i
 			c := c
 			g.Go(func() error {
-				failures, err := c.Execute(procsProvider)
+				result, err := c.executeTimed(procsProvider)
 				if err != nil {
 					return fmt.Errorf("test case %v failed: %v", i, err)
 				}
-				failureSlices[i] = failures
+				results[i] = result
 				return nil
 			})
 		}
@@ -85,13 +113,62 @@ func (d Definition) execute(filepath string, resourcesPaths []string, logger log
 		if err := g.Wait(); err != nil {
 			return nil, err
 		}
+	}
 
-		for _, fs := range failureSlices {
-			totalFailures = append(totalFailures, fs...)
+	if cov != nil {
+		if err := declareCoverage(procsProvider, cov); err != nil {
+			return nil, err
 		}
 	}
+	return results, nil
+}
 
-	return totalFailures, nil
+// declareCoverage constructs every processor of the target config, which
+// registers processors and Bloblang branches that were not constructed by any
+// test case with the coverage recorder so that they are reported as not
+// executed.
+func declareCoverage(provider *ProcessorsProvider, cov *Coverage) error {
+	paths, err := provider.processorPaths()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		procs, err := provider.Provide(path, nil)
+		if err != nil {
+			// Processors that cannot be constructed without the environment
+			// of a test case are still declared by their path.
+			continue
+		}
+		for _, proc := range procs {
+			proc.CloseAsync()
+		}
+		for _, proc := range procs {
+			_ = proc.WaitForClose(time.Second)
+		}
+	}
+	cov.declareProcessors(paths)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// CaseResult is the outcome of executing a test case.
+type CaseResult struct {
+	Name     string
+	TestLine int
+	Duration time.Duration
+	Failures []CaseFailure
+}
+
+func (c *Case) executeTimed(provider ProcProvider) (CaseResult, error) {
+	started := time.Now()
+	failures, err := c.Execute(provider)
+	return CaseResult{
+		Name:     c.Name,
+		TestLine: c.line,
+		Duration: time.Since(started),
+		Failures: failures,
+	}, err
 }
 
 //------------------------------------------------------------------------------
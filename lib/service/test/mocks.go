@@ -0,0 +1,239 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/metadata"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// CaseMocks defines resources that should be replaced with mocks during the
+// execution of a test case.
+type CaseMocks struct {
+	// Caches maps cache resource names to key/value pairs that the mocked
+	// cache is seeded with. Mocked caches are in memory and replace any cache
+	// resource of the same name.
+	Caches map[string]map[string]string `yaml:"caches"`
+
+	// Processors maps processor resource names to processor configs that
+	// replace them.
+	Processors map[string]processor.Config `yaml:"processors"`
+
+	// HTTP is a list of stubbed HTTP responses. When mocks are provided all HTTP
+	// requests made by components are intercepted, and requests that do not
+	// match a mock result in an error.
+	HTTP []HTTPMock `yaml:"http"`
+}
+
+// HTTPMock defines a stubbed response for HTTP requests matching a method and
+// URL.
+type HTTPMock struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Status  int               `yaml:"status"`
+	Body    string            `yaml:"body"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+// HTTPAssertion defines conditions to be checked against the requests that
+// were made to a mocked HTTP endpoint.
+type HTTPAssertion struct {
+	Method string `yaml:"method"`
+
+	URL string `yaml:"url"`
+
+	// Count, when set, is the exact number of requests expected.
+	Count *int `yaml:"count"`
+
+	// Requests is a list of conditions to check against each request in the
+	// order they were made, where the content is the request body and the
+	// metadata is the request headers.
+	Requests []ConditionsMap `yaml:"requests"`
+}
+
+// ResourceMocks contains resource mocks to be applied by a MockedProcProvider.
+type ResourceMocks struct {
+	Caches           map[string]map[string]string
+	Processors       map[string]processor.Config
+	HTTPRoundTripper http.RoundTripper
+}
+
+// MockedProcProvider is a ProcProvider that is able to replace the resources of
+// a config with mocks.
+type MockedProcProvider interface {
+	ProcProvider
+
+	// ProvideMocked returns compiled processors along with the resources
+	// manager they were created with, where resources have been replaced
+	// according to the mocks provided.
+	ProvideMocked(jsonPtr string, environment map[string]string, mocks ResourceMocks) ([]types.Processor, types.Manager, error)
+}
+
+//------------------------------------------------------------------------------
+
+type recordedRequest struct {
+	method  string
+	url     string
+	body    []byte
+	headers http.Header
+}
+
+// mockTransport is an http.RoundTripper that responds with stubbed responses
+// and records all requests made.
+type mockTransport struct {
+	mocks []HTTPMock
+
+	mut      sync.Mutex
+	requests []recordedRequest
+}
+
+func newMockTransport(mocks []HTTPMock) *mockTransport {
+	return &mockTransport{mocks: mocks}
+}
+
+func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	urlStr := req.URL.String()
+
+	m.mut.Lock()
+	m.requests = append(m.requests, recordedRequest{
+		method:  req.Method,
+		url:     urlStr,
+		body:    body,
+		headers: req.Header.Clone(),
+	})
+	m.mut.Unlock()
+
+	for _, mock := range m.mocks {
+		if !httpMatches(mock.Method, mock.URL, req.Method, urlStr) {
+			continue
+		}
+		status := mock.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		res := &http.Response{
+			Status:        fmt.Sprintf("%v %v", status, http.StatusText(status)),
+			StatusCode:    status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          ioutil.NopCloser(bytes.NewReader([]byte(mock.Body))),
+			ContentLength: int64(len(mock.Body)),
+			Request:       req,
+		}
+		for k, v := range mock.Headers {
+			res.Header.Set(k, v)
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("no HTTP mock matches request %v %v", req.Method, urlStr)
+}
+
+func httpMatches(expMethod, expURL, method, url string) bool {
+	if expMethod != "" && !strings.EqualFold(expMethod, method) {
+		return false
+	}
+	return expURL == url
+}
+
+func (m *mockTransport) requestsMatching(method, url string) []recordedRequest {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	var matched []recordedRequest
+	for _, r := range m.requests {
+		if httpMatches(method, url, r.method, r.url) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+//------------------------------------------------------------------------------
+
+func checkCacheAssertions(mgr types.Manager, assertions map[string]map[string]ConditionsMap) (failures []string) {
+	cacheNames := make([]string, 0, len(assertions))
+	for k := range assertions {
+		cacheNames = append(cacheNames, k)
+	}
+	sort.Strings(cacheNames)
+
+	for _, cacheName := range cacheNames {
+		c, err := mgr.GetCache(cacheName)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("cache '%v': %v", cacheName, err))
+			continue
+		}
+
+		keys := make([]string, 0, len(assertions[cacheName]))
+		for k := range assertions[cacheName] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value, err := c.Get(key)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("cache '%v' key '%v': %v", cacheName, key, err))
+				continue
+			}
+			for _, condErr := range assertions[cacheName][key].CheckAll(message.NewPart(value)) {
+				failures = append(failures, fmt.Sprintf("cache '%v' key '%v': %v", cacheName, key, condErr))
+			}
+		}
+	}
+	return
+}
+
+func checkHTTPAssertions(transport *mockTransport, assertions []HTTPAssertion) (failures []string) {
+	for _, a := range assertions {
+		reqs := transport.requestsMatching(a.Method, a.URL)
+
+		target := a.URL
+		if a.Method != "" {
+			target = a.Method + " " + target
+		}
+
+		if a.Count != nil && *a.Count != len(reqs) {
+			failures = append(failures, fmt.Sprintf("http %v: wrong request count, expected %v, got %v", target, *a.Count, len(reqs)))
+		}
+		for i, conds := range a.Requests {
+			if i >= len(reqs) {
+				failures = append(failures, fmt.Sprintf("http %v: request %v was not made", target, i))
+				continue
+			}
+			part := message.NewPart(reqs[i].body)
+			meta := map[string]string{}
+			for k := range reqs[i].headers {
+				meta[k] = reqs[i].headers.Get(k)
+			}
+			part.SetMetadata(metadata.New(meta))
+			for _, condErr := range conds.CheckAll(part) {
+				failures = append(failures, fmt.Sprintf("http %v: request %v: %v", target, i, condErr))
+			}
+		}
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestCaseMocks(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+resources:
+  caches:
+    foocache:
+      redis:
+        url: tcp://localhost:6379
+  processors:
+    lookup:
+      sql:
+        driver: mysql
+        data_source_name: foo:bar@tcp(localhost:3306)/baz
+        query: "SELECT * FROM footable WHERE id = ?;"
+        args: [ "${! content() }" ]
+
+pipeline:
+  processors:
+    - cache:
+        resource: foocache
+        operator: get
+        key: ${! content() }
+    - resource: lookup
+    - http:
+        url: http://example.com/enrich
+        verb: POST
+    - cache:
+        resource: foocache
+        operator: set
+        key: result
+        value: ${! content() }
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	var def Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: mocked resources
+    mocks:
+      caches:
+        foocache:
+          foo: bar
+      processors:
+        lookup:
+          bloblang: 'root = content().uppercase()'
+      http:
+        - method: POST
+          url: http://example.com/enrich
+          body: '{"enriched":true}'
+          headers:
+            Content-Type: application/json
+    input_batch:
+      - content: foo
+    output_batches:
+      - - json_equals: { "enriched": true }
+    cache_assertions:
+      foocache:
+        result:
+          json_equals: { "enriched": true }
+        foo:
+          content_equals: bar
+    http_assertions:
+      - method: POST
+        url: http://example.com/enrich
+        count: 1
+        requests:
+          - content_equals: BAR
+
+  - name: failed mock assertions
+    mocks:
+      caches:
+        foocache:
+          foo: bar
+      processors:
+        lookup:
+          bloblang: 'root = content().uppercase()'
+      http:
+        - url: http://example.com/enrich
+          body: nope
+    input_batch:
+      - content: foo
+    output_batches: []
+    cache_assertions:
+      foocache:
+        result:
+          content_equals: yep
+        missing:
+          content_equals: nope
+    http_assertions:
+      - method: POST
+        url: http://example.com/enrich
+        count: 2
+        requests:
+          - content_equals: bar
+`), &def))
+
+	failures, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+	require.NoError(t, err)
+
+	var failureStrs []string
+	for _, f := range failures {
+		failureStrs = append(failureStrs, f.Reason)
+	}
+	assert.Equal(t, []string{
+		"unexpected batch: [nope]",
+		"cache 'foocache' key 'missing': key does not exist",
+		"cache 'foocache' key 'result': content_equals: content mismatch\n  expected: yep\n  received: nope",
+		"http POST http://example.com/enrich: wrong request count, expected 2, got 1",
+		"http POST http://example.com/enrich: request 0: content_equals: content mismatch\n  expected: bar\n  received: BAR",
+	}, failureStrs)
+}
+
+func TestCaseMocksNoProviderSupport(t *testing.T) {
+	c := NewCase()
+	c.Mocks.Caches = map[string]map[string]string{
+		"foo": {"bar": "baz"},
+	}
+
+	_, err := c.Execute(mockProvider{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support resource mocks")
+}
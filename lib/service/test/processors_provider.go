@@ -5,7 +5,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/manager"
@@ -20,6 +24,7 @@ import (
 type cachedConfig struct {
 	mgr   manager.Config
 	procs []processor.Config
+	paths []string
 }
 
 // ProcessorsProvider consumes a Benthos config and, given a JSON Pointer,
@@ -29,7 +34,9 @@ type ProcessorsProvider struct {
 	resourcesPaths []string
 	cachedConfigs  map[string]cachedConfig
 
-	logger log.Modular
+	logger         log.Modular
+	stats          metrics.Type
+	branchCoverage *query.BranchCoverage
 }
 
 // NewProcessorsProvider returns a new processors provider aimed at a filepath.
@@ -38,6 +45,7 @@ func NewProcessorsProvider(targetPath string, opts ...func(*ProcessorsProvider))
 		targetPath:    targetPath,
 		cachedConfigs: map[string]cachedConfig{},
 		logger:        log.Noop(),
+		stats:         metrics.Noop(),
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -61,6 +69,23 @@ func OptProcessorsProviderSetLogger(logger log.Modular) func(*ProcessorsProvider
 	}
 }
 
+// OptProcessorsProviderSetStats sets the metrics aggregator used by tested
+// components. The metrics of each processor extracted from a config are
+// namespaced by the JSON Pointer of the processor.
+func OptProcessorsProviderSetStats(stats metrics.Type) func(*ProcessorsProvider) {
+	return func(p *ProcessorsProvider) {
+		p.stats = stats
+	}
+}
+
+// OptProcessorsProviderSetBranchCoverage sets a recorder for the branches of
+// Bloblang mappings parsed by tested components.
+func OptProcessorsProviderSetBranchCoverage(c *query.BranchCoverage) func(*ProcessorsProvider) {
+	return func(p *ProcessorsProvider) {
+		p.branchCoverage = c
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Provide attempts to extract an array of processors from a Benthos config. If
@@ -74,21 +99,139 @@ func (p *ProcessorsProvider) Provide(jsonPtr string, environment map[string]stri
 	return p.initProcs(confs)
 }
 
+// ProvideMocked attempts to extract an array of processors from a Benthos
+// config, where the resources of the config are replaced with mocks. The
+// resources manager used by the processors is returned so that mocked
+// resources can be inspected once the processors have been executed.
+func (p *ProcessorsProvider) ProvideMocked(jsonPtr string, environment map[string]string, mocks ResourceMocks) ([]types.Processor, types.Manager, error) {
+	confs, err := p.getConfs(jsonPtr, environment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if confs.mgr, err = applyResourceMocks(confs.mgr, mocks); err != nil {
+		return nil, nil, err
+	}
+	return p.initProcsWithManager(confs, p.managerOpts(mocks)...)
+}
+
+// applyResourceMocks returns a copy of a resources config where resources have
+// been replaced with mocks.
+func applyResourceMocks(conf manager.Config, mocks ResourceMocks) (manager.Config, error) {
+	mgrConf := manager.NewConfig()
+	if err := mgrConf.AddFrom(&conf); err != nil {
+		return mgrConf, err
+	}
+	for k, values := range mocks.Caches {
+		cacheConf := cache.NewConfig()
+		cacheConf.Type = cache.TypeMemory
+		cacheConf.Memory.InitValues = values
+		mgrConf.Caches[k] = cacheConf
+	}
+	for k, procConf := range mocks.Processors {
+		mgrConf.Processors[k] = procConf
+	}
+	return mgrConf, nil
+}
+
+// processorPaths returns the JSON Pointers of all processors within the target
+// config, including those of inputs, outputs and resources, but excluding the
+// child processors of other processors.
+func (p *ProcessorsProvider) processorPaths() ([]string, error) {
+	configBytes, err := config.ReadWithJSONPointers(p.targetPath, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+	var root interface{}
+	if err = yaml.Unmarshal(configBytes, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", p.targetPath, err)
+	}
+	var paths []string
+	walkprocessorPaths("", root, &paths)
+	return paths, nil
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func walkprocessorPaths(ptr string, v interface{}, paths *[]string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ptr == "" && k == "tests" {
+				continue
+			}
+			childPtr := ptr + "/" + jsonPointerEscaper.Replace(k)
+			if k == "processors" {
+				switch procs := t[k].(type) {
+				case []interface{}:
+					for i := range procs {
+						*paths = append(*paths, fmt.Sprintf("%v/%v", childPtr, i))
+					}
+					continue
+				case map[string]interface{}:
+					if ptr == "/resources" {
+						names := make([]string, 0, len(procs))
+						for name := range procs {
+							names = append(names, name)
+						}
+						sort.Strings(names)
+						for _, name := range names {
+							*paths = append(*paths, childPtr+"/"+jsonPointerEscaper.Replace(name))
+						}
+						continue
+					}
+				}
+			}
+			walkprocessorPaths(childPtr, t[k], paths)
+		}
+	case []interface{}:
+		for i, child := range t {
+			walkprocessorPaths(fmt.Sprintf("%v/%v", ptr, i), child, paths)
+		}
+	}
+}
+
+func (p *ProcessorsProvider) managerOpts(mocks ResourceMocks) []func(*manager.Type) {
+	mgrOpts := mocks.managerOpts()
+	if p.branchCoverage != nil {
+		mgrOpts = append(mgrOpts, manager.OptSetBloblangBranchCoverage(p.branchCoverage))
+	}
+	return mgrOpts
+}
+
+func (r ResourceMocks) managerOpts() []func(*manager.Type) {
+	var mgrOpts []func(*manager.Type)
+	if r.HTTPRoundTripper != nil {
+		mgrOpts = append(mgrOpts, manager.OptSetHTTPRoundTripper(r.HTTPRoundTripper))
+	}
+	return mgrOpts
+}
+
 //------------------------------------------------------------------------------
 
 func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, error) {
-	mgr, err := manager.New(confs.mgr, types.NoopMgr(), p.logger, metrics.Noop())
+	procs, _, err := p.initProcsWithManager(confs, p.managerOpts(ResourceMocks{})...)
+	return procs, err
+}
+
+func (p *ProcessorsProvider) initProcsWithManager(confs cachedConfig, mgrOpts ...func(*manager.Type)) ([]types.Processor, types.Manager, error) {
+	mgr, err := manager.New(confs.mgr, types.NoopMgr(), p.logger, p.stats, mgrOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+		return nil, nil, fmt.Errorf("failed to initialise resources: %v", err)
 	}
 
 	procs := make([]types.Processor, len(confs.procs))
 	for i, conf := range confs.procs {
-		if procs[i], err = processor.New(conf, mgr, p.logger, metrics.Noop()); err != nil {
-			return nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
+		if procs[i], err = processor.New(conf, mgr, p.logger, metrics.Namespaced(p.stats, confs.paths[i])); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
 		}
 	}
-	return procs, nil
+	return procs, mgr, nil
 }
 
 func confTargetID(jsonPtr string, environment map[string]string) string {
@@ -144,6 +287,27 @@ func resolveProcessorsPointer(targetFile string, jsonPtr string) (filePath, proc
 	return
 }
 
+func (p *ProcessorsProvider) addExtraResources(mgrConf *manager.Config) error {
+	for _, path := range p.resourcesPaths {
+		resourceBytes, err := config.ReadWithJSONPointers(path, true)
+		if err != nil {
+			return fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+		}
+		extraMgrWrapper := struct {
+			Manager manager.Config `yaml:"resources"`
+		}{
+			Manager: manager.NewConfig(),
+		}
+		if err = yaml.Unmarshal(resourceBytes, &extraMgrWrapper); err != nil {
+			return fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
+		}
+		if err = mgrConf.AddFrom(&extraMgrWrapper.Manager); err != nil {
+			return fmt.Errorf("failed to merge resources from '%v': %v", path, err)
+		}
+	}
+	return nil
+}
+
 func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]string) (cachedConfig, error) {
 	cacheKey := confTargetID(jsonPtr, environment)
 
@@ -184,22 +348,8 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
-	for _, path := range p.resourcesPaths {
-		resourceBytes, err := config.ReadWithJSONPointers(path, true)
-		if err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
-		}
-		extraMgrWrapper := struct {
-			Manager manager.Config `yaml:"resources"`
-		}{
-			Manager: manager.NewConfig(),
-		}
-		if err = yaml.Unmarshal(resourceBytes, &extraMgrWrapper); err != nil {
-			return confs, fmt.Errorf("failed to parse resources config file '%v': %v", path, err)
-		}
-		if err = mgrWrapper.Manager.AddFrom(&extraMgrWrapper.Manager); err != nil {
-			return confs, fmt.Errorf("failed to merge resources from '%v': %v", path, err)
-		}
+	if err = p.addExtraResources(&mgrWrapper.Manager); err != nil {
+		return confs, err
 	}
 
 	confs.mgr = mgrWrapper.Manager
@@ -224,12 +374,16 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		if err = yaml.Unmarshal(rawBytes, &confs.procs); err != nil {
 			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
 		}
+		for i := range confs.procs {
+			confs.paths = append(confs.paths, fmt.Sprintf("%v/%v", jsonPtr, i))
+		}
 	default:
 		var procConf processor.Config
 		if err = yaml.Unmarshal(rawBytes, &procConf); err != nil {
 			return confs, fmt.Errorf("failed to resolve case processors from '%v': %v", targetPath, err)
 		}
 		confs.procs = append(confs.procs, procConf)
+		confs.paths = append(confs.paths, jsonPtr)
 	}
 
 	p.cachedConfigs[cacheKey] = confs
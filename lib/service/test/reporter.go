@@ -0,0 +1,374 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// targetResult is the outcome of executing the test definition of a config.
+type targetResult struct {
+	path     string
+	lints    []string
+	cases    []CaseResult
+	coverage *Coverage
+}
+
+func (t targetResult) failed() bool {
+	if len(t.lints) > 0 {
+		return true
+	}
+	for _, c := range t.cases {
+		if len(c.Failures) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// reporter writes the results of test targets in a given format.
+type reporter interface {
+	// Called after each target has been executed.
+	target(w io.Writer, result targetResult)
+
+	// Called once all targets have been executed.
+	finish(w io.Writer, results []targetResult) error
+}
+
+// ReportFormats lists the formats that test results can be written in.
+var ReportFormats = []string{"default", "junit", "tap", "json"}
+
+func newReporter(format string) (reporter, error) {
+	switch format {
+	case "", "default":
+		return defaultReporter{}, nil
+	case "junit":
+		return junitReporter{}, nil
+	case "tap":
+		return tapReporter{}, nil
+	case "json":
+		return jsonReporter{}, nil
+	}
+	return nil, fmt.Errorf("format '%v' was not recognised, expected one of: %v", format, strings.Join(ReportFormats, ", "))
+}
+
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func formatMilliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+//------------------------------------------------------------------------------
+
+type defaultReporter struct{}
+
+func (defaultReporter) target(w io.Writer, result targetResult) {
+	if result.failed() {
+		fmt.Fprintf(w, "Test '%v' %v\n", result.path, red("failed"))
+	} else {
+		fmt.Fprintf(w, "Test '%v' %v\n", result.path, green("succeeded"))
+	}
+}
+
+func (defaultReporter) finish(w io.Writer, results []targetResult) error {
+	var fails []targetResult
+	for _, r := range results {
+		if r.failed() {
+			fails = append(fails, r)
+		}
+	}
+	if len(fails) > 0 {
+		fmt.Fprintf(w, "\nFailures:\n\n")
+		for i, fail := range fails {
+			if i > 0 {
+				fmt.Fprintln(w, "")
+			}
+			fmt.Fprintf(w, "--- %v ---\n\n", fail.path)
+			for _, lint := range fail.lints {
+				fmt.Fprintf(w, "Lint: %v\n", lint)
+			}
+			var caseFails []CaseFailure
+			for _, c := range fail.cases {
+				caseFails = append(caseFails, c.Failures...)
+			}
+			if len(caseFails) > 0 {
+				if len(fail.lints) > 0 {
+					fmt.Fprintln(w, "")
+				}
+				var namePrev string
+				for i, fail := range caseFails {
+					if namePrev != fail.Name {
+						if i > 0 {
+							fmt.Fprintln(w, "")
+						}
+						fmt.Fprintf(w, "%v [line %v]:\n", fail.Name, fail.TestLine)
+						namePrev = fail.Name
+					}
+					fmt.Fprintln(w, fail.Reason)
+				}
+			}
+		}
+	}
+	for _, r := range results {
+		if r.coverage == nil {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- %v coverage ---\n\n", r.path)
+		writeCoverage(w, "", r.coverage)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitReporter struct{}
+
+func (junitReporter) target(w io.Writer, result targetResult) {}
+
+func (junitReporter) finish(w io.Writer, results []targetResult) error {
+	var total time.Duration
+	suites := junitTestSuites{}
+	for _, r := range results {
+		var suiteTime time.Duration
+		suite := junitTestSuite{Name: r.path}
+		if len(r.lints) > 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "lint",
+				Classname: r.path,
+				Time:      formatSeconds(0),
+				Failure: &junitFailure{
+					Message: fmt.Sprintf("%v lint errors", len(r.lints)),
+					Text:    strings.Join(r.lints, "\n"),
+				},
+			})
+			suite.Failures++
+		}
+		for _, c := range r.cases {
+			tc := junitTestCase{
+				Name:      c.Name,
+				Classname: r.path,
+				Time:      formatSeconds(c.Duration),
+			}
+			if len(c.Failures) > 0 {
+				reasons := make([]string, len(c.Failures))
+				for i, f := range c.Failures {
+					reasons[i] = f.Reason
+				}
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("%v failures [line %v]", len(c.Failures), c.TestLine),
+					Text:    strings.Join(reasons, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+			suiteTime += c.Duration
+		}
+		if r.coverage != nil {
+			var buf bytes.Buffer
+			writeCoverage(&buf, "", r.coverage)
+			suite.SystemOut = buf.String()
+		}
+		suite.Tests = len(suite.Cases)
+		suite.Time = formatSeconds(suiteTime)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+		total += suiteTime
+	}
+	suites.Time = formatSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+type tapReporter struct{}
+
+func (tapReporter) target(w io.Writer, result targetResult) {}
+
+func writeTAPDiagnostics(w io.Writer, diag interface{}) error {
+	diagBytes, err := yaml.Marshal(diag)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "  ---")
+	for _, line := range strings.Split(strings.TrimSuffix(string(diagBytes), "\n"), "\n") {
+		fmt.Fprintf(w, "  %v\n", line)
+	}
+	fmt.Fprintln(w, "  ...")
+	return nil
+}
+
+func (tapReporter) finish(w io.Writer, results []targetResult) error {
+	total := 0
+	for _, r := range results {
+		if len(r.lints) > 0 {
+			total++
+		}
+		total += len(r.cases)
+	}
+
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%v\n", total)
+
+	n := 0
+	for _, r := range results {
+		if len(r.lints) > 0 {
+			n++
+			fmt.Fprintf(w, "not ok %v - %v: lint\n", n, r.path)
+			if err := writeTAPDiagnostics(w, map[string]interface{}{
+				"lints": r.lints,
+			}); err != nil {
+				return err
+			}
+		}
+		for _, c := range r.cases {
+			n++
+			if len(c.Failures) == 0 {
+				fmt.Fprintf(w, "ok %v - %v: %v\n", n, r.path, c.Name)
+				continue
+			}
+			fmt.Fprintf(w, "not ok %v - %v: %v\n", n, r.path, c.Name)
+			reasons := make([]string, len(c.Failures))
+			for i, f := range c.Failures {
+				reasons[i] = f.Reason
+			}
+			if err := writeTAPDiagnostics(w, map[string]interface{}{
+				"line":        c.TestLine,
+				"duration_ms": formatMilliseconds(c.Duration),
+				"failures":    reasons,
+			}); err != nil {
+				return err
+			}
+		}
+		if r.coverage != nil {
+			fmt.Fprintf(w, "# %v coverage\n", r.path)
+			writeCoverage(w, "# ", r.coverage)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type jsonCase struct {
+	Name       string   `json:"name"`
+	Line       int      `json:"line"`
+	DurationMS float64  `json:"duration_ms"`
+	Passed     bool     `json:"passed"`
+	Failures   []string `json:"failures,omitempty"`
+}
+
+type jsonCoverage struct {
+	Processors []CoverageStat `json:"processors"`
+	Branches   []CoverageStat `json:"bloblang_branches"`
+}
+
+type jsonTarget struct {
+	Path     string        `json:"path"`
+	Passed   bool          `json:"passed"`
+	Lints    []string      `json:"lints,omitempty"`
+	Cases    []jsonCase    `json:"cases"`
+	Coverage *jsonCoverage `json:"coverage,omitempty"`
+}
+
+type jsonReport struct {
+	Passed  bool         `json:"passed"`
+	Targets []jsonTarget `json:"targets"`
+}
+
+type jsonReporter struct{}
+
+func (jsonReporter) target(w io.Writer, result targetResult) {}
+
+func (jsonReporter) finish(w io.Writer, results []targetResult) error {
+	report := jsonReport{
+		Passed:  true,
+		Targets: []jsonTarget{},
+	}
+	for _, r := range results {
+		target := jsonTarget{
+			Path:   r.path,
+			Passed: !r.failed(),
+			Lints:  r.lints,
+			Cases:  []jsonCase{},
+		}
+		for _, c := range r.cases {
+			jc := jsonCase{
+				Name:       c.Name,
+				Line:       c.TestLine,
+				DurationMS: formatMilliseconds(c.Duration),
+				Passed:     len(c.Failures) == 0,
+			}
+			for _, f := range c.Failures {
+				jc.Failures = append(jc.Failures, f.Reason)
+			}
+			target.Cases = append(target.Cases, jc)
+		}
+		if r.coverage != nil {
+			target.Coverage = &jsonCoverage{
+				Processors: r.coverage.Processors(),
+				Branches:   r.coverage.Branches(),
+			}
+		}
+		if !target.Passed {
+			report.Passed = false
+		}
+		report.Targets = append(report.Targets, target)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+//------------------------------------------------------------------------------
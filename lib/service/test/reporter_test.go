@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reporterTestConfig = `
+pipeline:
+  processors:
+    - bloblang: |
+        root = match {
+          this.type == "a" => "first"
+          this.type == "b" => "second"
+          _ => "other"
+        }
+    - switch:
+        - check: content() == "first"
+          processors:
+            - bloblang: 'root = content().uppercase()'
+        - processors:
+            - bloblang: 'root = if content() == "nope" { "nah" } else { content() }'
+
+tests:
+  - name: passes
+    input_batch:
+      - content: '{"type":"a"}'
+    output_batches:
+      - - content_equals: FIRST
+
+  - name: fails
+    input_batch:
+      - content: '{"type":"c"}'
+    output_batches:
+      - - content_equals: nope
+
+output:
+  drop: {}
+  processors:
+    - bloblang: 'root = if this.untested { "foo" } else { "bar" }'
+`
+
+func runReporterTest(t *testing.T, format string) string {
+	t.Helper()
+
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config.yaml": reporterTestConfig,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(testDir)
+	})
+
+	rep, err := newReporter(format)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.False(t, runAllTo(&buf, []string{filepath.Join(testDir, "config.yaml")}, "_benthos_test", false, log.Noop(), nil, rep, true))
+	return strings.ReplaceAll(buf.String(), testDir, "TESTDIR")
+}
+
+func TestReporterJSON(t *testing.T) {
+	var report jsonReport
+	require.NoError(t, json.Unmarshal([]byte(runReporterTest(t, "json")), &report))
+
+	assert.False(t, report.Passed)
+	require.Len(t, report.Targets, 1)
+
+	target := report.Targets[0]
+	assert.Equal(t, "TESTDIR/config.yaml", target.Path)
+	assert.False(t, target.Passed)
+
+	require.Len(t, target.Cases, 2)
+	assert.Equal(t, "passes", target.Cases[0].Name)
+	assert.Equal(t, 18, target.Cases[0].Line)
+	assert.True(t, target.Cases[0].Passed)
+	assert.Empty(t, target.Cases[0].Failures)
+
+	assert.Equal(t, "fails", target.Cases[1].Name)
+	assert.False(t, target.Cases[1].Passed)
+	assert.Equal(t, []string{
+		"batch 0 message 0: content_equals: content mismatch\n  expected: nope\n  received: other",
+	}, target.Cases[1].Failures)
+
+	require.NotNil(t, target.Coverage)
+	assert.Equal(t, []CoverageStat{
+		{Label: "/output/processors/0", Hits: 0},
+		{Label: "/pipeline/processors/0", Hits: 2},
+		{Label: "/pipeline/processors/1", Hits: 2},
+		{Label: "/pipeline/processors/1.0.0", Hits: 1},
+		{Label: "/pipeline/processors/1.1.0", Hits: 1},
+	}, target.Coverage.Processors)
+	assert.Equal(t, []CoverageStat{
+		{Label: `line 2: match case this.type == "a" => "first"`, Hits: 1},
+		{Label: `line 3: match case this.type == "b" => "second"`, Hits: 0},
+		{Label: `line 4: match case _ => "other"`, Hits: 1},
+		{Label: `line 1: if content() == "nope"`, Hits: 0},
+		{Label: `line 1: else of if content() == "nope"`, Hits: 1},
+		{Label: `line 1: if this.untested`, Hits: 0},
+		{Label: `line 1: else of if this.untested`, Hits: 0},
+	}, target.Coverage.Branches)
+}
+
+func TestReporterTAP(t *testing.T) {
+	lines := strings.Split(runReporterTest(t, "tap"), "\n")
+	require.True(t, len(lines) > 10, lines)
+
+	assert.Equal(t, []string{
+		"TAP version 13",
+		"1..2",
+		"ok 1 - TESTDIR/config.yaml: passes",
+		"not ok 2 - TESTDIR/config.yaml: fails",
+		"  ---",
+	}, lines[:5])
+	assert.Contains(t, lines, "  line: 24")
+	assert.Contains(t, lines, "# Bloblang branches: 3/7 executed")
+	assert.Contains(t, lines, `#   line 3: match case this.type == "b" => "second": not executed`)
+}
+
+func TestReporterJUnit(t *testing.T) {
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal([]byte(runReporterTest(t, "junit")), &suites))
+
+	assert.Equal(t, 2, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	require.Len(t, suites.Suites, 1)
+
+	suite := suites.Suites[0]
+	assert.Equal(t, "TESTDIR/config.yaml", suite.Name)
+	require.Len(t, suite.Cases, 2)
+	assert.Equal(t, "passes", suite.Cases[0].Name)
+	assert.Nil(t, suite.Cases[0].Failure)
+	assert.Equal(t, "fails", suite.Cases[1].Name)
+	require.NotNil(t, suite.Cases[1].Failure)
+	assert.Equal(t, "1 failures [line 24]", suite.Cases[1].Failure.Message)
+	assert.Contains(t, suite.Cases[1].Failure.Text, "content_equals: content mismatch")
+	assert.Contains(t, suite.SystemOut, "Processors: 4/5 executed")
+}
+
+func TestReporterDefault(t *testing.T) {
+	out := runReporterTest(t, "default")
+	assert.Contains(t, out, "Test 'TESTDIR/config.yaml' failed")
+	assert.Contains(t, out, "fails [line 24]:\nbatch 0 message 0")
+	assert.Contains(t, out, "--- TESTDIR/config.yaml coverage ---")
+	assert.Contains(t, out, "  /pipeline/processors/0: 2\n")
+}
+
+func TestReporterBadFormat(t *testing.T) {
+	_, err := newReporter("nope")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "format 'nope' was not recognised")
+}
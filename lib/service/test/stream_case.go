@@ -0,0 +1,155 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/metadata"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// StreamCase defines an end-to-end test of a complete stream, where the input
+// of the config is replaced with the input batches of the test and each output
+// is replaced with a capturing sink.
+type StreamCase struct {
+	// Target is an optional path to the config being tested, relative to the
+	// test definition. When empty the config being tested is used.
+	Target string `yaml:"target"`
+
+	// InputBatches are sent into the stream in order.
+	InputBatches [][]InputPart `yaml:"input_batches"`
+
+	// Outputs maps outputs, identified by their JSON Pointer within the
+	// config, to the expected batches written to them.
+	Outputs map[string]OutputExpectation `yaml:"outputs"`
+
+	// Acks optionally lists, for each input batch, whether the batch is
+	// expected to be acknowledged (true) or rejected (false).
+	Acks []bool `yaml:"acks"`
+
+	// Timeout is the maximum period to wait for all input batches to be
+	// acknowledged or rejected.
+	Timeout string `yaml:"timeout"`
+}
+
+// OutputExpectation describes the expected behaviour of an output during a
+// stream test case.
+type OutputExpectation struct {
+	// Nack configures the output to reject all messages it receives.
+	Nack bool `yaml:"nack"`
+
+	// Batches lists the batches expected to be written to the output, where
+	// each batch lists conditions for each message.
+	Batches [][]ConditionsMap `yaml:"batches"`
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Case) executeStream(
+	provider ProcProvider,
+	mocks ResourceMocks,
+	reportFailure func(string),
+	checkMocks func(types.Manager),
+) error {
+	sProvider, ok := provider.(StreamProvider)
+	if !ok {
+		return fmt.Errorf("processors provider %T does not support stream tests", provider)
+	}
+
+	timeout := time.Second * 10
+	if len(c.Stream.Timeout) > 0 {
+		var err error
+		if timeout, err = time.ParseDuration(c.Stream.Timeout); err != nil {
+			return fmt.Errorf("failed to parse stream timeout: %v", err)
+		}
+	}
+
+	strm, err := sProvider.ProvideStream(c.Stream.Target, c.Environment, mocks)
+	if err != nil {
+		return fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	outputPaths := make([]string, 0, len(c.Stream.Outputs))
+	for k := range c.Stream.Outputs {
+		outputPaths = append(outputPaths, k)
+	}
+	sort.Strings(outputPaths)
+
+	for _, path := range outputPaths {
+		if err := strm.SetOutputNack(path, c.Stream.Outputs[path].Nack); err != nil {
+			_ = strm.Stop(timeout)
+			return err
+		}
+	}
+
+	if err := strm.Start(); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	resChans := make([]<-chan types.Response, 0, len(c.Stream.InputBatches))
+	for i, inputBatch := range c.Stream.InputBatches {
+		parts := make([]types.Part, len(inputBatch))
+		for j, v := range inputBatch {
+			part := message.NewPart([]byte(v.Content))
+			part.SetMetadata(metadata.New(v.Metadata))
+			parts[j] = part
+		}
+		msg := message.New(nil)
+		msg.SetAll(parts)
+
+		resChan, err := strm.Send(msg, time.Until(deadline))
+		if err != nil {
+			reportFailure(fmt.Sprintf("input batch %v: %v", i, err))
+			break
+		}
+		resChans = append(resChans, resChan)
+	}
+
+	acks := make([]*bool, len(c.Stream.InputBatches))
+	for i, resChan := range resChans {
+		select {
+		case res := <-resChan:
+			acked := res.Error() == nil
+			acks[i] = &acked
+		case <-time.After(time.Until(deadline)):
+			reportFailure(fmt.Sprintf("input batch %v: timed out waiting for acknowledgement", i))
+		}
+	}
+
+	checkMocks(strm.mgr)
+	if err := strm.Stop(timeout); err != nil {
+		reportFailure(fmt.Sprintf("failed to cleanly stop stream: %v", err))
+	}
+
+	for i, exp := range c.Stream.Acks {
+		if i >= len(acks) {
+			reportFailure(fmt.Sprintf("input batch %v: expected ack result but no such input batch was defined", i))
+			continue
+		}
+		if acks[i] != nil && *acks[i] != exp {
+			reportFailure(fmt.Sprintf("input batch %v: expected acknowledged %v, got %v", i, exp, *acks[i]))
+		}
+	}
+
+	for _, path := range strm.OutputPaths() {
+		exp, exists := c.Stream.Outputs[path]
+		batches := strm.OutputBatches(path)
+		if !exists {
+			for _, b := range batches {
+				reportFailure(fmt.Sprintf("output %v: unexpected batch: %s", path, message.GetAllBytes(b)))
+			}
+			continue
+		}
+		checkOutputBatches(exp.Batches, batches, func(reason string) {
+			reportFailure(fmt.Sprintf("output %v: %v", path, reason))
+		})
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const streamCaseTestConfig = `
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+    consumer_group: bar
+
+pipeline:
+  processors:
+    - bloblang: 'root = this.merge({"processed":true})'
+
+output:
+  switch:
+    retry_until_success: false
+    max_in_flight: 2
+    cases:
+      - check: this.type == "a"
+        output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: a
+            batching:
+              count: 2
+      - check: this.type == "b"
+        output:
+          http_client:
+            url: http://localhost:1234
+          processors:
+            - bloblang: 'root = this.merge({"sent":true})'
+      - output:
+          drop: {}
+`
+
+func TestStreamCase(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": streamCaseTestConfig,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	var def Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: routing
+    stream:
+      input_batches:
+        - - content: '{"type":"a","id":1}'
+        - - content: '{"type":"b","id":2}'
+        - - content: '{"type":"a","id":3}'
+        - - content: '{"type":"c","id":4}'
+      acks: [ true, true, true, true ]
+      outputs:
+        /output/switch/cases/0/output:
+          batches:
+            - - json_equals: { "type": "a", "id": 1, "processed": true }
+              - json_equals: { "type": "a", "id": 3, "processed": true }
+        /output/switch/cases/1/output:
+          batches:
+            - - json_equals: { "type": "b", "id": 2, "processed": true, "sent": true }
+        /output/switch/cases/2/output:
+          batches:
+            - - json_contains: { "type": "c" }
+
+  - name: nacks
+    stream:
+      input_batches:
+        - - content: '{"type":"b","id":1}'
+        - - content: '{"type":"c","id":2}'
+      acks: [ false, false ]
+      outputs:
+        /output/switch/cases/1/output:
+          nack: true
+          batches:
+            - - json_contains: { "id": 1 }
+`), &def))
+
+	failures, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+	require.NoError(t, err)
+
+	var failureStrs []string
+	for _, f := range failures {
+		failureStrs = append(failureStrs, f.String())
+	}
+	assert.Equal(t, []string{
+		`nacks [line 23]: input batch 1: expected acknowledged false, got true`,
+		`nacks [line 23]: output /output/switch/cases/2/output: unexpected batch: [{"id":2,"processed":true,"type":"c"}]`,
+	}, failureStrs)
+}
+
+func TestStreamCaseBadOutput(t *testing.T) {
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": streamCaseTestConfig,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	var def Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: bad output
+    stream:
+      input_batches:
+        - - content: '{"type":"a","id":1}'
+      outputs:
+        /output/switch/cases/5/output:
+          batches: []
+`), &def))
+
+	_, err = def.Execute(filepath.Join(testDir, "config1.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output '/output/switch/cases/5/output' was not found")
+}
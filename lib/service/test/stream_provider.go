@@ -0,0 +1,421 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// StreamProvider creates complete streams from a Benthos config where the input
+// is replaced with an injected message source and each output is replaced with
+// a capturing sink.
+type StreamProvider interface {
+	ProvideStream(target string, environment map[string]string, mocks ResourceMocks) (*TestStream, error)
+}
+
+// ErrMockNack is returned by capturing sinks that are configured to reject
+// messages.
+var ErrMockNack = errors.New("message rejected by mocked output")
+
+//------------------------------------------------------------------------------
+
+// ProvideStream attempts to parse a full Benthos config and construct a test
+// stream from it. The target is a path relative to the directory of the config
+// being tested, and when empty the config being tested is used.
+func (p *ProcessorsProvider) ProvideStream(target string, environment map[string]string, mocks ResourceMocks) (*TestStream, error) {
+	targetPath := p.targetPath
+	if len(target) > 0 {
+		targetPath = filepath.Join(filepath.Dir(p.targetPath), target)
+	}
+
+	cleanupEnv := setEnvironment(environment)
+	configBytes, err := config.ReadWithJSONPointers(targetPath, true)
+	cleanupEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	}
+
+	conf := config.New()
+	if err = yaml.Unmarshal(configBytes, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	}
+	if err = p.addExtraResources(&conf.Manager); err != nil {
+		return nil, err
+	}
+	if conf.Manager, err = applyResourceMocks(conf.Manager, mocks); err != nil {
+		return nil, err
+	}
+	return newTestStream(conf, p.logger, p.stats, p.managerOpts(mocks)...)
+}
+
+//------------------------------------------------------------------------------
+
+var testStreamPipeCounter int64
+
+func testStreamPipeID(kind string) string {
+	return fmt.Sprintf("benthos_test_%v_%v", kind, atomic.AddInt64(&testStreamPipeCounter, 1))
+}
+
+// captureSink records the batches written to a replaced output.
+type captureSink struct {
+	pipeID string
+
+	mut     sync.Mutex
+	nack    bool
+	batches []types.Message
+}
+
+// TestStream is a running Benthos stream where the input is replaced with an
+// injected message source and each output is replaced with a capturing sink,
+// identified by the JSON Pointer of the output within the original config.
+type TestStream struct {
+	conf     stream.Config
+	mgrConf  manager.Config
+	mgrOpts  []func(*manager.Type)
+	logger   log.Modular
+	stats    metrics.Type
+	inputID  string
+	sinks    map[string]*captureSink
+	tranChan chan types.Transaction
+
+	mgr      *manager.Type
+	strm     *stream.Type
+	sinksWG  sync.WaitGroup
+	stopChan chan struct{}
+}
+
+func newTestStream(conf config.Type, logger log.Modular, stats metrics.Type, mgrOpts ...func(*manager.Type)) (*TestStream, error) {
+	t := &TestStream{
+		mgrConf:  conf.Manager,
+		mgrOpts:  mgrOpts,
+		logger:   logger,
+		stats:    stats,
+		inputID:  testStreamPipeID("input"),
+		sinks:    map[string]*captureSink{},
+		tranChan: make(chan types.Transaction),
+		stopChan: make(chan struct{}),
+	}
+
+	t.conf = conf.Config
+
+	inputConf := input.NewConfig()
+	inputConf.Type = input.TypeInproc
+	inputConf.Inproc = input.InprocConfig(t.inputID)
+	inputConf.Processors = conf.Input.Processors
+	t.conf.Input = inputConf
+
+	// Processing is restricted to a single thread in order to keep the
+	// ordering of messages deterministic.
+	t.conf.Pipeline.Threads = 1
+
+	var err error
+	if t.conf.Output, err = t.replaceOutputs("/output", conf.Output); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// replaceOutputs walks an output config and replaces all outputs that are not
+// brokers of other outputs with capturing sinks.
+func (t *TestStream) replaceOutputs(path string, conf output.Config) (output.Config, error) {
+	var err error
+	switch conf.Type {
+	case output.TypeBroker:
+		outputs := make([]output.Config, len(conf.Broker.Outputs))
+		for i, c := range conf.Broker.Outputs {
+			if outputs[i], err = t.replaceOutputs(fmt.Sprintf("%v/broker/outputs/%v", path, i), c); err != nil {
+				return conf, err
+			}
+		}
+		conf.Broker.Outputs = outputs
+		return conf, nil
+	case output.TypeSwitch:
+		cases := make([]output.SwitchConfigCase, len(conf.Switch.Cases))
+		for i, c := range conf.Switch.Cases {
+			cases[i] = c
+			if cases[i].Output, err = t.replaceOutputs(fmt.Sprintf("%v/switch/cases/%v/output", path, i), c.Output); err != nil {
+				return conf, err
+			}
+		}
+		conf.Switch.Cases = cases
+		outputs := make([]output.SwitchConfigOutput, len(conf.Switch.Outputs))
+		for i, c := range conf.Switch.Outputs {
+			outputs[i] = c
+			if outputs[i].Output, err = t.replaceOutputs(fmt.Sprintf("%v/switch/outputs/%v/output", path, i), c.Output); err != nil {
+				return conf, err
+			}
+		}
+		conf.Switch.Outputs = outputs
+		return conf, nil
+	case output.TypeTry:
+		outputs := make(output.TryConfig, len(conf.Try))
+		for i, c := range conf.Try {
+			if outputs[i], err = t.replaceOutputs(fmt.Sprintf("%v/try/%v", path, i), c); err != nil {
+				return conf, err
+			}
+		}
+		conf.Try = outputs
+		return conf, nil
+	case output.TypeRetry:
+		if conf.Retry.Output != nil {
+			child, err := t.replaceOutputs(path+"/retry/output", *conf.Retry.Output)
+			if err != nil {
+				return conf, err
+			}
+			conf.Retry.Output = &child
+		}
+		return conf, nil
+	case output.TypeDropOn:
+		if conf.DropOn.Output != nil {
+			child, err := t.replaceOutputs(path+"/drop_on/output", *conf.DropOn.Output)
+			if err != nil {
+				return conf, err
+			}
+			conf.DropOn.Output = &child
+		}
+		return conf, nil
+	case output.TypeDropOnError:
+		if conf.DropOnError.Config != nil {
+			child, err := t.replaceOutputs(path+"/drop_on_error", *conf.DropOnError.Config)
+			if err != nil {
+				return conf, err
+			}
+			conf.DropOnError.Config = &child
+		}
+		return conf, nil
+	case output.TypeCircuitBreaker:
+		if conf.CircuitBreaker.Output != nil {
+			child, err := t.replaceOutputs(path+"/circuit_breaker/output", *conf.CircuitBreaker.Output)
+			if err != nil {
+				return conf, err
+			}
+			conf.CircuitBreaker.Output = &child
+		}
+		if conf.CircuitBreaker.Fallback != nil {
+			child, err := t.replaceOutputs(path+"/circuit_breaker/fallback", *conf.CircuitBreaker.Fallback)
+			if err != nil {
+				return conf, err
+			}
+			conf.CircuitBreaker.Fallback = &child
+		}
+		return conf, nil
+	}
+
+	sink := &captureSink{pipeID: testStreamPipeID("output")}
+	t.sinks[path] = sink
+
+	inprocConf := output.NewConfig()
+	inprocConf.Type = output.TypeInproc
+	inprocConf.Inproc = output.InprocConfig(sink.pipeID)
+
+	policy, err := outputBatchPolicy(conf)
+	if err != nil {
+		return conf, fmt.Errorf("failed to extract batching policy of output '%v': %v", path, err)
+	}
+
+	newConf := output.NewConfig()
+	newConf.Processors = conf.Processors
+	if policy.IsNoop() {
+		newConf.Type = output.TypeInproc
+		newConf.Inproc = inprocConf.Inproc
+	} else {
+		// A try broker of a single output is used in order to apply the
+		// batching policy of the replaced output whilst still propagating
+		// nacks from the sink.
+		newConf.Type = output.TypeBroker
+		newConf.Broker.Pattern = "try"
+		newConf.Broker.Batching = policy
+		newConf.Broker.Outputs = []output.Config{inprocConf}
+	}
+	return newConf, nil
+}
+
+// outputBatchPolicy attempts to extract the batching policy of an output
+// config, if it has one.
+func outputBatchPolicy(conf output.Config) (batch.PolicyConfig, error) {
+	policy := batch.NewPolicyConfig()
+
+	sanit, err := output.SanitiseConfig(conf)
+	if err != nil {
+		return policy, err
+	}
+	sanitBytes, err := yaml.Marshal(sanit)
+	if err != nil {
+		return policy, err
+	}
+	var sanitMap map[string]interface{}
+	if err = yaml.Unmarshal(sanitBytes, &sanitMap); err != nil {
+		return policy, err
+	}
+	typeConf, ok := sanitMap[conf.Type].(map[string]interface{})
+	if !ok {
+		return policy, nil
+	}
+	batchingConf, exists := typeConf["batching"]
+	if !exists {
+		return policy, nil
+	}
+
+	batchingBytes, err := yaml.Marshal(batchingConf)
+	if err != nil {
+		return policy, err
+	}
+	err = yaml.Unmarshal(batchingBytes, &policy)
+	return policy, err
+}
+
+//------------------------------------------------------------------------------
+
+// OutputPaths returns the JSON Pointers of each output that was replaced with a
+// capturing sink, in sorted order.
+func (t *TestStream) OutputPaths() []string {
+	paths := make([]string, 0, len(t.sinks))
+	for k := range t.sinks {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// SetOutputNack configures the capturing sink of an output to reject all
+// messages it receives.
+func (t *TestStream) SetOutputNack(path string, nack bool) error {
+	sink, exists := t.sinks[path]
+	if !exists {
+		return fmt.Errorf("output '%v' was not found, available outputs are: %v", path, strings.Join(t.OutputPaths(), ", "))
+	}
+	sink.mut.Lock()
+	sink.nack = nack
+	sink.mut.Unlock()
+	return nil
+}
+
+// OutputBatches returns the batches captured by the sink of an output.
+func (t *TestStream) OutputBatches(path string) []types.Message {
+	sink, exists := t.sinks[path]
+	if !exists {
+		return nil
+	}
+	sink.mut.Lock()
+	defer sink.mut.Unlock()
+	return append([]types.Message{}, sink.batches...)
+}
+
+// Start the stream.
+func (t *TestStream) Start() error {
+	var err error
+	if t.mgr, err = manager.New(t.mgrConf, types.NoopMgr(), t.logger, t.stats, t.mgrOpts...); err != nil {
+		return fmt.Errorf("failed to initialise resources: %v", err)
+	}
+	t.mgr.SetPipe(t.inputID, t.tranChan)
+
+	if t.strm, err = stream.New(
+		t.conf,
+		stream.OptSetLogger(t.logger),
+		stream.OptSetStats(t.stats),
+		stream.OptSetManager(t.mgr),
+	); err != nil {
+		t.mgr.CloseAsync()
+		_ = t.mgr.WaitForClose(time.Second * 5)
+		return fmt.Errorf("failed to initialise stream: %v", err)
+	}
+
+	for _, sink := range t.sinks {
+		t.sinksWG.Add(1)
+		go t.runSink(sink)
+	}
+	return nil
+}
+
+func (t *TestStream) runSink(sink *captureSink) {
+	defer t.sinksWG.Done()
+
+	var tChan <-chan types.Transaction
+	for tChan == nil {
+		var err error
+		if tChan, err = t.mgr.GetPipe(sink.pipeID); err != nil {
+			select {
+			case <-time.After(time.Millisecond * 10):
+			case <-t.stopChan:
+				return
+			}
+		}
+	}
+
+	for {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-tChan:
+			if !open {
+				return
+			}
+		case <-t.stopChan:
+			return
+		}
+
+		sink.mut.Lock()
+		sink.batches = append(sink.batches, tran.Payload.DeepCopy())
+		var res types.Response = response.NewAck()
+		if sink.nack {
+			res = response.NewError(ErrMockNack)
+		}
+		sink.mut.Unlock()
+
+		select {
+		case tran.ResponseChan <- res:
+		case <-t.stopChan:
+			return
+		}
+	}
+}
+
+// Send a batch of messages into the stream, returning a channel that receives
+// the resulting response.
+func (t *TestStream) Send(msg types.Message, timeout time.Duration) (<-chan types.Response, error) {
+	resChan := make(chan types.Response, 1)
+	select {
+	case t.tranChan <- types.NewTransaction(msg, resChan):
+	case <-time.After(timeout):
+		return nil, errors.New("timed out sending message batch into the stream")
+	}
+	return resChan, nil
+}
+
+// Stop the stream within a timeout.
+func (t *TestStream) Stop(timeout time.Duration) error {
+	if t.strm == nil {
+		return nil
+	}
+	stopAt := time.Now().Add(timeout)
+	err := t.strm.Stop(timeout)
+	close(t.stopChan)
+	t.sinksWG.Wait()
+
+	t.mgr.CloseAsync()
+	if mErr := t.mgr.WaitForClose(time.Until(stopAt)); err == nil {
+		err = mErr
+	}
+	return err
+}
+
+//------------------------------------------------------------------------------
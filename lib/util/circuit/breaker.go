@@ -0,0 +1,321 @@
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrOpen is returned by components when a request is rejected because the
+// circuit is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State represents the state of a circuit breaker.
+type State int
+
+// Circuit breaker states, the numerical values of which are reported by the
+// circuit_breaker.state gauge.
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+// String returns a human readable representation of the state.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+//------------------------------------------------------------------------------
+
+const windowBuckets = 10
+
+// Permit is obtained from a Breaker when a request is allowed and must be
+// passed back to Record along with the outcome of the request.
+type Permit struct {
+	generation uint64
+}
+
+type windowBucket struct {
+	epoch    int64
+	requests int
+	failures int
+}
+
+// Breaker tracks the outcome of requests to a dependency and opens the circuit
+// when the rate of failed requests within a rolling window exceeds a threshold.
+// After a period the circuit becomes half-open, where a limited number of
+// requests are allowed in order to determine whether the circuit should close
+// again.
+type Breaker struct {
+	name             string
+	threshold        float64
+	minRequests      int
+	halfOpenRequests int
+	bucketWidth      time.Duration
+	openPeriod       time.Duration
+
+	mut               sync.Mutex
+	state             State
+	generation        uint64
+	openedAt          time.Time
+	buckets           [windowBuckets]windowBucket
+	halfOpenInFlight  int
+	halfOpenSuccesses int
+
+	now func() time.Time
+
+	mState    metrics.StatGauge
+	mOpened   metrics.StatCounter
+	mRejected metrics.StatCounter
+
+	registrar EndpointRegistrar
+}
+
+func newBreaker(conf Config, window, openPeriod time.Duration, stats metrics.Type) *Breaker {
+	bucketWidth := window / windowBuckets
+	if bucketWidth <= 0 {
+		bucketWidth = 1
+	}
+	b := &Breaker{
+		name:             conf.Name,
+		threshold:        conf.ErrorThreshold,
+		minRequests:      conf.MinRequests,
+		halfOpenRequests: conf.HalfOpenRequests,
+		bucketWidth:      bucketWidth,
+		openPeriod:       openPeriod,
+		now:              time.Now,
+
+		mState:    stats.GetGauge("circuit_breaker.state"),
+		mOpened:   stats.GetCounter("circuit_breaker.opened"),
+		mRejected: stats.GetCounter("circuit_breaker.rejected"),
+	}
+	b.mState.Set(int64(StateClosed))
+	return b
+}
+
+//------------------------------------------------------------------------------
+
+func (b *Breaker) setState(s State) {
+	b.state = s
+	b.generation++
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+	switch s {
+	case StateOpen:
+		b.openedAt = b.now()
+		b.mOpened.Incr(1)
+	case StateClosed:
+		b.buckets = [windowBuckets]windowBucket{}
+	}
+	b.mState.Set(int64(s))
+}
+
+// windowCounts returns the number of requests and failures within the rolling
+// window.
+func (b *Breaker) windowCounts() (requests, failures int) {
+	epoch := b.now().UnixNano() / int64(b.bucketWidth)
+	for _, bucket := range b.buckets {
+		if epoch-bucket.epoch < windowBuckets {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return
+}
+
+// Allow returns a permit and true if a request should be attempted. Each
+// request allowed must be followed by a call to Record with its permit and
+// outcome.
+func (b *Breaker) Allow() (Permit, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.openPeriod {
+			b.mRejected.Incr(1)
+			return Permit{}, false
+		}
+		b.setState(StateHalfOpen)
+	}
+	if b.state == StateHalfOpen {
+		if b.halfOpenInFlight >= b.halfOpenRequests {
+			b.mRejected.Incr(1)
+			return Permit{}, false
+		}
+		b.halfOpenInFlight++
+	}
+	return Permit{generation: b.generation}, true
+}
+
+// Record registers the outcome of a request that was allowed. Outcomes of
+// requests that were allowed before the last change of state are ignored, this
+// prevents slow requests from a closed circuit being counted as half-open
+// probes.
+func (b *Breaker) Record(p Permit, failed bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if p.generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		if failed {
+			b.setState(StateOpen)
+			return
+		}
+		if b.halfOpenSuccesses++; b.halfOpenSuccesses >= b.halfOpenRequests {
+			b.setState(StateClosed)
+		}
+	case StateClosed:
+		epoch := b.now().UnixNano() / int64(b.bucketWidth)
+		bucket := &b.buckets[epoch%windowBuckets]
+		if bucket.epoch != epoch {
+			*bucket = windowBucket{epoch: epoch}
+		}
+		bucket.requests++
+		if failed {
+			bucket.failures++
+		}
+		if requests, failures := b.windowCounts(); requests >= b.minRequests &&
+			float64(failures)/float64(requests) >= b.threshold {
+			b.setState(StateOpen)
+		}
+	}
+}
+
+// Wait blocks until a request is allowed, returns false if the context is
+// cancelled first. As with Allow each successful call must be followed by a
+// call to Record.
+func (b *Breaker) Wait(ctx context.Context) (Permit, bool) {
+	for {
+		if p, ok := b.Allow(); ok {
+			return p, true
+		}
+		b.mut.Lock()
+		wait := b.openPeriod - b.now().Sub(b.openedAt)
+		b.mut.Unlock()
+		if wait <= 0 || wait > b.openPeriod {
+			// Requests are already being attempted in a half-open state.
+			wait = time.Millisecond * 50
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return Permit{}, false
+		}
+	}
+}
+
+// State returns the current state of the circuit breaker.
+func (b *Breaker) State() State {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openPeriod {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+//------------------------------------------------------------------------------
+
+// EndpointRegistrar is implemented by types that expose HTTP endpoints, such as
+// a types.Manager.
+type EndpointRegistrar interface {
+	RegisterEndpoint(path, desc string, h http.HandlerFunc)
+}
+
+var (
+	registeredMut   sync.Mutex
+	registeredNames = map[EndpointRegistrar]map[string]struct{}{}
+)
+
+// RegisterEndpoint exposes the state of a named circuit breaker at the path
+// /circuit_breakers/{name}. Circuit breakers without a name are not exposed.
+// An error is returned if a circuit breaker of the same name is already
+// registered with r and has not been closed.
+func (b *Breaker) RegisterEndpoint(r EndpointRegistrar) error {
+	if b.name == "" || r == nil {
+		return nil
+	}
+
+	registeredMut.Lock()
+	names, exists := registeredNames[r]
+	if !exists {
+		names = map[string]struct{}{}
+		registeredNames[r] = names
+	}
+	if _, exists = names[b.name]; exists {
+		registeredMut.Unlock()
+		return fmt.Errorf("a circuit breaker named '%v' is already registered", b.name)
+	}
+	names[b.name] = struct{}{}
+	registeredMut.Unlock()
+
+	b.registrar = r
+	r.RegisterEndpoint(
+		"/circuit_breakers/"+b.name,
+		"Returns the current state of the circuit breaker '"+b.name+"'.",
+		b.handleState,
+	)
+	return nil
+}
+
+// Close releases the name of a circuit breaker registered with
+// RegisterEndpoint so that it can be registered again.
+func (b *Breaker) Close() {
+	if b.registrar == nil {
+		return
+	}
+
+	registeredMut.Lock()
+	if names, exists := registeredNames[b.registrar]; exists {
+		delete(names, b.name)
+		if len(names) == 0 {
+			delete(registeredNames, b.registrar)
+		}
+	}
+	registeredMut.Unlock()
+
+	b.registrar = nil
+}
+
+func (b *Breaker) handleState(w http.ResponseWriter, r *http.Request) {
+	state := b.State()
+
+	b.mut.Lock()
+	requests, failures := b.windowCounts()
+	b.mut.Unlock()
+
+	var errorRate float64
+	if requests > 0 {
+		errorRate = float64(failures) / float64(requests)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":       b.name,
+		"state":      state.String(),
+		"requests":   requests,
+		"failures":   failures,
+		"error_rate": errorRate,
+	})
+}
@@ -0,0 +1,263 @@
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.t
+}
+
+func (f *fakeClock) add(d time.Duration) {
+	f.t = f.t.Add(d)
+}
+
+func TestBreakerConfigErrors(t *testing.T) {
+	tests := map[string]func(c *Config){
+		"bad threshold":    func(c *Config) { c.ErrorThreshold = 0 },
+		"big threshold":    func(c *Config) { c.ErrorThreshold = 1.5 },
+		"bad min requests": func(c *Config) { c.MinRequests = 0 },
+		"bad half open":    func(c *Config) { c.HalfOpenRequests = 0 },
+		"bad window":       func(c *Config) { c.Window = "nope" },
+		"zero window":      func(c *Config) { c.Window = "0s" },
+		"bad open period":  func(c *Config) { c.OpenPeriod = "nope" },
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		fn(&conf)
+		_, err := conf.NewBreaker(metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestBreakerOpensOnThreshold(t *testing.T) {
+	conf := NewConfig()
+	conf.MinRequests = 4
+	conf.ErrorThreshold = 0.5
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+
+	for _, failed := range []bool{false, true, false} {
+		p, ok := b.Allow()
+		require.True(t, ok)
+		b.Record(p, failed)
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	p, ok := b.Allow()
+	require.True(t, ok)
+	b.Record(p, true)
+	assert.Equal(t, StateOpen, b.State())
+
+	_, ok = b.Allow()
+	assert.False(t, ok)
+}
+
+func TestBreakerWindowExpires(t *testing.T) {
+	conf := NewConfig()
+	conf.MinRequests = 2
+	conf.Window = "10s"
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b.now = clock.now
+
+	p, ok := b.Allow()
+	require.True(t, ok)
+	b.Record(p, true)
+
+	clock.add(time.Second * 11)
+
+	for i := 0; i < 2; i++ {
+		p, ok = b.Allow()
+		require.True(t, ok)
+		b.Record(p, false)
+	}
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	conf := NewConfig()
+	conf.MinRequests = 1
+	conf.OpenPeriod = "30s"
+	conf.HalfOpenRequests = 2
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b.now = clock.now
+
+	p, ok := b.Allow()
+	require.True(t, ok)
+	b.Record(p, true)
+	assert.Equal(t, StateOpen, b.State())
+
+	clock.add(time.Second * 29)
+	_, ok = b.Allow()
+	assert.False(t, ok)
+
+	clock.add(time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	probeA, ok := b.Allow()
+	require.True(t, ok)
+	probeB, ok := b.Allow()
+	require.True(t, ok)
+	_, ok = b.Allow()
+	assert.False(t, ok)
+
+	b.Record(probeA, false)
+	assert.Equal(t, StateHalfOpen, b.State())
+	b.Record(probeB, true)
+	assert.Equal(t, StateOpen, b.State())
+
+	clock.add(time.Second * 30)
+
+	probeA, ok = b.Allow()
+	require.True(t, ok)
+	probeB, ok = b.Allow()
+	require.True(t, ok)
+	b.Record(probeA, false)
+	b.Record(probeB, false)
+	assert.Equal(t, StateClosed, b.State())
+
+	_, ok = b.Allow()
+	assert.True(t, ok)
+}
+
+func TestBreakerLateResults(t *testing.T) {
+	conf := NewConfig()
+	conf.MinRequests = 1
+	conf.OpenPeriod = "30s"
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b.now = clock.now
+
+	slow, ok := b.Allow()
+	require.True(t, ok)
+	p, ok := b.Allow()
+	require.True(t, ok)
+	b.Record(p, true)
+	assert.Equal(t, StateOpen, b.State())
+
+	clock.add(time.Second * 30)
+
+	probe, ok := b.Allow()
+	require.True(t, ok)
+
+	// A result from before the circuit opened must not count as a probe.
+	b.Record(slow, false)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	b.Record(probe, false)
+	assert.Equal(t, StateClosed, b.State())
+
+	// Nor should a late probe result count towards the closed window.
+	b.Record(probe, true)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerWaitCancelled(t *testing.T) {
+	conf := NewConfig()
+	conf.MinRequests = 1
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+
+	p, ok := b.Allow()
+	require.True(t, ok)
+	b.Record(p, true)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer done()
+
+	_, ok = b.Wait(ctx)
+	assert.False(t, ok)
+}
+
+type testRegistrar struct {
+	endpoints map[string]http.HandlerFunc
+}
+
+func (r *testRegistrar) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+	r.endpoints[path] = h
+}
+
+func TestBreakerEndpoint(t *testing.T) {
+	conf := NewConfig()
+	conf.Name = "foo"
+	conf.MinRequests = 2
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+	defer b.Close()
+
+	reg := &testRegistrar{endpoints: map[string]http.HandlerFunc{}}
+	require.NoError(t, b.RegisterEndpoint(reg))
+	require.Contains(t, reg.endpoints, "/circuit_breakers/foo")
+
+	for i := 0; i < 2; i++ {
+		p, ok := b.Allow()
+		require.True(t, ok)
+		b.Record(p, true)
+	}
+
+	rec := httptest.NewRecorder()
+	reg.endpoints["/circuit_breakers/foo"](rec, httptest.NewRequest("GET", "/circuit_breakers/foo", nil))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "foo", body["name"])
+	assert.Equal(t, "open", body["state"])
+
+	unnamed, err := NewConfig().NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+
+	unnamedReg := &testRegistrar{endpoints: map[string]http.HandlerFunc{}}
+	require.NoError(t, unnamed.RegisterEndpoint(unnamedReg))
+	assert.Empty(t, unnamedReg.endpoints)
+}
+
+func TestBreakerEndpointDuplicate(t *testing.T) {
+	conf := NewConfig()
+	conf.Name = "foo"
+
+	reg := &testRegistrar{endpoints: map[string]http.HandlerFunc{}}
+
+	a, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, a.RegisterEndpoint(reg))
+
+	b, err := conf.NewBreaker(metrics.Noop())
+	require.NoError(t, err)
+	assert.Error(t, b.RegisterEndpoint(reg))
+
+	otherReg := &testRegistrar{endpoints: map[string]http.HandlerFunc{}}
+	require.NoError(t, b.RegisterEndpoint(otherReg))
+	b.Close()
+
+	a.Close()
+	require.NoError(t, b.RegisterEndpoint(reg))
+	b.Close()
+}
@@ -0,0 +1,58 @@
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+// Config contains configuration params for a circuit breaker.
+type Config struct {
+	Name             string  `json:"name" yaml:"name"`
+	ErrorThreshold   float64 `json:"error_threshold" yaml:"error_threshold"`
+	MinRequests      int     `json:"min_requests" yaml:"min_requests"`
+	Window           string  `json:"window" yaml:"window"`
+	OpenPeriod       string  `json:"open_period" yaml:"open_period"`
+	HalfOpenRequests int     `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Name:             "",
+		ErrorThreshold:   0.5,
+		MinRequests:      20,
+		Window:           "10s",
+		OpenPeriod:       "30s",
+		HalfOpenRequests: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewBreaker returns a Breaker based on the configuration values of Config.
+func (c Config) NewBreaker(stats metrics.Type) (*Breaker, error) {
+	if c.ErrorThreshold <= 0 || c.ErrorThreshold > 1 {
+		return nil, errors.New("error_threshold must be greater than 0 and no more than 1")
+	}
+	if c.MinRequests < 1 {
+		return nil, errors.New("min_requests must be greater than zero")
+	}
+	if c.HalfOpenRequests < 1 {
+		return nil, errors.New("half_open_requests must be greater than zero")
+	}
+	window, err := time.ParseDuration(c.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse window: %v", err)
+	}
+	if window <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+	openPeriod, err := time.ParseDuration(c.OpenPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse open_period: %v", err)
+	}
+	return newBreaker(c, window, openPeriod, stats), nil
+}
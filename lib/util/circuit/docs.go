@@ -0,0 +1,15 @@
+package circuit
+
+import "github.com/Jeffail/benthos/v3/internal/docs"
+
+// FieldSpecs returns documentation specs for circuit breaker fields.
+func FieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("name", "An optional name for the circuit breaker. When set the state of the circuit breaker can be queried from the HTTP endpoint `/circuit_breakers/{name}`. Names must be unique, which means a named circuit breaker cannot be used within a processor pipeline that has more than one thread."),
+		docs.FieldCommon("error_threshold", "The ratio of failed requests within `window` at which the circuit opens, between 0 and 1."),
+		docs.FieldAdvanced("min_requests", "The minimum number of requests within `window` before the error rate is considered."),
+		docs.FieldAdvanced("window", "The rolling period over which the error rate of requests is calculated."),
+		docs.FieldCommon("open_period", "The period to wait after the circuit opens before requests are attempted again in a half-open state."),
+		docs.FieldAdvanced("half_open_requests", "The number of successful requests required in a half-open state for the circuit to close. Any failed request in a half-open state opens the circuit again."),
+	}
+}
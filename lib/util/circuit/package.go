@@ -0,0 +1,3 @@
+// Package circuit implements a circuit breaker around a standard configuration
+// scheme, allowing components to stop calling a dependency that is failing.
+package circuit
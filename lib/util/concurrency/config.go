@@ -0,0 +1,64 @@
+package concurrency
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config contains configuration params for adaptive concurrency.
+type Config struct {
+	Enabled          bool    `json:"enabled" yaml:"enabled"`
+	Algorithm        string  `json:"algorithm" yaml:"algorithm"`
+	MinInFlight      int     `json:"min_in_flight" yaml:"min_in_flight"`
+	MaxInFlight      int     `json:"max_in_flight" yaml:"max_in_flight"`
+	LatencyThreshold string  `json:"latency_threshold" yaml:"latency_threshold"`
+	BackoffRatio     float64 `json:"backoff_ratio" yaml:"backoff_ratio"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:          false,
+		Algorithm:        "aimd",
+		MinInFlight:      1,
+		MaxInFlight:      64,
+		LatencyThreshold: "1s",
+		BackoffRatio:     0.9,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewLimiter returns a Limiter based on the configuration values of Config.
+func (c Config) NewLimiter() (*Limiter, error) {
+	if c.MinInFlight < 1 {
+		return nil, errors.New("min_in_flight must be greater than zero")
+	}
+	if c.MaxInFlight < c.MinInFlight {
+		return nil, fmt.Errorf("max_in_flight (%v) must not be less than min_in_flight (%v)", c.MaxInFlight, c.MinInFlight)
+	}
+	if c.BackoffRatio <= 0 || c.BackoffRatio >= 1 {
+		return nil, errors.New("backoff_ratio must be between 0 and 1")
+	}
+
+	var algo algorithm
+	switch c.Algorithm {
+	case "aimd":
+		threshold, err := time.ParseDuration(c.LatencyThreshold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse latency_threshold: %v", err)
+		}
+		algo = &aimd{
+			threshold:    threshold,
+			backoffRatio: c.BackoffRatio,
+		}
+	case "gradient":
+		algo = &gradient{
+			backoffRatio: c.BackoffRatio,
+		}
+	default:
+		return nil, fmt.Errorf("algorithm not recognised: %v", c.Algorithm)
+	}
+	return newLimiter(c.MinInFlight, c.MaxInFlight, algo), nil
+}
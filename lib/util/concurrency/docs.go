@@ -0,0 +1,23 @@
+package concurrency
+
+import "github.com/Jeffail/benthos/v3/internal/docs"
+
+// FieldSpec returns documentation specs for adaptive concurrency fields.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"adaptive_concurrency",
+		"Adjust the number of messages in flight between `min_in_flight` and `max_in_flight` based on the observed latency and errors of requests. When enabled the `max_in_flight` field of the output is ignored in favour of the `max_in_flight` of this object, and the current limit is exposed as the gauge `in_flight.limit`.",
+	).WithChildren(
+		docs.FieldAdvanced("enabled", "Whether the number of messages in flight should be adjusted adaptively."),
+		docs.FieldAdvanced(
+			"algorithm", "The algorithm used to adjust the limit.",
+		).HasAnnotatedOptions(
+			"aimd", "Increases the limit by one for each window of successful requests, and multiplies the limit by `backoff_ratio` when a request fails or exceeds `latency_threshold`.",
+			"gradient", "Adjusts the limit by the ratio of the long term average latency to the latency of each request, and multiplies the limit by `backoff_ratio` when a request fails.",
+		),
+		docs.FieldAdvanced("min_in_flight", "The minimum number of messages to have in flight, which is also the starting limit."),
+		docs.FieldAdvanced("max_in_flight", "The maximum number of messages to have in flight."),
+		docs.FieldAdvanced("latency_threshold", "The latency above which requests are treated as a sign of an overloaded target by the `aimd` algorithm.", "500ms", "5s"),
+		docs.FieldAdvanced("backoff_ratio", "The ratio by which the limit is multiplied when the target appears overloaded, must be between 0 and 1."),
+	).AtVersion("3.42.0")
+}
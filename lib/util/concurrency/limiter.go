@@ -0,0 +1,134 @@
+package concurrency
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// algorithm calculates a new limit from the current limit and the outcome of a
+// request.
+type algorithm interface {
+	Update(limit float64, latency time.Duration, failed bool) float64
+}
+
+// aimd implements additive increase, multiplicative decrease.
+type aimd struct {
+	threshold    time.Duration
+	backoffRatio float64
+}
+
+func (a *aimd) Update(limit float64, latency time.Duration, failed bool) float64 {
+	if failed || latency > a.threshold {
+		return limit * a.backoffRatio
+	}
+	return limit + 1/limit
+}
+
+const (
+	gradientSmoothing  = 0.2
+	gradientLongWindow = 600
+	gradientTolerance  = 1.5
+)
+
+// gradient adjusts the limit by the ratio of the long term average latency to
+// the latency of the most recent request, with a queue allowance of the
+// square root of the limit in order to probe for more capacity.
+type gradient struct {
+	backoffRatio float64
+	longRTT      float64
+	samples      int
+}
+
+func (g *gradient) Update(limit float64, latency time.Duration, failed bool) float64 {
+	if failed {
+		return limit * g.backoffRatio
+	}
+
+	rtt := float64(latency)
+	if rtt <= 0 {
+		rtt = 1
+	}
+	if g.samples < gradientLongWindow {
+		g.samples++
+	}
+	g.longRTT += (rtt - g.longRTT) / float64(g.samples)
+
+	// If the long term average has drifted far beyond recent latencies then
+	// it's reset towards them so that recovery isn't held back.
+	if g.longRTT/rtt > 2 {
+		g.longRTT = rtt * 2
+	}
+
+	grad := math.Max(0.5, math.Min(1, gradientTolerance*g.longRTT/rtt))
+	newLimit := limit*grad + math.Sqrt(limit)
+	return limit*(1-gradientSmoothing) + newLimit*gradientSmoothing
+}
+
+//------------------------------------------------------------------------------
+
+// Limiter restricts the number of requests in flight to a limit that is
+// adjusted according to the observed latency and errors of those requests.
+type Limiter struct {
+	min, max int
+	algo     algorithm
+
+	mut      sync.Mutex
+	limit    float64
+	inFlight int
+	released chan struct{}
+}
+
+func newLimiter(min, max int, algo algorithm) *Limiter {
+	return &Limiter{
+		min:      min,
+		max:      max,
+		algo:     algo,
+		limit:    float64(min),
+		released: make(chan struct{}),
+	}
+}
+
+// Limit returns the current limit of requests in flight.
+func (l *Limiter) Limit() int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return int(l.limit)
+}
+
+// Acquire blocks until a request can be made without exceeding the current
+// limit, returns false if the context is cancelled first.
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	for {
+		l.mut.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mut.Unlock()
+			return true
+		}
+		released := l.released
+		l.mut.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Release marks the end of a request, providing its latency and whether it
+// failed in order to adjust the limit.
+func (l *Limiter) Release(latency time.Duration, failed bool) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.inFlight--
+	l.limit = math.Max(float64(l.min), math.Min(float64(l.max), l.algo.Update(l.limit, latency, failed)))
+
+	close(l.released)
+	l.released = make(chan struct{})
+}
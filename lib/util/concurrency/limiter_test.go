@@ -0,0 +1,147 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterConfigErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf        func(c *Config)
+		errContains string
+	}{
+		"bad min": {
+			conf:        func(c *Config) { c.MinInFlight = 0 },
+			errContains: "min_in_flight must be greater than zero",
+		},
+		"max below min": {
+			conf: func(c *Config) {
+				c.MinInFlight = 5
+				c.MaxInFlight = 2
+			},
+			errContains: "must not be less than min_in_flight",
+		},
+		"bad ratio": {
+			conf:        func(c *Config) { c.BackoffRatio = 1.5 },
+			errContains: "backoff_ratio must be between 0 and 1",
+		},
+		"bad algorithm": {
+			conf:        func(c *Config) { c.Algorithm = "nope" },
+			errContains: "algorithm not recognised",
+		},
+		"bad threshold": {
+			conf:        func(c *Config) { c.LatencyThreshold = "nope" },
+			errContains: "failed to parse latency_threshold",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			test.conf(&conf)
+			_, err := conf.NewLimiter()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
+
+func TestLimiterAIMD(t *testing.T) {
+	conf := NewConfig()
+	conf.MinInFlight = 2
+	conf.LatencyThreshold = "100ms"
+	conf.BackoffRatio = 0.5
+
+	conf.MaxInFlight = 10
+	l, err := conf.NewLimiter()
+	require.NoError(t, err)
+	assert.Equal(t, 2, l.Limit())
+
+	ctx := context.Background()
+	for i := 0; i < 200; i++ {
+		require.True(t, l.Acquire(ctx))
+		l.Release(time.Millisecond, false)
+	}
+	assert.Equal(t, 10, l.Limit())
+
+	require.True(t, l.Acquire(ctx))
+	l.Release(time.Second, false)
+	assert.Equal(t, 5, l.Limit())
+
+	require.True(t, l.Acquire(ctx))
+	l.Release(time.Millisecond, true)
+	assert.Equal(t, 2, l.Limit())
+
+	require.True(t, l.Acquire(ctx))
+	l.Release(time.Millisecond, true)
+	assert.Equal(t, 2, l.Limit(), "limit does not drop below min")
+}
+
+func TestLimiterGradient(t *testing.T) {
+	conf := NewConfig()
+	conf.Algorithm = "gradient"
+	conf.MinInFlight = 1
+
+	conf.MaxInFlight = 50
+	l, err := conf.NewLimiter()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		require.True(t, l.Acquire(ctx))
+		l.Release(10*time.Millisecond, false)
+	}
+	assert.Equal(t, 50, l.Limit())
+
+	for i := 0; i < 20; i++ {
+		require.True(t, l.Acquire(ctx))
+		l.Release(100*time.Millisecond, false)
+	}
+	assert.Less(t, l.Limit(), 25)
+
+	before := l.Limit()
+	require.True(t, l.Acquire(ctx))
+	l.Release(10*time.Millisecond, true)
+	assert.Less(t, l.Limit(), before)
+}
+
+func TestLimiterAcquireBlocks(t *testing.T) {
+	conf := NewConfig()
+	conf.MinInFlight = 2
+
+	conf.MaxInFlight = 2
+	l, err := conf.NewLimiter()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.True(t, l.Acquire(ctx))
+	require.True(t, l.Acquire(ctx))
+
+	tctx, done := context.WithTimeout(ctx, time.Millisecond*50)
+	assert.False(t, l.Acquire(tctx))
+	done()
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- l.Acquire(ctx)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired beyond the limit")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	l.Release(time.Millisecond, false)
+	select {
+	case ok := <-acquired:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
@@ -0,0 +1,3 @@
+// Package concurrency implements adaptive limits on the number of in-flight
+// requests around a standard configuration scheme.
+package concurrency
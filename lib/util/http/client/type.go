@@ -115,6 +115,10 @@ type Type struct {
 	closeChan <-chan struct{}
 }
 
+type roundTripperProvider interface {
+	GetHTTPRoundTripper() http.RoundTripper
+}
+
 // New creates a new Type.
 func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	urlStr, err := bloblang.NewField(conf.URL)
@@ -198,6 +202,14 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		opt(&h)
 	}
 
+	// The manager is able to override the transport of all HTTP clients, which
+	// is used in order to mock endpoints during config tests.
+	if rtp, ok := h.mgr.(roundTripperProvider); ok {
+		if rt := rtp.GetHTTPRoundTripper(); rt != nil {
+			h.client.Transport = rt
+		}
+	}
+
 	h.mCount = h.stats.GetCounter("count")
 	h.mErr = h.stats.GetCounter("error")
 	h.mErrReq = h.stats.GetCounter("error.request")
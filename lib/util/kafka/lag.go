@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// LagMetricsPeriod is the period at which partition lag gauges are updated.
+var LagMetricsPeriod = time.Second * 5
+
+// PartitionLag tracks the committed offset of a consumed partition in order to
+// report how far it lags behind the high water mark of the partition.
+type PartitionLag struct {
+	committed int64
+	hwmFn     func() int64
+	gauge     metrics.StatGauge
+}
+
+// NewPartitionLag creates a lag tracker for a partition, where the initial
+// offset is the offset that consumption begins from, which may be negative
+// when it is not yet known, and hwmFn returns the current high water mark of
+// the partition.
+func NewPartitionLag(initialOffset int64, hwmFn func() int64, gauge metrics.StatGauge) *PartitionLag {
+	if initialOffset < 0 {
+		initialOffset = -1
+	}
+	return &PartitionLag{
+		committed: initialOffset,
+		hwmFn:     hwmFn,
+		gauge:     gauge,
+	}
+}
+
+// Consumed should be called with the offset of each consumed message, it is
+// used as the committed offset until a commit has been made.
+func (p *PartitionLag) Consumed(offset int64) {
+	atomic.CompareAndSwapInt64(&p.committed, -1, offset)
+}
+
+// Committed should be called with the offset to be committed for the
+// partition, which is the offset of the next message to consume.
+func (p *PartitionLag) Committed(offset int64) {
+	atomic.StoreInt64(&p.committed, offset)
+}
+
+// Lag returns the difference between the high water mark of the partition and
+// the committed offset, and a boolean indicating whether the committed offset
+// is known yet.
+func (p *PartitionLag) Lag() (int64, bool) {
+	committed := atomic.LoadInt64(&p.committed)
+	if committed < 0 {
+		return 0, false
+	}
+	lag := p.hwmFn() - committed
+	if lag < 0 {
+		lag = 0
+	}
+	return lag, true
+}
+
+// Run updates the lag gauge periodically until the context is cancelled.
+func (p *PartitionLag) Run(ctx context.Context) {
+	ticker := time.NewTicker(LagMetricsPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if lag, ok := p.Lag(); ok {
+				p.gauge.Set(lag)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
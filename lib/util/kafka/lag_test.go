@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionLag(t *testing.T) {
+	hwm := int64(20)
+	lag := NewPartitionLag(-2, func() int64 {
+		return atomic.LoadInt64(&hwm)
+	}, metrics.Noop().GetGauge("foo"))
+
+	_, ok := lag.Lag()
+	assert.False(t, ok)
+
+	lag.Consumed(5)
+	lag.Consumed(6)
+	l, ok := lag.Lag()
+	assert.True(t, ok)
+	assert.Equal(t, int64(15), l)
+
+	lag.Committed(12)
+	l, _ = lag.Lag()
+	assert.Equal(t, int64(8), l)
+
+	lag.Consumed(13)
+	atomic.StoreInt64(&hwm, 30)
+	l, _ = lag.Lag()
+	assert.Equal(t, int64(18), l)
+
+	lag.Committed(35)
+	l, _ = lag.Lag()
+	assert.Equal(t, int64(0), l)
+}
+
+func TestPartitionLagRun(t *testing.T) {
+	defer func(p time.Duration) {
+		LagMetricsPeriod = p
+	}(LagMetricsPeriod)
+	LagMetricsPeriod = time.Millisecond
+
+	stats := metrics.NewLocal()
+	lag := NewPartitionLag(10, func() int64 {
+		return 25
+	}, stats.GetGauge("lag"))
+
+	ctx, done := context.WithCancel(context.Background())
+	go lag.Run(ctx)
+
+	assert.Eventually(t, func() bool {
+		return stats.GetCounters()["lag"] == 15
+	}, time.Second, time.Millisecond)
+	done()
+}
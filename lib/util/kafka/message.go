@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+// MetadataDescription describes the metadata fields added to messages consumed
+// from Kafka, for use within the documentation of inputs.
+const MetadataDescription = `### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- kafka_key
+- kafka_topic
+- kafka_partition
+- kafka_offset
+- kafka_lag
+- kafka_timestamp
+- kafka_timestamp_unix
+- kafka_timestamp_unix_ms
+- All existing message headers (version 0.11+)
+` + "```" + `
+
+The field ` + "`kafka_lag`" + ` is the calculated difference between the high
+water mark offset of the partition at the time of ingestion and the current
+message offset.
+
+The field ` + "`kafka_timestamp`" + ` is the timestamp of the message in RFC
+3339 format, and the fields ` + "`kafka_timestamp_unix`" + ` and
+` + "`kafka_timestamp_unix_ms`" + ` are the same timestamp as a unix epoch in
+seconds and milliseconds respectively.
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).`
+
+// MessageToPart converts a message consumed from Kafka into a message part,
+// with the headers of the message and details such as its topic, partition,
+// offset, timestamp and lag added as metadata. The high water mark is the
+// offset of the next message to be produced to the partition.
+func MessageToPart(highWaterMark int64, data *sarama.ConsumerMessage) types.Part {
+	part := message.NewPart(data.Value)
+
+	meta := part.Metadata()
+	for _, hdr := range data.Headers {
+		meta.Set(string(hdr.Key), string(hdr.Value))
+	}
+
+	lag := highWaterMark - data.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+
+	meta.Set("kafka_key", string(data.Key))
+	meta.Set("kafka_partition", strconv.Itoa(int(data.Partition)))
+	meta.Set("kafka_topic", data.Topic)
+	meta.Set("kafka_offset", strconv.FormatInt(data.Offset, 10))
+	meta.Set("kafka_lag", strconv.FormatInt(lag, 10))
+	meta.Set("kafka_timestamp", data.Timestamp.Format(time.RFC3339Nano))
+	meta.Set("kafka_timestamp_unix", strconv.FormatInt(data.Timestamp.Unix(), 10))
+	meta.Set("kafka_timestamp_unix_ms", strconv.FormatInt(data.Timestamp.UnixNano()/int64(time.Millisecond), 10))
+
+	return part
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageToPart(t *testing.T) {
+	ts := time.Unix(1600000000, int64(250*time.Millisecond)).UTC()
+
+	part := MessageToPart(15, &sarama.ConsumerMessage{
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("foo"), Value: []byte("bar")},
+		},
+		Timestamp: ts,
+		Key:       []byte("mykey"),
+		Value:     []byte("hello world"),
+		Topic:     "mytopic",
+		Partition: 2,
+		Offset:    10,
+	})
+
+	assert.Equal(t, "hello world", string(part.Get()))
+
+	meta := map[string]string{}
+	part.Metadata().Iter(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+	assert.Equal(t, map[string]string{
+		"foo":                     "bar",
+		"kafka_key":               "mykey",
+		"kafka_partition":         "2",
+		"kafka_topic":             "mytopic",
+		"kafka_offset":            "10",
+		"kafka_lag":               "4",
+		"kafka_timestamp":         "2020-09-13T12:26:40.25Z",
+		"kafka_timestamp_unix":    "1600000000",
+		"kafka_timestamp_unix_ms": "1600000000250",
+	}, meta)
+}
+
+func TestMessageToPartNoLag(t *testing.T) {
+	part := MessageToPart(5, &sarama.ConsumerMessage{
+		Offset: 10,
+	})
+	assert.Equal(t, "0", part.Metadata().Get("kafka_lag"))
+}
@@ -0,0 +1,21 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ParseTimestamp parses a timestamp from either an RFC 3339 formatted string or
+// a unix timestamp in seconds.
+func ParseTimestamp(str string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(str, 64); err == nil {
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*float64(time.Second))), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp '%v': expected RFC 3339 or unix seconds", str)
+	}
+	return t, nil
+}
@@ -0,0 +1,26 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := ParseTimestamp("1600000000")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1600000000, 0), ts)
+
+	ts, err = ParseTimestamp("1600000000.5")
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1600000000, int64(500*time.Millisecond)), ts)
+
+	ts, err = ParseTimestamp("2020-09-13T12:26:40Z")
+	require.NoError(t, err)
+	assert.True(t, time.Unix(1600000000, 0).Equal(ts))
+
+	_, err = ParseTimestamp("not a timestamp")
+	require.Error(t, err)
+}
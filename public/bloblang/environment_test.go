@@ -42,3 +42,13 @@ func TestEnvironment(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "bar", v)
 }
+
+func TestExecutorCheckTypes(t *testing.T) {
+	exe, err := Parse(`root.a = this.a.uppercase()
+root.b = 10.uppercase()`)
+	require.NoError(t, err)
+
+	errs := exe.CheckTypes()
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "line 2: method uppercase: expected string or bytes value, found number")
+}
@@ -31,3 +31,26 @@ func (e *Executor) Query(v interface{}) (interface{}, error) {
 		MsgBatch: msg,
 	}.WithValue(v))
 }
+
+// CheckTypes statically analyses the mapping and returns any type errors that
+// are certain to occur when it is executed, regardless of the input data. For
+// example, calling a string method on the result of a function that always
+// returns a number.
+func (e *Executor) CheckTypes() []error {
+	return e.exec.CheckTypes()
+}
+
+// XUnwrapper is for internal use only, do not use this.
+func (e *Executor) XUnwrapper() interface {
+	Unwrap() *mapping.Executor
+} {
+	return executorUnwrapper{exec: e.exec}
+}
+
+type executorUnwrapper struct {
+	exec *mapping.Executor
+}
+
+func (e executorUnwrapper) Unwrap() *mapping.Executor {
+	return e.exec
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+)
+
+// BatchPolicy describes the mechanisms by which batching should be performed
+// of messages destined for a batch output. This is returned by constructors of
+// batch outputs.
+type BatchPolicy struct {
+	ByteSize int
+	Count    int
+	Check    string
+	Period   string
+}
+
+func (b BatchPolicy) toInternal() batch.PolicyConfig {
+	batchConf := batch.NewPolicyConfig()
+	batchConf.ByteSize = b.ByteSize
+	batchConf.Count = b.Count
+	batchConf.Check = b.Check
+	batchConf.Period = b.Period
+	return batchConf
+}
+
+// NewBatchPolicyField defines a new object type config field that describes a
+// batching policy for batched outputs. It is then possible to extract a
+// BatchPolicy from the resulting parsed config with the method
+// FieldBatchPolicy.
+func NewBatchPolicyField(name string) *ConfigField {
+	return NewObjectField(name,
+		NewIntField("count").
+			Description("A number of messages at which the batch should be flushed. If `0` disables count based batching.").
+			Default(0),
+		NewIntField("byte_size").
+			Description("An amount of bytes at which the batch should be flushed. If `0` disables size based batching.").
+			Default(0),
+		NewDurationField("period").
+			Description("A period in which an incomplete batch should be flushed regardless of its size.").
+			Default("").
+			Example("1s").Example("1m").Example("500ms"),
+		NewBloblangField("check").
+			Description("A [Bloblang query](/docs/guides/bloblang/about/) that should return a boolean value indicating whether a message should end a batch.").
+			Default("").
+			Example(`this.type == "end_of_transaction"`),
+	).Description("Allows you to configure a [batching policy](/docs/configuration/batching).")
+}
+
+// FieldBatchPolicy accesses a field from a parsed config that was defined with
+// NewBatchPolicyField and returns a BatchPolicy, or an error if the
+// configuration was invalid.
+func (p *ParsedConfig) FieldBatchPolicy(path ...string) (conf BatchPolicy, err error) {
+	pConf := p.Namespace(path...)
+	if conf.Count, err = pConf.FieldInt("count"); err != nil {
+		return
+	}
+	if conf.ByteSize, err = pConf.FieldInt("byte_size"); err != nil {
+		return
+	}
+	if conf.Check, err = pConf.FieldString("check"); err != nil {
+		return
+	}
+	if conf.Period, err = pConf.FieldString("period"); err != nil {
+		return
+	}
+	return
+}
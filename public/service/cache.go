@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Errors returned by cache types.
+var (
+	ErrKeyAlreadyExists = errors.New("key already exists")
+	ErrKeyNotFound      = errors.New("key does not exist")
+)
+
+// Cache is an interface implemented by Benthos caches.
+type Cache interface {
+	// Get a cache item.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set a cache item, specifying an optional TTL. It is okay for caches to
+	// ignore the ttl parameter if it isn't possible to implement.
+	Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error
+
+	// Add is the same operation as Set except that it returns an error if the
+	// key already exists. It is okay for caches to return nil on duplicates if
+	// it isn't possible to implement.
+	Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error
+
+	// Delete attempts to remove a key. If the key does not exist then it is
+	// considered correct to return an error, however, for cache
+	// implementations where it is difficult to determine this then it is
+	// acceptable to return nil.
+	Delete(ctx context.Context, key string) error
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// CacheConstructor is a func that's provided a configuration type and access
+// to a service manager and must return an instantiation of a cache based on
+// the config, or an error.
+type CacheConstructor func(conf *ParsedConfig, mgr *Resources) (Cache, error)
+
+// RegisterCache attempts to register a new cache plugin by providing a
+// description of the configuration for the plugin as well as a constructor for
+// the cache itself. The constructor will be called for each instantiation of
+// the component within a config.
+func RegisterCache(name string, spec *ConfigSpec, ctor CacheConstructor) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if _, exists := cache.Constructors[name]; exists {
+		return fmt.Errorf("cache '%v' is already registered", name)
+	}
+	if err := claimName("cache", name); err != nil {
+		return err
+	}
+	cache.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Cache, error) {
+		c, err := ctor(spec.configFromPlugin(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapCache(c), nil
+	})
+	cache.DocumentPlugin(name, spec.pluginDescription(), spec.configSanitiser())
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapCache adapts a Cache implementation to the types.CacheWithTTL
+// interface.
+type airGapCache struct {
+	c Cache
+	*asyncCloser
+}
+
+func newAirGapCache(c Cache) *airGapCache {
+	return &airGapCache{
+		c:           c,
+		asyncCloser: newAsyncCloser(c.Close),
+	}
+}
+
+func toInternalCacheErr(err error) error {
+	if errors.Is(err, ErrKeyNotFound) {
+		return types.ErrKeyNotFound
+	}
+	if errors.Is(err, ErrKeyAlreadyExists) {
+		return types.ErrKeyAlreadyExists
+	}
+	return err
+}
+
+func (a *airGapCache) Get(key string) ([]byte, error) {
+	b, err := a.c.Get(context.Background(), key)
+	return b, toInternalCacheErr(err)
+}
+
+func (a *airGapCache) Set(key string, value []byte) error {
+	return a.SetWithTTL(key, value, nil)
+}
+
+func (a *airGapCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return toInternalCacheErr(a.c.Set(context.Background(), key, value, ttl))
+}
+
+func (a *airGapCache) SetMulti(items map[string][]byte) error {
+	for k, v := range items {
+		if err := a.SetWithTTL(k, v, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *airGapCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := a.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *airGapCache) Add(key string, value []byte) error {
+	return a.AddWithTTL(key, value, nil)
+}
+
+func (a *airGapCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return toInternalCacheErr(a.c.Add(context.Background(), key, value, ttl))
+}
+
+func (a *airGapCache) Delete(key string) error {
+	return toInternalCacheErr(a.c.Delete(context.Background(), key))
+}
+
+//------------------------------------------------------------------------------
+
+// reverseAirGapCache adapts a types.Cache implementation to the Cache
+// interface, allowing plugins to access cache resources.
+type reverseAirGapCache struct {
+	c types.Cache
+}
+
+func newReverseAirGapCache(c types.Cache) *reverseAirGapCache {
+	return &reverseAirGapCache{c}
+}
+
+func fromInternalCacheErr(err error) error {
+	switch err {
+	case types.ErrKeyNotFound:
+		return ErrKeyNotFound
+	case types.ErrKeyAlreadyExists:
+		return ErrKeyAlreadyExists
+	}
+	return err
+}
+
+func (r *reverseAirGapCache) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := r.c.Get(key)
+	return b, fromInternalCacheErr(err)
+}
+
+func (r *reverseAirGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if ttlCache, ok := r.c.(types.CacheWithTTL); ok {
+		return fromInternalCacheErr(ttlCache.SetWithTTL(key, value, ttl))
+	}
+	return fromInternalCacheErr(r.c.Set(key, value))
+}
+
+func (r *reverseAirGapCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if ttlCache, ok := r.c.(types.CacheWithTTL); ok {
+		return fromInternalCacheErr(ttlCache.AddWithTTL(key, value, ttl))
+	}
+	return fromInternalCacheErr(r.c.Add(key, value))
+}
+
+func (r *reverseAirGapCache) Delete(ctx context.Context, key string) error {
+	return fromInternalCacheErr(r.c.Delete(key))
+}
+
+func (r *reverseAirGapCache) Close(ctx context.Context) error {
+	// Cache resources are owned by the service manager and are therefore not
+	// closed by plugins.
+	return nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// asyncCloser adapts the context aware Close method of a plugin to the
+// CloseAsync and WaitForClose methods expected by Benthos components.
+type asyncCloser struct {
+	closeFn    func(ctx context.Context) error
+	closeOnce  sync.Once
+	closedChan chan struct{}
+}
+
+func newAsyncCloser(closeFn func(ctx context.Context) error) *asyncCloser {
+	return &asyncCloser{
+		closeFn:    closeFn,
+		closedChan: make(chan struct{}),
+	}
+}
+
+// CloseAsync triggers the plugin to close in the background.
+func (a *asyncCloser) CloseAsync() {
+	a.closeOnce.Do(func() {
+		go func() {
+			_ = a.closeFn(context.Background())
+			close(a.closedChan)
+		}()
+	})
+}
+
+// WaitForClose blocks until the plugin has closed or the timeout is reached.
+func (a *asyncCloser) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-a.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+func checkName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("plugin name '%v' does not match the required regular expression /%v/", name, nameRegexp.String())
+	}
+	return nil
+}
+
+var (
+	registeredMut   sync.Mutex
+	registeredNames = map[string]struct{}{}
+)
+
+// claimName reserves a plugin name for a given component type, returning an
+// error if a plugin of the same type and name has already been registered.
+func claimName(kind, name string) error {
+	registeredMut.Lock()
+	defer registeredMut.Unlock()
+
+	key := kind + ":" + name
+	if _, exists := registeredNames[key]; exists {
+		return fmt.Errorf("%v '%v' is already registered", kind, name)
+	}
+	registeredNames[key] = struct{}{}
+	return nil
+}
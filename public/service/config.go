@@ -0,0 +1,599 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigField describes a field within a component configuration, to be added
+// to a ConfigSpec.
+type ConfigField struct {
+	field docs.FieldSpec
+}
+
+// NewStringField describes a new string type config field.
+func NewStringField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldString),
+	}
+}
+
+// NewInterpolatedStringField describes a new config field consisting of a
+// string containing interpolation functions. These fields can be parsed with
+// ParsedConfig.FieldInterpolatedString.
+func NewInterpolatedStringField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldString).SupportsInterpolation(false),
+	}
+}
+
+// NewBloblangField describes a new config field consisting of a Bloblang
+// mapping. These fields can be parsed with ParsedConfig.FieldBloblang.
+func NewBloblangField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldString),
+	}
+}
+
+// NewDurationField describes a new string type config field containing a
+// duration, such as "1s" or "100ms". These fields can be parsed with
+// ParsedConfig.FieldDuration.
+func NewDurationField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldString),
+	}
+}
+
+// NewStringListField describes a new config field consisting of a list of
+// strings.
+func NewStringListField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldArray),
+	}
+}
+
+// NewIntField describes a new int type config field.
+func NewIntField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldNumber),
+	}
+}
+
+// NewFloatField describes a new float type config field.
+func NewFloatField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldNumber),
+	}
+}
+
+// NewBoolField describes a new bool type config field.
+func NewBoolField(name string) *ConfigField {
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").HasType(docs.FieldBool),
+	}
+}
+
+// NewObjectField describes a new object type config field, consisting of one
+// or more child fields.
+func NewObjectField(name string, fields ...*ConfigField) *ConfigField {
+	children := make([]docs.FieldSpec, len(fields))
+	for i, f := range fields {
+		children[i] = f.field
+	}
+	return &ConfigField{
+		field: docs.FieldCommon(name, "").WithChildren(children...),
+	}
+}
+
+// Description adds a description to the field which will be shown when
+// printing documentation for the component config spec.
+func (c *ConfigField) Description(d string) *ConfigField {
+	c.field.Description = d
+	return c
+}
+
+// Advanced marks the config field as being advanced, and therefore it will not
+// appear in simplified documentation examples.
+func (c *ConfigField) Advanced() *ConfigField {
+	c.field.Advanced = true
+	return c
+}
+
+// Default specifies a default value that this field will assume if it is
+// omitted from a provided config. Fields that do not have a default value are
+// considered mandatory, and so accessing them from a parsed config will fail
+// in their absence.
+func (c *ConfigField) Default(v interface{}) *ConfigField {
+	c.field.Default = v
+	return c
+}
+
+// Example adds an example value to the field which will be shown when printing
+// documentation for the component config spec.
+func (c *ConfigField) Example(e interface{}) *ConfigField {
+	c.field.Examples = append(c.field.Examples, e)
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+// ConfigSpec describes the configuration specification for a plugin
+// component. This will be used for validating and linting configuration files
+// and providing a parsed configuration struct to the plugin constructor.
+type ConfigSpec struct {
+	component docs.ComponentSpec
+}
+
+// NewConfigSpec creates a new empty component configuration spec. If the
+// plugin does not require configuration fields the result of this call is
+// enough.
+func NewConfigSpec() *ConfigSpec {
+	return &ConfigSpec{
+		component: docs.ComponentSpec{
+			Status: docs.StatusStable,
+		},
+	}
+}
+
+// Stable sets a documentation label on the component indicating that its
+// configuration spec is stable. Plugins are considered stable by default.
+func (c *ConfigSpec) Stable() *ConfigSpec {
+	c.component.Status = docs.StatusStable
+	return c
+}
+
+// Beta sets a documentation label on the component indicating that its
+// configuration spec is ready for beta testing, meaning backwards incompatible
+// changes will not be made unless a fundamental problem is found.
+func (c *ConfigSpec) Beta() *ConfigSpec {
+	c.component.Status = docs.StatusBeta
+	return c
+}
+
+// Experimental sets a documentation label on the component indicating that its
+// configuration spec is experimental and therefore might change in a backwards
+// incompatible way.
+func (c *ConfigSpec) Experimental() *ConfigSpec {
+	c.component.Status = docs.StatusExperimental
+	return c
+}
+
+// Version specifies that this component was introduced in a given version.
+func (c *ConfigSpec) Version(v string) *ConfigSpec {
+	c.component.Version = v
+	return c
+}
+
+// Categories adds one or more string tags to the component, these are used for
+// arbitrarily grouping components in documentation.
+func (c *ConfigSpec) Categories(categories ...string) *ConfigSpec {
+	c.component.Categories = append(c.component.Categories, categories...)
+	return c
+}
+
+// Summary adds a short summary to the component config spec that describes the
+// general purpose of the component.
+func (c *ConfigSpec) Summary(summary string) *ConfigSpec {
+	c.component.Summary = summary
+	return c
+}
+
+// Description adds a description to the component config spec that describes
+// the behaviour of the component and how it should be configured.
+func (c *ConfigSpec) Description(description string) *ConfigSpec {
+	c.component.Description = description
+	return c
+}
+
+// Field sets the specification of a field within the config spec, used for
+// linting and generating documentation for the component.
+func (c *ConfigSpec) Field(f *ConfigField) *ConfigSpec {
+	c.component.Fields = append(c.component.Fields, f.field)
+	return c
+}
+
+// Example adds an example to a plugin configuration spec that demonstrates how
+// the component can be used. An example has a title, summary, and a YAML
+// configuration snippet showing a real use case.
+func (c *ConfigSpec) Example(title, summary, config string) *ConfigSpec {
+	c.component.Examples = append(c.component.Examples, docs.AnnotatedExample{
+		Title:   title,
+		Summary: summary,
+		Config:  config,
+	})
+	return c
+}
+
+// ParseYAML attempts to parse a YAML document as the defined configuration
+// spec and returns a parsed config view. This is useful for testing plugin
+// constructors.
+func (c *ConfigSpec) ParseYAML(yamlStr string) (*ParsedConfig, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(yamlStr), &raw); err != nil {
+		return nil, err
+	}
+	return c.configFromRaw(raw), nil
+}
+
+//------------------------------------------------------------------------------
+
+// pluginConfig is the config structure provided to the underlying plugin APIs
+// of each component type, it captures the raw plugin config so that it can be
+// parsed according to a spec during construction.
+type pluginConfig struct {
+	raw interface{}
+}
+
+func (p *pluginConfig) UnmarshalYAML(value *yaml.Node) error {
+	return value.Decode(&p.raw)
+}
+
+func (p pluginConfig) MarshalYAML() (interface{}, error) {
+	return p.raw, nil
+}
+
+func (p pluginConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.raw)
+}
+
+// configConstructor returns a function that creates plugin configs populated
+// with the default values of the spec.
+func (c *ConfigSpec) configConstructor() func() interface{} {
+	return func() interface{} {
+		return &pluginConfig{
+			raw: defaultsOf(c.component.Fields),
+		}
+	}
+}
+
+// configSanitiser returns a function that reduces a plugin config to only the
+// fields that are known by the spec, which results in unknown fields being
+// reported by the config linter.
+func (c *ConfigSpec) configSanitiser() func(conf interface{}) interface{} {
+	return func(conf interface{}) interface{} {
+		pConf, ok := conf.(*pluginConfig)
+		if !ok || pConf == nil {
+			return conf
+		}
+		return sanitiseFields(c.component.Fields, pConf.raw)
+	}
+}
+
+// configFromPlugin creates a parsed config from the config given to a plugin
+// constructor.
+func (c *ConfigSpec) configFromPlugin(conf interface{}) *ParsedConfig {
+	var raw interface{}
+	if pConf, ok := conf.(*pluginConfig); ok && pConf != nil {
+		raw = pConf.raw
+	}
+	return c.configFromRaw(raw)
+}
+
+func (c *ConfigSpec) configFromRaw(raw interface{}) *ParsedConfig {
+	return &ParsedConfig{
+		generic: applyDefaults(c.component.Fields, raw),
+	}
+}
+
+func defaultsOf(fields docs.FieldSpecs) map[string]interface{} {
+	defaults := map[string]interface{}{}
+	for _, f := range fields {
+		if f.Default != nil {
+			defaults[f.Name] = f.Default
+		} else if len(f.Children) > 0 {
+			if childDefaults := defaultsOf(f.Children); len(childDefaults) > 0 {
+				defaults[f.Name] = childDefaults
+			}
+		}
+	}
+	return defaults
+}
+
+func applyDefaults(fields docs.FieldSpecs, raw interface{}) interface{} {
+	obj, isObj := raw.(map[string]interface{})
+	if raw == nil {
+		obj, isObj = map[string]interface{}{}, true
+	}
+	if !isObj {
+		return raw
+	}
+	res := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		res[k] = v
+	}
+	for _, f := range fields {
+		v, exists := res[f.Name]
+		if !exists {
+			if f.Default != nil {
+				res[f.Name] = f.Default
+			} else if len(f.Children) > 0 {
+				if childDefaults := defaultsOf(f.Children); len(childDefaults) > 0 {
+					res[f.Name] = childDefaults
+				}
+			}
+			continue
+		}
+		if len(f.Children) > 0 {
+			res[f.Name] = applyDefaults(f.Children, v)
+		}
+	}
+	return res
+}
+
+func sanitiseFields(fields docs.FieldSpecs, raw interface{}) interface{} {
+	obj, isObj := raw.(map[string]interface{})
+	if !isObj {
+		return raw
+	}
+	res := map[string]interface{}{}
+	for _, f := range fields {
+		v, exists := obj[f.Name]
+		if !exists {
+			continue
+		}
+		if len(f.Children) > 0 {
+			v = sanitiseFields(f.Children, v)
+		}
+		res[f.Name] = v
+	}
+	return res
+}
+
+//------------------------------------------------------------------------------
+
+// pluginDescription generates a markdown description of the spec for the
+// documentation of a plugin.
+func (c *ConfigSpec) pluginDescription() string {
+	var buf bytes.Buffer
+	if c.component.Status != docs.StatusStable {
+		fmt.Fprintf(&buf, "%v\n\n", strings.ToUpper(string(c.component.Status)))
+	}
+	if len(c.component.Summary) > 0 {
+		fmt.Fprintf(&buf, "%v\n\n", c.component.Summary)
+	}
+	if len(c.component.Description) > 0 {
+		fmt.Fprintf(&buf, "%v\n\n", strings.TrimSpace(c.component.Description))
+	}
+	if len(c.component.Fields) > 0 {
+		buf.WriteString("### Fields\n\n")
+		writeFieldDocs(&buf, "", c.component.Fields)
+	}
+	if len(c.component.Examples) > 0 {
+		buf.WriteString("### Examples\n\n")
+		for _, e := range c.component.Examples {
+			fmt.Fprintf(&buf, "#### %v\n\n", e.Title)
+			if len(e.Summary) > 0 {
+				fmt.Fprintf(&buf, "%v\n\n", e.Summary)
+			}
+			fmt.Fprintf(&buf, "```yaml\n%v\n```\n\n", strings.TrimSpace(e.Config))
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+func writeFieldDocs(buf *bytes.Buffer, prefix string, fields docs.FieldSpecs) {
+	for _, f := range fields {
+		fmt.Fprintf(buf, "#### `%v%v`\n\n", prefix, f.Name)
+		if len(f.Description) > 0 {
+			fmt.Fprintf(buf, "%v\n\n", f.Description)
+		}
+		if f.Interpolation != docs.FieldInterpolationNone {
+			buf.WriteString("This field supports [interpolation functions](/docs/configuration/interpolation#bloblang-queries).\n\n")
+		}
+		fmt.Fprintf(buf, "Type: `%v`  \n", f.Type)
+		if f.Default != nil {
+			defaultBytes, _ := json.Marshal(f.Default)
+			fmt.Fprintf(buf, "Default: `%s`  \n", defaultBytes)
+		}
+		for _, e := range f.Examples {
+			exampleBytes, _ := json.Marshal(e)
+			fmt.Fprintf(buf, "Example: `%s`  \n", exampleBytes)
+		}
+		buf.WriteString("\n")
+		if len(f.Children) > 0 {
+			writeFieldDocs(buf, prefix+f.Name+".", f.Children)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ParsedConfig represents a plugin configuration that has been validated and
+// parsed from a ConfigSpec, and allows plugin constructors to access
+// configuration fields.
+type ParsedConfig struct {
+	path    []string
+	generic interface{}
+}
+
+// Namespace returns a version of the parsed config at a given field namespace.
+// This is useful for extracting multiple fields under the same grouping.
+func (p *ParsedConfig) Namespace(path ...string) *ParsedConfig {
+	v, _ := p.field(path...)
+	return &ParsedConfig{
+		path:    append(append([]string{}, p.path...), path...),
+		generic: v,
+	}
+}
+
+// Contains checks whether the parsed config contains a given field identified
+// by its name.
+func (p *ParsedConfig) Contains(path ...string) bool {
+	_, exists := p.field(path...)
+	return exists
+}
+
+func (p *ParsedConfig) field(path ...string) (interface{}, bool) {
+	v := p.generic
+	for _, k := range path {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func (p *ParsedConfig) fullDotPath(path ...string) string {
+	return strings.Join(append(append([]string{}, p.path...), path...), ".")
+}
+
+func (p *ParsedConfig) requiredField(path ...string) (interface{}, error) {
+	v, exists := p.field(path...)
+	if !exists || v == nil {
+		return nil, fmt.Errorf("field '%v' is required and was not present in the config", p.fullDotPath(path...))
+	}
+	return v, nil
+}
+
+// FieldString accesses a string field from the parsed config by its name. If
+// the field is not found or is not a string an error is returned.
+func (p *ParsedConfig) FieldString(path ...string) (string, error) {
+	v, err := p.requiredField(path...)
+	if err != nil {
+		return "", err
+	}
+	str, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected field '%v' to be a string, got %T", p.fullDotPath(path...), v)
+	}
+	return str, nil
+}
+
+// FieldStringList accesses a field that is a list of strings from the parsed
+// config by its name and returns the value. Returns an error if the field is
+// not found, or is not a list of strings.
+func (p *ParsedConfig) FieldStringList(path ...string) ([]string, error) {
+	v, err := p.requiredField(path...)
+	if err != nil {
+		return nil, err
+	}
+	iList, ok := v.([]interface{})
+	if !ok {
+		if sList, ok := v.([]string); ok {
+			return sList, nil
+		}
+		return nil, fmt.Errorf("expected field '%v' to be a string list, got %T", p.fullDotPath(path...), v)
+	}
+	sList := make([]string, len(iList))
+	for i, ev := range iList {
+		if sList[i], ok = ev.(string); !ok {
+			return nil, fmt.Errorf("expected field '%v' to be a string list, found an element of type %T", p.fullDotPath(path...), ev)
+		}
+	}
+	return sList, nil
+}
+
+// FieldInt accesses an int field from the parsed config by its name and
+// returns the value. Returns an error if the field is not found or is not an
+// int.
+func (p *ParsedConfig) FieldInt(path ...string) (int, error) {
+	v, err := p.requiredField(path...)
+	if err != nil {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case int64:
+		return int(t), nil
+	case uint64:
+		return int(t), nil
+	case float64:
+		if t == float64(int(t)) {
+			return int(t), nil
+		}
+	}
+	return 0, fmt.Errorf("expected field '%v' to be an int, got %T", p.fullDotPath(path...), v)
+}
+
+// FieldFloat accesses a float field from the parsed config by its name and
+// returns the value. Returns an error if the field is not found or is not a
+// float.
+func (p *ParsedConfig) FieldFloat(path ...string) (float64, error) {
+	v, err := p.requiredField(path...)
+	if err != nil {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case uint64:
+		return float64(t), nil
+	}
+	return 0, fmt.Errorf("expected field '%v' to be a float, got %T", p.fullDotPath(path...), v)
+}
+
+// FieldBool accesses a bool field from the parsed config by its name and
+// returns the value. Returns an error if the field is not found or is not a
+// bool.
+func (p *ParsedConfig) FieldBool(path ...string) (bool, error) {
+	v, err := p.requiredField(path...)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected field '%v' to be a bool, got %T", p.fullDotPath(path...), v)
+	}
+	return b, nil
+}
+
+// FieldDuration accesses a duration string field from the parsed config by
+// its name. If the field is not found or is not a valid duration string an
+// error is returned.
+func (p *ParsedConfig) FieldDuration(path ...string) (time.Duration, error) {
+	str, err := p.FieldString(path...)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse field '%v' as a duration: %w", p.fullDotPath(path...), err)
+	}
+	return d, nil
+}
+
+// FieldInterpolatedString accesses a field containing an interpolated string
+// value from the parsed config by its name. If the field is not found or is
+// not a valid interpolated string an error is returned.
+func (p *ParsedConfig) FieldInterpolatedString(path ...string) (*InterpolatedString, error) {
+	str, err := p.FieldString(path...)
+	if err != nil {
+		return nil, err
+	}
+	i, err := NewInterpolatedString(str)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse field '%v' as an interpolated string: %w", p.fullDotPath(path...), err)
+	}
+	return i, nil
+}
+
+// FieldBloblang accesses a field containing a Bloblang mapping from the parsed
+// config by its name. If the field is not found or is not a valid mapping an
+// error is returned.
+func (p *ParsedConfig) FieldBloblang(path ...string) (*bloblang.Executor, error) {
+	str, err := p.FieldString(path...)
+	if err != nil {
+		return nil, err
+	}
+	exec, err := bloblang.Parse(str)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse field '%v' as a Bloblang mapping: %w", p.fullDotPath(path...), err)
+	}
+	return exec, nil
+}
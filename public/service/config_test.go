@@ -0,0 +1,152 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigParseDefaults(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewStringField("a").Default("default a")).
+		Field(NewIntField("b").Default(10)).
+		Field(NewObjectField("c",
+			NewBoolField("d").Default(true),
+			NewStringListField("e").Default([]string{"foo"}),
+		)).
+		Field(NewDurationField("f").Default("5s")).
+		Field(NewFloatField("g"))
+
+	parsed, err := spec.ParseYAML(`
+b: 20
+c:
+  e: [ bar, baz ]
+`)
+	require.NoError(t, err)
+
+	a, err := parsed.FieldString("a")
+	require.NoError(t, err)
+	assert.Equal(t, "default a", a)
+
+	b, err := parsed.FieldInt("b")
+	require.NoError(t, err)
+	assert.Equal(t, 20, b)
+
+	d, err := parsed.FieldBool("c", "d")
+	require.NoError(t, err)
+	assert.True(t, d)
+
+	e, err := parsed.Namespace("c").FieldStringList("e")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz"}, e)
+
+	f, err := parsed.FieldDuration("f")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*5, f)
+
+	assert.False(t, parsed.Contains("g"))
+	_, err = parsed.FieldFloat("g")
+	assert.EqualError(t, err, "field 'g' is required and was not present in the config")
+}
+
+func TestConfigFieldErrors(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewStringField("a")).
+		Field(NewIntField("b")).
+		Field(NewDurationField("c")).
+		Field(NewObjectField("d", NewBoolField("e")))
+
+	parsed, err := spec.ParseYAML(`
+a: [ not, a, string ]
+b: 1.5
+c: not a duration
+d:
+  e: nope
+`)
+	require.NoError(t, err)
+
+	_, err = parsed.FieldString("a")
+	assert.EqualError(t, err, "expected field 'a' to be a string, got []interface {}")
+
+	_, err = parsed.FieldInt("b")
+	assert.Error(t, err)
+
+	_, err = parsed.FieldDuration("c")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse field 'c' as a duration")
+
+	_, err = parsed.FieldBool("d", "e")
+	assert.EqualError(t, err, "expected field 'd.e' to be a bool, got string")
+}
+
+func TestConfigInterpolationAndBloblang(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewInterpolatedStringField("a")).
+		Field(NewBloblangField("b"))
+
+	parsed, err := spec.ParseYAML(`
+a: ${! meta("foo") } bar
+b: root = this.value.uppercase()
+`)
+	require.NoError(t, err)
+
+	msg := NewMessage([]byte(`{"value":"hello"}`))
+	msg.MetaSet("foo", "baz")
+
+	a, err := parsed.FieldInterpolatedString("a")
+	require.NoError(t, err)
+	assert.Equal(t, "baz bar", a.String(msg))
+
+	b, err := parsed.FieldBloblang("b")
+	require.NoError(t, err)
+
+	res, err := msg.BloblangQuery(b)
+	require.NoError(t, err)
+
+	resBytes, err := res.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(resBytes))
+}
+
+func TestConfigSanitiser(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewStringField("a")).
+		Field(NewObjectField("b", NewIntField("c")))
+
+	conf := spec.configConstructor()().(*pluginConfig)
+	conf.raw = map[string]interface{}{
+		"a": "foo",
+		"b": map[string]interface{}{
+			"c": 5,
+			"d": "unknown",
+		},
+		"e": "unknown",
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"a": "foo",
+		"b": map[string]interface{}{
+			"c": 5,
+		},
+	}, spec.configSanitiser()(conf))
+}
+
+func TestConfigPluginDescription(t *testing.T) {
+	spec := NewConfigSpec().
+		Beta().
+		Summary("Does a thing.").
+		Field(NewStringField("a").Description("The a field.").Default("foo")).
+		Field(NewObjectField("b", NewIntField("c").Example(10))).
+		Example("Basic", "A basic example.", `a: bar`)
+
+	desc := spec.pluginDescription()
+	assert.Contains(t, desc, "BETA")
+	assert.Contains(t, desc, "Does a thing.")
+	assert.Contains(t, desc, "#### `a`\n\nThe a field.")
+	assert.Contains(t, desc, "Default: `\"foo\"`")
+	assert.Contains(t, desc, "#### `b.c`")
+	assert.Contains(t, desc, "Example: `10`")
+	assert.Contains(t, desc, "#### Basic\n\nA basic example.\n\n```yaml\na: bar\n```")
+}
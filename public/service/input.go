@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/input/reader"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Errors returned by input plugins in order to signal particular states.
+var (
+	// ErrNotConnected is returned by inputs and outputs when their Read or
+	// Write methods are called and the connection that they maintain is lost.
+	// This error prompts the upstream component to call Connect until the
+	// connection is re-established.
+	ErrNotConnected = errors.New("not connected")
+
+	// ErrEndOfInput is returned by inputs that have exhausted their source of
+	// data to the point where subsequent Read calls will be ineffective. This
+	// error prompts the upstream component to gracefully terminate the
+	// pipeline.
+	ErrEndOfInput = errors.New("end of input")
+)
+
+// AckFunc is a common function returned by inputs that must be called once for
+// each message consumed. This function ensures that the source of the message
+// receives either an acknowledgement (err is nil) or an error that can either
+// be propagated upstream as a nack, or trigger a reattempt at delivering the
+// same message.
+//
+// If your input implementation doesn't have a specific mechanism for dealing
+// with a nack then you can wrap your input implementation with AutoRetryNacks
+// to get automatic retries.
+type AckFunc func(ctx context.Context, err error) error
+
+// Input is an interface implemented by Benthos inputs. Calls to Read should
+// block until either a message has been received, the connection is lost, or
+// the provided context is cancelled.
+type Input interface {
+	// Establish a connection to the upstream service. Connect will always be
+	// called first when a reader is instantiated, and will be continuously
+	// called with back off until a nil error is returned.
+	//
+	// The provided context remains open only for the duration of the connecting
+	// phase, and should not be used to establish the lifetime of the
+	// connection itself.
+	//
+	// Once Connect returns a nil error the Read method will be called until
+	// either ErrNotConnected is returned, or the reader is closed.
+	Connect(context.Context) error
+
+	// Read a single message from a source, along with a function to be called
+	// once the message can be either acked (successfully sent or intentionally
+	// filtered) or nacked (failed to be processed or dispatched to the output).
+	//
+	// The AckFunc will be called for every message at least once, but there
+	// are no guarantees as to when this will occur. If your input
+	// implementation doesn't have a specific mechanism for dealing with a nack
+	// then you can wrap your input implementation with AutoRetryNacks to get
+	// automatic retries.
+	//
+	// If this method returns ErrNotConnected then Read will not be called
+	// again until Connect has returned a nil error. If ErrEndOfInput is
+	// returned then Read will no longer be called and the pipeline will
+	// gracefully terminate.
+	Read(context.Context) (*Message, AckFunc, error)
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// InputConstructor is a func that's provided a configuration type and access
+// to a service manager, and must return an instantiation of a reader based on
+// the config, or an error.
+type InputConstructor func(conf *ParsedConfig, mgr *Resources) (Input, error)
+
+// RegisterInput attempts to register a new input plugin by providing a
+// description of the configuration for the plugin as well as a constructor for
+// the input itself. The constructor will be called for each instantiation of
+// the component within a config.
+func RegisterInput(name string, spec *ConfigSpec, ctor InputConstructor) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if _, exists := input.Constructors[name]; exists {
+		return fmt.Errorf("input '%v' is already registered", name)
+	}
+	if err := claimName("input", name); err != nil {
+		return err
+	}
+	input.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Input, error) {
+		i, err := ctor(spec.configFromPlugin(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		var rdr reader.Async = newAirGapReader(i)
+		if _, isAutoRetry := i.(*autoRetryInput); isAutoRetry {
+			rdr = reader.NewAsyncPreserver(rdr)
+		}
+		return input.NewAsyncReader(name, false, rdr, logger, stats)
+	})
+	input.DocumentPlugin(name, spec.pluginDescription(), spec.configSanitiser())
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// AutoRetryNacks wraps an input implementation with a component that
+// automatically reattempts messages that fail downstream. This is useful for
+// inputs that do not support nacks, and therefore don't have an answer for
+// when an ack func is called with an error.
+//
+// When messages fail to be delivered they will be reattempted with back off
+// until success or the stream is stopped.
+func AutoRetryNacks(i Input) Input {
+	return &autoRetryInput{i}
+}
+
+type autoRetryInput struct {
+	Input
+}
+
+//------------------------------------------------------------------------------
+
+// airGapReader adapts an Input implementation to the reader.Async interface.
+type airGapReader struct {
+	r Input
+	*asyncCloser
+}
+
+func newAirGapReader(r Input) *airGapReader {
+	if ar, isAutoRetry := r.(*autoRetryInput); isAutoRetry {
+		r = ar.Input
+	}
+	return &airGapReader{
+		r:           r,
+		asyncCloser: newAsyncCloser(r.Close),
+	}
+}
+
+func (a *airGapReader) ConnectWithContext(ctx context.Context) error {
+	err := a.r.Connect(ctx)
+	if err != nil && errors.Is(err, ErrEndOfInput) {
+		err = types.ErrTypeClosed
+	}
+	return err
+}
+
+func (a *airGapReader) ReadWithContext(ctx context.Context) (types.Message, reader.AsyncAckFn, error) {
+	msg, ackFn, err := a.r.Read(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNotConnected) {
+			err = types.ErrNotConnected
+		} else if errors.Is(err, ErrEndOfInput) {
+			err = types.ErrTypeClosed
+		}
+		return nil, nil, err
+	}
+	tMsg := message.New(nil)
+	tMsg.Append(msg.part)
+	return tMsg, func(ctx context.Context, res types.Response) error {
+		return ackFn(ctx, res.Error())
+	}, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/bloblang"
+	"github.com/Jeffail/benthos/v3/lib/message"
+)
+
+// InterpolatedString resolves a string containing dynamic interpolation
+// functions for a given message.
+type InterpolatedString struct {
+	expr bloblang.Field
+}
+
+// NewInterpolatedString parses an interpolated string expression.
+func NewInterpolatedString(expr string) (*InterpolatedString, error) {
+	e, err := bloblang.NewField(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &InterpolatedString{expr: e}, nil
+}
+
+// String resolves the interpolated string for a given message.
+func (i *InterpolatedString) String(m *Message) string {
+	msg := message.New(nil)
+	msg.Append(m.part)
+	return i.expr.String(0, msg)
+}
+
+// Bytes resolves the interpolated string for a given message as a byte slice.
+func (i *InterpolatedString) Bytes(m *Message) []byte {
+	msg := message.New(nil)
+	msg.Append(m.part)
+	return i.expr.Bytes(0, msg)
+}
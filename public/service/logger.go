@@ -0,0 +1,52 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+// Logger allows plugin authors to write custom logs from components that are
+// exported the same way as native Benthos logs. It's safe to use the same
+// logger from multiple goroutines.
+type Logger struct {
+	m log.Modular
+}
+
+func newLogger(m log.Modular) *Logger {
+	return &Logger{m: m}
+}
+
+// Tracef logs a trace message using fmt.Sprintf when args are specified.
+func (l *Logger) Tracef(template string, args ...interface{}) {
+	l.m.Tracef(template, args...)
+}
+
+// Debugf logs a debug message using fmt.Sprintf when args are specified.
+func (l *Logger) Debugf(template string, args ...interface{}) {
+	l.m.Debugf(template, args...)
+}
+
+// Infof logs an info message using fmt.Sprintf when args are specified.
+func (l *Logger) Infof(template string, args ...interface{}) {
+	l.m.Infof(template, args...)
+}
+
+// Warnf logs a warning message using fmt.Sprintf when args are specified.
+func (l *Logger) Warnf(template string, args ...interface{}) {
+	l.m.Warnf(template, args...)
+}
+
+// Errorf logs an error message using fmt.Sprintf when args are specified.
+func (l *Logger) Errorf(template string, args ...interface{}) {
+	l.m.Errorf(template, args...)
+}
+
+// With adds a variadic set of key/value pairs to the logger, which will be
+// included in all subsequent log messages. Keys without a matching value are
+// ignored.
+func (l *Logger) With(keyValuePairs ...string) *Logger {
+	fields := map[string]string{}
+	for i := 0; i+1 < len(keyValuePairs); i += 2 {
+		fields[keyValuePairs[i]] = keyValuePairs[i+1]
+	}
+	return newLogger(l.m.WithFields(fields))
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/public/bloblang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCopyAirGap(t *testing.T) {
+	p := NewMessage([]byte("hello world"))
+	p.MetaSet("foo", "bar")
+	g1 := p.Copy()
+	g2 := p.Copy()
+
+	g2.SetBytes([]byte("and now this"))
+	g2.MetaSet("foo", "baz")
+
+	b, err := p.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+
+	b, err = g1.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+	v, _ := g1.MetaGet("foo")
+	assert.Equal(t, "bar", v)
+
+	b, err = g2.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "and now this", string(b))
+	v, _ = g2.MetaGet("foo")
+	assert.Equal(t, "baz", v)
+}
+
+func TestMessageMetadata(t *testing.T) {
+	m := NewMessage(nil)
+	m.MetaSet("foo", "bar")
+	m.MetaSet("baz", "buz")
+
+	v, exists := m.MetaGet("foo")
+	assert.True(t, exists)
+	assert.Equal(t, "bar", v)
+
+	m.MetaSet("foo", "")
+	_, exists = m.MetaGet("foo")
+	assert.False(t, exists)
+
+	m.MetaDelete("baz")
+	_, exists = m.MetaGet("baz")
+	assert.False(t, exists)
+
+	m.MetaSet("a", "b")
+	seen := map[string]string{}
+	require.NoError(t, m.MetaWalk(func(k, v string) error {
+		seen[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]string{"a": "b"}, seen)
+}
+
+func TestMessageStructured(t *testing.T) {
+	m := NewMessage([]byte(`{"foo":"bar"}`))
+
+	v, err := m.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, v)
+
+	mv, err := m.AsStructuredMut()
+	require.NoError(t, err)
+	mv.(map[string]interface{})["foo"] = "baz"
+
+	b, err := m.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"bar"}`, string(b))
+
+	require.NoError(t, m.SetStructured(mv))
+	b, err = m.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"baz"}`, string(b))
+
+	require.Error(t, m.SetStructured(map[string]interface{}{"foo": func() {}}))
+	b, err = m.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"baz"}`, string(b))
+}
+
+func TestMessageErrors(t *testing.T) {
+	m := NewMessage(nil)
+	assert.NoError(t, m.GetError())
+
+	m.SetError(errors.New("nope"))
+	assert.EqualError(t, m.GetError(), "nope")
+}
+
+func TestMessageBloblangQuery(t *testing.T) {
+	blobl, err := bloblang.Parse(`
+root = if this.drop { deleted() } else { this.value.uppercase() }
+meta foo = "bar"
+`)
+	require.NoError(t, err)
+
+	res, err := NewMessage([]byte(`{"value":"hello","drop":false}`)).BloblangQuery(blobl)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+
+	b, err := res.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", string(b))
+
+	v, _ := res.MetaGet("foo")
+	assert.Equal(t, "bar", v)
+
+	res, err = NewMessage([]byte(`{"value":"hello","drop":true}`)).BloblangQuery(blobl)
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	_, err = NewMessage([]byte(`not json`)).BloblangQuery(blobl)
+	require.Error(t, err)
+}
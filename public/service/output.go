@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Output is an interface implemented by Benthos outputs that support single
+// message writes. Each call to Write should block until either the message has
+// been successfully or unsuccessfully sent, or the context is cancelled.
+//
+// Multiple write calls can be performed in parallel, and the constructor of an
+// output must provide a MaxInFlight parameter indicating the maximum number of
+// parallel write calls the output supports.
+type Output interface {
+	// Establish a connection to the downstream service. Connect will always be
+	// called first when a writer is instantiated, and will be continuously
+	// called with back off until a nil error is returned.
+	//
+	// The provided context remains open only for the duration of the connecting
+	// phase, and should not be used to establish the lifetime of the
+	// connection itself.
+	//
+	// Once Connect returns a nil error the write method will be called until
+	// either ErrNotConnected is returned, or the writer is closed.
+	Connect(context.Context) error
+
+	// Write a message to a sink, or return an error if delivery is not
+	// possible.
+	//
+	// If this method returns ErrNotConnected then write will not be called
+	// again until Connect has returned a nil error.
+	Write(context.Context, *Message) error
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// OutputConstructor is a func that's provided a configuration type and access
+// to a service manager and must return an instantiation of an output based on
+// the config, along with the maximum number of parallel write calls the output
+// supports, or an error.
+type OutputConstructor func(conf *ParsedConfig, mgr *Resources) (out Output, maxInFlight int, err error)
+
+// RegisterOutput attempts to register a new output plugin by providing a
+// description of the configuration for the plugin as well as a constructor for
+// the output itself. The constructor will be called for each instantiation of
+// the component within a config.
+func RegisterOutput(name string, spec *ConfigSpec, ctor OutputConstructor) error {
+	return registerOutput(name, spec, func(conf *ParsedConfig, mgr *Resources) (BatchOutput, BatchPolicy, int, error) {
+		out, maxInFlight, err := ctor(conf, mgr)
+		if err != nil {
+			return nil, BatchPolicy{}, 0, err
+		}
+		return &singleToBatchOutput{out}, BatchPolicy{}, maxInFlight, nil
+	})
+}
+
+// BatchOutput is an interface implemented by Benthos outputs that require
+// Benthos to batch messages before dispatch in order to improve throughput.
+// Each call to WriteBatch should block until either all messages in the batch
+// have been successfully or unsuccessfully sent, or the context is cancelled.
+//
+// Multiple write calls can be performed in parallel, and the constructor of an
+// output must provide a MaxInFlight parameter indicating the maximum number of
+// parallel batched write calls the output supports.
+type BatchOutput interface {
+	// Establish a connection to the downstream service. Connect will always be
+	// called first when a writer is instantiated, and will be continuously
+	// called with back off until a nil error is returned.
+	//
+	// The provided context remains open only for the duration of the connecting
+	// phase, and should not be used to establish the lifetime of the
+	// connection itself.
+	//
+	// Once Connect returns a nil error the write method will be called until
+	// either ErrNotConnected is returned, or the writer is closed.
+	Connect(context.Context) error
+
+	// Write a batch of messages to a sink, or return an error if delivery is
+	// not possible.
+	//
+	// If this method returns ErrNotConnected then write will not be called
+	// again until Connect has returned a nil error.
+	WriteBatch(context.Context, MessageBatch) error
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// BatchOutputConstructor is a func that's provided a configuration type and
+// access to a service manager and must return an instantiation of an output
+// based on the config, a batching policy, and the maximum number of parallel
+// write calls the output supports, or an error.
+type BatchOutputConstructor func(conf *ParsedConfig, mgr *Resources) (out BatchOutput, batchPolicy BatchPolicy, maxInFlight int, err error)
+
+// RegisterBatchOutput attempts to register a new output plugin by providing a
+// description of the configuration for the plugin as well as a constructor for
+// the output itself. The constructor will be called for each instantiation of
+// the component within a config.
+//
+// The constructor of a batch output is able to return a batch policy to be
+// applied before calls to write are made, creating batches from the stream of
+// messages. However, batches can also be created by other means and it is
+// therefore not guaranteed that batches written to the output will match the
+// returned policy.
+func RegisterBatchOutput(name string, spec *ConfigSpec, ctor BatchOutputConstructor) error {
+	return registerOutput(name, spec, ctor)
+}
+
+func registerOutput(name string, spec *ConfigSpec, ctor BatchOutputConstructor) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if _, exists := output.Constructors[name]; exists {
+		return fmt.Errorf("output '%v' is already registered", name)
+	}
+	if err := claimName("output", name); err != nil {
+		return err
+	}
+	output.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Output, error) {
+		op, policy, maxInFlight, err := ctor(spec.configFromPlugin(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		if maxInFlight < 1 {
+			return nil, fmt.Errorf("invalid maxInFlight parameter: %v", maxInFlight)
+		}
+		var out output.Type
+		if out, err = output.NewAsyncWriter(name, maxInFlight, newAirGapWriter(op), logger, stats); err != nil {
+			return nil, err
+		}
+		if policyConf := policy.toInternal(); !policyConf.IsNoop() {
+			pol, err := batch.NewPolicy(policyConf, mgr, logger.NewModule(".batching"), metrics.Namespaced(stats, "batching"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to construct batch policy: %v", err)
+			}
+			out = output.NewBatcher(pol, out, logger, stats)
+		}
+		return out, nil
+	})
+	output.DocumentPlugin(name, spec.pluginDescription(), spec.configSanitiser())
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// singleToBatchOutput adapts an Output to the BatchOutput interface by writing
+// the messages of a batch one at a time.
+type singleToBatchOutput struct {
+	o Output
+}
+
+func (s *singleToBatchOutput) Connect(ctx context.Context) error {
+	return s.o.Connect(ctx)
+}
+
+func (s *singleToBatchOutput) WriteBatch(ctx context.Context, b MessageBatch) error {
+	for _, m := range b {
+		if err := s.o.Write(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *singleToBatchOutput) Close(ctx context.Context) error {
+	return s.o.Close(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+// airGapWriter adapts a BatchOutput implementation to the output.AsyncSink
+// interface.
+type airGapWriter struct {
+	w BatchOutput
+	*asyncCloser
+}
+
+func newAirGapWriter(w BatchOutput) *airGapWriter {
+	return &airGapWriter{
+		w:           w,
+		asyncCloser: newAsyncCloser(w.Close),
+	}
+}
+
+func (a *airGapWriter) ConnectWithContext(ctx context.Context) error {
+	return a.w.Connect(ctx)
+}
+
+func (a *airGapWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	b := make(MessageBatch, msg.Len())
+	_ = msg.Iter(func(i int, part types.Part) error {
+		b[i] = newMessageFromPart(part)
+		return nil
+	})
+	err := a.w.WriteBatch(ctx, b)
+	if err != nil && errors.Is(err, ErrNotConnected) {
+		err = types.ErrNotConnected
+	}
+	return err
+}
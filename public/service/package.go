@@ -0,0 +1,10 @@
+// Package service provides a high level API for registering custom plugin
+// components and executing either a standard Benthos CLI, or programmatically
+// building isolated pipelines with a StreamBuilder API.
+//
+// Plugins are registered globally and are configured with a ConfigSpec, which
+// is used for parsing, linting and documenting their configuration fields.
+//
+// In order to add custom Bloblang functions and methods use the
+// ./public/bloblang package.
+package service
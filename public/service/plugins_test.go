@@ -0,0 +1,226 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type testCountInput struct {
+	prefix string
+	count  int
+}
+
+func (t *testCountInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (t *testCountInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	if t.count <= 0 {
+		return nil, nil, service.ErrEndOfInput
+	}
+	t.count--
+	return service.NewMessage([]byte(t.prefix)), func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (t *testCountInput) Close(ctx context.Context) error {
+	return nil
+}
+
+type testSuffixProcessor struct {
+	suffix string
+}
+
+func (t *testSuffixProcessor) Process(ctx context.Context, m *service.Message) (service.MessageBatch, error) {
+	b, err := m.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if string(b) == "fail" {
+		return nil, errors.New("failed on purpose")
+	}
+	m.SetBytes(append(b, []byte(t.suffix)...))
+	return service.MessageBatch{m}, nil
+}
+
+func (t *testSuffixProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+var registerTestPlugins sync.Once
+
+func registerPlugins(t *testing.T) {
+	t.Helper()
+	registerTestPlugins.Do(func() {
+		require.NoError(t, service.RegisterInput(
+			"test_count_input",
+			service.NewConfigSpec().
+				Field(service.NewStringField("prefix")).
+				Field(service.NewIntField("count").Default(1)),
+			func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+				prefix, err := conf.FieldString("prefix")
+				if err != nil {
+					return nil, err
+				}
+				count, err := conf.FieldInt("count")
+				if err != nil {
+					return nil, err
+				}
+				return &testCountInput{prefix: prefix, count: count}, nil
+			},
+		))
+
+		require.NoError(t, service.RegisterProcessor(
+			"test_suffix_processor",
+			service.NewConfigSpec().
+				Field(service.NewStringField("suffix").Default(" world")),
+			func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+				suffix, err := conf.FieldString("suffix")
+				if err != nil {
+					return nil, err
+				}
+				return &testSuffixProcessor{suffix: suffix}, nil
+			},
+		))
+	})
+}
+
+func TestPluginRegistrationErrors(t *testing.T) {
+	registerPlugins(t)
+
+	err := service.RegisterProcessor("test_suffix_processor", service.NewConfigSpec(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already registered")
+
+	err = service.RegisterProcessor("Not A Valid Name", service.NewConfigSpec(), nil)
+	require.Error(t, err)
+}
+
+func TestPluginStream(t *testing.T) {
+	registerPlugins(t)
+
+	b := service.NewStreamBuilder()
+	require.NoError(t, b.SetLoggerYAML(`level: NONE`))
+	require.NoError(t, b.AddInputYAML(`
+type: test_count_input
+plugin:
+  prefix: hello
+  count: 2
+`))
+	require.NoError(t, b.AddProcessorYAML(`
+type: test_suffix_processor
+plugin: {}
+`))
+
+	var outMut sync.Mutex
+	var outputs []string
+	require.NoError(t, b.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		outMut.Lock()
+		defer outMut.Unlock()
+
+		bytes, err := m.AsBytes()
+		require.NoError(t, err)
+		outputs = append(outputs, string(bytes))
+		return nil
+	}))
+
+	strm, err := b.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	require.NoError(t, strm.Run(ctx))
+
+	outMut.Lock()
+	assert.Equal(t, []string{"hello world", "hello world"}, outputs)
+	outMut.Unlock()
+}
+
+func TestPluginProcessorErrors(t *testing.T) {
+	registerPlugins(t)
+
+	b := service.NewStreamBuilder()
+	require.NoError(t, b.SetLoggerYAML(`level: NONE`))
+	require.NoError(t, b.AddProcessorYAML(`
+type: test_suffix_processor
+plugin:
+  suffix: " there"
+`))
+
+	pushFn, err := b.AddProducerFunc()
+	require.NoError(t, err)
+
+	var outMut sync.Mutex
+	var outputs, errs []string
+	require.NoError(t, b.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		outMut.Lock()
+		defer outMut.Unlock()
+
+		bytes, err := m.AsBytes()
+		require.NoError(t, err)
+		outputs = append(outputs, string(bytes))
+		if err := m.GetError(); err != nil {
+			errs = append(errs, err.Error())
+		}
+		return nil
+	}))
+
+	strm, err := b.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	go func() {
+		_ = strm.Run(ctx)
+	}()
+
+	require.NoError(t, pushFn(ctx, service.NewMessage([]byte("hello"))))
+	require.NoError(t, pushFn(ctx, service.NewMessage([]byte("fail"))))
+	require.NoError(t, strm.StopWithin(time.Second*10))
+
+	outMut.Lock()
+	assert.Equal(t, []string{"hello there", "fail"}, outputs)
+	assert.Equal(t, []string{"failed on purpose"}, errs)
+	outMut.Unlock()
+}
+
+func TestPluginLint(t *testing.T) {
+	registerPlugins(t)
+
+	confBytes := []byte(`
+input:
+  type: test_count_input
+  plugin:
+    prefix: foo
+    nope: bar
+output:
+  type: drop
+`)
+
+	conf := config.New()
+	require.NoError(t, yaml.Unmarshal(confBytes, &conf))
+
+	lints, err := config.Lint(confBytes, conf)
+	require.NoError(t, err)
+
+	var found bool
+	for _, l := range lints {
+		if strings.Contains(l, "nope") {
+			found = true
+		}
+	}
+	assert.True(t, found, "lints: %v", lints)
+}
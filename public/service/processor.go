@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Processor is a Benthos processor implementation that works against single
+// messages.
+type Processor interface {
+	// Process a message into one or more resulting messages, or return an
+	// error if the message could not be processed. If zero messages are
+	// returned and the error is nil then the message is filtered.
+	//
+	// When an error is returned the input message will continue down the
+	// pipeline but will be marked with the error with Message.SetError, and
+	// can be handled using the various error handling capabilities Benthos
+	// offers.
+	Process(context.Context, *Message) (MessageBatch, error)
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// ProcessorConstructor is a func that's provided its parsed config and
+// access to a service manager and must return an instantiation of a processor
+// based on the config, or an error.
+type ProcessorConstructor func(conf *ParsedConfig, mgr *Resources) (Processor, error)
+
+// RegisterProcessor attempts to register a new processor plugin by providing a
+// description of the configuration for the processor and a constructor for the
+// processor itself. The constructor will be called for each instantiation of
+// the component within a config.
+func RegisterProcessor(name string, spec *ConfigSpec, ctor ProcessorConstructor) error {
+	return registerProcessor(name, spec, func(conf *ParsedConfig, mgr *Resources) (types.Processor, error) {
+		p, err := ctor(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapProcessor(p, mgr.logger, mgr.stats), nil
+	})
+}
+
+// BatchProcessor is a Benthos processor implementation that works against
+// batches of messages, which allows windowed processing.
+type BatchProcessor interface {
+	// Process a batch of messages into one or more resulting batches, or
+	// return an error if the entire batch could not be processed. If zero
+	// messages are returned and the error is nil then all messages are
+	// filtered.
+	//
+	// When an error is returned all of the input messages will continue down
+	// the pipeline but will be marked with the error with Message.SetError,
+	// and can be handled using the various error handling capabilities Benthos
+	// offers.
+	ProcessBatch(context.Context, MessageBatch) ([]MessageBatch, error)
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// BatchProcessorConstructor is a func that's provided its parsed config and
+// access to a service manager and must return an instantiation of a batch
+// processor based on the config, or an error.
+type BatchProcessorConstructor func(conf *ParsedConfig, mgr *Resources) (BatchProcessor, error)
+
+// RegisterBatchProcessor attempts to register a new batch processor plugin by
+// providing a description of the configuration for the processor and a
+// constructor for the processor itself. The constructor will be called for
+// each instantiation of the component within a config.
+func RegisterBatchProcessor(name string, spec *ConfigSpec, ctor BatchProcessorConstructor) error {
+	return registerProcessor(name, spec, func(conf *ParsedConfig, mgr *Resources) (types.Processor, error) {
+		p, err := ctor(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapBatchProcessor(p, mgr.logger, mgr.stats), nil
+	})
+}
+
+func registerProcessor(
+	name string,
+	spec *ConfigSpec,
+	ctor func(conf *ParsedConfig, mgr *Resources) (types.Processor, error),
+) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if _, exists := processor.Constructors[name]; exists {
+		return fmt.Errorf("processor '%v' is already registered", name)
+	}
+	if err := claimName("processor", name); err != nil {
+		return err
+	}
+	processor.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.Processor, error) {
+		return ctor(spec.configFromPlugin(conf), newResources(mgr, logger, stats))
+	})
+	processor.DocumentPlugin(name, spec.pluginDescription(), spec.configSanitiser())
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapProcessor adapts a Processor implementation to the types.Processor
+// interface.
+type airGapProcessor struct {
+	p Processor
+	*asyncCloser
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+	mDropped   metrics.StatCounter
+}
+
+func newAirGapProcessor(p Processor, log log.Modular, stats metrics.Type) *airGapProcessor {
+	return &airGapProcessor{
+		p:           p,
+		asyncCloser: newAsyncCloser(p.Close),
+		log:         log,
+		mCount:      stats.GetCounter("count"),
+		mErr:        stats.GetCounter("error"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+		mDropped:    stats.GetCounter("dropped"),
+	}
+}
+
+// ProcessMessage applies the processor to each message of a batch.
+func (a *airGapProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	a.mCount.Incr(1)
+
+	newParts := make([]types.Part, 0, msg.Len())
+	_ = msg.Iter(func(i int, part types.Part) error {
+		batch, err := a.p.Process(context.Background(), newMessageFromPart(part.Copy()))
+		if err != nil {
+			a.mErr.Incr(1)
+			a.log.Errorf("%v\n", err)
+			part = part.Copy()
+			processor.FlagErr(part, err)
+			newParts = append(newParts, part)
+			return nil
+		}
+		if len(batch) == 0 {
+			a.mDropped.Incr(1)
+		}
+		for _, m := range batch {
+			newParts = append(newParts, m.part)
+		}
+		return nil
+	})
+
+	if len(newParts) == 0 {
+		return nil, response.NewAck()
+	}
+
+	newMsg := message.New(nil)
+	newMsg.SetAll(newParts)
+
+	a.mBatchSent.Incr(1)
+	a.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapBatchProcessor adapts a BatchProcessor implementation to the
+// types.Processor interface.
+type airGapBatchProcessor struct {
+	p BatchProcessor
+	*asyncCloser
+
+	log log.Modular
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+	mDropped   metrics.StatCounter
+}
+
+func newAirGapBatchProcessor(p BatchProcessor, log log.Modular, stats metrics.Type) *airGapBatchProcessor {
+	return &airGapBatchProcessor{
+		p:           p,
+		asyncCloser: newAsyncCloser(p.Close),
+		log:         log,
+		mCount:      stats.GetCounter("count"),
+		mErr:        stats.GetCounter("error"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+		mDropped:    stats.GetCounter("dropped"),
+	}
+}
+
+// ProcessMessage applies the processor to a message batch.
+func (a *airGapBatchProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	a.mCount.Incr(1)
+
+	b := make(MessageBatch, msg.Len())
+	_ = msg.Iter(func(i int, part types.Part) error {
+		b[i] = newMessageFromPart(part.Copy())
+		return nil
+	})
+
+	batches, err := a.p.ProcessBatch(context.Background(), b)
+	if err != nil {
+		a.mErr.Incr(1)
+		a.log.Errorf("%v\n", err)
+		for _, m := range b {
+			m.SetError(err)
+		}
+		batches = []MessageBatch{b}
+	}
+
+	var msgs []types.Message
+	for _, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+		parts := make([]types.Part, len(batch))
+		for i, m := range batch {
+			parts[i] = m.part
+		}
+		newMsg := message.New(nil)
+		newMsg.SetAll(parts)
+		msgs = append(msgs, newMsg)
+
+		a.mBatchSent.Incr(1)
+		a.mSent.Incr(int64(newMsg.Len()))
+	}
+	if len(msgs) == 0 {
+		a.mDropped.Incr(int64(msg.Len()))
+		return nil, response.NewAck()
+	}
+	return msgs, nil
+}
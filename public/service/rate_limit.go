@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// RateLimit is an interface implemented by Benthos rate limits.
+type RateLimit interface {
+	// Access the rate limited resource. Returns a duration or an error if the
+	// rate limit check fails. The returned duration is either zero (meaning the
+	// resource may be accessed) or a reasonable length of time to wait before
+	// requesting again.
+	Access(context.Context) (time.Duration, error)
+
+	// Close the component, blocks until either the underlying resources are
+	// cleaned up or the context is cancelled. Returns an error if the context
+	// is cancelled.
+	Close(ctx context.Context) error
+}
+
+// RateLimitConstructor is a func that's provided a configuration type and
+// access to a service manager and must return an instantiation of a rate limit
+// based on the config, or an error.
+type RateLimitConstructor func(conf *ParsedConfig, mgr *Resources) (RateLimit, error)
+
+// RegisterRateLimit attempts to register a new rate limit plugin by providing
+// a description of the configuration for the plugin as well as a constructor
+// for the rate limit itself. The constructor will be called for each
+// instantiation of the component within a config.
+func RegisterRateLimit(name string, spec *ConfigSpec, ctor RateLimitConstructor) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	if _, exists := ratelimit.Constructors[name]; exists {
+		return fmt.Errorf("rate limit '%v' is already registered", name)
+	}
+	if err := claimName("rate limit", name); err != nil {
+		return err
+	}
+	ratelimit.RegisterPlugin(name, spec.configConstructor(), func(
+		conf interface{},
+		mgr types.Manager,
+		logger log.Modular,
+		stats metrics.Type,
+	) (types.RateLimit, error) {
+		r, err := ctor(spec.configFromPlugin(conf), newResources(mgr, logger, stats))
+		if err != nil {
+			return nil, err
+		}
+		return newAirGapRateLimit(r), nil
+	})
+	ratelimit.DocumentPlugin(name, spec.pluginDescription(), spec.configSanitiser())
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// airGapRateLimit adapts a RateLimit implementation to the types.RateLimit
+// interface.
+type airGapRateLimit struct {
+	r RateLimit
+	*asyncCloser
+}
+
+func newAirGapRateLimit(r RateLimit) *airGapRateLimit {
+	return &airGapRateLimit{
+		r:           r,
+		asyncCloser: newAsyncCloser(r.Close),
+	}
+}
+
+func (a *airGapRateLimit) Access() (time.Duration, error) {
+	return a.r.Access(context.Background())
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Resources provides access to service-wide resources, such as loggers,
+// metrics aggregators and caches, to plugin constructors.
+type Resources struct {
+	mgr    types.Manager
+	logger log.Modular
+	stats  metrics.Type
+}
+
+func newResources(mgr types.Manager, logger log.Modular, stats metrics.Type) *Resources {
+	return &Resources{
+		mgr:    mgr,
+		logger: logger,
+		stats:  stats,
+	}
+}
+
+// Logger returns a logger preset with the scope of the component being
+// constructed.
+func (r *Resources) Logger() *Logger {
+	return newLogger(r.logger)
+}
+
+// Metrics returns a mechanism for creating custom metrics scoped to the
+// component being constructed.
+func (r *Resources) Metrics() *Metrics {
+	return newMetrics(r.stats)
+}
+
+// AccessCache attempts to access a cache resource by name. This action can
+// block if CRUD operations are being actively performed on the resource.
+func (r *Resources) AccessCache(ctx context.Context, name string, fn func(c Cache)) error {
+	c, err := r.mgr.GetCache(name)
+	if err != nil {
+		return err
+	}
+	fn(newReverseAirGapCache(c))
+	return nil
+}
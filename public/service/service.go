@@ -0,0 +1,16 @@
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/service"
+)
+
+// RunCLI executes Benthos as a CLI, allowing users to specify a configuration
+// file path(s) and execute subcommands for linting configs, testing configs,
+// etc. This is how a standard distribution of Benthos operates.
+//
+// This call blocks until either the pipeline shuts down or a termination
+// signal is received. Plugins registered before this call will be available
+// to the configs that are executed.
+func RunCLI() {
+	service.Run()
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+// Stream is a Benthos stream pipeline that can be started and stopped.
+type Stream struct {
+	conf            stream.Config
+	shutdownTimeout time.Duration
+
+	mgr    *manager.Type
+	logger log.Modular
+	stats  metrics.Type
+
+	consumerID   string
+	consumerFunc MessageHandlerFunc
+
+	mut            sync.Mutex
+	strm           *stream.Type
+	stopped        bool
+	consumerCancel func()
+	consumerDone   chan struct{}
+}
+
+func newStream(
+	conf stream.Config,
+	shutdownTimeout time.Duration,
+	mgr *manager.Type,
+	logger log.Modular,
+	stats metrics.Type,
+	consumerID string,
+	consumerFunc MessageHandlerFunc,
+) *Stream {
+	return &Stream{
+		conf:            conf,
+		shutdownTimeout: shutdownTimeout,
+		mgr:             mgr,
+		logger:          logger,
+		stats:           stats,
+		consumerID:      consumerID,
+		consumerFunc:    consumerFunc,
+	}
+}
+
+// Run attempts to start the stream pipeline and blocks until either the stream
+// has gracefully come to a stop, or the provided context is cancelled, in
+// which case the stream is stopped within the configured shutdown timeout and
+// the context error is returned.
+func (s *Stream) Run(ctx context.Context) error {
+	closedChan := make(chan struct{})
+
+	s.mut.Lock()
+	if s.strm != nil || s.stopped {
+		s.mut.Unlock()
+		return errors.New("stream has already been run")
+	}
+	strm, err := stream.New(
+		s.conf,
+		stream.OptSetLogger(s.logger),
+		stream.OptSetStats(s.stats),
+		stream.OptSetManager(s.mgr),
+		stream.OptOnClose(func() {
+			close(closedChan)
+		}),
+	)
+	if err != nil {
+		s.mut.Unlock()
+		return err
+	}
+	s.strm = strm
+
+	consumerCtx, consumerCancel := context.WithCancel(context.Background())
+	s.consumerCancel = consumerCancel
+	s.consumerDone = make(chan struct{})
+	if s.consumerFunc != nil {
+		go s.runConsumer(consumerCtx)
+	} else {
+		close(s.consumerDone)
+	}
+	s.mut.Unlock()
+
+	select {
+	case <-closedChan:
+		return s.StopWithin(s.shutdownTimeout)
+	case <-ctx.Done():
+	}
+	if err := s.StopWithin(s.shutdownTimeout); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// StopWithin attempts to close the stream within the specified timeout
+// period. Initially the attempt is graceful, but as the timeout draws close
+// the attempt becomes progressively less graceful.
+//
+// An error is returned if the stream has not been started, or if the stream
+// failed to stop within the timeout.
+func (s *Stream) StopWithin(timeout time.Duration) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.strm == nil {
+		return errors.New("stream has not been run yet")
+	}
+	if s.stopped {
+		return nil
+	}
+	s.stopped = true
+
+	stopAt := time.Now().Add(timeout)
+	if err := s.strm.Stop(timeout); err != nil {
+		s.consumerCancel()
+		return err
+	}
+
+	s.consumerCancel()
+	select {
+	case <-s.consumerDone:
+	case <-time.After(time.Until(stopAt)):
+		return types.ErrTimeout
+	}
+
+	s.mgr.CloseAsync()
+	if err := s.mgr.WaitForClose(time.Until(stopAt)); err != nil {
+		return err
+	}
+	if err := s.stats.Close(); err != nil {
+		s.logger.Errorf("Failed to cleanly close metrics aggregator: %v\n", err)
+	}
+	return log.Close(s.logger)
+}
+
+//------------------------------------------------------------------------------
+
+// runConsumer reads transactions from the inproc pipe of the consumer func
+// output and executes the consumer func on each message.
+func (s *Stream) runConsumer(ctx context.Context) {
+	defer close(s.consumerDone)
+
+	var tChan <-chan types.Transaction
+	for tChan == nil {
+		var err error
+		if tChan, err = s.mgr.GetPipe(s.consumerID); err != nil {
+			select {
+			case <-time.After(time.Millisecond * 10):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	for {
+		var t types.Transaction
+		var open bool
+		select {
+		case t, open = <-tChan:
+			if !open {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		err := t.Payload.Iter(func(i int, part types.Part) error {
+			return s.consumerFunc(ctx, newMessageFromPart(part))
+		})
+
+		var res types.Response = response.NewAck()
+		if err != nil {
+			res = response.NewError(err)
+		}
+		select {
+		case t.ResponseChan <- res:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
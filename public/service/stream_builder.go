@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+// MessageHandlerFunc is a function signature defining a component that
+// consumes Benthos messages. An error must be returned if the context is
+// cancelled, or if the message could not be delivered or processed.
+type MessageHandlerFunc func(context.Context, *Message) error
+
+// StreamBuilder provides methods for building a Benthos stream configuration.
+// When parsing Benthos configs this builder follows the schema and field
+// defaults of a standard Benthos configuration.
+//
+// Benthos streams register HTTP endpoints by default that expose metrics and
+// ready checks. However, the stream builder does not start an HTTP server and
+// these endpoints are therefore not accessible.
+type StreamBuilder struct {
+	conf    config.Type
+	inputs  []input.Config
+	outputs []output.Config
+
+	producerChan chan types.Transaction
+	producerID   string
+
+	consumerFunc MessageHandlerFunc
+	consumerID   string
+}
+
+// NewStreamBuilder creates a new StreamBuilder.
+func NewStreamBuilder() *StreamBuilder {
+	return &StreamBuilder{
+		conf: config.New(),
+	}
+}
+
+var builderPipeCounter int64
+
+func uniquePipeID(kind string) string {
+	return fmt.Sprintf("stream_builder_%v_%v", kind, atomic.AddInt64(&builderPipeCounter, 1))
+}
+
+// SetYAML parses a full Benthos config and uses it to configure the builder.
+// Any inputs, processors, outputs, resources and other settings that were
+// previously added to the builder are replaced.
+func (s *StreamBuilder) SetYAML(conf string) error {
+	newConf := config.New()
+	if err := yaml.Unmarshal([]byte(conf), &newConf); err != nil {
+		return err
+	}
+	s.conf = newConf
+	s.inputs = []input.Config{newConf.Input}
+	s.outputs = []output.Config{newConf.Output}
+	return nil
+}
+
+// SetLoggerYAML parses a logger YAML configuration and adds it to the builder
+// such that all stream components have access to it.
+func (s *StreamBuilder) SetLoggerYAML(conf string) error {
+	lconf := log.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &lconf); err != nil {
+		return err
+	}
+	s.conf.Logger = lconf
+	return nil
+}
+
+// SetMetricsYAML parses a metrics YAML configuration and adds it to the
+// builder such that all stream components emit metrics through it.
+func (s *StreamBuilder) SetMetricsYAML(conf string) error {
+	mconf := metrics.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &mconf); err != nil {
+		return err
+	}
+	s.conf.Metrics = mconf
+	return nil
+}
+
+// SetThreads configures the number of pipeline processor threads.
+func (s *StreamBuilder) SetThreads(n int) {
+	s.conf.Pipeline.Threads = n
+}
+
+// AddInputYAML parses an input YAML configuration and adds it to the builder.
+// If more than one input is added they will be combined with a broker.
+func (s *StreamBuilder) AddInputYAML(conf string) error {
+	iconf := input.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &iconf); err != nil {
+		return err
+	}
+	s.inputs = append(s.inputs, iconf)
+	return nil
+}
+
+// AddProcessorYAML parses a processor YAML configuration and adds it to the
+// builder to be executed within the pipeline.processors section, after all
+// prior added processor configs.
+func (s *StreamBuilder) AddProcessorYAML(conf string) error {
+	pconf := processor.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &pconf); err != nil {
+		return err
+	}
+	s.conf.Pipeline.Processors = append(s.conf.Pipeline.Processors, pconf)
+	return nil
+}
+
+// AddOutputYAML parses an output YAML configuration and adds it to the
+// builder. If more than one output is added they will be combined with a
+// fan_out broker.
+func (s *StreamBuilder) AddOutputYAML(conf string) error {
+	oconf := output.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &oconf); err != nil {
+		return err
+	}
+	s.outputs = append(s.outputs, oconf)
+	return nil
+}
+
+// AddCacheYAML parses a cache YAML configuration and adds it to the builder
+// as a resource with a given name.
+func (s *StreamBuilder) AddCacheYAML(name, conf string) error {
+	cconf := cache.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &cconf); err != nil {
+		return err
+	}
+	if _, exists := s.conf.Manager.Caches[name]; exists {
+		return fmt.Errorf("cache resource '%v' already exists", name)
+	}
+	s.conf.Manager.Caches[name] = cconf
+	return nil
+}
+
+// AddRateLimitYAML parses a rate limit YAML configuration and adds it to the
+// builder as a resource with a given name.
+func (s *StreamBuilder) AddRateLimitYAML(name, conf string) error {
+	rconf := ratelimit.NewConfig()
+	if err := yaml.Unmarshal([]byte(conf), &rconf); err != nil {
+		return err
+	}
+	if _, exists := s.conf.Manager.RateLimits[name]; exists {
+		return fmt.Errorf("rate limit resource '%v' already exists", name)
+	}
+	s.conf.Manager.RateLimits[name] = rconf
+	return nil
+}
+
+// AddProducerFunc adds an input to the builder that allows you to write
+// messages directly into the stream with a closure function. If any other
+// input has or will be added to the stream builder they will be automatically
+// composed within a broker when the pipeline is built.
+//
+// The returned MessageHandlerFunc can be called concurrently from any number
+// of goroutines, and each call will block until the message is either
+// successfully delivered to the outputs of the stream, or an error occurs.
+//
+// Only one producer func can be added to a stream builder, and subsequent
+// calls will return an error.
+func (s *StreamBuilder) AddProducerFunc() (MessageHandlerFunc, error) {
+	if s.producerChan != nil {
+		return nil, errors.New("unable to add multiple producer funcs to a stream builder")
+	}
+
+	s.producerChan = make(chan types.Transaction)
+	s.producerID = uniquePipeID("producer")
+
+	tChan := s.producerChan
+	return func(ctx context.Context, m *Message) error {
+		tMsg := message.New(nil)
+		tMsg.Append(m.part)
+		message.InitIngestionTimes(time.Now(), tMsg)
+
+		resChan := make(chan types.Response)
+		select {
+		case tChan <- types.NewTransaction(tMsg, resChan):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case res := <-resChan:
+			return res.Error()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, nil
+}
+
+// AddConsumerFunc adds an output to the builder that executes a closure
+// function for each message that reaches the end of the stream. If any other
+// output has or will be added to the stream builder they will be
+// automatically composed within a fan_out broker when the pipeline is built.
+//
+// The provided MessageHandlerFunc is called sequentially from a single
+// goroutine, and therefore the stream will not deliver the next message until
+// the function has returned. If an error is returned then the message is
+// nacked and will be reattempted or handled according to the input it came
+// from.
+//
+// Only one consumer func can be added to a stream builder, and subsequent
+// calls will return an error.
+func (s *StreamBuilder) AddConsumerFunc(fn MessageHandlerFunc) error {
+	if s.consumerFunc != nil {
+		return errors.New("unable to add multiple consumer funcs to a stream builder")
+	}
+	s.consumerFunc = fn
+	s.consumerID = uniquePipeID("consumer")
+	return nil
+}
+
+// AsYAML prints a YAML representation of the stream config as it has been
+// currently built.
+func (s *StreamBuilder) AsYAML() (string, error) {
+	conf, err := s.buildConfig()
+	if err != nil {
+		return "", err
+	}
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(sanit)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (s *StreamBuilder) buildConfig() (config.Type, error) {
+	conf := s.conf
+
+	inputs := append([]input.Config{}, s.inputs...)
+	if s.producerChan != nil {
+		iconf := input.NewConfig()
+		iconf.Type = input.TypeInproc
+		iconf.Inproc = input.InprocConfig(s.producerID)
+		inputs = append(inputs, iconf)
+	}
+	switch len(inputs) {
+	case 0:
+		return conf, errors.New("the stream must have at least one input")
+	case 1:
+		conf.Input = inputs[0]
+	default:
+		conf.Input = input.NewConfig()
+		conf.Input.Type = input.TypeBroker
+		conf.Input.Broker.Inputs = inputs
+	}
+
+	outputs := append([]output.Config{}, s.outputs...)
+	if s.consumerFunc != nil {
+		oconf := output.NewConfig()
+		oconf.Type = output.TypeInproc
+		oconf.Inproc = output.InprocConfig(s.consumerID)
+		outputs = append(outputs, oconf)
+	}
+	switch len(outputs) {
+	case 0:
+		return conf, errors.New("the stream must have at least one output")
+	case 1:
+		conf.Output = outputs[0]
+	default:
+		conf.Output = output.NewConfig()
+		conf.Output.Type = output.TypeBroker
+		conf.Output.Broker.Pattern = "fan_out"
+		conf.Output.Broker.Outputs = outputs
+	}
+	return conf, nil
+}
+
+// Build a Benthos stream pipeline according to the components specified by
+// this stream builder.
+func (s *StreamBuilder) Build() (*Stream, error) {
+	conf, err := s.buildConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	shutdownTimeout, err := time.ParseDuration(conf.SystemCloseTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse shutdown timeout: %w", err)
+	}
+
+	logger, err := log.NewV2(os.Stdout, conf.Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	stats, err := metrics.New(conf.Metrics, metrics.OptSetLogger(logger))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics: %w", err)
+	}
+
+	mgr, err := manager.New(conf.Manager, types.NoopMgr(), logger, stats)
+	if err != nil {
+		stats.Close()
+		return nil, fmt.Errorf("failed to create resources: %w", err)
+	}
+
+	if s.producerChan != nil {
+		mgr.SetPipe(s.producerID, s.producerChan)
+	}
+
+	return newStream(conf.Config, shutdownTimeout, mgr, logger, stats, s.consumerID, s.consumerFunc), nil
+}
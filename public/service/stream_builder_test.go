@@ -0,0 +1,148 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/public/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamBuilderProducerConsumer(t *testing.T) {
+	b := service.NewStreamBuilder()
+	require.NoError(t, b.SetLoggerYAML(`level: NONE`))
+	require.NoError(t, b.AddProcessorYAML(`bloblang: 'root = content().uppercase()'`))
+
+	pushFn, err := b.AddProducerFunc()
+	require.NoError(t, err)
+
+	_, err = b.AddProducerFunc()
+	require.Error(t, err)
+
+	var outMut sync.Mutex
+	var outputs []string
+	require.NoError(t, b.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		outMut.Lock()
+		defer outMut.Unlock()
+
+		bytes, err := m.AsBytes()
+		require.NoError(t, err)
+		outputs = append(outputs, string(bytes))
+		return nil
+	}))
+
+	strm, err := b.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	runErrChan := make(chan error, 1)
+	go func() {
+		runErrChan <- strm.Run(ctx)
+	}()
+
+	require.NoError(t, pushFn(ctx, service.NewMessage([]byte("hello world"))))
+	require.NoError(t, pushFn(ctx, service.NewMessage([]byte("and another"))))
+
+	require.NoError(t, strm.StopWithin(time.Second*10))
+	require.NoError(t, <-runErrChan)
+
+	outMut.Lock()
+	assert.Equal(t, []string{"HELLO WORLD", "AND ANOTHER"}, outputs)
+	outMut.Unlock()
+}
+
+func TestStreamBuilderConsumerNack(t *testing.T) {
+	b := service.NewStreamBuilder()
+	require.NoError(t, b.SetLoggerYAML(`level: NONE`))
+
+	pushFn, err := b.AddProducerFunc()
+	require.NoError(t, err)
+
+	require.NoError(t, b.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		return errors.New("nope")
+	}))
+
+	strm, err := b.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	go func() {
+		_ = strm.Run(ctx)
+	}()
+
+	require.EqualError(t, pushFn(ctx, service.NewMessage([]byte("hello world"))), "nope")
+	require.NoError(t, strm.StopWithin(time.Second*10))
+}
+
+func TestStreamBuilderSetYAML(t *testing.T) {
+	b := service.NewStreamBuilder()
+	require.NoError(t, b.SetYAML(`
+input:
+  generate:
+    count: 3
+    interval: ""
+    mapping: 'root = "foo"'
+pipeline:
+  processors:
+    - bloblang: 'root = content() + " bar"'
+output:
+  drop: {}
+logger:
+  level: NONE
+`))
+
+	var outMut sync.Mutex
+	var outputs []string
+	require.NoError(t, b.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+		outMut.Lock()
+		defer outMut.Unlock()
+
+		bytes, err := m.AsBytes()
+		require.NoError(t, err)
+		outputs = append(outputs, string(bytes))
+		return nil
+	}))
+
+	confStr, err := b.AsYAML()
+	require.NoError(t, err)
+	assert.Contains(t, confStr, "fan_out")
+	assert.Contains(t, confStr, "drop")
+
+	strm, err := b.Build()
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	require.NoError(t, strm.Run(ctx))
+
+	outMut.Lock()
+	assert.Equal(t, []string{"foo bar", "foo bar", "foo bar"}, outputs)
+	outMut.Unlock()
+}
+
+func TestStreamBuilderErrors(t *testing.T) {
+	b := service.NewStreamBuilder()
+	_, err := b.Build()
+	require.EqualError(t, err, "the stream must have at least one input")
+
+	require.NoError(t, b.AddInputYAML(`stdin: {}`))
+	_, err = b.Build()
+	require.EqualError(t, err, "the stream must have at least one output")
+
+	require.Error(t, b.AddInputYAML(`not_a_real_input: {}`))
+
+	require.NoError(t, b.AddCacheYAML("foo", `memory: {}`))
+	err = b.AddCacheYAML("foo", `memory: {}`)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "already exists"))
+}